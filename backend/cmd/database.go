@@ -1,6 +1,13 @@
 package cmd
 
-import "github.com/spf13/cobra"
+import (
+	"fmt"
+
+	sqliteRepo "github.com/potibm/kasseapparat/internal/app/repository/sqlite"
+	"github.com/potibm/kasseapparat/internal/app/utils"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
 
 func NewDatabaseCmd() *cobra.Command {
 	cmd := &cobra.Command{
@@ -11,3 +18,28 @@ func NewDatabaseCmd() *cobra.Command {
 
 	return cmd
 }
+
+// migrateDatabase migrates the tables and opens the inventory ledger of the products stored before
+// their stock was kept in it.
+func migrateDatabase(db *gorm.DB) error {
+	if err := utils.MigrateDatabase(db); err != nil {
+		return err
+	}
+
+	if err := sqliteRepo.NewRepository(db, 0).BackfillInventoryMovements(); err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	return nil
+}
+
+// seedDatabase seeds the database, the seeded stock and sales open the inventory ledger.
+func seedDatabase(db *gorm.DB, includeTestData bool) error {
+	utils.SeedDatabase(db, includeTestData)
+
+	if err := sqliteRepo.NewRepository(db, 0).BackfillInventoryMovements(); err != nil {
+		return fmt.Errorf("failed to seed the inventory ledger: %w", err)
+	}
+
+	return nil
+}
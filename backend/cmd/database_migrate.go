@@ -26,7 +26,7 @@ func NewDbMigrateCmd() *cobra.Command {
 				}
 			}()
 
-			err = utils.MigrateDatabase(db)
+			err = migrateDatabase(db)
 			if err != nil {
 				return fmt.Errorf("failed to migrate database: %w", err)
 			}
@@ -60,7 +60,7 @@ func performDatabaseReset(dbName string, shouldSeed, withTestData bool) error {
 		fn  func() error
 	}{
 		{"Deleting old tables...", func() error { return utils.PurgeDatabase(db) }},
-		{"Rebuilding table structure...", func() error { return utils.MigrateDatabase(db) }},
+		{"Rebuilding table structure...", func() error { return migrateDatabase(db) }},
 	}
 
 	for _, step := range steps {
@@ -73,7 +73,9 @@ func performDatabaseReset(dbName string, shouldSeed, withTestData bool) error {
 
 	if shouldSeed {
 		slog.Info("Running seeding...", "with_test_data", withTestData)
-		utils.SeedDatabase(db, withTestData)
+		if err := seedDatabase(db, withTestData); err != nil {
+			return err
+		}
 	}
 
 	slog.Info("Database reset completed successfully!")
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/potibm/kasseapparat/internal/app/repository/sqlite"
+	"github.com/potibm/kasseapparat/internal/app/service/journal"
+	"github.com/potibm/kasseapparat/internal/app/utils"
+	"github.com/spf13/cobra"
+)
+
+var errJournalBroken = errors.New("the journal chain is broken")
+
+func NewJournalCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "journal",
+		Short: "Journal of sales events commands",
+	}
+
+	return cmd
+}
+
+func NewJournalVerifyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Verifies the hash chain of the journal and reports every break",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := utils.ConnectToDatabase(Cfg.App.DbFilename)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = utils.CloseDatabase(db) }()
+
+			repo := sqlite.NewRepository(db, Cfg.Format.Currency.FractionDigitsMax)
+
+			result, err := journal.NewJournalService(repo).Verify()
+			if err != nil {
+				return fmt.Errorf("failed to verify the journal: %w", err)
+			}
+
+			for _, entryBreak := range result.Breaks {
+				fmt.Printf("❌ Entry %d: %s\n", entryBreak.Sequence, entryBreak.Reason)
+			}
+
+			fmt.Printf("\nVerified %d entries. Last hash: %s\n", result.EntryCount, result.LastHash)
+
+			if !result.Valid() {
+				return fmt.Errorf("%w: %d breaks found", errJournalBroken, len(result.Breaks))
+			}
+
+			fmt.Println("✅ The journal is intact!")
+
+			return nil
+		},
+	}
+
+	return cmd
+}
@@ -104,6 +104,19 @@ func Execute() error {
 	)
 	rootCmd.AddCommand(userCmd)
 
+	journalCmd := NewJournalCmd()
+	journalCmd.AddCommand(
+		NewJournalVerifyCmd(),
+	)
+	rootCmd.AddCommand(journalCmd)
+
+	sumupCmd := NewSumupCmd()
+	sumupCmd.AddCommand(
+		NewSumupReconcileCmd(),
+		NewSumupSettleRefundsCmd(),
+	)
+	rootCmd.AddCommand(sumupCmd)
+
 	rootCmd.AddCommand(NewConfigCmd())
 
 	return rootCmd.ExecuteContext(ctx)
@@ -12,10 +12,12 @@ import (
 	handlerHttp "github.com/potibm/kasseapparat/internal/app/handler/http"
 	"github.com/potibm/kasseapparat/internal/app/handler/websocket"
 	"github.com/potibm/kasseapparat/internal/app/initializer"
+	"github.com/potibm/kasseapparat/internal/app/mailer"
 	"github.com/potibm/kasseapparat/internal/app/models"
 	"github.com/potibm/kasseapparat/internal/app/monitor"
 	sqliteRepo "github.com/potibm/kasseapparat/internal/app/repository/sqlite"
 	sumupRepo "github.com/potibm/kasseapparat/internal/app/repository/sumup"
+	"github.com/potibm/kasseapparat/internal/app/repository/terminal"
 	purchaseService "github.com/potibm/kasseapparat/internal/app/service/purchase"
 	"github.com/potibm/kasseapparat/internal/app/utils"
 )
@@ -69,36 +71,37 @@ func NewServeCmd() *cobra.Command {
 			// 5. Dependency Injection (Repositories & Middleware)
 			sqliteRepository := sqliteRepo.NewRepository(db, Cfg.Format.Currency.FractionDigitsMax)
 			sumupRepository := sumupRepo.NewRepository(initializer.GetSumupService())
+			terminalProvider := initializer.InitializePaymentTerminal(Cfg.Terminal, sumupRepository)
 			mailer := initializer.InitializeMailer(Cfg.Mailer)
 			jwtMiddleware := initializer.InitializeJwtMiddleware(sqliteRepository, Cfg.Jwt, &Cfg.App.RedisURL)
 
 			// 6. Services & Handler
-			purchaseSvc := purchaseService.NewPurchaseService(
-				sqliteRepository,
-				sumupRepository,
-				&mailer,
-				Cfg.Format.Currency.FractionDigitsMax,
-				Cfg.Format.Currency.Code,
-			)
+			purchaseSvc, err := newPurchaseService(sqliteRepository, terminalProvider, &mailer)
+			if err != nil {
+				return err
+			}
+
+			publisher := &websocket.WebsocketPublisher{}
+			pendingTimeout := time.Duration(Cfg.Terminal.PendingTimeoutMinutes) * time.Minute
+			poller := monitor.NewPoller(terminalProvider, sqliteRepository, purchaseSvc, publisher, pendingTimeout)
 
 			websocketHandler := websocket.NewHandler(
 				sqliteRepository,
-				sumupRepository,
+				poller,
 				purchaseSvc,
 				jwtMiddleware,
 				&Cfg.App.CorsAllowOrigins,
 			)
-			publisher := &websocket.WebsocketPublisher{}
-			poller := monitor.NewPoller(sumupRepository, sqliteRepository, purchaseSvc, publisher)
 
 			httpHandlerConfig := handlerHttp.HandlerConfig{
-				Repo:            sqliteRepository,
-				SumupRepository: sumupRepository,
-				PurchaseService: purchaseSvc,
-				Monitor:         poller,
-				StatusPublisher: publisher,
-				Mailer:          mailer,
-				AppConfig:       Cfg,
+				Repo:             sqliteRepository,
+				SumupRepository:  sumupRepository,
+				TerminalProvider: terminalProvider,
+				PurchaseService:  purchaseSvc,
+				Monitor:          poller,
+				StatusPublisher:  publisher,
+				Mailer:           mailer,
+				AppConfig:        Cfg,
 			}
 			httpHandler := handlerHttp.NewHandler(httpHandlerConfig)
 
@@ -119,6 +122,8 @@ func NewServeCmd() *cobra.Command {
 			// 8. Start background tasks
 			startPollerForPendingPurchases(poller, sqliteRepository)
 			startCleanupForWebsocketConnections()
+			startRetentionForReceiptMails(sqliteRepository, Cfg.Receipt.MailRetentionDays)
+			startCleanupForIdempotencyKeys(sqliteRepository)
 
 			// 9. Start up HTTP Server
 			portStr := ":" + strconv.Itoa(port)
@@ -140,13 +145,71 @@ func startCleanupForWebsocketConnections() {
 	websocket.StartCleanupRoutine(cleanupInterval)
 }
 
-func startPollerForPendingPurchases(poller monitor.Poller, sqliteRepository *sqliteRepo.Repository) {
-	hasClientTransactionID := true
+// startRetentionForReceiptMails erases the email addresses of receipts sent by mail once the
+// retention period is over.
+func startRetentionForReceiptMails(sqliteRepository *sqliteRepo.Repository, retentionDays int) {
+	const retentionInterval = time.Hour
+
+	eraseExpiredAddresses := func() {
+		erased, err := sqliteRepository.ErasePurchaseReceiptMailAddresses(time.Now().AddDate(0, 0, -retentionDays))
+		if err != nil {
+			slog.Error("Failed to erase email addresses of receipt mails", "error", err)
+
+			return
+		}
+
+		if erased > 0 {
+			slog.Info("Erased email addresses of receipt mails", "count", erased)
+		}
+	}
+
+	go func() {
+		eraseExpiredAddresses()
+
+		ticker := time.NewTicker(retentionInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			eraseExpiredAddresses()
+		}
+	}()
+}
 
+// startCleanupForIdempotencyKeys removes the idempotency keys and their responses once they expired.
+func startCleanupForIdempotencyKeys(sqliteRepository *sqliteRepo.Repository) {
+	const cleanupInterval = time.Hour
+
+	deleteExpiredKeys := func() {
+		deleted, err := sqliteRepository.DeleteExpiredIdempotencyKeys(time.Now())
+		if err != nil {
+			slog.Error("Failed to delete expired idempotency keys", "error", err)
+
+			return
+		}
+
+		if deleted > 0 {
+			slog.Info("Deleted expired idempotency keys", "count", deleted)
+		}
+	}
+
+	go func() {
+		deleteExpiredKeys()
+
+		ticker := time.NewTicker(cleanupInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			deleteExpiredKeys()
+		}
+	}()
+}
+
+// startPollerForPendingPurchases polls the pending card payments, including the ones whose checkout
+// never reached a terminal, so they expire after the timeout.
+func startPollerForPendingPurchases(poller monitor.Poller, sqliteRepository *sqliteRepo.Repository) {
 	filters := sqliteRepo.PurchaseFilters{
-		PaymentMethods:         []models.PaymentMethod{models.PaymentMethodSumUp},
-		StatusList:             &models.PurchaseStatusList{models.PurchaseStatusPending},
-		HasClientTransactionID: &hasClientTransactionID,
+		PaymentMethods: []models.PaymentMethod{models.PaymentMethodSumUp},
+		StatusList:     &models.PurchaseStatusList{models.PurchaseStatusPending},
 	}
 
 	const plentyOfTransactions = 1000
@@ -163,3 +226,29 @@ func startPollerForPendingPurchases(poller monitor.Poller, sqliteRepository *sql
 		poller.Start(tx.ID)
 	}
 }
+
+func newPurchaseService(
+	sqliteRepository *sqliteRepo.Repository,
+	terminalProvider terminal.PaymentTerminalProvider,
+	mail *mailer.Mailer,
+) (*purchaseService.PurchaseService, error) {
+	purchaseSvc, err := purchaseService.NewPurchaseService(
+		sqliteRepository,
+		terminalProvider,
+		mail,
+		Cfg.Format.Currency.FractionDigitsMax,
+		Cfg.Format.Currency.Code,
+		purchaseService.Options{
+			ReceiptNumberPrefix:  Cfg.Receipt.NumberPrefix,
+			FiscalYearStartMonth: time.Month(Cfg.Receipt.FiscalYearStartMonth),
+			RoundingRules:        Cfg.PaymentMethods.RoundingRules(),
+			QuoteSecret:          Cfg.Jwt.Secret,
+			OfflineMaxAge:        Cfg.Purchases.OfflineMaxAge(),
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize the purchase service: %w", err)
+	}
+
+	return purchaseSvc, nil
+}
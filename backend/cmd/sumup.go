@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/potibm/kasseapparat/internal/app/initializer"
+	"github.com/potibm/kasseapparat/internal/app/repository/sqlite"
+	sumupRepo "github.com/potibm/kasseapparat/internal/app/repository/sumup"
+	"github.com/potibm/kasseapparat/internal/app/service/reconcile"
+	"github.com/potibm/kasseapparat/internal/app/utils"
+	"github.com/spf13/cobra"
+)
+
+const reconcileDayFmt = "2006-01-02"
+
+var (
+	errSumupNotReconciled = errors.New("the SumUp transactions and the purchases disagree")
+	errRefundsNotSettled  = errors.New("refunds could not be settled")
+)
+
+func NewSumupCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sumup",
+		Short: "SumUp payment commands",
+	}
+
+	return cmd
+}
+
+func NewSumupReconcileCmd() *cobra.Command {
+	var (
+		from   string
+		to     string
+		repair bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "reconcile",
+		Short: "Matches the SumUp transactions of a period with the purchases and reports every disagreement",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			periodStart, err := parseReconcileTime(from)
+			if err != nil {
+				return fmt.Errorf("invalid --from: %w", err)
+			}
+
+			periodEnd, err := parseReconcileTime(to)
+			if err != nil {
+				return fmt.Errorf("invalid --to: %w", err)
+			}
+
+			db, err := utils.ConnectToDatabase(Cfg.App.DbFilename)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = utils.CloseDatabase(db) }()
+
+			initializer.InitializeSumup(Cfg.Sumup)
+
+			repo := sqlite.NewRepository(db, Cfg.Format.Currency.FractionDigitsMax)
+			sumupRepository := sumupRepo.NewRepository(initializer.GetSumupService())
+			terminalProvider := initializer.InitializePaymentTerminal(Cfg.Terminal, sumupRepository)
+			mail := initializer.InitializeMailer(Cfg.Mailer)
+
+			purchaseSvc, err := newPurchaseService(repo, terminalProvider, &mail)
+			if err != nil {
+				return err
+			}
+
+			service := reconcile.NewReconcileService(
+				sumupRepository,
+				repo,
+				purchaseSvc,
+				Cfg.Format.Currency.FractionDigitsMax,
+			)
+
+			result, err := service.Reconcile(cmd.Context(), periodStart, periodEnd, repair)
+			if err != nil {
+				return fmt.Errorf("failed to reconcile the SumUp transactions: %w", err)
+			}
+
+			unresolved := 0
+
+			for _, issue := range result.Issues {
+				if issue.Repaired() {
+					fmt.Printf("🔧 %s, repaired to %s\n", describeReconcileIssue(issue), issue.RepairedStatus)
+
+					continue
+				}
+
+				unresolved++
+
+				if issue.RepairError != "" {
+					fmt.Printf("❌ %s, repair failed: %s\n", describeReconcileIssue(issue), issue.RepairError)
+
+					continue
+				}
+
+				fmt.Printf("❌ %s\n", describeReconcileIssue(issue))
+			}
+
+			fmt.Printf("\nReconciled %d transactions and %d purchases, %d matched.\n",
+				result.TransactionCount, result.PurchaseCount, result.MatchedCount)
+
+			if !result.Consistent() {
+				return fmt.Errorf("%w: %d issues found", errSumupNotReconciled, unresolved)
+			}
+
+			fmt.Println("✅ The SumUp transactions and the purchases agree!")
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&from, "from", "", "Start of the period (YYYY-MM-DD or RFC 3339)")
+	cmd.Flags().StringVar(&to, "to", "", "End of the period, excluded (YYYY-MM-DD or RFC 3339)")
+	cmd.Flags().BoolVar(&repair, "repair", false, "Change pending purchases to the final status of their transaction")
+	_ = cmd.MarkFlagRequired("from")
+	_ = cmd.MarkFlagRequired("to")
+
+	return cmd
+}
+
+func NewSumupSettleRefundsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "settle-refunds",
+		Short: "Retries refunding the card share of every refund that is not settled yet",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := utils.ConnectToDatabase(Cfg.App.DbFilename)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = utils.CloseDatabase(db) }()
+
+			initializer.InitializeSumup(Cfg.Sumup)
+
+			repo := sqlite.NewRepository(db, Cfg.Format.Currency.FractionDigitsMax)
+			sumupRepository := sumupRepo.NewRepository(initializer.GetSumupService())
+			terminalProvider := initializer.InitializePaymentTerminal(Cfg.Terminal, sumupRepository)
+			mail := initializer.InitializeMailer(Cfg.Mailer)
+
+			purchaseSvc, err := newPurchaseService(repo, terminalProvider, &mail)
+			if err != nil {
+				return err
+			}
+
+			refunds, err := repo.GetUnsettledPurchaseRefunds()
+			if err != nil {
+				return err
+			}
+
+			failed := 0
+
+			for _, refund := range refunds {
+				if _, err := purchaseSvc.SettleRefund(cmd.Context(), refund.ID); err != nil {
+					failed++
+
+					fmt.Printf("❌ Refund %d of purchase %s: %s\n", refund.ID, refund.PurchaseID, err)
+
+					continue
+				}
+
+				fmt.Printf("🔧 Refund %d of purchase %s settled\n", refund.ID, refund.PurchaseID)
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("%w: %d of %d refunds failed", errRefundsNotSettled, failed, len(refunds))
+			}
+
+			fmt.Printf("✅ %d refunds settled!\n", len(refunds))
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// parseReconcileTime parses a point in time, a day stands for its start in local time.
+func parseReconcileTime(value string) (time.Time, error) {
+	if day, err := time.ParseInLocation(reconcileDayFmt, value, time.Local); err == nil {
+		return day, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
+
+func describeReconcileIssue(issue reconcile.Issue) string {
+	transaction := issue.TransactionCode
+	if transaction == "" {
+		transaction = issue.TransactionID.String()
+	}
+
+	switch issue.Kind {
+	case reconcile.IssueOrphanedTransaction:
+		return fmt.Sprintf("Transaction %s (%s, %s) has no purchase",
+			transaction, issue.TransactionStatus, issue.TransactionAmount)
+	case reconcile.IssueOrphanedPurchase:
+		return fmt.Sprintf("Purchase %s (%s, %s) has no SumUp transaction",
+			issue.PurchaseID, issue.PurchaseStatus, issue.PurchaseAmount)
+	case reconcile.IssueAmountMismatch:
+		return fmt.Sprintf("Purchase %s is about %s, its transaction %s charged %s",
+			issue.PurchaseID, issue.PurchaseAmount, transaction, issue.TransactionAmount)
+	default:
+		return fmt.Sprintf("Purchase %s is %s, its transaction %s is %s",
+			issue.PurchaseID, issue.PurchaseStatus, transaction, issue.TransactionStatus)
+	}
+}
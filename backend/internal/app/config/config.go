@@ -19,6 +19,15 @@ const (
 	DefaultStandardVatRate = 25
 	DefaultReducedVatRate  = 12
 	DefaultZeroVatRate     = 0
+	DefaultDayStartHour    = 6
+	DefaultReceiptWidth    = 42
+
+	DefaultReceiptMailRetentionDays   = 30
+	DefaultIdempotencyKeyHours        = 24
+	DefaultIdempotencyKeyLeaseMinutes = 15
+	DefaultOfflineMaxAgeHours         = 24
+	DefaultFakeTerminalDelaySeconds   = 3
+	DefaultPendingTimeoutMinutes      = 10
 )
 
 var (
@@ -79,6 +88,23 @@ func InitViper() {
 	viper.SetDefault("sumup.application_id", "")
 	viper.SetDefault("sumup.public_url", "")
 
+	viper.SetDefault("terminal.provider", "sumup")
+	viper.SetDefault("terminal.fake_delay_seconds", DefaultFakeTerminalDelaySeconds)
+	viper.SetDefault("terminal.pending_timeout_minutes", DefaultPendingTimeoutMinutes)
+
+	viper.SetDefault("purchases.idempotency_key_hours", DefaultIdempotencyKeyHours)
+	viper.SetDefault("purchases.idempotency_key_lease_minutes", DefaultIdempotencyKeyLeaseMinutes)
+	viper.SetDefault("purchases.offline_max_age_hours", DefaultOfflineMaxAgeHours)
+
+	viper.SetDefault("reports.day_start_hour", DefaultDayStartHour)
+
+	viper.SetDefault("receipt.header", "")
+	viper.SetDefault("receipt.footer", "Thank you!")
+	viper.SetDefault("receipt.width", DefaultReceiptWidth)
+	viper.SetDefault("receipt.mail_retention_days", DefaultReceiptMailRetentionDays)
+	viper.SetDefault("receipt.number_prefix", "")
+	viper.SetDefault("receipt.fiscal_year_start_month", 1)
+
 	viper.SetDefault("vatrates", DefaultVatRates)
 	viper.SetDefault("payment_methods", DefaultPaymentMethods)
 
@@ -3,6 +3,7 @@ package config
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
@@ -46,3 +47,18 @@ func TestInitViperComplexDefaults(t *testing.T) {
 	paymentMethods := viper.Get("payment_methods")
 	assert.NotNil(t, paymentMethods)
 }
+
+func TestIdempotencyKeyLeaseOutlastsTheTerminalTimeout(t *testing.T) {
+	cfg := Config{
+		Purchases: PurchasesConfig{IdempotencyKeyLeaseMinutes: DefaultIdempotencyKeyLeaseMinutes},
+		Terminal:  TerminalConfig{PendingTimeoutMinutes: DefaultPendingTimeoutMinutes},
+	}
+	assert.Equal(t, 15*time.Minute, cfg.IdempotencyKeyLease())
+
+	cfg.Terminal.PendingTimeoutMinutes = 30
+	assert.Equal(t, 35*time.Minute, cfg.IdempotencyKeyLease())
+
+	cfg.Purchases.IdempotencyKeyLeaseMinutes = 0
+	cfg.Terminal.PendingTimeoutMinutes = 0
+	assert.Equal(t, 5*time.Minute, cfg.IdempotencyKeyLease())
+}
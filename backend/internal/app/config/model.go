@@ -2,6 +2,7 @@ package config
 
 import (
 	"net/url"
+	"time"
 
 	"github.com/potibm/kasseapparat/internal/app/models"
 )
@@ -79,8 +80,16 @@ type VatRatesConfig []VatRateConfig
 type PaymentMethods []PaymentMethodConfig
 
 type PaymentMethodConfig struct {
-	Code models.PaymentMethod
-	Name string
+	Code     models.PaymentMethod
+	Name     string
+	Rounding RoundingConfig `mapstructure:"rounding"`
+}
+
+// RoundingConfig rounds the amounts paid with a payment method to a multiple of the increment, e.g.
+// cash to 0.50. Without an increment the amounts are paid exactly.
+type RoundingConfig struct {
+	Increment string              `mapstructure:"increment"`
+	Mode      models.RoundingMode `mapstructure:"mode"`
 }
 
 type SumupConfig struct {
@@ -93,13 +102,65 @@ type SumupConfig struct {
 	PublicURL         string `mapstructure:"public_url"          validate:"omitempty,https_url"`
 }
 
+// TerminalConfig selects the provider of the card payment terminals. The fake provider simulates
+// the terminals locally, e.g. to train cashiers, and decides a checkout after the fake delay.
+// A card payment still pending after the pending timeout is failed, 0 keeps it pending.
+type TerminalConfig struct {
+	Provider              string `mapstructure:"provider"                validate:"omitempty,oneof=sumup fake"`
+	FakeDelaySeconds      int    `mapstructure:"fake_delay_seconds"      validate:"gte=0"`
+	PendingTimeoutMinutes int    `mapstructure:"pending_timeout_minutes" validate:"gte=0"`
+}
+
+type ReceiptConfig struct {
+	Header string `mapstructure:"header"`
+	Footer string `mapstructure:"footer"`
+	Width  int    `mapstructure:"width"  validate:"omitempty,gte=24"`
+
+	MailRetentionDays int `mapstructure:"mail_retention_days" validate:"gte=0"`
+
+	NumberPrefix         string `mapstructure:"number_prefix"           validate:"omitempty,alphanum,max=8"`
+	FiscalYearStartMonth int    `mapstructure:"fiscal_year_start_month" validate:"omitempty,gte=1,lte=12"`
+}
+
+type PurchasesConfig struct {
+	IdempotencyKeyHours        int `mapstructure:"idempotency_key_hours"         validate:"gte=0"`
+	IdempotencyKeyLeaseMinutes int `mapstructure:"idempotency_key_lease_minutes" validate:"gte=0"`
+	OfflineMaxAgeHours         int `mapstructure:"offline_max_age_hours"         validate:"gte=0"`
+}
+
+// OfflineMaxAge is how long ago a purchase synced from offline may have been recorded, 0 is unlimited.
+func (c PurchasesConfig) OfflineMaxAge() time.Duration {
+	return time.Duration(c.OfflineMaxAgeHours) * time.Hour
+}
+
+// idempotencyKeyLeaseMargin is how much longer than the terminal timeout the key of a request is kept.
+const idempotencyKeyLeaseMargin = 5 * time.Minute
+
+// IdempotencyKeyLease is how long the key of a request is kept while the request is handled. It
+// outlasts the pending timeout of the terminals by a margin, so the key of a card payment is not
+// given up while its checkout may still run.
+func (c Config) IdempotencyKeyLease() time.Duration {
+	lease := time.Duration(c.Purchases.IdempotencyKeyLeaseMinutes) * time.Minute
+	terminalTimeout := time.Duration(c.Terminal.PendingTimeoutMinutes) * time.Minute
+
+	return max(lease, terminalTimeout+idempotencyKeyLeaseMargin)
+}
+
+type ReportsConfig struct {
+	DayStartHour int `mapstructure:"day_start_hour" validate:"gte=0,lte=23"`
+}
+
 type Config struct {
-	App    AppConfig    `mapstructure:"app"`
-	Format FormatConfig `mapstructure:"format"`
-	Sentry SentryConfig `mapstructure:"sentry"`
-	Jwt    JwtConfig    `mapstructure:"jwt"`
-	Mailer MailerConfig `mapstructure:"mailer"`
-	Sumup  SumupConfig  `mapstructure:"sumup"`
+	App       AppConfig       `mapstructure:"app"`
+	Format    FormatConfig    `mapstructure:"format"`
+	Sentry    SentryConfig    `mapstructure:"sentry"`
+	Jwt       JwtConfig       `mapstructure:"jwt"`
+	Mailer    MailerConfig    `mapstructure:"mailer"`
+	Sumup     SumupConfig     `mapstructure:"sumup"`
+	Terminal  TerminalConfig  `mapstructure:"terminal"`
+	Purchases PurchasesConfig `mapstructure:"purchases"`
+	Reports   ReportsConfig   `mapstructure:"reports"`
+	Receipt   ReceiptConfig   `mapstructure:"receipt"`
 
 	VATRates       VatRatesConfig `mapstructure:"vat_rates"`
 	PaymentMethods PaymentMethods `mapstructure:"payment_methods"`
@@ -1,7 +1,10 @@
 package config
 
 import (
+	"fmt"
+
 	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/shopspring/decimal"
 )
 
 var allAvailablePaymentMethods = map[models.PaymentMethod]string{
@@ -31,6 +34,60 @@ func (pm PaymentMethods) GetName(code models.PaymentMethod) *string {
 	return nil
 }
 
+// RoundingRules returns the rounding rules of the payment methods that round their amounts.
+func (pm PaymentMethods) RoundingRules() map[models.PaymentMethod]models.RoundingRule {
+	rules := make(map[models.PaymentMethod]models.RoundingRule)
+
+	for _, method := range pm {
+		if rule, ok := method.Rounding.Rule(); ok {
+			rules[method.Code] = rule
+		}
+	}
+
+	return rules
+}
+
+func (pm PaymentMethods) Validate() error {
+	for _, method := range pm {
+		if err := method.Rounding.Validate(); err != nil {
+			return fmt.Errorf("payment_methods '%s': %w", method.Code, err)
+		}
+	}
+
+	return nil
+}
+
+// Rule returns the rounding rule, if an increment is configured. The mode defaults to nearest.
+func (rc RoundingConfig) Rule() (models.RoundingRule, bool) {
+	increment, err := decimal.NewFromString(rc.Increment)
+	if err != nil || !increment.IsPositive() {
+		return models.RoundingRule{}, false
+	}
+
+	mode := rc.Mode
+	if mode == "" {
+		mode = models.RoundingModeNearest
+	}
+
+	return models.RoundingRule{Increment: increment, Mode: mode}, true
+}
+
+func (rc RoundingConfig) Validate() error {
+	if rc.Increment != "" {
+		increment, err := decimal.NewFromString(rc.Increment)
+		if err != nil || !increment.IsPositive() {
+			return fmt.Errorf("rounding.increment '%s' is not a positive amount", rc.Increment)
+		}
+	}
+
+	switch rc.Mode {
+	case "", models.RoundingModeNearest, models.RoundingModeUp, models.RoundingModeDown:
+		return nil
+	default:
+		return fmt.Errorf("rounding.mode '%s' is not one of nearest, up or down", rc.Mode)
+	}
+}
+
 func isValidPaymentMethod(code models.PaymentMethod) bool {
 	_, exists := allAvailablePaymentMethods[code]
 
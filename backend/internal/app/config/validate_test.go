@@ -146,3 +146,21 @@ func TestAppConfigValidate(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "db_filename '../invalid' contains invalid characters")
 }
+
+func TestPaymentMethodsValidateRounding(t *testing.T) {
+	methods := PaymentMethods{
+		{Code: "CASH", Name: "Cash", Rounding: RoundingConfig{Increment: "0.50", Mode: "nearest"}},
+		{Code: "CC", Name: "Creditcard"},
+	}
+	assert.NoError(t, methods.Validate())
+
+	methods[0].Rounding = RoundingConfig{Increment: "-0.50"}
+	err := methods.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "payment_methods 'CASH': rounding.increment '-0.50' is not a positive amount")
+
+	methods[0].Rounding = RoundingConfig{Increment: "0.50", Mode: "banker"}
+	err = methods.Validate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "rounding.mode 'banker' is not one of nearest, up or down")
+}
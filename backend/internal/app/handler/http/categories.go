@@ -0,0 +1,215 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/potibm/kasseapparat/internal/app/models"
+	sqliteRepo "github.com/potibm/kasseapparat/internal/app/repository/sqlite"
+)
+
+type CategoryRequest struct {
+	Name  string `json:"name"  form:"name"  binding:"required"`
+	Pos   int    `json:"pos"   form:"pos"   binding:"numeric"`
+	Color string `json:"color" form:"color" binding:"omitempty,hexcolor"`
+	Icon  string `json:"icon"  form:"icon"`
+}
+
+// GetCategories lists the categories. Users restricted to categories only see their categories.
+func (handler *Handler) GetCategories(c *gin.Context) {
+	executingUserObj, err := handler.getUserFromContext(c)
+	if err != nil {
+		_ = c.Error(UnableToRetrieveExecutingUser.WithCause(err))
+
+		return
+	}
+
+	start, _ := strconv.Atoi(c.DefaultQuery("_start", "0"))
+	end, _ := strconv.Atoi(c.DefaultQuery("_end", "10"))
+	sort := c.DefaultQuery("_sort", "pos")
+	order := c.DefaultQuery("_order", "ASC")
+
+	filters := sqliteRepo.CategoryFilters{}
+	filters.Query = c.DefaultQuery("q", "")
+	filters.IDs = allowedIDs(queryArrayInt(c, "id"), executingUserObj.CategoryIDs())
+
+	if filters.IDs == nil {
+		c.Header("X-Total-Count", "0")
+		c.JSON(http.StatusOK, []models.Category{})
+
+		return
+	}
+
+	categories, err := handler.repo.GetCategories(end-start, start, sort, order, filters)
+	if err != nil {
+		_ = c.Error(InternalServerError.WithCauseMsg(err))
+
+		return
+	}
+
+	total, err := handler.repo.GetTotalCategories(filters)
+	if err != nil {
+		_ = c.Error(InternalServerError.WithCause(err))
+
+		return
+	}
+
+	c.Header("X-Total-Count", strconv.Itoa(int(total)))
+	c.JSON(http.StatusOK, categories)
+}
+
+// allowedIDs narrows the requested IDs down to the allowed ones. An empty list stands for all IDs,
+// nil is returned if none of the requested IDs is allowed.
+func allowedIDs(requested []int, allowed []int) []int {
+	if len(allowed) == 0 {
+		return requested
+	}
+
+	if len(requested) == 0 {
+		return allowed
+	}
+
+	var ids []int
+
+	for _, id := range requested {
+		if slices.Contains(allowed, id) {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+func (handler *Handler) GetCategoryByID(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	category, err := handler.repo.GetCategoryByID(id)
+	if err != nil {
+		_ = c.Error(NotFound.WithCause(err))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+func (handler *Handler) CreateCategory(c *gin.Context) {
+	executingUserObj, err := handler.getUserFromContext(c)
+	if err != nil {
+		_ = c.Error(UnableToRetrieveExecutingUser.WithCause(err))
+
+		return
+	}
+
+	if !executingUserObj.Admin {
+		_ = c.Error(Forbidden)
+
+		return
+	}
+
+	category, ok := bindCategory(c)
+	if !ok {
+		return
+	}
+
+	category.CreatedByID = &executingUserObj.ID
+
+	category, err = handler.repo.CreateCategory(category)
+	if err != nil {
+		_ = c.Error(InternalServerError.WithCauseMsg(err))
+
+		return
+	}
+
+	c.JSON(http.StatusCreated, category)
+}
+
+func (handler *Handler) UpdateCategoryByID(c *gin.Context) {
+	executingUserObj, err := handler.getUserFromContext(c)
+	if err != nil {
+		_ = c.Error(UnableToRetrieveExecutingUser.WithCause(err))
+
+		return
+	}
+
+	if !executingUserObj.Admin {
+		_ = c.Error(Forbidden)
+
+		return
+	}
+
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	category, ok := bindCategory(c)
+	if !ok {
+		return
+	}
+
+	category.UpdatedByID = &executingUserObj.ID
+
+	updatedCategory, err := handler.repo.UpdateCategoryByID(id, category)
+	if errors.Is(err, sqliteRepo.ErrCategoryNotFound) {
+		_ = c.Error(NotFound.WithCause(err))
+
+		return
+	}
+
+	if err != nil {
+		_ = c.Error(InternalServerError.WithCause(err))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, updatedCategory)
+}
+
+func (handler *Handler) DeleteCategoryByID(c *gin.Context) {
+	executingUserObj, err := handler.getUserFromContext(c)
+	if err != nil {
+		_ = c.Error(UnableToRetrieveExecutingUser.WithCause(err))
+
+		return
+	}
+
+	if !executingUserObj.Admin {
+		_ = c.Error(Forbidden)
+
+		return
+	}
+
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	category, err := handler.repo.GetCategoryByID(id)
+	if err != nil {
+		_ = c.Error(NotFound.WithCause(err))
+
+		return
+	}
+
+	if err := handler.repo.DeleteCategory(*category, *executingUserObj); err != nil {
+		_ = c.Error(InternalServerError.WithCause(err))
+
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func bindCategory(c *gin.Context) (models.Category, bool) {
+	var req CategoryRequest
+	if err := c.ShouldBind(&req); err != nil {
+		_ = c.Error(InvalidRequest.WithCauseMsg(err))
+
+		return models.Category{}, false
+	}
+
+	return models.Category{
+		Name:  req.Name,
+		Pos:   req.Pos,
+		Color: req.Color,
+		Icon:  req.Icon,
+	}, true
+}
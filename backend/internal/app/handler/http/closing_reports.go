@@ -0,0 +1,288 @@
+package http
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/potibm/kasseapparat/internal/app/models"
+	sqliteRepo "github.com/potibm/kasseapparat/internal/app/repository/sqlite"
+	"github.com/potibm/kasseapparat/templates"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	closingReportTemplate = "reports/closing_report.html"
+	closingReportDayFmt   = "2006-01-02"
+)
+
+type ClosingReportRequest struct {
+	Day         string     `json:"day"         form:"day"`
+	PeriodStart *time.Time `json:"periodStart" form:"periodStart"`
+	PeriodEnd   *time.Time `json:"periodEnd"   form:"periodEnd"`
+}
+
+// Period returns the period of the report. An event day starts at the configured hour and
+// lasts until the same hour of the next day, so nights past midnight belong to the day before.
+func (req ClosingReportRequest) Period(dayStartHour int) (time.Time, time.Time, error) {
+	if req.Day != "" {
+		day, err := time.ParseInLocation(closingReportDayFmt, req.Day, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("day must be formatted as YYYY-MM-DD")
+		}
+
+		start := day.Add(time.Duration(dayStartHour) * time.Hour)
+
+		return start, start.AddDate(0, 0, 1), nil
+	}
+
+	if req.PeriodStart == nil || req.PeriodEnd == nil {
+		return time.Time{}, time.Time{}, errors.New("either day or periodStart and periodEnd are required")
+	}
+
+	if !req.PeriodStart.Before(*req.PeriodEnd) {
+		return time.Time{}, time.Time{}, errors.New("periodStart must be before periodEnd")
+	}
+
+	return *req.PeriodStart, *req.PeriodEnd, nil
+}
+
+func (handler *Handler) GetClosingReports(c *gin.Context) {
+	if !handler.requireAdmin(c) {
+		return
+	}
+
+	start, _ := strconv.Atoi(c.DefaultQuery("_start", "0"))
+	end, _ := strconv.Atoi(c.DefaultQuery("_end", "10"))
+	sort := c.DefaultQuery("_sort", "number")
+	order := c.DefaultQuery("_order", "DESC")
+
+	reports, err := handler.repo.GetClosingReports(end-start, start, sort, order)
+	if err != nil {
+		_ = c.Error(InternalServerError.WithCauseMsg(err))
+
+		return
+	}
+
+	total, err := handler.repo.GetTotalClosingReports()
+	if err != nil {
+		_ = c.Error(InternalServerError.WithCause(err))
+
+		return
+	}
+
+	c.Header("X-Total-Count", strconv.Itoa(int(total)))
+	c.JSON(http.StatusOK, reports)
+}
+
+func (handler *Handler) GetClosingReportByID(c *gin.Context) {
+	if !handler.requireAdmin(c) {
+		return
+	}
+
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	report, err := handler.repo.GetClosingReportByID(id)
+	if err != nil {
+		_ = c.Error(NotFound.WithCause(err))
+
+		return
+	}
+
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		c.JSON(http.StatusOK, report)
+	case "csv":
+		handler.writeClosingReportCSV(c, report)
+	case "html":
+		handler.writeClosingReportHTML(c, report)
+	default:
+		_ = c.Error(InvalidRequest.WithMsg("Format must be one of json, csv or html"))
+	}
+}
+
+func (handler *Handler) CreateClosingReport(c *gin.Context) {
+	executingUserObj, err := handler.getUserFromContext(c)
+	if err != nil {
+		_ = c.Error(UnableToRetrieveExecutingUser.WithCause(err))
+
+		return
+	}
+
+	if !executingUserObj.Admin {
+		_ = c.Error(Forbidden)
+
+		return
+	}
+
+	var req ClosingReportRequest
+	if err := c.ShouldBind(&req); err != nil {
+		_ = c.Error(InvalidRequest.WithCauseMsg(err))
+
+		return
+	}
+
+	periodStart, periodEnd, err := req.Period(handler.config.Reports.DayStartHour)
+	if err != nil {
+		_ = c.Error(InvalidRequest.WithCauseMsg(err))
+
+		return
+	}
+
+	// the figures of a period that is not over yet would change after the report was stored
+	if periodEnd.After(time.Now()) {
+		_ = c.Error(InvalidRequest.WithMsg("A closing report can only be generated for a period that is over"))
+
+		return
+	}
+
+	figures, err := handler.repo.GetClosingReportFigures(periodStart, periodEnd)
+	if err != nil {
+		_ = c.Error(InternalServerError.WithCauseMsg(err))
+
+		return
+	}
+
+	report := models.ClosingReport{
+		PeriodStart: periodStart,
+		PeriodEnd:   periodEnd,
+		Figures:     figures,
+	}
+	report.CreatedByID = &executingUserObj.ID
+
+	storedReport, err := handler.repo.StoreClosingReport(report)
+	if errors.Is(err, sqliteRepo.ErrClosingReportOverlaps) {
+		_ = c.Error(Conflict.WithMsg("A closing report already covers this period").WithCause(err))
+
+		return
+	}
+
+	if err != nil {
+		_ = c.Error(InternalServerError.WithCauseMsg(err))
+
+		return
+	}
+
+	c.JSON(http.StatusCreated, storedReport)
+}
+
+func (handler *Handler) requireAdmin(c *gin.Context) bool {
+	executingUserObj, err := handler.getUserFromContext(c)
+	if err != nil {
+		_ = c.Error(UnableToRetrieveExecutingUser.WithCause(err))
+
+		return false
+	}
+
+	if !executingUserObj.Admin {
+		_ = c.Error(Forbidden)
+
+		return false
+	}
+
+	return true
+}
+
+func closingReportFilename(report *models.ClosingReport, extension string) string {
+	return fmt.Sprintf("closing_report_%04d_%s.%s", report.Number, report.PeriodStart.Format("20060102"), extension)
+}
+
+func (handler *Handler) writeClosingReportCSV(c *gin.Context, report *models.ClosingReport) {
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", "attachment; filename=\""+closingReportFilename(report, "csv")+"\"")
+
+	writer := csv.NewWriter(c.Writer)
+	defer writer.Flush()
+
+	amount := func(value decimal.Decimal) string {
+		return value.StringFixed(handler.decimalPlaces)
+	}
+
+	figures := report.Figures
+	rows := [][]string{
+		{"Section", "Name", "Quantity", "Net Price", "VAT Amount", "Gross Price", "Refunded"},
+		{
+			"Summary", "Total", strconv.Itoa(figures.PurchaseCount),
+			amount(figures.TotalNetPrice), amount(figures.TotalVATAmount), amount(figures.TotalGrossPrice),
+			amount(figures.Refunds.GrossPrice),
+		},
+	}
+
+	for _, vatRate := range figures.VATRates {
+		rows = append(rows, []string{
+			"VAT Rate", vatRate.VATRate.String(), "",
+			amount(vatRate.NetPrice), amount(vatRate.VATAmount), amount(vatRate.GrossPrice),
+			amount(vatRate.RefundedGrossPrice),
+		})
+	}
+
+	for _, method := range figures.PaymentMethods {
+		rows = append(rows, []string{
+			"Payment Method", string(method.PaymentMethod), "", "", "", amount(method.Amount),
+			amount(method.RefundedAmount),
+		})
+	}
+
+	for _, product := range figures.Products {
+		rows = append(rows, []string{
+			"Product", product.Name, strconv.Itoa(product.Quantity),
+			amount(product.NetPrice), "", amount(product.GrossPrice),
+			strconv.FormatUint(uint64(product.RefundedQuantity), 10),
+		})
+	}
+
+	rows = append(rows,
+		[]string{
+			"Refunds", "Refunds", strconv.Itoa(figures.Refunds.Count),
+			amount(figures.Refunds.NetPrice), "", amount(figures.Refunds.GrossPrice), "",
+		},
+		[]string{
+			"Cancellations", "Cancellations", strconv.Itoa(figures.Cancellations.Count),
+			"", "", amount(figures.Cancellations.GrossPrice), "",
+		},
+	)
+
+	if err := writer.WriteAll(rows); err != nil {
+		_ = c.Error(InternalServerError.WithMsg("Failed to write CSV: " + err.Error()).WithCause(err))
+	}
+}
+
+func (handler *Handler) writeClosingReportHTML(c *gin.Context, report *models.ClosingReport) {
+	tpl, err := template.New("closing_report.html").Funcs(template.FuncMap{
+		"formatAmount": func(value decimal.Decimal) string {
+			return value.StringFixed(handler.decimalPlaces)
+		},
+		"formatTime": func(value time.Time) string {
+			return value.Format("2006-01-02 15:04")
+		},
+		"paymentMethodName": func(code models.PaymentMethod) string {
+			if name := handler.config.PaymentMethods.GetName(code); name != nil {
+				return *name
+			}
+
+			return string(code)
+		},
+	}).ParseFS(templates.ReportTemplateFiles, closingReportTemplate)
+	if err != nil {
+		_ = c.Error(InternalServerError.WithMsg("Failed to parse report template").WithCause(err))
+
+		return
+	}
+
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Header("Content-Disposition", "inline; filename=\""+closingReportFilename(report, "html")+"\"")
+	c.Status(http.StatusOK)
+
+	err = tpl.Execute(c.Writer, map[string]any{
+		"Report":   report,
+		"Currency": handler.config.Format.Currency.Code,
+	})
+	if err != nil {
+		_ = c.Error(InternalServerError.WithMsg("Failed to render report").WithCause(err))
+	}
+}
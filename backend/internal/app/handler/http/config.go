@@ -8,8 +8,15 @@ import (
 )
 
 type PaymentMethodsConfig struct {
-	Code string `json:"code"`
-	Name string `json:"name"`
+	Code     string                 `json:"code"`
+	Name     string                 `json:"name"`
+	Rounding *PaymentRoundingConfig `json:"rounding"`
+}
+
+// PaymentRoundingConfig tells the client how the amounts paid with a payment method are rounded.
+type PaymentRoundingConfig struct {
+	Increment string `json:"increment"`
+	Mode      string `json:"mode"`
 }
 
 type VatRateConfig struct {
@@ -61,10 +68,19 @@ func convertPaymentMethods(paymentMethods []cfgTypes.PaymentMethodConfig) []Paym
 	result := make([]PaymentMethodsConfig, 0, len(paymentMethods))
 
 	for _, configPaymentMethod := range paymentMethods {
-		result = append(result, PaymentMethodsConfig{
+		paymentMethod := PaymentMethodsConfig{
 			Code: string(configPaymentMethod.Code),
 			Name: configPaymentMethod.Name,
-		})
+		}
+
+		if rule, ok := configPaymentMethod.Rounding.Rule(); ok {
+			paymentMethod.Rounding = &PaymentRoundingConfig{
+				Increment: rule.Increment.String(),
+				Mode:      string(rule.Mode),
+			}
+		}
+
+		result = append(result, paymentMethod)
 	}
 
 	return result
@@ -0,0 +1,204 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/potibm/kasseapparat/internal/app/models"
+	sqliteRepo "github.com/potibm/kasseapparat/internal/app/repository/sqlite"
+	"github.com/potibm/kasseapparat/internal/app/utils"
+	"github.com/shopspring/decimal"
+)
+
+type DiscountRequest struct {
+	Name      string              `json:"name"      form:"name"      binding:"required"`
+	Code      string              `json:"code"      form:"code"`
+	Type      models.DiscountType `json:"type"      form:"type"      binding:"required"`
+	Value     decimal.Decimal     `json:"value"     form:"value"     binding:"required"`
+	ProductID *int                `json:"productId" form:"productId"`
+}
+
+// toDiscount validates the request and returns the discount rule. Codes are stored in upper case.
+func (req DiscountRequest) toDiscount() (models.Discount, error) {
+	discount := models.Discount{
+		Name:      req.Name,
+		Type:      req.Type,
+		Value:     req.Value,
+		ProductID: req.ProductID,
+	}
+
+	if code := models.NormalizeDiscountCode(req.Code); code != "" {
+		discount.Code = &code
+	}
+
+	return discount, discount.Validate()
+}
+
+// GetDiscounts lists the discount rules. They are available to all users, so they can be offered
+// at the register.
+func (handler *Handler) GetDiscounts(c *gin.Context) {
+	start, _ := strconv.Atoi(c.DefaultQuery("_start", "0"))
+	end, _ := strconv.Atoi(c.DefaultQuery("_end", "10"))
+	sort := c.DefaultQuery("_sort", "id")
+	order := c.DefaultQuery("_order", "ASC")
+
+	filters := sqliteRepo.DiscountFilters{}
+	filters.Query = c.DefaultQuery("q", "")
+	filters.IDs = queryArrayInt(c, "id")
+
+	discounts, err := handler.repo.GetDiscounts(end-start, start, sort, order, filters)
+	if err != nil {
+		_ = c.Error(InternalServerError.WithCauseMsg(err))
+
+		return
+	}
+
+	total, err := handler.repo.GetTotalDiscounts(filters)
+	if err != nil {
+		_ = c.Error(InternalServerError.WithCause(err))
+
+		return
+	}
+
+	c.Header("X-Total-Count", strconv.Itoa(int(total)))
+	c.JSON(http.StatusOK, discounts)
+}
+
+func (handler *Handler) GetDiscountByID(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	discount, err := handler.repo.GetDiscountByID(id)
+	if err != nil {
+		_ = c.Error(NotFound.WithCause(err))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, discount)
+}
+
+func (handler *Handler) CreateDiscount(c *gin.Context) {
+	executingUserObj, err := handler.getUserFromContext(c)
+	if err != nil {
+		_ = c.Error(UnableToRetrieveExecutingUser.WithCause(err))
+
+		return
+	}
+
+	if !executingUserObj.Admin {
+		_ = c.Error(Forbidden)
+
+		return
+	}
+
+	discount, ok := handler.bindDiscount(c)
+	if !ok {
+		return
+	}
+
+	discount.CreatedByID = &executingUserObj.ID
+
+	discount, err = handler.repo.CreateDiscount(discount)
+	if err != nil {
+		_ = c.Error(InternalServerError.WithCauseMsg(err))
+
+		return
+	}
+
+	c.JSON(http.StatusCreated, discount)
+}
+
+func (handler *Handler) UpdateDiscountByID(c *gin.Context) {
+	executingUserObj, err := handler.getUserFromContext(c)
+	if err != nil {
+		_ = c.Error(UnableToRetrieveExecutingUser.WithCause(err))
+
+		return
+	}
+
+	if !executingUserObj.Admin {
+		_ = c.Error(Forbidden)
+
+		return
+	}
+
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	discount, ok := handler.bindDiscount(c)
+	if !ok {
+		return
+	}
+
+	discount.UpdatedByID = &executingUserObj.ID
+
+	updatedDiscount, err := handler.repo.UpdateDiscountByID(id, discount)
+	if errors.Is(err, sqliteRepo.ErrDiscountNotFound) {
+		_ = c.Error(NotFound.WithCause(err))
+
+		return
+	}
+
+	if err != nil {
+		_ = c.Error(InternalServerError.WithCause(err))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, updatedDiscount)
+}
+
+func (handler *Handler) DeleteDiscountByID(c *gin.Context) {
+	executingUserObj, err := handler.getUserFromContext(c)
+	if err != nil {
+		_ = c.Error(UnableToRetrieveExecutingUser.WithCause(err))
+
+		return
+	}
+
+	if !executingUserObj.Admin {
+		_ = c.Error(Forbidden)
+
+		return
+	}
+
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	discount, err := handler.repo.GetDiscountByID(id)
+	if err != nil {
+		_ = c.Error(NotFound.WithCause(err))
+
+		return
+	}
+
+	handler.repo.DeleteDiscount(*discount, *executingUserObj)
+
+	c.Status(http.StatusNoContent)
+}
+
+func (handler *Handler) bindDiscount(c *gin.Context) (models.Discount, bool) {
+	var req DiscountRequest
+	if err := c.ShouldBind(&req); err != nil {
+		_ = c.Error(InvalidRequest.WithCauseMsg(err))
+
+		return models.Discount{}, false
+	}
+
+	discount, err := req.toDiscount()
+	if err != nil {
+		_ = c.Error(InvalidRequest.WithMsg(utils.CapitalizeFirstRune(err.Error())).WithCause(err))
+
+		return models.Discount{}, false
+	}
+
+	if discount.ProductID != nil {
+		if _, err := handler.repo.GetProductByID(*discount.ProductID); err != nil {
+			_ = c.Error(InvalidRequest.WithMsg("Product not found").WithCause(err))
+
+			return models.Discount{}, false
+		}
+	}
+
+	return discount, true
+}
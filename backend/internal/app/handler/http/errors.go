@@ -33,6 +33,16 @@ var (
 		"Forbidden",
 		"You do not have permission to access this resource.",
 	)
+	Conflict = NewHTTPError(
+		http.StatusConflict,
+		"Conflict",
+		"The request conflicts with the current state of the resource.",
+	)
+	UnprocessableEntity = NewHTTPError(
+		http.StatusUnprocessableEntity,
+		"Unprocessable Entity",
+		"The request is well-formed but cannot be processed.",
+	)
 	BadRequest = NewHTTPError(
 		http.StatusBadRequest,
 		"Bad Request",
@@ -6,9 +6,12 @@ import (
 	"github.com/potibm/kasseapparat/internal/app/mailer"
 	"github.com/potibm/kasseapparat/internal/app/models"
 	"github.com/potibm/kasseapparat/internal/app/monitor"
+	"github.com/potibm/kasseapparat/internal/app/receipt"
 	sqliteRepo "github.com/potibm/kasseapparat/internal/app/repository/sqlite"
 	sumupRepo "github.com/potibm/kasseapparat/internal/app/repository/sumup"
+	"github.com/potibm/kasseapparat/internal/app/repository/terminal"
 	purchaseService "github.com/potibm/kasseapparat/internal/app/service/purchase"
+	"github.com/potibm/kasseapparat/internal/app/service/reconcile"
 )
 
 type StatusPublisher interface {
@@ -16,35 +19,47 @@ type StatusPublisher interface {
 }
 
 type Handler struct {
-	repo            sqliteRepo.RepositoryInterface
-	sumupRepository sumupRepo.RepositoryInterface
-	purchaseService purchaseService.Service
-	monitor         monitor.Poller
-	statusPublisher StatusPublisher
-	mailer          mailer.Mailer
-	config          config.Config
-	decimalPlaces   int32
+	repo             sqliteRepo.RepositoryInterface
+	sumupRepository  sumupRepo.RepositoryInterface
+	terminalProvider terminal.PaymentTerminalProvider
+	purchaseService  purchaseService.Service
+	monitor          monitor.Poller
+	statusPublisher  StatusPublisher
+	mailer           mailer.Mailer
+	receipts         *receipt.Renderer
+	reconciler       *reconcile.ReconcileService
+	config           config.Config
+	decimalPlaces    int32
 }
 
 type HandlerConfig struct {
-	Repo            sqliteRepo.RepositoryInterface
-	SumupRepository sumupRepo.RepositoryInterface
-	PurchaseService purchaseService.Service
-	Monitor         monitor.Poller
-	StatusPublisher StatusPublisher
-	Mailer          mailer.Mailer
-	AppConfig       config.Config
+	Repo             sqliteRepo.RepositoryInterface
+	SumupRepository  sumupRepo.RepositoryInterface
+	TerminalProvider terminal.PaymentTerminalProvider
+	PurchaseService  purchaseService.Service
+	Monitor          monitor.Poller
+	StatusPublisher  StatusPublisher
+	Mailer           mailer.Mailer
+	AppConfig        config.Config
 }
 
 func NewHandler(cfg HandlerConfig) *Handler {
 	return &Handler{
-		repo:            cfg.Repo,
-		sumupRepository: cfg.SumupRepository,
-		purchaseService: cfg.PurchaseService,
-		monitor:         cfg.Monitor,
-		statusPublisher: cfg.StatusPublisher,
-		mailer:          cfg.Mailer,
-		config:          cfg.AppConfig,
-		decimalPlaces:   cfg.AppConfig.Format.Currency.FractionDigitsMax,
+		repo:             cfg.Repo,
+		sumupRepository:  cfg.SumupRepository,
+		terminalProvider: cfg.TerminalProvider,
+		purchaseService:  cfg.PurchaseService,
+		monitor:          cfg.Monitor,
+		statusPublisher:  cfg.StatusPublisher,
+		mailer:           cfg.Mailer,
+		receipts:         receipt.NewRenderer(cfg.AppConfig, cfg.TerminalProvider),
+		reconciler: reconcile.NewReconcileService(
+			cfg.SumupRepository,
+			cfg.Repo,
+			cfg.PurchaseService,
+			cfg.AppConfig.Format.Currency.FractionDigitsMax,
+		),
+		config:        cfg.AppConfig,
+		decimalPlaces: cfg.AppConfig.Format.Currency.FractionDigitsMax,
 	}
 }
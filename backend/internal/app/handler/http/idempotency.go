@@ -0,0 +1,189 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/potibm/kasseapparat/internal/app/middleware"
+	"github.com/potibm/kasseapparat/internal/app/models"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+)
+
+// idempotencyRecorder passes the response through and keeps a copy of its body.
+type idempotencyRecorder struct {
+	gin.ResponseWriter
+
+	body bytes.Buffer
+}
+
+func (r *idempotencyRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *idempotencyRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+
+	return r.ResponseWriter.WriteString(s)
+}
+
+// Idempotent handles a request sent with an Idempotency-Key header only once per user and key. A
+// repeated request gets the response to the first request, a repeat with a different request is
+// rejected. A request rejected with a client error changed nothing, so its key is released and the
+// request can be sent again with it. The response to a server error is kept like a success, as the
+// request may have failed after its side effects, e.g. once the checkout on a terminal was created.
+// The key of a request still being handled is kept for the configured lease only, so a request that
+// never completes, e.g. because the server stopped, does not block its key. Without a retention period for the keys the header is ignored.
+func (handler *Handler) Idempotent() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := strings.TrimSpace(c.GetHeader(IdempotencyKeyHeader))
+		if key == "" || handler.config.Purchases.IdempotencyKeyHours == 0 {
+			c.Next()
+
+			return
+		}
+
+		stored, reserved, ok := handler.reserveIdempotencyKey(c, key)
+		if !ok {
+			c.Abort()
+
+			return
+		}
+
+		if !reserved {
+			replayIdempotentRequest(c, stored)
+			c.Abort()
+
+			return
+		}
+
+		recorder := &idempotencyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		// a panicking request is failed, its key is released before the panic is passed on
+		defer func() {
+			if r := recover(); r != nil {
+				handler.releaseIdempotencyKey(c, stored.ID)
+
+				panic(r)
+			}
+		}()
+
+		c.Next()
+
+		// the error is written here rather than by the error middleware, so its response is kept
+		if len(c.Errors) > 0 && !c.Writer.Written() {
+			middleware.WriteErrorResponse(c, c.Errors[0].Err)
+		}
+
+		if isClientError(recorder.Status()) {
+			handler.releaseIdempotencyKey(c, stored.ID)
+
+			return
+		}
+
+		retention := time.Duration(handler.config.Purchases.IdempotencyKeyHours) * time.Hour
+
+		err := handler.repo.CompleteIdempotencyKey(
+			stored.ID,
+			recorder.Status(),
+			recorder.Header().Get("Content-Type"),
+			recorder.body.String(),
+			time.Now().Add(retention),
+		)
+		if err != nil {
+			slog.ErrorContext(c.Request.Context(), "Error storing the response of the idempotency key", "error", err)
+		}
+	}
+}
+
+// reserveIdempotencyKey stores the key with the hash of the request, or returns the key stored for
+// an earlier request. A key stored for a different request is rejected.
+func (handler *Handler) reserveIdempotencyKey(c *gin.Context, key string) (models.IdempotencyKey, bool, bool) {
+	if len(key) > maxIdempotencyKeyLength {
+		_ = c.Error(InvalidRequest.WithMsg("Idempotency key is too long"))
+
+		return models.IdempotencyKey{}, false, false
+	}
+
+	executingUserObj, err := handler.getUserFromContext(c)
+	if err != nil {
+		_ = c.Error(UnableToRetrieveExecutingUser.WithCause(err))
+
+		return models.IdempotencyKey{}, false, false
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		_ = c.Error(InvalidRequest.WithCauseMsg(err))
+
+		return models.IdempotencyKey{}, false, false
+	}
+
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	requestHash := hashIdempotentRequest(c.Request.Method, c.Request.URL.Path, body)
+
+	stored, reserved, err := handler.repo.ReserveIdempotencyKey(models.IdempotencyKey{
+		UserID:      executingUserObj.ID,
+		Key:         key,
+		RequestHash: requestHash,
+		ExpiresAt:   time.Now().Add(handler.config.IdempotencyKeyLease()),
+	})
+	if err != nil {
+		_ = c.Error(InternalServerError.WithCause(err))
+
+		return models.IdempotencyKey{}, false, false
+	}
+
+	if stored.RequestHash != requestHash {
+		_ = c.Error(UnprocessableEntity.WithMsg("Idempotency key was already used for a different request"))
+
+		return models.IdempotencyKey{}, false, false
+	}
+
+	return stored, reserved, true
+}
+
+func (handler *Handler) releaseIdempotencyKey(c *gin.Context, id int) {
+	if err := handler.repo.ReleaseIdempotencyKey(id); err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error releasing the idempotency key", "error", err)
+	}
+}
+
+func isClientError(status int) bool {
+	return status >= http.StatusBadRequest && status < http.StatusInternalServerError
+}
+
+func replayIdempotentRequest(c *gin.Context, stored models.IdempotencyKey) {
+	if !stored.Completed() {
+		_ = c.Error(Conflict.WithMsg("A request with this idempotency key is still being processed"))
+
+		return
+	}
+
+	c.Header(IdempotentReplayedHeader, "true")
+	c.Data(stored.StatusCode, stored.ContentType, []byte(stored.ResponseBody))
+}
+
+func hashIdempotentRequest(method, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + path + "\n"))
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,236 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/potibm/kasseapparat/internal/app/models"
+	sqliteRepo "github.com/potibm/kasseapparat/internal/app/repository/sqlite"
+	"github.com/potibm/kasseapparat/internal/app/response"
+)
+
+// InventoryMovementRequest records a movement of the stock of a product or of one of its variants.
+// Initial stock, restocks and write-offs are given as a positive quantity, a count as the counted
+// stock on hand.
+type InventoryMovementRequest struct {
+	VariantID       *int                         `json:"variantId"`
+	Type            models.InventoryMovementType `json:"type"            binding:"required,oneof=initial restock write_off count_correction"`
+	Quantity        int                          `json:"quantity"        binding:"gte=0"`
+	CountedQuantity *int                         `json:"countedQuantity" binding:"omitempty,gte=0"`
+	Note            string                       `json:"note"`
+}
+
+// GetInventoryMovementsByProductID lists the inventory ledger of a product, newest first.
+func (handler *Handler) GetInventoryMovementsByProductID(c *gin.Context) {
+	start, _ := strconv.Atoi(c.DefaultQuery("_start", "0"))
+	end, _ := strconv.Atoi(c.DefaultQuery("_end", "10"))
+	productID, _ := strconv.Atoi(c.Param("id"))
+
+	filters := sqliteRepo.InventoryMovementFilters{ProductID: productID}
+	if variantID, err := strconv.Atoi(c.Query("variantId")); err == nil {
+		filters.VariantID = &variantID
+	}
+
+	movements, err := handler.repo.GetInventoryMovements(end-start, start, filters)
+	if err != nil {
+		_ = c.Error(InternalServerError.WithCauseMsg(err))
+
+		return
+	}
+
+	total, err := handler.repo.GetTotalInventoryMovements(filters)
+	if err != nil {
+		_ = c.Error(InternalServerError.WithCauseMsg(err))
+
+		return
+	}
+
+	c.Header("X-Total-Count", strconv.Itoa(int(total)))
+	c.JSON(http.StatusOK, movements)
+}
+
+// CreateInventoryMovement records a movement of the stock of a product. The stock of a product with
+// variants is kept per variant, and a bundle has no stock of its own.
+func (handler *Handler) CreateInventoryMovement(c *gin.Context) {
+	executingUserObj, err := handler.getUserFromContext(c)
+	if err != nil {
+		_ = c.Error(UnableToRetrieveExecutingUser.WithCause(err))
+
+		return
+	}
+
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	product, err := handler.repo.GetProductByID(id)
+	if err != nil {
+		_ = c.Error(NotFound.WithCause(err))
+
+		return
+	}
+
+	var request InventoryMovementRequest
+	if err := c.ShouldBind(&request); err != nil {
+		_ = c.Error(InvalidRequest.WithCauseMsg(err))
+
+		return
+	}
+
+	movement, ok := inventoryMovementFromRequest(c, product, request)
+	if !ok {
+		return
+	}
+
+	movement.CreatedByID = &executingUserObj.ID
+
+	movement, err = handler.repo.RecordInventoryMovement(movement)
+	if err != nil {
+		_ = c.Error(InternalServerError.WithCause(err))
+
+		return
+	}
+
+	c.JSON(http.StatusCreated, movement)
+}
+
+func inventoryMovementFromRequest(
+	c *gin.Context,
+	product *models.Product,
+	request InventoryMovementRequest,
+) (models.InventoryMovement, bool) {
+	movement := models.InventoryMovement{
+		ProductID: product.ID,
+		Type:      request.Type,
+		Quantity:  request.Quantity,
+		Note:      request.Note,
+	}
+
+	switch {
+	case product.IsBundle():
+		_ = c.Error(InvalidRequest.WithMsg("A bundle has no stock of its own"))
+
+		return movement, false
+	case product.HasVariants() && request.VariantID == nil:
+		_ = c.Error(InvalidRequest.WithMsg("The stock of a product with variants is kept per variant"))
+
+		return movement, false
+	case request.VariantID != nil:
+		if _, ok := product.Variant(*request.VariantID); !ok {
+			_ = c.Error(InvalidRequest.WithMsg("Variant not found"))
+
+			return movement, false
+		}
+
+		movement.VariantID = request.VariantID
+	}
+
+	if request.Type == models.InventoryMovementCountCorrection {
+		if request.CountedQuantity == nil {
+			_ = c.Error(InvalidRequest.WithMsg("A count needs the counted quantity"))
+
+			return movement, false
+		}
+
+		movement.CountedQuantity = request.CountedQuantity
+
+		return movement, true
+	}
+
+	if request.Quantity == 0 {
+		_ = c.Error(InvalidRequest.WithMsg("The quantity must be positive"))
+
+		return movement, false
+	}
+
+	if request.Type == models.InventoryMovementWriteOff {
+		movement.Quantity = -request.Quantity
+	}
+
+	return movement, true
+}
+
+// GetStockCounts reports the stock counts, comparing the counted to the expected stock.
+func (handler *Handler) GetStockCounts(c *gin.Context) {
+	start, _ := strconv.Atoi(c.DefaultQuery("_start", "0"))
+	end, _ := strconv.Atoi(c.DefaultQuery("_end", "10"))
+
+	filters := sqliteRepo.InventoryMovementFilters{Counted: true}
+	if productID, err := strconv.Atoi(c.Query("productId")); err == nil {
+		filters.ProductID = productID
+	}
+
+	movements, err := handler.repo.GetInventoryMovements(end-start, start, filters)
+	if err != nil {
+		_ = c.Error(InternalServerError.WithCauseMsg(err))
+
+		return
+	}
+
+	total, err := handler.repo.GetTotalInventoryMovements(filters)
+	if err != nil {
+		_ = c.Error(InternalServerError.WithCauseMsg(err))
+
+		return
+	}
+
+	c.Header("X-Total-Count", strconv.Itoa(int(total)))
+	c.JSON(http.StatusOK, response.ToStockCounts(movements))
+}
+
+// recordFormStockMovements records the stock changed in the product form in the inventory ledger.
+// Stock given to a product or variant without stock opens its ledger, more stock is recorded as a
+// restock and less as a correction, as the form does not tell why the stock is gone.
+func recordFormStockMovements(repo sqliteRepo.RepositoryInterface, previous, product models.Product, userID int) error {
+	if err := recordFormStockMovement(repo, product.ID, nil, previous.TotalStock, product.TotalStock, userID); err != nil {
+		return err
+	}
+
+	for _, variant := range product.Variants {
+		previousVariant, _ := previous.Variant(variant.ID)
+		if err := recordFormStockMovement(
+			repo,
+			product.ID,
+			&variant.ID,
+			previousVariant.TotalStock,
+			variant.TotalStock,
+			userID,
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func recordFormStockMovement(
+	repo sqliteRepo.RepositoryInterface,
+	productID int,
+	variantID *int,
+	previousStock, stock int,
+	userID int,
+) error {
+	if stock == previousStock {
+		return nil
+	}
+
+	movement := models.InventoryMovement{
+		ProductID:   productID,
+		VariantID:   variantID,
+		Type:        models.InventoryMovementRestock,
+		Quantity:    stock - previousStock,
+		CreatedByID: &userID,
+		Note:        fmt.Sprintf("Total stock changed from %d to %d", previousStock, stock),
+	}
+
+	switch {
+	case previousStock == 0:
+		movement.Type = models.InventoryMovementInitial
+	case movement.Quantity < 0:
+		movement.Type = models.InventoryMovementCountCorrection
+	}
+
+	_, err := repo.StoreInventoryMovement(movement)
+
+	return err
+}
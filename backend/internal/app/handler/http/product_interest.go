@@ -18,7 +18,8 @@ var (
 )
 
 type ProductInterestCreateRequest struct {
-	ProductID int `json:"productId" form:"productId" binding:"required"`
+	ProductID int  `json:"productId" form:"productId" binding:"required"`
+	VariantID *int `json:"variantId" form:"variantId"`
 }
 
 func (handler *Handler) GetProductInterests(c *gin.Context) {
@@ -84,6 +85,7 @@ func (handler *Handler) CreateProductInterest(c *gin.Context) {
 	}
 
 	productInterest.ProductID = productInterestRequest.ProductID
+	productInterest.VariantID = productInterestRequest.VariantID
 
 	product, err := handler.repo.GetProductByID(productInterest.ProductID) // check if product exists
 	if product == nil || err != nil {
@@ -92,6 +94,19 @@ func (handler *Handler) CreateProductInterest(c *gin.Context) {
 		return
 	}
 
+	variantName := ""
+
+	if productInterest.VariantID != nil {
+		variant, ok := product.Variant(*productInterest.VariantID)
+		if !ok {
+			_ = c.Error(BadRequest.WithMsg("Variant not found"))
+
+			return
+		}
+
+		variantName = variant.Name
+	}
+
 	productInterest, err = handler.repo.CreateProductInterest(productInterest, *executingUserObj)
 	if err != nil {
 		_ = c.Error(InternalServerError.WithCauseMsg(err))
@@ -103,6 +118,7 @@ func (handler *Handler) CreateProductInterest(c *gin.Context) {
 		metric.WithAttributes(
 			attribute.Int("product_id", int(productInterest.ProductID)),
 			attribute.String("product_name", product.Name),
+			attribute.String("variant_name", variantName),
 		),
 	)
 
@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	response "github.com/potibm/kasseapparat/internal/app/response"
 )
 
 func (handler *Handler) GetProductStats(c *gin.Context) {
@@ -18,3 +19,18 @@ func (handler *Handler) GetProductStats(c *gin.Context) {
 	c.Header("X-Total-Count", strconv.Itoa(len(products)))
 	c.JSON(http.StatusOK, products)
 }
+
+// GetCategoryStats returns the sales figures by category.
+func (handler *Handler) GetCategoryStats(c *gin.Context) {
+	products, err := handler.repo.GetProductStats()
+	if err != nil {
+		_ = c.Error(InternalServerError.WithCause(err))
+
+		return
+	}
+
+	categories := response.ToCategoryStats(products)
+
+	c.Header("X-Total-Count", strconv.Itoa(len(categories)))
+	c.JSON(http.StatusOK, categories)
+}
@@ -1,46 +1,106 @@
 package http
 
 import (
+	"log/slog"
 	"net/http"
+	"slices"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/potibm/kasseapparat/internal/app/models"
 	sqliteRepo "github.com/potibm/kasseapparat/internal/app/repository/sqlite"
 	response "github.com/potibm/kasseapparat/internal/app/response"
+	"github.com/potibm/kasseapparat/internal/app/utils"
 	"github.com/shopspring/decimal"
 )
 
+// ProductBundleItemRequest is a component of a bundle product.
+type ProductBundleItemRequest struct {
+	ProductID int  `json:"productId" binding:"required"`
+	Quantity  uint `json:"quantity"  binding:"required,gte=1"`
+}
+
+// ProductVariantRequest is a variant of a product. Variants without a net price are sold at the
+// price of the product. Existing variants are updated by their ID.
+type ProductVariantRequest struct {
+	ID         int              `json:"id"`
+	Name       string           `json:"name"       binding:"required"`
+	NetPrice   *decimal.Decimal `json:"netPrice"`
+	Pos        int              `json:"pos"`
+	TotalStock int              `json:"totalStock" binding:"gte=0"`
+	TrackStock bool             `json:"trackStock"`
+	SoldOut    bool             `json:"soldOut"`
+}
+
+// ProductPriceRuleRequest is a scheduled price of a product. Weekdays are numbered from 0 for
+// Sunday to 6 for Saturday.
+type ProductPriceRuleRequest struct {
+	NetPrice   decimal.Decimal `json:"netPrice"   binding:"required"`
+	ValidFrom  *time.Time      `json:"validFrom"`
+	ValidUntil *time.Time      `json:"validUntil"`
+	Weekdays   []time.Weekday  `json:"weekdays"   binding:"omitempty,dive,gte=0,lte=6"`
+	FromHour   *int            `json:"fromHour"   binding:"omitempty,gte=0,lte=23"`
+	UntilHour  *int            `json:"untilHour"  binding:"omitempty,gte=0,lte=23"`
+	Pos        int             `json:"pos"`
+}
+
+// ProductRequestCreate creates a product. A product with bundle items is a bundle, it is sold for
+// the gross price and its net price and VAT rate are taken from the components.
 type ProductRequestCreate struct {
-	Name      string          `json:"name"      form:"name"      binding:"required"`
-	NetPrice  decimal.Decimal `json:"netPrice"  form:"netPrice"  binding:"required"`
-	VATRate   decimal.Decimal `json:"vatRate"   form:"vatRate"   binding:"required"`
-	WrapAfter bool            `json:"wrapAfter" form:"wrapAfter"`
-	Pos       int             `json:"pos"       form:"pos"       binding:"numeric,required"`
-	Hidden    bool            `json:"hidden"    form:"hidden"    binding:"boolean"`
+	Name        string                     `json:"name"        form:"name"        binding:"required"`
+	NetPrice    decimal.Decimal            `json:"netPrice"    form:"netPrice"    binding:"required"`
+	VATRate     decimal.Decimal            `json:"vatRate"     form:"vatRate"     binding:"required"`
+	WrapAfter   bool                       `json:"wrapAfter"   form:"wrapAfter"`
+	Pos         int                        `json:"pos"         form:"pos"         binding:"numeric,required"`
+	Hidden      bool                       `json:"hidden"      form:"hidden"      binding:"boolean"`
+	CategoryID  *int                       `json:"categoryId"  form:"categoryId"`
+	GrossPrice  decimal.Decimal            `json:"grossPrice"  form:"grossPrice"`
+	BundleItems []ProductBundleItemRequest `json:"bundleItems" form:"bundleItems" binding:"omitempty,dive"`
+	Variants    []ProductVariantRequest    `json:"variants"    form:"variants"    binding:"omitempty,dive"`
+	PriceRules  []ProductPriceRuleRequest  `json:"priceRules"  form:"priceRules"  binding:"omitempty,dive"`
 }
 
 type ProductRequestUpdate struct {
-	Name       string          `json:"name"       form:"name"       binding:"required"`
-	NetPrice   decimal.Decimal `json:"netPrice"   form:"netPrice"   binding:"required"`
-	VATRate    decimal.Decimal `json:"vatRate"    form:"vatRate"    binding:"required"`
-	WrapAfter  bool            `json:"wrapAfter"  form:"wrapAfter"`
-	Pos        int             `json:"pos"        form:"pos"        binding:"numeric,required"`
-	APIExport  bool            `json:"apiExport"  form:"apiExport"  binding:"boolean"`
-	Hidden     bool            `json:"hidden"     form:"hidden"     binding:"boolean"`
-	SoldOut    bool            `json:"soldOut"    form:"soldOut"    binding:"boolean"`
-	TotalStock int             `json:"totalStock" form:"totalStock" binding:"numeric"`
+	Name        string                     `json:"name"        form:"name"        binding:"required"`
+	NetPrice    decimal.Decimal            `json:"netPrice"    form:"netPrice"    binding:"required"`
+	VATRate     decimal.Decimal            `json:"vatRate"     form:"vatRate"     binding:"required"`
+	WrapAfter   bool                       `json:"wrapAfter"   form:"wrapAfter"`
+	Pos         int                        `json:"pos"         form:"pos"         binding:"numeric,required"`
+	APIExport   bool                       `json:"apiExport"   form:"apiExport"   binding:"boolean"`
+	Hidden      bool                       `json:"hidden"      form:"hidden"      binding:"boolean"`
+	SoldOut     bool                       `json:"soldOut"     form:"soldOut"     binding:"boolean"`
+	TotalStock  int                        `json:"totalStock"  form:"totalStock"  binding:"numeric"`
+	TrackStock  bool                       `json:"trackStock"  form:"trackStock"  binding:"boolean"`
+	CategoryID  *int                       `json:"categoryId"  form:"categoryId"`
+	GrossPrice  decimal.Decimal            `json:"grossPrice"  form:"grossPrice"`
+	BundleItems []ProductBundleItemRequest `json:"bundleItems" form:"bundleItems" binding:"omitempty,dive"`
+	Variants    []ProductVariantRequest    `json:"variants"    form:"variants"    binding:"omitempty,dive"`
+	PriceRules  []ProductPriceRuleRequest  `json:"priceRules"  form:"priceRules"  binding:"omitempty,dive"`
 }
 
+// GetProducts lists the products. Users restricted to categories only see the products of their
+// categories.
 func (handler *Handler) GetProducts(c *gin.Context) {
+	executingUserObj, err := handler.getUserFromContext(c)
+	if err != nil {
+		_ = c.Error(UnableToRetrieveExecutingUser.WithCause(err))
+
+		return
+	}
+
 	start, _ := strconv.Atoi(c.DefaultQuery("_start", "0"))
 	end, _ := strconv.Atoi(c.DefaultQuery("_end", "10"))
 	sort := c.DefaultQuery("_sort", "pos")
 	order := c.DefaultQuery("_order", "ASC")
 	filterHidden := c.DefaultQuery("_filter_hidden", "false")
-	ids := queryArrayInt(c, "id")
 
-	products, err := handler.repo.GetProducts(end-start, start, sort, order, ids)
+	filters := sqliteRepo.ProductFilters{}
+	filters.IDs = queryArrayInt(c, "id")
+	filters.CategoryIDs = queryArrayInt(c, "categoryId")
+	filters.AllowedCategoryIDs = executingUserObj.CategoryIDs()
+
+	products, err := handler.repo.GetProducts(end-start, start, sort, order, filters)
 	if err != nil {
 		_ = c.Error(InternalServerError.WithCauseMsg(err))
 
@@ -51,7 +111,7 @@ func (handler *Handler) GetProducts(c *gin.Context) {
 		products = filterHiddenProducts(products)
 	}
 
-	total, err := handler.repo.GetTotalProducts()
+	total, err := handler.repo.GetTotalProducts(filters)
 	if err != nil {
 		_ = c.Error(InternalServerError.WithCauseMsg(err))
 
@@ -91,6 +151,7 @@ func createExtendedProductResponse(
 	decimalPlaces int32,
 ) []response.ExtendedProductResponse {
 	productsResponse := make([]response.ExtendedProductResponse, 0, len(products))
+	now := time.Now()
 
 	for _, product := range products {
 		unitsSold, _ := repo.GetPurchasedQuantitiesByProductID(product.ID)
@@ -98,8 +159,10 @@ func createExtendedProductResponse(
 
 		productResponse := response.ToExtendedProductResponse(
 			product,
+			now,
 			unitsSold,
 			soldOutRequestCount,
+			createProductVariantResponses(repo, product.PriceAt(now), decimalPlaces),
 			decimalPlaces,
 		)
 
@@ -109,7 +172,61 @@ func createExtendedProductResponse(
 	return productsResponse
 }
 
+func createProductVariantResponses(
+	repo sqliteRepo.RepositoryInterface,
+	product models.Product,
+	decimalPlaces int32,
+) []response.ProductVariantResponse {
+	variants := make([]response.ProductVariantResponse, 0, len(product.Variants))
+
+	for _, variant := range product.Variants {
+		unitsSold, _ := repo.GetPurchasedQuantitiesByVariantID(variant.ID)
+		soldOutRequestCount, _ := repo.GetProductInterestCountByVariantID(variant.ID)
+
+		variantResponse := response.ToProductVariantResponse(
+			product,
+			variant,
+			unitsSold,
+			soldOutRequestCount,
+			decimalPlaces,
+		)
+		variantResponse.StockOnHand, _ = repo.GetStockOnHand(product.ID, &variant.ID)
+
+		variants = append(variants, variantResponse)
+	}
+
+	return variants
+}
+
+// stockOnHand returns the stock on hand of the product, the sum of its variants for a product with
+// variants.
+func stockOnHand(
+	repo sqliteRepo.RepositoryInterface,
+	product models.Product,
+	variants []response.ProductVariantResponse,
+) int {
+	if !product.HasVariants() {
+		onHand, _ := repo.GetStockOnHand(product.ID, nil)
+
+		return onHand
+	}
+
+	onHand := 0
+	for _, variant := range variants {
+		onHand += variant.StockOnHand
+	}
+
+	return onHand
+}
+
 func (handler *Handler) GetProductByID(c *gin.Context) {
+	executingUserObj, err := handler.getUserFromContext(c)
+	if err != nil {
+		_ = c.Error(UnableToRetrieveExecutingUser.WithCause(err))
+
+		return
+	}
+
 	id, _ := strconv.Atoi(c.Param("id"))
 
 	product, err := handler.repo.GetProductByID(id)
@@ -119,15 +236,27 @@ func (handler *Handler) GetProductByID(c *gin.Context) {
 		return
 	}
 
+	// a user restricted to categories only sees the products of its categories
+	if allowed := executingUserObj.CategoryIDs(); len(allowed) > 0 &&
+		(product.CategoryID == nil || !slices.Contains(allowed, *product.CategoryID)) {
+		_ = c.Error(Forbidden)
+
+		return
+	}
+
 	unitsSold, _ := handler.repo.GetPurchasedQuantitiesByProductID(product.ID)
 	soldOutRequestCount, _ := handler.repo.GetProductInterestCountByProductID(product.ID)
 
+	now := time.Now()
 	productResponse := response.ToExtendedProductResponse(
 		*product,
+		now,
 		unitsSold,
 		soldOutRequestCount,
+		createProductVariantResponses(handler.repo, product.PriceAt(now), handler.decimalPlaces),
 		handler.decimalPlaces,
 	)
+	productResponse.StockOnHand = stockOnHand(handler.repo, *product, productResponse.Variants)
 
 	c.JSON(http.StatusOK, productResponse)
 }
@@ -156,6 +285,8 @@ func (handler *Handler) UpdateProductByID(c *gin.Context) {
 		return
 	}
 
+	previous := *product
+
 	product.Name = productRequest.Name
 	product.NetPrice = productRequest.NetPrice
 	product.VATRate = productRequest.VATRate
@@ -166,14 +297,35 @@ func (handler *Handler) UpdateProductByID(c *gin.Context) {
 	product.UpdatedByID = &executingUserObj.ID
 	product.SoldOut = productRequest.SoldOut
 	product.TotalStock = productRequest.TotalStock
+	product.TrackStock = productRequest.TrackStock
+	product.CategoryID = productRequest.CategoryID
+
+	if !handler.validateCategory(c, product.CategoryID) ||
+		!handler.applyBundleItems(c, product, productRequest.BundleItems, productRequest.GrossPrice) ||
+		!applyVariants(c, product, productRequest.Variants) ||
+		!applyPriceRules(c, product, productRequest.PriceRules) {
+		return
+	}
 
-	product, err = handler.repo.UpdateProductByID(id, *product)
+	handler.applyStockSoldOut(product, previous)
+
+	// the stock changed in the form is recorded in the inventory ledger together with the product
+	err = handler.repo.WithTransaction(c.Request.Context(), func(txRepo sqliteRepo.RepositoryInterface) error {
+		product, err = txRepo.UpdateProductByID(id, *product)
+		if err != nil {
+			return err
+		}
+
+		return recordFormStockMovements(txRepo, previous, *product, executingUserObj.ID)
+	})
 	if err != nil {
 		_ = c.Error(InternalServerError.WithCause(err))
 
 		return
 	}
 
+	handler.recordPriceChanges(c, previous, *product)
+
 	c.JSON(http.StatusOK, product)
 }
 
@@ -200,9 +352,26 @@ func (handler *Handler) CreateProduct(c *gin.Context) {
 	product.WrapAfter = productRequest.WrapAfter
 	product.Pos = productRequest.Pos
 	product.Hidden = productRequest.Hidden
+	product.CategoryID = productRequest.CategoryID
 	product.CreatedByID = &executingUserObj.ID
 
-	product, err = handler.repo.CreateProduct(product)
+	if !handler.validateCategory(c, product.CategoryID) ||
+		!handler.applyBundleItems(c, &product, productRequest.BundleItems, productRequest.GrossPrice) ||
+		!applyVariants(c, &product, productRequest.Variants) ||
+		!applyPriceRules(c, &product, productRequest.PriceRules) {
+		return
+	}
+
+	handler.applyStockSoldOut(&product, models.Product{})
+
+	err = handler.repo.WithTransaction(c.Request.Context(), func(txRepo sqliteRepo.RepositoryInterface) error {
+		product, err = txRepo.CreateProduct(product)
+		if err != nil {
+			return err
+		}
+
+		return recordFormStockMovements(txRepo, models.Product{}, product, executingUserObj.ID)
+	})
 	if err != nil {
 		_ = c.Error(InternalServerError.WithCause(err))
 
@@ -239,3 +408,172 @@ func (handler *Handler) DeleteProductByID(c *gin.Context) {
 
 	c.Status(http.StatusNoContent)
 }
+
+// applyStockSoldOut flips the sold-out flag of the product and its variants with tracked stock when
+// a change of their stock sells them out or restocks them. Tracked items without stock are sold out.
+// The stock on hand is taken from the inventory ledger, which the changed stock is recorded in.
+func (handler *Handler) applyStockSoldOut(product *models.Product, previous models.Product) {
+	if product.TracksStock() {
+		onHand, _ := handler.repo.GetStockOnHand(product.ID, nil)
+		product.SoldOut = models.SoldOutForStock(
+			product.SoldOut,
+			onHand,
+			onHand+product.TotalStock-previous.TotalStock,
+		)
+	}
+
+	for i, variant := range product.Variants {
+		if !variant.TracksStock() {
+			continue
+		}
+
+		// a new variant has no stock on hand yet
+		previousVariant, _ := previous.Variant(variant.ID)
+		onHand := 0
+
+		if variant.ID != 0 {
+			onHand, _ = handler.repo.GetStockOnHand(product.ID, &variant.ID)
+		}
+
+		product.Variants[i].SoldOut = models.SoldOutForStock(
+			variant.SoldOut,
+			onHand,
+			onHand+variant.TotalStock-previousVariant.TotalStock,
+		)
+	}
+}
+
+// recordPriceChanges keeps the history of the prices, purchases recorded offline are checked against it.
+func (handler *Handler) recordPriceChanges(c *gin.Context, previous, product models.Product) {
+	if err := handler.repo.StoreProductPriceChanges(product.PriceChanges(previous)); err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error recording the price changes", "error", err)
+	}
+}
+
+func (handler *Handler) validateCategory(c *gin.Context, categoryID *int) bool {
+	if categoryID == nil {
+		return true
+	}
+
+	if _, err := handler.repo.GetCategoryByID(*categoryID); err != nil {
+		_ = c.Error(InvalidRequest.WithMsg("Category not found").WithCause(err))
+
+		return false
+	}
+
+	return true
+}
+
+// applyBundleItems makes the product a bundle of the requested components, or a regular product
+// when no components are requested.
+func (handler *Handler) applyBundleItems(
+	c *gin.Context,
+	product *models.Product,
+	requests []ProductBundleItemRequest,
+	grossPrice decimal.Decimal,
+) bool {
+	if len(requests) == 0 {
+		product.BundleItems = nil
+
+		return true
+	}
+
+	items := make([]models.ProductBundleItem, 0, len(requests))
+
+	for _, req := range requests {
+		component, err := handler.repo.GetProductByID(req.ProductID)
+		if err != nil {
+			_ = c.Error(InvalidRequest.WithMsg("Bundle component not found").WithCause(err))
+
+			return false
+		}
+
+		items = append(items, models.ProductBundleItem{
+			ComponentID: component.ID,
+			Component:   *component,
+			Quantity:    req.Quantity,
+		})
+	}
+
+	if err := product.SetBundleItems(items, grossPrice, handler.decimalPlaces); err != nil {
+		_ = c.Error(InvalidRequest.WithMsg(utils.CapitalizeFirstRune(err.Error())).WithCause(err))
+
+		return false
+	}
+
+	return true
+}
+
+// applyVariants sets the variants of the product. Variants keep their ID, so only variants of the
+// product can be updated.
+func applyVariants(c *gin.Context, product *models.Product, requests []ProductVariantRequest) bool {
+	if len(requests) > 0 && product.IsBundle() {
+		_ = c.Error(InvalidRequest.WithMsg("A bundle cannot have variants"))
+
+		return false
+	}
+
+	variants := make([]models.ProductVariant, 0, len(requests))
+
+	for _, req := range requests {
+		variant := models.ProductVariant{
+			Name:       req.Name,
+			NetPrice:   req.NetPrice,
+			Pos:        req.Pos,
+			TotalStock: req.TotalStock,
+			TrackStock: req.TrackStock,
+			SoldOut:    req.SoldOut,
+		}
+
+		if req.ID != 0 {
+			if _, ok := product.Variant(req.ID); !ok {
+				_ = c.Error(InvalidRequest.WithMsg("Variant not found"))
+
+				return false
+			}
+
+			variant.ID = req.ID
+		}
+
+		variants = append(variants, variant)
+	}
+
+	product.Variants = variants
+
+	return true
+}
+
+// applyPriceRules sets the scheduled prices of the product.
+func applyPriceRules(c *gin.Context, product *models.Product, requests []ProductPriceRuleRequest) bool {
+	if len(requests) > 0 && product.IsBundle() {
+		_ = c.Error(InvalidRequest.WithMsg("A bundle cannot have price rules"))
+
+		return false
+	}
+
+	rules := make([]models.ProductPriceRule, 0, len(requests))
+
+	for _, req := range requests {
+		rule := models.ProductPriceRule{
+			NetPrice:   req.NetPrice,
+			ValidFrom:  req.ValidFrom,
+			ValidUntil: req.ValidUntil,
+			Weekdays:   req.Weekdays,
+			FromHour:   req.FromHour,
+			UntilHour:  req.UntilHour,
+			Pos:        req.Pos,
+		}
+
+		if err := rule.Validate(); err != nil {
+			_ = c.Error(InvalidRequest.WithMsg(utils.CapitalizeFirstRune(err.Error())).WithCause(err))
+
+			return false
+		}
+
+		rules = append(rules, rule)
+	}
+
+	product.PriceRules = rules
+
+	return true
+}
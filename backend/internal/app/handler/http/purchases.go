@@ -1,6 +1,8 @@
 package http
 
 import (
+	"errors"
+	"io"
 	"log/slog"
 	"net/http"
 	"strconv"
@@ -13,6 +15,7 @@ import (
 	response "github.com/potibm/kasseapparat/internal/app/response"
 	purchaseService "github.com/potibm/kasseapparat/internal/app/service/purchase"
 	"github.com/potibm/kasseapparat/internal/app/utils"
+	"gorm.io/gorm"
 )
 
 const invalidPurchaseIDMsg = "Invalid purchase ID"
@@ -32,14 +35,36 @@ func (handler *Handler) DeletePurchase(c *gin.Context) {
 		return
 	}
 
-	handler.repo.DeletePurchaseByID(id, *executingUserObj)
+	err = handler.purchaseService.DeletePurchase(c.Request.Context(), id, *executingUserObj)
 
-	_ = handler.repo.RollbackVisitedGuestsByPurchaseID(id)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		_ = c.Error(NotFound.WithCause(err))
+
+		return
+	case err != nil:
+		_ = c.Error(InternalServerError.WithCauseMsg(err))
+
+		return
+	}
 
 	c.Status(http.StatusNoContent)
 }
 
 func (handler *Handler) RefundPurchase(c *gin.Context) {
+	executingUserObj, id, ok := handler.authorizeRefund(c)
+	if !ok {
+		return
+	}
+
+	purchase, err := handler.purchaseService.RefundPurchase(c.Request.Context(), id, executingUserObj.ID)
+
+	handler.respondRefund(c, purchase, err)
+}
+
+// CancelPurchaseCheckout cancels the card payment of a pending purchase on its terminal. If the
+// payment succeeded meanwhile, the purchase is confirmed and the cancel is rejected.
+func (handler *Handler) CancelPurchaseCheckout(c *gin.Context) {
 	executingUserObj, err := handler.getUserFromContext(c)
 	if err != nil {
 		_ = c.Error(UnableToRetrieveExecutingUser.WithCause(err))
@@ -54,36 +79,195 @@ func (handler *Handler) RefundPurchase(c *gin.Context) {
 		return
 	}
 
-	purchase, err := handler.repo.GetPurchaseByID(id)
+	var req struct {
+		SumupReaderID string `json:"sumupReaderId"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		_ = c.Error(InvalidRequest.WithCauseMsg(err))
+
+		return
+	}
+
+	ctx := purchaseService.WithTransitionOrigin(
+		c.Request.Context(),
+		models.PurchaseTransitionSourceUser,
+		&executingUserObj.ID,
+	)
+
+	purchase, err := handler.purchaseService.CancelTerminalCheckout(ctx, id, req.SumupReaderID)
+	if purchase != nil && purchase.Status != models.PurchaseStatusPending {
+		handler.monitor.Stop(id)
+		handler.statusPublisher.PushUpdate(id, purchase.Status)
+	}
+
+	switch {
+	case errors.Is(err, purchaseService.ErrPurchaseNotPending),
+		errors.Is(err, purchaseService.ErrPaymentAlreadySucceeded):
+		_ = c.Error(Conflict.WithMsg(utils.CapitalizeFirstRune(err.Error())).WithCause(err))
+
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		_ = c.Error(NotFound.WithCause(err))
+
+		return
+	case err != nil:
+		_ = c.Error(InternalServerError.WithCauseMsg(err))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, response.ToPurchaseResponse(*purchase, handler.decimalPlaces))
+}
+
+func (handler *Handler) RefundPurchaseItems(c *gin.Context) {
+	var req PurchaseRefundRequest
+	if err := c.ShouldBind(&req); err != nil {
+		_ = c.Error(InvalidRequest.WithCauseMsg(err))
+
+		return
+	}
+
+	executingUserObj, id, ok := handler.authorizeRefund(c)
+	if !ok {
+		return
+	}
+
+	purchase, err := handler.purchaseService.RefundPurchaseItems(
+		c.Request.Context(),
+		id,
+		req.ToInput(),
+		executingUserObj.ID,
+	)
+
+	handler.respondRefund(c, purchase, err)
+}
+
+// respondRefund answers a refund request with the refunded purchase. A refund that has been
+// recorded, but whose card has not been refunded yet, is accepted, so its idempotency key is kept
+// and the card refund can be retried by an admin without recording the refund twice.
+func (handler *Handler) respondRefund(c *gin.Context, purchase *models.Purchase, err error) {
+	status := http.StatusOK
+
+	switch {
+	case errors.Is(err, purchaseService.ErrRefundNotSettled) && purchase != nil:
+		status = http.StatusAccepted
+	case err != nil:
+		_ = c.Error(mapRefundError(err))
+
+		return
+	}
+
+	c.JSON(status, response.ToPurchaseResponse(*purchase, handler.decimalPlaces))
+}
+
+// SettlePurchaseRefund retries refunding the card share of an unsettled refund of the purchase.
+// Only admins may settle refunds.
+func (handler *Handler) SettlePurchaseRefund(c *gin.Context) {
+	executingUserObj, err := handler.getUserFromContext(c)
 	if err != nil {
-		_ = c.Error(NotFound.WithMsg("Purchase not found").WithCause(err))
+		_ = c.Error(UnableToRetrieveExecutingUser.WithCause(err))
 
 		return
 	}
 
-	isCreator := purchase.CreatedByID != nil && *purchase.CreatedByID == executingUserObj.ID
-	if !executingUserObj.Admin && !isCreator {
-		_ = c.Error(Forbidden.WithMsg("You are not allowed to refund this purchase"))
+	if !executingUserObj.Admin {
+		_ = c.Error(Forbidden)
 
 		return
 	}
 
-	if !executingUserObj.Admin && time.Since(purchase.CreatedAt) > 15*time.Minute {
-		_ = c.Error(Forbidden.WithMsg("You can only refund purchases within 15 minutes of creation"))
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		_ = c.Error(InvalidRequest.WithMsg(invalidPurchaseIDMsg).WithCause(err))
 
 		return
 	}
 
-	purchase, err = handler.purchaseService.RefundPurchase(c.Request.Context(), id)
+	refundID, err := strconv.Atoi(c.Param("refundId"))
 	if err != nil {
+		_ = c.Error(InvalidRequest.WithMsg("Invalid refund ID").WithCause(err))
+
+		return
+	}
+
+	refund, err := handler.repo.GetPurchaseRefundByID(refundID)
+	if err != nil || refund.PurchaseID != id {
+		_ = c.Error(NotFound.WithMsg("Refund not found").WithCause(err))
+
+		return
+	}
+
+	refund, err = handler.purchaseService.SettleRefund(c.Request.Context(), refundID)
+
+	switch {
+	case errors.Is(err, purchaseService.ErrRefundAlreadySettled),
+		errors.Is(err, purchaseService.ErrRefundBeingSettled):
+		_ = c.Error(Conflict.WithMsg(utils.CapitalizeFirstRune(err.Error())).WithCause(err))
+
+		return
+	case err != nil:
 		_ = c.Error(InternalServerError.WithCauseMsg(err))
 
 		return
 	}
 
-	purchaseResponse := response.ToPurchaseResponse(*purchase, handler.decimalPlaces)
+	c.JSON(http.StatusOK, response.ToPurchaseRefundResponse(*refund))
+}
 
-	c.JSON(http.StatusOK, purchaseResponse)
+// authorizeRefund checks whether the executing user may refund the purchase in the request path.
+// Only admins and the creator of the purchase may refund it, the creator only within 15 minutes.
+func (handler *Handler) authorizeRefund(c *gin.Context) (*models.User, uuid.UUID, bool) {
+	executingUserObj, err := handler.getUserFromContext(c)
+	if err != nil {
+		_ = c.Error(UnableToRetrieveExecutingUser.WithCause(err))
+
+		return nil, uuid.Nil, false
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		_ = c.Error(InvalidRequest.WithMsg(invalidPurchaseIDMsg).WithCause(err))
+
+		return nil, uuid.Nil, false
+	}
+
+	purchase, err := handler.repo.GetPurchaseByID(id)
+	if err != nil {
+		_ = c.Error(NotFound.WithMsg("Purchase not found").WithCause(err))
+
+		return nil, uuid.Nil, false
+	}
+
+	isCreator := purchase.CreatedByID != nil && *purchase.CreatedByID == executingUserObj.ID
+	if !executingUserObj.Admin && !isCreator {
+		_ = c.Error(Forbidden.WithMsg("You are not allowed to refund this purchase"))
+
+		return nil, uuid.Nil, false
+	}
+
+	if !executingUserObj.Admin && time.Since(purchase.CreatedAt) > 15*time.Minute {
+		_ = c.Error(Forbidden.WithMsg("You can only refund purchases within 15 minutes of creation"))
+
+		return nil, uuid.Nil, false
+	}
+
+	return executingUserObj, id, true
+}
+
+func mapRefundError(err error) error {
+	switch {
+	case errors.Is(err, purchaseService.ErrRefundItemNotFound),
+		errors.Is(err, purchaseService.ErrInvalidRefundQuantity),
+		errors.Is(err, purchaseService.ErrRefundQuantityExceedsItem),
+		errors.Is(err, purchaseService.ErrEmptyRefund):
+		return InvalidRequest.WithMsg(utils.CapitalizeFirstRune(err.Error())).WithCause(err)
+	case errors.Is(err, purchaseService.ErrNoOpenRegisterSession),
+		errors.Is(err, purchaseService.ErrPurchaseNotRefundable):
+		return Conflict.WithMsg(utils.CapitalizeFirstRune(err.Error())).WithCause(err)
+	default:
+		return InternalServerError.WithCauseMsg(err)
+	}
 }
 
 func (handler *Handler) PostPurchases(c *gin.Context) {
@@ -101,7 +285,12 @@ func (handler *Handler) PostPurchases(c *gin.Context) {
 		return
 	}
 
-	err = handler.ValidatePaymentMethodPayload(req.PaymentMethod, req.SumupReaderID)
+	input, ok := handler.applyQuote(c, req, req.ToInput(), executingUserObj.ID)
+	if !ok {
+		return
+	}
+
+	err = handler.ValidatePaymentLinesPayload(input, req.SumupReaderID)
 	if err != nil {
 		_ = c.Error(InvalidRequest.WithCauseMsg(err))
 
@@ -114,12 +303,21 @@ func (handler *Handler) PostPurchases(c *gin.Context) {
 		return
 	}
 
-	input := req.ToInput()
+	// cash sales require an open register session of the cashier
+	if input.HasPaymentMethod(models.PaymentMethodCash) {
+		if _, err := handler.repo.GetOpenRegisterSessionByUserID(executingUserObj.ID); err != nil {
+			_ = c.Error(Conflict.WithMsg(noOpenRegisterSessionMsg).WithCause(err))
+
+			return
+		}
+	}
 
 	// create a variable purchase that is a nil pointer to models.Purchase
 	var purchase *models.Purchase
 
-	if req.PaymentMethod == models.PaymentMethodSumUp {
+	isSumupPurchase := input.PrimaryPaymentMethod() == models.PaymentMethodSumUp
+
+	if isSumupPurchase {
 		purchase, err = handler.purchaseService.CreatePendingPurchase(
 			c.Request.Context(),
 			input,
@@ -133,6 +331,12 @@ func (handler *Handler) PostPurchases(c *gin.Context) {
 		)
 	}
 
+	if err != nil && req.QuoteToken != "" && isPriceMismatch(err) {
+		_ = c.Error(Conflict.WithMsg(quoteOutdatedMsg).WithCause(err))
+
+		return
+	}
+
 	if err != nil {
 		_ = c.Error(mapPurchaseCreationError(err))
 
@@ -144,9 +348,9 @@ func (handler *Handler) PostPurchases(c *gin.Context) {
 		reloadedPurchase = purchase // Fallback to the created purchase if reloading fails
 	}
 
-	if req.PaymentMethod == models.PaymentMethodSumUp {
-		if sumupErr := handler.processSumupCheckout(c, reloadedPurchase, req.SumupReaderID); sumupErr != nil {
-			_ = c.Error(sumupErr)
+	if isSumupPurchase {
+		if checkoutErr := handler.processTerminalCheckout(c, reloadedPurchase, req.SumupReaderID); checkoutErr != nil {
+			_ = c.Error(checkoutErr)
 
 			return
 		}
@@ -170,6 +374,9 @@ func (handler *Handler) GetPurchases(c *gin.Context) {
 	filters.TotalGrossPriceLte = queryDecimal(c, "totalGrossPrice_lte")
 	filters.IDs = queryArrayInt(c, "id")
 	filters.StatusList = queryPurchaseStatusList(c, "status")
+	filters.RegisterSessionID, _ = strconv.Atoi(c.DefaultQuery("registerSessionId", "0"))
+	filters.Offline = queryBool(c, "offline")
+	filters.Expired = queryBool(c, "expired")
 
 	purchases, err := handler.repo.GetPurchases(end-start, start, sort, order, filters)
 	if err != nil {
@@ -225,11 +432,22 @@ func (handler *Handler) GetPurchaseStats(c *gin.Context) {
 		totalQuantity += stat.Quantity
 	}
 
+	paymentStats, err := handler.repo.GetPaymentMethodStats()
+	if err != nil {
+		_ = c.Error(InternalServerError.WithCauseMsg(err))
+
+		return
+	}
+
 	c.Header("Access-Control-Allow-Origin", "*")
-	c.JSON(http.StatusOK, gin.H{"stats": stats, "totalQuantity": totalQuantity})
+	c.JSON(http.StatusOK, gin.H{"stats": stats, "totalQuantity": totalQuantity, "payments": paymentStats})
 }
 
 func mapPurchaseCreationError(err error) error {
+	if errors.Is(err, purchaseService.ErrStockExhausted) {
+		return Conflict.WithMsg(utils.CapitalizeFirstRune(err.Error())).WithCause(err)
+	}
+
 	switch err {
 	case purchaseService.ErrInvalidProductPrice,
 		purchaseService.ErrInvalidTotalGrossPrice,
@@ -238,23 +456,44 @@ func mapPurchaseCreationError(err error) error {
 		purchaseService.ErrGuestNotFound,
 		purchaseService.ErrGuestAlreadyAttended,
 		purchaseService.ErrTooManyAdditionalGuests,
-		purchaseService.ErrListItemWrongProduct:
+		purchaseService.ErrListItemWrongProduct,
+		purchaseService.ErrInvalidPaymentAmount,
+		purchaseService.ErrInvalidPaymentTotal,
+		purchaseService.ErrMultipleSumupPayments,
+		purchaseService.ErrUnsettledPayment,
+		purchaseService.ErrVoucherCodeRequired,
+		sqliteRepo.ErrVoucherNotFound,
+		models.ErrVoucherExpired,
+		models.ErrVoucherInsufficientBalance,
+		purchaseService.ErrDiscountNotApplicable,
+		purchaseService.ErrDuplicateDiscount,
+		purchaseService.ErrVariantRequired,
+		purchaseService.ErrVariantNotFound,
+		sqliteRepo.ErrDiscountNotFound:
 		return InvalidRequest.WithMsg(utils.CapitalizeFirstRune(err.Error())).WithCause(err)
+	case purchaseService.ErrDiscountNotPermitted,
+		purchaseService.ErrProductNotAllowed:
+		return Forbidden.WithMsg(utils.CapitalizeFirstRune(err.Error())).WithCause(err)
+	case purchaseService.ErrNoOpenRegisterSession:
+		return Conflict.WithMsg(noOpenRegisterSessionMsg).WithCause(err)
+	case sqliteRepo.ErrVoucherBalanceChanged:
+		return Conflict.WithMsg(utils.CapitalizeFirstRune(err.Error())).WithCause(err)
 	default:
 		return InternalServerError.WithCauseMsg(err)
 	}
 }
 
-func (handler *Handler) processSumupCheckout(c *gin.Context, purchase *models.Purchase, readerID string) error {
-	clientTransactionID, err := handler.sumupRepository.CreateReaderCheckout(
-		readerID,
-		purchase.TotalGrossPrice,
+// processTerminalCheckout starts the checkout of the card payment on the terminal of the configured
+// payment terminal provider and monitors it until the purchase is confirmed or failed.
+func (handler *Handler) processTerminalCheckout(c *gin.Context, purchase *models.Purchase, terminalID string) error {
+	clientTransactionID, err := handler.terminalProvider.CreateCheckout(
+		terminalID,
+		purchase.SumupAmount(),
 		"Purchase from Kasseapparat",
 		purchase.ID.String(),
-		handler.sumupRepository.GetWebhookURL(),
 	)
 	if err != nil {
-		slog.ErrorContext(c.Request.Context(), "Error creating SumUp reader checkout", "error", err)
+		slog.ErrorContext(c.Request.Context(), "Error creating terminal checkout", "error", err)
 
 		_, cancelErr := handler.purchaseService.CancelPurchase(c.Request.Context(), purchase.ID)
 		if cancelErr != nil {
@@ -268,7 +507,7 @@ func (handler *Handler) processSumupCheckout(c *gin.Context, purchase *models.Pu
 			)
 		}
 
-		return InternalServerError.WithMsg("Failed to create SumUp reader checkout: " + err.Error()).WithCause(err)
+		return InternalServerError.WithMsg("Failed to create terminal checkout: " + err.Error()).WithCause(err)
 	}
 
 	clientTransactionIDStr := "nil"
@@ -278,7 +517,7 @@ func (handler *Handler) processSumupCheckout(c *gin.Context, purchase *models.Pu
 
 	slog.InfoContext(
 		c.Request.Context(),
-		"Created SumUp reader checkout",
+		"Created terminal checkout",
 		"client_transaction_id",
 		clientTransactionIDStr,
 	)
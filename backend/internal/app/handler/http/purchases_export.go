@@ -3,13 +3,16 @@ package http
 import (
 	"encoding/csv"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/potibm/kasseapparat/internal/app/models"
 	sqliteRepo "github.com/potibm/kasseapparat/internal/app/repository/sqlite"
+	"github.com/shopspring/decimal"
 )
 
 func (handler *Handler) ExportPurchases(c *gin.Context) {
@@ -61,6 +64,8 @@ func (handler *Handler) ExportPurchases(c *gin.Context) {
 			"Purchase Net Price",
 			"Purchase VAT",
 			"Payment Method",
+			"Payment Amount",
+			"Receipt Number",
 		},
 	)
 	if err != nil {
@@ -69,8 +74,22 @@ func (handler *Handler) ExportPurchases(c *gin.Context) {
 		return
 	}
 
+	exportedRoundings := make(map[uuid.UUID]bool)
+
 	for _, p := range purchases {
-		if err := handler.exportSinglePurchase(writer, p); err != nil {
+		if err := handler.exportSinglePurchase(writer, p, filters.PaymentMethods); err != nil {
+			_ = c.Error(InternalServerError.WithMsg("Failed to write CSV: " + err.Error()).WithCause(err))
+
+			return
+		}
+
+		if exportedRoundings[p.PurchaseID] {
+			continue
+		}
+
+		exportedRoundings[p.PurchaseID] = true
+
+		if err := handler.exportRoundings(writer, p.Purchase, filters.PaymentMethods); err != nil {
 			_ = c.Error(InternalServerError.WithMsg("Failed to write CSV: " + err.Error()).WithCause(err))
 
 			return
@@ -78,24 +97,247 @@ func (handler *Handler) ExportPurchases(c *gin.Context) {
 	}
 }
 
-func (handler *Handler) exportSinglePurchase(writer *csv.Writer, p models.PurchaseItem) error {
+// exportRoundings writes a line per rounding line of the purchase and its refunds. The rounding is
+// not part of the prices, so it has no VAT and only shows up in the payment amount.
+func (handler *Handler) exportRoundings(
+	writer *csv.Writer,
+	purchase models.Purchase,
+	paymentMethods []models.PaymentMethod,
+) error {
+	vat := purchase.TotalGrossPrice.Sub(purchase.TotalNetPrice)
+
+	receiptNumber := ""
+	if purchase.ReceiptNumber != nil {
+		receiptNumber = *purchase.ReceiptNumber
+	}
+
+	for _, rounding := range purchase.Roundings {
+		if len(paymentMethods) > 0 && !slices.Contains(paymentMethods, rounding.PaymentMethod) {
+			continue
+		}
+
+		zero := decimal.Zero.StringFixed(handler.decimalPlaces)
+
+		createdAt, name := purchase.CreatedAt, "Rounding"
+		if rounding.IsRefund() {
+			createdAt, name = rounding.CreatedAt, "Refund Rounding"
+		}
+
+		err := writer.Write([]string{
+			createdAt.Format("2006-01-02 15:04:05"),
+			purchase.ID.String(),
+			"1",
+			name,
+			"",
+			zero,
+			zero,
+			zero,
+			zero,
+			zero,
+			zero,
+			purchase.TotalGrossPrice.StringFixed(handler.decimalPlaces),
+			purchase.TotalNetPrice.StringFixed(handler.decimalPlaces),
+			vat.StringFixed(handler.decimalPlaces),
+			string(rounding.PaymentMethod),
+			rounding.Amount.StringFixed(handler.decimalPlaces),
+			receiptNumber,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type exportPaymentLine struct {
+	PaymentMethod models.PaymentMethod
+	Amount        decimal.Decimal
+}
+
+// exportSinglePurchase writes one line per payment line of the purchase. The item totals
+// are split across the payment lines by their share of the purchase's gross price, and the
+// payment amount of a line is the share paid with its payment method, so the payment amounts
+// of a purchase and its roundings add up to the amounts paid. Discounts of the item are written
+// as additional lines with negative amounts, refunds of the item as additional lines with
+// negative quantities and amounts.
+func (handler *Handler) exportSinglePurchase(
+	writer *csv.Writer,
+	p models.PurchaseItem,
+	paymentMethods []models.PaymentMethod,
+) error {
+	payments := p.Purchase.PaymentLines()
+	lines := make([]exportPaymentLine, 0, len(payments))
+
+	for _, payment := range payments {
+		lines = append(lines, exportPaymentLine{PaymentMethod: payment.PaymentMethod, Amount: payment.Amount})
+	}
+
+	err := handler.writeExportLines(writer, p, exportLine{
+		CreatedAt:       p.CreatedAt,
+		Quantity:        p.Quantity,
+		Name:            p.Name(),
+		UnitGrossPrice:  p.GrossPrice(handler.decimalPlaces),
+		UnitNetPrice:    p.NetPrice,
+		UnitVATAmount:   p.VATAmount(handler.decimalPlaces),
+		TotalGrossPrice: p.TotalGrossPrice(handler.decimalPlaces),
+		TotalNetPrice:   p.TotalNetPrice(handler.decimalPlaces),
+		Payments:        lines,
+	}, paymentMethods)
+	if err != nil {
+		return err
+	}
+
+	for _, discount := range p.Discounts {
+		err := handler.writeExportLines(writer, p, exportLine{
+			CreatedAt:       p.CreatedAt,
+			Quantity:        1,
+			Name:            "Discount: " + discount.Name,
+			UnitGrossPrice:  discount.GrossAmount.Neg(),
+			UnitNetPrice:    discount.NetAmount.Neg(),
+			UnitVATAmount:   discount.NetAmount.Sub(discount.GrossAmount),
+			TotalGrossPrice: discount.GrossAmount.Neg(),
+			TotalNetPrice:   discount.NetAmount.Neg(),
+			Payments:        lines,
+		}, paymentMethods)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, refundItem := range p.Refunds {
+		if refundItem.PurchaseRefund == nil {
+			continue
+		}
+
+		err := handler.writeExportLines(writer, p, exportLine{
+			CreatedAt:       refundItem.PurchaseRefund.CreatedAt,
+			Quantity:        refundItem.Quantity,
+			Name:            p.Name(),
+			UnitGrossPrice:  p.GrossPrice(handler.decimalPlaces),
+			UnitNetPrice:    p.NetPrice,
+			UnitVATAmount:   p.VATAmount(handler.decimalPlaces),
+			TotalGrossPrice: refundItem.TotalGrossPrice(handler.decimalPlaces),
+			TotalNetPrice:   refundItem.TotalNetPrice(handler.decimalPlaces),
+			Payments:        refundPaymentLines(*refundItem.PurchaseRefund, p.Purchase),
+			IsRefund:        true,
+		}, paymentMethods)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type exportLine struct {
+	CreatedAt       time.Time
+	Quantity        uint
+	Name            string
+	UnitGrossPrice  decimal.Decimal
+	UnitNetPrice    decimal.Decimal
+	UnitVATAmount   decimal.Decimal
+	TotalGrossPrice decimal.Decimal
+	TotalNetPrice   decimal.Decimal
+	Payments        []exportPaymentLine
+	IsRefund        bool
+}
+
+func (handler *Handler) writeExportLines(
+	writer *csv.Writer,
+	p models.PurchaseItem,
+	line exportLine,
+	paymentMethods []models.PaymentMethod,
+) error {
 	vat := p.Purchase.TotalGrossPrice.Sub(p.Purchase.TotalNetPrice)
 
-	return writer.Write([]string{
-		p.CreatedAt.Format("2006-01-02 15:04:05"),
-		p.Purchase.ID.String(),
-		strconv.FormatUint(uint64(p.Quantity), 10),
-		p.Product.Name,
-		p.VATRate.String() + "%",
-		p.GrossPrice(handler.decimalPlaces).StringFixed(handler.decimalPlaces),
-		p.NetPrice.StringFixed(handler.decimalPlaces),
-		p.VATAmount(handler.decimalPlaces).StringFixed(handler.decimalPlaces),
-		p.TotalGrossPrice(handler.decimalPlaces).StringFixed(handler.decimalPlaces),
-		p.TotalNetPrice(handler.decimalPlaces).StringFixed(handler.decimalPlaces),
-		p.TotalVATAmount(handler.decimalPlaces).StringFixed(handler.decimalPlaces),
-		p.Purchase.TotalGrossPrice.StringFixed(handler.decimalPlaces),
-		p.Purchase.TotalNetPrice.StringFixed(handler.decimalPlaces),
-		vat.StringFixed(handler.decimalPlaces),
-		string(p.Purchase.PaymentMethod),
-	})
+	grossShares := allocateToPayments(line.TotalGrossPrice, line.Payments, handler.decimalPlaces)
+	netShares := allocateToPayments(line.TotalNetPrice, line.Payments, handler.decimalPlaces)
+
+	sign := decimal.NewFromInt(1)
+	quantity := strconv.FormatUint(uint64(line.Quantity), 10)
+
+	receiptNumber := ""
+	if p.Purchase.ReceiptNumber != nil {
+		receiptNumber = *p.Purchase.ReceiptNumber
+	}
+
+	if line.IsRefund {
+		sign = decimal.NewFromInt(-1)
+		quantity = "-" + quantity
+	}
+
+	for i, payment := range line.Payments {
+		if len(paymentMethods) > 0 && !slices.Contains(paymentMethods, payment.PaymentMethod) {
+			continue
+		}
+
+		err := writer.Write([]string{
+			line.CreatedAt.Format("2006-01-02 15:04:05"),
+			p.Purchase.ID.String(),
+			quantity,
+			line.Name,
+			p.VATRate.String() + "%",
+			line.UnitGrossPrice.StringFixed(handler.decimalPlaces),
+			line.UnitNetPrice.StringFixed(handler.decimalPlaces),
+			line.UnitVATAmount.StringFixed(handler.decimalPlaces),
+			grossShares[i].Mul(sign).StringFixed(handler.decimalPlaces),
+			netShares[i].Mul(sign).StringFixed(handler.decimalPlaces),
+			grossShares[i].Sub(netShares[i]).Mul(sign).StringFixed(handler.decimalPlaces),
+			p.Purchase.TotalGrossPrice.StringFixed(handler.decimalPlaces),
+			p.Purchase.TotalNetPrice.StringFixed(handler.decimalPlaces),
+			vat.StringFixed(handler.decimalPlaces),
+			string(payment.PaymentMethod),
+			grossShares[i].Mul(sign).StringFixed(handler.decimalPlaces),
+			receiptNumber,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// refundPaymentLines returns the payment lines of a refund. Refunds without any amount paid
+// back (e.g. of free products) are attributed to the purchase's payment method.
+func refundPaymentLines(refund models.PurchaseRefund, purchase models.Purchase) []exportPaymentLine {
+	if len(refund.Payments) == 0 {
+		return []exportPaymentLine{{PaymentMethod: purchase.PaymentMethod, Amount: decimal.Zero}}
+	}
+
+	lines := make([]exportPaymentLine, 0, len(refund.Payments))
+	for _, payment := range refund.Payments {
+		lines = append(lines, exportPaymentLine{PaymentMethod: payment.PaymentMethod, Amount: payment.Amount})
+	}
+
+	return lines
+}
+
+// allocateToPayments splits an amount across the payment lines by their share of the
+// lines' total. The last line absorbs rounding differences.
+func allocateToPayments(amount decimal.Decimal, payments []exportPaymentLine, decimalPlaces int32) []decimal.Decimal {
+	total := decimal.Zero
+	for _, payment := range payments {
+		total = total.Add(payment.Amount)
+	}
+
+	shares := make([]decimal.Decimal, len(payments))
+	remaining := amount
+
+	for i, payment := range payments {
+		if i == len(payments)-1 {
+			shares[i] = remaining
+
+			break
+		}
+
+		if !total.IsZero() {
+			shares[i] = amount.Mul(payment.Amount.Div(total)).Round(decimalPlaces)
+		}
+
+		remaining = remaining.Sub(shares[i])
+	}
+
+	return shares
 }
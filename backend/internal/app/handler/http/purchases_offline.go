@@ -0,0 +1,121 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	response "github.com/potibm/kasseapparat/internal/app/response"
+	purchaseService "github.com/potibm/kasseapparat/internal/app/service/purchase"
+	"github.com/potibm/kasseapparat/internal/app/utils"
+)
+
+// OfflinePurchaseRequest is a purchase recorded by a client while it was offline, with the ID the
+// client gave it and the time it was recorded at.
+type OfflinePurchaseRequest struct {
+	PurchaseRequest
+
+	ID         uuid.UUID `form:"id"         binding:"required"`
+	RecordedAt time.Time `form:"recordedAt" binding:"required"`
+}
+
+type OfflinePurchaseSyncRequest struct {
+	Purchases []OfflinePurchaseRequest `form:"purchases" binding:"required,min=1,max=100,dive"`
+}
+
+// SyncOfflinePurchases stores the purchases a client recorded while it was offline and reports the
+// outcome of each one. Syncing the same purchases again is safe, they are reported as duplicates.
+func (handler *Handler) SyncOfflinePurchases(c *gin.Context) {
+	executingUserObj, err := handler.getUserFromContext(c)
+	if err != nil {
+		_ = c.Error(UnableToRetrieveExecutingUser.WithCause(err))
+
+		return
+	}
+
+	var req OfflinePurchaseSyncRequest
+	if err := c.ShouldBind(&req); err != nil {
+		_ = c.Error(InvalidRequest.WithCauseMsg(err))
+
+		return
+	}
+
+	// a sync that is too old could sell at prices retired since, it is rejected as a whole
+	if maxAge := handler.config.Purchases.OfflineMaxAge(); maxAge > 0 {
+		for _, purchaseReq := range req.Purchases {
+			if time.Since(purchaseReq.RecordedAt) > maxAge {
+				_ = c.Error(UnprocessableEntity.WithMsg(
+					utils.CapitalizeFirstRune(purchaseService.ErrRecordedTooLongAgo.Error()),
+				))
+
+				return
+			}
+		}
+	}
+
+	results := make([]response.OfflinePurchaseResult, len(req.Purchases))
+	inputs := make([]purchaseService.OfflinePurchaseInput, 0, len(req.Purchases))
+	positions := make([]int, 0, len(req.Purchases))
+
+	for i, purchaseReq := range req.Purchases {
+		input := purchaseReq.ToInput()
+
+		err := purchaseReq.Validate()
+		if err == nil {
+			err = handler.ValidatePaymentLinesPayload(input, purchaseReq.SumupReaderID)
+		}
+
+		if err != nil {
+			results[i] = offlinePurchaseResult(purchaseService.OfflinePurchaseResult{
+				ID:     purchaseReq.ID,
+				Status: purchaseService.OfflinePurchaseConflict,
+				Err:    err,
+			}, handler.decimalPlaces)
+
+			continue
+		}
+
+		inputs = append(inputs, purchaseService.OfflinePurchaseInput{
+			PurchaseInput: input,
+			ID:            purchaseReq.ID,
+			RecordedAt:    purchaseReq.RecordedAt,
+		})
+		positions = append(positions, i)
+	}
+
+	synced := handler.purchaseService.SyncOfflinePurchases(c.Request.Context(), inputs, executingUserObj.ID)
+	for i, result := range synced {
+		if result.Purchase != nil {
+			if reloaded, err := handler.repo.GetPurchaseByID(result.ID); err == nil {
+				result.Purchase = reloaded
+			}
+		}
+
+		results[positions[i]] = offlinePurchaseResult(result, handler.decimalPlaces)
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+func offlinePurchaseResult(
+	result purchaseService.OfflinePurchaseResult,
+	decimalPlaces int32,
+) response.OfflinePurchaseResult {
+	resultResponse := response.OfflinePurchaseResult{
+		ID:              result.ID,
+		Status:          string(result.Status),
+		SkippedGuestIDs: result.SkippedGuestIDs,
+	}
+
+	if result.Err != nil {
+		resultResponse.Error = utils.CapitalizeFirstRune(result.Err.Error())
+	}
+
+	if result.Purchase != nil {
+		purchaseResponse := response.ToPurchaseResponse(*result.Purchase, decimalPlaces)
+		resultResponse.Purchase = &purchaseResponse
+	}
+
+	return resultResponse
+}
@@ -0,0 +1,139 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/potibm/kasseapparat/internal/app/models"
+	response "github.com/potibm/kasseapparat/internal/app/response"
+	purchaseService "github.com/potibm/kasseapparat/internal/app/service/purchase"
+	"github.com/potibm/kasseapparat/internal/app/utils"
+)
+
+const quoteOutdatedMsg = "The prices have changed since the quote, please request a new quote"
+
+// QuotePurchase prices a cart without storing anything. Besides the prices it reports the stock
+// left and the guests who cannot be admitted, which do not fail the quote.
+func (handler *Handler) QuotePurchase(c *gin.Context) {
+	executingUserObj, err := handler.getUserFromContext(c)
+	if err != nil {
+		_ = c.Error(UnableToRetrieveExecutingUser.WithCause(err))
+
+		return
+	}
+
+	var req QuoteRequest
+	if err := c.ShouldBind(&req); err != nil {
+		_ = c.Error(InvalidRequest.WithCauseMsg(err))
+
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		_ = c.Error(InvalidRequest.WithCauseMsg(err))
+
+		return
+	}
+
+	quote, err := handler.purchaseService.QuoteCart(req.ToInput(), executingUserObj.ID)
+	if err != nil {
+		_ = c.Error(mapPurchaseCreationError(err))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, quoteResponse(*quote, handler.decimalPlaces))
+}
+
+// applyQuote takes the prices and totals of the purchase from its quote token, if it has one.
+func (handler *Handler) applyQuote(
+	c *gin.Context,
+	req PurchaseRequest,
+	input purchaseService.PurchaseInput,
+	userID int,
+) (purchaseService.PurchaseInput, bool) {
+	if req.QuoteToken == "" {
+		return input, true
+	}
+
+	input, err := handler.purchaseService.ApplyQuote(req.QuoteToken, input, userID)
+	if err != nil {
+		msg := utils.CapitalizeFirstRune(err.Error())
+		if errors.Is(err, purchaseService.ErrQuoteExpired) {
+			_ = c.Error(Conflict.WithMsg(msg).WithCause(err))
+		} else {
+			_ = c.Error(InvalidRequest.WithMsg(msg).WithCause(err))
+		}
+
+		return input, false
+	}
+
+	return input, true
+}
+
+func quoteResponse(quote purchaseService.Quote, decimalPlaces int32) response.QuoteResponse {
+	var discounts []models.PurchaseDiscount
+	for _, item := range quote.Items {
+		discounts = append(discounts, item.Discounts...)
+	}
+
+	quoteResp := response.QuoteResponse{
+		Items:           response.ToPurchaseItemsResponse(quote.Items, decimalPlaces),
+		Discounts:       response.ToPurchaseDiscountsResponse(discounts),
+		VATRates:        make([]response.QuoteVATRateResponse, 0, len(quote.VATRates)),
+		PaymentAmounts:  make([]response.QuotePaymentAmountResponse, 0, len(quote.PaymentAmounts)),
+		TotalNetPrice:   quote.TotalNetPrice,
+		TotalVatAmount:  quote.TotalGrossPrice.Sub(quote.TotalNetPrice),
+		TotalGrossPrice: quote.TotalGrossPrice,
+		Stock:           make([]response.QuoteStockResponse, 0, len(quote.Stock)),
+		GuestErrors:     make([]response.QuoteGuestErrorResponse, 0, len(quote.GuestErrors)),
+		Token:           quote.Token,
+		ExpiresAt:       quote.ExpiresAt,
+	}
+
+	for _, rate := range quote.VATRates {
+		quoteResp.VATRates = append(quoteResp.VATRates, response.QuoteVATRateResponse{
+			VATRate:    rate.VATRate,
+			NetPrice:   rate.NetPrice,
+			VATAmount:  rate.VATAmount,
+			GrossPrice: rate.GrossPrice,
+		})
+	}
+
+	for _, amount := range quote.PaymentAmounts {
+		quoteResp.PaymentAmounts = append(quoteResp.PaymentAmounts, response.QuotePaymentAmountResponse{
+			PaymentMethod: amount.PaymentMethod,
+			Amount:        amount.Amount,
+			Rounding:      amount.Rounding,
+		})
+	}
+
+	for _, stock := range quote.Stock {
+		quoteResp.Stock = append(quoteResp.Stock, response.QuoteStockResponse{
+			ProductID:  stock.ProductID,
+			VariantID:  stock.VariantID,
+			Name:       stock.Name,
+			Quantity:   stock.Quantity,
+			Available:  stock.Available,
+			Sufficient: stock.Sufficient,
+		})
+	}
+
+	for _, guestError := range quote.GuestErrors {
+		quoteResp.GuestErrors = append(quoteResp.GuestErrors, response.QuoteGuestErrorResponse{
+			GuestID:   guestError.GuestID,
+			ProductID: guestError.ProductID,
+			Error:     utils.CapitalizeFirstRune(guestError.Err.Error()),
+		})
+	}
+
+	return quoteResp
+}
+
+// isPriceMismatch reports whether the purchase failed as its prices are not the current ones.
+func isPriceMismatch(err error) bool {
+	return errors.Is(err, purchaseService.ErrInvalidProductPrice) ||
+		errors.Is(err, purchaseService.ErrInvalidTotalNetPrice) ||
+		errors.Is(err, purchaseService.ErrInvalidTotalGrossPrice)
+}
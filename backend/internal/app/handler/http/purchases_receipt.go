@@ -0,0 +1,130 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/potibm/kasseapparat/internal/app/models"
+	response "github.com/potibm/kasseapparat/internal/app/response"
+)
+
+type PurchaseReceiptMailRequest struct {
+	Email string `json:"email" form:"email" binding:"required,email"`
+}
+
+func (handler *Handler) GetPurchaseReceipt(c *gin.Context) {
+	purchase, ok := handler.getReceiptPurchase(c)
+	if !ok {
+		return
+	}
+
+	receipt := handler.receipts.Build(*purchase)
+
+	var (
+		body        bytes.Buffer
+		contentType string
+		err         error
+	)
+
+	switch c.DefaultQuery("format", "html") {
+	case "html":
+		contentType = "text/html; charset=utf-8"
+		err = handler.receipts.RenderHTML(&body, receipt)
+	case "text":
+		contentType = "text/plain; charset=utf-8"
+		err = handler.receipts.RenderText(&body, receipt)
+	default:
+		_ = c.Error(InvalidRequest.WithMsg("Format must be one of html or text"))
+
+		return
+	}
+
+	if err != nil {
+		_ = c.Error(InternalServerError.WithMsg("Failed to render receipt").WithCause(err))
+
+		return
+	}
+
+	c.Data(http.StatusOK, contentType, body.Bytes())
+}
+
+func (handler *Handler) SendPurchaseReceiptMail(c *gin.Context) {
+	executingUserObj, err := handler.getUserFromContext(c)
+	if err != nil {
+		_ = c.Error(UnableToRetrieveExecutingUser.WithCause(err))
+
+		return
+	}
+
+	purchase, ok := handler.getReceiptPurchase(c)
+	if !ok {
+		return
+	}
+
+	var req PurchaseReceiptMailRequest
+	if err := c.ShouldBind(&req); err != nil {
+		_ = c.Error(InvalidRequest.WithCauseMsg(err))
+
+		return
+	}
+
+	var body bytes.Buffer
+	if err := handler.receipts.RenderText(&body, handler.receipts.Build(*purchase)); err != nil {
+		_ = c.Error(InternalServerError.WithMsg("Failed to render receipt").WithCause(err))
+
+		return
+	}
+
+	if err := handler.mailer.SendPurchaseReceiptMail(req.Email, body.String()); err != nil {
+		_ = c.Error(InternalServerError.WithMsg("Failed to send receipt").WithCause(err))
+
+		return
+	}
+
+	mail := models.PurchaseReceiptMail{
+		PurchaseID: purchase.ID,
+		SentAt:     time.Now(),
+	}
+	mail.CreatedByID = &executingUserObj.ID
+
+	// without a retention period the address is not stored at all
+	if handler.config.Receipt.MailRetentionDays > 0 {
+		mail.Email = &req.Email
+	}
+
+	storedMail, err := handler.repo.StorePurchaseReceiptMail(mail)
+	if err != nil {
+		_ = c.Error(InternalServerError.WithCauseMsg(err))
+
+		return
+	}
+
+	c.JSON(http.StatusCreated, response.ToPurchaseReceiptMailResponse(storedMail))
+}
+
+func (handler *Handler) getReceiptPurchase(c *gin.Context) (*models.Purchase, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		_ = c.Error(InvalidRequest.WithMsg(invalidPurchaseIDMsg).WithCause(err))
+
+		return nil, false
+	}
+
+	purchase, err := handler.repo.GetPurchaseByID(id)
+	if err != nil {
+		_ = c.Error(NotFound.WithCause(err))
+
+		return nil, false
+	}
+
+	if purchase.Status != models.PurchaseStatusConfirmed && purchase.Status != models.PurchaseStatusRefunded {
+		_ = c.Error(Conflict.WithMsg("Receipts are only available for confirmed purchases"))
+
+		return nil, false
+	}
+
+	return purchase, true
+}
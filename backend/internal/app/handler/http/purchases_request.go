@@ -15,17 +15,39 @@ type PurchaseListItemRequest struct {
 
 type PurchaseCartRequest struct {
 	ID        int                       `form:"ID"        binding:"required"`
+	VariantID *int                      `form:"variantId" binding:"omitempty"`
 	Quantity  uint                      `form:"quantity"  binding:"required"`
 	NetPrice  decimal.Decimal           `form:"netPrice"  binding:"required"`
 	ListItems []PurchaseListItemRequest `form:"listItems" binding:"required,dive"`
 }
 
+type PurchasePaymentRequest struct {
+	PaymentMethod models.PaymentMethod `form:"paymentMethod" binding:"required"`
+	Amount        decimal.Decimal      `form:"amount"        binding:"required"`
+	VoucherCode   string               `form:"voucherCode"   binding:"omitempty"`
+}
+
+type PurchaseDiscountRequest struct {
+	Code       string `form:"code"       binding:"omitempty"`
+	DiscountID int    `form:"discountId" binding:"omitempty"`
+}
+
 type PurchaseRequest struct {
-	TotalNetPrice   decimal.Decimal       `form:"totalNetPrice"   binding:"required"`
-	TotalGrossPrice decimal.Decimal       `form:"totalGrossPrice" binding:"required"`
-	Cart            []PurchaseCartRequest `form:"cart"            binding:"required,dive"`
-	PaymentMethod   models.PaymentMethod  `form:"paymentMethod"   binding:"required"`
-	SumupReaderID   string                `form:"sumupReaderId"   binding:"omitempty"`
+	TotalNetPrice   decimal.Decimal           `form:"totalNetPrice"   binding:"required"`
+	TotalGrossPrice decimal.Decimal           `form:"totalGrossPrice" binding:"required"`
+	Cart            []PurchaseCartRequest     `form:"cart"            binding:"required,dive"`
+	PaymentMethod   models.PaymentMethod      `form:"paymentMethod"   binding:"required_without=Payments"`
+	VoucherCode     string                    `form:"voucherCode"     binding:"omitempty"`
+	Payments        []PurchasePaymentRequest  `form:"payments"        binding:"omitempty,dive"`
+	Discounts       []PurchaseDiscountRequest `form:"discounts"       binding:"omitempty,dive"`
+	SumupReaderID   string                    `form:"sumupReaderId"   binding:"omitempty"`
+	QuoteToken      string                    `form:"quoteToken"      binding:"omitempty"`
+}
+
+// QuoteRequest is a cart to be priced by the server. The prices of the cart items are ignored.
+type QuoteRequest struct {
+	Cart      []PurchaseCartRequest     `form:"cart"      binding:"required,dive"`
+	Discounts []PurchaseDiscountRequest `form:"discounts" binding:"omitempty,dive"`
 }
 
 func (req PurchaseRequest) Validate() error {
@@ -33,30 +55,65 @@ func (req PurchaseRequest) Validate() error {
 		return fmt.Errorf("total price must not be negative")
 	}
 
-	if len(req.Cart) == 0 {
+	if err := validateCart(req.Cart, req.Discounts); err != nil {
+		return err
+	}
+
+	for _, payment := range req.Payments {
+		if payment.Amount.IsNegative() {
+			return fmt.Errorf("payment amount must not be negative")
+		}
+	}
+
+	return nil
+}
+
+func (req QuoteRequest) Validate() error {
+	return validateCart(req.Cart, req.Discounts)
+}
+
+func validateCart(cart []PurchaseCartRequest, discounts []PurchaseDiscountRequest) error {
+	if len(cart) == 0 {
 		return fmt.Errorf("cart must not be empty")
 	}
 
-	seen := make(map[int]struct{})
-	for _, cart := range req.Cart {
-		if err := validateCartItem(cart, seen); err != nil {
+	seen := make(map[cartItemKey]struct{})
+	for _, item := range cart {
+		if err := validateCartItem(item, seen); err != nil {
 			return err
 		}
 	}
 
+	for _, discount := range discounts {
+		if discount.Code == "" && discount.DiscountID <= 0 {
+			return fmt.Errorf("discount requires a code or a discount ID")
+		}
+	}
+
 	return nil
 }
 
-func validateCartItem(cart PurchaseCartRequest, seen map[int]struct{}) error {
+// cartItemKey identifies a cart item, the variants of a product are separate cart items.
+type cartItemKey struct {
+	productID int
+	variantID int
+}
+
+func validateCartItem(cart PurchaseCartRequest, seen map[cartItemKey]struct{}) error {
 	if cart.Quantity < 1 {
 		return fmt.Errorf("quantity must be at least 1")
 	}
 
-	if _, ok := seen[cart.ID]; ok {
+	key := cartItemKey{productID: cart.ID}
+	if cart.VariantID != nil {
+		key.variantID = *cart.VariantID
+	}
+
+	if _, ok := seen[key]; ok {
 		return fmt.Errorf("duplicate product ID: %d", cart.ID)
 	}
 
-	seen[cart.ID] = struct{}{}
+	seen[key] = struct{}{}
 
 	for _, li := range cart.ListItems {
 		if err := validateListItem(li); err != nil {
@@ -82,25 +139,92 @@ func validateListItem(li PurchaseListItemRequest) error {
 func (req PurchaseRequest) ToInput() purchaseService.PurchaseInput {
 	input := purchaseService.PurchaseInput{
 		PaymentMethod:   req.PaymentMethod,
+		VoucherCode:     req.VoucherCode,
 		TotalNetPrice:   req.TotalNetPrice,
 		TotalGrossPrice: req.TotalGrossPrice,
+		SumupReaderID:   req.SumupReaderID,
 	}
 
-	for _, cart := range req.Cart {
+	for _, payment := range req.Payments {
+		input.Payments = append(input.Payments, purchaseService.PaymentInput{
+			PaymentMethod: payment.PaymentMethod,
+			Amount:        payment.Amount,
+			VoucherCode:   payment.VoucherCode,
+		})
+	}
+
+	input.Discounts = toDiscountInputs(req.Discounts)
+
+	if input.PaymentMethod == "" && len(input.Payments) > 0 {
+		input.PaymentMethod = input.PrimaryPaymentMethod()
+	}
+
+	input.Cart = toCartInput(req.Cart)
+
+	return input
+}
+
+func (req QuoteRequest) ToInput() purchaseService.PurchaseInput {
+	return purchaseService.PurchaseInput{
+		Cart:      toCartInput(req.Cart),
+		Discounts: toDiscountInputs(req.Discounts),
+	}
+}
+
+func toDiscountInputs(discounts []PurchaseDiscountRequest) []purchaseService.DiscountInput {
+	var inputs []purchaseService.DiscountInput
+
+	for _, discount := range discounts {
+		inputs = append(inputs, purchaseService.DiscountInput{
+			Code:       discount.Code,
+			DiscountID: discount.DiscountID,
+		})
+	}
+
+	return inputs
+}
+
+func toCartInput(cart []PurchaseCartRequest) []purchaseService.PurchaseCartItem {
+	var items []purchaseService.PurchaseCartItem
+
+	for _, cartItem := range cart {
 		item := purchaseService.PurchaseCartItem{
-			ID:       cart.ID,
-			Quantity: cart.Quantity,
-			NetPrice: cart.NetPrice,
+			ID:        cartItem.ID,
+			VariantID: cartItem.VariantID,
+			Quantity:  cartItem.Quantity,
+			NetPrice:  cartItem.NetPrice,
 		}
-		for _, li := range cart.ListItems {
+		for _, li := range cartItem.ListItems {
 			item.ListItems = append(item.ListItems, purchaseService.ListItemInput{
 				ID:             li.ID,
 				AttendedGuests: li.AttendedGuests,
 			})
 		}
 
-		input.Cart = append(input.Cart, item)
+		items = append(items, item)
 	}
 
-	return input
+	return items
+}
+
+type PurchaseRefundItemRequest struct {
+	PurchaseItemID int  `form:"purchaseItemId" binding:"required"`
+	Quantity       uint `form:"quantity"       binding:"required,gt=0"`
+}
+
+type PurchaseRefundRequest struct {
+	Items []PurchaseRefundItemRequest `form:"items" binding:"required,min=1,dive"`
+}
+
+func (req PurchaseRefundRequest) ToInput() []purchaseService.RefundItemInput {
+	items := make([]purchaseService.RefundItemInput, 0, len(req.Items))
+
+	for _, item := range req.Items {
+		items = append(items, purchaseService.RefundItemInput{
+			PurchaseItemID: item.PurchaseItemID,
+			Quantity:       item.Quantity,
+		})
+	}
+
+	return items
 }
@@ -60,6 +60,27 @@ func TestToInputWithDuplicateProductId(t *testing.T) {
 	}
 }
 
+func TestToInputWithVariantsOfTheSameProduct(t *testing.T) {
+	small, large := 1, 2
+	req := PurchaseRequest{
+		Cart: []PurchaseCartRequest{
+			{ID: 2, VariantID: &small, Quantity: 1},
+			{ID: 2, VariantID: &large, Quantity: 1},
+		},
+	}
+
+	if err := req.Validate(); err != nil {
+		t.Errorf("expected variants of a product to be separate cart items, got %v", err)
+	}
+
+	req.Cart = append(req.Cart, PurchaseCartRequest{ID: 2, VariantID: &large, Quantity: 1})
+
+	err := req.Validate()
+	if err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("expected duplicate product id error, got %v", err)
+	}
+}
+
 func TestToInputWithEmptyCart(t *testing.T) {
 	req := PurchaseRequest{
 		Cart: []PurchaseCartRequest{},
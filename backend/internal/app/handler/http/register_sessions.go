@@ -0,0 +1,285 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/potibm/kasseapparat/internal/app/models"
+	sqliteRepo "github.com/potibm/kasseapparat/internal/app/repository/sqlite"
+	"github.com/potibm/kasseapparat/internal/app/utils"
+	"github.com/shopspring/decimal"
+)
+
+const noOpenRegisterSessionMsg = "No open register session, please open a session before selling for cash"
+
+type RegisterSessionOpenRequest struct {
+	OpeningFloat decimal.Decimal `json:"openingFloat" form:"openingFloat" binding:"required"`
+}
+
+type RegisterSessionDenominationRequest struct {
+	Value decimal.Decimal `json:"value" form:"value" binding:"required"`
+	Count uint            `json:"count" form:"count"`
+}
+
+type RegisterSessionCloseRequest struct {
+	CountedCash   *decimal.Decimal                     `json:"countedCash"   form:"countedCash"`
+	Denominations []RegisterSessionDenominationRequest `json:"denominations" form:"denominations" binding:"omitempty,dive"`
+	Note          *string                              `json:"note"          form:"note"`
+}
+
+// Counted returns the counted cash, summed up from the denominations if they are given.
+func (req RegisterSessionCloseRequest) Counted() (decimal.Decimal, error) {
+	if len(req.Denominations) == 0 {
+		if req.CountedCash == nil {
+			return decimal.Zero, errors.New("either countedCash or denominations are required")
+		}
+
+		return *req.CountedCash, nil
+	}
+
+	total := decimal.Zero
+
+	for _, denomination := range req.Denominations {
+		if !denomination.Value.IsPositive() {
+			return decimal.Zero, errors.New("denomination value must be positive")
+		}
+
+		total = total.Add(denomination.Value.Mul(decimal.NewFromUint64(uint64(denomination.Count))))
+	}
+
+	if req.CountedCash != nil && !req.CountedCash.Equal(total) {
+		return decimal.Zero, errors.New("countedCash does not match the sum of the denominations")
+	}
+
+	return total, nil
+}
+
+func (handler *Handler) GetRegisterSessions(c *gin.Context) {
+	executingUserObj, err := handler.getUserFromContext(c)
+	if err != nil {
+		_ = c.Error(UnableToRetrieveExecutingUser.WithCause(err))
+
+		return
+	}
+
+	start, _ := strconv.Atoi(c.DefaultQuery("_start", "0"))
+	end, _ := strconv.Atoi(c.DefaultQuery("_end", "10"))
+	sort := c.DefaultQuery("_sort", "openedAt")
+	order := c.DefaultQuery("_order", "DESC")
+
+	filters := sqliteRepo.RegisterSessionFilters{}
+	filters.CreatedByID, _ = strconv.Atoi(c.DefaultQuery("createdById", "0"))
+	filters.Status = models.RegisterSessionStatus(c.DefaultQuery("status", ""))
+	filters.IDs = queryArrayInt(c, "id")
+
+	// cashiers only see their own sessions
+	if !executingUserObj.Admin {
+		filters.CreatedByID = executingUserObj.ID
+	}
+
+	sessions, err := handler.repo.GetRegisterSessions(end-start, start, sort, order, filters)
+	if err != nil {
+		_ = c.Error(InternalServerError.WithCauseMsg(err))
+
+		return
+	}
+
+	total, err := handler.repo.GetTotalRegisterSessions(filters)
+	if err != nil {
+		_ = c.Error(InternalServerError.WithCause(err))
+
+		return
+	}
+
+	c.Header("X-Total-Count", strconv.Itoa(int(total)))
+	c.JSON(http.StatusOK, sessions)
+}
+
+func (handler *Handler) GetRegisterSessionByID(c *gin.Context) {
+	executingUserObj, err := handler.getUserFromContext(c)
+	if err != nil {
+		_ = c.Error(UnableToRetrieveExecutingUser.WithCause(err))
+
+		return
+	}
+
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	session, err := handler.repo.GetRegisterSessionByID(id)
+	if err != nil {
+		_ = c.Error(NotFound.WithCause(err))
+
+		return
+	}
+
+	if !canAccessRegisterSession(executingUserObj, session) {
+		_ = c.Error(Forbidden)
+
+		return
+	}
+
+	if err := handler.applyRegisterSessionCashTotals(session); err != nil {
+		_ = c.Error(InternalServerError.WithCauseMsg(err))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+func (handler *Handler) GetCurrentRegisterSession(c *gin.Context) {
+	executingUserObj, err := handler.getUserFromContext(c)
+	if err != nil {
+		_ = c.Error(UnableToRetrieveExecutingUser.WithCause(err))
+
+		return
+	}
+
+	session, err := handler.repo.GetOpenRegisterSessionByUserID(executingUserObj.ID)
+	if err != nil {
+		_ = c.Error(NotFound.WithMsg("No open register session").WithCause(err))
+
+		return
+	}
+
+	if err := handler.applyRegisterSessionCashTotals(session); err != nil {
+		_ = c.Error(InternalServerError.WithCauseMsg(err))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+func (handler *Handler) OpenRegisterSession(c *gin.Context) {
+	executingUserObj, err := handler.getUserFromContext(c)
+	if err != nil {
+		_ = c.Error(UnableToRetrieveExecutingUser.WithCause(err))
+
+		return
+	}
+
+	var req RegisterSessionOpenRequest
+	if err := c.ShouldBind(&req); err != nil {
+		_ = c.Error(InvalidRequest.WithCauseMsg(err))
+
+		return
+	}
+
+	if req.OpeningFloat.IsNegative() {
+		_ = c.Error(InvalidRequest.WithMsg("Opening float must not be negative"))
+
+		return
+	}
+
+	session := models.RegisterSession{
+		Status:       models.RegisterSessionStatusOpen,
+		OpenedAt:     time.Now(),
+		OpeningFloat: req.OpeningFloat,
+	}
+	session.ApplyCashTotals(decimal.Zero, decimal.Zero)
+	session.CreatedByID = &executingUserObj.ID
+
+	newSession, err := handler.repo.CreateRegisterSession(session)
+	if errors.Is(err, sqliteRepo.ErrRegisterSessionAlreadyOpen) {
+		_ = c.Error(Conflict.WithMsg(utils.CapitalizeFirstRune(err.Error())).WithCause(err))
+
+		return
+	}
+
+	if err != nil {
+		_ = c.Error(InternalServerError.WithCauseMsg(err))
+
+		return
+	}
+
+	c.JSON(http.StatusCreated, newSession)
+}
+
+func (handler *Handler) CloseRegisterSession(c *gin.Context) {
+	executingUserObj, err := handler.getUserFromContext(c)
+	if err != nil {
+		_ = c.Error(UnableToRetrieveExecutingUser.WithCause(err))
+
+		return
+	}
+
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	session, err := handler.repo.GetRegisterSessionByID(id)
+	if err != nil {
+		_ = c.Error(NotFound.WithCause(err))
+
+		return
+	}
+
+	if !canAccessRegisterSession(executingUserObj, session) {
+		_ = c.Error(Forbidden)
+
+		return
+	}
+
+	if !session.IsOpen() {
+		_ = c.Error(Conflict.WithMsg("The register session is already closed"))
+
+		return
+	}
+
+	var req RegisterSessionCloseRequest
+	if err := c.ShouldBind(&req); err != nil {
+		_ = c.Error(InvalidRequest.WithCauseMsg(err))
+
+		return
+	}
+
+	counted, err := req.Counted()
+	if err != nil {
+		_ = c.Error(InvalidRequest.WithCauseMsg(err))
+
+		return
+	}
+
+	session.Note = req.Note
+	session.UpdatedByID = &executingUserObj.ID
+	session.Denominations = make([]models.RegisterSessionDenomination, 0, len(req.Denominations))
+
+	for _, denomination := range req.Denominations {
+		session.Denominations = append(session.Denominations, models.RegisterSessionDenomination{
+			Value: denomination.Value,
+			Count: denomination.Count,
+		})
+	}
+
+	closedSession, err := handler.repo.CloseRegisterSession(*session, counted)
+	if err != nil {
+		_ = c.Error(InternalServerError.WithCauseMsg(err))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, closedSession)
+}
+
+// applyRegisterSessionCashTotals calculates the expected cash of an open session. The figures of
+// closed sessions have been stored on closing and are left untouched.
+func (handler *Handler) applyRegisterSessionCashTotals(session *models.RegisterSession) error {
+	if !session.IsOpen() {
+		return nil
+	}
+
+	totals, err := handler.repo.GetRegisterSessionCashTotals(session.ID)
+	if err != nil {
+		return err
+	}
+
+	session.ApplyCashTotals(totals.CashIn, totals.CashOut)
+
+	return nil
+}
+
+func canAccessRegisterSession(user *models.User, session *models.RegisterSession) bool {
+	return user.Admin || (session.CreatedByID != nil && *session.CreatedByID == user.ID)
+}
@@ -0,0 +1,113 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/potibm/kasseapparat/internal/app/response"
+	purchaseService "github.com/potibm/kasseapparat/internal/app/service/purchase"
+	"github.com/potibm/kasseapparat/internal/app/service/reconcile"
+	"github.com/potibm/kasseapparat/internal/app/utils"
+)
+
+type SumupReconciliationRequest struct {
+	From   time.Time `json:"from"   binding:"required"`
+	To     time.Time `json:"to"     binding:"required"`
+	Repair bool      `json:"repair"`
+}
+
+// ReconcileSumupTransactions matches the SumUp transactions of a period with the purchases and
+// reports every disagreement. With repair, pending purchases whose SumUp transaction is final
+// are changed to its status.
+func (handler *Handler) ReconcileSumupTransactions(c *gin.Context) {
+	executingUserObj, err := handler.getUserFromContext(c)
+	if err != nil {
+		_ = c.Error(UnableToRetrieveExecutingUser.WithCause(err))
+
+		return
+	}
+
+	if !executingUserObj.Admin {
+		_ = c.Error(Forbidden)
+
+		return
+	}
+
+	var req SumupReconciliationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(InvalidRequest.WithCauseMsg(err))
+
+		return
+	}
+
+	ctx := purchaseService.WithTransitionOrigin(
+		c.Request.Context(),
+		models.PurchaseTransitionSourceUser,
+		&executingUserObj.ID,
+	)
+
+	result, err := handler.reconciler.Reconcile(ctx, req.From, req.To, req.Repair)
+	if errors.Is(err, reconcile.ErrInvalidPeriod) {
+		_ = c.Error(InvalidRequest.WithMsg(utils.CapitalizeFirstRune(err.Error())).WithCause(err))
+
+		return
+	}
+
+	if err != nil {
+		_ = c.Error(InternalServerError.WithCauseMsg(err))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, sumupReconciliationResponse(result))
+}
+
+func sumupReconciliationResponse(result reconcile.Result) response.SumupReconciliationResponse {
+	resp := response.SumupReconciliationResponse{
+		From:             result.From,
+		To:               result.To,
+		TransactionCount: result.TransactionCount,
+		PurchaseCount:    result.PurchaseCount,
+		MatchedCount:     result.MatchedCount,
+		Consistent:       result.Consistent(),
+		Issues:           make([]response.SumupReconciliationIssueResponse, 0, len(result.Issues)),
+	}
+
+	for _, issue := range result.Issues {
+		issueResp := response.SumupReconciliationIssueResponse{
+			Kind:                string(issue.Kind),
+			ClientTransactionID: uuidOrNil(issue.ClientTransactionID),
+			RepairedStatus:      string(issue.RepairedStatus),
+			RepairError:         issue.RepairError,
+		}
+
+		if issue.PurchaseID != uuid.Nil {
+			issueResp.PurchaseID = &issue.PurchaseID
+			issueResp.PurchaseStatus = string(issue.PurchaseStatus)
+			issueResp.PurchaseAmount = &issue.PurchaseAmount
+		}
+
+		if issue.TransactionStatus != "" {
+			issueResp.TransactionID = uuidOrNil(issue.TransactionID)
+			issueResp.TransactionCode = issue.TransactionCode
+			issueResp.TransactionStatus = issue.TransactionStatus
+			issueResp.TransactionAmount = &issue.TransactionAmount
+		}
+
+		resp.Issues = append(resp.Issues, issueResp)
+	}
+
+	return resp
+}
+
+func uuidOrNil(id uuid.UUID) *uuid.UUID {
+	if id == uuid.Nil {
+		return nil
+	}
+
+	return &id
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/google/uuid"
 	model "github.com/potibm/kasseapparat/internal/app/models"
 	"github.com/potibm/kasseapparat/internal/app/repository/sumup"
+	purchaseService "github.com/potibm/kasseapparat/internal/app/service/purchase"
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
@@ -122,37 +123,38 @@ func (handler *Handler) GetSumupTransactionWebhook(c *gin.Context) {
 		return
 	}
 
-	if purchase.Status != model.PurchaseStatusPending {
-		c.JSON(http.StatusConflict, gin.H{"error": "purchase is not in pending status"})
+	ctx := purchaseService.WithTransitionOrigin(c.Request.Context(), model.PurchaseTransitionSourceWebhook, nil)
 
-		return
-	}
+	var updated *model.Purchase
 
-	ctx := c.Request.Context()
-
-	switch payload.Payload.Status {
+	switch payload.Payload.Status.Normalize() {
 	case sumup.StatusSuccessful:
-		// Update purchase status to confirmed
 		slog.InfoContext(
 			ctx,
 			"Updating purchase status to confirmed",
 			"transaction_id",
 			payload.Payload.ClientTransactionID,
 		)
-		handler.statusPublisher.PushUpdate(purchase.ID, model.PurchaseStatusConfirmed)
 
-		_, err = handler.purchaseService.FinalizePurchase(ctx, purchase.ID)
+		updated, err = handler.purchaseService.FinalizePurchase(ctx, purchase.ID)
 	case sumup.StatusFailed:
-		// Update purchase status to failed
 		slog.InfoContext(
 			ctx,
 			"Updating purchase status to failed",
 			"transaction_id",
 			payload.Payload.ClientTransactionID,
 		)
-		handler.statusPublisher.PushUpdate(purchase.ID, model.PurchaseStatusFailed)
 
-		_, err = handler.purchaseService.FailPurchase(ctx, purchase.ID)
+		updated, err = handler.purchaseService.FailPurchase(ctx, purchase.ID)
+	case sumup.StatusCancelled:
+		slog.InfoContext(
+			ctx,
+			"Updating purchase status to cancelled",
+			"transaction_id",
+			payload.Payload.ClientTransactionID,
+		)
+
+		updated, err = handler.purchaseService.CancelPurchase(ctx, purchase.ID)
 	default:
 		slog.WarnContext(ctx, "Unsupported status", "status", payload.Payload.Status)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported status"})
@@ -160,6 +162,17 @@ func (handler *Handler) GetSumupTransactionWebhook(c *gin.Context) {
 		return
 	}
 
+	// a redelivered notification of a confirmed purchase is answered like the first one
+	if errors.Is(err, purchaseService.ErrPurchaseAlreadyConfirmed) {
+		err = nil
+	}
+
+	if errors.Is(err, purchaseService.ErrPurchaseNotPending) {
+		c.JSON(http.StatusConflict, gin.H{"error": "purchase is not in pending status"})
+
+		return
+	}
+
 	if err != nil {
 		slog.ErrorContext(ctx, "Failed to update purchase status", "error", err)
 		_ = c.Error(InternalServerError.WithMsg("failed to update purchase status").WithCause(err))
@@ -167,6 +180,8 @@ func (handler *Handler) GetSumupTransactionWebhook(c *gin.Context) {
 		return
 	}
 
+	handler.statusPublisher.PushUpdate(purchase.ID, updated.Status)
+
 	c.JSON(http.StatusOK, gin.H{"status": "success"})
 }
 
@@ -0,0 +1,43 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/potibm/kasseapparat/internal/app/repository/terminal"
+)
+
+type TerminalResponse struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Model  string `json:"model"`
+}
+
+// GetTerminals lists the card terminals of the configured payment terminal provider.
+func (handler *Handler) GetTerminals(c *gin.Context) {
+	terminals, err := handler.terminalProvider.GetTerminals()
+	if err != nil {
+		_ = c.Error(InternalServerError.WithMsg("Failed to retrieve terminals").WithCause(err))
+
+		return
+	}
+
+	response := make([]TerminalResponse, 0, len(terminals))
+	for _, t := range terminals {
+		response = append(response, toTerminalResponse(t))
+	}
+
+	c.Header("X-Total-Count", strconv.Itoa(len(terminals)))
+	c.JSON(http.StatusOK, response)
+}
+
+func toTerminalResponse(t terminal.Terminal) TerminalResponse {
+	return TerminalResponse{
+		ID:     t.ID,
+		Name:   t.Name,
+		Status: t.Status,
+		Model:  t.Model,
+	}
+}
@@ -56,16 +56,22 @@ func (handler *Handler) GetUserByID(c *gin.Context) {
 }
 
 type UserCreateRequest struct {
-	Username string `json:"username" form:"username" binding:"required"`
-	Email    string `json:"email"    form:"email"    binding:"required"`
-	Admin    bool   `json:"admin"    form:"admin"    binding:""`
+	Username       string `json:"username"       form:"username"       binding:"required"`
+	Email          string `json:"email"          form:"email"          binding:"required"`
+	Admin          bool   `json:"admin"          form:"admin"          binding:""`
+	GrantDiscounts bool   `json:"grantDiscounts" form:"grantDiscounts" binding:""`
+	CategoryIDs    []int  `json:"categoryIds"    form:"categoryIds"    binding:""`
 }
 
+// UserUpdateRequest updates a user. The categories of the user are kept when CategoryIDs is
+// missing, an empty list removes the restriction.
 type UserUpdateRequest struct {
-	Username string `json:"username" form:"username" binding:"required"`
-	Password string `json:"password" form:"password" binding:""`
-	Email    string `json:"email"    form:"email"    binding:"required"`
-	Admin    bool   `json:"admin"    form:"admin"    binding:""`
+	Username       string `json:"username"       form:"username"       binding:"required"`
+	Password       string `json:"password"       form:"password"       binding:""`
+	Email          string `json:"email"          form:"email"          binding:"required"`
+	Admin          bool   `json:"admin"          form:"admin"          binding:""`
+	GrantDiscounts bool   `json:"grantDiscounts" form:"grantDiscounts" binding:""`
+	CategoryIDs    []int  `json:"categoryIds"    form:"categoryIds"    binding:""`
 }
 
 func (handler *Handler) UpdateUserByID(c *gin.Context) {
@@ -107,6 +113,11 @@ func (handler *Handler) UpdateUserByID(c *gin.Context) {
 	// only an admin may change the role of a user
 	if executingUserObj.Admin {
 		user.Admin = userRequest.Admin
+		user.GrantDiscounts = userRequest.GrantDiscounts
+
+		if userRequest.CategoryIDs != nil && !handler.applyUserCategories(c, user, userRequest.CategoryIDs) {
+			return
+		}
 	}
 
 	user, err = handler.repo.UpdateUserByID(id, *user)
@@ -148,8 +159,14 @@ func (handler *Handler) CreateUser(c *gin.Context) {
 	// only an admin may change the role of a user
 	if executingUserObj.Admin {
 		user.Admin = userRequest.Admin
+		user.GrantDiscounts = userRequest.GrantDiscounts
+
+		if !handler.applyUserCategories(c, &user, userRequest.CategoryIDs) {
+			return
+		}
 	} else {
 		user.Admin = false
+		user.GrantDiscounts = false
 	}
 
 	user, err = handler.repo.CreateUser(user)
@@ -217,3 +234,30 @@ func (handler *Handler) getUserFromContext(c *gin.Context) (*models.User, error)
 
 	return userObj, nil
 }
+
+// applyUserCategories restricts the user to the categories with the IDs.
+func (handler *Handler) applyUserCategories(c *gin.Context, user *models.User, categoryIDs []int) bool {
+	user.Categories = []models.Category{}
+	if len(categoryIDs) == 0 {
+		return true
+	}
+
+	filters := sqliteRepo.CategoryFilters{IDs: categoryIDs}
+
+	categories, err := handler.repo.GetCategories(len(categoryIDs), 0, "id", "ASC", filters)
+	if err != nil {
+		_ = c.Error(InternalServerError.WithCause(err))
+
+		return false
+	}
+
+	if len(categories) != len(categoryIDs) {
+		_ = c.Error(InvalidRequest.WithMsg("Category not found"))
+
+		return false
+	}
+
+	user.Categories = categories
+
+	return true
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/potibm/kasseapparat/internal/app/config"
 	"github.com/potibm/kasseapparat/internal/app/models"
+	purchaseService "github.com/potibm/kasseapparat/internal/app/service/purchase"
 	"github.com/shopspring/decimal"
 )
 
@@ -48,6 +49,16 @@ func queryDecimal(c *gin.Context, field string) *decimal.Decimal {
 	}
 }
 
+// queryBool returns the boolean query parameter, or nil if it is missing or not a boolean.
+func queryBool(c *gin.Context, field string) *bool {
+	value, err := strconv.ParseBool(c.Query(field))
+	if err != nil {
+		return nil
+	}
+
+	return &value
+}
+
 func queryTime(c *gin.Context, field string, defaultValue *time.Time) *time.Time {
 	timeString := c.DefaultQuery(field, "")
 
@@ -135,3 +146,13 @@ func (handler *Handler) ValidatePaymentMethodPayload(code models.PaymentMethod,
 
 	return nil
 }
+
+func (handler *Handler) ValidatePaymentLinesPayload(input purchaseService.PurchaseInput, sumupReaderID string) error {
+	for _, line := range input.PaymentLines() {
+		if err := handler.ValidatePaymentMethodPayload(line.PaymentMethod, sumupReaderID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
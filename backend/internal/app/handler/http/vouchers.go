@@ -0,0 +1,263 @@
+package http
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/potibm/kasseapparat/internal/app/models"
+	sqliteRepo "github.com/potibm/kasseapparat/internal/app/repository/sqlite"
+	"github.com/potibm/kasseapparat/templates"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	voucherTemplate    = "vouchers/vouchers.html"
+	maxVouchersPerPage = 500
+)
+
+type VoucherCreateRequest struct {
+	Name      string          `json:"name"      form:"name"`
+	Value     decimal.Decimal `json:"value"     form:"value"     binding:"required"`
+	Count     int             `json:"count"     form:"count"     binding:"omitempty,gte=1,lte=500"`
+	ExpiresAt *time.Time      `json:"expiresAt" form:"expiresAt"`
+}
+
+type VoucherUpdateRequest struct {
+	Name      string     `json:"name"      form:"name"`
+	ExpiresAt *time.Time `json:"expiresAt" form:"expiresAt"`
+}
+
+func (handler *Handler) GetVouchers(c *gin.Context) {
+	if !handler.requireAdmin(c) {
+		return
+	}
+
+	start, _ := strconv.Atoi(c.DefaultQuery("_start", "0"))
+	end, _ := strconv.Atoi(c.DefaultQuery("_end", "10"))
+	sort := c.DefaultQuery("_sort", "id")
+	order := c.DefaultQuery("_order", "DESC")
+
+	filters := sqliteRepo.VoucherFilters{}
+	filters.Query = c.DefaultQuery("q", "")
+	filters.IDs = queryArrayInt(c, "id")
+
+	vouchers, err := handler.repo.GetVouchers(end-start, start, sort, order, filters)
+	if err != nil {
+		_ = c.Error(InternalServerError.WithCauseMsg(err))
+
+		return
+	}
+
+	total, err := handler.repo.GetTotalVouchers(filters)
+	if err != nil {
+		_ = c.Error(InternalServerError.WithCause(err))
+
+		return
+	}
+
+	c.Header("X-Total-Count", strconv.Itoa(int(total)))
+	c.JSON(http.StatusOK, vouchers)
+}
+
+// GetVoucherByID returns the voucher with its redemption history.
+func (handler *Handler) GetVoucherByID(c *gin.Context) {
+	if !handler.requireAdmin(c) {
+		return
+	}
+
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	voucher, err := handler.repo.GetVoucherByID(id)
+	if err != nil {
+		_ = c.Error(NotFound.WithCause(err))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, voucher)
+}
+
+// CreateVouchers generates the requested number of vouchers with the same value and expiry,
+// each with its own random code.
+func (handler *Handler) CreateVouchers(c *gin.Context) {
+	executingUserObj, err := handler.getUserFromContext(c)
+	if err != nil {
+		_ = c.Error(UnableToRetrieveExecutingUser.WithCause(err))
+
+		return
+	}
+
+	if !executingUserObj.Admin {
+		_ = c.Error(Forbidden)
+
+		return
+	}
+
+	var req VoucherCreateRequest
+	if err := c.ShouldBind(&req); err != nil {
+		_ = c.Error(InvalidRequest.WithCauseMsg(err))
+
+		return
+	}
+
+	if !req.Value.IsPositive() {
+		_ = c.Error(InvalidRequest.WithMsg("Value must be positive"))
+
+		return
+	}
+
+	count := max(req.Count, 1)
+	vouchers := make([]models.Voucher, 0, count)
+
+	for range count {
+		code, err := models.GenerateVoucherCode()
+		if err != nil {
+			_ = c.Error(InternalServerError.WithCauseMsg(err))
+
+			return
+		}
+
+		voucher := models.Voucher{
+			Code:      code,
+			Name:      req.Name,
+			Value:     req.Value,
+			Balance:   req.Value,
+			ExpiresAt: req.ExpiresAt,
+		}
+		voucher.CreatedByID = &executingUserObj.ID
+
+		vouchers = append(vouchers, voucher)
+	}
+
+	vouchers, err = handler.repo.CreateVouchers(vouchers)
+	if err != nil {
+		_ = c.Error(InternalServerError.WithCauseMsg(err))
+
+		return
+	}
+
+	c.JSON(http.StatusCreated, vouchers)
+}
+
+func (handler *Handler) UpdateVoucherByID(c *gin.Context) {
+	executingUserObj, err := handler.getUserFromContext(c)
+	if err != nil {
+		_ = c.Error(UnableToRetrieveExecutingUser.WithCause(err))
+
+		return
+	}
+
+	if !executingUserObj.Admin {
+		_ = c.Error(Forbidden)
+
+		return
+	}
+
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	var req VoucherUpdateRequest
+	if err := c.ShouldBind(&req); err != nil {
+		_ = c.Error(InvalidRequest.WithCauseMsg(err))
+
+		return
+	}
+
+	voucher := models.Voucher{
+		Name:      req.Name,
+		ExpiresAt: req.ExpiresAt,
+	}
+	voucher.UpdatedByID = &executingUserObj.ID
+
+	updatedVoucher, err := handler.repo.UpdateVoucherByID(id, voucher)
+	if errors.Is(err, sqliteRepo.ErrVoucherNotFound) {
+		_ = c.Error(NotFound.WithCause(err))
+
+		return
+	}
+
+	if err != nil {
+		_ = c.Error(InternalServerError.WithCause(err))
+
+		return
+	}
+
+	c.JSON(http.StatusOK, updatedVoucher)
+}
+
+func (handler *Handler) DeleteVoucherByID(c *gin.Context) {
+	executingUserObj, err := handler.getUserFromContext(c)
+	if err != nil {
+		_ = c.Error(UnableToRetrieveExecutingUser.WithCause(err))
+
+		return
+	}
+
+	if !executingUserObj.Admin {
+		_ = c.Error(Forbidden)
+
+		return
+	}
+
+	id, _ := strconv.Atoi(c.Param("id"))
+
+	voucher, err := handler.repo.GetVoucherByID(id)
+	if err != nil {
+		_ = c.Error(NotFound.WithCause(err))
+
+		return
+	}
+
+	handler.repo.DeleteVoucher(*voucher, *executingUserObj)
+
+	c.Status(http.StatusNoContent)
+}
+
+// PrintVouchers renders the vouchers with the given IDs as a sheet to print and cut out.
+func (handler *Handler) PrintVouchers(c *gin.Context) {
+	if !handler.requireAdmin(c) {
+		return
+	}
+
+	filters := sqliteRepo.VoucherFilters{IDs: queryArrayInt(c, "id")}
+	if len(filters.IDs) == 0 {
+		_ = c.Error(InvalidRequest.WithMsg("At least one voucher ID is required"))
+
+		return
+	}
+
+	vouchers, err := handler.repo.GetVouchers(maxVouchersPerPage, 0, "id", "ASC", filters)
+	if err != nil {
+		_ = c.Error(InternalServerError.WithCauseMsg(err))
+
+		return
+	}
+
+	tpl, err := template.New("vouchers.html").Funcs(template.FuncMap{
+		"formatAmount": func(value decimal.Decimal) string {
+			return value.StringFixed(handler.decimalPlaces)
+		},
+		"formatTime": func(value *time.Time) string {
+			return value.Format("2006-01-02 15:04")
+		},
+	}).ParseFS(templates.VoucherTemplateFiles, voucherTemplate)
+	if err != nil {
+		_ = c.Error(InternalServerError.WithMsg("Failed to parse voucher template").WithCause(err))
+
+		return
+	}
+
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	err = tpl.Execute(c.Writer, map[string]any{
+		"Vouchers": vouchers,
+		"Currency": handler.config.Format.Currency.Code,
+	})
+	if err != nil {
+		_ = c.Error(InternalServerError.WithMsg("Failed to render vouchers").WithCause(err))
+	}
+}
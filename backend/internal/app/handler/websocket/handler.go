@@ -11,7 +11,6 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/potibm/kasseapparat/internal/app/config"
 	"github.com/potibm/kasseapparat/internal/app/models"
-	sumupRepo "github.com/potibm/kasseapparat/internal/app/repository/sumup"
 	purchaseService "github.com/potibm/kasseapparat/internal/app/service/purchase"
 )
 
@@ -32,7 +31,7 @@ func (w *WebsocketPublisher) PushUpdate(purchaseID uuid.UUID, status models.Purc
 }
 
 type Handler struct {
-	sumupRepository  sumupRepo.RepositoryInterface
+	poller           PollerStopper
 	sqliteRepository PurchaseGetter
 	purchaseService  purchaseService.Service
 	upgrader         websocket.Upgrader
@@ -43,9 +42,14 @@ type PurchaseGetter interface {
 	GetPurchaseByID(id uuid.UUID) (*models.Purchase, error)
 }
 
+// PollerStopper stops the polling of a transaction whose checkout was cancelled.
+type PollerStopper interface {
+	Stop(transactionID uuid.UUID)
+}
+
 func NewHandler(
 	sqliteRepository PurchaseGetter,
-	sumupRepository sumupRepo.RepositoryInterface,
+	poller PollerStopper,
 	purchaseSvc purchaseService.Service,
 	jwtMiddleware *jwt.GinJWTMiddleware,
 	corsAllowOrigins *config.CorsAllowOriginsConfig,
@@ -56,7 +60,7 @@ func NewHandler(
 
 	return &Handler{
 		sqliteRepository: sqliteRepository,
-		sumupRepository:  sumupRepository,
+		poller:           poller,
 		purchaseService:  purchaseSvc,
 		upgrader:         upgrader,
 		jwtMiddleware:    jwtMiddleware,
@@ -2,6 +2,7 @@ package websocket
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"net/http"
 	"time"
@@ -10,6 +11,9 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/potibm/kasseapparat/internal/app/middleware"
+	"github.com/potibm/kasseapparat/internal/app/models"
+	purchaseService "github.com/potibm/kasseapparat/internal/app/service/purchase"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 )
@@ -59,7 +63,7 @@ func (h *Handler) HandleTransactionWebSocket(c *gin.Context) {
 		return
 	}
 
-	h.listenAndHandleMessages(conn, transactionID)
+	h.listenAndHandleMessages(conn, transactionID, middleware.UserIDFromClaims(claims))
 }
 
 func (h *Handler) upgradeAndRegister(c *gin.Context) (uuid.UUID, *websocket.Conn, bool) {
@@ -115,7 +119,7 @@ func (h *Handler) sendInitialStatus(conn *websocket.Conn, transactionID uuid.UUI
 	return nil
 }
 
-func (h *Handler) listenAndHandleMessages(conn *websocket.Conn, transactionID uuid.UUID) {
+func (h *Handler) listenAndHandleMessages(conn *websocket.Conn, transactionID uuid.UUID, userID int) {
 	for {
 		ctx := context.Background()
 
@@ -150,8 +154,8 @@ func (h *Handler) listenAndHandleMessages(conn *websocket.Conn, transactionID uu
 		)
 
 		switch msgType {
-		case "cancel_payment":
-			h.handleCancelPayment(conn, msg, transactionID)
+		case "cancel", "cancel_payment":
+			h.handleCancelPayment(conn, msg, transactionID, userID)
 		case "ping":
 			sendWSMessage(conn, "ping_ack", gin.H{}, transactionID)
 
@@ -161,18 +165,33 @@ func (h *Handler) listenAndHandleMessages(conn *websocket.Conn, transactionID uu
 	}
 }
 
-func (h *Handler) handleCancelPayment(conn *websocket.Conn, msg map[string]any, transactionID uuid.UUID) {
-	readerID, ok := msg["reader_id"].(string)
-	if !ok || readerID == "" {
-		sendWSMessage(conn, "error", gin.H{"message": "reader_id missing or invalid"}, transactionID)
+// handleCancelPayment cancels the card payment on the terminal and the purchase. The reader is
+// the one the checkout was started on, unless the message names one.
+func (h *Handler) handleCancelPayment(conn *websocket.Conn, msg map[string]any, transactionID uuid.UUID, userID int) {
+	readerID, _ := msg["reader_id"].(string)
 
-		return
+	var actorID *int
+	if userID != 0 {
+		actorID = &userID
 	}
 
-	err := h.sumupRepository.CreateReaderTerminateAction(readerID)
-	if err != nil {
+	ctx := purchaseService.WithTransitionOrigin(context.Background(), models.PurchaseTransitionSourceUser, actorID)
+
+	purchase, err := h.purchaseService.CancelTerminalCheckout(ctx, transactionID, readerID)
+	if purchase != nil && purchase.Status != models.PurchaseStatusPending {
+		h.poller.Stop(transactionID)
+		PushUpdate(transactionID, purchase.Status)
+	}
+
+	switch {
+	case errors.Is(err, purchaseService.ErrPaymentAlreadySucceeded):
+		sendWSMessage(conn, "error", gin.H{"message": "payment already succeeded"}, transactionID)
+	case errors.Is(err, purchaseService.ErrPurchaseNotPending):
+		sendWSMessage(conn, "error", gin.H{"message": "purchase is not pending"}, transactionID)
+	case err != nil:
+		slog.Warn("Failed to cancel payment", "transaction_id", transactionID.String(), "error", err)
 		sendWSMessage(conn, "error", gin.H{"message": "failed to cancel payment"}, transactionID)
-	} else {
+	default:
 		sendWSMessage(conn, "cancel_ack", gin.H{"transaction_id": transactionID}, transactionID)
 	}
 }
@@ -12,7 +12,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/potibm/kasseapparat/internal/app/models"
-	"github.com/potibm/kasseapparat/internal/app/repository/sumup"
+	purchaseService "github.com/potibm/kasseapparat/internal/app/service/purchase"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -35,25 +35,40 @@ func (m *mockSqliteRepo) GetPurchaseByID(id uuid.UUID) (*models.Purchase, error)
 	return purchase, args.Error(1)
 }
 
-// Mock for the SumUp Repository.
-type mockSumupRepo struct {
-	sumup.RepositoryInterface
+// Mock for the purchase service.
+type mockPurchaseService struct {
+	purchaseService.Service
 	mock.Mock
 }
 
-func (m *mockSumupRepo) CreateReaderTerminateAction(readerID string) error {
-	args := m.Called(readerID)
+func (m *mockPurchaseService) CancelTerminalCheckout(
+	ctx context.Context,
+	id uuid.UUID,
+	readerID string,
+) (*models.Purchase, error) {
+	args := m.Called(id, readerID)
 
-	return args.Error(0)
+	purchase, _ := args.Get(0).(*models.Purchase)
+
+	return purchase, args.Error(1)
+}
+
+// Mock for the transaction poller.
+type mockPoller struct {
+	mock.Mock
+}
+
+func (m *mockPoller) Stop(transactionID uuid.UUID) {
+	m.Called(transactionID)
 }
 
 // --- TEST SETUP ---
 
-func setupTestServer(t *testing.T) (*Handler, *httptest.Server, *mockSqliteRepo, *mockSumupRepo, string) {
+func setupTestServer(t *testing.T) (*Handler, *httptest.Server, *mockSqliteRepo, *mockPurchaseService, string) {
 	gin.SetMode(gin.TestMode)
 
 	mockSqlite := new(mockSqliteRepo)
-	mockSumup := new(mockSumupRepo)
+	mockService := new(mockPurchaseService)
 
 	// Real JWT Middleware Setup to generate valid tokens for testing
 	jwtMid, err := jwt.New(&jwt.GinJWTMiddleware{
@@ -69,7 +84,7 @@ func setupTestServer(t *testing.T) (*Handler, *httptest.Server, *mockSqliteRepo,
 	handler := &Handler{
 		jwtMiddleware:    jwtMid,
 		sqliteRepository: mockSqlite,
-		sumupRepository:  mockSumup,
+		purchaseService:  mockService,
 		upgrader: websocket.Upgrader{
 			// Allow testing from any origin.
 			CheckOrigin: func(r *http.Request) bool { return true },
@@ -81,7 +96,7 @@ func setupTestServer(t *testing.T) (*Handler, *httptest.Server, *mockSqliteRepo,
 
 	server := httptest.NewServer(router)
 
-	return handler, server, mockSqlite, mockSumup, token.AccessToken
+	return handler, server, mockSqlite, mockService, token.AccessToken
 }
 
 func TestHandleTransactionWebSocketAuthFailures(t *testing.T) {
@@ -122,7 +137,7 @@ func TestHandleTransactionWebSocketInvalidUUID(t *testing.T) {
 }
 
 func TestHandleTransactionWebSocketHappyPath(t *testing.T) {
-	_, server, mockSqlite, mockSumup, validToken := setupTestServer(t)
+	handler, server, mockSqlite, mockService, validToken := setupTestServer(t)
 	defer server.Close()
 
 	transactionID := uuid.New()
@@ -132,7 +147,12 @@ func TestHandleTransactionWebSocketHappyPath(t *testing.T) {
 	// Initial status call
 	mockSqlite.On("GetPurchaseByID", transactionID).Return(&models.Purchase{Status: "pending"}, nil)
 	// Cancel Payment Call
-	mockSumup.On("CreateReaderTerminateAction", "reader-123").Return(nil)
+	mockService.On("CancelTerminalCheckout", transactionID, "reader-123").
+		Return(&models.Purchase{Status: models.PurchaseStatusCancelled}, nil)
+
+	poller := new(mockPoller)
+	poller.On("Stop", transactionID).Return()
+	handler.poller = poller
 
 	// 2. Setup connection
 	headers := http.Header{secWebsocketProtocol: []string{validToken}}
@@ -168,6 +188,13 @@ func TestHandleTransactionWebSocketHappyPath(t *testing.T) {
 	})
 	require.NoError(t, err)
 
+	var statusUpdate map[string]interface{}
+
+	err = conn.ReadJSON(&statusUpdate)
+	require.NoError(t, err)
+	assert.Equal(t, "status_update", statusUpdate["type"])
+	assert.Equal(t, "cancelled", statusUpdate["status"])
+
 	var cancelAck map[string]interface{}
 
 	err = conn.ReadJSON(&cancelAck)
@@ -175,5 +202,49 @@ func TestHandleTransactionWebSocketHappyPath(t *testing.T) {
 	assert.Equal(t, "cancel_ack", cancelAck["type"])
 
 	mockSqlite.AssertExpectations(t)
-	mockSumup.AssertExpectations(t)
+	mockService.AssertExpectations(t)
+	poller.AssertExpectations(t)
+}
+
+func TestHandleTransactionWebSocketCancelAfterPaymentSucceeded(t *testing.T) {
+	handler, server, mockSqlite, mockService, validToken := setupTestServer(t)
+	defer server.Close()
+
+	transactionID := uuid.New()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws/" + transactionID.String()
+
+	mockSqlite.On("GetPurchaseByID", transactionID).Return(&models.Purchase{Status: "pending"}, nil)
+	mockService.On("CancelTerminalCheckout", transactionID, "").
+		Return(&models.Purchase{Status: models.PurchaseStatusConfirmed}, purchaseService.ErrPaymentAlreadySucceeded)
+
+	poller := new(mockPoller)
+	poller.On("Stop", transactionID).Return()
+	handler.poller = poller
+
+	headers := http.Header{secWebsocketProtocol: []string{validToken}}
+	conn, resp, err := websocket.DefaultDialer.Dial(wsURL, headers)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	defer conn.Close()
+
+	var initialMsg map[string]interface{}
+
+	require.NoError(t, conn.ReadJSON(&initialMsg))
+
+	require.NoError(t, conn.WriteJSON(map[string]interface{}{"type": "cancel"}))
+
+	var statusUpdate map[string]interface{}
+
+	require.NoError(t, conn.ReadJSON(&statusUpdate))
+	assert.Equal(t, "confirmed", statusUpdate["status"])
+
+	var errorMsg map[string]interface{}
+
+	require.NoError(t, conn.ReadJSON(&errorMsg))
+	assert.Equal(t, "error", errorMsg["type"])
+	assert.Equal(t, "payment already succeeded", errorMsg["message"])
+
+	mockService.AssertExpectations(t)
+	poller.AssertExpectations(t)
 }
@@ -84,8 +84,8 @@ func CreateCorsMiddleware(allowedOrigins []string) gin.HandlerFunc {
 	corsConfig.AllowOrigins = allowedOrigins
 	corsConfig.AllowAllOrigins = false
 	corsConfig.AllowCredentials = true
-	corsConfig.AddAllowHeaders("Authorization", "Credentials")
-	corsConfig.AddExposeHeaders("X-Total-Count", "Content-Disposition")
+	corsConfig.AddAllowHeaders("Authorization", "Credentials", httpHandler.IdempotencyKeyHeader)
+	corsConfig.AddExposeHeaders("X-Total-Count", "Content-Disposition", httpHandler.IdempotentReplayedHeader)
 
 	return cors.New(corsConfig)
 }
@@ -140,17 +140,26 @@ func registerAPIRoutes(
 	{
 		registerProductRoutes(protectedAPIRouter, httpHdlr)
 		registerProductInterestRoutes(protectedAPIRouter, httpHdlr)
+		registerCategoryRoutes(protectedAPIRouter, httpHdlr)
 		protectedAPIRouter.GET("/productStats", httpHdlr.GetProductStats)
+		protectedAPIRouter.GET("/categoryStats", httpHdlr.GetCategoryStats)
+		protectedAPIRouter.GET("/stockCounts", httpHdlr.GetStockCounts)
 
 		registerGuestlistRoutes(protectedAPIRouter, httpHdlr)
 		registerGuestRoutes(protectedAPIRouter, httpHdlr)
 		protectedAPIRouter.POST("/guestsUpload", httpHdlr.ImportGuestsFromDeineTicketsCsv)
 
 		registerPurchaseRoutes(protectedAPIRouter, httpHdlr)
+		registerRegisterSessionRoutes(protectedAPIRouter, httpHdlr)
+		registerClosingReportRoutes(protectedAPIRouter, httpHdlr)
 		registerUserRoutes(protectedAPIRouter, httpHdlr)
+		registerVoucherRoutes(protectedAPIRouter, httpHdlr)
+		registerDiscountRoutes(protectedAPIRouter, httpHdlr)
 
 		registerSumupReadersRoutes(protectedAPIRouter, httpHdlr)
 		registerSumupTransactionRoutes(protectedAPIRouter, httpHdlr)
+		protectedAPIRouter.POST("/sumup/reconciliation", httpHdlr.ReconcileSumupTransactions)
+		protectedAPIRouter.GET("/terminals", httpHdlr.GetTerminals)
 	}
 
 	// unprotected routes
@@ -173,6 +182,8 @@ func registerProductRoutes(rg *gin.RouterGroup, handler httpHandler.Handler) {
 		products.GET("", handler.GetProducts)
 		products.GET("/:id", handler.GetProductByID)
 		products.GET("/:id/guests", handler.GetGuestsByProductID)
+		products.GET("/:id/inventoryMovements", handler.GetInventoryMovementsByProductID)
+		products.POST("/:id/inventoryMovements", handler.CreateInventoryMovement)
 		products.PUT("/:id", handler.UpdateProductByID)
 		products.DELETE("/:id", handler.DeleteProductByID)
 		products.POST("", handler.CreateProduct)
@@ -209,10 +220,37 @@ func registerPurchaseRoutes(
 	{
 		purchases.GET("", handler.GetPurchases)
 		purchases.GET("/:id", handler.GetPurchaseByID)
-		purchases.POST("", handler.PostPurchases)
-		purchases.DELETE("/:id", handler.DeletePurchase)
+		purchases.POST("", handler.Idempotent(), handler.PostPurchases)
+		purchases.DELETE("/:id", handler.Idempotent(), handler.DeletePurchase)
+		purchases.POST("/quote", handler.QuotePurchase)
+		purchases.POST("/sync", handler.SyncOfflinePurchases)
 		purchases.GET("/export", handler.ExportPurchases)
-		purchases.POST("/:id/refund", handler.RefundPurchase)
+		purchases.GET("/:id/receipt", handler.GetPurchaseReceipt)
+		purchases.POST("/:id/receipt/mail", handler.SendPurchaseReceiptMail)
+		purchases.POST("/:id/cancel", handler.CancelPurchaseCheckout)
+		purchases.POST("/:id/refund", handler.Idempotent(), handler.RefundPurchase)
+		purchases.POST("/:id/refunds", handler.Idempotent(), handler.RefundPurchaseItems)
+		purchases.POST("/:id/refunds/:refundId/settle", handler.SettlePurchaseRefund)
+	}
+}
+
+func registerRegisterSessionRoutes(rg *gin.RouterGroup, handler httpHandler.Handler) {
+	registerSessions := rg.Group("/registerSessions")
+	{
+		registerSessions.GET("", handler.GetRegisterSessions)
+		registerSessions.GET("/current", handler.GetCurrentRegisterSession)
+		registerSessions.GET("/:id", handler.GetRegisterSessionByID)
+		registerSessions.POST("", handler.OpenRegisterSession)
+		registerSessions.POST("/:id/close", handler.CloseRegisterSession)
+	}
+}
+
+func registerClosingReportRoutes(rg *gin.RouterGroup, handler httpHandler.Handler) {
+	closingReports := rg.Group("/closingReports")
+	{
+		closingReports.GET("", handler.GetClosingReports)
+		closingReports.GET("/:id", handler.GetClosingReportByID)
+		closingReports.POST("", handler.CreateClosingReport)
 	}
 }
 
@@ -227,6 +265,40 @@ func registerUserRoutes(rg *gin.RouterGroup, handler httpHandler.Handler) {
 	}
 }
 
+func registerVoucherRoutes(rg *gin.RouterGroup, handler httpHandler.Handler) {
+	vouchers := rg.Group("/vouchers")
+	{
+		vouchers.GET("", handler.GetVouchers)
+		vouchers.GET("/print", handler.PrintVouchers)
+		vouchers.GET("/:id", handler.GetVoucherByID)
+		vouchers.POST("", handler.CreateVouchers)
+		vouchers.PUT("/:id", handler.UpdateVoucherByID)
+		vouchers.DELETE("/:id", handler.DeleteVoucherByID)
+	}
+}
+
+func registerDiscountRoutes(rg *gin.RouterGroup, handler httpHandler.Handler) {
+	discounts := rg.Group("/discounts")
+	{
+		discounts.GET("", handler.GetDiscounts)
+		discounts.GET("/:id", handler.GetDiscountByID)
+		discounts.POST("", handler.CreateDiscount)
+		discounts.PUT("/:id", handler.UpdateDiscountByID)
+		discounts.DELETE("/:id", handler.DeleteDiscountByID)
+	}
+}
+
+func registerCategoryRoutes(rg *gin.RouterGroup, handler httpHandler.Handler) {
+	categories := rg.Group("/categories")
+	{
+		categories.GET("", handler.GetCategories)
+		categories.GET("/:id", handler.GetCategoryByID)
+		categories.POST("", handler.CreateCategory)
+		categories.PUT("/:id", handler.UpdateCategoryByID)
+		categories.DELETE("/:id", handler.DeleteCategoryByID)
+	}
+}
+
 func registerProductInterestRoutes(rg *gin.RouterGroup, handler httpHandler.Handler) {
 	productInterests := rg.Group("/productInterests")
 	{
@@ -0,0 +1,24 @@
+package initializer
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/potibm/kasseapparat/internal/app/config"
+	sumupRepo "github.com/potibm/kasseapparat/internal/app/repository/sumup"
+	"github.com/potibm/kasseapparat/internal/app/repository/terminal"
+)
+
+// InitializePaymentTerminal returns the provider of the card terminals selected in the configuration.
+func InitializePaymentTerminal(
+	terminalConfig config.TerminalConfig,
+	sumupRepository sumupRepo.RepositoryInterface,
+) terminal.PaymentTerminalProvider {
+	if terminalConfig.Provider == terminal.ProviderFake {
+		slog.Warn("Using the fake payment terminal provider, card payments are only simulated")
+
+		return terminal.NewFakeProvider(time.Duration(terminalConfig.FakeDelaySeconds) * time.Second)
+	}
+
+	return terminal.NewSumupProvider(sumupRepository)
+}
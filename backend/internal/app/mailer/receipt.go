@@ -0,0 +1,35 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+
+	"github.com/potibm/kasseapparat/templates"
+)
+
+const (
+	purchaseReceiptSubject = "Your receipt"
+)
+
+func (mailer *Mailer) SendPurchaseReceiptMail(to, receipt string) error {
+	tpl, err := template.ParseFS(
+		templates.MailTemplateFiles,
+		"mail/purchase_receipt.txt",
+		footerTemplate,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to parse email template: %w", err)
+	}
+
+	var body bytes.Buffer
+
+	err = tpl.Execute(&body, map[string]string{
+		"Receipt": receipt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to execute email template: %w", err)
+	}
+
+	return mailer.SendMail(to, purchaseReceiptSubject, body.String())
+}
@@ -39,6 +39,11 @@ func extractUserID(data any) int {
 	}
 }
 
+// UserIDFromClaims returns the ID of the user the claims of a token were issued for, or 0.
+func UserIDFromClaims(claims map[string]interface{}) int {
+	return extractIDFromClaims(claims)
+}
+
 func extractIDFromClaims(claims map[string]interface{}) int {
 	if val, exists := claims["id"]; exists {
 		if id, valid := extractInt(val); valid {
@@ -29,8 +29,10 @@ func ErrorHandlingMiddleware() gin.HandlerFunc {
 		// capture the error in Sentry
 		captureError(hub, err)
 
-		// return error response to the client
-		writeErrorResponse(c, err)
+		// return error response to the client, unless it was written by a handler keeping it
+		if !c.Writer.Written() {
+			WriteErrorResponse(c, err)
+		}
 	}
 }
 
@@ -51,7 +53,9 @@ func captureError(hub *sentry.Hub, err error) {
 	hub.CaptureException(err)
 }
 
-func writeErrorResponse(c *gin.Context, err error) {
+// WriteErrorResponse writes the response of the error, an HTTPError with its status code and
+// message, any other error as an internal server error.
+func WriteErrorResponse(c *gin.Context, err error) {
 	if httpErr, ok := err.(HTTPError); ok {
 		response := gin.H{
 			"error": httpErr.Error(),
@@ -0,0 +1,27 @@
+package models
+
+// Category groups products, e.g. tickets, drinks or merchandise. Users can be restricted to
+// categories, so a till only lists the products of its categories.
+type Category struct {
+	GormOwnedModel
+
+	Name  string `json:"name"`
+	Pos   int    `json:"pos"   gorm:"default:0"`
+	Color string `json:"color"`
+	Icon  string `json:"icon"`
+}
+
+// CategoryIDs returns the IDs of the categories the user is restricted to. Admins and users without
+// categories are not restricted, for them the result is empty.
+func (u User) CategoryIDs() []int {
+	if u.Admin {
+		return nil
+	}
+
+	ids := make([]int, 0, len(u.Categories))
+	for _, category := range u.Categories {
+		ids = append(ids, category.ID)
+	}
+
+	return ids
+}
@@ -0,0 +1,272 @@
+package models
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+var ErrClosingReportImmutable = errors.New("closing reports cannot be changed")
+
+// ClosingReport (Z-report) holds the figures of a period as they were at the time the report
+// was generated. Reports are numbered consecutively and cannot be changed afterwards.
+type ClosingReport struct {
+	GormOwnedModel
+
+	Number      uint                 `json:"number"      gorm:"uniqueIndex"`
+	PeriodStart time.Time            `json:"periodStart" gorm:"index"`
+	PeriodEnd   time.Time            `json:"periodEnd"   gorm:"index"`
+	Figures     ClosingReportFigures `json:"figures"     gorm:"type:TEXT;serializer:json"`
+}
+
+// ClosingReportFigures are the totals of a period. Sales are booked on the time of the purchase,
+// refunds on the time of the refund. The breakdowns show the figures after refunds.
+type ClosingReportFigures struct {
+	PurchaseCount   int                          `json:"purchaseCount"`
+	TotalNetPrice   decimal.Decimal              `json:"totalNetPrice"`
+	TotalVATAmount  decimal.Decimal              `json:"totalVatAmount"`
+	TotalGrossPrice decimal.Decimal              `json:"totalGrossPrice"`
+	VATRates        []ClosingReportVATRate       `json:"vatRates"`
+	PaymentMethods  []ClosingReportPaymentMethod `json:"paymentMethods"`
+	Products        []ClosingReportProduct       `json:"products"`
+	Refunds         ClosingReportRefunds         `json:"refunds"`
+	Cancellations   ClosingReportCancellations   `json:"cancellations"`
+	Offline         ClosingReportOffline         `json:"offline"`
+}
+
+type ClosingReportVATRate struct {
+	VATRate            decimal.Decimal `json:"vatRate"`
+	NetPrice           decimal.Decimal `json:"netPrice"`
+	VATAmount          decimal.Decimal `json:"vatAmount"`
+	GrossPrice         decimal.Decimal `json:"grossPrice"`
+	RefundedGrossPrice decimal.Decimal `json:"refundedGrossPrice"`
+}
+
+// ClosingReportPaymentMethod is the amount taken with a payment method. The rounding of the amounts
+// paid is not part of the amount, which adds up to the gross price of the sales.
+type ClosingReportPaymentMethod struct {
+	PaymentMethod  PaymentMethod   `json:"paymentMethod"`
+	Amount         decimal.Decimal `json:"amount"`
+	RefundedAmount decimal.Decimal `json:"refundedAmount"`
+	RoundingAmount decimal.Decimal `json:"roundingAmount"`
+}
+
+type ClosingReportProduct struct {
+	ProductID        int             `json:"productId"`
+	Name             string          `json:"name"`
+	Quantity         int             `json:"quantity"`
+	RefundedQuantity uint            `json:"refundedQuantity"`
+	NetPrice         decimal.Decimal `json:"netPrice"`
+	GrossPrice       decimal.Decimal `json:"grossPrice"`
+}
+
+type ClosingReportRefunds struct {
+	Count      int             `json:"count"`
+	NetPrice   decimal.Decimal `json:"netPrice"`
+	GrossPrice decimal.Decimal `json:"grossPrice"`
+}
+
+type ClosingReportCancellations struct {
+	Count      int             `json:"count"`
+	GrossPrice decimal.Decimal `json:"grossPrice"`
+}
+
+// ClosingReportOffline are the purchases recorded by a client while it was offline, which are
+// included in the figures above.
+type ClosingReportOffline struct {
+	Count      int             `json:"count"`
+	GrossPrice decimal.Decimal `json:"grossPrice"`
+}
+
+func (r *ClosingReport) BeforeUpdate(tx *gorm.DB) error {
+	return ErrClosingReportImmutable
+}
+
+func (r *ClosingReport) BeforeDelete(tx *gorm.DB) error {
+	return ErrClosingReportImmutable
+}
+
+// ClosingReportBuilder sums up purchases, refunds and cancellations to the figures of a report.
+type ClosingReportBuilder struct {
+	decimalPlaces  int32
+	figures        ClosingReportFigures
+	vatRates       map[string]*ClosingReportVATRate
+	paymentMethods map[PaymentMethod]*ClosingReportPaymentMethod
+	products       map[int]*ClosingReportProduct
+}
+
+func NewClosingReportBuilder(decimalPlaces int32) *ClosingReportBuilder {
+	return &ClosingReportBuilder{
+		decimalPlaces: decimalPlaces,
+		figures: ClosingReportFigures{
+			TotalNetPrice:   decimal.Zero,
+			TotalVATAmount:  decimal.Zero,
+			TotalGrossPrice: decimal.Zero,
+			Refunds:         ClosingReportRefunds{NetPrice: decimal.Zero, GrossPrice: decimal.Zero},
+			Cancellations:   ClosingReportCancellations{GrossPrice: decimal.Zero},
+			Offline:         ClosingReportOffline{GrossPrice: decimal.Zero},
+		},
+		vatRates:       make(map[string]*ClosingReportVATRate),
+		paymentMethods: make(map[PaymentMethod]*ClosingReportPaymentMethod),
+		products:       make(map[int]*ClosingReportProduct),
+	}
+}
+
+// AddPurchase adds a confirmed (or since refunded) purchase with its items and payment lines.
+func (b *ClosingReportBuilder) AddPurchase(purchase Purchase) {
+	b.figures.PurchaseCount++
+
+	if purchase.Offline {
+		b.figures.Offline.Count++
+		b.figures.Offline.GrossPrice = b.figures.Offline.GrossPrice.Add(purchase.TotalGrossPrice)
+	}
+
+	for _, item := range purchase.PurchaseItems {
+		net := item.TotalDiscountedNetPrice(b.decimalPlaces)
+		gross := item.TotalDiscountedGrossPrice(b.decimalPlaces)
+
+		vatRate := b.vatRate(item.VATRate)
+		vatRate.NetPrice = vatRate.NetPrice.Add(net)
+		vatRate.GrossPrice = vatRate.GrossPrice.Add(gross)
+
+		product := b.product(item.ProductID, item.Product.Name)
+		product.Quantity += int(item.Quantity)
+		product.NetPrice = product.NetPrice.Add(net)
+		product.GrossPrice = product.GrossPrice.Add(gross)
+	}
+
+	for _, payment := range purchase.PaymentLines() {
+		method := b.paymentMethod(payment.PaymentMethod)
+		method.Amount = method.Amount.Add(payment.Amount)
+	}
+
+	// the rounding of a refund is added with the refund
+	for _, rounding := range purchase.RoundingLines() {
+		method := b.paymentMethod(rounding.PaymentMethod)
+		method.RoundingAmount = method.RoundingAmount.Add(rounding.Amount)
+	}
+}
+
+// AddRefund adds a refund. The purchase items of the refund lines are looked up by their ID.
+func (b *ClosingReportBuilder) AddRefund(refund PurchaseRefund, purchaseItems map[int]PurchaseItem) {
+	b.figures.Refunds.Count++
+	b.figures.Refunds.NetPrice = b.figures.Refunds.NetPrice.Add(refund.TotalNetPrice)
+	b.figures.Refunds.GrossPrice = b.figures.Refunds.GrossPrice.Add(refund.TotalGrossPrice)
+
+	for _, item := range refund.Items {
+		net := item.TotalNetPrice(b.decimalPlaces)
+		gross := item.TotalGrossPrice(b.decimalPlaces)
+
+		vatRate := b.vatRate(item.VATRate)
+		vatRate.NetPrice = vatRate.NetPrice.Sub(net)
+		vatRate.GrossPrice = vatRate.GrossPrice.Sub(gross)
+		vatRate.RefundedGrossPrice = vatRate.RefundedGrossPrice.Add(gross)
+
+		purchaseItem := purchaseItems[item.PurchaseItemID]
+
+		product := b.product(purchaseItem.ProductID, purchaseItem.Product.Name)
+		product.Quantity -= int(item.Quantity)
+		product.RefundedQuantity += item.Quantity
+		product.NetPrice = product.NetPrice.Sub(net)
+		product.GrossPrice = product.GrossPrice.Sub(gross)
+	}
+
+	for _, payment := range refund.Payments {
+		method := b.paymentMethod(payment.PaymentMethod)
+		method.Amount = method.Amount.Sub(payment.Amount)
+		method.RefundedAmount = method.RefundedAmount.Add(payment.Amount)
+	}
+
+	for _, rounding := range refund.Roundings {
+		method := b.paymentMethod(rounding.PaymentMethod)
+		method.RoundingAmount = method.RoundingAmount.Add(rounding.Amount)
+	}
+}
+
+// AddCancellation adds a purchase that has been cancelled before it was paid.
+func (b *ClosingReportBuilder) AddCancellation(purchase Purchase) {
+	b.figures.Cancellations.Count++
+	b.figures.Cancellations.GrossPrice = b.figures.Cancellations.GrossPrice.Add(purchase.TotalGrossPrice)
+}
+
+// Figures returns the figures with the breakdowns sorted by VAT rate, payment method and product.
+func (b *ClosingReportBuilder) Figures() ClosingReportFigures {
+	figures := b.figures
+	figures.VATRates = make([]ClosingReportVATRate, 0, len(b.vatRates))
+	figures.PaymentMethods = make([]ClosingReportPaymentMethod, 0, len(b.paymentMethods))
+	figures.Products = make([]ClosingReportProduct, 0, len(b.products))
+
+	for _, vatRate := range b.vatRates {
+		vatRate.VATAmount = vatRate.GrossPrice.Sub(vatRate.NetPrice)
+		figures.VATRates = append(figures.VATRates, *vatRate)
+
+		figures.TotalNetPrice = figures.TotalNetPrice.Add(vatRate.NetPrice)
+		figures.TotalVATAmount = figures.TotalVATAmount.Add(vatRate.VATAmount)
+		figures.TotalGrossPrice = figures.TotalGrossPrice.Add(vatRate.GrossPrice)
+	}
+
+	for _, method := range b.paymentMethods {
+		figures.PaymentMethods = append(figures.PaymentMethods, *method)
+	}
+
+	for _, product := range b.products {
+		figures.Products = append(figures.Products, *product)
+	}
+
+	slices.SortFunc(figures.VATRates, func(a, b ClosingReportVATRate) int {
+		return a.VATRate.Cmp(b.VATRate)
+	})
+	slices.SortFunc(figures.PaymentMethods, func(a, b ClosingReportPaymentMethod) int {
+		return cmp.Compare(a.PaymentMethod, b.PaymentMethod)
+	})
+	slices.SortFunc(figures.Products, func(a, b ClosingReportProduct) int {
+		return cmp.Compare(a.ProductID, b.ProductID)
+	})
+
+	return figures
+}
+
+func (b *ClosingReportBuilder) vatRate(rate decimal.Decimal) *ClosingReportVATRate {
+	key := rate.String()
+	if _, ok := b.vatRates[key]; !ok {
+		b.vatRates[key] = &ClosingReportVATRate{
+			VATRate:            rate,
+			NetPrice:           decimal.Zero,
+			VATAmount:          decimal.Zero,
+			GrossPrice:         decimal.Zero,
+			RefundedGrossPrice: decimal.Zero,
+		}
+	}
+
+	return b.vatRates[key]
+}
+
+func (b *ClosingReportBuilder) paymentMethod(paymentMethod PaymentMethod) *ClosingReportPaymentMethod {
+	if _, ok := b.paymentMethods[paymentMethod]; !ok {
+		b.paymentMethods[paymentMethod] = &ClosingReportPaymentMethod{
+			PaymentMethod:  paymentMethod,
+			Amount:         decimal.Zero,
+			RefundedAmount: decimal.Zero,
+			RoundingAmount: decimal.Zero,
+		}
+	}
+
+	return b.paymentMethods[paymentMethod]
+}
+
+func (b *ClosingReportBuilder) product(productID int, name string) *ClosingReportProduct {
+	if _, ok := b.products[productID]; !ok {
+		b.products[productID] = &ClosingReportProduct{
+			ProductID:  productID,
+			Name:       name,
+			NetPrice:   decimal.Zero,
+			GrossPrice: decimal.Zero,
+		}
+	}
+
+	return b.products[productID]
+}
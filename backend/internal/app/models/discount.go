@@ -0,0 +1,171 @@
+package models
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type DiscountType string
+
+const (
+	DiscountTypePercentage DiscountType = "percentage"
+	DiscountTypeFixed      DiscountType = "fixed"
+)
+
+var (
+	ErrInvalidDiscountType  = errors.New("invalid discount type")
+	ErrInvalidDiscountValue = errors.New("discount value must be positive and a percentage must not exceed 100")
+)
+
+const hundredPercent = 100
+
+// Discount is a discount rule. A rule with a product reduces the price of every unit of that
+// product, a rule without a product reduces the cart total. Rules with a code are applied by
+// entering the code, every rule can be applied manually by users allowed to grant discounts.
+type Discount struct {
+	GormOwnedModel
+
+	Name      string          `json:"name"`
+	Code      *string         `json:"code"      gorm:"uniqueIndex"`
+	Type      DiscountType    `json:"type"      gorm:"type:TEXT"`
+	Value     decimal.Decimal `json:"value"     gorm:"type:TEXT"`
+	ProductID *int            `json:"productId" gorm:"index"`
+	Product   *Product        `json:"product"   gorm:"foreignKey:ProductID"`
+}
+
+// PurchaseDiscount is a discount line of a purchase. A discount is split across the purchase items
+// it applies to, so every line carries the VAT rate of its item and the VAT figures stay correct.
+// The amounts are the reduction and therefore positive.
+type PurchaseDiscount struct {
+	GormModel
+
+	PurchaseID     uuid.UUID       `json:"purchaseId"     gorm:"type:text;index"`
+	PurchaseItemID int             `json:"purchaseItemId" gorm:"index"`
+	DiscountID     *int            `json:"discountId"     gorm:"index"`
+	Name           string          `json:"name"`
+	Code           *string         `json:"code"`
+	VATRate        decimal.Decimal `json:"vatRate"        gorm:"type:TEXT"`
+	NetAmount      decimal.Decimal `json:"netAmount"      gorm:"type:TEXT"`
+	GrossAmount    decimal.Decimal `json:"grossAmount"    gorm:"type:TEXT"`
+}
+
+// NormalizeDiscountCode brings an entered code into the stored form, so codes are case insensitive.
+func NormalizeDiscountCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func (d Discount) Validate() error {
+	if d.Type != DiscountTypePercentage && d.Type != DiscountTypeFixed {
+		return ErrInvalidDiscountType
+	}
+
+	if !d.Value.IsPositive() {
+		return ErrInvalidDiscountValue
+	}
+
+	if d.Type == DiscountTypePercentage && d.Value.GreaterThan(decimal.NewFromInt(hundredPercent)) {
+		return ErrInvalidDiscountValue
+	}
+
+	return nil
+}
+
+// ApplyTo adds the discount lines of the discount to the items it applies to and reports whether
+// the discount applied to any item. Discounts are applied on the prices after earlier discounts and
+// never reduce an item below zero. The reduction is split across the items by their gross price,
+// the last item absorbs rounding differences.
+func (d Discount) ApplyTo(items []PurchaseItem, decimalPlaces int32) bool {
+	var targets []int
+
+	base := decimal.Zero
+
+	for i, item := range items {
+		if d.ProductID != nil && item.ProductID != *d.ProductID {
+			continue
+		}
+
+		price := item.TotalDiscountedGrossPrice(decimalPlaces)
+		if !price.IsPositive() {
+			continue
+		}
+
+		targets = append(targets, i)
+		base = base.Add(price)
+	}
+
+	if len(targets) == 0 {
+		return false
+	}
+
+	remaining := d.grossAmount(items, targets, base, decimalPlaces)
+
+	for n, i := range targets {
+		price := items[i].TotalDiscountedGrossPrice(decimalPlaces)
+
+		gross := remaining
+		if n < len(targets)-1 {
+			gross = remaining.Mul(price).Div(base).Round(decimalPlaces)
+		}
+
+		gross = decimal.Min(gross, price)
+
+		base = base.Sub(price)
+		remaining = remaining.Sub(gross)
+
+		if !gross.IsPositive() {
+			continue
+		}
+
+		items[i].Discounts = append(items[i].Discounts, d.line(items[i], gross, decimalPlaces))
+	}
+
+	return true
+}
+
+// grossAmount returns the total reduction of the discount. A fixed amount of a product discount
+// is taken off every unit.
+func (d Discount) grossAmount(
+	items []PurchaseItem,
+	targets []int,
+	base decimal.Decimal,
+	decimalPlaces int32,
+) decimal.Decimal {
+	if d.Type == DiscountTypePercentage {
+		return base.Mul(d.Value).Div(decimal.NewFromInt(hundredPercent)).Round(decimalPlaces)
+	}
+
+	if d.ProductID == nil {
+		return decimal.Min(d.Value, base)
+	}
+
+	var quantity uint
+	for _, i := range targets {
+		quantity += items[i].Quantity
+	}
+
+	return decimal.Min(d.Value.Mul(decimal.NewFromUint64(uint64(quantity))), base)
+}
+
+func (d Discount) line(item PurchaseItem, gross decimal.Decimal, decimalPlaces int32) PurchaseDiscount {
+	net := gross.Mul(decimal.NewFromInt(hundredPercent)).
+		Div(item.VATRate.Add(decimal.NewFromInt(hundredPercent))).
+		Round(decimalPlaces)
+
+	line := PurchaseDiscount{
+		PurchaseID:  item.PurchaseID,
+		Name:        d.Name,
+		Code:        d.Code,
+		VATRate:     item.VATRate,
+		NetAmount:   net,
+		GrossAmount: gross,
+	}
+
+	if d.ID != 0 {
+		line.DiscountID = &d.ID
+	}
+
+	return line
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// IdempotencyKey is a key sent by a client with a request it may repeat, stored with the response
+// to the request. A repeated request with the key gets the stored response instead of being
+// handled again. The status code is zero while the first request is being handled.
+type IdempotencyKey struct {
+	ID           int       `json:"id"          gorm:"primarykey"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UserID       int       `json:"userId"      gorm:"uniqueIndex:idx_idempotency_keys_user_key"`
+	Key          string    `json:"key"         gorm:"uniqueIndex:idx_idempotency_keys_user_key"`
+	RequestHash  string    `json:"requestHash"`
+	StatusCode   int       `json:"statusCode"`
+	ContentType  string    `json:"contentType"`
+	ResponseBody string    `json:"-"           gorm:"type:TEXT"`
+	ExpiresAt    time.Time `json:"expiresAt"   gorm:"index"`
+}
+
+func (k IdempotencyKey) Completed() bool {
+	return k.StatusCode != 0
+}
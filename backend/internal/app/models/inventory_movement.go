@@ -0,0 +1,57 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrInventoryMovementImmutable = errors.New("inventory movements cannot be changed")
+
+type InventoryMovementType string
+
+const (
+	InventoryMovementInitial         InventoryMovementType = "initial"
+	InventoryMovementRestock         InventoryMovementType = "restock"
+	InventoryMovementSale            InventoryMovementType = "sale"
+	InventoryMovementRefund          InventoryMovementType = "refund"
+	InventoryMovementWriteOff        InventoryMovementType = "write_off"
+	InventoryMovementCountCorrection InventoryMovementType = "count_correction"
+)
+
+// AdjustsTotalStock reports whether movements of this type change the stock brought in. Sales and
+// refunds only move stock between the shelf and the customers.
+func (t InventoryMovementType) AdjustsTotalStock() bool {
+	return t != InventoryMovementSale && t != InventoryMovementRefund
+}
+
+// InventoryMovement is an entry of the inventory ledger of a product or, if given, of one of its
+// variants. The quantity is added to the stock on hand, it is negative for sales and write-offs.
+// The stock on hand is the sum of all movements, the total stock of a product or variant the sum
+// of the movements adjusting it. A count correction records the counted and the expected stock.
+type InventoryMovement struct {
+	ID               int                   `json:"id"                  gorm:"primarykey"`
+	CreatedAt        time.Time             `json:"createdAt"`
+	ProductID        int                   `json:"productId"           gorm:"index"`
+	Product          *Product              `json:"product,omitempty"`
+	VariantID        *int                  `json:"variantId"           gorm:"index"`
+	Variant          *ProductVariant       `json:"variant,omitempty"   gorm:"foreignKey:VariantID"`
+	Type             InventoryMovementType `json:"type"                gorm:"type:TEXT"`
+	Quantity         int                   `json:"quantity"`
+	PurchaseID       *uuid.UUID            `json:"purchaseId"          gorm:"type:text;index"`
+	CreatedByID      *int                  `json:"createdById"`
+	CreatedBy        *User                 `json:"createdBy,omitempty"`
+	Note             string                `json:"note"`
+	ExpectedQuantity *int                  `json:"expectedQuantity"`
+	CountedQuantity  *int                  `json:"countedQuantity"`
+}
+
+func (m *InventoryMovement) BeforeUpdate(tx *gorm.DB) error {
+	return ErrInventoryMovementImmutable
+}
+
+func (m *InventoryMovement) BeforeDelete(tx *gorm.DB) error {
+	return ErrInventoryMovementImmutable
+}
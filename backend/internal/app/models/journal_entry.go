@@ -0,0 +1,109 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrJournalEntryImmutable = errors.New("journal entries cannot be changed")
+
+type JournalEventType string
+
+const (
+	JournalEventPurchaseCreated       JournalEventType = "purchase_created"
+	JournalEventPurchaseStatusChanged JournalEventType = "purchase_status_changed"
+	JournalEventPurchaseRefunded      JournalEventType = "purchase_refunded"
+	JournalEventPurchaseDeleted       JournalEventType = "purchase_deleted"
+)
+
+// JournalEntry is an entry of the append-only journal of sales events. Every entry contains the
+// hash of the entry before, so changing, removing or inserting an entry breaks the chain.
+type JournalEntry struct {
+	ID           int              `json:"id"           gorm:"primarykey"`
+	Sequence     uint             `json:"sequence"     gorm:"uniqueIndex"`
+	CreatedAt    time.Time        `json:"createdAt"`
+	EventType    JournalEventType `json:"eventType"    gorm:"type:TEXT"`
+	PurchaseID   uuid.UUID        `json:"purchaseId"   gorm:"type:text;index"`
+	UserID       *int             `json:"userId"`
+	Payload      string           `json:"payload"      gorm:"type:TEXT"`
+	PreviousHash string           `json:"previousHash"`
+	Hash         string           `json:"hash"         gorm:"uniqueIndex"`
+}
+
+func (e *JournalEntry) BeforeUpdate(tx *gorm.DB) error {
+	return ErrJournalEntryImmutable
+}
+
+func (e *JournalEntry) BeforeDelete(tx *gorm.DB) error {
+	return ErrJournalEntryImmutable
+}
+
+// ComputeHash returns the SHA-256 hash over the content of the entry and the previous hash.
+func (e *JournalEntry) ComputeHash() string {
+	userID := ""
+	if e.UserID != nil {
+		userID = strconv.Itoa(*e.UserID)
+	}
+
+	content := strings.Join([]string{
+		strconv.FormatUint(uint64(e.Sequence), 10),
+		e.CreatedAt.UTC().Format(time.RFC3339Nano),
+		string(e.EventType),
+		e.PurchaseID.String(),
+		userID,
+		e.Payload,
+		e.PreviousHash,
+	}, "\n")
+
+	sum := sha256.Sum256([]byte(content))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Chain links the entry to the previous entry of the journal (nil for the first entry) and
+// seals it with its hash.
+func (e *JournalEntry) Chain(previous *JournalEntry) {
+	e.Sequence = 1
+	e.PreviousHash = ""
+
+	if previous != nil {
+		e.Sequence = previous.Sequence + 1
+		e.PreviousHash = previous.Hash
+	}
+
+	e.Hash = e.ComputeHash()
+}
+
+// VerifyChain checks that the entry follows the previous entry (nil for the first entry) and that
+// its content has not been changed since it was sealed.
+func (e *JournalEntry) VerifyChain(previous *JournalEntry) error {
+	expectedSequence := uint(1)
+	expectedPreviousHash := ""
+
+	if previous != nil {
+		expectedSequence = previous.Sequence + 1
+		expectedPreviousHash = previous.Hash
+	}
+
+	if e.Sequence != expectedSequence {
+		return fmt.Errorf("expected sequence %d, got %d", expectedSequence, e.Sequence)
+	}
+
+	if e.PreviousHash != expectedPreviousHash {
+		return errors.New("previous hash does not match the hash of the previous entry")
+	}
+
+	if e.Hash != e.ComputeHash() {
+		return errors.New("hash does not match the content of the entry")
+	}
+
+	return nil
+}
@@ -8,26 +8,40 @@ import (
 type Product struct {
 	GormOwnedModel
 
-	Name                string          `json:"name"                gorm:""`
-	NetPrice            decimal.Decimal `json:"netPrice"            gorm:"type:TEXT"`
-	VATRate             decimal.Decimal `json:"vatRate"             gorm:"type:TEXT;default:'0.0'"`
-	WrapAfter           bool            `json:"wrapAfter"           gorm:"default:false"`
-	Hidden              bool            `json:"hidden"              gorm:"default:false"`
-	SoldOut             bool            `json:"soldOut"             gorm:"default:false"`
-	APIExport           bool            `json:"apiExport"           gorm:"default:false"`
-	Pos                 int             `json:"pos"                 gorm:""`
-	TotalStock          int             `json:"totalStock"          gorm:"default:0"`
-	UnitsSold           int             `json:"unitsSold"           gorm:"default:0"`
-	SoldOutRequestCount int             `json:"soldOutRequestCount" gorm:"default:0"`
-	Guestlists          []Guestlist     `json:"guestlists"          gorm:""`
+	Name                string              `json:"name"                gorm:""`
+	NetPrice            decimal.Decimal     `json:"netPrice"            gorm:"type:TEXT"`
+	VATRate             decimal.Decimal     `json:"vatRate"             gorm:"type:TEXT;default:'0.0'"`
+	WrapAfter           bool                `json:"wrapAfter"           gorm:"default:false"`
+	Hidden              bool                `json:"hidden"              gorm:"default:false"`
+	SoldOut             bool                `json:"soldOut"             gorm:"default:false"`
+	APIExport           bool                `json:"apiExport"           gorm:"default:false"`
+	Pos                 int                 `json:"pos"                 gorm:""`
+	TotalStock          int                 `json:"totalStock"          gorm:"default:0"`
+	TrackStock          bool                `json:"trackStock"          gorm:"default:false"`
+	UnitsSold           int                 `json:"unitsSold"           gorm:"default:0"`
+	SoldOutRequestCount int                 `json:"soldOutRequestCount" gorm:"default:0"`
+	Guestlists          []Guestlist         `json:"guestlists"          gorm:""`
+	BundleItems         []ProductBundleItem `json:"bundleItems"         gorm:"foreignKey:BundleID"`
+	Variants            []ProductVariant    `json:"variants"            gorm:"foreignKey:ProductID"`
+	PriceRules          []ProductPriceRule  `json:"priceRules"          gorm:"foreignKey:ProductID"`
+	CategoryID          *int                `json:"categoryId"          gorm:"index"`
+	Category            *Category           `json:"category"            gorm:"foreignKey:CategoryID"`
 }
 
 func (p Product) GrossPrice(decimalPlaces int32) decimal.Decimal {
+	if p.IsBundle() {
+		return p.bundleGrossPrice(decimalPlaces)
+	}
+
 	return p.NetPrice.Add(p.VATAmount(decimalPlaces)).Round(decimalPlaces)
 }
 
 func (p Product) VATAmount(decimalPlaces int32) decimal.Decimal {
 	const hundred = 100
 
+	if p.IsBundle() {
+		return p.bundleGrossPrice(decimalPlaces).Sub(p.bundleNetPrice(decimalPlaces))
+	}
+
 	return p.NetPrice.Mul(p.VATRate.Div(decimal.NewFromInt(hundred))).Round(decimalPlaces)
 }
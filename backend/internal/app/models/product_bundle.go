@@ -0,0 +1,130 @@
+package models
+
+import (
+	"errors"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrBundleComponentIsBundle = errors.New("a bundle cannot contain another bundle")
+	ErrInvalidBundlePrice      = errors.New("bundle price must be positive")
+)
+
+// ProductBundleItem is a component of a bundle product. Selling a bundle sells its components, so
+// their stock and statistics include the units sold in bundles. NetPrice and VATRate are the
+// component's share of the bundle price per unit.
+type ProductBundleItem struct {
+	GormModel
+
+	BundleID    int             `json:"bundleId"    gorm:"index"`
+	ComponentID int             `json:"componentId"`
+	Component   Product         `json:"-"           gorm:"foreignKey:ComponentID"`
+	Quantity    uint            `json:"quantity"`
+	NetPrice    decimal.Decimal `json:"netPrice"    gorm:"type:TEXT"`
+	VATRate     decimal.Decimal `json:"vatRate"     gorm:"type:TEXT"`
+}
+
+// SetBundleItems makes the product a bundle of the items, sold for the gross price. The items need
+// their component. The price is split across the items by the list prices of their components, so
+// every component is sold at its own VAT rate. The last item takes what is left after rounding, as
+// its net price is rounded per unit the bundle price may still differ from the gross price by a cent.
+func (p *Product) SetBundleItems(items []ProductBundleItem, grossPrice decimal.Decimal, decimalPlaces int32) error {
+	if !grossPrice.IsPositive() {
+		return ErrInvalidBundlePrice
+	}
+
+	for _, item := range items {
+		if item.Component.IsBundle() || (p.ID != 0 && item.ComponentID == p.ID) {
+			return ErrBundleComponentIsBundle
+		}
+	}
+
+	splitBundlePrice(grossPrice, items, decimalPlaces)
+
+	p.BundleItems = items
+	p.NetPrice = p.bundleNetPrice(decimalPlaces)
+
+	return nil
+}
+
+func splitBundlePrice(grossPrice decimal.Decimal, items []ProductBundleItem, decimalPlaces int32) {
+	weights := make([]decimal.Decimal, len(items))
+	total := decimal.Zero
+
+	for i, item := range items {
+		weights[i] = item.Component.GrossPrice(decimalPlaces).Mul(decimal.NewFromUint64(uint64(item.Quantity)))
+		total = total.Add(weights[i])
+	}
+
+	// components without a price share the bundle price by their quantity
+	if !total.IsPositive() {
+		for i, item := range items {
+			weights[i] = decimal.NewFromUint64(uint64(item.Quantity))
+			total = total.Add(weights[i])
+		}
+	}
+
+	remaining := grossPrice
+
+	for i := range items {
+		share := remaining
+		if i < len(items)-1 {
+			share = grossPrice.Mul(weights[i]).Div(total).Round(decimalPlaces)
+		}
+
+		quantity := decimal.NewFromUint64(uint64(items[i].Quantity))
+		items[i].VATRate = items[i].Component.VATRate
+		items[i].NetPrice = share.Div(quantity).
+			Mul(decimal.NewFromInt(hundredPercent)).
+			Div(items[i].VATRate.Add(decimal.NewFromInt(hundredPercent))).
+			Round(decimalPlaces)
+
+		// the following items make up for the rounding of this one
+		item := PurchaseItem{Quantity: items[i].Quantity, NetPrice: items[i].NetPrice, VATRate: items[i].VATRate}
+		remaining = remaining.Sub(item.TotalGrossPrice(decimalPlaces))
+	}
+}
+
+// ExpandBundle returns the purchase items selling the given quantity of the bundle.
+func (p Product) ExpandBundle(quantity uint) []PurchaseItem {
+	items := make([]PurchaseItem, 0, len(p.BundleItems))
+
+	for _, bundleItem := range p.BundleItems {
+		items = append(items, PurchaseItem{
+			ProductID: bundleItem.ComponentID,
+			BundleID:  &p.ID,
+			Quantity:  bundleItem.Quantity * quantity,
+			NetPrice:  bundleItem.NetPrice,
+			VATRate:   bundleItem.VATRate,
+		})
+	}
+
+	return items
+}
+
+func (p Product) IsBundle() bool {
+	return len(p.BundleItems) > 0
+}
+
+// bundleNetPrice returns the net price of one bundle.
+func (p Product) bundleNetPrice(decimalPlaces int32) decimal.Decimal {
+	price := decimal.Zero
+
+	for _, item := range p.ExpandBundle(1) {
+		price = price.Add(item.TotalNetPrice(decimalPlaces))
+	}
+
+	return price
+}
+
+// bundleGrossPrice returns the gross price of one bundle.
+func (p Product) bundleGrossPrice(decimalPlaces int32) decimal.Decimal {
+	price := decimal.Zero
+
+	for _, item := range p.ExpandBundle(1) {
+		price = price.Add(item.TotalGrossPrice(decimalPlaces))
+	}
+
+	return price
+}
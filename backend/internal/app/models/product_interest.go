@@ -3,6 +3,8 @@ package models
 type ProductInterest struct {
 	GormOwnedModel
 
-	ProductID int     `json:"productID"`
-	Product   Product `json:"product"   gorm:""`
+	ProductID int             `json:"productID"`
+	Product   Product         `json:"product"   gorm:""`
+	VariantID *int            `json:"variantID" gorm:"index"`
+	Variant   *ProductVariant `json:"variant"   gorm:"foreignKey:VariantID"`
 }
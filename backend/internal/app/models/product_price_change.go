@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ProductPriceChange records a change of the list price of a product or, if given, of the price of
+// one of its variants. A variant without a price of its own is sold at the price of the product,
+// its prices are nil then. The history lets purchases recorded offline be checked against the
+// prices in effect when they were recorded.
+type ProductPriceChange struct {
+	ID               int              `json:"id"               gorm:"primarykey"`
+	CreatedAt        time.Time        `json:"createdAt"        gorm:"index"`
+	ProductID        int              `json:"productId"        gorm:"index"`
+	VariantID        *int             `json:"variantId"`
+	PreviousNetPrice *decimal.Decimal `json:"previousNetPrice" gorm:"type:TEXT"`
+	NetPrice         *decimal.Decimal `json:"netPrice"         gorm:"type:TEXT"`
+}
+
+// PriceChanges returns the changes of the list price of the product and of the prices of its
+// variants since the previous version of the product.
+func (p Product) PriceChanges(previous Product) []ProductPriceChange {
+	var changes []ProductPriceChange
+
+	if !p.NetPrice.Equal(previous.NetPrice) {
+		previousPrice, price := previous.NetPrice, p.NetPrice
+		changes = append(changes, ProductPriceChange{
+			ProductID:        p.ID,
+			PreviousNetPrice: &previousPrice,
+			NetPrice:         &price,
+		})
+	}
+
+	for _, variant := range p.Variants {
+		previousVariant, ok := previous.Variant(variant.ID)
+		if !ok || equalPrices(previousVariant.NetPrice, variant.NetPrice) {
+			continue
+		}
+
+		changes = append(changes, ProductPriceChange{
+			ProductID:        p.ID,
+			VariantID:        &variant.ID,
+			PreviousNetPrice: previousVariant.NetPrice,
+			NetPrice:         variant.NetPrice,
+		})
+	}
+
+	return changes
+}
+
+func equalPrices(a, b *decimal.Decimal) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.Equal(*b)
+}
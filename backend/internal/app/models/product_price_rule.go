@@ -0,0 +1,143 @@
+package models
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrInvalidPriceRulePrice  = errors.New("price rule net price must not be negative")
+	ErrInvalidPriceRulePeriod = errors.New("price rule must end after it starts")
+	ErrInvalidPriceRuleHours  = errors.New("price rule hours must be given both and must differ")
+)
+
+const (
+	hoursPerDay = 24
+	daysPerWeek = 7
+)
+
+// ProductPriceRule is a scheduled price of a product, like an early bird price, a day ticket or a
+// happy hour. A rule applies within its validity period and, if given, on its weekdays and between
+// its hours. FromHour is inclusive and UntilHour exclusive, a window from 22 until 2 spans midnight.
+// Of several matching rules the first by position applies.
+type ProductPriceRule struct {
+	GormModel
+
+	ProductID  int             `json:"productId"  gorm:"index"`
+	NetPrice   decimal.Decimal `json:"netPrice"   gorm:"type:TEXT"`
+	ValidFrom  *time.Time      `json:"validFrom"`
+	ValidUntil *time.Time      `json:"validUntil"`
+	Weekdays   []time.Weekday  `json:"weekdays"   gorm:"type:TEXT;serializer:json"`
+	FromHour   *int            `json:"fromHour"`
+	UntilHour  *int            `json:"untilHour"`
+	Pos        int             `json:"pos"        gorm:"default:0"`
+}
+
+func (r ProductPriceRule) Validate() error {
+	if r.NetPrice.IsNegative() {
+		return ErrInvalidPriceRulePrice
+	}
+
+	if r.ValidFrom != nil && r.ValidUntil != nil && !r.ValidUntil.After(*r.ValidFrom) {
+		return ErrInvalidPriceRulePeriod
+	}
+
+	if (r.FromHour == nil) != (r.UntilHour == nil) || (r.FromHour != nil && *r.FromHour == *r.UntilHour) {
+		return ErrInvalidPriceRuleHours
+	}
+
+	return nil
+}
+
+// ActiveAt reports whether the rule applies at the time.
+func (r ProductPriceRule) ActiveAt(at time.Time) bool {
+	if r.ValidFrom != nil && at.Before(*r.ValidFrom) {
+		return false
+	}
+
+	if r.ValidUntil != nil && !at.Before(*r.ValidUntil) {
+		return false
+	}
+
+	if len(r.Weekdays) > 0 && !slices.Contains(r.Weekdays, at.Weekday()) {
+		return false
+	}
+
+	if r.FromHour == nil || r.UntilHour == nil {
+		return true
+	}
+
+	hour := at.Hour()
+	if *r.FromHour < *r.UntilHour {
+		return hour >= *r.FromHour && hour < *r.UntilHour
+	}
+
+	return hour >= *r.FromHour || hour < *r.UntilHour
+}
+
+func (r ProductPriceRule) hasPattern() bool {
+	return len(r.Weekdays) > 0 || r.FromHour != nil
+}
+
+// PriceAt returns the product as sold at the time, with the price of the first active price rule.
+func (p Product) PriceAt(at time.Time) Product {
+	for _, rule := range p.PriceRules {
+		if rule.ActiveAt(at) {
+			p.NetPrice = rule.NetPrice
+
+			break
+		}
+	}
+
+	return p
+}
+
+// NextPriceChange returns when the price of the product changes next after the time and the net
+// price from then on. Weekday and hour patterns are followed for a week ahead.
+func (p Product) NextPriceChange(at time.Time) (time.Time, decimal.Decimal, bool) {
+	current := p.PriceAt(at).NetPrice
+
+	for _, change := range p.priceChangeCandidates(at) {
+		price := p.PriceAt(change).NetPrice
+		if !price.Equal(current) {
+			return change, price, true
+		}
+	}
+
+	return time.Time{}, decimal.Zero, false
+}
+
+// priceChangeCandidates returns the times after the given one at which a price rule may start or
+// end, in order.
+func (p Product) priceChangeCandidates(at time.Time) []time.Time {
+	var candidates []time.Time
+
+	hasPattern := false
+
+	for _, rule := range p.PriceRules {
+		for _, bound := range []*time.Time{rule.ValidFrom, rule.ValidUntil} {
+			if bound != nil && bound.After(at) {
+				candidates = append(candidates, *bound)
+			}
+		}
+
+		hasPattern = hasPattern || rule.hasPattern()
+	}
+
+	if hasPattern {
+		// the hours of the patterns are local, so is the start of the hour, whatever the offset of the zone
+		hour := time.Date(at.Year(), at.Month(), at.Day(), at.Hour(), 0, 0, 0, at.Location())
+		for i := 1; i <= hoursPerDay*daysPerWeek; i++ {
+			candidates = append(candidates, hour.Add(time.Duration(i)*time.Hour))
+		}
+	}
+
+	slices.SortFunc(candidates, func(a, b time.Time) int {
+		return a.Compare(b)
+	})
+
+	return candidates
+}
@@ -0,0 +1,32 @@
+package models
+
+// TracksStock reports whether the stock of the product is enforced. A product with variants keeps
+// its stock in its variants.
+func (p Product) TracksStock() bool {
+	return !p.HasVariants() && p.TrackStock
+}
+
+// TracksStock reports whether the stock of the variant is enforced.
+func (v ProductVariant) TracksStock() bool {
+	return v.TrackStock
+}
+
+// ReservesStock reports whether the items of a purchase in this status are taken from the stock.
+func (s PurchaseStatus) ReservesStock() bool {
+	return s == PurchaseStatusConfirmed || s == PurchaseStatusPending
+}
+
+// SoldOutForStock returns the sold-out flag once the available stock changed from before to after.
+// An item is sold out when nothing is left, and it is available again when it was sold out for
+// lack of stock and stock is left again. Otherwise the flag is kept, as it may have been set by hand.
+func SoldOutForStock(soldOut bool, availableBefore, availableAfter int) bool {
+	if availableAfter <= 0 {
+		return true
+	}
+
+	if availableBefore <= 0 {
+		return false
+	}
+
+	return soldOut
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// ProductVariant is a variant of a product, like a size or a colour. Every variant has its own
+// stock and sold-out flag and can override the price of its product.
+type ProductVariant struct {
+	GormModel
+
+	ProductID  int              `json:"productId"  gorm:"index"`
+	Name       string           `json:"name"`
+	NetPrice   *decimal.Decimal `json:"netPrice"   gorm:"type:TEXT"`
+	Pos        int              `json:"pos"        gorm:"default:0"`
+	TotalStock int              `json:"totalStock" gorm:"default:0"`
+	TrackStock bool             `json:"trackStock" gorm:"default:false"`
+	SoldOut    bool             `json:"soldOut"    gorm:"default:false"`
+}
+
+func (p Product) HasVariants() bool {
+	return len(p.Variants) > 0
+}
+
+// Variant returns the variant of the product with the ID.
+func (p Product) Variant(id int) (ProductVariant, bool) {
+	for _, variant := range p.Variants {
+		if variant.ID == id {
+			return variant, true
+		}
+	}
+
+	return ProductVariant{}, false
+}
+
+// WithVariant returns the product as sold in the variant, with the price of the variant if it
+// overrides the price of the product.
+func (p Product) WithVariant(variant ProductVariant) Product {
+	if variant.NetPrice != nil {
+		p.NetPrice = *variant.NetPrice
+	}
+
+	return p
+}
+
+// VariantTotalStock returns the stock of all variants, or the stock of the product if it has no
+// variants.
+func (p Product) VariantTotalStock() int {
+	if !p.HasVariants() {
+		return p.TotalStock
+	}
+
+	stock := 0
+	for _, variant := range p.Variants {
+		stock += variant.TotalStock
+	}
+
+	return stock
+}
+
+// VariantsSoldOut reports whether the product is sold out, which for a product with variants is
+// the case when every variant is sold out.
+func (p Product) VariantsSoldOut() bool {
+	if !p.HasVariants() {
+		return p.SoldOut
+	}
+
+	for _, variant := range p.Variants {
+		if !variant.SoldOut {
+			return false
+		}
+	}
+
+	return true
+}
@@ -30,18 +30,32 @@ const (
 	PaymentMethodVoucher PaymentMethod = "VOUCHER"
 )
 
+// Purchase is a sale at the register. A purchase recorded by a client while it was offline is
+// booked when it is synced, RecordedAt keeps the time it was recorded at. A card payment that
+// never reached a final state in time is failed, ExpiredAt marks such purchases for review.
 type Purchase struct {
 	GormOwnedModel
 
-	ID                       uuid.UUID       `json:"id"                       gorm:"type:text;primaryKey"`
-	CreatedAt                time.Time       `json:"createdAt"                gorm:"index"`
-	TotalNetPrice            decimal.Decimal `json:"totalNetPrice"            gorm:"type:TEXT"`
-	TotalGrossPrice          decimal.Decimal `json:"totalGrossPrice"          gorm:"type:TEXT"`
-	PurchaseItems            []PurchaseItem  `json:"purchaseItems"            gorm:"foreignKey:PurchaseID"`
-	PaymentMethod            PaymentMethod   `json:"paymentMethod"            gorm:"type:TEXT"`
-	SumupTransactionID       *uuid.UUID      `json:"sumupTransactionId"       gorm:"type:TEXT"`
-	SumupClientTransactionID *uuid.UUID      `json:"sumupClientTransactionId" gorm:"type:TEXT"`
-	Status                   PurchaseStatus  `json:"status"                   gorm:"type:TEXT;default:'confirmed'"`
+	ID                       uuid.UUID                  `json:"id"                       gorm:"type:text;primaryKey"`
+	CreatedAt                time.Time                  `json:"createdAt"                gorm:"index"`
+	TotalNetPrice            decimal.Decimal            `json:"totalNetPrice"            gorm:"type:TEXT"`
+	TotalGrossPrice          decimal.Decimal            `json:"totalGrossPrice"          gorm:"type:TEXT"`
+	PurchaseItems            []PurchaseItem             `json:"purchaseItems"            gorm:"foreignKey:PurchaseID"`
+	Payments                 []PurchasePayment          `json:"payments"                 gorm:"foreignKey:PurchaseID"`
+	Roundings                []PurchaseRounding         `json:"roundings"                gorm:"foreignKey:PurchaseID"`
+	Refunds                  []PurchaseRefund           `json:"refunds"                  gorm:"foreignKey:PurchaseID"`
+	ReceiptMails             []PurchaseReceiptMail      `json:"receiptMails"             gorm:"foreignKey:PurchaseID"`
+	StatusTransitions        []PurchaseStatusTransition `json:"statusTransitions"        gorm:"foreignKey:PurchaseID"`
+	PaymentMethod            PaymentMethod              `json:"paymentMethod"            gorm:"type:TEXT"`
+	SumupTransactionID       *uuid.UUID                 `json:"sumupTransactionId"       gorm:"type:TEXT"`
+	SumupClientTransactionID *uuid.UUID                 `json:"sumupClientTransactionId" gorm:"type:TEXT"`
+	SumupReaderID            *string                    `json:"sumupReaderId"            gorm:"type:TEXT"`
+	Status                   PurchaseStatus             `json:"status"                   gorm:"type:TEXT;default:'confirmed'"`
+	RegisterSessionID        *int                       `json:"registerSessionId"        gorm:"index"`
+	ReceiptNumber            *string                    `json:"receiptNumber"            gorm:"uniqueIndex"`
+	Offline                  bool                       `json:"offline"                  gorm:"default:false;index"`
+	RecordedAt               *time.Time                 `json:"recordedAt"`
+	ExpiredAt                *time.Time                 `json:"expiredAt"                gorm:"index"`
 }
 
 func (p *Purchase) BeforeCreate(tx *gorm.DB) (err error) {
@@ -51,3 +65,123 @@ func (p *Purchase) BeforeCreate(tx *gorm.DB) (err error) {
 
 	return
 }
+
+// PaymentLines returns the payment lines of the purchase. Purchases stored before
+// payment lines were introduced are represented by a single line covering the total.
+func (p Purchase) PaymentLines() []PurchasePayment {
+	if len(p.Payments) > 0 {
+		return p.Payments
+	}
+
+	return []PurchasePayment{
+		{
+			PurchaseID:               p.ID,
+			PaymentMethod:            p.PaymentMethod,
+			Amount:                   p.TotalGrossPrice,
+			SumupTransactionID:       p.SumupTransactionID,
+			SumupClientTransactionID: p.SumupClientTransactionID,
+		},
+	}
+}
+
+// SumupPayment returns the payment line that has to be settled via SumUp, if any.
+func (p Purchase) SumupPayment() *PurchasePayment {
+	for i := range p.Payments {
+		if p.Payments[i].PaymentMethod == PaymentMethodSumUp {
+			return &p.Payments[i]
+		}
+	}
+
+	return nil
+}
+
+// SumupAmount returns the amount to be charged via SumUp, including its rounding. Purchases stored
+// before payment lines were introduced are charged their total.
+func (p Purchase) SumupAmount() decimal.Decimal {
+	sumupPayment := p.SumupPayment()
+	if sumupPayment == nil {
+		return p.TotalGrossPrice
+	}
+
+	return sumupPayment.Amount.Add(p.RoundingAmount(PaymentMethodSumUp))
+}
+
+// RoundingLines returns the rounding lines of the amounts paid for the purchase, without the rounding
+// lines of its refunds.
+func (p Purchase) RoundingLines() []PurchaseRounding {
+	var roundings []PurchaseRounding
+
+	for _, rounding := range p.Roundings {
+		if !rounding.IsRefund() {
+			roundings = append(roundings, rounding)
+		}
+	}
+
+	return roundings
+}
+
+// RoundingAmount returns the rounding of the amount paid with the given payment method.
+func (p Purchase) RoundingAmount(method PaymentMethod) decimal.Decimal {
+	amount := decimal.Zero
+
+	for _, rounding := range p.RoundingLines() {
+		if rounding.PaymentMethod == method {
+			amount = amount.Add(rounding.Amount)
+		}
+	}
+
+	return amount
+}
+
+// IsFullySettled reports whether every payment line of the purchase has been settled.
+func (p Purchase) IsFullySettled() bool {
+	for _, payment := range p.Payments {
+		if !payment.IsSettled() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// PaymentShare returns the fraction of the total gross price covered by the given amount.
+func (p Purchase) PaymentShare(amount decimal.Decimal) decimal.Decimal {
+	if p.TotalGrossPrice.IsZero() {
+		return decimal.Zero
+	}
+
+	return amount.Div(p.TotalGrossPrice)
+}
+
+// DiscountLines returns the discount lines of all purchase items.
+func (p Purchase) DiscountLines() []PurchaseDiscount {
+	var discounts []PurchaseDiscount
+
+	for _, item := range p.PurchaseItems {
+		discounts = append(discounts, item.Discounts...)
+	}
+
+	return discounts
+}
+
+// IsFullyRefunded reports whether every purchase item has been refunded completely.
+func (p Purchase) IsFullyRefunded() bool {
+	for _, item := range p.PurchaseItems {
+		if item.RemainingQuantity() > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// RefundedAmount returns the amount already paid back with the given payment method.
+func (p Purchase) RefundedAmount(method PaymentMethod) decimal.Decimal {
+	amount := decimal.Zero
+
+	for _, refund := range p.Refunds {
+		amount = amount.Add(refund.PaymentAmount(method))
+	}
+
+	return amount
+}
@@ -8,13 +8,27 @@ import (
 type PurchaseItem struct {
 	GormModel
 
-	PurchaseID uuid.UUID       `json:"purchaseID" gorm:"type:text"` // Foreign key to Purchase
-	Purchase   Purchase        `json:"-"          gorm:"foreignKey:PurchaseID"`
-	ProductID  int             `json:"productID"` // Foreign key to Product
-	Product    Product         `json:"product"    gorm:"foreignKey:ProductID"`
-	Quantity   uint            `json:"quantity"`
-	NetPrice   decimal.Decimal `json:"netPrice"   gorm:"type:TEXT"`
-	VATRate    decimal.Decimal `json:"vatRate"    gorm:"type:TEXT"`
+	PurchaseID uuid.UUID            `json:"purchaseID" gorm:"type:text"` // Foreign key to Purchase
+	Purchase   Purchase             `json:"-"          gorm:"foreignKey:PurchaseID"`
+	ProductID  int                  `json:"productID"` // Foreign key to Product
+	Product    Product              `json:"product"    gorm:"foreignKey:ProductID"`
+	BundleID   *int                 `json:"bundleID"   gorm:"index"`
+	VariantID  *int                 `json:"variantID"  gorm:"index"`
+	Variant    *ProductVariant      `json:"variant"    gorm:"foreignKey:VariantID"`
+	Quantity   uint                 `json:"quantity"`
+	NetPrice   decimal.Decimal      `json:"netPrice"   gorm:"type:TEXT"`
+	VATRate    decimal.Decimal      `json:"vatRate"    gorm:"type:TEXT"`
+	Refunds    []PurchaseRefundItem `json:"refunds"    gorm:"foreignKey:PurchaseItemID"`
+	Discounts  []PurchaseDiscount   `json:"discounts"  gorm:"foreignKey:PurchaseItemID"`
+}
+
+// Name returns the name of the product, with the variant if one was chosen.
+func (pi PurchaseItem) Name() string {
+	if pi.Variant == nil {
+		return pi.Product.Name
+	}
+
+	return pi.Product.Name + " (" + pi.Variant.Name + ")"
 }
 
 func (pi PurchaseItem) GrossPrice(decimalPlaces int32) decimal.Decimal {
@@ -37,6 +51,70 @@ func (pi PurchaseItem) TotalVATAmount(decimalPlaces int32) decimal.Decimal {
 	return pi.VATAmount(decimalPlaces).Mul(pi.getQuantityAsDecimal()).Round(decimalPlaces)
 }
 
+// DiscountNetAmount returns the net amount the price of the item has been reduced by.
+func (pi PurchaseItem) DiscountNetAmount() decimal.Decimal {
+	amount := decimal.Zero
+
+	for _, discount := range pi.Discounts {
+		amount = amount.Add(discount.NetAmount)
+	}
+
+	return amount
+}
+
+// DiscountGrossAmount returns the gross amount the price of the item has been reduced by.
+func (pi PurchaseItem) DiscountGrossAmount() decimal.Decimal {
+	amount := decimal.Zero
+
+	for _, discount := range pi.Discounts {
+		amount = amount.Add(discount.GrossAmount)
+	}
+
+	return amount
+}
+
+func (pi PurchaseItem) TotalDiscountedNetPrice(decimalPlaces int32) decimal.Decimal {
+	return pi.TotalNetPrice(decimalPlaces).Sub(pi.DiscountNetAmount())
+}
+
+func (pi PurchaseItem) TotalDiscountedGrossPrice(decimalPlaces int32) decimal.Decimal {
+	return pi.TotalGrossPrice(decimalPlaces).Sub(pi.DiscountGrossAmount())
+}
+
+// RefundedDiscount returns the net and gross amounts of the discount that have already been
+// refunded with the refunded quantity.
+func (pi PurchaseItem) RefundedDiscount() (net, gross decimal.Decimal) {
+	net, gross = decimal.Zero, decimal.Zero
+
+	for _, refund := range pi.Refunds {
+		net = net.Add(refund.DiscountNetAmount)
+		gross = gross.Add(refund.DiscountGrossAmount)
+	}
+
+	return net, gross
+}
+
+// RefundedQuantity returns the quantity of the item that has already been refunded.
+func (pi PurchaseItem) RefundedQuantity() uint {
+	var quantity uint
+
+	for _, refund := range pi.Refunds {
+		quantity += refund.Quantity
+	}
+
+	return quantity
+}
+
+// RemainingQuantity returns the quantity of the item that has not been refunded yet.
+func (pi PurchaseItem) RemainingQuantity() uint {
+	refunded := pi.RefundedQuantity()
+	if refunded >= pi.Quantity {
+		return 0
+	}
+
+	return pi.Quantity - refunded
+}
+
 func (pi PurchaseItem) getQuantityAsDecimal() decimal.Decimal {
 	return decimal.NewFromUint64(uint64(pi.Quantity))
 }
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// PurchasePayment is a single payment line of a purchase. A purchase can be paid
+// with several lines (e.g. part cash, part card) that add up to its total gross price.
+type PurchasePayment struct {
+	GormModel
+
+	PurchaseID               uuid.UUID       `json:"purchaseID"               gorm:"type:text;index"`
+	PaymentMethod            PaymentMethod   `json:"paymentMethod"            gorm:"type:TEXT"`
+	Amount                   decimal.Decimal `json:"amount"                   gorm:"type:TEXT"`
+	SumupTransactionID       *uuid.UUID      `json:"sumupTransactionId"       gorm:"type:TEXT"`
+	SumupClientTransactionID *uuid.UUID      `json:"sumupClientTransactionId" gorm:"type:TEXT"`
+	SettledAt                *time.Time      `json:"settledAt"`
+	VoucherID                *int            `json:"voucherId"                gorm:"index"`
+}
+
+func (pp PurchasePayment) IsSettled() bool {
+	return pp.SettledAt != nil
+}
+
+// RequiresSettlement reports whether the payment line has to be confirmed by an
+// external payment provider before the purchase can be confirmed.
+func (pp PurchasePayment) RequiresSettlement() bool {
+	return pp.PaymentMethod == PaymentMethodSumUp
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PurchaseReceiptMail records a receipt sent by email. The email address is erased once the
+// configured retention period is over, the record of the send itself is kept.
+type PurchaseReceiptMail struct {
+	GormOwnedModel
+
+	PurchaseID uuid.UUID `json:"purchaseID" gorm:"type:text;index"`
+	Email      *string   `json:"email"`
+	SentAt     time.Time `json:"sentAt"     gorm:"index"`
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// PurchaseRefund records a (partial) refund of a purchase. It consists of refund lines
+// linked to the refunded purchase items and the amounts paid back per payment method.
+// A refund paying back money via the payment terminal provider is settled once the
+// provider has refunded the card. While the card is being refunded, the refund is claimed
+// with SettlingAt, so it is refunded only once.
+type PurchaseRefund struct {
+	GormOwnedModel
+
+	PurchaseID        uuid.UUID               `json:"purchaseID"        gorm:"type:text;index"`
+	TotalNetPrice     decimal.Decimal         `json:"totalNetPrice"     gorm:"type:TEXT"`
+	TotalGrossPrice   decimal.Decimal         `json:"totalGrossPrice"   gorm:"type:TEXT"`
+	RegisterSessionID *int                    `json:"registerSessionId" gorm:"index"`
+	SettledAt         *time.Time              `json:"settledAt"`
+	SettlingAt        *time.Time              `json:"settlingAt"`
+	Items             []PurchaseRefundItem    `json:"items"             gorm:"foreignKey:PurchaseRefundID"`
+	Payments          []PurchaseRefundPayment `json:"payments"          gorm:"foreignKey:PurchaseRefundID"`
+	Roundings         []PurchaseRounding      `json:"roundings"         gorm:"foreignKey:PurchaseRefundID"`
+}
+
+// PurchaseRefundItem is a refund line for a quantity of a purchase item.
+type PurchaseRefundItem struct {
+	GormModel
+
+	PurchaseRefundID int             `json:"purchaseRefundID" gorm:"index"`
+	PurchaseRefund   *PurchaseRefund `json:"-"                gorm:"foreignKey:PurchaseRefundID"`
+	PurchaseItemID   int             `json:"purchaseItemID"   gorm:"index"`
+	Quantity         uint            `json:"quantity"`
+	NetPrice         decimal.Decimal `json:"netPrice"         gorm:"type:TEXT"`
+	VATRate          decimal.Decimal `json:"vatRate"          gorm:"type:TEXT"`
+	// the part of the item's discount that belongs to the refunded quantity
+	DiscountNetAmount   decimal.Decimal `json:"discountNetAmount"   gorm:"type:TEXT;default:'0'"`
+	DiscountGrossAmount decimal.Decimal `json:"discountGrossAmount" gorm:"type:TEXT;default:'0'"`
+}
+
+// PurchaseRefundPayment is the amount of a refund paid back with a payment method.
+type PurchaseRefundPayment struct {
+	GormModel
+
+	PurchaseRefundID int             `json:"purchaseRefundID" gorm:"index"`
+	PaymentMethod    PaymentMethod   `json:"paymentMethod"    gorm:"type:TEXT"`
+	Amount           decimal.Decimal `json:"amount"           gorm:"type:TEXT"`
+}
+
+func (ri PurchaseRefundItem) asPurchaseItem() PurchaseItem {
+	return PurchaseItem{
+		Quantity: ri.Quantity,
+		NetPrice: ri.NetPrice,
+		VATRate:  ri.VATRate,
+	}
+}
+
+// TotalNetPrice returns the net amount refunded for the quantity, after its share of the discount.
+func (ri PurchaseRefundItem) TotalNetPrice(decimalPlaces int32) decimal.Decimal {
+	return ri.asPurchaseItem().TotalNetPrice(decimalPlaces).Sub(ri.DiscountNetAmount)
+}
+
+// TotalGrossPrice returns the gross amount refunded for the quantity, after its share of the discount.
+func (ri PurchaseRefundItem) TotalGrossPrice(decimalPlaces int32) decimal.Decimal {
+	return ri.asPurchaseItem().TotalGrossPrice(decimalPlaces).Sub(ri.DiscountGrossAmount)
+}
+
+func (r PurchaseRefund) IsSettled() bool {
+	return r.SettledAt != nil
+}
+
+// RequiresSettlement reports whether the refund pays back money via an external payment
+// provider, which has to confirm the refund before it is settled.
+func (r PurchaseRefund) RequiresSettlement() bool {
+	return r.PaymentAmount(PaymentMethodSumUp).IsPositive()
+}
+
+// PaidBackAmount returns the amount actually paid back with the given payment method, after its rounding.
+func (r PurchaseRefund) PaidBackAmount(method PaymentMethod) decimal.Decimal {
+	amount := r.PaymentAmount(method)
+
+	for _, rounding := range r.Roundings {
+		if rounding.PaymentMethod == method {
+			amount = amount.Sub(rounding.Amount)
+		}
+	}
+
+	return amount
+}
+
+// PaymentAmount returns the amount paid back with the given payment method.
+func (r PurchaseRefund) PaymentAmount(method PaymentMethod) decimal.Decimal {
+	amount := decimal.Zero
+
+	for _, payment := range r.Payments {
+		if payment.PaymentMethod == method {
+			amount = amount.Add(payment.Amount)
+		}
+	}
+
+	return amount
+}
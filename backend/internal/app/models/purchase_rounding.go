@@ -0,0 +1,59 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type RoundingMode string
+
+const (
+	RoundingModeNearest RoundingMode = "nearest"
+	RoundingModeUp      RoundingMode = "up"
+	RoundingModeDown    RoundingMode = "down"
+)
+
+// RoundingRule rounds the amount paid with a payment method to a multiple of the increment, e.g.
+// cash in Denmark to the nearest 0.50. A rule without an increment leaves the amounts exact.
+type RoundingRule struct {
+	Increment decimal.Decimal
+	Mode      RoundingMode
+}
+
+// Round returns the amount rounded by the rule. Halves are rounded away from zero.
+func (r RoundingRule) Round(amount decimal.Decimal) decimal.Decimal {
+	if !r.Increment.IsPositive() {
+		return amount
+	}
+
+	steps := amount.Div(r.Increment)
+
+	switch r.Mode {
+	case RoundingModeUp:
+		steps = steps.Ceil()
+	case RoundingModeDown:
+		steps = steps.Floor()
+	default:
+		steps = steps.Round(0)
+	}
+
+	return steps.Mul(r.Increment)
+}
+
+// PurchaseRounding is the difference between the amount paid with a payment method that rounds
+// its amounts and the exact amount of its payment lines. It is positive if the customer paid more.
+// The rounding is not part of the prices, so it carries no VAT. The rounding of the amount paid
+// back by a refund belongs to the refund, it is negative if the customer got more back.
+type PurchaseRounding struct {
+	GormModel
+
+	PurchaseID       uuid.UUID       `json:"purchaseId"       gorm:"type:text;index"`
+	PurchaseRefundID *int            `json:"purchaseRefundId" gorm:"index"`
+	PaymentMethod    PaymentMethod   `json:"paymentMethod"    gorm:"type:TEXT"`
+	Amount           decimal.Decimal `json:"amount"           gorm:"type:TEXT"`
+}
+
+// IsRefund reports whether the rounding belongs to a refund rather than to the purchase itself.
+func (r PurchaseRounding) IsRefund() bool {
+	return r.PurchaseRefundID != nil
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PurchaseTransitionSource is the channel through which the status of a purchase was changed.
+type PurchaseTransitionSource string
+
+const (
+	PurchaseTransitionSourceUser    PurchaseTransitionSource = "user"
+	PurchaseTransitionSourcePoller  PurchaseTransitionSource = "poller"
+	PurchaseTransitionSourceWebhook PurchaseTransitionSource = "webhook"
+	PurchaseTransitionSourceSystem  PurchaseTransitionSource = "system"
+)
+
+// PurchaseStatusTransition records a change of the status of a purchase. The first transition of
+// a purchase has no previous status, it records the status the purchase was created with.
+type PurchaseStatusTransition struct {
+	ID         int                      `json:"id"         gorm:"primarykey"`
+	CreatedAt  time.Time                `json:"createdAt"`
+	PurchaseID uuid.UUID                `json:"purchaseId" gorm:"type:text;index"`
+	FromStatus PurchaseStatus           `json:"fromStatus" gorm:"type:TEXT"`
+	ToStatus   PurchaseStatus           `json:"toStatus"   gorm:"type:TEXT"`
+	Source     PurchaseTransitionSource `json:"source"     gorm:"type:TEXT"`
+	ActorID    *int                     `json:"actorId"`
+	Actor      *User                    `json:"actor"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// ReceiptNumberSequence holds the last receipt number issued for a prefix in a fiscal year.
+// Numbers are taken from the sequence inside the transaction that confirms a purchase, so
+// the numbers are gap-free and a number is never issued twice.
+type ReceiptNumberSequence struct {
+	ID         int    `json:"id"         gorm:"primarykey"`
+	Prefix     string `json:"prefix"     gorm:"uniqueIndex:idx_receipt_number_sequence"`
+	FiscalYear int    `json:"fiscalYear" gorm:"uniqueIndex:idx_receipt_number_sequence"`
+	LastNumber uint   `json:"lastNumber"`
+}
+
+// FiscalYear returns the fiscal year of the given time, named after the calendar year it starts in.
+func FiscalYear(t time.Time, startMonth time.Month) int {
+	if startMonth < time.January || startMonth > time.December {
+		startMonth = time.January
+	}
+
+	if t.Month() < startMonth {
+		return t.Year() - 1
+	}
+
+	return t.Year()
+}
+
+// FormatReceiptNumber formats a receipt number, e.g. "K1-2026-000042".
+func FormatReceiptNumber(prefix string, fiscalYear int, number uint) string {
+	if prefix == "" {
+		return fmt.Sprintf("%d-%06d", fiscalYear, number)
+	}
+
+	return fmt.Sprintf("%s-%d-%06d", prefix, fiscalYear, number)
+}
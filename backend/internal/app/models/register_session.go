@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type RegisterSessionStatus string
+
+const (
+	RegisterSessionStatusOpen   RegisterSessionStatus = "open"
+	RegisterSessionStatusClosed RegisterSessionStatus = "closed"
+)
+
+// RegisterSession is a cashier's shift at a till. It starts with an opening float, collects
+// the cash purchases and refunds of the cashier and ends with the counted cash.
+type RegisterSession struct {
+	GormOwnedModel
+
+	Status        RegisterSessionStatus         `json:"status"        gorm:"type:TEXT;index;default:'open'"`
+	OpenedAt      time.Time                     `json:"openedAt"`
+	ClosedAt      *time.Time                    `json:"closedAt"`
+	OpeningFloat  decimal.Decimal               `json:"openingFloat"  gorm:"type:TEXT"`
+	CashIn        decimal.Decimal               `json:"cashIn"        gorm:"type:TEXT;default:'0'"`
+	CashOut       decimal.Decimal               `json:"cashOut"       gorm:"type:TEXT;default:'0'"`
+	ExpectedCash  decimal.Decimal               `json:"expectedCash"  gorm:"type:TEXT;default:'0'"`
+	CountedCash   *decimal.Decimal              `json:"countedCash"   gorm:"type:TEXT"`
+	Difference    *decimal.Decimal              `json:"difference"    gorm:"type:TEXT"`
+	Note          *string                       `json:"note"`
+	Denominations []RegisterSessionDenomination `json:"denominations" gorm:"foreignKey:RegisterSessionID"`
+}
+
+// RegisterSessionDenomination is the number of coins or notes of a value counted on closing.
+type RegisterSessionDenomination struct {
+	GormModel
+
+	RegisterSessionID int             `json:"registerSessionId" gorm:"index"`
+	Value             decimal.Decimal `json:"value"             gorm:"type:TEXT"`
+	Count             uint            `json:"count"`
+}
+
+func (d RegisterSessionDenomination) Total() decimal.Decimal {
+	return d.Value.Mul(decimal.NewFromUint64(uint64(d.Count)))
+}
+
+func (s RegisterSession) IsOpen() bool {
+	return s.Status == RegisterSessionStatusOpen
+}
+
+// ApplyCashTotals sets the cash taken and paid out during the session and the resulting
+// amount of cash expected in the till.
+func (s *RegisterSession) ApplyCashTotals(cashIn, cashOut decimal.Decimal) {
+	s.CashIn = cashIn
+	s.CashOut = cashOut
+	s.ExpectedCash = s.OpeningFloat.Add(cashIn).Sub(cashOut)
+}
+
+// Close records the counted cash and the difference to the expected cash.
+func (s *RegisterSession) Close(countedCash decimal.Decimal, closedAt time.Time) {
+	difference := countedCash.Sub(s.ExpectedCash)
+
+	s.Status = RegisterSessionStatusClosed
+	s.ClosedAt = &closedAt
+	s.CountedCash = &countedCash
+	s.Difference = &difference
+}
@@ -20,13 +20,15 @@ const (
 type User struct {
 	GormModel
 
-	ID                        int     `json:"id"       gorm:"primarykey"`
-	Username                  string  `json:"username" gorm:"unique"`
-	Email                     string  `json:"email"    gorm:"unique"`
-	Password                  string  `json:"-"`
-	Admin                     bool    `json:"admin"`
-	ChangePasswordToken       *string `json:"-"        gorm:"default:null"`
-	ChangePasswordTokenExpiry *int64  `json:"-"        gorm:"default:null"`
+	ID                        int        `json:"id"             gorm:"primarykey"`
+	Username                  string     `json:"username"       gorm:"unique"`
+	Email                     string     `json:"email"          gorm:"unique"`
+	Password                  string     `json:"-"`
+	Admin                     bool       `json:"admin"`
+	GrantDiscounts            bool       `json:"grantDiscounts"`
+	ChangePasswordToken       *string    `json:"-"              gorm:"default:null"`
+	ChangePasswordTokenExpiry *int64     `json:"-"              gorm:"default:null"`
+	Categories                []Category `json:"categories"     gorm:"many2many:user_categories"`
 }
 
 func (u *User) Role() string {
@@ -37,6 +39,11 @@ func (u *User) Role() string {
 	return "user"
 }
 
+// CanGrantDiscounts reports whether the user may apply discounts manually, without a code.
+func (u *User) CanGrantDiscounts() bool {
+	return u.Admin || u.GrantDiscounts
+}
+
 func (u *User) GravatarURL() string {
 	hasher := sha256.Sum256([]byte(strings.TrimSpace(u.Email)))
 	hash := hex.EncodeToString(hasher[:])
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrVoucherExpired             = errors.New("voucher has expired")
+	ErrVoucherInsufficientBalance = errors.New("voucher balance is insufficient")
+)
+
+const (
+	// voucherCodeAlphabet leaves out characters that are easily confused on a printout (0/O, 1/I).
+	voucherCodeAlphabet    = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+	voucherCodeGroups      = 3
+	voucherCodeGroupLength = 4
+)
+
+// Voucher is a prepaid voucher that can be redeemed with the VOUCHER payment method until its
+// balance is used up or it expires.
+type Voucher struct {
+	GormOwnedModel
+
+	Code        string              `json:"code"                  gorm:"uniqueIndex"`
+	Name        string              `json:"name"`
+	Value       decimal.Decimal     `json:"value"                 gorm:"type:TEXT"`
+	Balance     decimal.Decimal     `json:"balance"               gorm:"type:TEXT"`
+	ExpiresAt   *time.Time          `json:"expiresAt"`
+	Redemptions []VoucherRedemption `json:"redemptions,omitempty" gorm:"foreignKey:VoucherID"`
+}
+
+// VoucherRedemption is an amount taken from (or, if negative, credited back to) a voucher.
+type VoucherRedemption struct {
+	GormModel
+
+	VoucherID        int             `json:"voucherId"        gorm:"index"`
+	PurchaseID       uuid.UUID       `json:"purchaseId"       gorm:"type:text;index"`
+	PurchaseRefundID *int            `json:"purchaseRefundId"`
+	Amount           decimal.Decimal `json:"amount"           gorm:"type:TEXT"`
+}
+
+func (v Voucher) IsExpired(now time.Time) bool {
+	return v.ExpiresAt != nil && !now.Before(*v.ExpiresAt)
+}
+
+// CheckRedeemable checks whether the amount can be taken from the voucher.
+func (v Voucher) CheckRedeemable(amount decimal.Decimal, now time.Time) error {
+	if v.IsExpired(now) {
+		return ErrVoucherExpired
+	}
+
+	if v.Balance.LessThan(amount) {
+		return ErrVoucherInsufficientBalance
+	}
+
+	return nil
+}
+
+// GenerateVoucherCode returns a random code formatted for printing, e.g. "7KQM-X3RT-PW9C".
+func GenerateVoucherCode() (string, error) {
+	alphabetLength := big.NewInt(int64(len(voucherCodeAlphabet)))
+	code := make([]byte, 0, voucherCodeGroups*voucherCodeGroupLength)
+
+	for range voucherCodeGroups * voucherCodeGroupLength {
+		n, err := rand.Int(rand.Reader, alphabetLength)
+		if err != nil {
+			return "", err
+		}
+
+		code = append(code, voucherCodeAlphabet[n.Int64()])
+	}
+
+	return formatVoucherCode(string(code)), nil
+}
+
+// NormalizeVoucherCode brings a typed or scanned code into the printed format, so codes can be
+// entered without dashes and in lower case.
+func NormalizeVoucherCode(code string) string {
+	var builder strings.Builder
+
+	for _, r := range strings.ToUpper(code) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			builder.WriteRune(r)
+		}
+	}
+
+	return formatVoucherCode(builder.String())
+}
+
+func formatVoucherCode(code string) string {
+	var groups []string
+
+	for len(code) > voucherCodeGroupLength {
+		groups = append(groups, code[:voucherCodeGroupLength])
+		code = code[voucherCodeGroupLength:]
+	}
+
+	return strings.Join(append(groups, code), "-")
+}
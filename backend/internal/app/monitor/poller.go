@@ -2,34 +2,32 @@ package monitor
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/potibm/kasseapparat/internal/app/models"
-	sumupRepo "github.com/potibm/kasseapparat/internal/app/repository/sumup"
+	"github.com/potibm/kasseapparat/internal/app/repository/terminal"
+	purchaseService "github.com/potibm/kasseapparat/internal/app/service/purchase"
 )
 
-// List of final states where we can stop polling.
-var finalStates = map[string]bool{
-	"confirmed": true,
-	"canceled":  true,
-	"failed":    true,
-	"timeout":   true,
-}
-
-func isFinal(status string) bool {
-	return finalStates[status]
+// isFinal reports whether the payment of the purchase is decided, i.e. the polling can stop once
+// the purchase can no longer be confirmed.
+func isFinal(status models.PurchaseStatus) bool {
+	return !purchaseService.CanTransition(status, models.PurchaseStatusConfirmed)
 }
 
 type Poller interface {
 	Start(transactionID uuid.UUID)
+	// Stop ends the polling for the transaction, e.g. once its checkout is cancelled.
+	Stop(transactionID uuid.UUID)
 }
 
 type StatusPublisher interface {
 	PushUpdate(purchaseID uuid.UUID, status models.PurchaseStatus)
 }
 
-type SumupTransactionReader interface {
-	GetTransactionByClientTransactionID(clientTransactionID uuid.UUID) (*sumupRepo.Transaction, error)
+type CheckoutReader interface {
+	GetCheckout(clientTransactionID uuid.UUID) (*terminal.Checkout, error)
 }
 
 type PurchaseRepository interface {
@@ -41,27 +39,32 @@ type PurchaseStatusService interface {
 	FinalizePurchase(ctx context.Context, id uuid.UUID) (*models.Purchase, error)
 	CancelPurchase(ctx context.Context, id uuid.UUID) (*models.Purchase, error)
 	FailPurchase(ctx context.Context, id uuid.UUID) (*models.Purchase, error)
+	ExpirePendingPurchase(ctx context.Context, id uuid.UUID) (*models.Purchase, error)
 }
 
 type transactionPoller struct {
-	SumupRepository  SumupTransactionReader
+	TerminalProvider CheckoutReader
 	SqliteRepository PurchaseRepository
 	PurchaseService  PurchaseStatusService
 	StatusPublisher  StatusPublisher
-	active           map[string]struct{}
+	// PendingTimeout is the time after which a purchase still pending is expired, 0 never expires it.
+	PendingTimeout time.Duration
+	active         map[string]struct{}
 }
 
 func NewPoller(
-	sumupRp SumupTransactionReader,
+	terminalPrvdr CheckoutReader,
 	sqliteRp PurchaseRepository,
 	purchaseSrvc PurchaseStatusService,
 	statusPblshr StatusPublisher,
+	pendingTimeout time.Duration,
 ) Poller {
 	return &transactionPoller{
-		SumupRepository:  sumupRp,
+		TerminalProvider: terminalPrvdr,
 		SqliteRepository: sqliteRp,
 		PurchaseService:  purchaseSrvc,
 		StatusPublisher:  statusPblshr,
+		PendingTimeout:   pendingTimeout,
 		active:           make(map[string]struct{}),
 	}
 }
@@ -7,7 +7,7 @@ import (
 )
 
 var (
-	activePollers = make(map[string]struct{}) // transactionID → active
+	activePollers = make(map[string]chan struct{}) // transactionID → closed to stop the poller
 	mu            sync.Mutex
 )
 
@@ -19,7 +19,7 @@ func registerPoller(id uuid.UUID) bool {
 		return false // already running
 	}
 
-	activePollers[id.String()] = struct{}{}
+	activePollers[id.String()] = make(chan struct{})
 
 	return true
 }
@@ -30,3 +30,30 @@ func unregisterPoller(id uuid.UUID) {
 
 	delete(activePollers, id.String())
 }
+
+// stopChannel returns the channel that is closed when the poller is asked to stop, nil if none is running.
+func stopChannel(id uuid.UUID) <-chan struct{} {
+	mu.Lock()
+	defer mu.Unlock()
+
+	return activePollers[id.String()]
+}
+
+// stopPoller asks a running poller to stop. It reports whether one was running.
+func stopPoller(id uuid.UUID) bool {
+	mu.Lock()
+	defer mu.Unlock()
+
+	stop, exists := activePollers[id.String()]
+	if !exists {
+		return false
+	}
+
+	select {
+	case <-stop:
+	default:
+		close(stop)
+	}
+
+	return true
+}
@@ -73,3 +73,23 @@ func TestRegisterPollerConcurrency(t *testing.T) {
 	// Cleanup
 	unregisterPoller(id)
 }
+
+func TestStopPoller(t *testing.T) {
+	id := uuid.New()
+
+	assert.False(t, stopPoller(id), "Stopping a poller that is not running should report it")
+
+	registerPoller(id)
+	defer unregisterPoller(id)
+
+	stop := stopChannel(id)
+
+	assert.True(t, stopPoller(id))
+	assert.True(t, stopPoller(id), "Stopping twice must not panic")
+
+	select {
+	case <-stop:
+	default:
+		t.Error("The stop channel should be closed")
+	}
+}
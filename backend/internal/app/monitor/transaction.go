@@ -2,13 +2,15 @@ package monitor
 
 import (
 	"context"
+	"errors"
 	"log/slog"
-	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/potibm/kasseapparat/internal/app/handler/websocket"
 	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/potibm/kasseapparat/internal/app/repository/terminal"
+	purchaseService "github.com/potibm/kasseapparat/internal/app/service/purchase"
 )
 
 func (n *transactionPoller) Start(transactionID uuid.UUID) {
@@ -20,28 +22,56 @@ func (n *transactionPoller) Start(transactionID uuid.UUID) {
 		return // already running
 	}
 
+	stop := stopChannel(transactionID)
+
 	go func() {
 		defer unregisterPoller(transactionID)
 
 		slog.Debug("Polling started for transaction", "transaction_id", transactionID.String())
 
-		const pollingInterval = 5 * time.Second
+		interval := initialPollingInterval
 
-		ticker := time.NewTicker(pollingInterval)
-		defer ticker.Stop()
+		timer := time.NewTimer(interval)
+		defer timer.Stop()
 
-		for range ticker.C {
-			if n.handleTransactionPolling(transactionID) {
-				slog.Debug("Polling ended for transaction", "transaction_id", transactionID.String())
+		for {
+			select {
+			case <-stop:
+				slog.Debug("Polling stopped for transaction", "transaction_id", transactionID.String())
 
 				return
+			case <-timer.C:
+				if n.handleTransactionPolling(transactionID) {
+					slog.Debug("Polling ended for transaction", "transaction_id", transactionID.String())
+
+					return
+				}
+
+				interval = nextPollingInterval(interval)
+				timer.Reset(interval)
 			}
 		}
 	}()
 }
 
+const (
+	initialPollingInterval = 2 * time.Second
+	maxPollingInterval     = 30 * time.Second
+)
+
+// nextPollingInterval backs off exponentially, most payments are decided within the first seconds.
+func nextPollingInterval(interval time.Duration) time.Duration {
+	return min(2*interval, maxPollingInterval)
+}
+
+func (n *transactionPoller) Stop(transactionID uuid.UUID) {
+	if stopPoller(transactionID) {
+		slog.Debug("Stopping polling for transaction", "transaction_id", transactionID.String())
+	}
+}
+
 func (n *transactionPoller) handleTransactionPolling(transactionID uuid.UUID) bool {
-	ctx := context.Background()
+	ctx := purchaseService.WithTransitionOrigin(context.Background(), models.PurchaseTransitionSourcePoller, nil)
 
 	purchase, err := n.SqliteRepository.GetPurchaseByID(transactionID)
 	if err != nil {
@@ -56,7 +86,7 @@ func (n *transactionPoller) handleTransactionPolling(transactionID uuid.UUID) bo
 		return true
 	}
 
-	if isFinal(string(purchase.Status)) {
+	if isFinal(purchase.Status) {
 		n.StatusPublisher.PushUpdate(transactionID, purchase.Status)
 
 		slog.Info("Polling ended for transaction", "transaction_id", transactionID.String())
@@ -64,26 +94,31 @@ func (n *transactionPoller) handleTransactionPolling(transactionID uuid.UUID) bo
 		return true
 	}
 
+	// a checkout that never reached the terminal cannot be checked, the purchase expires by its age
 	if purchase.SumupClientTransactionID == nil {
 		slog.Info("No SumUp client transaction ID for transaction", "transaction_id", transactionID.String())
 
-		return true
+		if n.pendingTimedOut(purchase) {
+			return n.expirePendingPurchase(ctx, transactionID)
+		}
+
+		return n.PendingTimeout == 0
 	}
 
-	// Fetch current status from SumUp
-	transaction, err := n.SumupRepository.GetTransactionByClientTransactionID(*purchase.SumupClientTransactionID)
+	// Fetch current status from the payment terminal provider
+	checkout, err := n.TerminalProvider.GetCheckout(*purchase.SumupClientTransactionID)
 	if err != nil {
 		slog.Error(
-			"Error fetching transaction from SumUp",
+			"Error fetching checkout from the payment terminal provider",
 			"transaction_id",
 			purchase.SumupClientTransactionID.String(),
 			"error",
 			err,
 		)
 
-		if strings.Contains(err.Error(), "NOT_FOUND") {
+		if errors.Is(err, terminal.ErrCheckoutNotFound) {
 			slog.Info(
-				"Transaction not found in SumUp, stopping polling",
+				"Checkout not found, stopping polling",
 				"transaction_id",
 				purchase.SumupClientTransactionID.String(),
 			)
@@ -101,14 +136,14 @@ func (n *transactionPoller) handleTransactionPolling(transactionID uuid.UUID) bo
 		return false
 	}
 
-	if purchase.SumupTransactionID == nil {
+	if purchase.SumupTransactionID == nil && checkout.TransactionID != uuid.Nil {
 		purchase, err = n.SqliteRepository.UpdatePurchaseSumupTransactionIDByID(
 			transactionID,
-			transaction.TransactionID,
+			checkout.TransactionID,
 		)
 		if err != nil {
 			slog.Error(
-				"Error updating purchase with SumUp transaction ID",
+				"Error updating purchase with the transaction ID",
 				"transaction_id",
 				transactionID.String(),
 				"error",
@@ -117,15 +152,42 @@ func (n *transactionPoller) handleTransactionPolling(transactionID uuid.UUID) bo
 		}
 	}
 
-	slog.Info("Transaction status update", "transaction_id", transactionID.String(), "status", transaction.Status)
+	slog.Info("Transaction status update", "transaction_id", transactionID.String(), "status", checkout.Status)
 
-	return n.handleStatusUpdate(ctx, transactionID, transaction.Status, purchase)
+	if checkout.Status == terminal.CheckoutStatusPending && n.pendingTimedOut(purchase) {
+		return n.expirePendingPurchase(ctx, transactionID)
+	}
+
+	return n.handleStatusUpdate(ctx, transactionID, checkout.Status, purchase)
+}
+
+func (n *transactionPoller) pendingTimedOut(purchase *models.Purchase) bool {
+	return n.PendingTimeout > 0 && purchase != nil && time.Since(purchase.CreatedAt) >= n.PendingTimeout
+}
+
+// expirePendingPurchase fails a purchase whose payment is still pending after the timeout. The
+// purchase service checks the checkout once more after terminating it on the reader.
+func (n *transactionPoller) expirePendingPurchase(ctx context.Context, transactionID uuid.UUID) bool {
+	slog.Warn("Transaction still pending after the timeout, expiring it", "transaction_id", transactionID.String())
+
+	ctx = purchaseService.WithTransitionOrigin(ctx, models.PurchaseTransitionSourceSystem, nil)
+
+	purchase, err := n.PurchaseService.ExpirePendingPurchase(ctx, transactionID)
+	if purchase == nil {
+		slog.Error("Error expiring the purchase", "transaction_id", transactionID.String(), "error", err)
+
+		return false
+	}
+
+	n.StatusPublisher.PushUpdate(transactionID, purchase.Status)
+
+	return purchase.Status != models.PurchaseStatusPending
 }
 
 func (n *transactionPoller) handleStatusUpdate(
 	ctx context.Context,
 	transactionID uuid.UUID,
-	status string,
+	status terminal.CheckoutStatus,
 	purchase *models.Purchase,
 ) bool {
 	var (
@@ -134,16 +196,20 @@ func (n *transactionPoller) handleStatusUpdate(
 	)
 
 	switch status {
-	case "PENDING":
+	case terminal.CheckoutStatusPending:
 		slog.Info("Transaction is still pending, continuing to poll", "transaction_id", transactionID.String())
 		websocket.PushUpdate(transactionID, purchase.Status)
 
 		return false
-	case "SUCCESSFUL":
+	case terminal.CheckoutStatusSuccessful:
 		updatedPurchase, err = n.PurchaseService.FinalizePurchase(ctx, transactionID)
-	case "FAILED":
+		// the webhook confirmed the purchase first
+		if errors.Is(err, purchaseService.ErrPurchaseAlreadyConfirmed) {
+			err = nil
+		}
+	case terminal.CheckoutStatusFailed:
 		updatedPurchase, err = n.PurchaseService.FailPurchase(ctx, transactionID)
-	case "CANCELED":
+	case terminal.CheckoutStatusCancelled:
 		updatedPurchase, err = n.PurchaseService.CancelPurchase(ctx, transactionID)
 	default:
 		slog.Warn("Unknown transaction status", "status", status, "transaction_id", transactionID.String())
@@ -159,5 +225,5 @@ func (n *transactionPoller) handleStatusUpdate(
 
 	n.StatusPublisher.PushUpdate(transactionID, updatedPurchase.Status)
 
-	return isFinal(string(updatedPurchase.Status))
+	return isFinal(updatedPurchase.Status)
 }
@@ -2,12 +2,12 @@ package monitor
 
 import (
 	"context"
-	"fmt"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/potibm/kasseapparat/internal/app/models"
-	sumupRepo "github.com/potibm/kasseapparat/internal/app/repository/sumup"
+	"github.com/potibm/kasseapparat/internal/app/repository/terminal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -32,15 +32,15 @@ func (m *MockSqlite) UpdatePurchaseSumupTransactionIDByID(id, sID uuid.UUID) (*m
 	return args.Get(0).(*models.Purchase), args.Error(1)
 }
 
-type MockSumup struct{ mock.Mock }
+type MockTerminal struct{ mock.Mock }
 
-func (m *MockSumup) GetTransactionByClientTransactionID(id uuid.UUID) (*sumupRepo.Transaction, error) {
+func (m *MockTerminal) GetCheckout(id uuid.UUID) (*terminal.Checkout, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 
-	return args.Get(0).(*sumupRepo.Transaction), args.Error(1)
+	return args.Get(0).(*terminal.Checkout), args.Error(1)
 }
 
 type MockService struct{ mock.Mock }
@@ -72,6 +72,15 @@ func (m *MockService) CancelPurchase(ctx context.Context, id uuid.UUID) (*models
 	return args.Get(0).(*models.Purchase), args.Error(1)
 }
 
+func (m *MockService) ExpirePendingPurchase(ctx context.Context, id uuid.UUID) (*models.Purchase, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*models.Purchase), args.Error(1)
+}
+
 type MockPublisher struct{ mock.Mock }
 
 func (m *MockPublisher) PushUpdate(id uuid.UUID, status models.PurchaseStatus) {
@@ -84,13 +93,13 @@ func TestHandleTransactionPollingSuccess(t *testing.T) {
 	sTransID := uuid.New()
 
 	mSqlite := new(MockSqlite)
-	mSumup := new(MockSumup)
+	mTerminal := new(MockTerminal)
 	mService := new(MockService)
 	mPub := new(MockPublisher)
 
 	poller := &transactionPoller{
 		SqliteRepository: mSqlite,
-		SumupRepository:  mSumup,
+		TerminalProvider: mTerminal,
 		PurchaseService:  mService,
 		StatusPublisher:  mPub,
 	}
@@ -100,16 +109,17 @@ func TestHandleTransactionPollingSuccess(t *testing.T) {
 		ID:                       tID,
 		PaymentMethod:            models.PaymentMethodSumUp,
 		SumupClientTransactionID: &sClientID,
+		Status:                   models.PurchaseStatusPending,
 	}
 	mSqlite.On("GetPurchaseByID", tID).Return(p, nil)
 
-	// 2. Mock: SumUp returns SUCCESSFUL
-	mSumup.On("GetTransactionByClientTransactionID", sClientID).Return(&sumupRepo.Transaction{
+	// 2. Mock: the terminal provider returns SUCCESSFUL
+	mTerminal.On("GetCheckout", sClientID).Return(&terminal.Checkout{
 		TransactionID: sTransID,
-		Status:        "SUCCESSFUL",
+		Status:        terminal.CheckoutStatusSuccessful,
 	}, nil)
 
-	// 3. Mock: Update DB with SumUp ID
+	// 3. Mock: Update DB with the transaction ID
 	mSqlite.On("UpdatePurchaseSumupTransactionIDByID", tID, sTransID).Return(p, nil)
 
 	// 4. Mock: Service finalizes the purchase
@@ -129,22 +139,27 @@ func TestHandleTransactionPollingNotFound(t *testing.T) {
 	sClientID := uuid.New()
 
 	mSqlite := new(MockSqlite)
-	mSumup := new(MockSumup)
+	mTerminal := new(MockTerminal)
 	mService := new(MockService)
 	mPub := new(MockPublisher)
 
 	poller := &transactionPoller{
 		SqliteRepository: mSqlite,
-		SumupRepository:  mSumup,
+		TerminalProvider: mTerminal,
 		PurchaseService:  mService,
 		StatusPublisher:  mPub,
 	}
 
-	p := &models.Purchase{ID: tID, PaymentMethod: models.PaymentMethodSumUp, SumupClientTransactionID: &sClientID}
+	p := &models.Purchase{
+		ID:                       tID,
+		PaymentMethod:            models.PaymentMethodSumUp,
+		SumupClientTransactionID: &sClientID,
+		Status:                   models.PurchaseStatusPending,
+	}
 	mSqlite.On("GetPurchaseByID", tID).Return(p, nil)
 
-	// Simulate NOT_FOUND
-	mSumup.On("GetTransactionByClientTransactionID", sClientID).Return(nil, fmt.Errorf("SumUp error: NOT_FOUND"))
+	// Simulate an unknown checkout
+	mTerminal.On("GetCheckout", sClientID).Return(nil, terminal.ErrCheckoutNotFound)
 
 	mService.On("FailPurchase", mock.Anything, tID).Return(&models.Purchase{Status: models.PurchaseStatusFailed}, nil)
 	mPub.On("PushUpdate", tID, models.PurchaseStatusFailed).Return()
@@ -153,3 +168,153 @@ func TestHandleTransactionPollingNotFound(t *testing.T) {
 
 	assert.True(t, shouldStop)
 }
+
+func TestHandleTransactionPollingCancelled(t *testing.T) {
+	tID := uuid.New()
+	sClientID := uuid.New()
+
+	mSqlite := new(MockSqlite)
+	mTerminal := new(MockTerminal)
+	mService := new(MockService)
+	mPub := new(MockPublisher)
+
+	poller := &transactionPoller{
+		SqliteRepository: mSqlite,
+		TerminalProvider: mTerminal,
+		PurchaseService:  mService,
+		StatusPublisher:  mPub,
+	}
+
+	p := &models.Purchase{
+		ID:                       tID,
+		PaymentMethod:            models.PaymentMethodSumUp,
+		SumupClientTransactionID: &sClientID,
+		Status:                   models.PurchaseStatusPending,
+	}
+	mSqlite.On("GetPurchaseByID", tID).Return(p, nil)
+
+	// a checkout cancelled on the terminal has no transaction ID yet
+	mTerminal.On("GetCheckout", sClientID).Return(&terminal.Checkout{Status: terminal.CheckoutStatusCancelled}, nil)
+
+	cancelledP := &models.Purchase{ID: tID, Status: models.PurchaseStatusCancelled}
+	mService.On("CancelPurchase", mock.Anything, tID).Return(cancelledP, nil)
+	mPub.On("PushUpdate", tID, models.PurchaseStatusCancelled).Return()
+
+	poller.handleTransactionPolling(tID)
+
+	mService.AssertExpectations(t)
+	mSqlite.AssertNotCalled(t, "UpdatePurchaseSumupTransactionIDByID", mock.Anything, mock.Anything)
+}
+
+func TestHandleTransactionPollingExpiresAfterTheTimeout(t *testing.T) {
+	tID := uuid.New()
+	sClientID := uuid.New()
+
+	mSqlite := new(MockSqlite)
+	mTerminal := new(MockTerminal)
+	mService := new(MockService)
+	mPub := new(MockPublisher)
+
+	poller := &transactionPoller{
+		SqliteRepository: mSqlite,
+		TerminalProvider: mTerminal,
+		PurchaseService:  mService,
+		StatusPublisher:  mPub,
+		PendingTimeout:   10 * time.Minute,
+	}
+
+	p := &models.Purchase{
+		ID:                       tID,
+		CreatedAt:                time.Now().Add(-11 * time.Minute),
+		PaymentMethod:            models.PaymentMethodSumUp,
+		SumupClientTransactionID: &sClientID,
+		Status:                   models.PurchaseStatusPending,
+	}
+	mSqlite.On("GetPurchaseByID", tID).Return(p, nil)
+	mTerminal.On("GetCheckout", sClientID).Return(&terminal.Checkout{Status: terminal.CheckoutStatusPending}, nil)
+
+	expiredP := &models.Purchase{ID: tID, Status: models.PurchaseStatusFailed}
+	mService.On("ExpirePendingPurchase", mock.Anything, tID).Return(expiredP, nil)
+	mPub.On("PushUpdate", tID, models.PurchaseStatusFailed).Return()
+
+	assert.True(t, poller.handleTransactionPolling(tID))
+
+	mService.AssertExpectations(t)
+	mPub.AssertExpectations(t)
+}
+
+func TestHandleTransactionPollingKeepsPollingBeforeTheTimeout(t *testing.T) {
+	tID := uuid.New()
+	sClientID := uuid.New()
+
+	mSqlite := new(MockSqlite)
+	mTerminal := new(MockTerminal)
+	mService := new(MockService)
+
+	poller := &transactionPoller{
+		SqliteRepository: mSqlite,
+		TerminalProvider: mTerminal,
+		PurchaseService:  mService,
+		StatusPublisher:  new(MockPublisher),
+		PendingTimeout:   10 * time.Minute,
+	}
+
+	p := &models.Purchase{
+		ID:                       tID,
+		CreatedAt:                time.Now().Add(-time.Minute),
+		PaymentMethod:            models.PaymentMethodSumUp,
+		SumupClientTransactionID: &sClientID,
+		Status:                   models.PurchaseStatusPending,
+	}
+	mSqlite.On("GetPurchaseByID", tID).Return(p, nil)
+	mTerminal.On("GetCheckout", sClientID).Return(&terminal.Checkout{Status: terminal.CheckoutStatusPending}, nil)
+
+	assert.False(t, poller.handleTransactionPolling(tID))
+
+	mService.AssertNotCalled(t, "ExpirePendingPurchase", mock.Anything, mock.Anything)
+}
+
+func TestHandleTransactionPollingExpiresAPurchaseWithoutCheckoutByAge(t *testing.T) {
+	tID := uuid.New()
+
+	mSqlite := new(MockSqlite)
+	mTerminal := new(MockTerminal)
+	mService := new(MockService)
+	mPub := new(MockPublisher)
+
+	poller := &transactionPoller{
+		SqliteRepository: mSqlite,
+		TerminalProvider: mTerminal,
+		PurchaseService:  mService,
+		StatusPublisher:  mPub,
+		PendingTimeout:   10 * time.Minute,
+	}
+
+	p := &models.Purchase{
+		ID:            tID,
+		CreatedAt:     time.Now().Add(-time.Minute),
+		PaymentMethod: models.PaymentMethodSumUp,
+		Status:        models.PurchaseStatusPending,
+	}
+	mSqlite.On("GetPurchaseByID", tID).Return(p, nil)
+
+	assert.False(t, poller.handleTransactionPolling(tID))
+	mService.AssertNotCalled(t, "ExpirePendingPurchase", mock.Anything, mock.Anything)
+
+	p.CreatedAt = time.Now().Add(-11 * time.Minute)
+
+	expiredP := &models.Purchase{ID: tID, Status: models.PurchaseStatusFailed}
+	mService.On("ExpirePendingPurchase", mock.Anything, tID).Return(expiredP, nil)
+	mPub.On("PushUpdate", tID, models.PurchaseStatusFailed).Return()
+
+	assert.True(t, poller.handleTransactionPolling(tID))
+
+	mService.AssertExpectations(t)
+	mTerminal.AssertNotCalled(t, "GetCheckout", mock.Anything)
+}
+
+func TestNextPollingIntervalBacksOffUpToTheMaximum(t *testing.T) {
+	assert.Equal(t, 4*time.Second, nextPollingInterval(initialPollingInterval))
+	assert.Equal(t, maxPollingInterval, nextPollingInterval(20*time.Second))
+	assert.Equal(t, maxPollingInterval, nextPollingInterval(maxPollingInterval))
+}
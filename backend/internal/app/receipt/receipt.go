@@ -0,0 +1,265 @@
+package receipt
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/potibm/kasseapparat/internal/app/config"
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/potibm/kasseapparat/internal/app/repository/terminal"
+	"github.com/shopspring/decimal"
+)
+
+// CheckoutLookup resolves the checkout of a card payment line to print its transaction code. It is
+// implemented by the payment terminal providers.
+type CheckoutLookup interface {
+	GetCheckout(clientTransactionID uuid.UUID) (*terminal.Checkout, error)
+}
+
+type Receipt struct {
+	Header          []string
+	Footer          []string
+	PurchaseID      uuid.UUID
+	ReceiptNumber   string
+	CreatedAt       time.Time
+	Cashier         string
+	Status          models.PurchaseStatus
+	Currency        string
+	Lines           []Line
+	Discounts       []Discount
+	VATRates        []VATRate
+	TotalNetPrice   decimal.Decimal
+	TotalVATAmount  decimal.Decimal
+	TotalGrossPrice decimal.Decimal
+	Rounding        decimal.Decimal
+	Payments        []Payment
+	RefundedAmount  decimal.Decimal
+}
+
+type Line struct {
+	Quantity        uint
+	Name            string
+	UnitGrossPrice  decimal.Decimal
+	TotalGrossPrice decimal.Decimal
+	VATCode         string
+}
+
+// Discount is a discount printed below the item lines. The discount lines of the purchase are
+// summed up per discount and VAT rate.
+type Discount struct {
+	Name        string
+	GrossAmount decimal.Decimal
+	VATCode     string
+}
+
+type VATRate struct {
+	Code       string
+	Rate       decimal.Decimal
+	NetPrice   decimal.Decimal
+	VATAmount  decimal.Decimal
+	GrossPrice decimal.Decimal
+}
+
+type Payment struct {
+	PaymentMethod   models.PaymentMethod
+	Name            string
+	Amount          decimal.Decimal
+	TransactionCode string
+}
+
+type Renderer struct {
+	config         config.ReceiptConfig
+	paymentMethods config.PaymentMethods
+	currency       string
+	decimalPlaces  int32
+	checkouts      CheckoutLookup
+}
+
+func NewRenderer(cfg config.Config, checkouts CheckoutLookup) *Renderer {
+	receiptConfig := cfg.Receipt
+	if receiptConfig.Width == 0 {
+		receiptConfig.Width = config.DefaultReceiptWidth
+	}
+
+	return &Renderer{
+		config:         receiptConfig,
+		paymentMethods: cfg.PaymentMethods,
+		currency:       cfg.Format.Currency.Code,
+		decimalPlaces:  cfg.Format.Currency.FractionDigitsMax,
+		checkouts:      checkouts,
+	}
+}
+
+// Build collects everything printed on the receipt of the purchase. VAT rates are labelled
+// with letters in ascending order of the rate, which are referenced by the item lines.
+func (r *Renderer) Build(purchase models.Purchase) Receipt {
+	receipt := Receipt{
+		Header:          splitLines(r.config.Header),
+		Footer:          splitLines(r.config.Footer),
+		PurchaseID:      purchase.ID,
+		CreatedAt:       purchase.CreatedAt,
+		Status:          purchase.Status,
+		Currency:        r.currency,
+		TotalNetPrice:   purchase.TotalNetPrice,
+		TotalVATAmount:  purchase.TotalGrossPrice.Sub(purchase.TotalNetPrice),
+		TotalGrossPrice: purchase.TotalGrossPrice,
+		Rounding:        decimal.Zero,
+		RefundedAmount:  decimal.Zero,
+	}
+
+	if purchase.ReceiptNumber != nil {
+		receipt.ReceiptNumber = *purchase.ReceiptNumber
+	}
+
+	if purchase.CreatedBy != nil {
+		receipt.Cashier = purchase.CreatedBy.Username
+	}
+
+	receipt.VATRates = r.buildVATRates(purchase.PurchaseItems)
+
+	for _, item := range purchase.PurchaseItems {
+		receipt.Lines = append(receipt.Lines, Line{
+			Quantity:        item.Quantity,
+			Name:            item.Name(),
+			UnitGrossPrice:  item.GrossPrice(r.decimalPlaces),
+			TotalGrossPrice: item.TotalGrossPrice(r.decimalPlaces),
+			VATCode:         vatCode(receipt.VATRates, item.VATRate),
+		})
+	}
+
+	receipt.Discounts = buildDiscounts(purchase.DiscountLines(), receipt.VATRates)
+
+	for _, payment := range purchase.PaymentLines() {
+		receipt.Payments = append(receipt.Payments, Payment{
+			PaymentMethod:   payment.PaymentMethod,
+			Name:            r.paymentMethodName(payment.PaymentMethod),
+			Amount:          payment.Amount,
+			TransactionCode: r.transactionCode(payment),
+		})
+	}
+
+	// the payments show the amounts paid, so the rounding is added to the last line of its payment method
+	for _, rounding := range purchase.RoundingLines() {
+		receipt.Rounding = receipt.Rounding.Add(rounding.Amount)
+
+		for i := len(receipt.Payments) - 1; i >= 0; i-- {
+			if receipt.Payments[i].PaymentMethod == rounding.PaymentMethod {
+				receipt.Payments[i].Amount = receipt.Payments[i].Amount.Add(rounding.Amount)
+
+				break
+			}
+		}
+	}
+
+	for _, refund := range purchase.Refunds {
+		receipt.RefundedAmount = receipt.RefundedAmount.Add(refund.TotalGrossPrice)
+	}
+
+	return receipt
+}
+
+func (r *Renderer) buildVATRates(items []models.PurchaseItem) []VATRate {
+	var rates []VATRate
+
+	for _, item := range items {
+		i := slices.IndexFunc(rates, func(rate VATRate) bool {
+			return rate.Rate.Equal(item.VATRate)
+		})
+		if i < 0 {
+			rates = append(rates, VATRate{
+				Rate:       item.VATRate,
+				NetPrice:   decimal.Zero,
+				VATAmount:  decimal.Zero,
+				GrossPrice: decimal.Zero,
+			})
+			i = len(rates) - 1
+		}
+
+		rates[i].NetPrice = rates[i].NetPrice.Add(item.TotalDiscountedNetPrice(r.decimalPlaces))
+		rates[i].GrossPrice = rates[i].GrossPrice.Add(item.TotalDiscountedGrossPrice(r.decimalPlaces))
+		rates[i].VATAmount = rates[i].GrossPrice.Sub(rates[i].NetPrice)
+	}
+
+	slices.SortFunc(rates, func(a, b VATRate) int {
+		return a.Rate.Cmp(b.Rate)
+	})
+
+	for i := range rates {
+		rates[i].Code = string(rune('A' + i))
+	}
+
+	return rates
+}
+
+func buildDiscounts(lines []models.PurchaseDiscount, rates []VATRate) []Discount {
+	var discounts []Discount
+
+	for _, line := range lines {
+		code := vatCode(rates, line.VATRate)
+
+		i := slices.IndexFunc(discounts, func(discount Discount) bool {
+			return discount.Name == line.Name && discount.VATCode == code
+		})
+		if i < 0 {
+			discounts = append(discounts, Discount{Name: line.Name, GrossAmount: decimal.Zero, VATCode: code})
+			i = len(discounts) - 1
+		}
+
+		discounts[i].GrossAmount = discounts[i].GrossAmount.Add(line.GrossAmount)
+	}
+
+	return discounts
+}
+
+func vatCode(rates []VATRate, rate decimal.Decimal) string {
+	for _, r := range rates {
+		if r.Rate.Equal(rate) {
+			return r.Code
+		}
+	}
+
+	return ""
+}
+
+func (r *Renderer) paymentMethodName(code models.PaymentMethod) string {
+	if name := r.paymentMethods.GetName(code); name != nil {
+		return *name
+	}
+
+	return string(code)
+}
+
+// transactionCode returns the transaction code of the card payment line. If the payment terminal
+// provider cannot be reached the transaction ID is printed instead, so the payment can still be traced.
+func (r *Renderer) transactionCode(payment models.PurchasePayment) string {
+	if payment.PaymentMethod != models.PaymentMethodSumUp || r.checkouts == nil {
+		return ""
+	}
+
+	if payment.SumupClientTransactionID != nil {
+		checkout, err := r.checkouts.GetCheckout(*payment.SumupClientTransactionID)
+		if err == nil && checkout.TransactionCode != "" {
+			return checkout.TransactionCode
+		}
+
+		slog.Warn("Unable to retrieve the transaction code for the receipt", "error", err)
+	}
+
+	if payment.SumupTransactionID != nil {
+		return payment.SumupTransactionID.String()
+	}
+
+	return ""
+}
+
+func splitLines(text string) []string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+
+	return strings.Split(text, "\n")
+}
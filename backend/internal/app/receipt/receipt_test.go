@@ -0,0 +1,146 @@
+package receipt
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/potibm/kasseapparat/internal/app/config"
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/potibm/kasseapparat/internal/app/repository/terminal"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockCheckoutLookup struct{}
+
+func (m mockCheckoutLookup) GetCheckout(clientTransactionID uuid.UUID) (*terminal.Checkout, error) {
+	if clientTransactionID == unknownClientTransactionID {
+		return nil, terminal.ErrCheckoutNotFound
+	}
+
+	return &terminal.Checkout{ClientTransactionID: clientTransactionID, TransactionCode: "TEENSK4W2K"}, nil
+}
+
+var unknownClientTransactionID = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+func newTestRenderer() *Renderer {
+	cfg := config.Config{
+		Format: config.FormatConfig{
+			Currency: config.CurrencyFormatConfig{Code: "EUR", FractionDigitsMax: 2},
+		},
+		PaymentMethods: config.PaymentMethods{
+			{Code: models.PaymentMethodCash, Name: "Cash"},
+			{Code: models.PaymentMethodSumUp, Name: "SumUp"},
+		},
+		Receipt: config.ReceiptConfig{
+			Header: "Demoparty e.V.\nExample Street 1",
+			Footer: "Thank you!",
+			Width:  32,
+		},
+	}
+
+	return NewRenderer(cfg, mockCheckoutLookup{})
+}
+
+func newTestPurchase() models.Purchase {
+	transactionID := uuid.New()
+	clientTransactionID := uuid.New()
+
+	purchase := models.Purchase{
+		ID:              uuid.New(),
+		CreatedAt:       time.Date(2026, 10, 18, 21, 30, 0, 0, time.UTC),
+		TotalNetPrice:   decimal.NewFromInt(30),
+		TotalGrossPrice: decimal.NewFromFloat(33.8),
+		Status:          models.PurchaseStatusConfirmed,
+		PurchaseItems: []models.PurchaseItem{
+			{
+				Product:  models.Product{Name: "Entrance ticket with a very long name"},
+				Quantity: 2,
+				NetPrice: decimal.NewFromInt(10),
+				VATRate:  decimal.NewFromInt(19),
+			},
+			{
+				Product:  models.Product{Name: "Sticker"},
+				Quantity: 1,
+				NetPrice: decimal.NewFromInt(10),
+				VATRate:  decimal.NewFromInt(0),
+			},
+		},
+		Payments: []models.PurchasePayment{
+			{PaymentMethod: models.PaymentMethodCash, Amount: decimal.NewFromFloat(13.8)},
+			{
+				PaymentMethod:            models.PaymentMethodSumUp,
+				Amount:                   decimal.NewFromInt(20),
+				SumupTransactionID:       &transactionID,
+				SumupClientTransactionID: &clientTransactionID,
+			},
+		},
+	}
+	purchase.CreatedBy = &models.User{Username: "demo"}
+
+	return purchase
+}
+
+func TestBuildReceipt(t *testing.T) {
+	receipt := newTestRenderer().Build(newTestPurchase())
+
+	assert.Equal(t, []string{"Demoparty e.V.", "Example Street 1"}, receipt.Header)
+	assert.Equal(t, "demo", receipt.Cashier)
+
+	require.Len(t, receipt.VATRates, 2)
+	assert.Equal(t, "A", receipt.VATRates[0].Code)
+	assert.True(t, decimal.Zero.Equal(receipt.VATRates[0].Rate))
+	assert.Equal(t, "B", receipt.VATRates[1].Code)
+	assert.True(t, decimal.NewFromFloat(3.8).Equal(receipt.VATRates[1].VATAmount))
+
+	require.Len(t, receipt.Lines, 2)
+	assert.Equal(t, "B", receipt.Lines[0].VATCode)
+	assert.True(t, decimal.NewFromFloat(23.8).Equal(receipt.Lines[0].TotalGrossPrice))
+
+	require.Len(t, receipt.Payments, 2)
+	assert.Equal(t, "", receipt.Payments[0].TransactionCode)
+	assert.Equal(t, "TEENSK4W2K", receipt.Payments[1].TransactionCode)
+}
+
+func TestBuildReceiptPrintsTheTransactionIDWithoutCheckout(t *testing.T) {
+	purchase := newTestPurchase()
+	purchase.Payments[1].SumupClientTransactionID = &unknownClientTransactionID
+
+	receipt := newTestRenderer().Build(purchase)
+
+	require.Len(t, receipt.Payments, 2)
+	assert.Equal(t, purchase.Payments[1].SumupTransactionID.String(), receipt.Payments[1].TransactionCode)
+}
+
+func TestRenderText(t *testing.T) {
+	renderer := newTestRenderer()
+
+	var out bytes.Buffer
+	require.NoError(t, renderer.RenderText(&out, renderer.Build(newTestPurchase())))
+
+	text := out.String()
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		assert.LessOrEqual(t, len([]rune(line)), 32, "line exceeds the receipt width: %q", line)
+	}
+
+	assert.Contains(t, text, "TOTAL EUR                  33.80")
+	assert.Contains(t, text, "2 x Entrance ticket with 23.80 B")
+	assert.Contains(t, text, "TEENSK4W2K")
+	assert.Contains(t, text, "Thank you!")
+}
+
+func TestRenderHTML(t *testing.T) {
+	renderer := newTestRenderer()
+
+	var out bytes.Buffer
+	require.NoError(t, renderer.RenderHTML(&out, renderer.Build(newTestPurchase())))
+
+	html := out.String()
+	assert.Contains(t, html, "Demoparty e.V.")
+	assert.Contains(t, html, "33.80")
+	assert.Contains(t, html, "Transaction TEENSK4W2K")
+}
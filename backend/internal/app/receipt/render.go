@@ -0,0 +1,91 @@
+package receipt
+
+import (
+	"fmt"
+	htmlTemplate "html/template"
+	"io"
+	"strings"
+	textTemplate "text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/potibm/kasseapparat/templates"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	htmlTemplateFile = "receipts/receipt.html"
+	textTemplateFile = "receipts/receipt.txt"
+	timeFormat       = "2006-01-02 15:04"
+)
+
+func (r *Renderer) RenderHTML(w io.Writer, receipt Receipt) error {
+	tpl, err := htmlTemplate.New("receipt.html").
+		Funcs(htmlTemplate.FuncMap(r.funcs())).
+		ParseFS(templates.ReceiptTemplateFiles, htmlTemplateFile)
+	if err != nil {
+		return fmt.Errorf("failed to parse receipt template: %w", err)
+	}
+
+	if err := tpl.Execute(w, receipt); err != nil {
+		return fmt.Errorf("failed to execute receipt template: %w", err)
+	}
+
+	return nil
+}
+
+// RenderText renders the receipt as fixed-width text for receipt printers.
+func (r *Renderer) RenderText(w io.Writer, receipt Receipt) error {
+	tpl, err := textTemplate.New("receipt.txt").
+		Funcs(r.funcs()).
+		ParseFS(templates.ReceiptTemplateFiles, textTemplateFile)
+	if err != nil {
+		return fmt.Errorf("failed to parse receipt template: %w", err)
+	}
+
+	if err := tpl.Execute(w, receipt); err != nil {
+		return fmt.Errorf("failed to execute receipt template: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Renderer) funcs() textTemplate.FuncMap {
+	return textTemplate.FuncMap{
+		"amount": func(value decimal.Decimal) string {
+			return value.StringFixed(r.decimalPlaces)
+		},
+		"formatTime": func(value time.Time) string {
+			return value.Format(timeFormat)
+		},
+		"center":  r.center,
+		"columns": r.columns,
+		"rule": func() string {
+			return strings.Repeat("-", r.config.Width)
+		},
+	}
+}
+
+func (r *Renderer) center(text string) string {
+	const half = 2
+
+	padding := (r.config.Width - utf8.RuneCountInString(text)) / half
+	if padding <= 0 {
+		return text
+	}
+
+	return strings.Repeat(" ", padding) + text
+}
+
+// columns aligns the left text to the left and the right text to the right edge of the line.
+// Left texts that do not fit are cut off.
+func (r *Renderer) columns(left, right string) string {
+	space := max(r.config.Width-utf8.RuneCountInString(right)-1, 0)
+	if utf8.RuneCountInString(left) > space {
+		left = string([]rune(left)[:space])
+	}
+
+	padding := max(r.config.Width-utf8.RuneCountInString(left)-utf8.RuneCountInString(right), 1)
+
+	return left + strings.Repeat(" ", padding) + right
+}
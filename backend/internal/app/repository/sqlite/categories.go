@@ -0,0 +1,136 @@
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"gorm.io/gorm"
+)
+
+var ErrCategoryNotFound = errors.New("category not found")
+
+type CategoryFilters struct {
+	Query string
+	IDs   []int
+}
+
+var categorySortFieldMappings = map[string]string{
+	"id":   "categories.id",
+	"name": "LOWER(categories.name)",
+	"pos":  "categories.pos",
+}
+
+func (filters CategoryFilters) AddWhere(query *gorm.DB) *gorm.DB {
+	if len(filters.IDs) > 0 {
+		query = query.Where("categories.id IN ?", filters.IDs)
+	}
+
+	if filters.Query != "" {
+		query = query.Where("categories.name LIKE ?", "%"+filters.Query+"%")
+	}
+
+	return query
+}
+
+func (repo *Repository) GetCategories(
+	limit int,
+	offset int,
+	sort string,
+	order string,
+	filters CategoryFilters,
+) ([]models.Category, error) {
+	if order != "ASC" && order != "DESC" {
+		order = "ASC"
+	}
+
+	sortField, exists := categorySortFieldMappings[sort]
+	if !exists {
+		return nil, errors.New("invalid sort field name")
+	}
+
+	var categories []models.Category
+
+	query := repo.db.Model(&models.Category{}).
+		Order(sortField + " " + order + ", categories.id ASC").
+		Limit(limit).
+		Offset(offset)
+	query = filters.AddWhere(query)
+
+	if err := query.Find(&categories).Error; err != nil {
+		return nil, errors.New("categories not found")
+	}
+
+	return categories, nil
+}
+
+func (repo *Repository) GetTotalCategories(filters CategoryFilters) (int64, error) {
+	var totalRows int64
+
+	query := repo.db.Model(&models.Category{})
+	query = filters.AddWhere(query)
+
+	if err := query.Count(&totalRows).Error; err != nil {
+		return 0, err
+	}
+
+	return totalRows, nil
+}
+
+func (repo *Repository) GetCategoryByID(id int) (*models.Category, error) {
+	var category models.Category
+	if err := repo.db.First(&category, id).Error; err != nil {
+		return nil, ErrCategoryNotFound
+	}
+
+	return &category, nil
+}
+
+func (repo *Repository) CreateCategory(category models.Category) (models.Category, error) {
+	if err := repo.db.Create(&category).Error; err != nil {
+		return category, fmt.Errorf("unable to store the category: %w", err)
+	}
+
+	return category, nil
+}
+
+func (repo *Repository) UpdateCategoryByID(id int, updatedCategory models.Category) (*models.Category, error) {
+	var category models.Category
+	if err := repo.db.First(&category, id).Error; err != nil {
+		return nil, ErrCategoryNotFound
+	}
+
+	category.Name = updatedCategory.Name
+	category.Pos = updatedCategory.Pos
+	category.Color = updatedCategory.Color
+	category.Icon = updatedCategory.Icon
+	category.UpdatedByID = updatedCategory.UpdatedByID
+
+	if err := repo.db.Save(&category).Error; err != nil {
+		return nil, fmt.Errorf("failed to update category: %w", err)
+	}
+
+	return &category, nil
+}
+
+// DeleteCategory deletes the category. Its products are no longer assigned to a category and the
+// users restricted to it lose the restriction.
+func (repo *Repository) DeleteCategory(category models.Category, deletedBy models.User) error {
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.Product{}).Where("category_id = ?", category.ID).Update("category_id", nil).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Exec("DELETE FROM user_categories WHERE category_id = ?", category.ID).Error; err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.Category{}).Where(whereIDEquals, category.ID).Update("DeletedByID", deletedBy.ID).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Delete(&category).Error
+	})
+}
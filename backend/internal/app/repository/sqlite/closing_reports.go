@@ -0,0 +1,205 @@
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrClosingReportNotFound = errors.New("closing report not found")
+	ErrClosingReportOverlaps = errors.New("the period overlaps with an existing closing report")
+)
+
+var closingReportSortFieldMappings = map[string]string{
+	"id":          "closing_reports.id",
+	"number":      "closing_reports.number",
+	"periodStart": "closing_reports.period_start",
+	"periodEnd":   "closing_reports.period_end",
+	"createdAt":   "closing_reports.created_at",
+}
+
+// GetClosingReportFigures sums up the purchases, refunds and cancellations of a period. The
+// period includes its start and excludes its end.
+func (repo *Repository) GetClosingReportFigures(
+	periodStart time.Time,
+	periodEnd time.Time,
+) (models.ClosingReportFigures, error) {
+	builder := models.NewClosingReportBuilder(repo.decimalPlaces)
+	withDeletedProducts := func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}
+
+	var purchases []models.Purchase
+
+	err := repo.db.
+		Preload("PurchaseItems").
+		Preload("PurchaseItems.Product", withDeletedProducts).
+		Preload("PurchaseItems.Discounts").
+		Preload("Payments").
+		Preload("Roundings").
+		Where("purchases.created_at >= ? AND purchases.created_at < ?", periodStart, periodEnd).
+		Where("purchases.status IN ?",
+			models.PurchaseStatusList{models.PurchaseStatusConfirmed, models.PurchaseStatusRefunded}).
+		Find(&purchases).Error
+	if err != nil {
+		return models.ClosingReportFigures{}, fmt.Errorf("unable to retrieve the purchases: %w", err)
+	}
+
+	for _, purchase := range purchases {
+		builder.AddPurchase(purchase)
+	}
+
+	var refunds []models.PurchaseRefund
+
+	err = repo.db.
+		Preload("Items").
+		Preload("Payments").
+		Preload("Roundings").
+		Where("purchase_refunds.created_at >= ? AND purchase_refunds.created_at < ?", periodStart, periodEnd).
+		Find(&refunds).Error
+	if err != nil {
+		return models.ClosingReportFigures{}, fmt.Errorf("unable to retrieve the refunds: %w", err)
+	}
+
+	purchaseItems, err := repo.getRefundedPurchaseItems(refunds, withDeletedProducts)
+	if err != nil {
+		return models.ClosingReportFigures{}, err
+	}
+
+	for _, refund := range refunds {
+		builder.AddRefund(refund, purchaseItems)
+	}
+
+	var cancellations []models.Purchase
+
+	err = repo.db.
+		Where("purchases.created_at >= ? AND purchases.created_at < ?", periodStart, periodEnd).
+		Where("purchases.status = ?", models.PurchaseStatusCancelled).
+		Find(&cancellations).Error
+	if err != nil {
+		return models.ClosingReportFigures{}, fmt.Errorf("unable to retrieve the cancelled purchases: %w", err)
+	}
+
+	for _, purchase := range cancellations {
+		builder.AddCancellation(purchase)
+	}
+
+	return builder.Figures(), nil
+}
+
+func (repo *Repository) getRefundedPurchaseItems(
+	refunds []models.PurchaseRefund,
+	withDeletedProducts func(db *gorm.DB) *gorm.DB,
+) (map[int]models.PurchaseItem, error) {
+	var ids []int
+
+	for _, refund := range refunds {
+		for _, item := range refund.Items {
+			ids = append(ids, item.PurchaseItemID)
+		}
+	}
+
+	purchaseItems := make(map[int]models.PurchaseItem, len(ids))
+	if len(ids) == 0 {
+		return purchaseItems, nil
+	}
+
+	var items []models.PurchaseItem
+	if err := repo.db.Unscoped().Preload("Product", withDeletedProducts).Find(&items, ids).Error; err != nil {
+		return nil, fmt.Errorf("unable to retrieve the refunded purchase items: %w", err)
+	}
+
+	for _, item := range items {
+		purchaseItems[item.ID] = item
+	}
+
+	return purchaseItems, nil
+}
+
+func (repo *Repository) GetClosingReports(
+	limit int,
+	offset int,
+	sort string,
+	order string,
+) ([]models.ClosingReport, error) {
+	if order != "ASC" && order != "DESC" {
+		order = "ASC"
+	}
+
+	sortField, exists := closingReportSortFieldMappings[sort]
+	if !exists {
+		return nil, errors.New("invalid sort field name")
+	}
+
+	var reports []models.ClosingReport
+	if err := repo.db.Joins("CreatedBy").
+		Order(sortField + " " + order + ", closing_reports.id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&reports).Error; err != nil {
+		return nil, errors.New("closing reports not found")
+	}
+
+	return reports, nil
+}
+
+func (repo *Repository) GetTotalClosingReports() (int64, error) {
+	var totalRows int64
+
+	if err := repo.db.Model(&models.ClosingReport{}).Count(&totalRows).Error; err != nil {
+		return 0, err
+	}
+
+	return totalRows, nil
+}
+
+func (repo *Repository) GetClosingReportByID(id int) (*models.ClosingReport, error) {
+	var report models.ClosingReport
+	if err := repo.db.Joins("CreatedBy").First(&report, "closing_reports.id = ?", id).Error; err != nil {
+		return nil, ErrClosingReportNotFound
+	}
+
+	return &report, nil
+}
+
+// StoreClosingReport stores the report with the next consecutive number. Periods of reports
+// must not overlap, so that every purchase is part of one report at most.
+func (repo *Repository) StoreClosingReport(report models.ClosingReport) (*models.ClosingReport, error) {
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		var overlapping int64
+
+		err := tx.Model(&models.ClosingReport{}).
+			Where("period_start < ? AND period_end > ?", report.PeriodEnd, report.PeriodStart).
+			Count(&overlapping).Error
+		if err != nil {
+			return err
+		}
+
+		if overlapping > 0 {
+			return ErrClosingReportOverlaps
+		}
+
+		var lastNumber uint
+
+		err = tx.Unscoped().
+			Model(&models.ClosingReport{}).
+			Select("COALESCE(MAX(number), 0)").
+			Scan(&lastNumber).Error
+		if err != nil {
+			return err
+		}
+
+		report.Number = lastNumber + 1
+
+		return tx.Create(&report).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.GetClosingReportByID(report.ID)
+}
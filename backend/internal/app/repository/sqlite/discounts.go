@@ -0,0 +1,137 @@
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"gorm.io/gorm"
+)
+
+var ErrDiscountNotFound = errors.New("discount not found")
+
+type DiscountFilters struct {
+	Query string
+	IDs   []int
+}
+
+var discountSortFieldMappings = map[string]string{
+	"id":        "discounts.id",
+	"name":      "LOWER(discounts.name)",
+	"code":      "discounts.code",
+	"type":      "discounts.type",
+	"value":     "CAST(discounts.value AS REAL)",
+	"productId": "discounts.product_id",
+}
+
+func (filters DiscountFilters) AddWhere(query *gorm.DB) *gorm.DB {
+	if len(filters.IDs) > 0 {
+		query = query.Where("discounts.id IN ?", filters.IDs)
+	}
+
+	if filters.Query != "" {
+		query = query.Where(
+			"discounts.name LIKE ? OR discounts.code LIKE ?",
+			"%"+filters.Query+"%",
+			"%"+filters.Query+"%",
+		)
+	}
+
+	return query
+}
+
+func (repo *Repository) GetDiscounts(
+	limit int,
+	offset int,
+	sort string,
+	order string,
+	filters DiscountFilters,
+) ([]models.Discount, error) {
+	if order != "ASC" && order != "DESC" {
+		order = "ASC"
+	}
+
+	sortField, exists := discountSortFieldMappings[sort]
+	if !exists {
+		return nil, errors.New("invalid sort field name")
+	}
+
+	var discounts []models.Discount
+
+	query := repo.db.Model(&models.Discount{}).
+		Order(sortField + " " + order + ", discounts.id ASC").
+		Limit(limit).
+		Offset(offset)
+	query = filters.AddWhere(query)
+
+	if err := query.Find(&discounts).Error; err != nil {
+		return nil, errors.New("discounts not found")
+	}
+
+	return discounts, nil
+}
+
+func (repo *Repository) GetTotalDiscounts(filters DiscountFilters) (int64, error) {
+	var totalRows int64
+
+	query := repo.db.Model(&models.Discount{})
+	query = filters.AddWhere(query)
+
+	if err := query.Count(&totalRows).Error; err != nil {
+		return 0, err
+	}
+
+	return totalRows, nil
+}
+
+func (repo *Repository) GetDiscountByID(id int) (*models.Discount, error) {
+	var discount models.Discount
+	if err := repo.db.First(&discount, id).Error; err != nil {
+		return nil, ErrDiscountNotFound
+	}
+
+	return &discount, nil
+}
+
+// GetDiscountByCode returns the discount with the code. Codes are compared case insensitive.
+func (repo *Repository) GetDiscountByCode(code string) (*models.Discount, error) {
+	var discount models.Discount
+	if err := repo.db.Where("code = ?", models.NormalizeDiscountCode(code)).First(&discount).Error; err != nil {
+		return nil, ErrDiscountNotFound
+	}
+
+	return &discount, nil
+}
+
+func (repo *Repository) CreateDiscount(discount models.Discount) (models.Discount, error) {
+	if err := repo.db.Create(&discount).Error; err != nil {
+		return discount, fmt.Errorf("unable to store the discount: %w", err)
+	}
+
+	return discount, nil
+}
+
+func (repo *Repository) UpdateDiscountByID(id int, updatedDiscount models.Discount) (*models.Discount, error) {
+	var discount models.Discount
+	if err := repo.db.First(&discount, id).Error; err != nil {
+		return nil, ErrDiscountNotFound
+	}
+
+	discount.Name = updatedDiscount.Name
+	discount.Code = updatedDiscount.Code
+	discount.Type = updatedDiscount.Type
+	discount.Value = updatedDiscount.Value
+	discount.ProductID = updatedDiscount.ProductID
+	discount.UpdatedByID = updatedDiscount.UpdatedByID
+
+	if err := repo.db.Save(&discount).Error; err != nil {
+		return nil, fmt.Errorf("failed to update discount: %w", err)
+	}
+
+	return &discount, nil
+}
+
+func (repo *Repository) DeleteDiscount(discount models.Discount, deletedBy models.User) {
+	repo.db.Model(&models.Discount{}).Where(whereIDEquals, discount.ID).Update("DeletedByID", deletedBy.ID)
+	repo.db.Delete(&discount)
+}
@@ -0,0 +1,77 @@
+package sqlite
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReserveIdempotencyKey stores the key for the first request sent with it and reports true. If the
+// key has been stored for an earlier request, the stored key is returned instead. Expired keys are
+// replaced.
+func (repo *Repository) ReserveIdempotencyKey(key models.IdempotencyKey) (models.IdempotencyKey, bool, error) {
+	var stored models.IdempotencyKey
+
+	reserved := false
+
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ? AND key = ? AND expires_at <= ?", key.UserID, key.Key, time.Now()).
+			Delete(&models.IdempotencyKey{}).Error
+		if err != nil {
+			return err
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&key)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected > 0 {
+			stored = key
+			reserved = true
+
+			return nil
+		}
+
+		return tx.Where("user_id = ? AND key = ?", key.UserID, key.Key).Take(&stored).Error
+	})
+	if err != nil {
+		return models.IdempotencyKey{}, false, fmt.Errorf("unable to reserve the idempotency key: %w", err)
+	}
+
+	return stored, reserved, nil
+}
+
+// CompleteIdempotencyKey stores the response to the request sent with the key and keeps the key
+// until it expires.
+func (repo *Repository) CompleteIdempotencyKey(
+	id int,
+	statusCode int,
+	contentType, body string,
+	expiresAt time.Time,
+) error {
+	return repo.db.Model(&models.IdempotencyKey{}).
+		Where(whereIDEquals, id).
+		Updates(map[string]any{
+			"status_code":   statusCode,
+			"content_type":  contentType,
+			"response_body": body,
+			"expires_at":    expiresAt,
+		}).
+		Error
+}
+
+// ReleaseIdempotencyKey removes the key of a request that failed, so that it can be sent again.
+func (repo *Repository) ReleaseIdempotencyKey(id int) error {
+	return repo.db.Delete(&models.IdempotencyKey{}, id).Error
+}
+
+// DeleteExpiredIdempotencyKeys removes the keys expired before the time and returns their number.
+func (repo *Repository) DeleteExpiredIdempotencyKeys(before time.Time) (int64, error) {
+	result := repo.db.Where("expires_at <= ?", before).Delete(&models.IdempotencyKey{})
+
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,241 @@
+package sqlite
+
+import (
+	"fmt"
+
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"gorm.io/gorm"
+)
+
+const backfillInventoryNote = "Stock before the inventory ledger"
+
+// InventoryMovementFilters filter the inventory ledger. Counted restricts it to the count
+// corrections, which record a counted stock.
+type InventoryMovementFilters struct {
+	ProductID int
+	VariantID *int
+	Counted   bool
+}
+
+func (filters InventoryMovementFilters) AddWhere(query *gorm.DB) *gorm.DB {
+	if filters.ProductID != 0 {
+		query = query.Where("inventory_movements.product_id = ?", filters.ProductID)
+	}
+
+	if filters.VariantID != nil {
+		query = query.Where("inventory_movements.variant_id = ?", *filters.VariantID)
+	}
+
+	if filters.Counted {
+		query = query.Where("inventory_movements.counted_quantity IS NOT NULL")
+	}
+
+	return query
+}
+
+func (repo *Repository) GetInventoryMovements(
+	limit int,
+	offset int,
+	filters InventoryMovementFilters,
+) ([]models.InventoryMovement, error) {
+	var movements []models.InventoryMovement
+
+	query := repo.db.Model(&models.InventoryMovement{}).
+		Preload("Product").
+		Preload("Variant").
+		Preload("CreatedBy").
+		Order("inventory_movements.created_at DESC, inventory_movements.id DESC").
+		Limit(limit).
+		Offset(offset)
+	query = filters.AddWhere(query)
+
+	if err := query.Find(&movements).Error; err != nil {
+		return nil, fmt.Errorf("unable to retrieve the inventory movements: %w", err)
+	}
+
+	return movements, nil
+}
+
+func (repo *Repository) GetTotalInventoryMovements(filters InventoryMovementFilters) (int64, error) {
+	var totalRows int64
+
+	query := filters.AddWhere(repo.db.Model(&models.InventoryMovement{}))
+	if err := query.Count(&totalRows).Error; err != nil {
+		return 0, err
+	}
+
+	return totalRows, nil
+}
+
+// GetStockOnHand returns the stock on hand of a product, or of its variant if given, as the sum of
+// its inventory movements.
+func (repo *Repository) GetStockOnHand(productID int, variantID *int) (int, error) {
+	var onHand int
+
+	query := repo.db.Model(&models.InventoryMovement{}).
+		Select("COALESCE(SUM(inventory_movements.quantity), 0)").
+		Where("inventory_movements.product_id = ?", productID)
+
+	if variantID != nil {
+		query = query.Where("inventory_movements.variant_id = ?", *variantID)
+	} else {
+		query = query.Where("inventory_movements.variant_id IS NULL")
+	}
+
+	if err := query.Scan(&onHand).Error; err != nil {
+		return 0, err
+	}
+
+	return onHand, nil
+}
+
+// StoreInventoryMovement appends the movement to the inventory ledger. It neither changes the total
+// stock nor the sold-out flag, as the movements of sales follow the reservations of purchases and
+// the movements of the product form follow the stock stored with the product.
+func (repo *Repository) StoreInventoryMovement(movement models.InventoryMovement) (models.InventoryMovement, error) {
+	if err := repo.db.Create(&movement).Error; err != nil {
+		return models.InventoryMovement{}, fmt.Errorf("unable to store the inventory movement: %w", err)
+	}
+
+	return movement, nil
+}
+
+// RecordInventoryMovement records a restock, a write-off or a count of a product or variant. A count
+// is recorded with the difference of the counted to the expected stock on hand. The total stock
+// follows the movement and the sold-out flag flips when nothing or again something is left.
+func (repo *Repository) RecordInventoryMovement(movement models.InventoryMovement) (models.InventoryMovement, error) {
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		txRepo := repo.cloneWithDB(tx)
+
+		onHand, err := txRepo.GetStockOnHand(movement.ProductID, movement.VariantID)
+		if err != nil {
+			return err
+		}
+
+		if movement.CountedQuantity != nil {
+			expected := onHand
+			movement.ExpectedQuantity = &expected
+			movement.Quantity = *movement.CountedQuantity - expected
+		}
+
+		movement, err = txRepo.StoreInventoryMovement(movement)
+		if err != nil {
+			return err
+		}
+
+		if !movement.Type.AdjustsTotalStock() {
+			return nil
+		}
+
+		return txRepo.adjustTotalStock(movement, onHand, onHand+movement.Quantity)
+	})
+
+	return movement, err
+}
+
+func (repo *Repository) adjustTotalStock(movement models.InventoryMovement, onHandBefore, onHandAfter int) error {
+	if movement.VariantID != nil {
+		var variant models.ProductVariant
+		if err := repo.db.First(&variant, *movement.VariantID).Error; err != nil {
+			return err
+		}
+
+		variant.TotalStock += movement.Quantity
+		variant.SoldOut = stockSoldOut(variant.TracksStock(), variant.SoldOut, onHandBefore, onHandAfter)
+
+		return repo.db.Model(&models.ProductVariant{}).
+			Where(whereIDEquals, variant.ID).
+			Updates(map[string]any{"total_stock": variant.TotalStock, "sold_out": variant.SoldOut}).Error
+	}
+
+	var product models.Product
+	if err := repo.db.First(&product, movement.ProductID).Error; err != nil {
+		return err
+	}
+
+	product.TotalStock += movement.Quantity
+	product.SoldOut = stockSoldOut(product.TracksStock(), product.SoldOut, onHandBefore, onHandAfter)
+
+	return repo.db.Model(&models.Product{}).
+		Where(whereIDEquals, product.ID).
+		Updates(map[string]any{"total_stock": product.TotalStock, "sold_out": product.SoldOut}).Error
+}
+
+// stockSoldOut returns the sold-out flag of a product or variant once its stock on hand changed. The
+// flag of an item without tracked stock is set by hand only.
+func stockSoldOut(tracked, soldOut bool, onHandBefore, onHandAfter int) bool {
+	if !tracked {
+		return soldOut
+	}
+
+	return models.SoldOutForStock(soldOut, onHandBefore, onHandAfter)
+}
+
+// BackfillInventoryMovements opens the inventory ledger of the products and variants stored before
+// their stock was kept in it, with their total stock and the quantity sold so far.
+func (repo *Repository) BackfillInventoryMovements() error {
+	var products []models.Product
+
+	err := repo.db.Preload("Variants").
+		Where("NOT EXISTS (SELECT 1 FROM inventory_movements " +
+			"WHERE inventory_movements.product_id = products.id)").
+		Find(&products).Error
+	if err != nil {
+		return fmt.Errorf("unable to retrieve the products without inventory movements: %w", err)
+	}
+
+	return repo.db.Transaction(func(tx *gorm.DB) error {
+		txRepo := repo.cloneWithDB(tx)
+
+		for _, product := range products {
+			if !product.HasVariants() {
+				sold, err := txRepo.GetReservedQuantitiesByProductID(product.ID)
+				if err != nil {
+					return err
+				}
+
+				if err := txRepo.openInventoryLedger(product.ID, nil, product.TotalStock, sold); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			for _, variant := range product.Variants {
+				sold, err := txRepo.GetReservedQuantitiesByVariantID(variant.ID)
+				if err != nil {
+					return err
+				}
+
+				if err := txRepo.openInventoryLedger(product.ID, &variant.ID, variant.TotalStock, sold); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+func (repo *Repository) openInventoryLedger(productID int, variantID *int, totalStock, sold int) error {
+	movements := []models.InventoryMovement{
+		{Type: models.InventoryMovementInitial, Quantity: totalStock},
+		{Type: models.InventoryMovementSale, Quantity: -sold},
+	}
+
+	for _, movement := range movements {
+		if movement.Quantity == 0 {
+			continue
+		}
+
+		movement.ProductID = productID
+		movement.VariantID = variantID
+		movement.Note = backfillInventoryNote
+
+		if _, err := repo.StoreInventoryMovement(movement); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
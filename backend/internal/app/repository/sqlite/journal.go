@@ -0,0 +1,52 @@
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"gorm.io/gorm"
+)
+
+// AppendJournalEntry chains the entry to the last entry of the journal and stores it. It has to
+// be called within the transaction of the recorded change, so that both are stored or neither.
+func (repo *Repository) AppendJournalEntry(entry models.JournalEntry) (models.JournalEntry, error) {
+	var previous *models.JournalEntry
+
+	var last models.JournalEntry
+
+	err := repo.db.Order("sequence DESC").Take(&last).Error
+	switch {
+	case err == nil:
+		previous = &last
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return models.JournalEntry{}, fmt.Errorf("unable to retrieve the last journal entry: %w", err)
+	}
+
+	// the time is stored with microseconds, it has to match the hashed time once read back
+	entry.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	entry.Chain(previous)
+
+	if err := repo.db.Create(&entry).Error; err != nil {
+		return models.JournalEntry{}, fmt.Errorf("unable to store the journal entry: %w", err)
+	}
+
+	return entry, nil
+}
+
+// GetJournalEntries returns the entries following the given sequence number in order.
+func (repo *Repository) GetJournalEntries(afterSequence uint, limit int) ([]models.JournalEntry, error) {
+	var entries []models.JournalEntry
+
+	err := repo.db.
+		Where("sequence > ?", afterSequence).
+		Order("sequence ASC").
+		Limit(limit).
+		Find(&entries).Error
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve the journal entries: %w", err)
+	}
+
+	return entries, nil
+}
@@ -7,7 +7,7 @@ import (
 )
 
 func (repo *Repository) GetProductInterests(limit, offset int, ids []int) ([]models.ProductInterest, error) {
-	query := repo.db.Preload("Product").Order("created_at DESC").Limit(limit).Offset(offset)
+	query := repo.db.Preload("Product").Preload("Variant").Order("created_at DESC").Limit(limit).Offset(offset)
 
 	if len(ids) > 0 {
 		query = query.Where("id IN ?", ids)
@@ -31,7 +31,7 @@ func (repo *Repository) GetTotalProductInterests() (int64, error) {
 
 func (repo *Repository) GetProductInterestByID(id int) (*models.ProductInterest, error) {
 	var productInterest models.ProductInterest
-	if err := repo.db.Preload("Product").First(&productInterest, id).Error; err != nil {
+	if err := repo.db.Preload("Product").Preload("Variant").First(&productInterest, id).Error; err != nil {
 		return nil, errors.New("productInterest not found")
 	}
 
@@ -70,3 +70,17 @@ func (repo *Repository) GetProductInterestCountByProductID(productID int) (int,
 
 	return count, nil
 }
+
+func (repo *Repository) GetProductInterestCountByVariantID(variantID int) (int, error) {
+	var count int
+
+	err := repo.db.Table("product_interests").
+		Select("COUNT(*)").
+		Where("product_interests.variant_id = ? AND product_interests.deleted_at IS NULL", variantID).
+		Scan(&count).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
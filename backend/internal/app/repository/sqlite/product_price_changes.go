@@ -0,0 +1,35 @@
+package sqlite
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/potibm/kasseapparat/internal/app/models"
+)
+
+func (repo *Repository) StoreProductPriceChanges(changes []models.ProductPriceChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	return repo.db.Create(&changes).Error
+}
+
+// GetProductPriceChangesSince returns the price changes of a product and its variants after the
+// given time, oldest first.
+func (repo *Repository) GetProductPriceChangesSince(
+	productID int,
+	since time.Time,
+) ([]models.ProductPriceChange, error) {
+	var changes []models.ProductPriceChange
+
+	err := repo.db.
+		Where("product_price_changes.product_id = ? AND product_price_changes.created_at > ?", productID, since).
+		Order("product_price_changes.created_at ASC, product_price_changes.id ASC").
+		Find(&changes).Error
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve the price changes: %w", err)
+	}
+
+	return changes, nil
+}
@@ -12,7 +12,9 @@ func (repo *Repository) GetProductStats() ([]response.ProductStats, error) {
 	products := []response.ProductStats{}
 
 	query := repo.db.Table("products").
-		Select("products.id, products.name, 0 as sold_items, 0 as total_net_price, 0 as total_gross_price").
+		Select("products.id, products.name, products.category_id, categories.name as category_name, " +
+			"0 as sold_items, 0 as total_net_price, 0 as total_gross_price").
+		Joins("LEFT JOIN categories ON categories.id = products.category_id AND categories.deleted_at IS NULL").
 		Where("products.deleted_at IS NULL").
 		Group("products.id").
 		Order("products.pos ASC")
@@ -25,7 +27,8 @@ func (repo *Repository) GetProductStats() ([]response.ProductStats, error) {
 		var purchaseItems []models.PurchaseItem
 
 		purchaseQuery := repo.db.Table("purchase_items").
-			Select("purchase_items.quantity, purchase_items.net_price, purchase_items.vat_rate").
+			Select("(purchase_items.quantity - "+refundedQuantityExpr+") AS quantity, "+
+				"purchase_items.net_price, purchase_items.vat_rate, purchase_items.variant_id").
 			Joins("JOIN purchases ON purchases.id = purchase_items.purchase_id").
 			Where("purchase_items.product_id = ?", products[i].ID).
 			Where("purchases.deleted_at IS NULL").
@@ -48,7 +51,60 @@ func (repo *Repository) GetProductStats() ([]response.ProductStats, error) {
 				purchaseItems[j].TotalGrossPrice(repo.decimalPlaces),
 			)
 		}
+
+		variants, err := repo.getProductVariantStats(products[i].ID, purchaseItems)
+		if err != nil {
+			return nil, err
+		}
+
+		products[i].Variants = variants
 	}
 
 	return products, nil
 }
+
+// getProductVariantStats breaks the purchase items of a product down by its variants, including
+// variants that have been removed but were sold.
+func (repo *Repository) getProductVariantStats(
+	productID int,
+	purchaseItems []models.PurchaseItem,
+) ([]response.ProductVariantStats, error) {
+	var variants []models.ProductVariant
+
+	err := repo.db.Unscoped().
+		Where("product_id = ?", productID).
+		Order("pos ASC, id ASC").
+		Find(&variants).Error
+	if err != nil {
+		return nil, errors.New("unable to retrieve the variants for this product")
+	}
+
+	stats := make([]response.ProductVariantStats, 0, len(variants))
+
+	for _, variant := range variants {
+		variantStats := response.ProductVariantStats{
+			ID:              variant.ID,
+			Name:            variant.Name,
+			TotalNetPrice:   decimal.Zero,
+			TotalGrossPrice: decimal.Zero,
+		}
+
+		for _, item := range purchaseItems {
+			if item.VariantID == nil || *item.VariantID != variant.ID {
+				continue
+			}
+
+			variantStats.SoldItems += item.Quantity
+			variantStats.TotalNetPrice = variantStats.TotalNetPrice.Add(item.TotalNetPrice(repo.decimalPlaces))
+			variantStats.TotalGrossPrice = variantStats.TotalGrossPrice.Add(item.TotalGrossPrice(repo.decimalPlaces))
+		}
+
+		if variant.DeletedAt.Valid && variantStats.SoldItems == 0 {
+			continue
+		}
+
+		stats = append(stats, variantStats)
+	}
+
+	return stats, nil
+}
@@ -5,10 +5,35 @@ import (
 	"errors"
 
 	"github.com/potibm/kasseapparat/internal/app/models"
+	"gorm.io/gorm"
 )
 
 var ErrProductNotFound = errors.New("product not found")
 
+// ProductFilters filter the products. AllowedCategoryIDs restricts the products to the categories
+// of a user, while CategoryIDs are the categories asked for.
+type ProductFilters struct {
+	IDs                []int
+	CategoryIDs        []int
+	AllowedCategoryIDs []int
+}
+
+func (filters ProductFilters) AddWhere(query *gorm.DB) *gorm.DB {
+	if len(filters.IDs) > 0 {
+		query = query.Where("Id IN ?", filters.IDs)
+	}
+
+	if len(filters.CategoryIDs) > 0 {
+		query = query.Where("Category_Id IN ?", filters.CategoryIDs)
+	}
+
+	if len(filters.AllowedCategoryIDs) > 0 {
+		query = query.Where("Category_Id IN ?", filters.AllowedCategoryIDs)
+	}
+
+	return query
+}
+
 var productSortFieldMappings = map[string]string{
 	"id":         "ID",
 	"name":       "Name",
@@ -22,7 +47,7 @@ func (repo *Repository) GetProducts(
 	offset int,
 	sort string,
 	order string,
-	ids []int,
+	filters ProductFilters,
 ) ([]models.Product, error) {
 	if order != "ASC" && order != "DESC" {
 		order = "ASC"
@@ -37,13 +62,14 @@ func (repo *Repository) GetProducts(
 
 	query := repo.db.Table("Products").
 		Preload("Guestlists").
+		Preload("BundleItems").
+		Preload("Variants", orderVariants).
+		Preload("PriceRules", orderPriceRules).
+		Preload("Category").
 		Order(sortField + " " + order + ", Pos ASC, Id ASC").
 		Limit(limit).
 		Offset(offset)
-
-	if len(ids) > 0 {
-		query = query.Where("Id IN ?", ids)
-	}
+	query = filters.AddWhere(query)
 
 	if err := query.Find(&products).Error; err != nil {
 		return nil, errors.New("products not found")
@@ -60,17 +86,22 @@ func getProductsValidSortFieldName(input string) (string, error) {
 	return "", errors.New("invalid sort field name")
 }
 
-func (repo *Repository) GetTotalProducts() (int64, error) {
+func (repo *Repository) GetTotalProducts(filters ProductFilters) (int64, error) {
 	var totalRows int64
 
-	repo.db.Model(&models.Product{}).Count(&totalRows)
+	filters.AddWhere(repo.db.Model(&models.Product{})).Count(&totalRows)
 
 	return totalRows, nil
 }
 
 func (repo *Repository) GetProductByID(id int) (*models.Product, error) {
 	var product models.Product
-	if err := repo.db.Table("Products").First(&product, id).Error; err != nil {
+	if err := repo.db.Table("Products").
+		Preload("BundleItems").
+		Preload("Variants", orderVariants).
+		Preload("PriceRules", orderPriceRules).
+		Preload("Category").
+		First(&product, id).Error; err != nil {
 		return nil, ErrProductNotFound
 	}
 
@@ -94,15 +125,94 @@ func (repo *Repository) UpdateProductByID(id int, updatedProduct models.Product)
 	product.Hidden = updatedProduct.Hidden
 	product.SoldOut = updatedProduct.SoldOut
 	product.TotalStock = updatedProduct.TotalStock
-
-	// Save the updated product to the database
-	if err := repo.db.Save(&product).Error; err != nil {
+	product.TrackStock = updatedProduct.TrackStock
+	product.BundleItems = updatedProduct.BundleItems
+	product.Variants = updatedProduct.Variants
+	product.PriceRules = updatedProduct.PriceRules
+	product.CategoryID = updatedProduct.CategoryID
+	product.Category = nil
+
+	// Save the updated product to the database, replacing the items of a bundle, its price rules
+	// and its variants
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("bundle_id = ?", id).Delete(&models.ProductBundleItem{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("product_id = ?", id).Delete(&models.ProductPriceRule{}).Error; err != nil {
+			return err
+		}
+
+		for i := range product.BundleItems {
+			product.BundleItems[i].ID = 0
+		}
+
+		for i := range product.PriceRules {
+			product.PriceRules[i].ID = 0
+		}
+
+		if err := tx.Omit("Variants").Save(&product).Error; err != nil {
+			return err
+		}
+
+		return saveProductVariants(tx, id, updatedProduct.Variants)
+	})
+	if err != nil {
 		return nil, errors.New("failed to update product")
 	}
 
 	return &product, nil
 }
 
+func orderVariants(db *gorm.DB) *gorm.DB {
+	return db.Order("pos ASC, id ASC")
+}
+
+func orderPriceRules(db *gorm.DB) *gorm.DB {
+	return db.Order("pos ASC, id ASC")
+}
+
+// saveProductVariants stores the variants of the product and removes the variants that are no
+// longer listed. Existing variants keep their ID, so purchases still refer to them.
+func saveProductVariants(tx *gorm.DB, productID int, variants []models.ProductVariant) error {
+	keep := make([]int, 0, len(variants))
+
+	for i := range variants {
+		variants[i].ProductID = productID
+
+		if err := saveProductVariant(tx, &variants[i]); err != nil {
+			return err
+		}
+
+		keep = append(keep, variants[i].ID)
+	}
+
+	query := tx.Where("product_id = ?", productID)
+	if len(keep) > 0 {
+		query = query.Where("id NOT IN ?", keep)
+	}
+
+	return query.Delete(&models.ProductVariant{}).Error
+}
+
+func saveProductVariant(tx *gorm.DB, variant *models.ProductVariant) error {
+	if variant.ID == 0 {
+		return tx.Create(variant).Error
+	}
+
+	return tx.Model(variant).
+		Select("Name", "NetPrice", "Pos", "TotalStock", "TrackStock", "SoldOut").
+		Updates(variant).Error
+}
+
+func (repo *Repository) SetProductSoldOut(productID int, soldOut bool) error {
+	return repo.db.Model(&models.Product{}).Where(whereIDEquals, productID).Update("sold_out", soldOut).Error
+}
+
+func (repo *Repository) SetProductVariantSoldOut(variantID int, soldOut bool) error {
+	return repo.db.Model(&models.ProductVariant{}).Where(whereIDEquals, variantID).Update("sold_out", soldOut).Error
+}
+
 func (repo *Repository) CreateProduct(product models.Product) (models.Product, error) {
 	result := repo.db.Create(&product)
 
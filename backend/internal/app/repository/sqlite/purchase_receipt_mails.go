@@ -0,0 +1,25 @@
+package sqlite
+
+import (
+	"time"
+
+	"github.com/potibm/kasseapparat/internal/app/models"
+)
+
+func (repo *Repository) StorePurchaseReceiptMail(
+	mail models.PurchaseReceiptMail,
+) (models.PurchaseReceiptMail, error) {
+	result := repo.db.Create(&mail)
+
+	return mail, result.Error
+}
+
+// ErasePurchaseReceiptMailAddresses removes the email addresses of receipts sent before the
+// given time and returns the number of erased addresses.
+func (repo *Repository) ErasePurchaseReceiptMailAddresses(sentBefore time.Time) (int64, error) {
+	result := repo.db.Model(&models.PurchaseReceiptMail{}).
+		Where("sent_at < ? AND email IS NOT NULL", sentBefore).
+		Update("email", nil)
+
+	return result.RowsAffected, result.Error
+}
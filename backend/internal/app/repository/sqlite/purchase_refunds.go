@@ -0,0 +1,127 @@
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/potibm/kasseapparat/internal/app/models"
+)
+
+// refundedQuantityExpr is the quantity of a purchase item that has been refunded. It is used
+// to net out partial refunds in the stats and sold quantities.
+const refundedQuantityExpr = "COALESCE((SELECT SUM(purchase_refund_items.quantity) FROM purchase_refund_items " +
+	"WHERE purchase_refund_items.purchase_item_id = purchase_items.id AND " +
+	"purchase_refund_items.deleted_at IS NULL), 0)"
+
+var ErrPurchaseRefundNotFound = errors.New("refund not found")
+
+func (repo *Repository) StorePurchaseRefund(refund models.PurchaseRefund) (models.PurchaseRefund, error) {
+	result := repo.db.Create(&refund)
+
+	return refund, result.Error
+}
+
+func (repo *Repository) SettlePurchaseRefundByID(id int) error {
+	if err := repo.db.Model(&models.PurchaseRefund{}).
+		Where("id = ? AND settled_at IS NULL", id).
+		Updates(map[string]any{"settled_at": time.Now(), "settling_at": nil}).
+		Error; err != nil {
+		return fmt.Errorf("failed to settle refund %d: %w", id, err)
+	}
+
+	return nil
+}
+
+// ClaimPurchaseRefundSettlement claims the unsettled refund for refunding its card. It reports
+// false if the refund is settled or has been claimed already.
+func (repo *Repository) ClaimPurchaseRefundSettlement(id int) (bool, error) {
+	result := repo.db.Model(&models.PurchaseRefund{}).
+		Where("id = ? AND settled_at IS NULL AND settling_at IS NULL", id).
+		Update("settling_at", time.Now())
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to claim refund %d: %w", id, result.Error)
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// ReleasePurchaseRefundSettlement releases the claim of a refund whose card could not be refunded.
+func (repo *Repository) ReleasePurchaseRefundSettlement(id int) error {
+	if err := repo.db.Model(&models.PurchaseRefund{}).
+		Where("id = ? AND settled_at IS NULL", id).
+		Update("settling_at", nil).
+		Error; err != nil {
+		return fmt.Errorf("failed to release refund %d: %w", id, err)
+	}
+
+	return nil
+}
+
+func (repo *Repository) GetPurchaseRefundByID(id int) (*models.PurchaseRefund, error) {
+	var refund models.PurchaseRefund
+	if err := repo.db.
+		Preload("Items").
+		Preload("Payments").
+		Preload("Roundings").
+		First(&refund, "purchase_refunds.id = ?", id).
+		Error; err != nil {
+		return nil, ErrPurchaseRefundNotFound
+	}
+
+	return &refund, nil
+}
+
+// GetUnsettledPurchaseRefunds returns the refunds whose card share has not been refunded by the
+// payment terminal provider yet and is not being refunded, the oldest first.
+func (repo *Repository) GetUnsettledPurchaseRefunds() ([]models.PurchaseRefund, error) {
+	var refunds []models.PurchaseRefund
+
+	err := repo.db.
+		Preload("Items").
+		Preload("Payments").
+		Preload("Roundings").
+		Where("purchase_refunds.settled_at IS NULL AND purchase_refunds.settling_at IS NULL").
+		Order("purchase_refunds.created_at ASC").
+		Find(&refunds).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the unsettled refunds: %w", err)
+	}
+
+	return refunds, nil
+}
+
+func (repo *Repository) deletePurchaseRefundsByPurchaseID(purchaseID uuid.UUID) error {
+	refundIDs := repo.db.Model(&models.PurchaseRefund{}).Select("id").Where("purchase_id = ?", purchaseID)
+
+	for _, line := range []any{&models.PurchaseRefundItem{}, &models.PurchaseRefundPayment{}} {
+		if err := repo.db.Where("purchase_refund_id IN (?)", refundIDs).Delete(line).Error; err != nil {
+			return fmt.Errorf("failed to delete the refund lines of purchase %s: %w", purchaseID, err)
+		}
+	}
+
+	if err := repo.db.Where("purchase_id = ?", purchaseID).Delete(&models.PurchaseRefund{}).Error; err != nil {
+		return fmt.Errorf("failed to delete the refunds of purchase %s: %w", purchaseID, err)
+	}
+
+	return nil
+}
+
+func (repo *Repository) getConfirmedPurchaseRefundPayments() ([]models.PurchaseRefundPayment, error) {
+	var payments []models.PurchaseRefundPayment
+
+	err := repo.db.
+		Model(&models.PurchaseRefundPayment{}).
+		Select("purchase_refund_payments.payment_method, purchase_refund_payments.amount").
+		Joins("JOIN purchase_refunds ON "+
+			"purchase_refunds.id = purchase_refund_payments.purchase_refund_id AND "+
+			"purchase_refunds.deleted_at IS NULL").
+		Joins("JOIN purchases ON "+
+			"purchases.id = purchase_refunds.purchase_id AND "+
+			"purchases.deleted_at IS NULL AND "+
+			"purchases.status = ?", models.PurchaseStatusConfirmed).
+		Find(&payments).Error
+
+	return payments, err
+}
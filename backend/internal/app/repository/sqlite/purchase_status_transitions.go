@@ -0,0 +1,9 @@
+package sqlite
+
+import (
+	"github.com/potibm/kasseapparat/internal/app/models"
+)
+
+func (repo *Repository) StorePurchaseStatusTransition(transition models.PurchaseStatusTransition) error {
+	return repo.db.Create(&transition).Error
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/potibm/kasseapparat/internal/app/models"
@@ -17,14 +18,25 @@ type ProductPurchaseStats struct {
 	Name      string
 }
 
+type PaymentMethodStats struct {
+	PaymentMethod models.PaymentMethod `json:"paymentMethod"`
+	Count         int                  `json:"count"`
+	TotalAmount   decimal.Decimal      `json:"totalAmount"`
+}
+
 type PurchaseFilters struct {
 	CreatedByID            int
 	PaymentMethods         []models.PaymentMethod
 	StatusList             *models.PurchaseStatusList
 	TotalGrossPriceLte     *decimal.Decimal
 	TotalGrossPriceGte     *decimal.Decimal
+	CreatedAtGte           *time.Time
+	CreatedAtLt            *time.Time
 	IDs                    []int
 	HasClientTransactionID *bool
+	RegisterSessionID      int
+	Offline                *bool
+	Expired                *bool
 }
 
 func (filters PurchaseFilters) AddWhere(query *gorm.DB) *gorm.DB {
@@ -37,7 +49,7 @@ func (filters PurchaseFilters) AddWhere(query *gorm.DB) *gorm.DB {
 	}
 
 	if len(filters.PaymentMethods) > 0 {
-		query = query.Where("purchases.payment_method IN ?", filters.PaymentMethods)
+		query = query.Where("purchases.id IN (?)", purchaseIDsByPaymentMethods(query, filters.PaymentMethods))
 	}
 
 	if filters.TotalGrossPriceLte != nil {
@@ -48,10 +60,34 @@ func (filters PurchaseFilters) AddWhere(query *gorm.DB) *gorm.DB {
 		query = query.Where("purchases.total_gross_price >= ?", filters.TotalGrossPriceGte)
 	}
 
+	if filters.CreatedAtGte != nil {
+		query = query.Where("purchases.created_at >= ?", *filters.CreatedAtGte)
+	}
+
+	if filters.CreatedAtLt != nil {
+		query = query.Where("purchases.created_at < ?", *filters.CreatedAtLt)
+	}
+
 	if filters.StatusList != nil && len(*filters.StatusList) > 0 {
 		query = query.Where("purchases.status IN ?", *filters.StatusList)
 	}
 
+	if filters.RegisterSessionID != 0 {
+		query = query.Where("purchases.register_session_id = ?", filters.RegisterSessionID)
+	}
+
+	if filters.Offline != nil {
+		query = query.Where("purchases.offline = ?", *filters.Offline)
+	}
+
+	if filters.Expired != nil {
+		if *filters.Expired {
+			query = query.Where("purchases.expired_at IS NOT NULL")
+		} else {
+			query = query.Where("purchases.expired_at IS NULL")
+		}
+	}
+
 	if filters.HasClientTransactionID != nil {
 		if *filters.HasClientTransactionID {
 			query = query.Where("purchases.sumup_client_transaction_id IS NOT NULL")
@@ -63,6 +99,15 @@ func (filters PurchaseFilters) AddWhere(query *gorm.DB) *gorm.DB {
 	return query
 }
 
+// purchaseIDsByPaymentMethods returns a subquery selecting the purchases with at least
+// one payment line using one of the given payment methods.
+func purchaseIDsByPaymentMethods(query *gorm.DB, paymentMethods []models.PaymentMethod) *gorm.DB {
+	return query.Session(&gorm.Session{NewDB: true}).
+		Model(&models.PurchasePayment{}).
+		Select("purchase_id").
+		Where("payment_method IN ?", paymentMethods)
+}
+
 var purchaseSortFieldMappings = map[string]string{
 	"id":                 "purchases.ID",
 	"createdAt":          "purchases.created_at",
@@ -79,11 +124,27 @@ func (repo *Repository) StorePurchases(purchase models.Purchase) (models.Purchas
 	return purchase, result.Error
 }
 
-func (repo *Repository) DeletePurchaseByID(id uuid.UUID, deletedBy models.User) {
-	repo.db.Model(&models.Purchase{}).Where(whereIDEquals, id).Update("DeletedByID", deletedBy.ID)
-	repo.db.Where(whereIDEquals, id).Delete(&models.Purchase{})
+func (repo *Repository) DeletePurchaseByID(id uuid.UUID, deletedBy models.User) error {
+	if err := repo.db.Model(&models.Purchase{}).Where(whereIDEquals, id).Update("DeletedByID", deletedBy.ID).Error; err != nil {
+		return fmt.Errorf("failed to record who deleted purchase %s: %w", id, err)
+	}
 
-	repo.db.Where("purchase_id = ?", id).Delete(&models.PurchaseItem{})
+	if err := repo.db.Where(whereIDEquals, id).Delete(&models.Purchase{}).Error; err != nil {
+		return fmt.Errorf("failed to delete purchase %s: %w", id, err)
+	}
+
+	for _, line := range []any{
+		&models.PurchaseItem{},
+		&models.PurchaseDiscount{},
+		&models.PurchasePayment{},
+		&models.PurchaseRounding{},
+	} {
+		if err := repo.db.Where("purchase_id = ?", id).Delete(line).Error; err != nil {
+			return fmt.Errorf("failed to delete the lines of purchase %s: %w", id, err)
+		}
+	}
+
+	return repo.deletePurchaseRefundsByPurchaseID(id)
 }
 
 func (repo *Repository) GetPurchaseByID(id uuid.UUID) (*models.Purchase, error) {
@@ -96,11 +157,30 @@ func (repo *Repository) GetPurchaseBySumupClientTransactionID(
 	return repo.getPurchaseByQueryAndValue("sumup_client_transaction_id = ?", sumupClientTransactionID.String())
 }
 
+// withDeletedVariants loads the variants of purchase items even if they have been removed since.
+func withDeletedVariants(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
+
 func (repo *Repository) getPurchaseByQueryAndValue(query, value string) (*models.Purchase, error) {
 	var purchase models.Purchase
 	if err := repo.db.Model(&models.Purchase{}).
+		Preload("CreatedBy").
 		Preload("PurchaseItems").
 		Preload("PurchaseItems.Product").
+		Preload("PurchaseItems.Variant", withDeletedVariants).
+		Preload("PurchaseItems.Refunds").
+		Preload("PurchaseItems.Discounts").
+		Preload("Payments").
+		Preload("Roundings").
+		Preload("Refunds.Items").
+		Preload("Refunds.Payments").
+		Preload("Refunds.Roundings").
+		Preload("ReceiptMails").
+		Preload("StatusTransitions", func(db *gorm.DB) *gorm.DB {
+			return db.Order("purchase_status_transitions.id ASC")
+		}).
+		Preload("StatusTransitions.Actor").
 		Where(query, value).
 		First(&purchase).
 		Error; err != nil {
@@ -116,22 +196,68 @@ func (repo *Repository) UpdatePurchaseStatusByID(id uuid.UUID, status models.Pur
 	})
 }
 
+// MarkPurchaseExpiredByID records that the payment of the purchase did not reach a final state in time.
+func (repo *Repository) MarkPurchaseExpiredByID(id uuid.UUID, expiredAt time.Time) error {
+	if err := repo.db.Model(&models.Purchase{}).
+		Where("id = ?", id).
+		Update("expired_at", expiredAt).
+		Error; err != nil {
+		return fmt.Errorf("failed to mark purchase %s as expired: %w", id, err)
+	}
+
+	return nil
+}
+
 func (repo *Repository) UpdatePurchaseSumupClientTransactionIDByID(
 	id,
 	sumupClientTransactionID uuid.UUID,
 ) (*models.Purchase, error) {
-	return repo.updatePurchaseFieldByID(id, map[string]any{
+	fields := map[string]any{
 		"sumup_client_transaction_id": sumupClientTransactionID.String(),
-	})
+	}
+
+	if err := repo.updateSumupPaymentFieldsByPurchaseID(id, fields); err != nil {
+		return nil, err
+	}
+
+	return repo.updatePurchaseFieldByID(id, fields)
 }
 
 func (repo *Repository) UpdatePurchaseSumupTransactionIDByID(
 	id,
 	sumupTransactionID uuid.UUID,
 ) (*models.Purchase, error) {
-	return repo.updatePurchaseFieldByID(id, map[string]any{
+	fields := map[string]any{
 		"sumup_transaction_id": sumupTransactionID.String(),
-	})
+	}
+
+	if err := repo.updateSumupPaymentFieldsByPurchaseID(id, fields); err != nil {
+		return nil, err
+	}
+
+	return repo.updatePurchaseFieldByID(id, fields)
+}
+
+func (repo *Repository) updateSumupPaymentFieldsByPurchaseID(purchaseID uuid.UUID, fields map[string]any) error {
+	if err := repo.db.Model(&models.PurchasePayment{}).
+		Where("purchase_id = ? AND payment_method = ?", purchaseID.String(), models.PaymentMethodSumUp).
+		Updates(fields).
+		Error; err != nil {
+		return fmt.Errorf("failed to update SumUp payment of purchase %s: %w", purchaseID, err)
+	}
+
+	return nil
+}
+
+func (repo *Repository) SettlePurchasePaymentsByPurchaseID(purchaseID uuid.UUID) error {
+	if err := repo.db.Model(&models.PurchasePayment{}).
+		Where("purchase_id = ? AND settled_at IS NULL", purchaseID.String()).
+		Update("settled_at", time.Now()).
+		Error; err != nil {
+		return fmt.Errorf("failed to settle payments of purchase %s: %w", purchaseID, err)
+	}
+
+	return nil
 }
 
 func (repo *Repository) updatePurchaseFieldByID(id uuid.UUID, fields map[string]any) (*models.Purchase, error) {
@@ -168,6 +294,14 @@ func (repo *Repository) GetPurchases(
 		Model(&models.Purchase{}).
 		Preload("PurchaseItems").
 		Preload("PurchaseItems.Product").
+		Preload("PurchaseItems.Variant", withDeletedVariants).
+		Preload("PurchaseItems.Refunds").
+		Preload("PurchaseItems.Discounts").
+		Preload("Payments").
+		Preload("Roundings").
+		Preload("Refunds.Items").
+		Preload("Refunds.Payments").
+		Preload("Refunds.Roundings").
 		Order(sort + " " + order + ", purchases.created_at DESC").
 		Limit(limit).
 		Offset(offset)
@@ -187,7 +321,12 @@ func (repo *Repository) GetFilteredPurchases(filters PurchaseFilters) ([]models.
 		Model(&models.PurchaseItem{}).
 		Joins("JOIN purchases ON purchases.id = purchase_items.purchase_id").
 		Preload("Product").
-		Preload("Purchase")
+		Preload("Variant", withDeletedVariants).
+		Preload("Discounts").
+		Preload("Purchase").
+		Preload("Purchase.Payments").
+		Preload("Purchase.Roundings").
+		Preload("Refunds.PurchaseRefund.Payments")
 
 	query = filters.AddWhere(query)
 
@@ -224,7 +363,7 @@ func (repo *Repository) GetPurchaseStats() ([]ProductPurchaseStats, error) {
 	err := repo.db.
 		Model(&models.PurchaseItem{}).
 		Select("purchase_items.product_id, "+
-			"SUM(purchase_items.quantity) AS quantity, "+
+			"SUM(purchase_items.quantity - "+refundedQuantityExpr+") AS quantity, "+
 			"products.name").
 		Joins("JOIN purchases ON "+
 			"purchases.id = purchase_items.purchase_id AND "+
@@ -242,16 +381,83 @@ func (repo *Repository) GetPurchaseStats() ([]ProductPurchaseStats, error) {
 	return purchases, nil
 }
 
+func (repo *Repository) GetPaymentMethodStats() ([]PaymentMethodStats, error) {
+	var payments []models.PurchasePayment
+
+	err := repo.db.
+		Model(&models.PurchasePayment{}).
+		Select("purchase_payments.payment_method, purchase_payments.amount").
+		Joins("JOIN purchases ON "+
+			"purchases.id = purchase_payments.purchase_id AND "+
+			"purchases.deleted_at IS NULL AND "+
+			"purchases.status = ?", models.PurchaseStatusConfirmed).
+		Order("purchase_payments.payment_method ASC").
+		Find(&payments).Error
+	if err != nil {
+		return nil, err
+	}
+
+	stats := []PaymentMethodStats{}
+	indexByMethod := make(map[models.PaymentMethod]int)
+
+	for _, payment := range payments {
+		idx, ok := indexByMethod[payment.PaymentMethod]
+		if !ok {
+			stats = append(stats, PaymentMethodStats{PaymentMethod: payment.PaymentMethod, TotalAmount: decimal.Zero})
+			idx = len(stats) - 1
+			indexByMethod[payment.PaymentMethod] = idx
+		}
+
+		stats[idx].Count++
+		stats[idx].TotalAmount = stats[idx].TotalAmount.Add(payment.Amount)
+	}
+
+	refunds, err := repo.getConfirmedPurchaseRefundPayments()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, refund := range refunds {
+		if idx, ok := indexByMethod[refund.PaymentMethod]; ok {
+			stats[idx].TotalAmount = stats[idx].TotalAmount.Sub(refund.Amount)
+		}
+	}
+
+	return stats, nil
+}
+
 func (repo *Repository) GetPurchasedQuantitiesByProductID(productID int) (int, error) {
+	return repo.sumPurchasedQuantities("product_id", productID, models.PurchaseStatusConfirmed)
+}
+
+func (repo *Repository) GetPurchasedQuantitiesByVariantID(variantID int) (int, error) {
+	return repo.sumPurchasedQuantities("variant_id", variantID, models.PurchaseStatusConfirmed)
+}
+
+// GetReservedQuantitiesByProductID returns the quantity of the product taken from its stock by
+// confirmed and pending purchases, net of refunds.
+func (repo *Repository) GetReservedQuantitiesByProductID(productID int) (int, error) {
+	return repo.sumPurchasedQuantities("product_id", productID, reservingPurchaseStatuses...)
+}
+
+// GetReservedQuantitiesByVariantID returns the quantity of the variant taken from its stock by
+// confirmed and pending purchases, net of refunds.
+func (repo *Repository) GetReservedQuantitiesByVariantID(variantID int) (int, error) {
+	return repo.sumPurchasedQuantities("variant_id", variantID, reservingPurchaseStatuses...)
+}
+
+var reservingPurchaseStatuses = []models.PurchaseStatus{models.PurchaseStatusConfirmed, models.PurchaseStatusPending}
+
+func (repo *Repository) sumPurchasedQuantities(column string, id int, statuses ...models.PurchaseStatus) (int, error) {
 	var sum sql.NullInt64
 
 	err := repo.db.Table("purchase_items").
-		Select("SUM(quantity)").
+		Select("SUM(purchase_items.quantity - "+refundedQuantityExpr+")").
 		Joins("JOIN purchases ON "+
 			"(purchase_items.purchase_id = purchases.id AND purchase_items.deleted_at IS NULL)").
-		Where("purchase_items.product_id = ? AND "+
+		Where("purchase_items."+column+" = ? AND "+
 			"purchases.deleted_at IS NULL AND "+
-			"purchases.status = ?", productID, models.PurchaseStatusConfirmed).
+			"purchases.status IN ?", id, statuses).
 		Scan(&sum).Error
 	if err != nil {
 		return 0, err
@@ -263,3 +469,12 @@ func (repo *Repository) GetPurchasedQuantitiesByProductID(productID int) (int, e
 
 	return int(sum.Int64), nil
 }
+
+// PurchaseExists reports whether a purchase with the ID has been stored, even if it has been deleted since.
+func (repo *Repository) PurchaseExists(id uuid.UUID) (bool, error) {
+	var count int64
+
+	err := repo.db.Unscoped().Model(&models.Purchase{}).Where(whereIDEquals, id.String()).Count(&count).Error
+
+	return count > 0, err
+}
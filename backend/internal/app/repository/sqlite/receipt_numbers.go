@@ -0,0 +1,43 @@
+package sqlite
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/potibm/kasseapparat/internal/app/models"
+)
+
+// NextReceiptNumber increments the sequence of the prefix and fiscal year and returns the new
+// number. It has to be called within the transaction storing the number, so that a rollback
+// returns the number to the sequence.
+func (repo *Repository) NextReceiptNumber(prefix string, fiscalYear int) (uint, error) {
+	var number uint
+
+	err := repo.db.Raw(
+		`INSERT INTO receipt_number_sequences (prefix, fiscal_year, last_number) VALUES (?, ?, 1)
+		ON CONFLICT (prefix, fiscal_year) DO UPDATE SET last_number = last_number + 1
+		RETURNING last_number`,
+		prefix, fiscalYear,
+	).Scan(&number).Error
+	if err != nil {
+		return 0, fmt.Errorf("unable to get the next receipt number: %w", err)
+	}
+
+	return number, nil
+}
+
+// AssignPurchaseReceiptNumber sets the receipt number of a purchase that has none yet.
+func (repo *Repository) AssignPurchaseReceiptNumber(purchaseID uuid.UUID, receiptNumber string) error {
+	result := repo.db.Model(&models.Purchase{}).
+		Where("id = ? AND receipt_number IS NULL", purchaseID).
+		Update("receipt_number", receiptNumber)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("purchase %s already has a receipt number", purchaseID)
+	}
+
+	return nil
+}
@@ -0,0 +1,289 @@
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrRegisterSessionNotFound    = errors.New("register session not found")
+	ErrRegisterSessionAlreadyOpen = errors.New("there is already an open register session")
+)
+
+type RegisterSessionFilters struct {
+	CreatedByID int
+	Status      models.RegisterSessionStatus
+	IDs         []int
+}
+
+// RegisterSessionCashTotals is the cash taken by purchases and paid out by refunds in a session.
+type RegisterSessionCashTotals struct {
+	CashIn  decimal.Decimal
+	CashOut decimal.Decimal
+}
+
+var registerSessionSortFieldMappings = map[string]string{
+	"id":                 "register_sessions.id",
+	"openedAt":           "register_sessions.opened_at",
+	"closedAt":           "register_sessions.closed_at",
+	"status":             "register_sessions.status",
+	"createdBy.username": "CreatedBy.username",
+}
+
+func (filters RegisterSessionFilters) AddWhere(query *gorm.DB) *gorm.DB {
+	if len(filters.IDs) > 0 {
+		query = query.Where("register_sessions.id IN ?", filters.IDs)
+	}
+
+	if filters.CreatedByID != 0 {
+		query = query.Where("register_sessions.created_by_id = ?", filters.CreatedByID)
+	}
+
+	if filters.Status != "" {
+		query = query.Where("register_sessions.status = ?", filters.Status)
+	}
+
+	return query
+}
+
+func (repo *Repository) GetRegisterSessions(
+	limit int,
+	offset int,
+	sort string,
+	order string,
+	filters RegisterSessionFilters,
+) ([]models.RegisterSession, error) {
+	if order != "ASC" && order != "DESC" {
+		order = "ASC"
+	}
+
+	sortField, exists := registerSessionSortFieldMappings[sort]
+	if !exists {
+		return nil, errors.New("invalid sort field name")
+	}
+
+	var sessions []models.RegisterSession
+
+	query := repo.db.Joins("CreatedBy").
+		Model(&models.RegisterSession{}).
+		Preload("Denominations").
+		Order(sortField + " " + order + ", register_sessions.id DESC").
+		Limit(limit).
+		Offset(offset)
+	query = filters.AddWhere(query)
+
+	if err := query.Find(&sessions).Error; err != nil {
+		return nil, errors.New("register sessions not found")
+	}
+
+	return sessions, nil
+}
+
+func (repo *Repository) GetTotalRegisterSessions(filters RegisterSessionFilters) (int64, error) {
+	var totalRows int64
+
+	query := repo.db.Model(&models.RegisterSession{})
+	query = filters.AddWhere(query)
+
+	if err := query.Count(&totalRows).Error; err != nil {
+		return 0, err
+	}
+
+	return totalRows, nil
+}
+
+func (repo *Repository) GetRegisterSessionByID(id int) (*models.RegisterSession, error) {
+	var session models.RegisterSession
+	if err := repo.db.Joins("CreatedBy").Preload("Denominations").First(&session, "register_sessions.id = ?", id).
+		Error; err != nil {
+		return nil, ErrRegisterSessionNotFound
+	}
+
+	return &session, nil
+}
+
+func (repo *Repository) GetOpenRegisterSessionByUserID(userID int) (*models.RegisterSession, error) {
+	var session models.RegisterSession
+	if err := repo.db.
+		Where("created_by_id = ? AND status = ?", userID, models.RegisterSessionStatusOpen).
+		Order("opened_at DESC").
+		First(&session).
+		Error; err != nil {
+		return nil, ErrRegisterSessionNotFound
+	}
+
+	return &session, nil
+}
+
+// CreateRegisterSession opens the session. A cashier has at most one open session, which is
+// enforced by a partial unique index, so a second session opened concurrently is rejected.
+// Only a conflict on that index is ignored; any other constraint violation is returned.
+func (repo *Repository) CreateRegisterSession(session models.RegisterSession) (models.RegisterSession, error) {
+	result := repo.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "created_by_id"}},
+		// The target must repeat the predicate of the partial index literally for SQLite to match it
+		TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{
+			SQL: "status = '" + string(models.RegisterSessionStatusOpen) + "' AND deleted_at IS NULL",
+		}}},
+		DoNothing: true,
+	}).Create(&session)
+	if result.Error != nil {
+		return session, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return session, ErrRegisterSessionAlreadyOpen
+	}
+
+	return session, nil
+}
+
+// CloseRegisterSession closes the session with the counted cash and stores its closing figures
+// and counted denominations. The cash totals are calculated in the same transaction, so no
+// purchase or refund of the session is left out of the expected cash.
+func (repo *Repository) CloseRegisterSession(
+	session models.RegisterSession,
+	countedCash decimal.Decimal,
+) (*models.RegisterSession, error) {
+	err := repo.db.Transaction(func(tx *gorm.DB) error {
+		totals, err := repo.cloneWithDB(tx).GetRegisterSessionCashTotals(session.ID)
+		if err != nil {
+			return err
+		}
+
+		session.ApplyCashTotals(totals.CashIn, totals.CashOut)
+		session.Close(countedCash, time.Now())
+
+		result := tx.Model(&models.RegisterSession{}).
+			Where("id = ? AND status = ?", session.ID, models.RegisterSessionStatusOpen).
+			Updates(map[string]any{
+				"status":        session.Status,
+				"closed_at":     session.ClosedAt,
+				"cash_in":       session.CashIn,
+				"cash_out":      session.CashOut,
+				"expected_cash": session.ExpectedCash,
+				"counted_cash":  session.CountedCash,
+				"difference":    session.Difference,
+				"note":          session.Note,
+				"updated_by_id": session.UpdatedByID,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return ErrRegisterSessionNotFound
+		}
+
+		for _, denomination := range session.Denominations {
+			denomination.RegisterSessionID = session.ID
+			if err := tx.Create(&denomination).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to close register session %d: %w", session.ID, err)
+	}
+
+	return repo.GetRegisterSessionByID(session.ID)
+}
+
+// GetRegisterSessionCashTotals sums up the cash payments of the confirmed (or since refunded)
+// purchases, including their cash rounding, and the cash paid back by refunds attached to the
+// session, including the rounding of the refunds.
+func (repo *Repository) GetRegisterSessionCashTotals(sessionID int) (RegisterSessionCashTotals, error) {
+	totals := RegisterSessionCashTotals{CashIn: decimal.Zero, CashOut: decimal.Zero}
+
+	var payments []models.PurchasePayment
+
+	err := repo.db.
+		Model(&models.PurchasePayment{}).
+		Select("purchase_payments.amount").
+		Joins("JOIN purchases ON "+
+			"purchases.id = purchase_payments.purchase_id AND "+
+			"purchases.deleted_at IS NULL AND "+
+			"purchases.status IN ?",
+			models.PurchaseStatusList{models.PurchaseStatusConfirmed, models.PurchaseStatusRefunded}).
+		Where("purchases.register_session_id = ? AND purchase_payments.payment_method = ?",
+			sessionID, models.PaymentMethodCash).
+		Find(&payments).Error
+	if err != nil {
+		return totals, err
+	}
+
+	for _, payment := range payments {
+		totals.CashIn = totals.CashIn.Add(payment.Amount)
+	}
+
+	var roundings []models.PurchaseRounding
+
+	err = repo.db.
+		Model(&models.PurchaseRounding{}).
+		Select("purchase_roundings.amount").
+		Joins("JOIN purchases ON "+
+			"purchases.id = purchase_roundings.purchase_id AND "+
+			"purchases.deleted_at IS NULL AND "+
+			"purchases.status IN ?",
+			models.PurchaseStatusList{models.PurchaseStatusConfirmed, models.PurchaseStatusRefunded}).
+		Where("purchases.register_session_id = ? AND purchase_roundings.payment_method = ? AND "+
+			"purchase_roundings.purchase_refund_id IS NULL",
+			sessionID, models.PaymentMethodCash).
+		Find(&roundings).Error
+	if err != nil {
+		return totals, err
+	}
+
+	for _, rounding := range roundings {
+		totals.CashIn = totals.CashIn.Add(rounding.Amount)
+	}
+
+	var refunds []models.PurchaseRefundPayment
+
+	err = repo.db.
+		Model(&models.PurchaseRefundPayment{}).
+		Select("purchase_refund_payments.amount").
+		Joins("JOIN purchase_refunds ON "+
+			"purchase_refunds.id = purchase_refund_payments.purchase_refund_id AND "+
+			"purchase_refunds.deleted_at IS NULL").
+		Where("purchase_refunds.register_session_id = ? AND purchase_refund_payments.payment_method = ?",
+			sessionID, models.PaymentMethodCash).
+		Find(&refunds).Error
+	if err != nil {
+		return totals, err
+	}
+
+	for _, refund := range refunds {
+		totals.CashOut = totals.CashOut.Add(refund.Amount)
+	}
+
+	var refundRoundings []models.PurchaseRounding
+
+	err = repo.db.
+		Model(&models.PurchaseRounding{}).
+		Select("purchase_roundings.amount").
+		Joins("JOIN purchase_refunds ON "+
+			"purchase_refunds.id = purchase_roundings.purchase_refund_id AND "+
+			"purchase_refunds.deleted_at IS NULL").
+		Where("purchase_refunds.register_session_id = ? AND purchase_roundings.payment_method = ?",
+			sessionID, models.PaymentMethodCash).
+		Find(&refundRoundings).Error
+	if err != nil {
+		return totals, err
+	}
+
+	// the rounding of a refund is negative if more cash was paid back
+	for _, rounding := range refundRoundings {
+		totals.CashOut = totals.CashOut.Sub(rounding.Amount)
+	}
+
+	return totals, nil
+}
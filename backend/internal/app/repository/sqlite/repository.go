@@ -2,10 +2,12 @@ package sqlite
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/potibm/kasseapparat/internal/app/models"
 	response "github.com/potibm/kasseapparat/internal/app/response"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
@@ -21,6 +23,33 @@ type TransactionalRepository interface {
 	WithTransaction(ctx context.Context, fn func(repo RepositoryInterface) error) error
 }
 
+type CategoryRepository interface {
+	GetCategories(limit int, offset int, sort string, order string, filters CategoryFilters) ([]models.Category, error)
+	GetTotalCategories(filters CategoryFilters) (int64, error)
+	GetCategoryByID(id int) (*models.Category, error)
+	CreateCategory(category models.Category) (models.Category, error)
+	UpdateCategoryByID(id int, updatedCategory models.Category) (*models.Category, error)
+	DeleteCategory(category models.Category, deletedBy models.User) error
+}
+
+type ClosingReportRepository interface {
+	GetClosingReportFigures(periodStart time.Time, periodEnd time.Time) (models.ClosingReportFigures, error)
+	GetClosingReports(limit int, offset int, sort string, order string) ([]models.ClosingReport, error)
+	GetTotalClosingReports() (int64, error)
+	GetClosingReportByID(id int) (*models.ClosingReport, error)
+	StoreClosingReport(report models.ClosingReport) (*models.ClosingReport, error)
+}
+
+type DiscountRepository interface {
+	GetDiscounts(limit int, offset int, sort string, order string, filters DiscountFilters) ([]models.Discount, error)
+	GetTotalDiscounts(filters DiscountFilters) (int64, error)
+	GetDiscountByID(id int) (*models.Discount, error)
+	GetDiscountByCode(code string) (*models.Discount, error)
+	CreateDiscount(discount models.Discount) (models.Discount, error)
+	UpdateDiscountByID(id int, updatedDiscount models.Discount) (*models.Discount, error)
+	DeleteDiscount(discount models.Discount, deletedBy models.User)
+}
+
 type GuestRepository interface {
 	GuestCRUDRepository
 	GetGuestsByPurchaseID(purchaseID uuid.UUID) ([]models.Guest, error)
@@ -55,6 +84,27 @@ type GuestlistRepository interface {
 	DeleteGuestlist(guestlist models.Guestlist, deletedBy models.User)
 }
 
+type IdempotencyKeyRepository interface {
+	ReserveIdempotencyKey(key models.IdempotencyKey) (models.IdempotencyKey, bool, error)
+	CompleteIdempotencyKey(id int, statusCode int, contentType, body string, expiresAt time.Time) error
+	ReleaseIdempotencyKey(id int) error
+	DeleteExpiredIdempotencyKeys(before time.Time) (int64, error)
+}
+
+type InventoryRepository interface {
+	GetInventoryMovements(limit int, offset int, filters InventoryMovementFilters) ([]models.InventoryMovement, error)
+	GetTotalInventoryMovements(filters InventoryMovementFilters) (int64, error)
+	GetStockOnHand(productID int, variantID *int) (int, error)
+	StoreInventoryMovement(movement models.InventoryMovement) (models.InventoryMovement, error)
+	RecordInventoryMovement(movement models.InventoryMovement) (models.InventoryMovement, error)
+	BackfillInventoryMovements() error
+}
+
+type JournalRepository interface {
+	AppendJournalEntry(entry models.JournalEntry) (models.JournalEntry, error)
+	GetJournalEntries(afterSequence uint, limit int) ([]models.JournalEntry, error)
+}
+
 type ProductInterestRepository interface {
 	GetProductInterests(limit int, offset int, ids []int) ([]models.ProductInterest, error)
 	GetTotalProductInterests() (int64, error)
@@ -62,17 +112,22 @@ type ProductInterestRepository interface {
 	DeleteProductInterest(productInterest models.ProductInterest, deletedBy models.User)
 	CreateProductInterest(productInterest models.ProductInterest, createdBy models.User) (models.ProductInterest, error)
 	GetProductInterestCountByProductID(productID int) (int, error)
+	GetProductInterestCountByVariantID(variantID int) (int, error)
 }
 
 type ProductRepository interface {
 	GetProductStats() ([]response.ProductStats, error)
-	GetProducts(limit int, offset int, sort string, order string, ids []int) ([]models.Product, error)
-	GetTotalProducts() (int64, error)
+	GetProducts(limit int, offset int, sort string, order string, filters ProductFilters) ([]models.Product, error)
+	GetTotalProducts(filters ProductFilters) (int64, error)
 	GetProductByID(id int) (*models.Product, error)
 	UpdateProductByID(id int, updatedProduct models.Product) (*models.Product, error)
 	CreateProduct(product models.Product) (models.Product, error)
 	DeleteProduct(product models.Product, deletedBy models.User)
 	GetAttendedGuestSumByProductID(productID int) (int, error)
+	SetProductSoldOut(productID int, soldOut bool) error
+	SetProductVariantSoldOut(variantID int, soldOut bool) error
+	StoreProductPriceChanges(changes []models.ProductPriceChange) error
+	GetProductPriceChangesSince(productID int, since time.Time) ([]models.ProductPriceChange, error)
 }
 
 type PurchaseRepository interface {
@@ -81,23 +136,63 @@ type PurchaseRepository interface {
 	GetPurchaseBySumupClientTransactionID(sumupTransactionID uuid.UUID) (*models.Purchase, error)
 	UpdatePurchaseStatusByID(id uuid.UUID, status models.PurchaseStatus) (*models.Purchase, error)
 	UpdatePurchaseSumupTransactionIDByID(id, sumupTransactionID uuid.UUID) (*models.Purchase, error)
+	SettlePurchasePaymentsByPurchaseID(purchaseID uuid.UUID) error
+	MarkPurchaseExpiredByID(id uuid.UUID, expiredAt time.Time) error
+	StorePurchaseStatusTransition(transition models.PurchaseStatusTransition) error
 	UpdatePurchaseSumupClientTransactionIDByID(
 		id,
 		sumupClientTransactionID uuid.UUID,
 	) (*models.Purchase, error)
 	GetFilteredPurchases(filters PurchaseFilters) ([]models.PurchaseItem, error)
 	GetPurchaseStats() ([]ProductPurchaseStats, error)
+	GetPaymentMethodStats() ([]PaymentMethodStats, error)
 	GetPurchasedQuantitiesByProductID(productID int) (int, error)
+	GetPurchasedQuantitiesByVariantID(variantID int) (int, error)
+	GetReservedQuantitiesByProductID(productID int) (int, error)
+	GetReservedQuantitiesByVariantID(variantID int) (int, error)
+	StorePurchaseRefund(refund models.PurchaseRefund) (models.PurchaseRefund, error)
+	SettlePurchaseRefundByID(id int) error
+	ClaimPurchaseRefundSettlement(id int) (bool, error)
+	ReleasePurchaseRefundSettlement(id int) error
+	GetPurchaseRefundByID(id int) (*models.PurchaseRefund, error)
+	GetUnsettledPurchaseRefunds() ([]models.PurchaseRefund, error)
+	PurchaseExists(id uuid.UUID) (bool, error)
 }
 
 type PurchaseCRUDRepository interface {
 	StorePurchases(purchase models.Purchase) (models.Purchase, error)
-	DeletePurchaseByID(id uuid.UUID, deletedBy models.User)
+	DeletePurchaseByID(id uuid.UUID, deletedBy models.User) error
 	GetPurchaseByID(id uuid.UUID) (*models.Purchase, error)
 	GetTotalPurchases(filters PurchaseFilters) (int64, error)
 	GetPurchases(limit int, offset int, sort string, order string, filters PurchaseFilters) ([]models.Purchase, error)
 }
 
+type PurchaseReceiptMailRepository interface {
+	StorePurchaseReceiptMail(mail models.PurchaseReceiptMail) (models.PurchaseReceiptMail, error)
+	ErasePurchaseReceiptMailAddresses(sentBefore time.Time) (int64, error)
+}
+
+type ReceiptNumberRepository interface {
+	NextReceiptNumber(prefix string, fiscalYear int) (uint, error)
+	AssignPurchaseReceiptNumber(purchaseID uuid.UUID, receiptNumber string) error
+}
+
+type RegisterSessionRepository interface {
+	GetRegisterSessions(
+		limit int,
+		offset int,
+		sort string,
+		order string,
+		filters RegisterSessionFilters,
+	) ([]models.RegisterSession, error)
+	GetTotalRegisterSessions(filters RegisterSessionFilters) (int64, error)
+	GetRegisterSessionByID(id int) (*models.RegisterSession, error)
+	GetOpenRegisterSessionByUserID(userID int) (*models.RegisterSession, error)
+	CreateRegisterSession(session models.RegisterSession) (models.RegisterSession, error)
+	CloseRegisterSession(session models.RegisterSession, countedCash decimal.Decimal) (*models.RegisterSession, error)
+	GetRegisterSessionCashTotals(sessionID int) (RegisterSessionCashTotals, error)
+}
+
 type UserRepository interface {
 	GetUserByID(id int) (*models.User, error)
 	GetUsers(limit int, offset int, sort string, order string, filters UserFilters) ([]models.User, error)
@@ -110,14 +205,35 @@ type UserRepository interface {
 	GetUserByUsernameOrEmail(usernameOrEmail string) (*models.User, error)
 }
 
+type VoucherRepository interface {
+	GetVouchers(limit int, offset int, sort string, order string, filters VoucherFilters) ([]models.Voucher, error)
+	GetTotalVouchers(filters VoucherFilters) (int64, error)
+	GetVoucherByID(id int) (*models.Voucher, error)
+	CreateVouchers(vouchers []models.Voucher) ([]models.Voucher, error)
+	UpdateVoucherByID(id int, updatedVoucher models.Voucher) (*models.Voucher, error)
+	DeleteVoucher(voucher models.Voucher, deletedBy models.User)
+	RedeemVoucher(code string, amount decimal.Decimal, purchaseID uuid.UUID) (*models.Voucher, error)
+	CreditVoucherRedemptions(purchaseID uuid.UUID, amount decimal.Decimal, purchaseRefundID *int) error
+}
+
 type RepositoryInterface interface {
 	TransactionalRepository
+	CategoryRepository
+	ClosingReportRepository
+	DiscountRepository
 	GuestRepository
 	GuestlistRepository
+	IdempotencyKeyRepository
+	InventoryRepository
+	JournalRepository
 	ProductInterestRepository
 	ProductRepository
 	PurchaseRepository
+	PurchaseReceiptMailRepository
+	ReceiptNumberRepository
+	RegisterSessionRepository
 	UserRepository
+	VoucherRepository
 }
 
 var _ RepositoryInterface = (*Repository)(nil)
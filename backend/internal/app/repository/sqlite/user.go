@@ -47,7 +47,7 @@ func (filters UserFilters) AddWhere(query *gorm.DB) *gorm.DB {
 
 func (repo *Repository) GetUserByID(id int) (*models.User, error) {
 	var user models.User
-	if err := repo.db.Model(&models.User{}).First(&user, id).Error; err != nil {
+	if err := repo.db.Model(&models.User{}).Preload("Categories").First(&user, id).Error; err != nil {
 		return nil, ErrUserNotFound
 	}
 
@@ -120,7 +120,7 @@ func (repo *Repository) GetUsers(
 		order = "ASC"
 	}
 
-	query := repo.db.Order(sort + " " + order + ", ID ASC").Limit(limit).Offset(offset)
+	query := repo.db.Preload("Categories").Order(sort + " " + order + ", ID ASC").Limit(limit).Offset(offset)
 	query = filters.AddWhere(query)
 
 	var users []models.User
@@ -196,6 +196,7 @@ func (repo *Repository) UpdateUserByID(id int, updatedUser models.User) (*models
 	// Update the product with the new values
 	user.Username = strings.ToLower(updatedUser.Username)
 	user.Admin = updatedUser.Admin
+	user.GrantDiscounts = updatedUser.GrantDiscounts
 	user.Email = updatedUser.Email
 	user.ChangePasswordToken = updatedUser.ChangePasswordToken
 	user.ChangePasswordTokenExpiry = updatedUser.ChangePasswordTokenExpiry
@@ -211,5 +212,12 @@ func (repo *Repository) UpdateUserByID(id int, updatedUser models.User) (*models
 		return nil, errors.New("failed to update user")
 	}
 
-	return &user, nil
+	// the categories are only replaced when given
+	if updatedUser.Categories != nil {
+		if err := repo.db.Model(&user).Association("Categories").Replace(updatedUser.Categories); err != nil {
+			return nil, errors.New("failed to update the categories of the user")
+		}
+	}
+
+	return repo.GetUserByID(id)
 }
@@ -0,0 +1,258 @@
+package sqlite
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrVoucherNotFound       = errors.New("voucher not found")
+	ErrVoucherBalanceChanged = errors.New("voucher balance has been changed by another redemption")
+)
+
+type VoucherFilters struct {
+	Query string
+	IDs   []int
+}
+
+var voucherSortFieldMappings = map[string]string{
+	"id":        "vouchers.id",
+	"code":      "vouchers.code",
+	"name":      "LOWER(vouchers.name)",
+	"value":     "CAST(vouchers.value AS REAL)",
+	"balance":   "CAST(vouchers.balance AS REAL)",
+	"expiresAt": "vouchers.expires_at",
+	"createdAt": "vouchers.created_at",
+}
+
+func (filters VoucherFilters) AddWhere(query *gorm.DB) *gorm.DB {
+	if len(filters.IDs) > 0 {
+		query = query.Where("vouchers.id IN ?", filters.IDs)
+	}
+
+	if filters.Query != "" {
+		query = query.Where(
+			"vouchers.code LIKE ? OR vouchers.name LIKE ?",
+			"%"+filters.Query+"%",
+			"%"+filters.Query+"%",
+		)
+	}
+
+	return query
+}
+
+func (repo *Repository) GetVouchers(
+	limit int,
+	offset int,
+	sort string,
+	order string,
+	filters VoucherFilters,
+) ([]models.Voucher, error) {
+	if order != "ASC" && order != "DESC" {
+		order = "ASC"
+	}
+
+	sortField, exists := voucherSortFieldMappings[sort]
+	if !exists {
+		return nil, errors.New("invalid sort field name")
+	}
+
+	var vouchers []models.Voucher
+
+	query := repo.db.Model(&models.Voucher{}).
+		Order(sortField + " " + order + ", vouchers.id ASC").
+		Limit(limit).
+		Offset(offset)
+	query = filters.AddWhere(query)
+
+	if err := query.Find(&vouchers).Error; err != nil {
+		return nil, errors.New("vouchers not found")
+	}
+
+	return vouchers, nil
+}
+
+func (repo *Repository) GetTotalVouchers(filters VoucherFilters) (int64, error) {
+	var totalRows int64
+
+	query := repo.db.Model(&models.Voucher{})
+	query = filters.AddWhere(query)
+
+	if err := query.Count(&totalRows).Error; err != nil {
+		return 0, err
+	}
+
+	return totalRows, nil
+}
+
+// GetVoucherByID returns the voucher with its redemption history.
+func (repo *Repository) GetVoucherByID(id int) (*models.Voucher, error) {
+	var voucher models.Voucher
+
+	err := repo.db.
+		Preload("Redemptions", func(db *gorm.DB) *gorm.DB {
+			return db.Order("voucher_redemptions.id ASC")
+		}).
+		First(&voucher, "vouchers.id = ?", id).Error
+	if err != nil {
+		return nil, ErrVoucherNotFound
+	}
+
+	return &voucher, nil
+}
+
+func (repo *Repository) CreateVouchers(vouchers []models.Voucher) ([]models.Voucher, error) {
+	if err := repo.db.Create(&vouchers).Error; err != nil {
+		return nil, fmt.Errorf("unable to store the vouchers: %w", err)
+	}
+
+	return vouchers, nil
+}
+
+func (repo *Repository) UpdateVoucherByID(id int, updatedVoucher models.Voucher) (*models.Voucher, error) {
+	var voucher models.Voucher
+	if err := repo.db.First(&voucher, id).Error; err != nil {
+		return nil, ErrVoucherNotFound
+	}
+
+	voucher.Name = updatedVoucher.Name
+	voucher.ExpiresAt = updatedVoucher.ExpiresAt
+	voucher.UpdatedByID = updatedVoucher.UpdatedByID
+
+	if err := repo.db.Select("Name", "ExpiresAt", "UpdatedByID", "UpdatedAt").Save(&voucher).Error; err != nil {
+		return nil, errors.New("failed to update voucher")
+	}
+
+	return repo.GetVoucherByID(id)
+}
+
+func (repo *Repository) DeleteVoucher(voucher models.Voucher, deletedBy models.User) {
+	repo.db.Model(&models.Voucher{}).Where(whereIDEquals, voucher.ID).Update("DeletedByID", deletedBy.ID)
+	repo.db.Delete(&voucher)
+}
+
+// RedeemVoucher takes the amount from the balance of the voucher with the code and records the
+// redemption. It has to be called within the transaction storing the purchase. The balance is
+// only changed if it still is the balance that has been checked, so a voucher cannot be spent twice.
+func (repo *Repository) RedeemVoucher(
+	code string,
+	amount decimal.Decimal,
+	purchaseID uuid.UUID,
+) (*models.Voucher, error) {
+	var voucher models.Voucher
+	if err := repo.db.Where("code = ?", models.NormalizeVoucherCode(code)).First(&voucher).Error; err != nil {
+		return nil, ErrVoucherNotFound
+	}
+
+	if err := voucher.CheckRedeemable(amount, time.Now()); err != nil {
+		return nil, err
+	}
+
+	if err := repo.changeVoucherBalance(voucher, voucher.Balance.Sub(amount)); err != nil {
+		return nil, err
+	}
+
+	redemption := models.VoucherRedemption{
+		VoucherID:  voucher.ID,
+		PurchaseID: purchaseID,
+		Amount:     amount,
+	}
+	if err := repo.db.Create(&redemption).Error; err != nil {
+		return nil, fmt.Errorf("unable to store the voucher redemption: %w", err)
+	}
+
+	voucher.Balance = voucher.Balance.Sub(amount)
+
+	return &voucher, nil
+}
+
+// CreditVoucherRedemptions credits up to the amount back to the vouchers redeemed for the purchase,
+// starting with the voucher redeemed last. A voucher is never credited more than was taken from it.
+func (repo *Repository) CreditVoucherRedemptions(
+	purchaseID uuid.UUID,
+	amount decimal.Decimal,
+	purchaseRefundID *int,
+) error {
+	var redemptions []models.VoucherRedemption
+	if err := repo.db.Where("purchase_id = ?", purchaseID).Order("id DESC").Find(&redemptions).Error; err != nil {
+		return fmt.Errorf("unable to retrieve the voucher redemptions: %w", err)
+	}
+
+	redeemed := make(map[int]decimal.Decimal)
+
+	var voucherIDs []int
+
+	for _, redemption := range redemptions {
+		if _, ok := redeemed[redemption.VoucherID]; !ok {
+			voucherIDs = append(voucherIDs, redemption.VoucherID)
+		}
+
+		redeemed[redemption.VoucherID] = redeemed[redemption.VoucherID].Add(redemption.Amount)
+	}
+
+	remaining := amount
+
+	for _, voucherID := range voucherIDs {
+		credit := decimal.Min(remaining, redeemed[voucherID])
+		if !credit.IsPositive() {
+			continue
+		}
+
+		if err := repo.creditVoucher(voucherID, credit, purchaseID, purchaseRefundID); err != nil {
+			return err
+		}
+
+		remaining = remaining.Sub(credit)
+	}
+
+	return nil
+}
+
+func (repo *Repository) creditVoucher(
+	voucherID int,
+	credit decimal.Decimal,
+	purchaseID uuid.UUID,
+	purchaseRefundID *int,
+) error {
+	var voucher models.Voucher
+	if err := repo.db.Unscoped().First(&voucher, voucherID).Error; err != nil {
+		return ErrVoucherNotFound
+	}
+
+	if err := repo.changeVoucherBalance(voucher, voucher.Balance.Add(credit)); err != nil {
+		return err
+	}
+
+	redemption := models.VoucherRedemption{
+		VoucherID:        voucherID,
+		PurchaseID:       purchaseID,
+		PurchaseRefundID: purchaseRefundID,
+		Amount:           credit.Neg(),
+	}
+	if err := repo.db.Create(&redemption).Error; err != nil {
+		return fmt.Errorf("unable to store the voucher credit: %w", err)
+	}
+
+	return nil
+}
+
+func (repo *Repository) changeVoucherBalance(voucher models.Voucher, balance decimal.Decimal) error {
+	result := repo.db.Unscoped().Model(&models.Voucher{}).
+		Where("id = ? AND balance = ?", voucher.ID, voucher.Balance).
+		Update("balance", balance)
+	if result.Error != nil {
+		return fmt.Errorf("unable to update the voucher balance: %w", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return ErrVoucherBalanceChanged
+	}
+
+	return nil
+}
@@ -18,15 +18,16 @@ type Reader struct {
 }
 
 type Transaction struct {
-	ID              string
-	TransactionID   uuid.UUID
-	TransactionCode string
-	Amount          decimal.Decimal
-	Currency        string
-	CardType        string
-	CreatedAt       time.Time
-	Events          []TransactionEvent
-	Status          string
+	ID                  string
+	TransactionID       uuid.UUID
+	ClientTransactionID uuid.UUID
+	TransactionCode     string
+	Amount              decimal.Decimal
+	Currency            string
+	CardType            string
+	CreatedAt           time.Time
+	Events              []TransactionEvent
+	Status              string
 }
 
 type TransactionEvent struct {
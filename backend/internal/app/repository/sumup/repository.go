@@ -38,9 +38,10 @@ type ReaderRepository interface {
 
 type TransactionRepository interface {
 	GetTransactions(oldestTime *time.Time) ([]Transaction, error)
+	GetTransactionsInPeriod(oldestTime, newestTime time.Time) ([]Transaction, error)
 	GetTransactionByID(transactionID uuid.UUID) (*Transaction, error)
 	GetTransactionByClientTransactionID(clientTransactionID uuid.UUID) (*Transaction, error)
-	RefundTransaction(transactionID uuid.UUID) error
+	RefundTransaction(transactionID uuid.UUID, amount decimal.Decimal) error
 }
 
 func NewRepository(service *sumupService.Service) RepositoryInterface {
@@ -2,6 +2,7 @@ package sumup
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/url"
@@ -15,56 +16,70 @@ import (
 )
 
 const (
-	TransactionPageSize = 100
-	TransactionMaxPages = 5
+	TransactionPageSize       = 100
+	TransactionMaxPages       = 5
+	TransactionPeriodMaxPages = 100
 )
 
+var ErrTooManyTransactions = errors.New("too many transactions in the period")
+
 func (r *Repository) GetTransactions(oldestFrom *time.Time) ([]Transaction, error) {
 	ctx := context.Background()
 
-	sdkItems, err := r.fetchPagedTransactions(ctx, oldestFrom, TransactionMaxPages, TransactionPageSize)
-	if err != nil {
-		return nil, err
+	pageSize := TransactionPageSize
+	params := sumup.TransactionsListParams{
+		Limit:      &pageSize,
+		OldestTime: oldestFrom,
 	}
 
-	result := make([]Transaction, 0, len(sdkItems))
-	for _, sdkTx := range sdkItems {
-		result = append(result, *fromSDKTransaction(sdkTx))
+	sdkItems, _, err := r.fetchPagedTransactions(ctx, params, TransactionMaxPages)
+	if err != nil {
+		return nil, err
 	}
 
-	return result, nil
+	return fromSDKTransactions(sdkItems), nil
 }
 
-func (r *Repository) fetchPagedTransactions(
-	ctx context.Context,
-	oldestFrom *time.Time,
-	maxPages, pageSize int,
-) ([]*sumup.TransactionHistory, error) {
-	var allItems []*sumup.TransactionHistory
-
-	pageCount := 0
+// GetTransactionsInPeriod returns all transactions created in the given period. Other than
+// GetTransactions it does not cut off the list, but fails if the period holds too many transactions.
+func (r *Repository) GetTransactionsInPeriod(oldestTime, newestTime time.Time) ([]Transaction, error) {
+	ctx := context.Background()
 
+	pageSize := TransactionPageSize
 	params := sumup.TransactionsListParams{
-		Limit: &pageSize,
+		Limit:      &pageSize,
+		OldestTime: &oldestTime,
+		NewestTime: &newestTime,
 	}
-	if oldestFrom != nil {
-		params.OldestTime = oldestFrom
+
+	sdkItems, complete, err := r.fetchPagedTransactions(ctx, params, TransactionPeriodMaxPages)
+	if err != nil {
+		return nil, err
 	}
 
-	for {
-		if pageCount >= maxPages {
-			return allItems, nil
-		}
+	if !complete {
+		return nil, fmt.Errorf("%w: more than %d pages", ErrTooManyTransactions, TransactionPeriodMaxPages)
+	}
 
-		pageCount++
+	return fromSDKTransactions(sdkItems), nil
+}
 
+// fetchPagedTransactions follows the next page links up to maxPages and reports whether all pages were fetched.
+func (r *Repository) fetchPagedTransactions(
+	ctx context.Context,
+	params sumup.TransactionsListParams,
+	maxPages int,
+) ([]*sumup.TransactionHistory, bool, error) {
+	var allItems []*sumup.TransactionHistory
+
+	for pageCount := 0; pageCount < maxPages; pageCount++ {
 		resp, err := r.service.Client.Transactions.List(ctx, r.service.MerchantCode, params)
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 
 		if resp.Items == nil {
-			return allItems, nil
+			return allItems, true, nil
 		}
 
 		allItems = append(allItems, ptrSliceToSlice(&resp.Items)...)
@@ -72,18 +87,29 @@ func (r *Repository) fetchPagedTransactions(
 
 		nextHref := findNextHref(resp.Links)
 		if nextHref == "" {
-			return allItems, nil
+			return allItems, true, nil
 		}
 
 		nextParams, err := parseHrefToListTransactionsParams(nextHref)
 		if err != nil {
 			slog.WarnContext(ctx, "Error parsing next page link", "error", err)
 
-			return allItems, nil
+			return allItems, false, nil
 		}
 
 		params = *nextParams
 	}
+
+	return allItems, false, nil
+}
+
+func fromSDKTransactions(sdkItems []*sumup.TransactionHistory) []Transaction {
+	result := make([]Transaction, 0, len(sdkItems))
+	for _, sdkTx := range sdkItems {
+		result = append(result, *fromSDKTransaction(sdkTx))
+	}
+
+	return result
 }
 
 func sortTransactionsByCreatedAt(transactions []*sumup.TransactionHistory) {
@@ -228,12 +254,29 @@ func (r *Repository) GetTransactionByClientTransactionID(clientTransactionID uui
 	return transaction, nil
 }
 
-func (r *Repository) RefundTransaction(transactionID uuid.UUID) error {
-	body := sumup.TransactionsRefundParams{}
+// RefundTransaction refunds the given amount of a transaction. SumUp refunds the whole
+// transaction if no amount is given, so a zero amount is rejected.
+func (r *Repository) RefundTransaction(transactionID uuid.UUID, amount decimal.Decimal) error {
+	if !amount.IsPositive() {
+		return fmt.Errorf("invalid refund amount %s for transaction %s", amount, transactionID)
+	}
+
+	refundAmount := float32(amount.InexactFloat64())
+	body := sumup.TransactionsRefundParams{
+		Amount: &refundAmount,
+	}
 
 	err := r.service.Client.Transactions.Refund(context.Background(), transactionID.String(), body)
 	if err != nil {
-		slog.Error("Error refunding transaction with ID", "transaction_id", transactionID, "error", err)
+		slog.Error(
+			"Error refunding transaction with ID",
+			"transaction_id",
+			transactionID,
+			"amount",
+			amount,
+			"error",
+			err,
+		)
 
 		return normalizeSumupError(err)
 	}
@@ -252,14 +295,15 @@ func fromSDKTransaction(sdkCheckout *sumup.TransactionHistory) *Transaction {
 	}
 
 	return &Transaction{
-		ID:              stringOrEmpty(sdkCheckout.ID),
-		TransactionCode: stringOrEmpty(sdkCheckout.TransactionCode),
-		TransactionID:   transactionID,
-		Amount:          utils.F32PtrToDecimal(sdkCheckout.Amount),
-		Currency:        stringOrEmpty(sdkCheckout.Currency),
-		CardType:        stringOrEmpty(sdkCheckout.CardType),
-		CreatedAt:       utils.TimePtr(sdkCheckout.Timestamp),
-		Status:          stringOrEmpty(sdkCheckout.Status),
+		ID:                  stringOrEmpty(sdkCheckout.ID),
+		TransactionCode:     stringOrEmpty(sdkCheckout.TransactionCode),
+		TransactionID:       transactionID,
+		ClientTransactionID: parseUUIDOrNil(sdkCheckout.ClientTransactionID),
+		Amount:              utils.F32PtrToDecimal(sdkCheckout.Amount),
+		Currency:            stringOrEmpty(sdkCheckout.Currency),
+		CardType:            stringOrEmpty(sdkCheckout.CardType),
+		CreatedAt:           utils.TimePtr(sdkCheckout.Timestamp),
+		Status:              stringOrEmpty(sdkCheckout.Status),
 	}
 }
 
@@ -289,15 +333,16 @@ func fromSDKTransactionFull(sdkCheckout *sumup.TransactionFull) *Transaction {
 	}
 
 	return &Transaction{
-		ID:              transactionID.String(),
-		TransactionCode: stringOrEmpty(sdkCheckout.TransactionCode),
-		TransactionID:   transactionID,
-		Amount:          utils.F32PtrToDecimal(sdkCheckout.Amount),
-		Currency:        stringOrEmpty(sdkCheckout.Currency),
-		CardType:        cardType,
-		CreatedAt:       utils.TimePtr(sdkCheckout.Timestamp),
-		Events:          events,
-		Status:          stringOrEmpty(sdkCheckout.Status),
+		ID:                  transactionID.String(),
+		TransactionCode:     stringOrEmpty(sdkCheckout.TransactionCode),
+		TransactionID:       transactionID,
+		ClientTransactionID: parseUUIDOrNil(sdkCheckout.ClientTransactionID),
+		Amount:              utils.F32PtrToDecimal(sdkCheckout.Amount),
+		Currency:            stringOrEmpty(sdkCheckout.Currency),
+		CardType:            cardType,
+		CreatedAt:           utils.TimePtr(sdkCheckout.Timestamp),
+		Events:              events,
+		Status:              stringOrEmpty(sdkCheckout.Status),
 	}
 }
 
@@ -80,18 +80,20 @@ func TestFromSDKTransactionWithUUIDId(t *testing.T) {
 	currency := sumup.CurrencyEUR
 	cardType := sumup.CardTypeMastercard
 	status := sumup.TransactionHistoryStatus("SUCCESSFUL")
+	clientTransactionID := "7f0e9c4a-3b1d-4c8e-9a52-1d6f0b2e8c41"
 
 	timestamp := parseTime(t, "2025-06-15T20:45:27.588Z")
 
 	sdk := &sumup.TransactionHistory{
-		ID:              &id,
-		TransactionCode: &tc,
-		TransactionID:   &tid,
-		Amount:          &amount,
-		Currency:        &currency,
-		CardType:        &cardType,
-		Timestamp:       &timestamp,
-		Status:          &status,
+		ID:                  &id,
+		TransactionCode:     &tc,
+		TransactionID:       &tid,
+		ClientTransactionID: &clientTransactionID,
+		Amount:              &amount,
+		Currency:            &currency,
+		CardType:            &cardType,
+		Timestamp:           &timestamp,
+		Status:              &status,
 	}
 
 	tx := fromSDKTransaction(sdk)
@@ -99,6 +101,7 @@ func TestFromSDKTransactionWithUUIDId(t *testing.T) {
 	assert.Equal(t, id, tx.ID)
 	assert.Equal(t, tc, tx.TransactionCode)
 	assert.Equal(t, uuid.MustParse(id), tx.TransactionID)
+	assert.Equal(t, uuid.MustParse(clientTransactionID), tx.ClientTransactionID)
 	assert.Equal(t, "EUR", tx.Currency)
 	assert.Equal(t, "MASTERCARD", tx.CardType)
 	assert.Equal(t, "SUCCESSFUL", tx.Status)
@@ -131,6 +134,7 @@ func TestFromSDKTransactionWithNonUUIDId(t *testing.T) {
 	assert.Equal(t, id, tx.ID)
 	assert.Equal(t, tc, tx.TransactionCode)
 	assert.Equal(t, uuid.MustParse(string(tid)), tx.TransactionID)
+	assert.Equal(t, uuid.Nil, tx.ClientTransactionID)
 	assert.Equal(t, "EUR", tx.Currency)
 	assert.Equal(t, "MASTERCARD", tx.CardType)
 	assert.Equal(t, "REFUNDED", tx.Status)
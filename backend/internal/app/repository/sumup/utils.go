@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/google/uuid"
 	sumup "github.com/sumup/sumup-go"
 )
 
@@ -63,6 +64,20 @@ func stringOrEmpty[T ~string](s *T) string {
 	return string(*s)
 }
 
+// parseUUIDOrNil parses the UUID of a pointer or returns uuid.Nil if nil or not a UUID.
+func parseUUIDOrNil(s *string) uuid.UUID {
+	if s == nil {
+		return uuid.Nil
+	}
+
+	parsed, err := uuid.Parse(*s)
+	if err != nil {
+		return uuid.Nil
+	}
+
+	return parsed
+}
+
 func normalizeSumupError(err error) error {
 	if err == nil {
 		return nil
@@ -1,6 +1,7 @@
 package sumup
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,10 +10,24 @@ import (
 type TransactionStatus string
 
 const (
+	StatusPending    TransactionStatus = "pending"
 	StatusSuccessful TransactionStatus = "successful"
 	StatusFailed     TransactionStatus = "failed"
+	StatusCancelled  TransactionStatus = "cancelled"
+	StatusCanceled   TransactionStatus = "canceled"
+	StatusRefunded   TransactionStatus = "refunded"
 )
 
+// Normalize maps the spellings of a status, e.g. SUCCESSFUL or CANCELED, to the status constants.
+func (s TransactionStatus) Normalize() TransactionStatus {
+	status := TransactionStatus(strings.ToLower(string(s)))
+	if status == StatusCanceled {
+		return StatusCancelled
+	}
+
+	return status
+}
+
 type SumupTransactionWebhookPayload struct {
 	ID        uuid.UUID                          `json:"id"`
 	EventType string                             `json:"event_type"`
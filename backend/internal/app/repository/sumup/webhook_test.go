@@ -0,0 +1,15 @@
+package sumup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransactionStatusNormalize(t *testing.T) {
+	assert.Equal(t, StatusSuccessful, TransactionStatus("SUCCESSFUL").Normalize())
+	assert.Equal(t, StatusFailed, TransactionStatus("failed").Normalize())
+	assert.Equal(t, StatusCancelled, TransactionStatus("CANCELLED").Normalize())
+	assert.Equal(t, StatusCancelled, TransactionStatus("CANCELED").Normalize())
+	assert.Equal(t, TransactionStatus("pending"), TransactionStatus("PENDING").Normalize())
+}
@@ -0,0 +1,181 @@
+package terminal
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// The terminals of the fake provider. The outcome of a checkout depends on the terminal it runs on.
+const (
+	FakeTerminalApprove = "fake-approve"
+	FakeTerminalDecline = "fake-decline"
+	FakeTerminalTimeout = "fake-timeout"
+)
+
+var (
+	ErrTransactionNotFound = errors.New("transaction not found")
+	ErrInvalidRefundAmount = errors.New("invalid refund amount")
+)
+
+var fakeTerminals = []Terminal{
+	{ID: FakeTerminalApprove, Name: "Fake terminal (approves)", Status: "ONLINE", Model: "fake"},
+	{ID: FakeTerminalDecline, Name: "Fake terminal (declines)", Status: "ONLINE", Model: "fake"},
+	{ID: FakeTerminalTimeout, Name: "Fake terminal (never answers)", Status: "ONLINE", Model: "fake"},
+}
+
+type fakeCheckout struct {
+	Checkout
+
+	terminalID string
+	createdAt  time.Time
+	refunded   decimal.Decimal
+}
+
+// FakeProvider simulates payment terminals in memory, so cashiers can be trained and tests can run
+// without a vendor account. A checkout stays pending for the delay, then it is approved or declined
+// depending on its terminal. Checkouts on the timeout terminal stay pending until they are cancelled.
+type FakeProvider struct {
+	mu        sync.Mutex
+	delay     time.Duration
+	now       func() time.Time
+	checkouts map[uuid.UUID]*fakeCheckout
+}
+
+var _ PaymentTerminalProvider = (*FakeProvider)(nil)
+
+func NewFakeProvider(delay time.Duration) *FakeProvider {
+	return &FakeProvider{
+		delay:     delay,
+		now:       time.Now,
+		checkouts: make(map[uuid.UUID]*fakeCheckout),
+	}
+}
+
+func (p *FakeProvider) GetTerminals() ([]Terminal, error) {
+	terminals := make([]Terminal, len(fakeTerminals))
+	copy(terminals, fakeTerminals)
+
+	return terminals, nil
+}
+
+func (p *FakeProvider) CreateCheckout(
+	terminalID string,
+	amount decimal.Decimal,
+	_, _ string,
+) (*uuid.UUID, error) {
+	if !isFakeTerminal(terminalID) {
+		return nil, ErrTerminalNotFound
+	}
+
+	if !amount.IsPositive() {
+		return nil, fmt.Errorf("invalid checkout amount %s", amount)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	clientTransactionID := uuid.New()
+	p.checkouts[clientTransactionID] = &fakeCheckout{
+		Checkout: Checkout{
+			ClientTransactionID: clientTransactionID,
+			Amount:              amount,
+			Status:              CheckoutStatusPending,
+		},
+		terminalID: terminalID,
+		createdAt:  p.now(),
+	}
+
+	return &clientTransactionID, nil
+}
+
+func (p *FakeProvider) GetCheckout(clientTransactionID uuid.UUID) (*Checkout, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	checkout, ok := p.checkouts[clientTransactionID]
+	if !ok {
+		return nil, ErrCheckoutNotFound
+	}
+
+	p.resolve(checkout)
+
+	result := checkout.Checkout
+
+	return &result, nil
+}
+
+func (p *FakeProvider) CancelCheckout(terminalID string) error {
+	if !isFakeTerminal(terminalID) {
+		return ErrTerminalNotFound
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, checkout := range p.checkouts {
+		if checkout.terminalID != terminalID {
+			continue
+		}
+
+		p.resolve(checkout)
+
+		if checkout.Status == CheckoutStatusPending {
+			checkout.Status = CheckoutStatusCancelled
+		}
+	}
+
+	return nil
+}
+
+func (p *FakeProvider) RefundTransaction(transactionID uuid.UUID, amount decimal.Decimal) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, checkout := range p.checkouts {
+		if checkout.TransactionID != transactionID || checkout.Status != CheckoutStatusSuccessful {
+			continue
+		}
+
+		if !amount.IsPositive() || checkout.refunded.Add(amount).GreaterThan(checkout.Amount) {
+			return fmt.Errorf("%w %s for transaction %s", ErrInvalidRefundAmount, amount, transactionID)
+		}
+
+		checkout.refunded = checkout.refunded.Add(amount)
+
+		return nil
+	}
+
+	return ErrTransactionNotFound
+}
+
+// resolve approves or declines a pending checkout once its delay has passed.
+func (p *FakeProvider) resolve(checkout *fakeCheckout) {
+	if checkout.Status != CheckoutStatusPending || p.now().Sub(checkout.createdAt) < p.delay {
+		return
+	}
+
+	switch checkout.terminalID {
+	case FakeTerminalApprove:
+		checkout.Status = CheckoutStatusSuccessful
+		checkout.TransactionID = uuid.New()
+		checkout.TransactionCode = "FAKE" + strings.ToUpper(checkout.TransactionID.String()[:6])
+	case FakeTerminalDecline:
+		checkout.Status = CheckoutStatusFailed
+	}
+}
+
+func isFakeTerminal(terminalID string) bool {
+	for _, terminal := range fakeTerminals {
+		if terminal.ID == terminalID {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,111 @@
+package terminal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestFakeProvider() (*FakeProvider, *time.Time) {
+	now := time.Date(2026, 10, 18, 21, 30, 0, 0, time.UTC)
+	provider := NewFakeProvider(3 * time.Second)
+	provider.now = func() time.Time { return now }
+
+	return provider, &now
+}
+
+func TestFakeProviderCheckoutOutcomeDependsOnTheTerminal(t *testing.T) {
+	tests := []struct {
+		terminalID string
+		expected   CheckoutStatus
+	}{
+		{FakeTerminalApprove, CheckoutStatusSuccessful},
+		{FakeTerminalDecline, CheckoutStatusFailed},
+		{FakeTerminalTimeout, CheckoutStatusPending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.terminalID, func(t *testing.T) {
+			provider, now := newTestFakeProvider()
+
+			clientTransactionID, err := provider.CreateCheckout(tt.terminalID, decimal.NewFromInt(10), "", "")
+			require.NoError(t, err)
+
+			checkout, err := provider.GetCheckout(*clientTransactionID)
+			require.NoError(t, err)
+			assert.Equal(t, CheckoutStatusPending, checkout.Status)
+
+			*now = now.Add(3 * time.Second)
+
+			checkout, err = provider.GetCheckout(*clientTransactionID)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, checkout.Status)
+			assert.Equal(t, tt.expected == CheckoutStatusSuccessful, checkout.TransactionID != uuid.Nil)
+		})
+	}
+}
+
+func TestFakeProviderCancelsPendingCheckouts(t *testing.T) {
+	provider, now := newTestFakeProvider()
+
+	clientTransactionID, err := provider.CreateCheckout(FakeTerminalTimeout, decimal.NewFromInt(10), "", "")
+	require.NoError(t, err)
+
+	*now = now.Add(time.Minute)
+
+	require.NoError(t, provider.CancelCheckout(FakeTerminalTimeout))
+
+	checkout, err := provider.GetCheckout(*clientTransactionID)
+	require.NoError(t, err)
+	assert.Equal(t, CheckoutStatusCancelled, checkout.Status)
+}
+
+func TestFakeProviderDoesNotCancelFinishedCheckouts(t *testing.T) {
+	provider, now := newTestFakeProvider()
+
+	clientTransactionID, err := provider.CreateCheckout(FakeTerminalApprove, decimal.NewFromInt(10), "", "")
+	require.NoError(t, err)
+
+	*now = now.Add(5 * time.Second)
+
+	require.NoError(t, provider.CancelCheckout(FakeTerminalApprove))
+
+	checkout, err := provider.GetCheckout(*clientTransactionID)
+	require.NoError(t, err)
+	assert.Equal(t, CheckoutStatusSuccessful, checkout.Status)
+}
+
+func TestFakeProviderRefundsUpToTheAmountOfTheTransaction(t *testing.T) {
+	provider, now := newTestFakeProvider()
+
+	clientTransactionID, err := provider.CreateCheckout(FakeTerminalApprove, decimal.NewFromInt(10), "", "")
+	require.NoError(t, err)
+
+	*now = now.Add(5 * time.Second)
+
+	checkout, err := provider.GetCheckout(*clientTransactionID)
+	require.NoError(t, err)
+
+	require.NoError(t, provider.RefundTransaction(checkout.TransactionID, decimal.NewFromInt(6)))
+	require.ErrorIs(
+		t,
+		provider.RefundTransaction(checkout.TransactionID, decimal.NewFromInt(5)),
+		ErrInvalidRefundAmount,
+	)
+	require.NoError(t, provider.RefundTransaction(checkout.TransactionID, decimal.NewFromInt(4)))
+	require.ErrorIs(t, provider.RefundTransaction(uuid.New(), decimal.NewFromInt(1)), ErrTransactionNotFound)
+}
+
+func TestFakeProviderRejectsUnknownTerminalsAndCheckouts(t *testing.T) {
+	provider, _ := newTestFakeProvider()
+
+	_, err := provider.CreateCheckout("reader_1", decimal.NewFromInt(10), "", "")
+	require.ErrorIs(t, err, ErrTerminalNotFound)
+
+	_, err = provider.GetCheckout(uuid.New())
+	require.ErrorIs(t, err, ErrCheckoutNotFound)
+}
@@ -0,0 +1,60 @@
+package terminal
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	ProviderSumup = "sumup"
+	ProviderFake  = "fake"
+)
+
+var (
+	ErrTerminalNotFound = errors.New("payment terminal not found")
+	ErrCheckoutNotFound = errors.New("checkout not found")
+)
+
+// CheckoutStatus is the state of a checkout on a payment terminal. Providers map their own
+// states to these.
+type CheckoutStatus string
+
+const (
+	CheckoutStatusPending    CheckoutStatus = "PENDING"
+	CheckoutStatusSuccessful CheckoutStatus = "SUCCESSFUL"
+	CheckoutStatusFailed     CheckoutStatus = "FAILED"
+	CheckoutStatusCancelled  CheckoutStatus = "CANCELLED"
+)
+
+type Terminal struct {
+	ID     string
+	Name   string
+	Status string
+	Model  string
+}
+
+// Checkout is a payment started on a terminal. It is identified by the client transaction ID
+// returned when creating it, the transaction ID is known once the customer presented a card.
+type Checkout struct {
+	ClientTransactionID uuid.UUID
+	TransactionID       uuid.UUID
+	TransactionCode     string
+	Amount              decimal.Decimal
+	Status              CheckoutStatus
+}
+
+// PaymentTerminalProvider takes card payments on payment terminals. Every terminal vendor
+// implements it with an adapter.
+type PaymentTerminalProvider interface {
+	GetTerminals() ([]Terminal, error)
+	// CreateCheckout starts a checkout of the amount on the terminal and returns its client
+	// transaction ID. The reference is the ID of the purchase.
+	CreateCheckout(terminalID string, amount decimal.Decimal, description, reference string) (*uuid.UUID, error)
+	// GetCheckout returns the checkout or ErrCheckoutNotFound if the provider does not know it.
+	GetCheckout(clientTransactionID uuid.UUID) (*Checkout, error)
+	// CancelCheckout cancels the checkout that is currently running on the terminal.
+	CancelCheckout(terminalID string) error
+	RefundTransaction(transactionID uuid.UUID, amount decimal.Decimal) error
+}
@@ -0,0 +1,94 @@
+package terminal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	sumupRepo "github.com/potibm/kasseapparat/internal/app/repository/sumup"
+	"github.com/shopspring/decimal"
+)
+
+type sumupProvider struct {
+	repository sumupRepo.RepositoryInterface
+}
+
+var _ PaymentTerminalProvider = (*sumupProvider)(nil)
+
+// NewSumupProvider takes the card payments on SumUp readers.
+func NewSumupProvider(repository sumupRepo.RepositoryInterface) PaymentTerminalProvider {
+	return &sumupProvider{repository: repository}
+}
+
+func (p *sumupProvider) GetTerminals() ([]Terminal, error) {
+	readers, err := p.repository.GetReaders()
+	if err != nil {
+		return nil, err
+	}
+
+	terminals := make([]Terminal, 0, len(readers))
+	for _, reader := range readers {
+		terminals = append(terminals, Terminal{
+			ID:     reader.ID,
+			Name:   reader.Name,
+			Status: reader.Status,
+			Model:  reader.DeviceModel,
+		})
+	}
+
+	return terminals, nil
+}
+
+func (p *sumupProvider) CreateCheckout(
+	terminalID string,
+	amount decimal.Decimal,
+	description, reference string,
+) (*uuid.UUID, error) {
+	return p.repository.CreateReaderCheckout(terminalID, amount, description, reference, p.repository.GetWebhookURL())
+}
+
+func (p *sumupProvider) GetCheckout(clientTransactionID uuid.UUID) (*Checkout, error) {
+	transaction, err := p.repository.GetTransactionByClientTransactionID(clientTransactionID)
+	if err != nil {
+		if strings.Contains(err.Error(), "NOT_FOUND") {
+			return nil, fmt.Errorf("%w: %w", ErrCheckoutNotFound, err)
+		}
+
+		return nil, err
+	}
+
+	if transaction == nil {
+		return nil, ErrCheckoutNotFound
+	}
+
+	return &Checkout{
+		ClientTransactionID: clientTransactionID,
+		TransactionID:       transaction.TransactionID,
+		TransactionCode:     transaction.TransactionCode,
+		Amount:              transaction.Amount,
+		Status:              sumupCheckoutStatus(transaction.Status),
+	}, nil
+}
+
+func (p *sumupProvider) CancelCheckout(terminalID string) error {
+	return p.repository.CreateReaderTerminateAction(terminalID)
+}
+
+func (p *sumupProvider) RefundTransaction(transactionID uuid.UUID, amount decimal.Decimal) error {
+	return p.repository.RefundTransaction(transactionID, amount)
+}
+
+func sumupCheckoutStatus(status string) CheckoutStatus {
+	switch strings.ToUpper(status) {
+	case "SUCCESSFUL":
+		return CheckoutStatusSuccessful
+	case "FAILED":
+		return CheckoutStatusFailed
+	case "CANCELLED", "CANCELED":
+		return CheckoutStatusCancelled
+	case "PENDING":
+		return CheckoutStatusPending
+	default:
+		return CheckoutStatus(status)
+	}
+}
@@ -0,0 +1,53 @@
+package terminal
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	sumupRepo "github.com/potibm/kasseapparat/internal/app/repository/sumup"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockSumupRepository struct {
+	sumupRepo.RepositoryInterface
+
+	transaction *sumupRepo.Transaction
+	err         error
+}
+
+func (m mockSumupRepository) GetTransactionByClientTransactionID(uuid.UUID) (*sumupRepo.Transaction, error) {
+	return m.transaction, m.err
+}
+
+func TestSumupProviderMapsTheTransactionStatus(t *testing.T) {
+	tests := map[string]CheckoutStatus{
+		"PENDING":    CheckoutStatusPending,
+		"SUCCESSFUL": CheckoutStatusSuccessful,
+		"FAILED":     CheckoutStatusFailed,
+		"CANCELLED":  CheckoutStatusCancelled,
+	}
+
+	for status, expected := range tests {
+		transactionID := uuid.New()
+		provider := NewSumupProvider(mockSumupRepository{transaction: &sumupRepo.Transaction{
+			TransactionID: transactionID,
+			Amount:        decimal.NewFromInt(10),
+			Status:        status,
+		}})
+
+		checkout, err := provider.GetCheckout(uuid.New())
+		require.NoError(t, err)
+		assert.Equal(t, expected, checkout.Status, status)
+		assert.Equal(t, transactionID, checkout.TransactionID)
+	}
+}
+
+func TestSumupProviderReportsUnknownCheckouts(t *testing.T) {
+	provider := NewSumupProvider(mockSumupRepository{err: errors.New("SumUp error NOT_FOUND: not found")})
+
+	_, err := provider.GetCheckout(uuid.New())
+	require.ErrorIs(t, err, ErrCheckoutNotFound)
+}
@@ -0,0 +1,60 @@
+package response
+
+import (
+	"time"
+
+	"github.com/potibm/kasseapparat/internal/app/models"
+)
+
+// StockCount compares the stock counted for a product or variant to the stock on hand expected
+// from the inventory ledger at the time of the count.
+type StockCount struct {
+	ID               int       `json:"id"`
+	CreatedAt        time.Time `json:"createdAt"`
+	ProductID        int       `json:"productId"`
+	ProductName      string    `json:"productName"`
+	VariantID        *int      `json:"variantId"`
+	VariantName      string    `json:"variantName"`
+	ExpectedQuantity int       `json:"expectedQuantity"`
+	CountedQuantity  int       `json:"countedQuantity"`
+	Difference       int       `json:"difference"`
+	CountedBy        string    `json:"countedBy"`
+	Note             string    `json:"note"`
+}
+
+func ToStockCounts(movements []models.InventoryMovement) []StockCount {
+	counts := make([]StockCount, 0, len(movements))
+
+	for _, movement := range movements {
+		if movement.CountedQuantity == nil || movement.ExpectedQuantity == nil {
+			continue
+		}
+
+		count := StockCount{
+			ID:               movement.ID,
+			CreatedAt:        movement.CreatedAt,
+			ProductID:        movement.ProductID,
+			VariantID:        movement.VariantID,
+			ExpectedQuantity: *movement.ExpectedQuantity,
+			CountedQuantity:  *movement.CountedQuantity,
+			Difference:       *movement.CountedQuantity - *movement.ExpectedQuantity,
+			Note:             movement.Note,
+		}
+
+		if movement.Product != nil {
+			count.ProductName = movement.Product.Name
+		}
+
+		if movement.Variant != nil {
+			count.VariantName = movement.Variant.Name
+		}
+
+		if movement.CreatedBy != nil {
+			count.CountedBy = movement.CreatedBy.Username
+		}
+
+		counts = append(counts, count)
+	}
+
+	return counts
+}
@@ -0,0 +1,15 @@
+package response
+
+import (
+	"github.com/google/uuid"
+)
+
+// OfflinePurchaseResult is the outcome of syncing a purchase recorded offline: accepted, duplicate
+// if it has been synced before, or conflict if it cannot be stored.
+type OfflinePurchaseResult struct {
+	ID              uuid.UUID         `json:"id"`
+	Status          string            `json:"status"`
+	Error           string            `json:"error,omitempty"`
+	SkippedGuestIDs []int             `json:"skippedGuestIds,omitempty"`
+	Purchase        *PurchaseResponse `json:"purchase,omitempty"`
+}
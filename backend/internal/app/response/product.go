@@ -1,33 +1,69 @@
 package response
 
 import (
+	"time"
+
 	"github.com/potibm/kasseapparat/internal/app/models"
 	"github.com/shopspring/decimal"
 )
 
 type ProductResponse struct {
-	ID                  int                `json:"id"`
-	Name                string             `json:"name"`
-	NetPrice            decimal.Decimal    `json:"netPrice"`
-	GrossPrice          decimal.Decimal    `json:"grossPrice"`
-	VATRate             decimal.Decimal    `json:"vatRate"`
-	VATAmount           decimal.Decimal    `json:"vatAmount"`
-	WrapAfter           bool               `json:"wrapAfter"`
-	Hidden              bool               `json:"hidden"`
-	SoldOut             bool               `json:"soldOut"`
-	APIExport           bool               `json:"apiExport"`
-	Pos                 int                `json:"pos"`
-	TotalStock          int                `json:"totalStock"`
-	UnitsSold           int                `json:"unitsSold"`
-	SoldOutRequestCount int                `json:"soldOutRequestCount"`
-	Guestlists          []models.Guestlist `json:"guestlists"`
+	ID                  int                        `json:"id"`
+	Name                string                     `json:"name"`
+	NetPrice            decimal.Decimal            `json:"netPrice"`
+	GrossPrice          decimal.Decimal            `json:"grossPrice"`
+	VATRate             decimal.Decimal            `json:"vatRate"`
+	VATAmount           decimal.Decimal            `json:"vatAmount"`
+	WrapAfter           bool                       `json:"wrapAfter"`
+	Hidden              bool                       `json:"hidden"`
+	SoldOut             bool                       `json:"soldOut"`
+	APIExport           bool                       `json:"apiExport"`
+	Pos                 int                        `json:"pos"`
+	TotalStock          int                        `json:"totalStock"`
+	TrackStock          bool                       `json:"trackStock"`
+	UnitsSold           int                        `json:"unitsSold"`
+	SoldOutRequestCount int                        `json:"soldOutRequestCount"`
+	Guestlists          []models.Guestlist         `json:"guestlists"`
+	BundleItems         []models.ProductBundleItem `json:"bundleItems"`
+	PriceRules          []models.ProductPriceRule  `json:"priceRules"`
+	CategoryID          *int                       `json:"categoryId"`
+	Category            *models.Category           `json:"category"`
 }
 
+// ExtendedProductResponse is the product at its current price. ListNetPrice is the price without
+// price rules, NextPrice the price after the next scheduled change, if any. StockOnHand is taken
+// from the inventory ledger.
 type ExtendedProductResponse struct {
 	ProductResponse
 
-	UnitsSold           int `json:"unitsSold"`
-	SoldOutRequestCount int `json:"soldOutRequestCount"`
+	UnitsSold           int                      `json:"unitsSold"`
+	SoldOutRequestCount int                      `json:"soldOutRequestCount"`
+	Variants            []ProductVariantResponse `json:"variants"`
+	ListNetPrice        decimal.Decimal          `json:"listNetPrice"`
+	NextPrice           *ProductNextPrice        `json:"nextPrice"`
+	StockOnHand         int                      `json:"stockOnHand"`
+}
+
+type ProductNextPrice struct {
+	NetPrice   decimal.Decimal `json:"netPrice"`
+	GrossPrice decimal.Decimal `json:"grossPrice"`
+	ValidFrom  time.Time       `json:"validFrom"`
+}
+
+type ProductVariantResponse struct {
+	ID                  int             `json:"id"`
+	Name                string          `json:"name"`
+	NetPrice            decimal.Decimal `json:"netPrice"`
+	GrossPrice          decimal.Decimal `json:"grossPrice"`
+	VATAmount           decimal.Decimal `json:"vatAmount"`
+	PriceOverride       bool            `json:"priceOverride"`
+	Pos                 int             `json:"pos"`
+	SoldOut             bool            `json:"soldOut"`
+	TotalStock          int             `json:"totalStock"`
+	TrackStock          bool            `json:"trackStock"`
+	UnitsSold           int             `json:"unitsSold"`
+	SoldOutRequestCount int             `json:"soldOutRequestCount"`
+	StockOnHand         int             `json:"stockOnHand"`
 }
 
 func ToProductResponse(product models.Product, decimalPlaces int32) ProductResponse {
@@ -40,33 +76,81 @@ func ToProductResponse(product models.Product, decimalPlaces int32) ProductRespo
 		VATAmount:           product.VATAmount(decimalPlaces),
 		WrapAfter:           product.WrapAfter,
 		Hidden:              product.Hidden,
-		SoldOut:             product.SoldOut,
+		SoldOut:             product.VariantsSoldOut(),
 		APIExport:           product.APIExport,
 		Pos:                 product.Pos,
-		TotalStock:          product.TotalStock,
+		TotalStock:          product.VariantTotalStock(),
+		TrackStock:          product.TrackStock,
 		UnitsSold:           product.UnitsSold,
 		SoldOutRequestCount: product.SoldOutRequestCount,
 		Guestlists:          product.Guestlists,
+		BundleItems:         product.BundleItems,
+		PriceRules:          product.PriceRules,
+		CategoryID:          product.CategoryID,
+		Category:            product.Category,
 	}
 
 	return response
 }
 
+// ToExtendedProductResponse returns the product at its price at the given time with its sales
+// figures. The stock, the sold-out flag and the figures of a product with variants are the roll-up
+// of its variants.
 func ToExtendedProductResponse(
 	product models.Product,
+	at time.Time,
 	unitsSold int,
 	soldOutRequestCount int,
+	variants []ProductVariantResponse,
 	decimalPlaces int32,
 ) ExtendedProductResponse {
 	response := ExtendedProductResponse{
-		ProductResponse:     ToProductResponse(product, decimalPlaces),
+		ProductResponse:     ToProductResponse(product.PriceAt(at), decimalPlaces),
 		UnitsSold:           unitsSold,
 		SoldOutRequestCount: soldOutRequestCount,
+		Variants:            variants,
+		ListNetPrice:        product.NetPrice,
+	}
+
+	if validFrom, netPrice, ok := product.NextPriceChange(at); ok {
+		next := product
+		next.NetPrice = netPrice
+
+		response.NextPrice = &ProductNextPrice{
+			NetPrice:   netPrice,
+			GrossPrice: next.GrossPrice(decimalPlaces),
+			ValidFrom:  validFrom,
+		}
 	}
 
 	return response
 }
 
+func ToProductVariantResponse(
+	product models.Product,
+	variant models.ProductVariant,
+	unitsSold int,
+	soldOutRequestCount int,
+	decimalPlaces int32,
+) ProductVariantResponse {
+	variantProduct := product.WithVariant(variant)
+
+	return ProductVariantResponse{
+		ID:                  variant.ID,
+		Name:                variant.Name,
+		NetPrice:            variantProduct.NetPrice,
+		GrossPrice:          variantProduct.GrossPrice(decimalPlaces),
+		VATAmount:           variantProduct.VATAmount(decimalPlaces),
+		PriceOverride:       variant.NetPrice != nil,
+		Pos:                 variant.Pos,
+		SoldOut:             variant.SoldOut,
+		TotalStock:          variant.TotalStock,
+		TrackStock:          variant.TrackStock,
+		UnitsSold:           unitsSold,
+		SoldOutRequestCount: soldOutRequestCount,
+	}
+}
+
 func ToProductResponses(products []models.Product, decimalPlaces int32) []ProductResponse {
 	productResponses := make([]ProductResponse, len(products))
 	for i, product := range products {
@@ -2,10 +2,71 @@ package response
 
 import "github.com/shopspring/decimal"
 
+// ProductStats are the sales figures of a product. The figures of a product with variants are the
+// roll-up of its variants.
 type ProductStats struct {
+	ID              int                   `json:"id"`
+	Name            string                `json:"name"`
+	CategoryID      *int                  `json:"categoryId"`
+	CategoryName    *string               `json:"categoryName"`
+	SoldItems       uint                  `json:"soldItems"`
+	TotalNetPrice   decimal.Decimal       `json:"totalNetPrice"`
+	TotalGrossPrice decimal.Decimal       `json:"totalGrossPrice"`
+	Variants        []ProductVariantStats `json:"variants"        gorm:"-"`
+}
+
+type ProductVariantStats struct {
 	ID              int             `json:"id"`
 	Name            string          `json:"name"`
 	SoldItems       uint            `json:"soldItems"`
 	TotalNetPrice   decimal.Decimal `json:"totalNetPrice"`
 	TotalGrossPrice decimal.Decimal `json:"totalGrossPrice"`
 }
+
+// CategoryStats are the sales figures of a category. Products without a category are summed up
+// in the stats without an ID.
+type CategoryStats struct {
+	ID              *int            `json:"id"`
+	Name            string          `json:"name"`
+	SoldItems       uint            `json:"soldItems"`
+	TotalNetPrice   decimal.Decimal `json:"totalNetPrice"`
+	TotalGrossPrice decimal.Decimal `json:"totalGrossPrice"`
+}
+
+// ToCategoryStats sums up the sales figures of the products by their category, in the order of
+// the products.
+func ToCategoryStats(products []ProductStats) []CategoryStats {
+	stats := []CategoryStats{}
+	index := map[int]int{}
+
+	const uncategorized = 0
+
+	for _, product := range products {
+		key := uncategorized
+		if product.CategoryID != nil {
+			key = *product.CategoryID
+		}
+
+		i, exists := index[key]
+		if !exists {
+			i = len(stats)
+			index[key] = i
+
+			stats = append(stats, CategoryStats{
+				ID:              product.CategoryID,
+				TotalNetPrice:   decimal.Zero,
+				TotalGrossPrice: decimal.Zero,
+			})
+
+			if product.CategoryName != nil {
+				stats[i].Name = *product.CategoryName
+			}
+		}
+
+		stats[i].SoldItems += product.SoldItems
+		stats[i].TotalNetPrice = stats[i].TotalNetPrice.Add(product.TotalNetPrice)
+		stats[i].TotalGrossPrice = stats[i].TotalGrossPrice.Add(product.TotalGrossPrice)
+	}
+
+	return stats
+}
@@ -9,18 +9,30 @@ import (
 )
 
 type PurchaseResponse struct {
-	ID                       uuid.UUID              `json:"id"`
-	CreatedAt                time.Time              `json:"createdAt"`
-	CreatedByID              *int                   `json:"createdById"`
-	CreatedBy                *models.User           `json:"createdBy"`
-	PaymentMethod            models.PaymentMethod   `json:"paymentMethod"`
-	TotalNetPrice            decimal.Decimal        `json:"totalNetPrice"`
-	SumupTransactionID       uuid.UUID              `json:"sumupTransactionId,omitempty"`
-	SumupClientTransactionID uuid.UUID              `json:"sumupClientTransactionId,omitempty"`
-	TotalGrossPrice          decimal.Decimal        `json:"totalGrossPrice"`
-	TotalVatAmount           decimal.Decimal        `json:"totalVatAmount"`
-	PurchaseItems            []PurchaseItemResponse `json:"purchaseItems"`
-	Status                   string                 `json:"status"`
+	ID                       uuid.UUID                          `json:"id"`
+	CreatedAt                time.Time                          `json:"createdAt"`
+	CreatedByID              *int                               `json:"createdById"`
+	CreatedBy                *models.User                       `json:"createdBy"`
+	PaymentMethod            models.PaymentMethod               `json:"paymentMethod"`
+	TotalNetPrice            decimal.Decimal                    `json:"totalNetPrice"`
+	SumupTransactionID       uuid.UUID                          `json:"sumupTransactionId,omitempty"`
+	SumupClientTransactionID uuid.UUID                          `json:"sumupClientTransactionId,omitempty"`
+	SumupReaderID            *string                            `json:"sumupReaderId"`
+	TotalGrossPrice          decimal.Decimal                    `json:"totalGrossPrice"`
+	TotalVatAmount           decimal.Decimal                    `json:"totalVatAmount"`
+	PurchaseItems            []PurchaseItemResponse             `json:"purchaseItems"`
+	Discounts                []PurchaseDiscountResponse         `json:"discounts"`
+	Payments                 []PurchasePaymentResponse          `json:"payments"`
+	Roundings                []PurchaseRoundingResponse         `json:"roundings"`
+	Refunds                  []PurchaseRefundResponse           `json:"refunds"`
+	ReceiptMails             []PurchaseReceiptMailResponse      `json:"receiptMails"`
+	StatusTransitions        []PurchaseStatusTransitionResponse `json:"statusTransitions"`
+	Status                   string                             `json:"status"`
+	RegisterSessionID        *int                               `json:"registerSessionId"`
+	ReceiptNumber            *string                            `json:"receiptNumber"`
+	Offline                  bool                               `json:"offline"`
+	RecordedAt               *time.Time                         `json:"recordedAt"`
+	ExpiredAt                *time.Time                         `json:"expiredAt"`
 }
 
 func ToPurchaseResponse(purchase models.Purchase, decimalPlaces int32) PurchaseResponse {
@@ -34,9 +46,21 @@ func ToPurchaseResponse(purchase models.Purchase, decimalPlaces int32) PurchaseR
 		TotalGrossPrice:          purchase.TotalGrossPrice,
 		TotalVatAmount:           purchase.TotalGrossPrice.Sub(purchase.TotalNetPrice),
 		PurchaseItems:            ToPurchaseItemsResponse(purchase.PurchaseItems, decimalPlaces),
+		Discounts:                ToPurchaseDiscountsResponse(purchase.DiscountLines()),
+		Payments:                 ToPurchasePaymentsResponse(purchase.PaymentLines()),
+		Roundings:                ToPurchaseRoundingsResponse(purchase.RoundingLines()),
+		Refunds:                  ToPurchaseRefundsResponse(purchase.Refunds),
+		ReceiptMails:             ToPurchaseReceiptMailsResponse(purchase.ReceiptMails),
+		StatusTransitions:        ToPurchaseStatusTransitionsResponse(purchase.StatusTransitions),
 		Status:                   string(purchase.Status),
+		RegisterSessionID:        purchase.RegisterSessionID,
+		ReceiptNumber:            purchase.ReceiptNumber,
+		Offline:                  purchase.Offline,
+		RecordedAt:               purchase.RecordedAt,
+		ExpiredAt:                purchase.ExpiredAt,
 		SumupTransactionID:       uuid.Nil,
 		SumupClientTransactionID: uuid.Nil,
+		SumupReaderID:            purchase.SumupReaderID,
 	}
 
 	if purchase.SumupTransactionID != nil {
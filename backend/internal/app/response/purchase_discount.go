@@ -0,0 +1,42 @@
+package response
+
+import (
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/shopspring/decimal"
+)
+
+type PurchaseDiscountResponse struct {
+	ID             int             `json:"id"`
+	PurchaseItemID int             `json:"purchaseItemId"`
+	DiscountID     *int            `json:"discountId"`
+	Name           string          `json:"name"`
+	Code           *string         `json:"code"`
+	VATRate        decimal.Decimal `json:"vatRate"`
+	NetAmount      decimal.Decimal `json:"netAmount"`
+	GrossAmount    decimal.Decimal `json:"grossAmount"`
+	VATAmount      decimal.Decimal `json:"vatAmount"`
+}
+
+func ToPurchaseDiscountResponse(discount models.PurchaseDiscount) PurchaseDiscountResponse {
+	return PurchaseDiscountResponse{
+		ID:             discount.ID,
+		PurchaseItemID: discount.PurchaseItemID,
+		DiscountID:     discount.DiscountID,
+		Name:           discount.Name,
+		Code:           discount.Code,
+		VATRate:        discount.VATRate,
+		NetAmount:      discount.NetAmount,
+		GrossAmount:    discount.GrossAmount,
+		VATAmount:      discount.GrossAmount.Sub(discount.NetAmount),
+	}
+}
+
+func ToPurchaseDiscountsResponse(discounts []models.PurchaseDiscount) []PurchaseDiscountResponse {
+	responses := make([]PurchaseDiscountResponse, 0, len(discounts))
+
+	for _, discount := range discounts {
+		responses = append(responses, ToPurchaseDiscountResponse(discount))
+	}
+
+	return responses
+}
@@ -7,34 +7,42 @@ import (
 )
 
 type PurchaseItemResponse struct {
-	ID              int             `json:"id"`
-	PurchaseID      uuid.UUID       `json:"purchaseID"` // Foreign key to Purchase
-	ProductID       int             `json:"productID"`  // Foreign key to Product
-	Product         ProductResponse `json:"product"`
-	Quantity        uint            `json:"quantity"`
-	NetPrice        decimal.Decimal `json:"netPrice"`
-	GrossPrice      decimal.Decimal `json:"grossPrice"`
-	VATRate         decimal.Decimal `json:"vatRate"`
-	VATAmount       decimal.Decimal `json:"vatAmount"`
-	TotalNetPrice   decimal.Decimal `json:"totalNetPrice"`
-	TotalGrossPrice decimal.Decimal `json:"totalGrossPrice"`
-	TotalVATAmount  decimal.Decimal `json:"totalVatAmount"`
+	ID               int             `json:"id"`
+	PurchaseID       uuid.UUID       `json:"purchaseID"` // Foreign key to Purchase
+	ProductID        int             `json:"productID"`  // Foreign key to Product
+	Product          ProductResponse `json:"product"`
+	BundleID         *int            `json:"bundleID"`
+	VariantID        *int            `json:"variantID"`
+	Name             string          `json:"name"`
+	Quantity         uint            `json:"quantity"`
+	NetPrice         decimal.Decimal `json:"netPrice"`
+	GrossPrice       decimal.Decimal `json:"grossPrice"`
+	VATRate          decimal.Decimal `json:"vatRate"`
+	VATAmount        decimal.Decimal `json:"vatAmount"`
+	TotalNetPrice    decimal.Decimal `json:"totalNetPrice"`
+	TotalGrossPrice  decimal.Decimal `json:"totalGrossPrice"`
+	TotalVATAmount   decimal.Decimal `json:"totalVatAmount"`
+	RefundedQuantity uint            `json:"refundedQuantity"`
 }
 
 func ToPurchaseItemResponse(purchaseItem models.PurchaseItem, decimalPlaces int32) PurchaseItemResponse {
 	response := PurchaseItemResponse{
-		ID:              purchaseItem.ID,
-		PurchaseID:      purchaseItem.PurchaseID,
-		ProductID:       purchaseItem.ProductID,
-		Product:         ToProductResponse(purchaseItem.Product, decimalPlaces),
-		Quantity:        purchaseItem.Quantity,
-		NetPrice:        purchaseItem.NetPrice,
-		GrossPrice:      purchaseItem.GrossPrice(decimalPlaces),
-		TotalNetPrice:   purchaseItem.TotalNetPrice(decimalPlaces),
-		TotalGrossPrice: purchaseItem.TotalGrossPrice(decimalPlaces),
-		TotalVATAmount:  purchaseItem.TotalVATAmount(decimalPlaces),
-		VATRate:         purchaseItem.VATRate,
-		VATAmount:       purchaseItem.VATAmount(decimalPlaces),
+		ID:               purchaseItem.ID,
+		PurchaseID:       purchaseItem.PurchaseID,
+		ProductID:        purchaseItem.ProductID,
+		Product:          ToProductResponse(purchaseItem.Product, decimalPlaces),
+		BundleID:         purchaseItem.BundleID,
+		VariantID:        purchaseItem.VariantID,
+		Name:             purchaseItem.Name(),
+		Quantity:         purchaseItem.Quantity,
+		NetPrice:         purchaseItem.NetPrice,
+		GrossPrice:       purchaseItem.GrossPrice(decimalPlaces),
+		TotalNetPrice:    purchaseItem.TotalNetPrice(decimalPlaces),
+		TotalGrossPrice:  purchaseItem.TotalGrossPrice(decimalPlaces),
+		TotalVATAmount:   purchaseItem.TotalVATAmount(decimalPlaces),
+		VATRate:          purchaseItem.VATRate,
+		VATAmount:        purchaseItem.VATAmount(decimalPlaces),
+		RefundedQuantity: purchaseItem.RefundedQuantity(),
 	}
 
 	return response
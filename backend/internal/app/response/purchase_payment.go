@@ -0,0 +1,41 @@
+package response
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/shopspring/decimal"
+)
+
+type PurchasePaymentResponse struct {
+	ID                       int                  `json:"id"`
+	PaymentMethod            models.PaymentMethod `json:"paymentMethod"`
+	Amount                   decimal.Decimal      `json:"amount"`
+	SumupTransactionID       *uuid.UUID           `json:"sumupTransactionId,omitempty"`
+	SumupClientTransactionID *uuid.UUID           `json:"sumupClientTransactionId,omitempty"`
+	SettledAt                *time.Time           `json:"settledAt"`
+	VoucherID                *int                 `json:"voucherId,omitempty"`
+}
+
+func ToPurchasePaymentResponse(payment models.PurchasePayment) PurchasePaymentResponse {
+	return PurchasePaymentResponse{
+		ID:                       payment.ID,
+		PaymentMethod:            payment.PaymentMethod,
+		Amount:                   payment.Amount,
+		SumupTransactionID:       payment.SumupTransactionID,
+		SumupClientTransactionID: payment.SumupClientTransactionID,
+		SettledAt:                payment.SettledAt,
+		VoucherID:                payment.VoucherID,
+	}
+}
+
+func ToPurchasePaymentsResponse(payments []models.PurchasePayment) []PurchasePaymentResponse {
+	responses := make([]PurchasePaymentResponse, 0, len(payments))
+
+	for _, payment := range payments {
+		responses = append(responses, ToPurchasePaymentResponse(payment))
+	}
+
+	return responses
+}
@@ -0,0 +1,33 @@
+package response
+
+import (
+	"time"
+
+	"github.com/potibm/kasseapparat/internal/app/models"
+)
+
+type PurchaseReceiptMailResponse struct {
+	ID          int       `json:"id"`
+	CreatedByID *int      `json:"createdById"`
+	Email       *string   `json:"email"`
+	SentAt      time.Time `json:"sentAt"`
+}
+
+func ToPurchaseReceiptMailResponse(mail models.PurchaseReceiptMail) PurchaseReceiptMailResponse {
+	return PurchaseReceiptMailResponse{
+		ID:          mail.ID,
+		CreatedByID: mail.CreatedByID,
+		Email:       mail.Email,
+		SentAt:      mail.SentAt,
+	}
+}
+
+func ToPurchaseReceiptMailsResponse(mails []models.PurchaseReceiptMail) []PurchaseReceiptMailResponse {
+	responses := make([]PurchaseReceiptMailResponse, 0, len(mails))
+
+	for _, mail := range mails {
+		responses = append(responses, ToPurchaseReceiptMailResponse(mail))
+	}
+
+	return responses
+}
@@ -0,0 +1,70 @@
+package response
+
+import (
+	"time"
+
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/shopspring/decimal"
+)
+
+type PurchaseRefundResponse struct {
+	ID              int                             `json:"id"`
+	CreatedAt       time.Time                       `json:"createdAt"`
+	CreatedByID     *int                            `json:"createdById"`
+	TotalNetPrice   decimal.Decimal                 `json:"totalNetPrice"`
+	TotalGrossPrice decimal.Decimal                 `json:"totalGrossPrice"`
+	SettledAt       *time.Time                      `json:"settledAt"`
+	Items           []PurchaseRefundItemResponse    `json:"items"`
+	Payments        []PurchaseRefundPaymentResponse `json:"payments"`
+	Roundings       []PurchaseRoundingResponse      `json:"roundings"`
+}
+
+type PurchaseRefundItemResponse struct {
+	PurchaseItemID int  `json:"purchaseItemId"`
+	Quantity       uint `json:"quantity"`
+}
+
+type PurchaseRefundPaymentResponse struct {
+	PaymentMethod models.PaymentMethod `json:"paymentMethod"`
+	Amount        decimal.Decimal      `json:"amount"`
+}
+
+func ToPurchaseRefundResponse(refund models.PurchaseRefund) PurchaseRefundResponse {
+	response := PurchaseRefundResponse{
+		ID:              refund.ID,
+		CreatedAt:       refund.CreatedAt,
+		CreatedByID:     refund.CreatedByID,
+		TotalNetPrice:   refund.TotalNetPrice,
+		TotalGrossPrice: refund.TotalGrossPrice,
+		SettledAt:       refund.SettledAt,
+		Items:           make([]PurchaseRefundItemResponse, 0, len(refund.Items)),
+		Payments:        make([]PurchaseRefundPaymentResponse, 0, len(refund.Payments)),
+		Roundings:       ToPurchaseRoundingsResponse(refund.Roundings),
+	}
+
+	for _, item := range refund.Items {
+		response.Items = append(response.Items, PurchaseRefundItemResponse{
+			PurchaseItemID: item.PurchaseItemID,
+			Quantity:       item.Quantity,
+		})
+	}
+
+	for _, payment := range refund.Payments {
+		response.Payments = append(response.Payments, PurchaseRefundPaymentResponse{
+			PaymentMethod: payment.PaymentMethod,
+			Amount:        payment.Amount,
+		})
+	}
+
+	return response
+}
+
+func ToPurchaseRefundsResponse(refunds []models.PurchaseRefund) []PurchaseRefundResponse {
+	responses := make([]PurchaseRefundResponse, 0, len(refunds))
+
+	for _, refund := range refunds {
+		responses = append(responses, ToPurchaseRefundResponse(refund))
+	}
+
+	return responses
+}
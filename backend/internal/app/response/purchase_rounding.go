@@ -0,0 +1,24 @@
+package response
+
+import (
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/shopspring/decimal"
+)
+
+type PurchaseRoundingResponse struct {
+	PaymentMethod models.PaymentMethod `json:"paymentMethod"`
+	Amount        decimal.Decimal      `json:"amount"`
+}
+
+func ToPurchaseRoundingsResponse(roundings []models.PurchaseRounding) []PurchaseRoundingResponse {
+	responses := make([]PurchaseRoundingResponse, 0, len(roundings))
+
+	for _, rounding := range roundings {
+		responses = append(responses, PurchaseRoundingResponse{
+			PaymentMethod: rounding.PaymentMethod,
+			Amount:        rounding.Amount,
+		})
+	}
+
+	return responses
+}
@@ -0,0 +1,44 @@
+package response
+
+import (
+	"time"
+
+	"github.com/potibm/kasseapparat/internal/app/models"
+)
+
+type PurchaseStatusTransitionResponse struct {
+	FromStatus models.PurchaseStatus           `json:"fromStatus"`
+	ToStatus   models.PurchaseStatus           `json:"toStatus"`
+	Source     models.PurchaseTransitionSource `json:"source"`
+	ActorID    *int                            `json:"actorId"`
+	Actor      string                          `json:"actor"`
+	CreatedAt  time.Time                       `json:"createdAt"`
+}
+
+func ToPurchaseStatusTransitionResponse(transition models.PurchaseStatusTransition) PurchaseStatusTransitionResponse {
+	response := PurchaseStatusTransitionResponse{
+		FromStatus: transition.FromStatus,
+		ToStatus:   transition.ToStatus,
+		Source:     transition.Source,
+		ActorID:    transition.ActorID,
+		CreatedAt:  transition.CreatedAt,
+	}
+
+	if transition.Actor != nil {
+		response.Actor = transition.Actor.Username
+	}
+
+	return response
+}
+
+func ToPurchaseStatusTransitionsResponse(
+	transitions []models.PurchaseStatusTransition,
+) []PurchaseStatusTransitionResponse {
+	responses := make([]PurchaseStatusTransitionResponse, 0, len(transitions))
+
+	for _, transition := range transitions {
+		responses = append(responses, ToPurchaseStatusTransitionResponse(transition))
+	}
+
+	return responses
+}
@@ -0,0 +1,53 @@
+package response
+
+import (
+	"time"
+
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/shopspring/decimal"
+)
+
+// QuoteResponse is the price of a cart as the server computes it. The token creates the purchase
+// at the quoted prices until the quote expires.
+type QuoteResponse struct {
+	Items           []PurchaseItemResponse       `json:"items"`
+	Discounts       []PurchaseDiscountResponse   `json:"discounts"`
+	VATRates        []QuoteVATRateResponse       `json:"vatRates"`
+	PaymentAmounts  []QuotePaymentAmountResponse `json:"paymentAmounts"`
+	TotalNetPrice   decimal.Decimal              `json:"totalNetPrice"`
+	TotalVatAmount  decimal.Decimal              `json:"totalVatAmount"`
+	TotalGrossPrice decimal.Decimal              `json:"totalGrossPrice"`
+	Stock           []QuoteStockResponse         `json:"stock"`
+	GuestErrors     []QuoteGuestErrorResponse    `json:"guestErrors"`
+	Token           string                       `json:"token"`
+	ExpiresAt       time.Time                    `json:"expiresAt"`
+}
+
+type QuoteVATRateResponse struct {
+	VATRate    decimal.Decimal `json:"vatRate"`
+	NetPrice   decimal.Decimal `json:"netPrice"`
+	VATAmount  decimal.Decimal `json:"vatAmount"`
+	GrossPrice decimal.Decimal `json:"grossPrice"`
+}
+
+// QuotePaymentAmountResponse is the amount to be paid with a payment method that rounds its amounts.
+type QuotePaymentAmountResponse struct {
+	PaymentMethod models.PaymentMethod `json:"paymentMethod"`
+	Amount        decimal.Decimal      `json:"amount"`
+	Rounding      decimal.Decimal      `json:"rounding"`
+}
+
+type QuoteStockResponse struct {
+	ProductID  int    `json:"productId"`
+	VariantID  *int   `json:"variantId"`
+	Name       string `json:"name"`
+	Quantity   int    `json:"quantity"`
+	Available  *int   `json:"available"`
+	Sufficient bool   `json:"sufficient"`
+}
+
+type QuoteGuestErrorResponse struct {
+	GuestID   int    `json:"guestId"`
+	ProductID int    `json:"productId"`
+	Error     string `json:"error"`
+}
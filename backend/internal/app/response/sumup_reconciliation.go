@@ -0,0 +1,34 @@
+package response
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// SumupReconciliationResponse lists the disagreements between the SumUp transactions and the
+// purchases of a period. It is consistent if every issue has been repaired.
+type SumupReconciliationResponse struct {
+	From             time.Time                          `json:"from"`
+	To               time.Time                          `json:"to"`
+	TransactionCount int                                `json:"transactionCount"`
+	PurchaseCount    int                                `json:"purchaseCount"`
+	MatchedCount     int                                `json:"matchedCount"`
+	Consistent       bool                               `json:"consistent"`
+	Issues           []SumupReconciliationIssueResponse `json:"issues"`
+}
+
+type SumupReconciliationIssueResponse struct {
+	Kind                string           `json:"kind"`
+	ClientTransactionID *uuid.UUID       `json:"clientTransactionId,omitempty"`
+	PurchaseID          *uuid.UUID       `json:"purchaseId,omitempty"`
+	PurchaseStatus      string           `json:"purchaseStatus,omitempty"`
+	PurchaseAmount      *decimal.Decimal `json:"purchaseAmount,omitempty"`
+	TransactionID       *uuid.UUID       `json:"transactionId,omitempty"`
+	TransactionCode     string           `json:"transactionCode,omitempty"`
+	TransactionStatus   string           `json:"transactionStatus,omitempty"`
+	TransactionAmount   *decimal.Decimal `json:"transactionAmount,omitempty"`
+	RepairedStatus      string           `json:"repairedStatus,omitempty"`
+	RepairError         string           `json:"repairError,omitempty"`
+}
@@ -0,0 +1,69 @@
+package journal
+
+import (
+	"github.com/potibm/kasseapparat/internal/app/models"
+)
+
+const verifyBatchSize = 500
+
+type EntryReader interface {
+	GetJournalEntries(afterSequence uint, limit int) ([]models.JournalEntry, error)
+}
+
+type JournalService struct {
+	repo EntryReader
+}
+
+// Break is an entry that does not follow the entry before it.
+type Break struct {
+	Sequence uint
+	Reason   string
+}
+
+type VerifyResult struct {
+	EntryCount int
+	LastHash   string
+	Breaks     []Break
+}
+
+func (r VerifyResult) Valid() bool {
+	return len(r.Breaks) == 0
+}
+
+func NewJournalService(repo EntryReader) *JournalService {
+	return &JournalService{repo: repo}
+}
+
+// Verify walks the journal from the first entry and checks every link of the chain. After a break
+// the walk continues with the broken entry as the new predecessor, so every break is reported.
+func (s *JournalService) Verify() (VerifyResult, error) {
+	var (
+		result   VerifyResult
+		previous *models.JournalEntry
+		after    uint
+	)
+
+	for {
+		entries, err := s.repo.GetJournalEntries(after, verifyBatchSize)
+		if err != nil {
+			return result, err
+		}
+
+		for i := range entries {
+			entry := entries[i]
+
+			if err := entry.VerifyChain(previous); err != nil {
+				result.Breaks = append(result.Breaks, Break{Sequence: entry.Sequence, Reason: err.Error()})
+			}
+
+			result.EntryCount++
+			result.LastHash = entry.Hash
+			previous = &entry
+			after = entry.Sequence
+		}
+
+		if len(entries) < verifyBatchSize {
+			return result, nil
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package journal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type MockEntryReader struct {
+	Entries []models.JournalEntry
+}
+
+func (m *MockEntryReader) GetJournalEntries(afterSequence uint, limit int) ([]models.JournalEntry, error) {
+	var entries []models.JournalEntry
+
+	for _, entry := range m.Entries {
+		if entry.Sequence > afterSequence && len(entries) < limit {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+func buildJournal(count int) []models.JournalEntry {
+	entries := make([]models.JournalEntry, 0, count)
+	purchaseID := uuid.New()
+
+	for i := range count {
+		entry := models.JournalEntry{
+			CreatedAt:  time.Date(2026, time.March, 1, 20, i, 0, 0, time.UTC),
+			EventType:  models.JournalEventPurchaseCreated,
+			PurchaseID: purchaseID,
+			Payload:    `{"status":"confirmed"}`,
+		}
+
+		var previous *models.JournalEntry
+		if i > 0 {
+			previous = &entries[i-1]
+		}
+
+		entry.Chain(previous)
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+func TestVerifyWithIntactJournal(t *testing.T) {
+	entries := buildJournal(3)
+	service := NewJournalService(&MockEntryReader{Entries: entries})
+
+	result, err := service.Verify()
+	require.NoError(t, err)
+
+	assert.True(t, result.Valid())
+	assert.Equal(t, 3, result.EntryCount)
+	assert.Equal(t, entries[2].Hash, result.LastHash)
+}
+
+func TestVerifyWithChangedPayload(t *testing.T) {
+	entries := buildJournal(3)
+	entries[1].Payload = `{"status":"cancelled"}`
+	service := NewJournalService(&MockEntryReader{Entries: entries})
+
+	result, err := service.Verify()
+	require.NoError(t, err)
+
+	assert.False(t, result.Valid())
+	assert.Len(t, result.Breaks, 1)
+	assert.Equal(t, uint(2), result.Breaks[0].Sequence)
+}
+
+func TestVerifyWithRemovedEntry(t *testing.T) {
+	entries := buildJournal(3)
+	service := NewJournalService(&MockEntryReader{Entries: []models.JournalEntry{entries[0], entries[2]}})
+
+	result, err := service.Verify()
+	require.NoError(t, err)
+
+	assert.False(t, result.Valid())
+	assert.Equal(t, uint(3), result.Breaks[0].Sequence)
+}
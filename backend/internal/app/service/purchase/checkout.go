@@ -0,0 +1,142 @@
+package purchase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/potibm/kasseapparat/internal/app/repository/terminal"
+)
+
+var (
+	ErrPurchaseNotPending      = errors.New("the purchase is not pending")
+	ErrPaymentAlreadySucceeded = errors.New("the card payment succeeded before it could be cancelled")
+	// ErrPurchaseAlreadyConfirmed is returned when the payment of a purchase is reported again after
+	// it has been confirmed, e.g. by the webhook and by the poller.
+	ErrPurchaseAlreadyConfirmed = fmt.Errorf("%w: it has already been confirmed", ErrPurchaseNotPending)
+)
+
+// CancelTerminalCheckout cancels the card payment of a pending purchase on its terminal and then
+// the purchase. The terminal is the one the checkout was started on, unless another one is given.
+//
+// The customer may complete the payment while the cancel is in flight, so the checkout is looked
+// up once more after terminating it: a successful payment confirms the purchase, which is returned
+// with ErrPaymentAlreadySucceeded, and a declined one fails it.
+func (s *PurchaseService) CancelTerminalCheckout(
+	ctx context.Context,
+	purchaseID uuid.UUID,
+	terminalID string,
+) (*models.Purchase, error) {
+	return s.abortTerminalCheckout(ctx, purchaseID, terminalID, s.CancelPurchase)
+}
+
+// ExpirePendingPurchase fails a pending card payment that did not reach a final state in time,
+// e.g. because its reader was switched off, and marks the purchase as expired for review. The
+// checkout on the reader is terminated and checked like on a cancel, so a payment that succeeded
+// at the last moment still confirms the purchase.
+func (s *PurchaseService) ExpirePendingPurchase(ctx context.Context, purchaseID uuid.UUID) (*models.Purchase, error) {
+	return s.abortTerminalCheckout(ctx, purchaseID, "", s.expirePurchase)
+}
+
+func (s *PurchaseService) expirePurchase(ctx context.Context, purchaseID uuid.UUID) (*models.Purchase, error) {
+	expiredAt := time.Now()
+
+	purchase, err := s.rollbackPurchase(ctx, purchaseID, models.PurchaseStatusFailed, &expiredAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire the purchase: %w", err)
+	}
+
+	return purchase, nil
+}
+
+// abortTerminalCheckout terminates the checkout of a pending purchase on its terminal and settles
+// the purchase by the final status of the checkout. A checkout that was not declined or paid is
+// aborted with the given function.
+func (s *PurchaseService) abortTerminalCheckout(
+	ctx context.Context,
+	purchaseID uuid.UUID,
+	terminalID string,
+	abort func(ctx context.Context, purchaseID uuid.UUID) (*models.Purchase, error),
+) (*models.Purchase, error) {
+	purchase, err := s.sqliteRepo.GetPurchaseByID(purchaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	if purchase.Status != models.PurchaseStatusPending {
+		return purchase, ErrPurchaseNotPending
+	}
+
+	if terminalID == "" && purchase.SumupReaderID != nil {
+		terminalID = *purchase.SumupReaderID
+	}
+
+	if terminalID != "" {
+		// the checkout may have ended on the terminal already, its status tells
+		if err := s.terminalProvider.CancelCheckout(terminalID); err != nil {
+			slog.Warn("Failed to cancel the checkout on the terminal",
+				"purchase_id", purchaseID, "terminal_id", terminalID, "error", err)
+		}
+	}
+
+	status := terminal.CheckoutStatusCancelled
+
+	if purchase.SumupClientTransactionID != nil {
+		checkout, err := s.terminalProvider.GetCheckout(*purchase.SumupClientTransactionID)
+
+		switch {
+		case errors.Is(err, terminal.ErrCheckoutNotFound):
+		case err != nil:
+			return nil, fmt.Errorf("unable to verify the checkout on the terminal: %w", err)
+		case checkout.Status == terminal.CheckoutStatusSuccessful:
+			return s.confirmLatePayment(ctx, purchase, checkout)
+		default:
+			status = checkout.Status
+		}
+	}
+
+	if status == terminal.CheckoutStatusFailed {
+		return s.FailPurchase(ctx, purchaseID)
+	}
+
+	aborted, err := abort(ctx, purchaseID)
+	if errors.Is(err, ErrPurchaseNotPending) {
+		// the poller or the webhook finished the purchase meanwhile
+		current, getErr := s.sqliteRepo.GetPurchaseByID(purchaseID)
+		if getErr != nil {
+			return nil, getErr
+		}
+
+		if current.Status == models.PurchaseStatusConfirmed {
+			return current, ErrPaymentAlreadySucceeded
+		}
+
+		return current, nil
+	}
+
+	return aborted, err
+}
+
+func (s *PurchaseService) confirmLatePayment(
+	ctx context.Context,
+	purchase *models.Purchase,
+	checkout *terminal.Checkout,
+) (*models.Purchase, error) {
+	if purchase.SumupTransactionID == nil && checkout.TransactionID != uuid.Nil {
+		if _, err := s.sqliteRepo.UpdatePurchaseSumupTransactionIDByID(purchase.ID, checkout.TransactionID); err != nil {
+			return nil, err
+		}
+	}
+
+	// the poller or the webhook may have confirmed the purchase meanwhile
+	confirmed, err := s.FinalizePurchase(ctx, purchase.ID)
+	if err != nil && !errors.Is(err, ErrPurchaseAlreadyConfirmed) {
+		return nil, err
+	}
+
+	return confirmed, ErrPaymentAlreadySucceeded
+}
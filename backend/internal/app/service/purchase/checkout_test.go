@@ -0,0 +1,180 @@
+package purchase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/potibm/kasseapparat/internal/app/repository/terminal"
+)
+
+func newPendingCheckoutPurchase(
+	t *testing.T,
+	checkout *terminal.Checkout,
+) (*PurchaseService, *MockTerminal, uuid.UUID) {
+	t.Helper()
+
+	service, mockRepo := newMockPurchaseService(withStock(5))
+	mockTerminal := &MockTerminal{Checkout: checkout}
+	service.terminalProvider = mockTerminal
+
+	input := stockPurchaseInput(1)
+	input.SumupReaderID = "reader-1"
+
+	purchase, err := service.CreatePendingPurchase(context.Background(), input, 7)
+	if err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	clientTransactionID := uuid.New()
+	mockRepo.StoredPurchase.SumupClientTransactionID = &clientTransactionID
+
+	return service, mockTerminal, purchase.ID
+}
+
+func TestCancelTerminalCheckoutCancelsThePurchase(t *testing.T) {
+	service, mockTerminal, purchaseID := newPendingCheckoutPurchase(t,
+		&terminal.Checkout{Status: terminal.CheckoutStatusPending})
+
+	purchase, err := service.CancelTerminalCheckout(context.Background(), purchaseID, "")
+	if err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	if mockTerminal.CancelledTerminal != "reader-1" {
+		t.Errorf("expected the checkout on the reader of the purchase to be cancelled, got %q",
+			mockTerminal.CancelledTerminal)
+	}
+
+	if purchase.Status != models.PurchaseStatusCancelled {
+		t.Errorf("expected the purchase to be cancelled, got %s", purchase.Status)
+	}
+}
+
+func TestCancelTerminalCheckoutConfirmsAPaymentThatSucceededMeanwhile(t *testing.T) {
+	transactionID := uuid.New()
+	service, _, purchaseID := newPendingCheckoutPurchase(t, &terminal.Checkout{
+		TransactionID: transactionID,
+		Status:        terminal.CheckoutStatusSuccessful,
+	})
+
+	purchase, err := service.CancelTerminalCheckout(context.Background(), purchaseID, "")
+	if !errors.Is(err, ErrPaymentAlreadySucceeded) {
+		t.Fatalf("expected ErrPaymentAlreadySucceeded, got %v", err)
+	}
+
+	if purchase.Status != models.PurchaseStatusConfirmed {
+		t.Errorf("expected the purchase to be confirmed, got %s", purchase.Status)
+	}
+
+	if purchase.SumupTransactionID == nil || *purchase.SumupTransactionID != transactionID {
+		t.Errorf("expected the transaction ID to be recorded, got %v", purchase.SumupTransactionID)
+	}
+}
+
+func TestCancelTerminalCheckoutFailsADeclinedPayment(t *testing.T) {
+	service, _, purchaseID := newPendingCheckoutPurchase(t,
+		&terminal.Checkout{Status: terminal.CheckoutStatusFailed})
+
+	purchase, err := service.CancelTerminalCheckout(context.Background(), purchaseID, "")
+	if err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	if purchase.Status != models.PurchaseStatusFailed {
+		t.Errorf("expected the purchase to be failed, got %s", purchase.Status)
+	}
+}
+
+func TestCancelTerminalCheckoutRejectsAPurchaseThatIsNotPending(t *testing.T) {
+	service, _, purchaseID := newPendingCheckoutPurchase(t, nil)
+
+	if _, err := service.CancelTerminalCheckout(context.Background(), purchaseID, ""); err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	_, err := service.CancelTerminalCheckout(context.Background(), purchaseID, "")
+	if !errors.Is(err, ErrPurchaseNotPending) {
+		t.Errorf("expected ErrPurchaseNotPending, got %v", err)
+	}
+}
+
+func TestFinalizePurchaseKeepsACancelledPurchaseCancelled(t *testing.T) {
+	service, _, purchaseID := newPendingCheckoutPurchase(t, nil)
+
+	if _, err := service.CancelPurchase(context.Background(), purchaseID); err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	if _, err := service.FinalizePurchase(context.Background(), purchaseID); !errors.Is(err, ErrPurchaseNotPending) {
+		t.Errorf("expected ErrPurchaseNotPending, got %v", err)
+	}
+}
+
+func TestExpirePendingPurchaseFailsThePurchaseForReview(t *testing.T) {
+	service, mockTerminal, purchaseID := newPendingCheckoutPurchase(t,
+		&terminal.Checkout{Status: terminal.CheckoutStatusPending})
+
+	purchase, err := service.ExpirePendingPurchase(context.Background(), purchaseID)
+	if err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	if mockTerminal.CancelledTerminal != "reader-1" {
+		t.Errorf("expected the checkout on the reader of the purchase to be cancelled, got %q",
+			mockTerminal.CancelledTerminal)
+	}
+
+	if purchase.Status != models.PurchaseStatusFailed {
+		t.Errorf("expected the purchase to be failed, got %s", purchase.Status)
+	}
+
+	if purchase.ExpiredAt == nil {
+		t.Error("expected the purchase to be marked as expired")
+	}
+}
+
+func TestExpirePendingPurchaseConfirmsAPaymentThatSucceededAtTheLastMoment(t *testing.T) {
+	service, _, purchaseID := newPendingCheckoutPurchase(t, &terminal.Checkout{
+		TransactionID: uuid.New(),
+		Status:        terminal.CheckoutStatusSuccessful,
+	})
+
+	purchase, err := service.ExpirePendingPurchase(context.Background(), purchaseID)
+	if !errors.Is(err, ErrPaymentAlreadySucceeded) {
+		t.Fatalf("expected ErrPaymentAlreadySucceeded, got %v", err)
+	}
+
+	if purchase.Status != models.PurchaseStatusConfirmed || purchase.ExpiredAt != nil {
+		t.Errorf("expected the purchase to be confirmed and not expired, got %s", purchase.Status)
+	}
+}
+
+func TestFinalizePurchaseNotifiesTheGuestsOnlyOnce(t *testing.T) {
+	service, _, purchaseID := newPendingCheckoutPurchase(t, nil)
+	mailer := &MockMailer{}
+	service.Mailer = mailer
+
+	guest := &models.Guest{Name: "Alice", NotifyOnArrivalEmail: ptr("alice@example.com"), PurchaseID: &purchaseID}
+	service.sqliteRepo.(*MockRepository).Guests = map[int]*models.Guest{1: guest}
+
+	if _, err := service.FinalizePurchase(context.Background(), purchaseID); err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	// the payment is reported a second time, e.g. by the webhook after the poller
+	purchase, err := service.FinalizePurchase(context.Background(), purchaseID)
+	if !errors.Is(err, ErrPurchaseAlreadyConfirmed) {
+		t.Fatalf("expected ErrPurchaseAlreadyConfirmed, got %v", err)
+	}
+
+	if purchase == nil || purchase.Status != models.PurchaseStatusConfirmed {
+		t.Errorf("expected the confirmed purchase, got %+v", purchase)
+	}
+
+	if len(mailer.Sent) != 1 {
+		t.Errorf("expected the guest to be notified once, got %v", mailer.Sent)
+	}
+}
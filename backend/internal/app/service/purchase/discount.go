@@ -0,0 +1,211 @@
+package purchase
+
+import (
+	"errors"
+
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrDiscountNotPermitted  = errors.New("user is not allowed to grant discounts")
+	ErrDiscountNotApplicable = errors.New("discount does not apply to the cart")
+	ErrDuplicateDiscount     = errors.New("discount is applied more than once")
+)
+
+// DiscountInput applies a discount rule, either by its code or manually by its ID.
+type DiscountInput struct {
+	Code       string
+	DiscountID int
+}
+
+// IsManual reports whether the discount is applied without a code.
+func (input DiscountInput) IsManual() bool {
+	return input.Code == ""
+}
+
+// checkDiscountPermission checks that discounts applied without a code are granted by a user
+// allowed to do so.
+func (s *PurchaseService) checkDiscountPermission(input PurchaseInput, userID int) error {
+	for _, discount := range input.Discounts {
+		if !discount.IsManual() {
+			continue
+		}
+
+		user, err := s.sqliteRepo.GetUserByID(userID)
+		if err != nil || !user.CanGrantDiscounts() {
+			return ErrDiscountNotPermitted
+		}
+
+		return nil
+	}
+
+	return nil
+}
+
+// priceCart builds the purchase items of the cart at the product prices of the window and applies
+// the discounts in the given order. It returns the items with their discount lines and the totals.
+func (s *PurchaseService) priceCart(
+	input PurchaseInput,
+	prices priceWindow,
+) (items []models.PurchaseItem, totalNet, totalGross decimal.Decimal, err error) {
+	totalNet = decimal.NewFromInt(0)
+	totalGross = decimal.NewFromInt(0)
+
+	for _, item := range input.Cart {
+		product, err := s.cartItemProduct(item, prices)
+		if err != nil {
+			return nil, decimal.Zero, decimal.Zero, err
+		}
+
+		quantity := decimal.NewFromUint64(uint64(item.Quantity))
+		totalNet = totalNet.Add(product.NetPrice.Mul(quantity))
+		totalGross = totalGross.Add(product.GrossPrice(s.DecimalPlaces).Mul(quantity))
+
+		// a bundle is sold as its components
+		if product.IsBundle() {
+			items = append(items, product.ExpandBundle(item.Quantity)...)
+
+			continue
+		}
+
+		items = append(items, models.PurchaseItem{
+			ProductID: product.ID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+			NetPrice:  product.NetPrice,
+			VATRate:   product.VATRate,
+		})
+	}
+
+	discounts, err := s.resolveDiscounts(input.Discounts)
+	if err != nil {
+		return nil, decimal.Zero, decimal.Zero, err
+	}
+
+	for _, discount := range discounts {
+		if !discount.ApplyTo(items, s.DecimalPlaces) {
+			return nil, decimal.Zero, decimal.Zero, ErrDiscountNotApplicable
+		}
+	}
+
+	for _, item := range items {
+		totalNet = totalNet.Sub(item.DiscountNetAmount())
+		totalGross = totalGross.Sub(item.DiscountGrossAmount())
+	}
+
+	return items, totalNet, totalGross, nil
+}
+
+// cartItemProduct returns the product of the cart item at the price it is sold at, as sold in the
+// chosen variant, and checks the price of the cart item.
+func (s *PurchaseService) cartItemProduct(item PurchaseCartItem, prices priceWindow) (models.Product, error) {
+	// the price rules active at checkout set the price, purchases keep the price they were sold at
+	candidates, err := s.cartItemPrices(item, prices)
+	if err != nil {
+		return models.Product{}, err
+	}
+
+	for _, candidate := range candidates {
+		if candidate.NetPrice.Round(s.DecimalPlaces).Equal(item.NetPrice.Round(s.DecimalPlaces)) {
+			return candidate, nil
+		}
+	}
+
+	return models.Product{}, ErrInvalidProductPrice
+}
+
+// cartItemPrices returns the product of the cart item, as sold in the chosen variant, at every price
+// in effect within the window, the price at the start of the window first. A product with variants
+// can only be sold as one of them.
+func (s *PurchaseService) cartItemPrices(item PurchaseCartItem, prices priceWindow) ([]models.Product, error) {
+	product, err := s.sqliteRepo.GetProductByID(item.ID)
+	if err != nil || product == nil {
+		return nil, ErrProductNotFound
+	}
+
+	var variant *models.ProductVariant
+
+	switch {
+	case item.VariantID != nil:
+		found, ok := product.Variant(*item.VariantID)
+		if !ok {
+			return nil, ErrVariantNotFound
+		}
+
+		variant = &found
+	case product.HasVariants():
+		return nil, ErrVariantRequired
+	}
+
+	return s.pricesInEffect(*product, variant, prices)
+}
+
+func (s *PurchaseService) resolveDiscounts(inputs []DiscountInput) ([]models.Discount, error) {
+	discounts := make([]models.Discount, 0, len(inputs))
+	seen := make(map[int]bool, len(inputs))
+
+	for _, input := range inputs {
+		var (
+			discount *models.Discount
+			err      error
+		)
+
+		if input.IsManual() {
+			discount, err = s.sqliteRepo.GetDiscountByID(input.DiscountID)
+		} else {
+			discount, err = s.sqliteRepo.GetDiscountByCode(input.Code)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if seen[discount.ID] {
+			return nil, ErrDuplicateDiscount
+		}
+
+		seen[discount.ID] = true
+
+		discounts = append(discounts, *discount)
+	}
+
+	return discounts, nil
+}
+
+// refundDiscount keeps track of the discount of a purchase item that has not been refunded yet.
+type refundDiscount struct {
+	net   decimal.Decimal
+	gross decimal.Decimal
+}
+
+func newRefundDiscount(item models.PurchaseItem) *refundDiscount {
+	refundedNet, refundedGross := item.RefundedDiscount()
+
+	return &refundDiscount{
+		net:   item.DiscountNetAmount().Sub(refundedNet),
+		gross: item.DiscountGrossAmount().Sub(refundedGross),
+	}
+}
+
+// take returns the share of the item's discount for the refunded quantity. Refunding the last
+// units takes what is left, so the discount is refunded exactly.
+func (d *refundDiscount) take(
+	item models.PurchaseItem,
+	quantity uint,
+	last bool,
+	decimalPlaces int32,
+) (net, gross decimal.Decimal) {
+	net, gross = d.net, d.gross
+
+	if !last && item.Quantity > 0 {
+		share := decimal.NewFromUint64(uint64(quantity)).Div(decimal.NewFromUint64(uint64(item.Quantity)))
+		net = decimal.Min(item.DiscountNetAmount().Mul(share).Round(decimalPlaces), d.net)
+		gross = decimal.Min(item.DiscountGrossAmount().Mul(share).Round(decimalPlaces), d.gross)
+	}
+
+	d.net = d.net.Sub(net)
+	d.gross = d.gross.Sub(gross)
+
+	return net, gross
+}
@@ -0,0 +1,197 @@
+package purchase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/shopspring/decimal"
+)
+
+func newCrewDiscount() *models.Discount {
+	discount := &models.Discount{
+		Name:  "Crew",
+		Code:  ptr("CREW"),
+		Type:  models.DiscountTypePercentage,
+		Value: decimal.NewFromInt(10),
+	}
+	discount.ID = 2
+
+	return discount
+}
+
+func discountPurchaseInput(discount DiscountInput) PurchaseInput {
+	return PurchaseInput{
+		PaymentMethod:   models.PaymentMethodCash,
+		TotalNetPrice:   decimal.NewFromFloat(18.00),
+		TotalGrossPrice: decimal.NewFromFloat(21.42),
+		Discounts:       []DiscountInput{discount},
+		Cart: []PurchaseCartItem{
+			{ID: 1, Quantity: 2, NetPrice: decimal.NewFromFloat(10.00)},
+		},
+	}
+}
+
+func TestCreatePurchaseWithDiscountCode(t *testing.T) {
+	user := &models.User{}
+	user.ID = 7
+	service, _ := newMockPurchaseService(withDiscount(newCrewDiscount()), withCashier(user))
+
+	purchase, err := service.CreateConfirmedPurchase(
+		context.Background(),
+		discountPurchaseInput(DiscountInput{Code: "crew"}),
+		7,
+	)
+	if err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	lines := purchase.DiscountLines()
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 discount line, got %d", len(lines))
+	}
+
+	if !lines[0].GrossAmount.Equal(decimal.NewFromFloat(2.38)) ||
+		!lines[0].NetAmount.Equal(decimal.NewFromFloat(2.00)) {
+		t.Errorf("unexpected discount line: %+v", lines[0])
+	}
+
+	if lines[0].PurchaseID != purchase.ID || lines[0].DiscountID == nil || *lines[0].DiscountID != 2 {
+		t.Errorf("discount line not linked to the purchase and the rule: %+v", lines[0])
+	}
+
+	if !purchase.TotalGrossPrice.Equal(decimal.NewFromFloat(21.42)) {
+		t.Errorf("unexpected gross total: %s", purchase.TotalGrossPrice)
+	}
+}
+
+func TestCreatePurchaseWithManualDiscountWithoutPermission(t *testing.T) {
+	user := &models.User{}
+	user.ID = 7
+	service, _ := newMockPurchaseService(withDiscount(newCrewDiscount()), withCashier(user))
+
+	_, err := service.CreateConfirmedPurchase(
+		context.Background(),
+		discountPurchaseInput(DiscountInput{DiscountID: 2}),
+		7,
+	)
+	if err != ErrDiscountNotPermitted {
+		t.Fatalf("expected ErrDiscountNotPermitted, got %v", err)
+	}
+}
+
+func TestCreatePurchaseWithManualDiscountGrantedByPermittedUser(t *testing.T) {
+	user := &models.User{GrantDiscounts: true}
+	user.ID = 7
+	service, _ := newMockPurchaseService(withDiscount(newCrewDiscount()), withCashier(user))
+
+	purchase, err := service.CreateConfirmedPurchase(
+		context.Background(),
+		discountPurchaseInput(DiscountInput{DiscountID: 2}),
+		7,
+	)
+	if err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	if len(purchase.DiscountLines()) != 1 {
+		t.Errorf("expected 1 discount line, got %d", len(purchase.DiscountLines()))
+	}
+}
+
+func TestValidateAndCalculatePricesWithDuplicateDiscount(t *testing.T) {
+	user := &models.User{}
+	service, _ := newMockPurchaseService(withDiscount(newCrewDiscount()), withCashier(user))
+
+	input := discountPurchaseInput(DiscountInput{Code: "CREW"})
+	input.Discounts = append(input.Discounts, DiscountInput{Code: "crew"})
+
+	_, _, err := service.ValidateAndCalculatePrices(input)
+	if err != ErrDuplicateDiscount {
+		t.Fatalf("expected ErrDuplicateDiscount, got %v", err)
+	}
+}
+
+func TestValidateAndCalculatePricesWithDiscountForOtherProduct(t *testing.T) {
+	discount := newCrewDiscount()
+	discount.ProductID = intPtr(9)
+	service, _ := newMockPurchaseService(withDiscount(discount), withCashier(&models.User{}))
+
+	_, _, err := service.ValidateAndCalculatePrices(discountPurchaseInput(DiscountInput{Code: "CREW"}))
+	if err != ErrDiscountNotApplicable {
+		t.Fatalf("expected ErrDiscountNotApplicable, got %v", err)
+	}
+}
+
+func TestRefundPurchaseItemsRefundsDiscountShare(t *testing.T) {
+	transactionID := uuid.New()
+
+	purchase := &models.Purchase{
+		ID:                 uuid.New(),
+		TotalNetPrice:      decimal.NewFromFloat(18.00),
+		TotalGrossPrice:    decimal.NewFromFloat(21.42),
+		PaymentMethod:      models.PaymentMethodSumUp,
+		SumupTransactionID: &transactionID,
+		Status:             models.PurchaseStatusConfirmed,
+		Payments: []models.PurchasePayment{
+			{PaymentMethod: models.PaymentMethodSumUp, Amount: decimal.NewFromFloat(21.42)},
+		},
+		PurchaseItems: []models.PurchaseItem{
+			{
+				ProductID: 1,
+				Quantity:  2,
+				NetPrice:  decimal.NewFromFloat(10.00),
+				VATRate:   decimal.NewFromInt(19),
+				Discounts: []models.PurchaseDiscount{
+					{
+						Name:        "Crew",
+						VATRate:     decimal.NewFromInt(19),
+						NetAmount:   decimal.NewFromFloat(2.00),
+						GrossAmount: decimal.NewFromFloat(2.38),
+					},
+				},
+			},
+		},
+	}
+	purchase.PurchaseItems[0].ID = 5
+
+	mockRepo := &MockRepository{StoredPurchase: purchase}
+	refunder := &MockTerminal{}
+
+	service := &PurchaseService{
+		sqliteRepo:       mockRepo,
+		terminalProvider: refunder,
+		DecimalPlaces:    2,
+	}
+
+	for range 2 {
+		_, err := service.RefundPurchaseItems(
+			context.Background(),
+			purchase.ID,
+			[]RefundItemInput{{PurchaseItemID: 5, Quantity: 1}},
+			7,
+		)
+		if err != nil {
+			t.Fatalf(errUnexpected, err)
+		}
+	}
+
+	if len(mockRepo.StoredRefunds) != 2 {
+		t.Fatalf("expected 2 stored refunds, got %d", len(mockRepo.StoredRefunds))
+	}
+
+	total := decimal.Zero
+
+	for _, refund := range mockRepo.StoredRefunds {
+		if !refund.TotalGrossPrice.Equal(decimal.NewFromFloat(10.71)) {
+			t.Errorf("unexpected refund gross total: %s", refund.TotalGrossPrice)
+		}
+
+		total = total.Add(refund.TotalNetPrice)
+	}
+
+	if !total.Equal(decimal.NewFromFloat(18.00)) {
+		t.Errorf("refunds must add up to the discounted net total, got %s", total)
+	}
+}
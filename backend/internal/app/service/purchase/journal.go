@@ -0,0 +1,226 @@
+package purchase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/potibm/kasseapparat/internal/app/repository/sqlite"
+	"github.com/shopspring/decimal"
+)
+
+type journalPurchasePayload struct {
+	Status          models.PurchaseStatus    `json:"status"`
+	ReceiptNumber   *string                  `json:"receiptNumber"`
+	TotalNetPrice   decimal.Decimal          `json:"totalNetPrice"`
+	TotalGrossPrice decimal.Decimal          `json:"totalGrossPrice"`
+	Items           []journalItemPayload     `json:"items"`
+	Payments        []journalPaymentPayload  `json:"payments"`
+	Roundings       []journalPaymentPayload  `json:"roundings,omitempty"`
+	Discounts       []journalDiscountPayload `json:"discounts,omitempty"`
+}
+
+type journalDiscountPayload struct {
+	DiscountID  *int            `json:"discountId"`
+	Name        string          `json:"name"`
+	Code        *string         `json:"code"`
+	VATRate     decimal.Decimal `json:"vatRate"`
+	NetAmount   decimal.Decimal `json:"netAmount"`
+	GrossAmount decimal.Decimal `json:"grossAmount"`
+}
+
+type journalItemPayload struct {
+	ProductID int             `json:"productId"`
+	BundleID  *int            `json:"bundleId,omitempty"`
+	Quantity  uint            `json:"quantity"`
+	NetPrice  decimal.Decimal `json:"netPrice"`
+	VATRate   decimal.Decimal `json:"vatRate"`
+}
+
+type journalPaymentPayload struct {
+	PaymentMethod models.PaymentMethod `json:"paymentMethod"`
+	Amount        decimal.Decimal      `json:"amount"`
+}
+
+type journalStatusPayload struct {
+	From          models.PurchaseStatus `json:"from"`
+	To            models.PurchaseStatus `json:"to"`
+	ReceiptNumber *string               `json:"receiptNumber"`
+}
+
+type journalRefundPayload struct {
+	RefundID        int                        `json:"refundId"`
+	TotalNetPrice   decimal.Decimal            `json:"totalNetPrice"`
+	TotalGrossPrice decimal.Decimal            `json:"totalGrossPrice"`
+	Items           []journalRefundItemPayload `json:"items"`
+	Payments        []journalPaymentPayload    `json:"payments"`
+	Roundings       []journalPaymentPayload    `json:"roundings,omitempty"`
+}
+
+type journalRefundItemPayload struct {
+	PurchaseItemID int             `json:"purchaseItemId"`
+	Quantity       uint            `json:"quantity"`
+	NetPrice       decimal.Decimal `json:"netPrice"`
+	VATRate        decimal.Decimal `json:"vatRate"`
+}
+
+// DeletePurchase deletes the purchase, releases its guests and its stock and records the deletion in
+// the journal.
+func (s *PurchaseService) DeletePurchase(ctx context.Context, purchaseID uuid.UUID, deletedBy models.User) error {
+	return s.sqliteRepo.WithTransaction(ctx, func(txRepo sqlite.RepositoryInterface) error {
+		purchase, err := txRepo.GetPurchaseByID(purchaseID)
+		if err != nil {
+			return err
+		}
+
+		if err := txRepo.DeletePurchaseByID(purchaseID, deletedBy); err != nil {
+			return err
+		}
+
+		if purchase.Status.ReservesStock() {
+			if err := updateStock(txRepo, stockOrigin{
+				purchaseID: purchaseID,
+				userID:     &deletedBy.ID,
+				note:       "Purchase deleted",
+			}, releasedStock(purchase.PurchaseItems)); err != nil {
+				return err
+			}
+		}
+
+		if err := txRepo.RollbackVisitedGuestsByPurchaseID(purchaseID); err != nil {
+			return fmt.Errorf("failed to rollback visited guests: %w", err)
+		}
+
+		if err := creditVouchers(txRepo, purchase); err != nil {
+			return err
+		}
+
+		return s.journal(txRepo, models.JournalEventPurchaseDeleted, purchaseID, &deletedBy.ID,
+			purchaseJournalPayload(purchase))
+	})
+}
+
+func (s *PurchaseService) journalStatusChange(
+	txRepo sqlite.RepositoryInterface,
+	purchase *models.Purchase,
+	from models.PurchaseStatus,
+	userID *int,
+) error {
+	if purchase.Status == from {
+		return nil
+	}
+
+	return s.journal(txRepo, models.JournalEventPurchaseStatusChanged, purchase.ID, userID, journalStatusPayload{
+		From:          from,
+		To:            purchase.Status,
+		ReceiptNumber: purchase.ReceiptNumber,
+	})
+}
+
+func (s *PurchaseService) journalRefund(
+	txRepo sqlite.RepositoryInterface,
+	refund models.PurchaseRefund,
+	userID *int,
+) error {
+	payload := journalRefundPayload{
+		RefundID:        refund.ID,
+		TotalNetPrice:   refund.TotalNetPrice,
+		TotalGrossPrice: refund.TotalGrossPrice,
+	}
+
+	for _, item := range refund.Items {
+		payload.Items = append(payload.Items, journalRefundItemPayload{
+			PurchaseItemID: item.PurchaseItemID,
+			Quantity:       item.Quantity,
+			NetPrice:       item.NetPrice,
+			VATRate:        item.VATRate,
+		})
+	}
+
+	for _, payment := range refund.Payments {
+		payload.Payments = append(payload.Payments, journalPaymentPayload{
+			PaymentMethod: payment.PaymentMethod,
+			Amount:        payment.Amount,
+		})
+	}
+
+	for _, rounding := range refund.Roundings {
+		payload.Roundings = append(payload.Roundings, journalPaymentPayload{
+			PaymentMethod: rounding.PaymentMethod,
+			Amount:        rounding.Amount,
+		})
+	}
+
+	return s.journal(txRepo, models.JournalEventPurchaseRefunded, refund.PurchaseID, userID, payload)
+}
+
+// journal appends an entry to the journal. It is called within the transaction of the change.
+func (s *PurchaseService) journal(
+	txRepo sqlite.RepositoryInterface,
+	eventType models.JournalEventType,
+	purchaseID uuid.UUID,
+	userID *int,
+	payload any,
+) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode the journal entry: %w", err)
+	}
+
+	_, err = txRepo.AppendJournalEntry(models.JournalEntry{
+		EventType:  eventType,
+		PurchaseID: purchaseID,
+		UserID:     userID,
+		Payload:    string(data),
+	})
+
+	return err
+}
+
+func purchaseJournalPayload(purchase *models.Purchase) journalPurchasePayload {
+	payload := journalPurchasePayload{
+		Status:          purchase.Status,
+		ReceiptNumber:   purchase.ReceiptNumber,
+		TotalNetPrice:   purchase.TotalNetPrice,
+		TotalGrossPrice: purchase.TotalGrossPrice,
+	}
+
+	for _, item := range purchase.PurchaseItems {
+		payload.Items = append(payload.Items, journalItemPayload{
+			ProductID: item.ProductID,
+			BundleID:  item.BundleID,
+			Quantity:  item.Quantity,
+			NetPrice:  item.NetPrice,
+			VATRate:   item.VATRate,
+		})
+	}
+
+	for _, payment := range purchase.PaymentLines() {
+		payload.Payments = append(payload.Payments, journalPaymentPayload{
+			PaymentMethod: payment.PaymentMethod,
+			Amount:        payment.Amount,
+		})
+	}
+
+	for _, rounding := range purchase.RoundingLines() {
+		payload.Roundings = append(payload.Roundings, journalPaymentPayload{
+			PaymentMethod: rounding.PaymentMethod,
+			Amount:        rounding.Amount,
+		})
+	}
+
+	for _, discount := range purchase.DiscountLines() {
+		payload.Discounts = append(payload.Discounts, journalDiscountPayload{
+			DiscountID:  discount.DiscountID,
+			Name:        discount.Name,
+			Code:        discount.Code,
+			VATRate:     discount.VATRate,
+			NetAmount:   discount.NetAmount,
+			GrossAmount: discount.GrossAmount,
+		})
+	}
+
+	return payload
+}
@@ -0,0 +1,280 @@
+package purchase
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/shopspring/decimal"
+)
+
+// offlineClockSkew is how far the clock of a client may run ahead of the clock of the server.
+const offlineClockSkew = 5 * time.Minute
+
+type OfflinePurchaseStatus string
+
+const (
+	OfflinePurchaseAccepted  OfflinePurchaseStatus = "accepted"
+	OfflinePurchaseDuplicate OfflinePurchaseStatus = "duplicate"
+	OfflinePurchaseConflict  OfflinePurchaseStatus = "conflict"
+)
+
+var (
+	ErrOfflinePurchaseID   = errors.New("purchase recorded offline has no ID")
+	ErrRecordedInFuture    = errors.New("purchase is recorded in the future")
+	ErrRecordedTooLongAgo  = errors.New("purchase was recorded too long ago")
+	ErrOfflineSumupPayment = errors.New("purchases recorded offline cannot be paid via SumUp")
+)
+
+// OfflinePurchaseInput is a purchase recorded by a client while it was offline. It keeps the ID the
+// client gave it, so syncing it again does not store it twice.
+type OfflinePurchaseInput struct {
+	PurchaseInput
+
+	ID         uuid.UUID
+	RecordedAt time.Time
+}
+
+// OfflinePurchaseResult is the outcome of syncing a purchase recorded offline. Guests of the
+// purchase who already arrived are not marked again, their IDs are listed as skipped.
+type OfflinePurchaseResult struct {
+	ID              uuid.UUID
+	Status          OfflinePurchaseStatus
+	Purchase        *models.Purchase
+	SkippedGuestIDs []int
+	Err             error
+}
+
+// SyncOfflinePurchases stores the purchases recorded offline in their order, each one on its own.
+// A purchase that has been synced before is reported as a duplicate, one that cannot be stored as a
+// conflict. The prices are checked against the prices in effect from the time the purchase was
+// recorded until now, a purchase recorded longer ago than the maximum offline age is rejected.
+func (s *PurchaseService) SyncOfflinePurchases(
+	ctx context.Context,
+	inputs []OfflinePurchaseInput,
+	userID int,
+) []OfflinePurchaseResult {
+	results := make([]OfflinePurchaseResult, 0, len(inputs))
+
+	for _, input := range inputs {
+		results = append(results, s.syncOfflinePurchase(ctx, input, userID))
+	}
+
+	return results
+}
+
+func (s *PurchaseService) syncOfflinePurchase(
+	ctx context.Context,
+	input OfflinePurchaseInput,
+	userID int,
+) OfflinePurchaseResult {
+	result := OfflinePurchaseResult{ID: input.ID, Status: OfflinePurchaseConflict}
+
+	switch {
+	case input.ID == uuid.Nil:
+		result.Err = ErrOfflinePurchaseID
+
+		return result
+	case s.purchaseExists(input.ID):
+		result.Status = OfflinePurchaseDuplicate
+
+		return result
+	case input.RecordedAt.After(time.Now().Add(offlineClockSkew)):
+		result.Err = ErrRecordedInFuture
+
+		return result
+	case s.offlineMaxAge > 0 && time.Since(input.RecordedAt) > s.offlineMaxAge:
+		result.Err = ErrRecordedTooLongAgo
+
+		return result
+	case input.HasPaymentMethod(models.PaymentMethodSumUp):
+		result.Err = ErrOfflineSumupPayment
+
+		return result
+	}
+
+	recordedAt := input.RecordedAt
+
+	savedPurchase, guests, err := s.createPurchaseWithStatus(
+		ctx,
+		input.PurchaseInput,
+		userID,
+		models.PurchaseStatusConfirmed,
+		purchaseOptions{id: input.ID, recordedAt: &recordedAt},
+	)
+	if err != nil {
+		// the purchase may have been synced by a concurrent request in the meantime
+		if s.purchaseExists(input.ID) {
+			result.Status = OfflinePurchaseDuplicate
+
+			return result
+		}
+
+		result.Err = err
+
+		return result
+	}
+
+	s.notifyGuests(guests)
+
+	s.recordTransactionMetrics(ctx, savedPurchase)
+
+	result.Status = OfflinePurchaseAccepted
+	result.Purchase = savedPurchase
+	result.SkippedGuestIDs = skippedGuestIDs(input.PurchaseInput, guests)
+
+	return result
+}
+
+func (s *PurchaseService) purchaseExists(id uuid.UUID) bool {
+	exists, err := s.sqliteRepo.PurchaseExists(id)
+	if err != nil {
+		slog.Error("Error checking whether the purchase exists", "purchase_id", id, "error", err)
+	}
+
+	return exists
+}
+
+// skippedGuestIDs returns the guests of the list items that have not been marked as arrived.
+func skippedGuestIDs(input PurchaseInput, arrived []models.Guest) []int {
+	marked := make(map[int]bool, len(arrived))
+	for _, guest := range arrived {
+		marked[guest.ID] = true
+	}
+
+	var skipped []int
+
+	for _, item := range input.Cart {
+		for _, listInput := range item.ListItems {
+			if !marked[listInput.ID] {
+				skipped = append(skipped, listInput.ID)
+				marked[listInput.ID] = true
+			}
+		}
+	}
+
+	return skipped
+}
+
+// purchaseOptions create a purchase recorded offline, under the ID the client gave it. The zero
+// value creates a purchase at the register.
+type purchaseOptions struct {
+	id         uuid.UUID
+	recordedAt *time.Time
+}
+
+func (o purchaseOptions) offline() bool {
+	return o.recordedAt != nil
+}
+
+func (o purchaseOptions) purchaseID() uuid.UUID {
+	if o.id == uuid.Nil {
+		return uuid.New()
+	}
+
+	return o.id
+}
+
+// prices returns the window of the prices the purchase may be sold at. A purchase recorded offline
+// may have been sold at any price in effect from the time it was recorded until now.
+func (o purchaseOptions) prices() priceWindow {
+	window := currentPrices()
+	if o.recordedAt != nil && o.recordedAt.Before(window.until) {
+		window.from = *o.recordedAt
+	}
+
+	return window
+}
+
+// priceWindow is the period whose prices a cart may be sold at.
+type priceWindow struct {
+	from  time.Time
+	until time.Time
+}
+
+func currentPrices() priceWindow {
+	now := time.Now()
+
+	return priceWindow{from: now, until: now}
+}
+
+// ruleTimes returns the start of the window and the times within it at which the price rules of
+// the product change its price.
+func (w priceWindow) ruleTimes(product models.Product) []time.Time {
+	times := []time.Time{w.from}
+
+	for at := w.from; ; {
+		change, _, ok := product.NextPriceChange(at)
+		if !ok || change.After(w.until) {
+			return times
+		}
+
+		times = append(times, change)
+		at = change
+	}
+}
+
+// pricesInEffect returns the product as sold in the variant, if given, at every price in effect
+// within the window. The list prices of the product and the variant are taken from their price
+// changes, the price rules are applied at the times they change the price.
+func (s *PurchaseService) pricesInEffect(
+	product models.Product,
+	variant *models.ProductVariant,
+	prices priceWindow,
+) ([]models.Product, error) {
+	listPrices := []decimal.Decimal{product.NetPrice}
+
+	var variantPrices []*decimal.Decimal
+	if variant != nil {
+		variantPrices = append(variantPrices, variant.NetPrice)
+	}
+
+	if prices.from.Before(prices.until) {
+		changes, err := s.sqliteRepo.GetProductPriceChangesSince(product.ID, prices.from)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, change := range changes {
+			switch {
+			case change.VariantID == nil:
+				for _, price := range []*decimal.Decimal{change.PreviousNetPrice, change.NetPrice} {
+					if price != nil {
+						listPrices = append(listPrices, *price)
+					}
+				}
+			case variant != nil && *change.VariantID == variant.ID:
+				variantPrices = append(variantPrices, change.PreviousNetPrice, change.NetPrice)
+			}
+		}
+	}
+
+	var candidates []models.Product
+
+	ruleTimes := prices.ruleTimes(product)
+
+	for _, listPrice := range listPrices {
+		for _, at := range ruleTimes {
+			listed := product
+			listed.NetPrice = listPrice
+			sold := listed.PriceAt(at)
+
+			if variant == nil {
+				candidates = append(candidates, sold)
+
+				continue
+			}
+
+			for _, variantPrice := range variantPrices {
+				soldVariant := *variant
+				soldVariant.NetPrice = variantPrice
+				candidates = append(candidates, sold.WithVariant(soldVariant))
+			}
+		}
+	}
+
+	return candidates, nil
+}
@@ -0,0 +1,151 @@
+package purchase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/shopspring/decimal"
+)
+
+func offlinePurchaseInput(recordedAt time.Time, netPrice float64, guestIDs ...int) OfflinePurchaseInput {
+	net := decimal.NewFromFloat(netPrice)
+
+	item := PurchaseCartItem{ID: 1, Quantity: 1, NetPrice: net}
+	for _, guestID := range guestIDs {
+		item.ListItems = append(item.ListItems, ListItemInput{ID: guestID, AttendedGuests: 1})
+	}
+
+	return OfflinePurchaseInput{
+		PurchaseInput: PurchaseInput{
+			PaymentMethod:   models.PaymentMethodCC,
+			TotalNetPrice:   net,
+			TotalGrossPrice: net.Mul(decimal.NewFromFloat(1.19)).Round(2),
+			Cart:            []PurchaseCartItem{item},
+		},
+		ID:         uuid.New(),
+		RecordedAt: recordedAt,
+	}
+}
+
+func TestSyncOfflinePurchasesChecksHistoricPricesAndReportsDuplicates(t *testing.T) {
+	service, _ := newMockPurchaseService(
+		withGuest(42),
+		withPriceChange(decimal.NewFromFloat(10.00), decimal.NewFromFloat(12.00), time.Now().Add(-time.Hour)),
+	)
+
+	beforeChange := offlinePurchaseInput(time.Now().Add(-2*time.Hour), 10)
+	afterChange := offlinePurchaseInput(time.Now().Add(-30*time.Minute), 10)
+	unknownPrice := offlinePurchaseInput(time.Now().Add(-2*time.Hour), 9)
+
+	results := service.SyncOfflinePurchases(
+		context.Background(),
+		[]OfflinePurchaseInput{beforeChange, afterChange, unknownPrice, beforeChange},
+		7,
+	)
+
+	if results[0].Status != OfflinePurchaseAccepted {
+		t.Fatalf(
+			"expected the purchase at the former price to be accepted, got %s: %v",
+			results[0].Status,
+			results[0].Err,
+		)
+	}
+
+	purchase := results[0].Purchase
+	if purchase.ID != beforeChange.ID || !purchase.Offline || purchase.RecordedAt == nil {
+		t.Errorf("expected an offline purchase under the client ID, got %+v", purchase)
+	}
+
+	for i, result := range results[1:3] {
+		if result.Status != OfflinePurchaseConflict || !errors.Is(result.Err, ErrInvalidProductPrice) {
+			t.Errorf("expected purchase %d to conflict on its price, got %s: %v", i+1, result.Status, result.Err)
+		}
+	}
+
+	if results[3].Status != OfflinePurchaseDuplicate {
+		t.Errorf("expected the purchase synced again to be a duplicate, got %s", results[3].Status)
+	}
+}
+
+func TestSyncOfflinePurchasesSkipsGuestsWhoArrived(t *testing.T) {
+	service, mockRepo := newMockPurchaseService(
+		withGuest(42),
+		withPriceChange(decimal.NewFromFloat(10.00), decimal.NewFromFloat(12.00), time.Now().Add(-time.Hour)),
+	)
+
+	recordedAt := time.Now().Add(-10 * time.Minute)
+
+	results := service.SyncOfflinePurchases(
+		context.Background(),
+		[]OfflinePurchaseInput{
+			offlinePurchaseInput(recordedAt, 12, 42),
+			offlinePurchaseInput(recordedAt, 12, 42),
+		},
+		7,
+	)
+
+	for i, result := range results {
+		if result.Status != OfflinePurchaseAccepted {
+			t.Fatalf("expected purchase %d to be accepted, got %s: %v", i, result.Status, result.Err)
+		}
+	}
+
+	if len(results[0].SkippedGuestIDs) != 0 {
+		t.Errorf("expected the guest to arrive with the first purchase, skipped %v", results[0].SkippedGuestIDs)
+	}
+
+	if len(results[1].SkippedGuestIDs) != 1 || results[1].SkippedGuestIDs[0] != 42 {
+		t.Errorf("expected the guest to be skipped in the second purchase, skipped %v", results[1].SkippedGuestIDs)
+	}
+
+	guest := mockRepo.Guests[42]
+	if guest.PurchaseID == nil || *guest.PurchaseID != results[0].ID {
+		t.Errorf("expected the guest to stay with the first purchase, got %v", guest.PurchaseID)
+	}
+}
+
+func TestSyncOfflinePurchasesRejectsSumupPayments(t *testing.T) {
+	service, _ := newMockPurchaseService(
+		withGuest(42),
+		withPriceChange(decimal.NewFromFloat(10.00), decimal.NewFromFloat(12.00), time.Now().Add(-time.Hour)),
+	)
+
+	input := offlinePurchaseInput(time.Now(), 12)
+	input.PaymentMethod = models.PaymentMethodSumUp
+
+	results := service.SyncOfflinePurchases(context.Background(), []OfflinePurchaseInput{input}, 7)
+	if results[0].Status != OfflinePurchaseConflict || !errors.Is(results[0].Err, ErrOfflineSumupPayment) {
+		t.Errorf("expected a SumUp payment to conflict, got %s: %v", results[0].Status, results[0].Err)
+	}
+}
+
+func TestSyncOfflinePurchasesRejectsPurchasesOlderThanTheMaximumAge(t *testing.T) {
+	service, _ := newMockPurchaseService(
+		withGuest(42),
+		withPriceChange(decimal.NewFromFloat(10.00), decimal.NewFromFloat(12.00), time.Now().Add(-time.Hour)),
+	)
+	service.offlineMaxAge = 24 * time.Hour
+
+	withinMaxAge := offlinePurchaseInput(time.Now().Add(-24*time.Hour+time.Minute), 10)
+	beyondMaxAge := offlinePurchaseInput(time.Now().Add(-24*time.Hour-time.Minute), 10)
+
+	results := service.SyncOfflinePurchases(
+		context.Background(),
+		[]OfflinePurchaseInput{withinMaxAge, beyondMaxAge},
+		7,
+	)
+
+	if results[0].Status != OfflinePurchaseAccepted {
+		t.Errorf("expected the purchase within the maximum age to be accepted, got %s: %v",
+			results[0].Status, results[0].Err)
+	}
+
+	if results[1].Status != OfflinePurchaseConflict || !errors.Is(results[1].Err, ErrRecordedTooLongAgo) {
+		t.Errorf("expected the purchase beyond the maximum age to conflict, got %s: %v",
+			results[1].Status, results[1].Err)
+	}
+}
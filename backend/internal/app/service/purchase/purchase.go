@@ -5,10 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/potibm/kasseapparat/internal/app/models"
 	"github.com/potibm/kasseapparat/internal/app/repository/sqlite"
+	"github.com/potibm/kasseapparat/internal/app/repository/terminal"
 	"github.com/shopspring/decimal"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
@@ -28,18 +31,34 @@ var (
 type Service interface {
 	CreateConfirmedPurchase(ctx context.Context, input PurchaseInput, userID int) (*models.Purchase, error)
 	CreatePendingPurchase(ctx context.Context, input PurchaseInput, userID int) (*models.Purchase, error)
+	QuoteCart(input PurchaseInput, userID int) (*Quote, error)
+	ApplyQuote(token string, input PurchaseInput, userID int) (PurchaseInput, error)
+	SyncOfflinePurchases(ctx context.Context, inputs []OfflinePurchaseInput, userID int) []OfflinePurchaseResult
 	FinalizePurchase(ctx context.Context, id uuid.UUID) (*models.Purchase, error)
 	CancelPurchase(ctx context.Context, id uuid.UUID) (*models.Purchase, error)
 	FailPurchase(ctx context.Context, id uuid.UUID) (*models.Purchase, error)
-	RefundPurchase(ctx context.Context, purchaseID uuid.UUID) (*models.Purchase, error)
+	CancelTerminalCheckout(ctx context.Context, id uuid.UUID, terminalID string) (*models.Purchase, error)
+	ExpirePendingPurchase(ctx context.Context, id uuid.UUID) (*models.Purchase, error)
+	DeletePurchase(ctx context.Context, id uuid.UUID, deletedBy models.User) error
+	RefundPurchase(ctx context.Context, purchaseID uuid.UUID, userID int) (*models.Purchase, error)
+	RefundPurchaseItems(
+		ctx context.Context,
+		purchaseID uuid.UUID,
+		items []RefundItemInput,
+		userID int,
+	) (*models.Purchase, error)
+	SettleRefund(ctx context.Context, refundID int) (*models.PurchaseRefund, error)
 }
 
 var _ Service = (*PurchaseService)(nil)
 
 var _ sqlite.RepositoryInterface = (*sqlite.Repository)(nil)
 
-type Refunder interface {
-	RefundTransaction(purchaseID uuid.UUID) error
+// PaymentTerminal takes the card payments via the payment terminal provider.
+type PaymentTerminal interface {
+	GetCheckout(clientTransactionID uuid.UUID) (*terminal.Checkout, error)
+	CancelCheckout(terminalID string) error
+	RefundTransaction(transactionID uuid.UUID, amount decimal.Decimal) error
 }
 
 type Mailer interface {
@@ -47,11 +66,16 @@ type Mailer interface {
 }
 
 type PurchaseService struct {
-	sqliteRepo    sqlite.RepositoryInterface
-	sumupRepo     Refunder
-	Mailer        Mailer
-	DecimalPlaces int32
-	CurrencyCode  string
+	sqliteRepo           sqlite.RepositoryInterface
+	terminalProvider     PaymentTerminal
+	Mailer               Mailer
+	DecimalPlaces        int32
+	CurrencyCode         string
+	ReceiptNumberPrefix  string
+	FiscalYearStartMonth time.Month
+	roundingRules        map[models.PaymentMethod]models.RoundingRule
+	quoteKey             []byte
+	offlineMaxAge        time.Duration
 }
 
 type PurchaseInput struct {
@@ -59,6 +83,50 @@ type PurchaseInput struct {
 	TotalNetPrice   decimal.Decimal
 	TotalGrossPrice decimal.Decimal
 	PaymentMethod   models.PaymentMethod
+	VoucherCode     string
+	Payments        []PaymentInput
+	Discounts       []DiscountInput
+	// SumupReaderID is the terminal a card payment is taken on.
+	SumupReaderID string
+}
+
+type PaymentInput struct {
+	PaymentMethod models.PaymentMethod
+	Amount        decimal.Decimal
+	VoucherCode   string
+}
+
+// PaymentLines returns the payment lines of the input. Without explicit lines the
+// whole total is paid with the input's payment method.
+func (input PurchaseInput) PaymentLines() []PaymentInput {
+	if len(input.Payments) > 0 {
+		return input.Payments
+	}
+
+	return []PaymentInput{
+		{PaymentMethod: input.PaymentMethod, Amount: input.TotalGrossPrice, VoucherCode: input.VoucherCode},
+	}
+}
+
+// HasPaymentMethod reports whether any payment line uses the given payment method.
+func (input PurchaseInput) HasPaymentMethod(method models.PaymentMethod) bool {
+	for _, line := range input.PaymentLines() {
+		if line.PaymentMethod == method {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PrimaryPaymentMethod returns the payment method stored on the purchase itself.
+// A SumUp line takes precedence, as it drives the pending/confirmed flow.
+func (input PurchaseInput) PrimaryPaymentMethod() models.PaymentMethod {
+	if input.HasPaymentMethod(models.PaymentMethodSumUp) {
+		return models.PaymentMethodSumUp
+	}
+
+	return input.PaymentLines()[0].PaymentMethod
 }
 
 type ListItemInput struct {
@@ -68,6 +136,7 @@ type ListItemInput struct {
 
 type PurchaseCartItem struct {
 	ID        int
+	VariantID *int
 	NetPrice  decimal.Decimal
 	Quantity  uint
 	ListItems []ListItemInput
@@ -82,72 +151,183 @@ var (
 	ErrGuestAlreadyAttended    = errors.New("guest already attended")
 	ErrTooManyAdditionalGuests = errors.New("additional guests exceed available guests")
 	ErrListItemWrongProduct    = errors.New("list item does not belong to product")
+	ErrInvalidPaymentAmount    = errors.New("payment amount must not be negative")
+	ErrInvalidPaymentTotal     = errors.New("payments do not add up to total gross price")
+	ErrMultipleSumupPayments   = errors.New("only one SumUp payment per purchase is supported")
+	ErrUnsettledPayment        = errors.New("purchase contains payments that have to be settled first")
+	ErrNoOpenRegisterSession   = errors.New("no open register session for cash payments")
+	ErrVoucherCodeRequired     = errors.New("voucher payments require a voucher code")
+	ErrVariantRequired         = errors.New("product can only be sold as one of its variants")
+	ErrVariantNotFound         = errors.New("variant not found")
+	ErrProductNotAllowed       = errors.New("user is not allowed to sell the product")
 )
 
 func intPtr(v int) *int {
 	return &v
 }
 
+// Options configure the purchase service.
+type Options struct {
+	// ReceiptNumberPrefix is put in front of the receipt numbers, e.g. the register. The receipt
+	// numbers restart with every fiscal year, which starts in FiscalYearStartMonth.
+	ReceiptNumberPrefix  string
+	FiscalYearStartMonth time.Month
+	// RoundingRules set how the amounts paid with the payment methods are rounded, e.g. cash to the
+	// nearest 0.50. Payment methods without a rule are paid exactly.
+	RoundingRules map[models.PaymentMethod]models.RoundingRule
+	// QuoteSecret is the secret the quote tokens are signed with, it is required.
+	QuoteSecret string
+	// OfflineMaxAge is how long ago a purchase synced from offline may have been recorded, 0 is
+	// unlimited.
+	OfflineMaxAge time.Duration
+}
+
 func NewPurchaseService(
 	sqliteRepo sqlite.RepositoryInterface,
-	sumupRepo Refunder,
+	terminalProvider PaymentTerminal,
 	mailer Mailer,
 	decimalPlaces int32,
 	currencyCode string,
-) *PurchaseService {
-	return &PurchaseService{
-		sqliteRepo:    sqliteRepo,
-		sumupRepo:     sumupRepo,
-		Mailer:        mailer,
-		DecimalPlaces: decimalPlaces,
-		CurrencyCode:  currencyCode,
+	options Options,
+) (*PurchaseService, error) {
+	if options.QuoteSecret == "" {
+		return nil, ErrQuoteSecretRequired
 	}
+
+	return &PurchaseService{
+		sqliteRepo:           sqliteRepo,
+		terminalProvider:     terminalProvider,
+		Mailer:               mailer,
+		DecimalPlaces:        decimalPlaces,
+		CurrencyCode:         currencyCode,
+		ReceiptNumberPrefix:  options.ReceiptNumberPrefix,
+		FiscalYearStartMonth: options.FiscalYearStartMonth,
+		roundingRules:        options.RoundingRules,
+		quoteKey:             quoteKey(options.QuoteSecret),
+		offlineMaxAge:        options.OfflineMaxAge,
+	}, nil
 }
 
 func (s *PurchaseService) ValidateAndCalculatePrices(
 	input PurchaseInput,
 ) (totalNetResult, totalGrossResult decimal.Decimal, err error) {
-	totalNet := decimal.NewFromInt(0)
-	totalGross := decimal.NewFromInt(0)
+	_, totalNet, totalGross, err := s.validateCart(input, currentPrices())
 
-	for _, item := range input.Cart {
-		product, err := s.sqliteRepo.GetProductByID(item.ID)
-		if err != nil || product == nil {
-			return decimal.Zero, decimal.Zero, ErrProductNotFound
+	return totalNet, totalGross, err
+}
+
+// validateCart prices the cart and checks the result against the totals of the input.
+func (s *PurchaseService) validateCart(
+	input PurchaseInput,
+	prices priceWindow,
+) (items []models.PurchaseItem, totalNet, totalGross decimal.Decimal, err error) {
+	items, totalNet, totalGross, err = s.priceCart(input, prices)
+	if err != nil {
+		return nil, decimal.Zero, decimal.Zero, err
+	}
+
+	if !totalNet.Equal(input.TotalNetPrice) {
+		return items, totalNet, totalGross, ErrInvalidTotalNetPrice
+	}
+
+	if !totalGross.Equal(input.TotalGrossPrice) {
+		return items, totalNet, totalGross, ErrInvalidTotalGrossPrice
+	}
+
+	return items, totalNet, totalGross, nil
+}
+
+func (s *PurchaseService) ValidatePayments(input PurchaseInput, totalGross decimal.Decimal) error {
+	sum := decimal.Zero
+	sumupLines := 0
+
+	for _, line := range input.PaymentLines() {
+		if line.Amount.IsNegative() {
+			return ErrInvalidPaymentAmount
+		}
+
+		if line.PaymentMethod == models.PaymentMethodSumUp {
+			sumupLines++
 		}
 
-		if !product.NetPrice.Round(s.DecimalPlaces).Equal(item.NetPrice.Round(s.DecimalPlaces)) {
-			return decimal.Zero, decimal.Zero, ErrInvalidProductPrice
+		if line.PaymentMethod == models.PaymentMethodVoucher && line.VoucherCode == "" {
+			return ErrVoucherCodeRequired
 		}
 
-		net := product.NetPrice.Mul(decimal.NewFromUint64(uint64(item.Quantity)))
-		gross := product.GrossPrice(s.DecimalPlaces).Mul(decimal.NewFromUint64(uint64(item.Quantity)))
+		sum = sum.Add(line.Amount)
+	}
 
-		totalNet = totalNet.Add(net)
-		totalGross = totalGross.Add(gross)
+	if sumupLines > 1 {
+		return ErrMultipleSumupPayments
 	}
 
-	if !totalNet.Equal(input.TotalNetPrice) {
-		return totalNet, totalGross, ErrInvalidTotalNetPrice
+	if !sum.Round(s.DecimalPlaces).Equal(totalGross.Round(s.DecimalPlaces)) {
+		return ErrInvalidPaymentTotal
 	}
 
-	if !totalGross.Equal(input.TotalGrossPrice) {
-		return totalNet, totalGross, ErrInvalidTotalGrossPrice
+	return nil
+}
+
+// checkProductPermission checks that the products of the cart belong to the categories the user is
+// restricted to.
+func (s *PurchaseService) checkProductPermission(input PurchaseInput, userID int) error {
+	if len(input.Cart) == 0 {
+		return nil
+	}
+
+	user, err := s.sqliteRepo.GetUserByID(userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	categoryIDs := user.CategoryIDs()
+	if len(categoryIDs) == 0 {
+		return nil
+	}
+
+	for _, item := range input.Cart {
+		product, err := s.sqliteRepo.GetProductByID(item.ID)
+		if err != nil || product == nil {
+			return ErrProductNotFound
+		}
+
+		if product.CategoryID == nil || !slices.Contains(categoryIDs, *product.CategoryID) {
+			return ErrProductNotAllowed
+		}
 	}
 
-	return totalNet, totalGross, nil
+	return nil
 }
 
 func (s *PurchaseService) ValidateAndPrepareGuests(input PurchaseInput) ([]models.Guest, error) {
+	return s.prepareGuests(input, false)
+}
+
+// prepareGuests validates the list items of the cart and marks their guests as arrived. With
+// skipArrived, guests who already arrived, or are listed twice, are left out instead of failing
+// the purchase.
+func (s *PurchaseService) prepareGuests(input PurchaseInput, skipArrived bool) ([]models.Guest, error) {
 	var updatedGuests []models.Guest
 
+	listed := make(map[int]bool)
+
 	for _, item := range input.Cart {
 		for _, listInput := range item.ListItems {
+			if skipArrived && listed[listInput.ID] {
+				continue
+			}
+
 			guest, err := s.validateGuest(listInput, item.ID)
+			if skipArrived && errors.Is(err, ErrGuestAlreadyAttended) {
+				continue
+			}
+
 			if err != nil {
 				return nil, err
 			}
 
+			listed[listInput.ID] = true
+
 			updatedGuests = append(updatedGuests, *guest)
 		}
 	}
@@ -160,20 +340,24 @@ func (s *PurchaseService) CreateConfirmedPurchase(
 	input PurchaseInput,
 	userID int,
 ) (*models.Purchase, error) {
-	savedPurchase, guests, err := s.createPurchaseWithStatus(ctx, input, userID, models.PurchaseStatusConfirmed)
+	if input.PrimaryPaymentMethod() == models.PaymentMethodSumUp {
+		return nil, ErrUnsettledPayment
+	}
+
+	savedPurchase, guests, err := s.createPurchaseWithStatus(
+		ctx,
+		input,
+		userID,
+		models.PurchaseStatusConfirmed,
+		purchaseOptions{},
+	)
 	if err != nil {
 		return nil, err
 	}
 
 	s.notifyGuests(guests)
 
-	s.recordTransactionMetrics(
-		ctx,
-		savedPurchase.TotalGrossPrice,
-		savedPurchase.TotalNetPrice,
-		string(savedPurchase.PaymentMethod),
-		false,
-	)
+	s.recordTransactionMetrics(ctx, savedPurchase)
 
 	return savedPurchase, nil
 }
@@ -183,16 +367,32 @@ func (s *PurchaseService) CreatePendingPurchase(
 	input PurchaseInput,
 	userID int,
 ) (*models.Purchase, error) {
-	savedPurchase, _, err := s.createPurchaseWithStatus(ctx, input, userID, models.PurchaseStatusPending)
+	savedPurchase, _, err := s.createPurchaseWithStatus(
+		ctx,
+		input,
+		userID,
+		models.PurchaseStatusPending,
+		purchaseOptions{},
+	)
 
 	return savedPurchase, err
 }
 
+// FinalizePurchase settles the outstanding payment lines of the purchase and confirms it once all
+// lines are settled, then notifies its guests. A purchase confirmed before is returned together with
+// ErrPurchaseAlreadyConfirmed, without notifying its guests again.
 func (s *PurchaseService) FinalizePurchase(ctx context.Context, purchaseID uuid.UUID) (*models.Purchase, error) {
-	// update status of purchase to confirmed
-	purchase, err := s.setPurchaseStatus(ctx, purchaseID, models.PurchaseStatusConfirmed, false)
+	purchase, err := s.settleAndConfirmPurchase(ctx, purchaseID)
+	if errors.Is(err, ErrPurchaseAlreadyConfirmed) {
+		return purchase, err
+	}
+
 	if err != nil {
-		return nil, errors.New("failed to finalize purchase: " + err.Error())
+		return nil, fmt.Errorf("failed to finalize purchase: %w", err)
+	}
+
+	if purchase.Status != models.PurchaseStatusConfirmed {
+		return purchase, nil
 	}
 
 	// notify guests
@@ -208,80 +408,98 @@ func (s *PurchaseService) FinalizePurchase(ctx context.Context, purchaseID uuid.
 		s.notifyGuests(guests)
 	}
 
-	s.recordTransactionMetrics(
-		ctx,
-		purchase.TotalGrossPrice,
-		purchase.TotalNetPrice,
-		string(purchase.PaymentMethod),
-		false,
-	)
+	s.recordTransactionMetrics(ctx, purchase)
 
 	return purchase, nil
 }
 
-func (s *PurchaseService) CancelPurchase(ctx context.Context, purchaseID uuid.UUID) (*models.Purchase, error) {
-	purchase, err := s.rollbackPurchase(ctx, purchaseID, models.PurchaseStatusCancelled)
-	if err != nil {
-		return nil, errors.New("failed to cancel purchase: " + err.Error())
-	}
+func (s *PurchaseService) settleAndConfirmPurchase(
+	ctx context.Context,
+	purchaseID uuid.UUID,
+) (*models.Purchase, error) {
+	var purchase *models.Purchase
 
-	return purchase, nil
-}
+	err := s.sqliteRepo.WithTransaction(ctx, func(txRepo sqlite.RepositoryInterface) error {
+		current, err := txRepo.GetPurchaseByID(purchaseID)
+		if err != nil {
+			return err
+		}
 
-func (s *PurchaseService) FailPurchase(ctx context.Context, purchaseID uuid.UUID) (*models.Purchase, error) {
-	purchase, err := s.rollbackPurchase(ctx, purchaseID, models.PurchaseStatusFailed)
-	if err != nil {
-		return nil, errors.New("failed to set the purchase to failed: " + err.Error())
-	}
+		// the payment may be reported twice, e.g. by the webhook and by the poller
+		if current.Status == models.PurchaseStatusConfirmed {
+			purchase = current
 
-	return purchase, nil
-}
+			return ErrPurchaseAlreadyConfirmed
+		}
 
-func (s *PurchaseService) RefundPurchase(ctx context.Context, purchaseID uuid.UUID) (*models.Purchase, error) {
-	purchase, err := s.sqliteRepo.GetPurchaseByID(purchaseID)
-	if err != nil {
-		return nil, errors.New("failed to get purchase by ID: " + err.Error())
-	}
+		// a cancelled or failed purchase stays so, even if its payment is reported late
+		if err := checkTransition(current.Status, models.PurchaseStatusConfirmed); err != nil {
+			return err
+		}
 
-	// Validate current status
-	if purchase.Status != models.PurchaseStatusConfirmed {
-		return nil, fmt.Errorf("cannot refund purchase with status: %s", purchase.Status)
-	}
+		if err := txRepo.SettlePurchasePaymentsByPurchaseID(purchaseID); err != nil {
+			return err
+		}
 
-	// refund the purchase via SumUp
-	if purchase.PaymentMethod == models.PaymentMethodSumUp && purchase.SumupTransactionID != nil {
-		slog.Debug("Refunding transaction via SumUp for transaction", "transaction_id", *purchase.SumupTransactionID)
+		p, err := txRepo.GetPurchaseByID(purchaseID)
+		if err != nil {
+			return err
+		}
 
-		if err := s.sumupRepo.RefundTransaction(*purchase.SumupTransactionID); err != nil {
-			return nil, errors.New("failed to refund purchase via sumup: " + err.Error())
+		if !p.IsFullySettled() {
+			purchase = p
+
+			return nil
 		}
-	}
 
-	// update status of purchase to refunded
-	purchase, err = s.rollbackPurchase(ctx, purchaseID, models.PurchaseStatusRefunded)
+		if p.ReceiptNumber == nil {
+			receiptNumber, err := s.nextReceiptNumber(txRepo, time.Now())
+			if err != nil {
+				return err
+			}
+
+			if err := txRepo.AssignPurchaseReceiptNumber(purchaseID, receiptNumber); err != nil {
+				return err
+			}
+		}
+
+		purchase, err = s.transitionStatus(ctx, txRepo, purchaseID, p.Status, models.PurchaseStatusConfirmed)
+
+		return err
+	})
+
+	return purchase, err
+}
+
+func (s *PurchaseService) CancelPurchase(ctx context.Context, purchaseID uuid.UUID) (*models.Purchase, error) {
+	purchase, err := s.rollbackPurchase(ctx, purchaseID, models.PurchaseStatusCancelled, nil)
 	if err != nil {
-		return nil, errors.New("failed to set the purchase to refunded: " + err.Error())
+		return nil, fmt.Errorf("failed to cancel purchase: %w", err)
 	}
 
-	s.recordTransactionMetrics(
-		ctx,
-		purchase.TotalGrossPrice,
-		purchase.TotalNetPrice,
-		string(purchase.PaymentMethod),
-		true,
-	)
+	return purchase, nil
+}
+
+func (s *PurchaseService) FailPurchase(ctx context.Context, purchaseID uuid.UUID) (*models.Purchase, error) {
+	purchase, err := s.rollbackPurchase(ctx, purchaseID, models.PurchaseStatusFailed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set the purchase to failed: %w", err)
+	}
 
 	return purchase, nil
 }
 
+// rollbackPurchase cancels or fails the purchase. A purchase given the time it expired is marked
+// as expired in the same transaction.
 func (s *PurchaseService) rollbackPurchase(
 	ctx context.Context,
 	purchaseID uuid.UUID,
 	status models.PurchaseStatus,
+	expiredAt *time.Time,
 ) (*models.Purchase, error) {
-	purchase, err := s.setPurchaseStatus(ctx, purchaseID, status, true)
+	purchase, err := s.setPurchaseStatus(ctx, purchaseID, status, true, expiredAt)
 	if err != nil {
-		return nil, errors.New("failed to rollback purchase: " + err.Error())
+		return nil, fmt.Errorf("failed to rollback purchase: %w", err)
 	}
 
 	return purchase, err
@@ -291,22 +509,52 @@ func (s *PurchaseService) setPurchaseStatus(
 	ctx context.Context,
 	purchaseID uuid.UUID,
 	status models.PurchaseStatus,
-	rollbackGuests bool,
+	rollback bool,
+	expiredAt *time.Time,
 ) (*models.Purchase, error) {
 	var purchase *models.Purchase
 
 	err := s.sqliteRepo.WithTransaction(ctx, func(txRepo sqlite.RepositoryInterface) error {
-		p, err := txRepo.UpdatePurchaseStatusByID(purchaseID, status)
+		current, err := txRepo.GetPurchaseByID(purchaseID)
+		if err != nil {
+			return err
+		}
+
+		previousStatus := current.Status
+
+		// only a pending purchase can be cancelled or failed, e.g. not one confirmed meanwhile
+		p, err := s.transitionStatus(ctx, txRepo, purchaseID, previousStatus, status)
 		if err != nil {
 			return err
 		}
 
 		purchase = p
 
-		if rollbackGuests {
+		if expiredAt != nil {
+			if err := txRepo.MarkPurchaseExpiredByID(purchaseID, *expiredAt); err != nil {
+				return err
+			}
+
+			purchase.ExpiredAt = expiredAt
+		}
+
+		if rollback {
+			if previousStatus.ReservesStock() {
+				if err := updateStock(txRepo, stockOrigin{
+					purchaseID: purchaseID,
+					note:       "Purchase " + string(status),
+				}, releasedStock(current.PurchaseItems)); err != nil {
+					return err
+				}
+			}
+
 			if err := txRepo.RollbackVisitedGuestsByPurchaseID(purchaseID); err != nil {
 				return fmt.Errorf("failed to rollback visited guests: %w", err)
 			}
+
+			if err := creditVouchers(txRepo, current); err != nil {
+				return err
+			}
 		}
 
 		return nil
@@ -367,13 +615,26 @@ func (s *PurchaseService) createPurchaseWithStatus(
 	input PurchaseInput,
 	userID int,
 	status models.PurchaseStatus,
+	options purchaseOptions,
 ) (*models.Purchase, []models.Guest, error) {
-	net, gross, err := s.ValidateAndCalculatePrices(input)
+	if err := s.checkProductPermission(input, userID); err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.checkDiscountPermission(input, userID); err != nil {
+		return nil, nil, err
+	}
+
+	items, net, gross, err := s.validateCart(input, options.prices())
 	if err != nil {
 		return nil, nil, err
 	}
 
-	guests, err := s.ValidateAndPrepareGuests(input)
+	if err := s.ValidatePayments(input, gross); err != nil {
+		return nil, nil, err
+	}
+
+	guests, err := s.prepareGuests(input, options.offline())
 	if err != nil {
 		return nil, nil, err
 	}
@@ -382,27 +643,50 @@ func (s *PurchaseService) createPurchaseWithStatus(
 
 	err = s.sqliteRepo.WithTransaction(ctx, func(txRepo sqlite.RepositoryInterface) error {
 		purchase := &models.Purchase{
+			ID:              options.purchaseID(),
 			TotalNetPrice:   net,
 			TotalGrossPrice: gross,
-			PaymentMethod:   input.PaymentMethod,
+			PaymentMethod:   input.PrimaryPaymentMethod(),
+			Payments:        buildPaymentLines(input),
+			Roundings:       s.roundPayments(input),
+			PurchaseItems:   items,
 			Status:          status,
+			Offline:         options.offline(),
+			RecordedAt:      options.recordedAt,
 		}
 		purchase.CreatedByID = intPtr(userID)
 
-		for _, item := range input.Cart {
-			product, err := txRepo.GetProductByID(item.ID)
+		if input.SumupReaderID != "" && input.HasPaymentMethod(models.PaymentMethodSumUp) {
+			purchase.SumupReaderID = &input.SumupReaderID
+		}
+
+		if status == models.PurchaseStatusConfirmed {
+			receiptNumber, err := s.nextReceiptNumber(txRepo, time.Now())
 			if err != nil {
 				return err
 			}
 
-			pi := models.PurchaseItem{
-				ProductID: product.ID,
-				Quantity:  item.Quantity,
-				NetPrice:  product.NetPrice,
-				VATRate:   product.VATRate,
+			purchase.ReceiptNumber = &receiptNumber
+		}
+
+		// cash is collected into the till of the cashier's open register session
+		if input.HasPaymentMethod(models.PaymentMethodCash) {
+			session, err := txRepo.GetOpenRegisterSessionByUserID(userID)
+			if err != nil {
+				return ErrNoOpenRegisterSession
 			}
 
-			purchase.PurchaseItems = append(purchase.PurchaseItems, pi)
+			purchase.RegisterSessionID = &session.ID
+		}
+
+		if err := redeemVouchers(txRepo, purchase, input); err != nil {
+			return err
+		}
+
+		for i := range purchase.PurchaseItems {
+			for j := range purchase.PurchaseItems[i].Discounts {
+				purchase.PurchaseItems[i].Discounts[j].PurchaseID = purchase.ID
+			}
 		}
 
 		stored, err := txRepo.StorePurchases(*purchase)
@@ -412,6 +696,26 @@ func (s *PurchaseService) createPurchaseWithStatus(
 
 		savedPurchase = &stored
 
+		if err := updateStock(txRepo, stockOrigin{
+			purchaseID: stored.ID,
+			userID:     purchase.CreatedByID,
+		}, reservedStock(stored.PurchaseItems)); err != nil {
+			return err
+		}
+
+		err = s.journal(txRepo, models.JournalEventPurchaseCreated, stored.ID, purchase.CreatedByID,
+			purchaseJournalPayload(&stored))
+		if err != nil {
+			return err
+		}
+
+		if err := recordTransition(txRepo, stored.ID, "", stored.Status, TransitionOrigin{
+			Source:  models.PurchaseTransitionSourceUser,
+			ActorID: purchase.CreatedByID,
+		}); err != nil {
+			return err
+		}
+
 		for _, guest := range guests {
 			guest.PurchaseID = &stored.ID
 			if _, err := txRepo.UpdateGuestByID(guest.ID, guest); err != nil {
@@ -428,7 +732,142 @@ func (s *PurchaseService) createPurchaseWithStatus(
 	return savedPurchase, guests, nil
 }
 
+// redeemVouchers takes the amounts of the voucher payment lines from the vouchers and links the
+// lines to them. The payment lines of the purchase are in the order of the input lines.
+func redeemVouchers(txRepo sqlite.RepositoryInterface, purchase *models.Purchase, input PurchaseInput) error {
+	for i, line := range input.PaymentLines() {
+		if line.PaymentMethod != models.PaymentMethodVoucher {
+			continue
+		}
+
+		voucher, err := txRepo.RedeemVoucher(line.VoucherCode, line.Amount, purchase.ID)
+		if err != nil {
+			return err
+		}
+
+		purchase.Payments[i].VoucherID = &voucher.ID
+	}
+
+	return nil
+}
+
+// creditVouchers credits the voucher payment lines of a purchase that is rolled back or deleted
+// back to the vouchers. Amounts already credited by refunds are not credited twice.
+func creditVouchers(txRepo sqlite.RepositoryInterface, purchase *models.Purchase) error {
+	amount := decimal.Zero
+
+	for _, payment := range purchase.PaymentLines() {
+		if payment.PaymentMethod == models.PaymentMethodVoucher {
+			amount = amount.Add(payment.Amount)
+		}
+	}
+
+	if !amount.IsPositive() {
+		return nil
+	}
+
+	if err := txRepo.CreditVoucherRedemptions(purchase.ID, amount, nil); err != nil {
+		return fmt.Errorf("failed to credit the vouchers: %w", err)
+	}
+
+	return nil
+}
+
+// nextReceiptNumber takes the next number from the receipt number sequence of the fiscal year.
+func (s *PurchaseService) nextReceiptNumber(txRepo sqlite.RepositoryInterface, at time.Time) (string, error) {
+	fiscalYear := models.FiscalYear(at, s.FiscalYearStartMonth)
+
+	number, err := txRepo.NextReceiptNumber(s.ReceiptNumberPrefix, fiscalYear)
+	if err != nil {
+		return "", err
+	}
+
+	return models.FormatReceiptNumber(s.ReceiptNumberPrefix, fiscalYear, number), nil
+}
+
+func buildPaymentLines(input PurchaseInput) []models.PurchasePayment {
+	now := time.Now()
+	lines := input.PaymentLines()
+	payments := make([]models.PurchasePayment, 0, len(lines))
+
+	for _, line := range lines {
+		payment := models.PurchasePayment{
+			PaymentMethod: line.PaymentMethod,
+			Amount:        line.Amount,
+		}
+
+		if !payment.RequiresSettlement() {
+			payment.SettledAt = &now
+		}
+
+		payments = append(payments, payment)
+	}
+
+	return payments
+}
+
+// roundPayments returns the rounding lines of the payment methods with a rounding rule. The payment
+// lines keep their exact amounts, the lines of a payment method are rounded together.
+func (s *PurchaseService) roundPayments(input PurchaseInput) []models.PurchaseRounding {
+	var roundings []models.PurchaseRounding
+
+	for _, line := range input.PaymentLines() {
+		if _, ok := s.roundingRules[line.PaymentMethod]; !ok {
+			continue
+		}
+
+		i := slices.IndexFunc(roundings, func(rounding models.PurchaseRounding) bool {
+			return rounding.PaymentMethod == line.PaymentMethod
+		})
+		if i < 0 {
+			roundings = append(roundings, models.PurchaseRounding{
+				PaymentMethod: line.PaymentMethod,
+				Amount:        decimal.Zero,
+			})
+			i = len(roundings) - 1
+		}
+
+		roundings[i].Amount = roundings[i].Amount.Add(line.Amount)
+	}
+
+	// so far the lines hold the exact amounts paid, which are replaced by their rounding
+	for i, rounding := range roundings {
+		exact := rounding.Amount
+		roundings[i].Amount = s.roundingRules[rounding.PaymentMethod].Round(exact).Sub(exact)
+	}
+
+	return slices.DeleteFunc(roundings, func(rounding models.PurchaseRounding) bool {
+		return rounding.Amount.IsZero()
+	})
+}
+
 func (s *PurchaseService) recordTransactionMetrics(
+	ctx context.Context,
+	purchase *models.Purchase,
+) {
+	// amounts are broken down by payment line, the net amount is split by each line's share
+	for _, payment := range purchase.PaymentLines() {
+		net := purchase.TotalNetPrice.Mul(purchase.PaymentShare(payment.Amount))
+
+		s.recordSalesAmount(ctx, payment.Amount, net, string(payment.PaymentMethod), false)
+	}
+
+	recordOrder(ctx, purchase.PaymentMethod, false)
+}
+
+func recordOrder(ctx context.Context, method models.PaymentMethod, isRefund bool) {
+	entryType := "purchase"
+	if isRefund {
+		entryType = "refund"
+	}
+
+	salesOrdersCounter.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("payment_method", string(method)),
+		attribute.String("type", entryType),
+	))
+}
+
+func (s *PurchaseService) recordSalesAmount(
 	ctx context.Context,
 	gross, net decimal.Decimal,
 	method string,
@@ -446,7 +885,7 @@ func (s *PurchaseService) recordTransactionMetrics(
 	}
 
 	grossSubUnits := gross.Mul(multiplier).IntPart() * direction
-	netSubUnits := net.Mul(multiplier).IntPart() * direction
+	netSubUnits := net.Mul(multiplier).Round(0).IntPart() * direction
 
 	commonAttrs := []attribute.KeyValue{
 		attribute.String("type", entryType),
@@ -463,9 +902,4 @@ func (s *PurchaseService) recordTransactionMetrics(
 	salesAmountCounter.Add(ctx, netSubUnits, metric.WithAttributes(
 		append(commonAttrs, attribute.String("tax_status", "net"))...,
 	))
-
-	salesOrdersCounter.Add(ctx, 1, metric.WithAttributes(
-		attribute.String("payment_method", method),
-		attribute.String("type", entryType),
-	))
 }
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/potibm/kasseapparat/internal/app/models"
@@ -21,6 +23,24 @@ type MockRepository struct {
 	Guests         map[int]*models.Guest
 	StoredPurchase *models.Purchase
 	UpdatedGuests  map[int]*models.Guest
+	StoredRefunds  []models.PurchaseRefund
+	OpenSession    *models.RegisterSession
+	ReceiptNumbers map[string]uint
+	Journal        []models.JournalEntry
+	Vouchers       map[string]*models.Voucher
+	Redemptions    []models.VoucherRedemption
+	Discounts      map[int]*models.Discount
+	Users          map[int]*models.User
+	// StockOnHand is the stock on hand per product before the recorded inventory movements
+	StockOnHand        map[int]int
+	InventoryMovements []models.InventoryMovement
+	PriceChanges       []models.ProductPriceChange
+	StoredPurchaseIDs  []uuid.UUID
+	Transitions        []models.PurchaseStatusTransition
+	// DeletePurchaseErr is returned instead of deleting the purchase
+	DeletePurchaseErr error
+	// transactionDepth is the number of transactions the mock is running in
+	transactionDepth int
 }
 
 const errNotImplemented = "not implemented"
@@ -28,6 +48,9 @@ const errNotImplemented = "not implemented"
 var _ sqlite.RepositoryInterface = (*MockRepository)(nil)
 
 func (m *MockRepository) WithTransaction(ctx context.Context, fn func(repo sqlite.RepositoryInterface) error) error {
+	m.transactionDepth++
+	defer func() { m.transactionDepth-- }()
+
 	return fn(m)
 }
 
@@ -47,7 +70,7 @@ func (m *MockRepository) UpdatePurchaseStatusByID(
 func (m *MockRepository) GetProductByID(id int) (*models.Product, error) {
 	p, ok := m.Products[id]
 	if !ok {
-		return nil, errors.New("not found")
+		return nil, sqlite.ErrProductNotFound
 	}
 
 	return p, nil
@@ -63,12 +86,20 @@ func (m *MockRepository) GetFullGuestByID(id int) (*models.Guest, error) {
 }
 
 func (m *MockRepository) StorePurchases(purchase models.Purchase) (models.Purchase, error) {
-	purchase.ID = uuid.New()
+	if purchase.ID == uuid.Nil {
+		purchase.ID = uuid.New()
+	}
+
 	m.StoredPurchase = &purchase
+	m.StoredPurchaseIDs = append(m.StoredPurchaseIDs, purchase.ID)
 
 	return purchase, nil
 }
 
+func (m *MockRepository) PurchaseExists(id uuid.UUID) (bool, error) {
+	return slices.Contains(m.StoredPurchaseIDs, id), nil
+}
+
 func (m *MockRepository) UpdateGuestByID(id int, guest models.Guest) (*models.Guest, error) {
 	g, ok := m.Guests[id]
 	if !ok || g == nil {
@@ -212,6 +243,10 @@ func (m *MockRepository) GetProductInterestCountByProductID(productID int) (int,
 	panic(errNotImplemented)
 }
 
+func (m *MockRepository) GetProductInterestCountByVariantID(variantID int) (int, error) {
+	panic(errNotImplemented)
+}
+
 func (m *MockRepository) GetProductStats() ([]response.ProductStats, error) {
 	panic(errNotImplemented)
 }
@@ -221,12 +256,12 @@ func (m *MockRepository) GetProducts(
 	offset int,
 	sort string,
 	order string,
-	ids []int,
+	filters sqlite.ProductFilters,
 ) ([]models.Product, error) {
 	panic(errNotImplemented)
 }
 
-func (m *MockRepository) GetTotalProducts() (int64, error) {
+func (m *MockRepository) GetTotalProducts(filters sqlite.ProductFilters) (int64, error) {
 	panic(errNotImplemented)
 }
 
@@ -246,15 +281,31 @@ func (m *MockRepository) GetAttendedGuestSumByProductID(productID int) (int, err
 	panic(errNotImplemented)
 }
 
-func (m *MockRepository) DeletePurchaseByID(id uuid.UUID, deletedBy models.User) {
-	panic(errNotImplemented)
+func (m *MockRepository) DeletePurchaseByID(id uuid.UUID, deletedBy models.User) error {
+	if m.DeletePurchaseErr != nil {
+		return m.DeletePurchaseErr
+	}
+
+	if m.StoredPurchase == nil || m.StoredPurchase.ID != id {
+		return fmt.Errorf("purchase %s not found in mock", id)
+	}
+
+	m.StoredPurchase.DeletedByID = &deletedBy.ID
+
+	return nil
 }
 
 func (m *MockRepository) UpdatePurchaseSumupTransactionIDByID(
 	id,
 	sumupTransactionID uuid.UUID,
 ) (*models.Purchase, error) {
-	panic(errNotImplemented)
+	if m.StoredPurchase == nil || m.StoredPurchase.ID != id {
+		return nil, fmt.Errorf("purchase %s not found in mock", id)
+	}
+
+	m.StoredPurchase.SumupTransactionID = &sumupTransactionID
+
+	return m.StoredPurchase, nil
 }
 
 func (m *MockRepository) UpdatePurchaseSumupClientTransactionIDByID(
@@ -290,10 +341,129 @@ func (m *MockRepository) GetPurchasedQuantitiesByProductID(productID int) (int,
 	panic(errNotImplemented)
 }
 
-func (m *MockRepository) GetUserByID(id int) (*models.User, error) {
+func (m *MockRepository) GetPurchasedQuantitiesByVariantID(variantID int) (int, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) GetReservedQuantitiesByProductID(productID int) (int, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) GetReservedQuantitiesByVariantID(variantID int) (int, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) SetProductSoldOut(productID int, soldOut bool) error {
+	m.Products[productID].SoldOut = soldOut
+
+	return nil
+}
+
+func (m *MockRepository) SetProductVariantSoldOut(variantID int, soldOut bool) error {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) StoreProductPriceChanges(changes []models.ProductPriceChange) error {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) GetProductPriceChangesSince(
+	productID int,
+	since time.Time,
+) ([]models.ProductPriceChange, error) {
+	var changes []models.ProductPriceChange
+
+	for _, change := range m.PriceChanges {
+		if change.ProductID == productID && change.CreatedAt.After(since) {
+			changes = append(changes, change)
+		}
+	}
+
+	return changes, nil
+}
+
+func (m *MockRepository) ReserveIdempotencyKey(
+	key models.IdempotencyKey,
+) (models.IdempotencyKey, bool, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) CompleteIdempotencyKey(
+	id int,
+	statusCode int,
+	contentType, body string,
+	expiresAt time.Time,
+) error {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) ReleaseIdempotencyKey(id int) error {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) DeleteExpiredIdempotencyKeys(before time.Time) (int64, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) GetInventoryMovements(
+	limit int,
+	offset int,
+	filters sqlite.InventoryMovementFilters,
+) ([]models.InventoryMovement, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) GetTotalInventoryMovements(filters sqlite.InventoryMovementFilters) (int64, error) {
 	panic(errNotImplemented)
 }
 
+func (m *MockRepository) GetStockOnHand(productID int, variantID *int) (int, error) {
+	onHand := 0
+	if variantID == nil {
+		onHand = m.StockOnHand[productID]
+	}
+
+	for _, movement := range m.InventoryMovements {
+		if movement.ProductID == productID && equalIntPtr(movement.VariantID, variantID) {
+			onHand += movement.Quantity
+		}
+	}
+
+	return onHand, nil
+}
+
+func (m *MockRepository) StoreInventoryMovement(movement models.InventoryMovement) (models.InventoryMovement, error) {
+	movement.ID = len(m.InventoryMovements) + 1
+	m.InventoryMovements = append(m.InventoryMovements, movement)
+
+	return movement, nil
+}
+
+func (m *MockRepository) RecordInventoryMovement(movement models.InventoryMovement) (models.InventoryMovement, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) BackfillInventoryMovements() error {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) GetUserByID(id int) (*models.User, error) {
+	// without users every user is an unrestricted cashier
+	if m.Users == nil {
+		user := &models.User{}
+		user.ID = id
+
+		return user, nil
+	}
+
+	user, ok := m.Users[id]
+	if !ok {
+		return nil, sqlite.ErrUserNotFound
+	}
+
+	return user, nil
+}
+
 func (m *MockRepository) GetUsers(
 	limit int,
 	offset int,
@@ -337,199 +507,859 @@ func (m *MockRepository) GetPurchaseBySumupClientTransactionID(sumupTransactionI
 }
 
 func (m *MockRepository) GetGuestsByPurchaseID(purchaseID uuid.UUID) ([]models.Guest, error) {
-	panic(errNotImplemented)
-}
+	var guests []models.Guest
 
-type MockMailer struct {
-	Sent []string
-}
+	for _, guest := range m.Guests {
+		if guest.PurchaseID != nil && *guest.PurchaseID == purchaseID {
+			guests = append(guests, *guest)
+		}
+	}
 
-func (m *MockMailer) SendNotificationOnArrival(email, name string) error {
-	m.Sent = append(m.Sent, email+"|"+name)
+	if len(guests) == 0 {
+		return nil, sqlite.ErrGuestsNotFound
+	}
 
-	return nil
+	return guests, nil
 }
 
-func TestValidateAndCalculatePricesWithSuccess(t *testing.T) {
-	mockRepo := &MockRepository{
-		Products: map[int]*models.Product{
-			1: {
-				NetPrice: decimal.NewFromFloat(10.00),
-				VATRate:  decimal.NewFromInt(19),
-			},
-		},
-	}
+func (m *MockRepository) StorePurchaseRefund(refund models.PurchaseRefund) (models.PurchaseRefund, error) {
+	refund.ID = len(m.StoredRefunds) + 1
+	m.StoredRefunds = append(m.StoredRefunds, refund)
 
-	service := &PurchaseService{
-		sqliteRepo:    mockRepo,
-		DecimalPlaces: 2,
-	}
+	if m.StoredPurchase != nil && m.StoredPurchase.ID == refund.PurchaseID {
+		m.StoredPurchase.Refunds = append(m.StoredPurchase.Refunds, refund)
 
-	input := PurchaseInput{
-		Cart: []PurchaseCartItem{
-			{ID: 1, Quantity: 2, NetPrice: decimal.NewFromFloat(10.00)},
-		},
-		TotalNetPrice:   decimal.NewFromFloat(20.00),
-		TotalGrossPrice: decimal.NewFromFloat(23.80),
+		for _, refundItem := range refund.Items {
+			for i := range m.StoredPurchase.PurchaseItems {
+				if m.StoredPurchase.PurchaseItems[i].ID == refundItem.PurchaseItemID {
+					m.StoredPurchase.PurchaseItems[i].Refunds = append(
+						m.StoredPurchase.PurchaseItems[i].Refunds,
+						refundItem,
+					)
+				}
+			}
+		}
 	}
 
-	net, gross, err := service.ValidateAndCalculatePrices(input)
-	if err != nil {
-		t.Errorf(errUnexpected, err)
-	}
+	return refund, nil
+}
 
-	if !net.Equal(decimal.NewFromFloat(20.00)) {
-		t.Errorf("unexpected net total: %s", net)
-	}
+func (m *MockRepository) GetPurchaseRefundByID(id int) (*models.PurchaseRefund, error) {
+	for i := range m.StoredRefunds {
+		if m.StoredRefunds[i].ID == id {
+			refund := m.StoredRefunds[i]
 
-	if !gross.Equal(decimal.NewFromFloat(23.80)) {
-		t.Errorf("unexpected gross total: %s", gross)
+			return &refund, nil
+		}
 	}
-}
 
-func TestValidateAndCalculatePricesWithProductNotFound(t *testing.T) {
-	mockRepo := &MockRepository{
-		Products: map[int]*models.Product{},
-	}
+	return nil, sqlite.ErrPurchaseRefundNotFound
+}
 
-	service := &PurchaseService{
-		sqliteRepo:    mockRepo,
-		DecimalPlaces: 2,
-	}
+func (m *MockRepository) GetUnsettledPurchaseRefunds() ([]models.PurchaseRefund, error) {
+	var refunds []models.PurchaseRefund
 
-	input := PurchaseInput{
-		Cart: []PurchaseCartItem{
-			{ID: 999, Quantity: 1, NetPrice: decimal.NewFromFloat(10.00)},
-		},
-		TotalNetPrice:   decimal.NewFromFloat(10.00),
-		TotalGrossPrice: decimal.NewFromFloat(11.90),
+	for _, refund := range m.StoredRefunds {
+		if !refund.IsSettled() {
+			refunds = append(refunds, refund)
+		}
 	}
 
-	_, _, err := service.ValidateAndCalculatePrices(input)
-	if err == nil || err != ErrProductNotFound {
-		t.Errorf("expected ErrProductNotFound, got: %v", err)
-	}
+	return refunds, nil
 }
 
-func TestValidateAndCalculatePricesWithTotalPriceMismatch(t *testing.T) {
-	mockRepo := &MockRepository{
-		Products: map[int]*models.Product{
-			1: {
-				NetPrice: decimal.NewFromFloat(10.00),
-				VATRate:  decimal.NewFromInt(19),
-			},
-		},
-	}
+func (m *MockRepository) ClaimPurchaseRefundSettlement(id int) (bool, error) {
+	settlingAt := time.Now()
 
-	service := &PurchaseService{
-		sqliteRepo:    mockRepo,
-		DecimalPlaces: 2,
-	}
+	for i := range m.StoredRefunds {
+		if m.StoredRefunds[i].ID == id && !m.StoredRefunds[i].IsSettled() && m.StoredRefunds[i].SettlingAt == nil {
+			m.StoredRefunds[i].SettlingAt = &settlingAt
 
-	input := PurchaseInput{
-		Cart: []PurchaseCartItem{
-			{ID: 1, Quantity: 1, NetPrice: decimal.NewFromFloat(10.00)},
-		},
-		TotalNetPrice:   decimal.NewFromFloat(15.00), // wrong
-		TotalGrossPrice: decimal.NewFromFloat(17.85), // wrong
+			return true, nil
+		}
 	}
 
-	_, _, err := service.ValidateAndCalculatePrices(input)
-	if err == nil || err != ErrInvalidTotalNetPrice {
-		t.Errorf("expected ErrInvalidTotalNetPrice, got: %v", err)
-	}
+	return false, nil
 }
 
-func TestValidateAndCalculatePricesWithProductPriceMismatch(t *testing.T) {
-	mockRepo := &MockRepository{
-		Products: map[int]*models.Product{
-			1: {
-				NetPrice: decimal.NewFromFloat(10.00),
-				VATRate:  decimal.NewFromInt(19),
-			},
-		},
+func (m *MockRepository) ReleasePurchaseRefundSettlement(id int) error {
+	for i := range m.StoredRefunds {
+		if m.StoredRefunds[i].ID == id && !m.StoredRefunds[i].IsSettled() {
+			m.StoredRefunds[i].SettlingAt = nil
+		}
 	}
 
-	service := &PurchaseService{
-		sqliteRepo:    mockRepo,
-		DecimalPlaces: 2,
-	}
+	return nil
+}
 
-	input := PurchaseInput{
-		Cart: []PurchaseCartItem{
-			{ID: 1, Quantity: 1, NetPrice: decimal.NewFromFloat(15.00)},
-		},
-		TotalNetPrice:   decimal.NewFromFloat(10.00),
-		TotalGrossPrice: decimal.NewFromFloat(11.90),
-	}
+func (m *MockRepository) SettlePurchaseRefundByID(id int) error {
+	settledAt := time.Now()
 
-	_, _, err := service.ValidateAndCalculatePrices(input)
-	if err == nil || err != ErrInvalidProductPrice {
-		t.Errorf("expected ErrInvalidProductPrice, got: %v", err)
+	for i := range m.StoredRefunds {
+		if m.StoredRefunds[i].ID == id {
+			m.StoredRefunds[i].SettledAt = &settledAt
+			m.StoredRefunds[i].SettlingAt = nil
+		}
 	}
-}
 
-func TestValidateAndPrepareGuestsWithSuccess(t *testing.T) {
-	mockRepo := &MockRepository{
-		Guests: map[int]*models.Guest{
-			42: {
-				AttendedGuests:   0,
-				AdditionalGuests: 1,
-				Guestlist: models.Guestlist{
-					ProductID: 1,
-				},
-			},
-		},
+	if m.StoredPurchase != nil {
+		for i := range m.StoredPurchase.Refunds {
+			if m.StoredPurchase.Refunds[i].ID == id {
+				m.StoredPurchase.Refunds[i].SettledAt = &settledAt
+			}
+		}
 	}
 
-	service := &PurchaseService{
-		sqliteRepo: mockRepo,
-	}
+	return nil
+}
 
-	input := PurchaseInput{
-		Cart: []PurchaseCartItem{
-			{
-				ID:       1,
-				Quantity: 1,
-				ListItems: []ListItemInput{
-					{ID: 42, AttendedGuests: 1},
-				},
-			},
-		},
+func (m *MockRepository) SettlePurchasePaymentsByPurchaseID(purchaseID uuid.UUID) error {
+	if m.StoredPurchase == nil || m.StoredPurchase.ID != purchaseID {
+		return fmt.Errorf("purchase %s not found in mock", purchaseID)
 	}
 
-	guests, err := service.ValidateAndPrepareGuests(input)
-	if err != nil {
-		t.Errorf(errUnexpected, err)
-	}
+	now := time.Now()
 
-	if len(guests) != 1 {
-		t.Errorf("expected 1 updated guest, got %d", len(guests))
+	for i := range m.StoredPurchase.Payments {
+		if m.StoredPurchase.Payments[i].SettledAt == nil {
+			m.StoredPurchase.Payments[i].SettledAt = &now
+		}
 	}
 
-	if guests[0].AttendedGuests != 1 {
-		t.Errorf("expected guest to have AttendedGuests = 1, got %d", guests[0].AttendedGuests)
-	}
+	return nil
 }
 
-func TestValidateAndPrepareGuestsWithGuestNotFound(t *testing.T) {
-	service := &PurchaseService{
-		sqliteRepo: &MockRepository{Guests: map[int]*models.Guest{}}, // leer!
+func (m *MockRepository) MarkPurchaseExpiredByID(id uuid.UUID, expiredAt time.Time) error {
+	if m.StoredPurchase == nil || m.StoredPurchase.ID != id {
+		return fmt.Errorf("purchase %s not found in mock", id)
 	}
 
-	input := PurchaseInput{
-		Cart: []PurchaseCartItem{
-			{
-				ID:       1,
-				Quantity: 1,
-				ListItems: []ListItemInput{
-					{ID: 99, AttendedGuests: 1},
-				},
-			},
-		},
+	// the expiry is recorded together with the status change
+	if m.transactionDepth == 0 {
+		return fmt.Errorf("purchase %s marked as expired outside a transaction", id)
 	}
 
-	_, err := service.ValidateAndPrepareGuests(input)
+	m.StoredPurchase.ExpiredAt = &expiredAt
+
+	return nil
+}
+
+func (m *MockRepository) GetPaymentMethodStats() ([]sqlite.PaymentMethodStats, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) GetRegisterSessions(
+	limit int,
+	offset int,
+	sort string,
+	order string,
+	filters sqlite.RegisterSessionFilters,
+) ([]models.RegisterSession, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) GetTotalRegisterSessions(filters sqlite.RegisterSessionFilters) (int64, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) GetRegisterSessionByID(id int) (*models.RegisterSession, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) GetOpenRegisterSessionByUserID(userID int) (*models.RegisterSession, error) {
+	if m.OpenSession == nil || m.OpenSession.CreatedByID == nil || *m.OpenSession.CreatedByID != userID {
+		return nil, sqlite.ErrRegisterSessionNotFound
+	}
+
+	return m.OpenSession, nil
+}
+
+func (m *MockRepository) CreateRegisterSession(session models.RegisterSession) (models.RegisterSession, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) CloseRegisterSession(
+	session models.RegisterSession,
+	countedCash decimal.Decimal,
+) (*models.RegisterSession, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) GetRegisterSessionCashTotals(sessionID int) (sqlite.RegisterSessionCashTotals, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) GetClosingReportFigures(
+	periodStart time.Time,
+	periodEnd time.Time,
+) (models.ClosingReportFigures, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) GetClosingReports(
+	limit int,
+	offset int,
+	sort string,
+	order string,
+) ([]models.ClosingReport, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) GetTotalClosingReports() (int64, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) GetClosingReportByID(id int) (*models.ClosingReport, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) StoreClosingReport(report models.ClosingReport) (*models.ClosingReport, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) StorePurchaseReceiptMail(
+	mail models.PurchaseReceiptMail,
+) (models.PurchaseReceiptMail, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) ErasePurchaseReceiptMailAddresses(sentBefore time.Time) (int64, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) NextReceiptNumber(prefix string, fiscalYear int) (uint, error) {
+	if m.ReceiptNumbers == nil {
+		m.ReceiptNumbers = make(map[string]uint)
+	}
+
+	key := fmt.Sprintf("%s-%d", prefix, fiscalYear)
+	m.ReceiptNumbers[key]++
+
+	return m.ReceiptNumbers[key], nil
+}
+
+func (m *MockRepository) AssignPurchaseReceiptNumber(purchaseID uuid.UUID, receiptNumber string) error {
+	if m.StoredPurchase == nil || m.StoredPurchase.ID != purchaseID {
+		return fmt.Errorf("purchase %s not found in mock", purchaseID)
+	}
+
+	m.StoredPurchase.ReceiptNumber = &receiptNumber
+
+	return nil
+}
+
+func (m *MockRepository) StorePurchaseStatusTransition(transition models.PurchaseStatusTransition) error {
+	m.Transitions = append(m.Transitions, transition)
+
+	return nil
+}
+
+func (m *MockRepository) AppendJournalEntry(entry models.JournalEntry) (models.JournalEntry, error) {
+	var previous *models.JournalEntry
+	if len(m.Journal) > 0 {
+		previous = &m.Journal[len(m.Journal)-1]
+	}
+
+	entry.CreatedAt = time.Now()
+	entry.Chain(previous)
+	m.Journal = append(m.Journal, entry)
+
+	return entry, nil
+}
+
+func (m *MockRepository) GetJournalEntries(afterSequence uint, limit int) ([]models.JournalEntry, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) GetVouchers(
+	limit int,
+	offset int,
+	sort string,
+	order string,
+	filters sqlite.VoucherFilters,
+) ([]models.Voucher, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) GetTotalVouchers(filters sqlite.VoucherFilters) (int64, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) GetVoucherByID(id int) (*models.Voucher, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) CreateVouchers(vouchers []models.Voucher) ([]models.Voucher, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) UpdateVoucherByID(id int, updatedVoucher models.Voucher) (*models.Voucher, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) DeleteVoucher(voucher models.Voucher, deletedBy models.User) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) RedeemVoucher(
+	code string,
+	amount decimal.Decimal,
+	purchaseID uuid.UUID,
+) (*models.Voucher, error) {
+	voucher, ok := m.Vouchers[code]
+	if !ok {
+		return nil, sqlite.ErrVoucherNotFound
+	}
+
+	if err := voucher.CheckRedeemable(amount, time.Now()); err != nil {
+		return nil, err
+	}
+
+	voucher.Balance = voucher.Balance.Sub(amount)
+	m.Redemptions = append(m.Redemptions, models.VoucherRedemption{
+		VoucherID:  voucher.ID,
+		PurchaseID: purchaseID,
+		Amount:     amount,
+	})
+
+	return voucher, nil
+}
+
+func (m *MockRepository) CreditVoucherRedemptions(
+	purchaseID uuid.UUID,
+	amount decimal.Decimal,
+	purchaseRefundID *int,
+) error {
+	for _, voucher := range m.Vouchers {
+		for _, redemption := range m.Redemptions {
+			if redemption.VoucherID == voucher.ID && redemption.PurchaseID == purchaseID {
+				credit := decimal.Min(amount, redemption.Amount)
+				voucher.Balance = voucher.Balance.Add(credit)
+				amount = amount.Sub(credit)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m *MockRepository) GetCategories(
+	limit int,
+	offset int,
+	sort string,
+	order string,
+	filters sqlite.CategoryFilters,
+) ([]models.Category, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) GetTotalCategories(filters sqlite.CategoryFilters) (int64, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) GetCategoryByID(id int) (*models.Category, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) CreateCategory(category models.Category) (models.Category, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) UpdateCategoryByID(id int, updatedCategory models.Category) (*models.Category, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) DeleteCategory(category models.Category, deletedBy models.User) error {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) GetDiscounts(
+	limit int,
+	offset int,
+	sort string,
+	order string,
+	filters sqlite.DiscountFilters,
+) ([]models.Discount, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) GetTotalDiscounts(filters sqlite.DiscountFilters) (int64, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) GetDiscountByID(id int) (*models.Discount, error) {
+	discount, ok := m.Discounts[id]
+	if !ok {
+		return nil, sqlite.ErrDiscountNotFound
+	}
+
+	return discount, nil
+}
+
+func (m *MockRepository) GetDiscountByCode(code string) (*models.Discount, error) {
+	for _, discount := range m.Discounts {
+		if discount.Code != nil && *discount.Code == models.NormalizeDiscountCode(code) {
+			return discount, nil
+		}
+	}
+
+	return nil, sqlite.ErrDiscountNotFound
+}
+
+func (m *MockRepository) CreateDiscount(discount models.Discount) (models.Discount, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) UpdateDiscountByID(id int, updatedDiscount models.Discount) (*models.Discount, error) {
+	panic(errNotImplemented)
+}
+
+func (m *MockRepository) DeleteDiscount(discount models.Discount, deletedBy models.User) {
+	panic(errNotImplemented)
+}
+
+type MockMailer struct {
+	Sent []string
+}
+
+func (m *MockMailer) SendNotificationOnArrival(email, name string) error {
+	m.Sent = append(m.Sent, email+"|"+name)
+
+	return nil
+}
+
+// mockRepositoryOption fills the mock repository of a purchase service built for a test.
+type mockRepositoryOption func(*MockRepository)
+
+// newMockPurchaseService returns a purchase service on a mock repository that holds the shirt,
+// product 1 at a net price of 10 with 19% VAT, and is filled by the options.
+func newMockPurchaseService(options ...mockRepositoryOption) (*PurchaseService, *MockRepository) {
+	p := &models.Product{
+		Name:     "Shirt",
+		NetPrice: decimal.NewFromFloat(10.00),
+		VATRate:  decimal.NewFromFloat(19),
+	}
+	p.ID = 1
+
+	mockRepo := &MockRepository{Products: map[int]*models.Product{1: p}}
+	for _, option := range options {
+		option(mockRepo)
+	}
+
+	return &PurchaseService{sqliteRepo: mockRepo, DecimalPlaces: 2}, mockRepo
+}
+
+// withStock tracks the stock of the shirt, with the quantity on hand.
+func withStock(onHand int) mockRepositoryOption {
+	return func(m *MockRepository) {
+		m.Products[1].TotalStock = onHand
+		m.Products[1].TrackStock = true
+		m.StockOnHand = map[int]int{1: onHand}
+	}
+}
+
+func withVoucher(voucher *models.Voucher) mockRepositoryOption {
+	return func(m *MockRepository) {
+		m.Vouchers = map[string]*models.Voucher{voucher.Code: voucher}
+	}
+}
+
+func withDiscount(discount *models.Discount) mockRepositoryOption {
+	return func(m *MockRepository) {
+		m.Discounts = map[int]*models.Discount{discount.ID: discount}
+	}
+}
+
+// withGuest stores a guest on a guestlist of the shirt.
+func withGuest(id int) mockRepositoryOption {
+	return func(m *MockRepository) {
+		guest := &models.Guest{Guestlist: models.Guestlist{ProductID: 1}}
+		guest.ID = id
+
+		m.Guests = map[int]*models.Guest{id: guest}
+	}
+}
+
+// withPriceChange records that the net price of the shirt changed at the given time,
+// and sets the shirt to the new price.
+func withPriceChange(previousPrice, price decimal.Decimal, at time.Time) mockRepositoryOption {
+	return func(m *MockRepository) {
+		m.Products[1].NetPrice = price
+		m.PriceChanges = append(m.PriceChanges, models.ProductPriceChange{
+			CreatedAt:        at,
+			ProductID:        1,
+			PreviousNetPrice: &previousPrice,
+			NetPrice:         &price,
+		})
+	}
+}
+
+// withCashier stores the user with the open register session 3.
+func withCashier(user *models.User) mockRepositoryOption {
+	return func(m *MockRepository) {
+		session := &models.RegisterSession{Status: models.RegisterSessionStatusOpen}
+		session.ID = 3
+		session.CreatedByID = intPtr(user.ID)
+
+		m.Users = map[int]*models.User{user.ID: user}
+		m.OpenSession = session
+	}
+}
+
+func TestValidateAndCalculatePricesWithSuccess(t *testing.T) {
+	mockRepo := &MockRepository{
+		Products: map[int]*models.Product{
+			1: {
+				NetPrice: decimal.NewFromFloat(10.00),
+				VATRate:  decimal.NewFromInt(19),
+			},
+		},
+	}
+
+	service := &PurchaseService{
+		sqliteRepo:    mockRepo,
+		DecimalPlaces: 2,
+	}
+
+	input := PurchaseInput{
+		Cart: []PurchaseCartItem{
+			{ID: 1, Quantity: 2, NetPrice: decimal.NewFromFloat(10.00)},
+		},
+		TotalNetPrice:   decimal.NewFromFloat(20.00),
+		TotalGrossPrice: decimal.NewFromFloat(23.80),
+	}
+
+	net, gross, err := service.ValidateAndCalculatePrices(input)
+	if err != nil {
+		t.Errorf(errUnexpected, err)
+	}
+
+	if !net.Equal(decimal.NewFromFloat(20.00)) {
+		t.Errorf("unexpected net total: %s", net)
+	}
+
+	if !gross.Equal(decimal.NewFromFloat(23.80)) {
+		t.Errorf("unexpected gross total: %s", gross)
+	}
+}
+
+func TestValidateAndCalculatePricesWithProductNotFound(t *testing.T) {
+	mockRepo := &MockRepository{
+		Products: map[int]*models.Product{},
+	}
+
+	service := &PurchaseService{
+		sqliteRepo:    mockRepo,
+		DecimalPlaces: 2,
+	}
+
+	input := PurchaseInput{
+		Cart: []PurchaseCartItem{
+			{ID: 999, Quantity: 1, NetPrice: decimal.NewFromFloat(10.00)},
+		},
+		TotalNetPrice:   decimal.NewFromFloat(10.00),
+		TotalGrossPrice: decimal.NewFromFloat(11.90),
+	}
+
+	_, _, err := service.ValidateAndCalculatePrices(input)
+	if err == nil || err != ErrProductNotFound {
+		t.Errorf("expected ErrProductNotFound, got: %v", err)
+	}
+}
+
+func TestValidateAndCalculatePricesWithTotalPriceMismatch(t *testing.T) {
+	mockRepo := &MockRepository{
+		Products: map[int]*models.Product{
+			1: {
+				NetPrice: decimal.NewFromFloat(10.00),
+				VATRate:  decimal.NewFromInt(19),
+			},
+		},
+	}
+
+	service := &PurchaseService{
+		sqliteRepo:    mockRepo,
+		DecimalPlaces: 2,
+	}
+
+	input := PurchaseInput{
+		Cart: []PurchaseCartItem{
+			{ID: 1, Quantity: 1, NetPrice: decimal.NewFromFloat(10.00)},
+		},
+		TotalNetPrice:   decimal.NewFromFloat(15.00), // wrong
+		TotalGrossPrice: decimal.NewFromFloat(17.85), // wrong
+	}
+
+	_, _, err := service.ValidateAndCalculatePrices(input)
+	if err == nil || err != ErrInvalidTotalNetPrice {
+		t.Errorf("expected ErrInvalidTotalNetPrice, got: %v", err)
+	}
+}
+
+func TestValidateAndCalculatePricesWithProductPriceMismatch(t *testing.T) {
+	mockRepo := &MockRepository{
+		Products: map[int]*models.Product{
+			1: {
+				NetPrice: decimal.NewFromFloat(10.00),
+				VATRate:  decimal.NewFromInt(19),
+			},
+		},
+	}
+
+	service := &PurchaseService{
+		sqliteRepo:    mockRepo,
+		DecimalPlaces: 2,
+	}
+
+	input := PurchaseInput{
+		Cart: []PurchaseCartItem{
+			{ID: 1, Quantity: 1, NetPrice: decimal.NewFromFloat(15.00)},
+		},
+		TotalNetPrice:   decimal.NewFromFloat(10.00),
+		TotalGrossPrice: decimal.NewFromFloat(11.90),
+	}
+
+	_, _, err := service.ValidateAndCalculatePrices(input)
+	if err == nil || err != ErrInvalidProductPrice {
+		t.Errorf("expected ErrInvalidProductPrice, got: %v", err)
+	}
+}
+
+func TestCreatePurchaseExpandsBundle(t *testing.T) {
+	ticket := &models.Product{NetPrice: decimal.NewFromFloat(18.69), VATRate: decimal.NewFromInt(7)}
+	ticket.ID = 1
+	shirt := &models.Product{NetPrice: decimal.NewFromFloat(16.81), VATRate: decimal.NewFromInt(19)}
+	shirt.ID = 2
+
+	bundle := &models.Product{}
+	bundle.ID = 3
+
+	err := bundle.SetBundleItems([]models.ProductBundleItem{
+		{ComponentID: 1, Component: *ticket, Quantity: 1},
+		{ComponentID: 2, Component: *shirt, Quantity: 1},
+	}, decimal.NewFromInt(35), 2)
+	if err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	mockRepo := &MockRepository{
+		Products: map[int]*models.Product{1: ticket, 2: shirt, 3: bundle},
+	}
+
+	service := &PurchaseService{
+		sqliteRepo:    mockRepo,
+		DecimalPlaces: 2,
+	}
+
+	input := PurchaseInput{
+		PaymentMethod:   models.PaymentMethodCC,
+		TotalNetPrice:   decimal.NewFromFloat(62.12),
+		TotalGrossPrice: decimal.NewFromFloat(70.00),
+		Cart: []PurchaseCartItem{
+			{ID: 3, Quantity: 2, NetPrice: bundle.NetPrice},
+		},
+	}
+
+	purchase, err := service.CreateConfirmedPurchase(context.Background(), input, 7)
+	if err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	if len(purchase.PurchaseItems) != 2 {
+		t.Fatalf("expected the bundle to be expanded into 2 items, got %d", len(purchase.PurchaseItems))
+	}
+
+	for i, productID := range []int{1, 2} {
+		item := purchase.PurchaseItems[i]
+		if item.ProductID != productID || item.Quantity != 2 || item.BundleID == nil || *item.BundleID != 3 {
+			t.Errorf("unexpected bundle component: %+v", item)
+		}
+	}
+
+	if !purchase.PurchaseItems[1].VATRate.Equal(decimal.NewFromInt(19)) {
+		t.Errorf("component not sold at its own VAT rate: %s", purchase.PurchaseItems[1].VATRate)
+	}
+}
+
+func newProductWithVariants() *models.Product {
+	override := decimal.NewFromFloat(20.17)
+
+	shirt := &models.Product{
+		NetPrice: decimal.NewFromFloat(16.81),
+		VATRate:  decimal.NewFromInt(19),
+		Variants: []models.ProductVariant{{Name: "S"}, {Name: "XXL", NetPrice: &override}},
+	}
+	shirt.ID = 1
+	shirt.Variants[0].ID = 4
+	shirt.Variants[1].ID = 5
+
+	return shirt
+}
+
+func TestValidateAndCalculatePricesWithVariantPrice(t *testing.T) {
+	service := &PurchaseService{
+		sqliteRepo:    &MockRepository{Products: map[int]*models.Product{1: newProductWithVariants()}},
+		DecimalPlaces: 2,
+	}
+
+	input := PurchaseInput{
+		Cart: []PurchaseCartItem{
+			{ID: 1, VariantID: intPtr(4), Quantity: 1, NetPrice: decimal.NewFromFloat(16.81)},
+			{ID: 1, VariantID: intPtr(5), Quantity: 1, NetPrice: decimal.NewFromFloat(20.17)},
+		},
+		TotalNetPrice:   decimal.NewFromFloat(36.98),
+		TotalGrossPrice: decimal.NewFromFloat(44.00),
+	}
+
+	_, gross, err := service.ValidateAndCalculatePrices(input)
+	if err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	if !gross.Equal(decimal.NewFromFloat(44.00)) {
+		t.Errorf("unexpected gross total: %s", gross)
+	}
+
+	input.Cart[1].NetPrice = decimal.NewFromFloat(16.81)
+
+	_, _, err = service.ValidateAndCalculatePrices(input)
+	if err != ErrInvalidProductPrice {
+		t.Errorf("expected ErrInvalidProductPrice for the variant price, got %v", err)
+	}
+}
+
+func TestValidateAndCalculatePricesWithoutVariant(t *testing.T) {
+	service := &PurchaseService{
+		sqliteRepo:    &MockRepository{Products: map[int]*models.Product{1: newProductWithVariants()}},
+		DecimalPlaces: 2,
+	}
+
+	input := PurchaseInput{
+		Cart: []PurchaseCartItem{
+			{ID: 1, Quantity: 1, NetPrice: decimal.NewFromFloat(16.81)},
+		},
+		TotalNetPrice:   decimal.NewFromFloat(16.81),
+		TotalGrossPrice: decimal.NewFromFloat(20.00),
+	}
+
+	_, _, err := service.ValidateAndCalculatePrices(input)
+	if err != ErrVariantRequired {
+		t.Errorf("expected ErrVariantRequired, got %v", err)
+	}
+
+	input.Cart[0].VariantID = intPtr(9)
+
+	_, _, err = service.ValidateAndCalculatePrices(input)
+	if err != ErrVariantNotFound {
+		t.Errorf("expected ErrVariantNotFound, got %v", err)
+	}
+}
+
+func TestValidateAndCalculatePricesWithActivePriceRule(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	product := &models.Product{
+		NetPrice: decimal.NewFromFloat(37.38),
+		VATRate:  decimal.NewFromInt(7),
+		PriceRules: []models.ProductPriceRule{
+			{NetPrice: decimal.NewFromFloat(9.35), ValidFrom: &future},
+			{NetPrice: decimal.NewFromFloat(18.69), ValidFrom: &past},
+		},
+	}
+	product.ID = 1
+
+	service := &PurchaseService{
+		sqliteRepo:    &MockRepository{Products: map[int]*models.Product{1: product}},
+		DecimalPlaces: 2,
+	}
+
+	input := PurchaseInput{
+		Cart: []PurchaseCartItem{
+			{ID: 1, Quantity: 1, NetPrice: decimal.NewFromFloat(18.69)},
+		},
+		TotalNetPrice:   decimal.NewFromFloat(18.69),
+		TotalGrossPrice: decimal.NewFromFloat(20.00),
+	}
+
+	_, gross, err := service.ValidateAndCalculatePrices(input)
+	if err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	if !gross.Equal(decimal.NewFromFloat(20.00)) {
+		t.Errorf("unexpected gross total: %s", gross)
+	}
+
+	input.Cart[0].NetPrice = decimal.NewFromFloat(37.38)
+
+	_, _, err = service.ValidateAndCalculatePrices(input)
+	if err != ErrInvalidProductPrice {
+		t.Errorf("expected ErrInvalidProductPrice for the list price, got %v", err)
+	}
+}
+
+func TestValidateAndPrepareGuestsWithSuccess(t *testing.T) {
+	mockRepo := &MockRepository{
+		Guests: map[int]*models.Guest{
+			42: {
+				AttendedGuests:   0,
+				AdditionalGuests: 1,
+				Guestlist: models.Guestlist{
+					ProductID: 1,
+				},
+			},
+		},
+	}
+
+	service := &PurchaseService{
+		sqliteRepo: mockRepo,
+	}
+
+	input := PurchaseInput{
+		Cart: []PurchaseCartItem{
+			{
+				ID:       1,
+				Quantity: 1,
+				ListItems: []ListItemInput{
+					{ID: 42, AttendedGuests: 1},
+				},
+			},
+		},
+	}
+
+	guests, err := service.ValidateAndPrepareGuests(input)
+	if err != nil {
+		t.Errorf(errUnexpected, err)
+	}
+
+	if len(guests) != 1 {
+		t.Errorf("expected 1 updated guest, got %d", len(guests))
+	}
+
+	if guests[0].AttendedGuests != 1 {
+		t.Errorf("expected guest to have AttendedGuests = 1, got %d", guests[0].AttendedGuests)
+	}
+}
+
+func TestValidateAndPrepareGuestsWithGuestNotFound(t *testing.T) {
+	service := &PurchaseService{
+		sqliteRepo: &MockRepository{Guests: map[int]*models.Guest{}}, // leer!
+	}
+
+	input := PurchaseInput{
+		Cart: []PurchaseCartItem{
+			{
+				ID:       1,
+				Quantity: 1,
+				ListItems: []ListItemInput{
+					{ID: 99, AttendedGuests: 1},
+				},
+			},
+		},
+	}
+
+	_, err := service.ValidateAndPrepareGuests(input)
 	if err == nil {
 		t.Errorf("expected error for missing guest, got none")
 	}
@@ -622,6 +1452,10 @@ func TestCreatePurchaseWithSuccess(t *testing.T) {
 	}
 	p.ID = 1
 
+	session := &models.RegisterSession{Status: models.RegisterSessionStatusOpen}
+	session.ID = 3
+	session.CreatedByID = intPtr(7)
+
 	mockRepo := &MockRepository{
 		Products: map[int]*models.Product{
 			1: p,
@@ -629,12 +1463,15 @@ func TestCreatePurchaseWithSuccess(t *testing.T) {
 		Guests: map[int]*models.Guest{
 			42: g,
 		},
+		OpenSession: session,
 	}
 
 	service := &PurchaseService{
 		sqliteRepo:    mockRepo,
 		DecimalPlaces: 2,
 	}
+	service.ReceiptNumberPrefix = "K1"
+	service.FiscalYearStartMonth = time.January
 
 	input := PurchaseInput{
 		PaymentMethod:   "CASH",
@@ -665,6 +1502,19 @@ func TestCreatePurchaseWithSuccess(t *testing.T) {
 		t.Errorf("unexpected payment method: %s", purchase.PaymentMethod)
 	}
 
+	if purchase.RegisterSessionID == nil || *purchase.RegisterSessionID != 3 {
+		t.Errorf("purchase not attached to the open register session: %v", purchase.RegisterSessionID)
+	}
+
+	expectedReceiptNumber := models.FormatReceiptNumber("K1", models.FiscalYear(time.Now(), time.January), 1)
+	if purchase.ReceiptNumber == nil || *purchase.ReceiptNumber != expectedReceiptNumber {
+		t.Errorf("expected receipt number %s, got %v", expectedReceiptNumber, purchase.ReceiptNumber)
+	}
+
+	if len(mockRepo.Journal) != 1 || mockRepo.Journal[0].EventType != models.JournalEventPurchaseCreated {
+		t.Errorf("expected the purchase to be recorded in the journal: %+v", mockRepo.Journal)
+	}
+
 	if len(mockRepo.UpdatedGuests) != 1 {
 		t.Errorf("expected 1 updated guest, got %d", len(mockRepo.UpdatedGuests))
 	}
@@ -678,6 +1528,202 @@ func TestCreatePurchaseWithSuccess(t *testing.T) {
 	}
 }
 
+func TestCreatePurchaseWithCashWithoutOpenRegisterSession(t *testing.T) {
+	p := &models.Product{
+		NetPrice: decimal.NewFromFloat(10.00),
+		VATRate:  decimal.NewFromFloat(19),
+	}
+	p.ID = 1
+
+	service := &PurchaseService{
+		sqliteRepo:    &MockRepository{Products: map[int]*models.Product{1: p}},
+		DecimalPlaces: 2,
+	}
+
+	input := PurchaseInput{
+		PaymentMethod:   "CASH",
+		TotalNetPrice:   decimal.NewFromFloat(10.00),
+		TotalGrossPrice: decimal.NewFromFloat(11.90),
+		Cart: []PurchaseCartItem{
+			{ID: 1, Quantity: 1, NetPrice: decimal.NewFromFloat(10.00)},
+		},
+	}
+
+	_, err := service.CreateConfirmedPurchase(context.Background(), input, 7)
+	if err != ErrNoOpenRegisterSession {
+		t.Fatalf("expected ErrNoOpenRegisterSession, got %v", err)
+	}
+}
+
+func TestCreatePurchaseWithProductOutsideTheCategoriesOfTheUser(t *testing.T) {
+	p := &models.Product{
+		NetPrice:   decimal.NewFromFloat(10.00),
+		VATRate:    decimal.NewFromFloat(19),
+		CategoryID: intPtr(2),
+	}
+	p.ID = 1
+
+	user := &models.User{Categories: []models.Category{{}}}
+	user.ID = 7
+	user.Categories[0].ID = 3
+
+	service := &PurchaseService{
+		sqliteRepo: &MockRepository{
+			Products: map[int]*models.Product{1: p},
+			Users:    map[int]*models.User{7: user},
+		},
+		DecimalPlaces: 2,
+	}
+
+	input := PurchaseInput{
+		PaymentMethod:   "CASH",
+		TotalNetPrice:   decimal.NewFromFloat(10.00),
+		TotalGrossPrice: decimal.NewFromFloat(11.90),
+		Cart: []PurchaseCartItem{
+			{ID: 1, Quantity: 1, NetPrice: decimal.NewFromFloat(10.00)},
+		},
+	}
+
+	if _, err := service.CreateConfirmedPurchase(context.Background(), input, 7); err != ErrProductNotAllowed {
+		t.Fatalf("expected ErrProductNotAllowed, got %v", err)
+	}
+
+	if _, err := service.QuoteCart(input, 7); err != ErrProductNotAllowed {
+		t.Fatalf("expected ErrProductNotAllowed for the quote, got %v", err)
+	}
+}
+
+func TestValidatePaymentsWithSplitPayment(t *testing.T) {
+	service := &PurchaseService{DecimalPlaces: 2}
+
+	input := PurchaseInput{
+		TotalGrossPrice: decimal.NewFromFloat(11.90),
+		Payments: []PaymentInput{
+			{PaymentMethod: models.PaymentMethodCash, Amount: decimal.NewFromFloat(5.00)},
+			{PaymentMethod: models.PaymentMethodSumUp, Amount: decimal.NewFromFloat(6.90)},
+		},
+	}
+
+	if err := service.ValidatePayments(input, decimal.NewFromFloat(11.90)); err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	if input.PrimaryPaymentMethod() != models.PaymentMethodSumUp {
+		t.Errorf("unexpected primary payment method: %s", input.PrimaryPaymentMethod())
+	}
+}
+
+func TestValidatePaymentsWithTotalMismatch(t *testing.T) {
+	service := &PurchaseService{DecimalPlaces: 2}
+
+	input := PurchaseInput{
+		Payments: []PaymentInput{
+			{PaymentMethod: models.PaymentMethodCash, Amount: decimal.NewFromFloat(5.00)},
+			{PaymentMethod: models.PaymentMethodCC, Amount: decimal.NewFromFloat(5.00)},
+		},
+	}
+
+	err := service.ValidatePayments(input, decimal.NewFromFloat(11.90))
+	if err != ErrInvalidPaymentTotal {
+		t.Fatalf("expected ErrInvalidPaymentTotal, got %v", err)
+	}
+}
+
+func TestValidatePaymentsWithMultipleSumupPayments(t *testing.T) {
+	service := &PurchaseService{DecimalPlaces: 2}
+
+	input := PurchaseInput{
+		Payments: []PaymentInput{
+			{PaymentMethod: models.PaymentMethodSumUp, Amount: decimal.NewFromFloat(5.00)},
+			{PaymentMethod: models.PaymentMethodSumUp, Amount: decimal.NewFromFloat(6.90)},
+		},
+	}
+
+	err := service.ValidatePayments(input, decimal.NewFromFloat(11.90))
+	if err != ErrMultipleSumupPayments {
+		t.Fatalf("expected ErrMultipleSumupPayments, got %v", err)
+	}
+}
+
+func cashRounding(mode models.RoundingMode) map[models.PaymentMethod]models.RoundingRule {
+	return map[models.PaymentMethod]models.RoundingRule{
+		models.PaymentMethodCash: {Increment: decimal.NewFromFloat(0.5), Mode: mode},
+	}
+}
+
+func TestRoundPaymentsRoundsTheLinesOfAPaymentMethodTogether(t *testing.T) {
+	service := &PurchaseService{DecimalPlaces: 2}
+	service.roundingRules = cashRounding(models.RoundingModeNearest)
+
+	input := PurchaseInput{
+		Payments: []PaymentInput{
+			{PaymentMethod: models.PaymentMethodCash, Amount: decimal.NewFromFloat(5.13)},
+			{PaymentMethod: models.PaymentMethodCC, Amount: decimal.NewFromFloat(4.67)},
+			{PaymentMethod: models.PaymentMethodCash, Amount: decimal.NewFromFloat(2.20)},
+		},
+	}
+
+	roundings := service.roundPayments(input)
+	if len(roundings) != 1 || roundings[0].PaymentMethod != models.PaymentMethodCash ||
+		!roundings[0].Amount.Equal(decimal.NewFromFloat(0.17)) {
+		t.Fatalf("expected a cash rounding of 0.17, got %+v", roundings)
+	}
+
+	service.roundingRules = cashRounding(models.RoundingModeDown)
+
+	roundings = service.roundPayments(input)
+	if len(roundings) != 1 || !roundings[0].Amount.Equal(decimal.NewFromFloat(-0.33)) {
+		t.Errorf("expected a cash rounding of -0.33, got %+v", roundings)
+	}
+
+	input.Payments[2].Amount = decimal.NewFromFloat(2.37)
+
+	if roundings := service.roundPayments(input); len(roundings) != 0 {
+		t.Errorf("expected no rounding for an exact amount, got %+v", roundings)
+	}
+}
+
+func TestCreatePurchaseStoresTheCashRounding(t *testing.T) {
+	p := &models.Product{
+		NetPrice: decimal.NewFromFloat(10.00),
+		VATRate:  decimal.NewFromFloat(19),
+	}
+	p.ID = 1
+
+	session := &models.RegisterSession{Status: models.RegisterSessionStatusOpen}
+	session.ID = 3
+	session.CreatedByID = intPtr(7)
+
+	service := &PurchaseService{
+		sqliteRepo:    &MockRepository{Products: map[int]*models.Product{1: p}, OpenSession: session},
+		DecimalPlaces: 2,
+	}
+	service.roundingRules = cashRounding(models.RoundingModeNearest)
+
+	input := PurchaseInput{
+		PaymentMethod:   models.PaymentMethodCash,
+		TotalNetPrice:   decimal.NewFromFloat(10.00),
+		TotalGrossPrice: decimal.NewFromFloat(11.90),
+		Cart: []PurchaseCartItem{
+			{ID: 1, Quantity: 1, NetPrice: decimal.NewFromFloat(10.00)},
+		},
+	}
+
+	purchase, err := service.CreateConfirmedPurchase(context.Background(), input, 7)
+	if err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	if !purchase.TotalGrossPrice.Equal(decimal.NewFromFloat(11.90)) ||
+		!purchase.Payments[0].Amount.Equal(decimal.NewFromFloat(11.90)) {
+		t.Errorf("the prices and payment lines must stay exact, got %+v", purchase)
+	}
+
+	if !purchase.RoundingAmount(models.PaymentMethodCash).Equal(decimal.NewFromFloat(0.10)) {
+		t.Errorf("expected a cash rounding of 0.10, got %+v", purchase.Roundings)
+	}
+}
+
 func TestNotifyGuestsWithSendsExpectedEmails(t *testing.T) {
 	mailer := &MockMailer{}
 	service := &PurchaseService{
@@ -719,3 +1765,92 @@ func TestNotifyGuestsWithSendsExpectedEmails(t *testing.T) {
 func ptr(s string) *string {
 	return &s
 }
+
+func voucherPurchaseInput(code string) PurchaseInput {
+	return PurchaseInput{
+		PaymentMethod:   models.PaymentMethodVoucher,
+		VoucherCode:     code,
+		TotalNetPrice:   decimal.NewFromFloat(10.00),
+		TotalGrossPrice: decimal.NewFromFloat(11.90),
+		Cart: []PurchaseCartItem{
+			{ID: 1, Quantity: 1, NetPrice: decimal.NewFromFloat(10.00)},
+		},
+	}
+}
+
+func TestCreatePurchaseRedeemsVoucher(t *testing.T) {
+	voucher := &models.Voucher{Code: "7KQM-X3RT-PW9C", Balance: decimal.NewFromInt(20)}
+	voucher.ID = 4
+	service, mockRepo := newMockPurchaseService(withVoucher(voucher))
+
+	purchase, err := service.CreateConfirmedPurchase(context.Background(), voucherPurchaseInput(voucher.Code), 7)
+	if err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	if !voucher.Balance.Equal(decimal.NewFromFloat(8.10)) {
+		t.Errorf("unexpected voucher balance: %s", voucher.Balance)
+	}
+
+	if len(mockRepo.Redemptions) != 1 || mockRepo.Redemptions[0].PurchaseID != purchase.ID {
+		t.Errorf("expected a redemption for the purchase, got %+v", mockRepo.Redemptions)
+	}
+
+	if purchase.Payments[0].VoucherID == nil || *purchase.Payments[0].VoucherID != 4 {
+		t.Errorf("payment line not linked to the voucher: %v", purchase.Payments[0].VoucherID)
+	}
+}
+
+func TestCreatePurchaseWithExhaustedVoucher(t *testing.T) {
+	voucher := &models.Voucher{Code: "7KQM-X3RT-PW9C", Balance: decimal.NewFromInt(5)}
+	service, _ := newMockPurchaseService(withVoucher(voucher))
+
+	_, err := service.CreateConfirmedPurchase(context.Background(), voucherPurchaseInput(voucher.Code), 7)
+	if err != models.ErrVoucherInsufficientBalance {
+		t.Fatalf("expected ErrVoucherInsufficientBalance, got %v", err)
+	}
+
+	if !voucher.Balance.Equal(decimal.NewFromInt(5)) {
+		t.Errorf("voucher balance must not change: %s", voucher.Balance)
+	}
+}
+
+func TestCreatePurchaseWithUnknownVoucher(t *testing.T) {
+	voucher := &models.Voucher{Code: "7KQM-X3RT-PW9C", Balance: decimal.NewFromInt(20)}
+	service, _ := newMockPurchaseService(withVoucher(voucher))
+
+	_, err := service.CreateConfirmedPurchase(context.Background(), voucherPurchaseInput("AAAA-BBBB-CCCC"), 7)
+	if err != sqlite.ErrVoucherNotFound {
+		t.Fatalf("expected ErrVoucherNotFound, got %v", err)
+	}
+}
+
+func TestValidatePaymentsWithVoucherWithoutCode(t *testing.T) {
+	service := &PurchaseService{DecimalPlaces: 2}
+
+	err := service.ValidatePayments(voucherPurchaseInput(""), decimal.NewFromFloat(11.90))
+	if err != ErrVoucherCodeRequired {
+		t.Fatalf("expected ErrVoucherCodeRequired, got %v", err)
+	}
+}
+
+func TestDeletePurchaseDoesNotJournalAFailedDeletion(t *testing.T) {
+	service, mockRepo := newMockPurchaseService(withStock(5))
+
+	purchase, err := service.CreatePendingPurchase(context.Background(), stockPurchaseInput(1), 7)
+	if err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	journaled := len(mockRepo.Journal)
+	mockRepo.DeletePurchaseErr = errors.New("database is locked")
+
+	err = service.DeletePurchase(context.Background(), purchase.ID, models.User{ID: 7})
+	if !errors.Is(err, mockRepo.DeletePurchaseErr) {
+		t.Fatalf("expected the deletion to fail, got %v", err)
+	}
+
+	if len(mockRepo.Journal) != journaled {
+		t.Errorf("expected the failed deletion not to be journaled, got %+v", mockRepo.Journal[journaled:])
+	}
+}
@@ -0,0 +1,397 @@
+package purchase
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/shopspring/decimal"
+)
+
+// QuoteValidity is how long the token of a quote can be used to create the purchase.
+const QuoteValidity = 15 * time.Minute
+
+var (
+	ErrQuoteSecretRequired = errors.New("a secret to sign the quote tokens with is required")
+	ErrInvalidQuoteToken   = errors.New("invalid quote token")
+	ErrQuoteExpired        = errors.New("the quote has expired")
+	ErrQuoteMismatch       = errors.New("the quote does not match the cart")
+)
+
+// Quote is the price of a cart as the server computes it, with the purchase items and their
+// discount lines, the VAT per rate, the totals and the amounts to be paid with the payment methods
+// that round their amounts. It also reports the stock left for the cart and
+// the guests of the cart who cannot be admitted. The token lets the purchase be created at the
+// quoted prices without the client computing the totals.
+type Quote struct {
+	Items           []models.PurchaseItem
+	TotalNetPrice   decimal.Decimal
+	TotalGrossPrice decimal.Decimal
+	VATRates        []QuoteVATRate
+	PaymentAmounts  []QuotePaymentAmount
+	Stock           []QuoteStock
+	GuestErrors     []QuoteGuestError
+	Token           string
+	ExpiresAt       time.Time
+}
+
+type QuoteVATRate struct {
+	VATRate    decimal.Decimal
+	NetPrice   decimal.Decimal
+	VATAmount  decimal.Decimal
+	GrossPrice decimal.Decimal
+}
+
+// QuotePaymentAmount is the total gross price rounded by the rounding rule of a payment method, e.g.
+// the cash to be collected. Payment methods without a rule are paid the exact total.
+type QuotePaymentAmount struct {
+	PaymentMethod models.PaymentMethod
+	Amount        decimal.Decimal
+	Rounding      decimal.Decimal
+}
+
+// QuoteStock is the stock left of a product or variant of the cart. Available is nil if the stock
+// is not tracked.
+type QuoteStock struct {
+	ProductID  int
+	VariantID  *int
+	Name       string
+	Quantity   int
+	Available  *int
+	Sufficient bool
+}
+
+type QuoteGuestError struct {
+	GuestID   int
+	ProductID int
+	Err       error
+}
+
+// quoteKey derives the key the quote tokens are signed with from the secret, so they are not
+// signed with the same key as other tokens issued with the secret.
+func quoteKey(secret string) []byte {
+	key := sha256.Sum256([]byte("purchase-quote:" + secret))
+
+	return key[:]
+}
+
+// QuoteCart prices the cart at the current prices, ignoring the prices and totals of the input,
+// without storing anything. Carts that cannot be sold, like a product that does not exist or a
+// discount that does not apply, fail.
+func (s *PurchaseService) QuoteCart(input PurchaseInput, userID int) (*Quote, error) {
+	if err := s.checkProductPermission(input, userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkDiscountPermission(input, userID); err != nil {
+		return nil, err
+	}
+
+	prices := currentPrices()
+
+	input.Cart = slices.Clone(input.Cart)
+	for i, item := range input.Cart {
+		candidates, err := s.cartItemPrices(item, prices)
+		if err != nil {
+			return nil, err
+		}
+
+		input.Cart[i].NetPrice = candidates[0].NetPrice
+	}
+
+	items, totalNet, totalGross, err := s.priceCart(input, prices)
+	if err != nil {
+		return nil, err
+	}
+
+	stock, err := s.quoteStock(items)
+	if err != nil {
+		return nil, err
+	}
+
+	quote := &Quote{
+		Items:           s.namedItems(items),
+		TotalNetPrice:   totalNet,
+		TotalGrossPrice: totalGross,
+		VATRates:        quoteVATRates(items, s.DecimalPlaces),
+		PaymentAmounts:  s.quotePaymentAmounts(totalGross),
+		Stock:           stock,
+		GuestErrors:     s.quoteGuestErrors(input),
+		ExpiresAt:       prices.until.Add(QuoteValidity),
+	}
+
+	quote.Token, err = s.signQuote(quoteClaims{
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(quote.ExpiresAt)},
+		UserID:           userID,
+		Cart:             cartDigest(input),
+		TotalNetPrice:    totalNet,
+		TotalGrossPrice:  totalGross,
+		Prices:           cartPrices(input),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return quote, nil
+}
+
+// ApplyQuote takes the prices and totals of the input from the quote token. The cart of the input
+// has to be the quoted one, and the purchase is validated against the current prices as usual, so
+// a price changed since the quote fails the purchase.
+func (s *PurchaseService) ApplyQuote(token string, input PurchaseInput, userID int) (PurchaseInput, error) {
+	claims, err := s.verifyQuote(token)
+	if err != nil {
+		return input, err
+	}
+
+	if claims.UserID != userID || claims.Cart != cartDigest(input) || len(claims.Prices) != len(input.Cart) {
+		return input, ErrQuoteMismatch
+	}
+
+	cart := slices.Clone(input.Cart)
+	for i := range cart {
+		cart[i].NetPrice = claims.Prices[i]
+	}
+
+	input.Cart = cart
+	input.TotalNetPrice = claims.TotalNetPrice
+	input.TotalGrossPrice = claims.TotalGrossPrice
+
+	return input, nil
+}
+
+// namedItems returns the items with their products and variants, for showing them with their names.
+func (s *PurchaseService) namedItems(items []models.PurchaseItem) []models.PurchaseItem {
+	named := slices.Clone(items)
+
+	for i, item := range named {
+		product, err := s.sqliteRepo.GetProductByID(item.ProductID)
+		if err != nil || product == nil {
+			continue
+		}
+
+		named[i].Product = *product
+
+		if item.VariantID != nil {
+			if variant, ok := product.Variant(*item.VariantID); ok {
+				named[i].Variant = &variant
+			}
+		}
+	}
+
+	return named
+}
+
+func (s *PurchaseService) quotePaymentAmounts(totalGross decimal.Decimal) []QuotePaymentAmount {
+	amounts := make([]QuotePaymentAmount, 0, len(s.roundingRules))
+
+	for method, rule := range s.roundingRules {
+		rounded := rule.Round(totalGross)
+		amounts = append(amounts, QuotePaymentAmount{
+			PaymentMethod: method,
+			Amount:        rounded,
+			Rounding:      rounded.Sub(totalGross),
+		})
+	}
+
+	slices.SortFunc(amounts, func(a, b QuotePaymentAmount) int {
+		return strings.Compare(string(a.PaymentMethod), string(b.PaymentMethod))
+	})
+
+	return amounts
+}
+
+func quoteVATRates(items []models.PurchaseItem, decimalPlaces int32) []QuoteVATRate {
+	var rates []QuoteVATRate
+
+	for _, item := range items {
+		i := slices.IndexFunc(rates, func(rate QuoteVATRate) bool {
+			return rate.VATRate.Equal(item.VATRate)
+		})
+		if i < 0 {
+			rates = append(rates, QuoteVATRate{
+				VATRate:    item.VATRate,
+				NetPrice:   decimal.Zero,
+				GrossPrice: decimal.Zero,
+			})
+			i = len(rates) - 1
+		}
+
+		rates[i].NetPrice = rates[i].NetPrice.Add(item.TotalDiscountedNetPrice(decimalPlaces))
+		rates[i].GrossPrice = rates[i].GrossPrice.Add(item.TotalDiscountedGrossPrice(decimalPlaces))
+	}
+
+	for i := range rates {
+		rates[i].VATAmount = rates[i].GrossPrice.Sub(rates[i].NetPrice)
+	}
+
+	slices.SortFunc(rates, func(a, b QuoteVATRate) int {
+		return a.VATRate.Cmp(b.VATRate)
+	})
+
+	return rates
+}
+
+// quoteStock returns the stock left for the products and variants the items take from the stock.
+func (s *PurchaseService) quoteStock(items []models.PurchaseItem) ([]QuoteStock, error) {
+	var stock []QuoteStock
+
+	for _, change := range reservedStock(items) {
+		product, err := s.sqliteRepo.GetProductByID(change.productID)
+		if err != nil {
+			return nil, err
+		}
+
+		entry := QuoteStock{
+			ProductID:  change.productID,
+			VariantID:  change.variantID,
+			Name:       product.Name,
+			Quantity:   change.quantity,
+			Sufficient: true,
+		}
+
+		onHand, tracked, err := s.stockOf(product, change.variantID)
+		if err != nil {
+			return nil, err
+		}
+
+		if tracked {
+			available := max(onHand, 0)
+			entry.Available = &available
+			entry.Sufficient = available >= change.quantity
+		}
+
+		if change.variantID != nil {
+			if variant, ok := product.Variant(*change.variantID); ok {
+				entry.Name = product.Name + " (" + variant.Name + ")"
+			}
+		}
+
+		stock = append(stock, entry)
+	}
+
+	return stock, nil
+}
+
+// stockOf returns the stock on hand of the product, or of its variant if given, from the inventory
+// ledger, and whether its stock is tracked.
+func (s *PurchaseService) stockOf(product *models.Product, variantID *int) (int, bool, error) {
+	if variantID == nil {
+		if !product.TracksStock() {
+			return 0, false, nil
+		}
+
+		onHand, err := s.sqliteRepo.GetStockOnHand(product.ID, nil)
+
+		return onHand, true, err
+	}
+
+	variant, ok := product.Variant(*variantID)
+	if !ok || !variant.TracksStock() {
+		return 0, false, nil
+	}
+
+	onHand, err := s.sqliteRepo.GetStockOnHand(product.ID, &variant.ID)
+
+	return onHand, true, err
+}
+
+// quoteGuestErrors returns why guests of the cart cannot be admitted with the purchase.
+func (s *PurchaseService) quoteGuestErrors(input PurchaseInput) []QuoteGuestError {
+	var guestErrors []QuoteGuestError
+
+	for _, item := range input.Cart {
+		for _, listInput := range item.ListItems {
+			if _, err := s.validateGuest(listInput, item.ID); err != nil {
+				guestErrors = append(guestErrors, QuoteGuestError{
+					GuestID:   listInput.ID,
+					ProductID: item.ID,
+					Err:       err,
+				})
+			}
+		}
+	}
+
+	return guestErrors
+}
+
+// quoteClaims are signed into the token of a quote, a JWT that expires with the quote. The cart is
+// identified by its digest, the net prices are the prices of its items in their order.
+type quoteClaims struct {
+	jwt.RegisteredClaims
+
+	UserID          int               `json:"u"`
+	Cart            string            `json:"c"`
+	TotalNetPrice   decimal.Decimal   `json:"n"`
+	TotalGrossPrice decimal.Decimal   `json:"g"`
+	Prices          []decimal.Decimal `json:"p"`
+}
+
+func (s *PurchaseService) signQuote(claims quoteClaims) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.quoteKey)
+}
+
+func (s *PurchaseService) verifyQuote(token string) (quoteClaims, error) {
+	var claims quoteClaims
+
+	_, err := jwt.ParseWithClaims(
+		token,
+		&claims,
+		func(*jwt.Token) (any, error) { return s.quoteKey, nil },
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+	)
+
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return claims, ErrQuoteExpired
+	case err != nil:
+		return claims, ErrInvalidQuoteToken
+	}
+
+	return claims, nil
+}
+
+// cartDigest identifies the products, quantities, list items and discounts of the cart, but not
+// its prices.
+func cartDigest(input PurchaseInput) string {
+	type digestItem struct {
+		ID        int
+		VariantID *int
+		Quantity  uint
+		ListItems []ListItemInput
+	}
+
+	items := make([]digestItem, 0, len(input.Cart))
+	for _, item := range input.Cart {
+		items = append(items, digestItem{
+			ID:        item.ID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+			ListItems: item.ListItems,
+		})
+	}
+
+	payload, _ := json.Marshal(struct {
+		Items     []digestItem
+		Discounts []DiscountInput
+	}{items, input.Discounts})
+	digest := sha256.Sum256(payload)
+
+	return hex.EncodeToString(digest[:])
+}
+
+func cartPrices(input PurchaseInput) []decimal.Decimal {
+	prices := make([]decimal.Decimal, 0, len(input.Cart))
+	for _, item := range input.Cart {
+		prices = append(prices, item.NetPrice)
+	}
+
+	return prices
+}
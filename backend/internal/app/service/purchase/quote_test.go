@@ -0,0 +1,146 @@
+package purchase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/shopspring/decimal"
+)
+
+func TestQuoteCartPricesTheCartAndReportsTheStock(t *testing.T) {
+	service, mockRepo := newMockPurchaseService(withStock(1))
+	service.quoteKey = quoteKey("quote-secret")
+
+	input := stockPurchaseInput(2)
+	input.Cart[0].NetPrice = decimal.Zero
+	input.TotalNetPrice = decimal.Zero
+
+	quote, err := service.QuoteCart(input, 7)
+	if err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	if !quote.TotalNetPrice.Equal(decimal.NewFromInt(20)) || !quote.TotalGrossPrice.Equal(decimal.NewFromFloat(23.8)) {
+		t.Errorf("unexpected totals %s/%s", quote.TotalNetPrice, quote.TotalGrossPrice)
+	}
+
+	if len(quote.VATRates) != 1 || !quote.VATRates[0].VATAmount.Equal(decimal.NewFromFloat(3.8)) {
+		t.Errorf("unexpected VAT rates %+v", quote.VATRates)
+	}
+
+	stock := quote.Stock[0]
+	if stock.Available == nil || *stock.Available != 1 || stock.Sufficient {
+		t.Errorf("expected 1 item left and the stock to be insufficient, got %+v", stock)
+	}
+
+	if mockRepo.StoredPurchase != nil || len(mockRepo.InventoryMovements) != 0 {
+		t.Error("a quote must not store anything")
+	}
+
+	if !input.Cart[0].NetPrice.IsZero() {
+		t.Error("the quote must not change the cart of the input")
+	}
+
+	quoted, err := service.ApplyQuote(quote.Token, input, 7)
+	if err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	if !quoted.TotalGrossPrice.Equal(decimal.NewFromFloat(23.8)) ||
+		!quoted.Cart[0].NetPrice.Equal(decimal.NewFromInt(10)) {
+		t.Errorf("expected the quoted prices to be applied, got %+v", quoted)
+	}
+}
+
+func TestApplyQuoteRejectsOtherCartsUsersAndTamperedTokens(t *testing.T) {
+	service, _ := newMockPurchaseService(withStock(5))
+	service.quoteKey = quoteKey("quote-secret")
+
+	quote, err := service.QuoteCart(stockPurchaseInput(1), 7)
+	if err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	if _, err := service.ApplyQuote(quote.Token, stockPurchaseInput(2), 7); !errors.Is(err, ErrQuoteMismatch) {
+		t.Errorf("expected ErrQuoteMismatch for another cart, got %v", err)
+	}
+
+	if _, err := service.ApplyQuote(quote.Token, stockPurchaseInput(1), 8); !errors.Is(err, ErrQuoteMismatch) {
+		t.Errorf("expected ErrQuoteMismatch for another user, got %v", err)
+	}
+
+	if _, err := service.ApplyQuote(quote.Token+"x", stockPurchaseInput(1), 7); !errors.Is(err, ErrInvalidQuoteToken) {
+		t.Errorf("expected ErrInvalidQuoteToken for a tampered token, got %v", err)
+	}
+}
+
+func TestPurchaseAtQuotedPricesFailsOnceThePriceChanged(t *testing.T) {
+	service, mockRepo := newMockPurchaseService(withStock(5))
+	service.quoteKey = quoteKey("quote-secret")
+
+	quote, err := service.QuoteCart(stockPurchaseInput(1), 7)
+	if err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	mockRepo.Products[1].NetPrice = decimal.NewFromInt(12)
+
+	input, err := service.ApplyQuote(quote.Token, stockPurchaseInput(1), 7)
+	if err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	_, err = service.CreatePendingPurchase(context.Background(), input, 7)
+	if !errors.Is(err, ErrInvalidProductPrice) {
+		t.Errorf("expected ErrInvalidProductPrice, got %v", err)
+	}
+}
+
+func TestApplyQuoteRejectsExpiredTokens(t *testing.T) {
+	service, _ := newMockPurchaseService(withStock(5))
+	service.quoteKey = quoteKey("quote-secret")
+
+	token, err := service.signQuote(quoteClaims{
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute))},
+		UserID:           7,
+		Cart:             cartDigest(stockPurchaseInput(1)),
+	})
+	if err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	if _, err := service.ApplyQuote(token, stockPurchaseInput(1), 7); !errors.Is(err, ErrQuoteExpired) {
+		t.Errorf("expected ErrQuoteExpired, got %v", err)
+	}
+}
+
+func TestNewPurchaseServiceRequiresAQuoteSecret(t *testing.T) {
+	if _, err := NewPurchaseService(nil, nil, nil, 2, "EUR", Options{}); !errors.Is(err, ErrQuoteSecretRequired) {
+		t.Errorf("expected ErrQuoteSecretRequired, got %v", err)
+	}
+}
+
+func TestQuoteCartRoundsTheTotalForThePaymentMethodsWithARoundingRule(t *testing.T) {
+	service, _ := newMockPurchaseService(withStock(5))
+	service.quoteKey = quoteKey("quote-secret")
+	service.roundingRules = cashRounding(models.RoundingModeNearest)
+
+	quote, err := service.QuoteCart(stockPurchaseInput(2), 7)
+	if err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	if len(quote.PaymentAmounts) != 1 {
+		t.Fatalf("expected the amount of the cash payment only, got %+v", quote.PaymentAmounts)
+	}
+
+	amount := quote.PaymentAmounts[0]
+	if amount.PaymentMethod != models.PaymentMethodCash || !amount.Amount.Equal(decimal.NewFromInt(24)) ||
+		!amount.Rounding.Equal(decimal.NewFromFloat(0.2)) {
+		t.Errorf("expected 24.00 cash with a rounding of 0.20, got %+v", amount)
+	}
+}
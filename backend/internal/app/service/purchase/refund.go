@@ -0,0 +1,536 @@
+package purchase
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/potibm/kasseapparat/internal/app/repository/sqlite"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrEmptyRefund               = errors.New("no items to refund")
+	ErrRefundItemNotFound        = errors.New("purchase item not found")
+	ErrInvalidRefundQuantity     = errors.New("refund quantity must be positive")
+	ErrRefundQuantityExceedsItem = errors.New("refund quantity exceeds the remaining quantity")
+	ErrRefundNotSettled          = errors.New("the refund has been recorded, but the card has not been refunded")
+	ErrRefundAlreadySettled      = errors.New("the refund has already been settled")
+	ErrRefundBeingSettled        = errors.New("the card of the refund is already being refunded")
+	ErrPurchaseNotRefundable     = errors.New("only confirmed purchases can be refunded")
+)
+
+type RefundItemInput struct {
+	PurchaseItemID int
+	Quantity       uint
+}
+
+// RefundPurchase refunds everything of the purchase that has not been refunded yet.
+func (s *PurchaseService) RefundPurchase(
+	ctx context.Context,
+	purchaseID uuid.UUID,
+	userID int,
+) (*models.Purchase, error) {
+	return s.refundPurchaseItems(ctx, purchaseID, remainingRefundItems, userID)
+}
+
+// RefundPurchaseItems refunds the given quantities of the purchase items. The purchase
+// moves to refunded once every item has been refunded completely.
+func (s *PurchaseService) RefundPurchaseItems(
+	ctx context.Context,
+	purchaseID uuid.UUID,
+	items []RefundItemInput,
+	userID int,
+) (*models.Purchase, error) {
+	if len(items) == 0 {
+		return nil, ErrEmptyRefund
+	}
+
+	return s.refundPurchaseItems(ctx, purchaseID, func(*models.Purchase) []RefundItemInput {
+		return items
+	}, userID)
+}
+
+// remainingRefundItems returns the quantities of the purchase items that have not been refunded yet.
+func remainingRefundItems(purchase *models.Purchase) []RefundItemInput {
+	items := make([]RefundItemInput, 0, len(purchase.PurchaseItems))
+
+	for _, item := range purchase.PurchaseItems {
+		if remaining := item.RemainingQuantity(); remaining > 0 {
+			items = append(items, RefundItemInput{PurchaseItemID: item.ID, Quantity: remaining})
+		}
+	}
+
+	return items
+}
+
+func getRefundablePurchase(txRepo sqlite.RepositoryInterface, purchaseID uuid.UUID) (*models.Purchase, error) {
+	purchase, err := txRepo.GetPurchaseByID(purchaseID)
+	if err != nil {
+		return nil, errors.New("failed to get purchase by ID: " + err.Error())
+	}
+
+	// Validate current status
+	if purchase.Status != models.PurchaseStatusConfirmed {
+		return nil, fmt.Errorf("%w, the purchase is %s", ErrPurchaseNotRefundable, purchase.Status)
+	}
+
+	return purchase, nil
+}
+
+// refundPurchaseItems records the refund of the items selected from the purchase. The purchase
+// is loaded and the refund validated in the same transaction that records it, so concurrent
+// refunds cannot both pay back the same items. The card share is refunded via the payment
+// terminal provider only after the refund has been recorded, the refund stays pending until
+// the provider has refunded the card. If the provider fails, the refunded purchase is returned
+// together with ErrRefundNotSettled.
+func (s *PurchaseService) refundPurchaseItems(
+	ctx context.Context,
+	purchaseID uuid.UUID,
+	selectItems func(purchase *models.Purchase) []RefundItemInput,
+	userID int,
+) (*models.Purchase, error) {
+	var (
+		refund             *models.PurchaseRefund
+		sumupTransactionID *uuid.UUID
+	)
+
+	err := s.sqliteRepo.WithTransaction(ctx, func(txRepo sqlite.RepositoryInterface) error {
+		purchase, err := getRefundablePurchase(txRepo, purchaseID)
+		if err != nil {
+			return err
+		}
+
+		sumupTransactionID = purchase.SumupTransactionID
+
+		refund, err = s.recordRefund(ctx, txRepo, purchase, selectItems(purchase), userID)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	settleErr := s.settleRefund(ctx, *refund, sumupTransactionID)
+	if settleErr != nil {
+		slog.WarnContext(ctx, "Refund recorded, but not settled", "refund_id", refund.ID, "error", settleErr)
+	}
+
+	refundedPurchase, err := s.sqliteRepo.GetPurchaseByID(purchaseID)
+	if err != nil {
+		return nil, errors.New("failed to get purchase by ID: " + err.Error())
+	}
+
+	s.recordRefundMetrics(ctx, refundedPurchase, refund)
+
+	return refundedPurchase, settleErr
+}
+
+// recordRefund validates the refund of the items against the purchase and records it together
+// with the stock returned and the guests released. A refund paying back money via the payment
+// terminal provider is recorded unsettled.
+func (s *PurchaseService) recordRefund(
+	ctx context.Context,
+	txRepo sqlite.RepositoryInterface,
+	purchase *models.Purchase,
+	items []RefundItemInput,
+	userID int,
+) (*models.PurchaseRefund, error) {
+	refund, remaining, err := s.buildRefund(purchase, items)
+	if err != nil {
+		return nil, err
+	}
+
+	refund.CreatedByID = intPtr(userID)
+
+	completesPurchase := !slices.ContainsFunc(purchase.PurchaseItems, func(item models.PurchaseItem) bool {
+		return remaining[item.ID] > 0
+	})
+	refund.Payments = s.allocateRefundPayments(purchase, refund.TotalGrossPrice, completesPurchase)
+	refund.Roundings = s.roundRefundPayments(*refund)
+
+	if !refund.RequiresSettlement() {
+		settledAt := time.Now()
+		refund.SettledAt = &settledAt
+	}
+
+	// cash paid back is taken from the till of the cashier's open register session
+	if refund.PaidBackAmount(models.PaymentMethodCash).IsPositive() {
+		session, err := txRepo.GetOpenRegisterSessionByUserID(userID)
+		if err != nil {
+			return nil, ErrNoOpenRegisterSession
+		}
+
+		refund.RegisterSessionID = &session.ID
+	}
+
+	if len(refund.Items) > 0 {
+		stored, err := s.storeRefund(txRepo, *refund)
+		if err != nil {
+			return nil, fmt.Errorf("failed to refund purchase: %w", err)
+		}
+
+		refund.ID = stored.ID
+
+		if err := updateStock(txRepo, stockOrigin{
+			purchaseID: purchase.ID,
+			userID:     &userID,
+		}, refundedStock(purchase, refund.Items)); err != nil {
+			return nil, fmt.Errorf("failed to refund purchase: %w", err)
+		}
+	}
+
+	if completesPurchase {
+		if err := s.markPurchaseRefunded(ctx, txRepo, purchase.ID, purchase.Status, refund.CreatedByID); err != nil {
+			return nil, fmt.Errorf("failed to refund purchase: %w", err)
+		}
+	} else if err := rollbackRefundedGuests(txRepo, purchase, remaining); err != nil {
+		return nil, fmt.Errorf("failed to refund purchase: failed to rollback visited guests: %w", err)
+	}
+
+	return refund, nil
+}
+
+// settleRefund refunds the card share of a recorded refund via the payment terminal provider
+// and settles the refund once the provider has refunded the card. The refund is claimed before
+// the provider is called, so concurrent settlements cannot refund the card twice. If the provider
+// fails, the claim is released and the refund stays recorded unsettled.
+func (s *PurchaseService) settleRefund(
+	ctx context.Context,
+	refund models.PurchaseRefund,
+	sumupTransactionID *uuid.UUID,
+) error {
+	if refund.IsSettled() || len(refund.Items) == 0 {
+		return nil
+	}
+
+	if sumupTransactionID == nil {
+		return fmt.Errorf("%w: the purchase has no SumUp transaction", ErrRefundNotSettled)
+	}
+
+	if err := s.claimRefundSettlement(ctx, refund.ID); err != nil {
+		return fmt.Errorf("%w: %w", ErrRefundNotSettled, err)
+	}
+
+	sumupAmount := refund.PaymentAmount(models.PaymentMethodSumUp)
+
+	slog.Debug(
+		"Refunding transaction via the payment terminal provider",
+		"transaction_id",
+		*sumupTransactionID,
+		"amount",
+		sumupAmount,
+	)
+
+	if err := s.terminalProvider.RefundTransaction(*sumupTransactionID, sumupAmount); err != nil {
+		if releaseErr := s.sqliteRepo.ReleasePurchaseRefundSettlement(refund.ID); releaseErr != nil {
+			slog.ErrorContext(ctx, "Error releasing the refund", "refund_id", refund.ID, "error", releaseErr)
+		}
+
+		return fmt.Errorf("%w: %w", ErrRefundNotSettled, err)
+	}
+
+	// the card has been refunded, so the claim is kept even if the refund cannot be marked settled
+	if err := s.sqliteRepo.SettlePurchaseRefundByID(refund.ID); err != nil {
+		return fmt.Errorf("%w: %w", ErrRefundNotSettled, err)
+	}
+
+	return nil
+}
+
+// claimRefundSettlement claims the refund for refunding its card. A refund that has been settled
+// or claimed meanwhile is rejected.
+func (s *PurchaseService) claimRefundSettlement(ctx context.Context, refundID int) error {
+	return s.sqliteRepo.WithTransaction(ctx, func(txRepo sqlite.RepositoryInterface) error {
+		claimed, err := txRepo.ClaimPurchaseRefundSettlement(refundID)
+		if err != nil || claimed {
+			return err
+		}
+
+		refund, err := txRepo.GetPurchaseRefundByID(refundID)
+		if err != nil {
+			return err
+		}
+
+		if refund.IsSettled() {
+			return ErrRefundAlreadySettled
+		}
+
+		return ErrRefundBeingSettled
+	})
+}
+
+// SettleRefund retries refunding the card share of a refund that is still unsettled, e.g. because
+// the payment terminal provider failed when the refund was recorded.
+func (s *PurchaseService) SettleRefund(ctx context.Context, refundID int) (*models.PurchaseRefund, error) {
+	refund, err := s.sqliteRepo.GetPurchaseRefundByID(refundID)
+	if err != nil {
+		return nil, err
+	}
+
+	if refund.IsSettled() {
+		return refund, ErrRefundAlreadySettled
+	}
+
+	purchase, err := s.sqliteRepo.GetPurchaseByID(refund.PurchaseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get purchase by ID: %w", err)
+	}
+
+	if err := s.settleRefund(ctx, *refund, purchase.SumupTransactionID); err != nil {
+		return nil, err
+	}
+
+	return s.sqliteRepo.GetPurchaseRefundByID(refundID)
+}
+
+// storeRefund stores the refund, records it in the journal and credits the amount paid back with
+// the VOUCHER payment method to the redeemed vouchers.
+func (s *PurchaseService) storeRefund(
+	txRepo sqlite.RepositoryInterface,
+	refund models.PurchaseRefund,
+) (models.PurchaseRefund, error) {
+	stored, err := txRepo.StorePurchaseRefund(refund)
+	if err != nil {
+		return stored, fmt.Errorf("failed to store refund: %w", err)
+	}
+
+	if err := s.journalRefund(txRepo, stored, refund.CreatedByID); err != nil {
+		return stored, err
+	}
+
+	voucherAmount := stored.PaymentAmount(models.PaymentMethodVoucher)
+	if !voucherAmount.IsPositive() {
+		return stored, nil
+	}
+
+	if err := txRepo.CreditVoucherRedemptions(stored.PurchaseID, voucherAmount, &stored.ID); err != nil {
+		return stored, fmt.Errorf("failed to credit the vouchers: %w", err)
+	}
+
+	return stored, nil
+}
+
+// markPurchaseRefunded sets the status of a completely refunded purchase and releases its guests.
+func (s *PurchaseService) markPurchaseRefunded(
+	ctx context.Context,
+	txRepo sqlite.RepositoryInterface,
+	purchaseID uuid.UUID,
+	previousStatus models.PurchaseStatus,
+	userID *int,
+) error {
+	ctx = WithTransitionOrigin(ctx, models.PurchaseTransitionSourceUser, userID)
+
+	if _, err := s.transitionStatus(ctx, txRepo, purchaseID, previousStatus, models.PurchaseStatusRefunded); err != nil {
+		return err
+	}
+
+	if err := txRepo.RollbackVisitedGuestsByPurchaseID(purchaseID); err != nil {
+		return fmt.Errorf("failed to rollback visited guests: %w", err)
+	}
+
+	return nil
+}
+
+// buildRefund validates the refund lines against the purchase and returns the refund together
+// with the quantities per purchase item that remain after the refund.
+func (s *PurchaseService) buildRefund(
+	purchase *models.Purchase,
+	items []RefundItemInput,
+) (*models.PurchaseRefund, map[int]uint, error) {
+	remaining := make(map[int]uint, len(purchase.PurchaseItems))
+	purchaseItems := make(map[int]models.PurchaseItem, len(purchase.PurchaseItems))
+	discounts := make(map[int]*refundDiscount, len(purchase.PurchaseItems))
+
+	for _, item := range purchase.PurchaseItems {
+		remaining[item.ID] = item.RemainingQuantity()
+		purchaseItems[item.ID] = item
+		discounts[item.ID] = newRefundDiscount(item)
+	}
+
+	refund := &models.PurchaseRefund{
+		PurchaseID:      purchase.ID,
+		TotalNetPrice:   decimal.Zero,
+		TotalGrossPrice: decimal.Zero,
+	}
+
+	for _, input := range items {
+		item, ok := purchaseItems[input.PurchaseItemID]
+		if !ok {
+			return nil, nil, ErrRefundItemNotFound
+		}
+
+		if input.Quantity == 0 {
+			return nil, nil, ErrInvalidRefundQuantity
+		}
+
+		if input.Quantity > remaining[item.ID] {
+			return nil, nil, ErrRefundQuantityExceedsItem
+		}
+
+		remaining[item.ID] -= input.Quantity
+
+		discountNet, discountGross := discounts[item.ID].take(item, input.Quantity, remaining[item.ID] == 0,
+			s.DecimalPlaces)
+
+		refundItem := models.PurchaseRefundItem{
+			PurchaseItemID:      item.ID,
+			Quantity:            input.Quantity,
+			NetPrice:            item.NetPrice,
+			VATRate:             item.VATRate,
+			DiscountNetAmount:   discountNet,
+			DiscountGrossAmount: discountGross,
+		}
+
+		refund.Items = append(refund.Items, refundItem)
+		refund.TotalNetPrice = refund.TotalNetPrice.Add(refundItem.TotalNetPrice(s.DecimalPlaces))
+		refund.TotalGrossPrice = refund.TotalGrossPrice.Add(refundItem.TotalGrossPrice(s.DecimalPlaces))
+	}
+
+	return refund, remaining, nil
+}
+
+// allocateRefundPayments splits the refunded amount across the payment methods of the purchase
+// by their share of the total gross price. The refund completing a purchase pays back exactly
+// what is left per payment method, so rounding differences of earlier refunds even out.
+func (s *PurchaseService) allocateRefundPayments(
+	purchase *models.Purchase,
+	amount decimal.Decimal,
+	completesPurchase bool,
+) []models.PurchaseRefundPayment {
+	var methods []models.PaymentMethod
+
+	paid := make(map[models.PaymentMethod]decimal.Decimal)
+
+	for _, payment := range purchase.PaymentLines() {
+		if _, ok := paid[payment.PaymentMethod]; !ok {
+			methods = append(methods, payment.PaymentMethod)
+			paid[payment.PaymentMethod] = decimal.Zero
+		}
+
+		paid[payment.PaymentMethod] = paid[payment.PaymentMethod].Add(payment.Amount)
+	}
+
+	payments := make([]models.PurchaseRefundPayment, 0, len(methods))
+	allocated := decimal.Zero
+
+	for i, method := range methods {
+		var share decimal.Decimal
+
+		switch {
+		case completesPurchase:
+			share = paid[method].Sub(purchase.RefundedAmount(method))
+		case i == len(methods)-1:
+			share = amount.Sub(allocated)
+		default:
+			share = amount.Mul(purchase.PaymentShare(paid[method])).Round(s.DecimalPlaces)
+		}
+
+		allocated = allocated.Add(share)
+
+		if share.IsPositive() {
+			payments = append(payments, models.PurchaseRefundPayment{PaymentMethod: method, Amount: share})
+		}
+	}
+
+	return payments
+}
+
+// roundRefundPayments returns the rounding line of the cash paid back, rounded like cash payments.
+// The refund payments keep their exact amounts, so the rounding line is the exact amount minus the
+// cash actually paid back.
+func (s *PurchaseService) roundRefundPayments(refund models.PurchaseRefund) []models.PurchaseRounding {
+	rule, ok := s.roundingRules[models.PaymentMethodCash]
+	if !ok {
+		return nil
+	}
+
+	exact := refund.PaymentAmount(models.PaymentMethodCash)
+
+	difference := exact.Sub(rule.Round(exact))
+	if difference.IsZero() {
+		return nil
+	}
+
+	return []models.PurchaseRounding{{
+		PurchaseID:    refund.PurchaseID,
+		PaymentMethod: models.PaymentMethodCash,
+		Amount:        difference,
+	}}
+}
+
+// rollbackRefundedGuests rolls back the guests of the purchase that are no longer covered by
+// the remaining quantities. The most recently added guests are rolled back first.
+func rollbackRefundedGuests(
+	txRepo sqlite.RepositoryInterface,
+	purchase *models.Purchase,
+	remaining map[int]uint,
+) error {
+	guests, err := txRepo.GetGuestsByPurchaseID(purchase.ID)
+	if errors.Is(err, sqlite.ErrGuestsNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	remainingByProduct := make(map[int]uint)
+	for _, item := range purchase.PurchaseItems {
+		remainingByProduct[item.ProductID] += remaining[item.ID]
+	}
+
+	attendedByProduct := make(map[int]uint)
+	for _, guest := range guests {
+		attendedByProduct[guest.Guestlist.ProductID] += guest.AttendedGuests
+	}
+
+	slices.SortFunc(guests, func(a, b models.Guest) int {
+		return cmp.Compare(b.ID, a.ID)
+	})
+
+	for _, guest := range guests {
+		productID := guest.Guestlist.ProductID
+		if attendedByProduct[productID] <= remainingByProduct[productID] {
+			continue
+		}
+
+		excess := min(attendedByProduct[productID]-remainingByProduct[productID], guest.AttendedGuests)
+		attendedByProduct[productID] -= excess
+
+		guest.AttendedGuests -= excess
+		if guest.AttendedGuests == 0 {
+			guest.PurchaseID = nil
+			guest.ArrivedAt = nil
+		}
+
+		if _, err := txRepo.UpdateGuestByID(guest.ID, guest); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *PurchaseService) recordRefundMetrics(
+	ctx context.Context,
+	purchase *models.Purchase,
+	refund *models.PurchaseRefund,
+) {
+	for _, payment := range refund.Payments {
+		net := decimal.Zero
+		if !refund.TotalGrossPrice.IsZero() {
+			net = refund.TotalNetPrice.Mul(payment.Amount.Div(refund.TotalGrossPrice))
+		}
+
+		s.recordSalesAmount(ctx, payment.Amount, net, string(payment.PaymentMethod), true)
+	}
+
+	recordOrder(ctx, purchase.PaymentMethod, true)
+}
@@ -0,0 +1,466 @@
+package purchase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/potibm/kasseapparat/internal/app/repository/terminal"
+	"github.com/shopspring/decimal"
+)
+
+type MockTerminal struct {
+	Refunds []decimal.Decimal
+	// RefundErr is returned instead of refunding the transaction
+	RefundErr error
+	// Checkout is the checkout reported by the terminal, nil if the terminal does not know it
+	Checkout          *terminal.Checkout
+	CancelledTerminal string
+}
+
+func (m *MockTerminal) GetCheckout(clientTransactionID uuid.UUID) (*terminal.Checkout, error) {
+	if m.Checkout == nil {
+		return nil, terminal.ErrCheckoutNotFound
+	}
+
+	return m.Checkout, nil
+}
+
+func (m *MockTerminal) CancelCheckout(terminalID string) error {
+	m.CancelledTerminal = terminalID
+
+	return nil
+}
+
+func (m *MockTerminal) RefundTransaction(transactionID uuid.UUID, amount decimal.Decimal) error {
+	if m.RefundErr != nil {
+		return m.RefundErr
+	}
+
+	m.Refunds = append(m.Refunds, amount)
+
+	return nil
+}
+
+func newRefundablePurchase() *models.Purchase {
+	transactionID := uuid.New()
+
+	purchase := &models.Purchase{
+		ID:                 uuid.New(),
+		TotalNetPrice:      decimal.NewFromFloat(20.00),
+		TotalGrossPrice:    decimal.NewFromFloat(23.80),
+		PaymentMethod:      models.PaymentMethodSumUp,
+		SumupTransactionID: &transactionID,
+		Status:             models.PurchaseStatusConfirmed,
+		Payments: []models.PurchasePayment{
+			{PaymentMethod: models.PaymentMethodCash, Amount: decimal.NewFromFloat(3.80)},
+			{PaymentMethod: models.PaymentMethodSumUp, Amount: decimal.NewFromFloat(20.00)},
+		},
+		PurchaseItems: []models.PurchaseItem{
+			{
+				ProductID: 1,
+				Quantity:  2,
+				NetPrice:  decimal.NewFromFloat(10.00),
+				VATRate:   decimal.NewFromInt(19),
+			},
+		},
+	}
+	purchase.PurchaseItems[0].ID = 5
+
+	return purchase
+}
+
+// newRefundMockRepository returns a repository with the purchase and an open register session of
+// the refunding user 7.
+func newRefundMockRepository(purchase *models.Purchase) *MockRepository {
+	session := &models.RegisterSession{Status: models.RegisterSessionStatusOpen}
+	session.ID = 3
+	session.CreatedByID = intPtr(7)
+
+	return &MockRepository{StoredPurchase: purchase, OpenSession: session}
+}
+
+func newGuestOfPurchase(id int, purchaseID uuid.UUID) *models.Guest {
+	guest := &models.Guest{
+		AttendedGuests: 1,
+		PurchaseID:     &purchaseID,
+		Guestlist:      models.Guestlist{ProductID: 1},
+	}
+	guest.ID = id
+	guest.MarkAsArrived()
+
+	return guest
+}
+
+func TestRefundPurchaseItemsWithSplitPayment(t *testing.T) {
+	purchase := newRefundablePurchase()
+	firstGuest := newGuestOfPurchase(1, purchase.ID)
+	secondGuest := newGuestOfPurchase(2, purchase.ID)
+
+	mockRepo := newRefundMockRepository(purchase)
+	mockRepo.Guests = map[int]*models.Guest{1: firstGuest, 2: secondGuest}
+	refunder := &MockTerminal{}
+
+	service := &PurchaseService{
+		sqliteRepo:       mockRepo,
+		terminalProvider: refunder,
+		DecimalPlaces:    2,
+	}
+
+	refunded, err := service.RefundPurchaseItems(
+		context.Background(),
+		purchase.ID,
+		[]RefundItemInput{{PurchaseItemID: 5, Quantity: 1}},
+		7,
+	)
+	if err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	if refunded.Status != models.PurchaseStatusConfirmed {
+		t.Errorf("expected purchase to stay confirmed, got %s", refunded.Status)
+	}
+
+	if len(mockRepo.StoredRefunds) != 1 {
+		t.Fatalf("expected 1 stored refund, got %d", len(mockRepo.StoredRefunds))
+	}
+
+	refund := mockRepo.StoredRefunds[0]
+	if !refund.TotalGrossPrice.Equal(decimal.NewFromFloat(11.90)) {
+		t.Errorf("unexpected refund gross total: %s", refund.TotalGrossPrice)
+	}
+
+	if !refund.PaymentAmount(models.PaymentMethodCash).Equal(decimal.NewFromFloat(1.90)) {
+		t.Errorf("unexpected cash refund: %s", refund.PaymentAmount(models.PaymentMethodCash))
+	}
+
+	if refund.RegisterSessionID == nil || *refund.RegisterSessionID != 3 {
+		t.Errorf("expected the cash refund to be taken from register session 3, got %v", refund.RegisterSessionID)
+	}
+
+	if len(refunder.Refunds) != 1 || !refunder.Refunds[0].Equal(decimal.NewFromFloat(10.00)) {
+		t.Errorf("unexpected SumUp refunds: %v", refunder.Refunds)
+	}
+
+	if !mockRepo.StoredRefunds[0].IsSettled() {
+		t.Error("expected the refund to be settled after the card has been refunded")
+	}
+
+	if firstGuest.AttendedGuests != 1 || firstGuest.PurchaseID == nil {
+		t.Errorf("first guest should not be rolled back: %+v", firstGuest)
+	}
+
+	if secondGuest.AttendedGuests != 0 || secondGuest.PurchaseID != nil || secondGuest.ArrivedAt != nil {
+		t.Errorf("second guest should be rolled back: %+v", secondGuest)
+	}
+}
+
+func TestRefundPurchaseItemsWithQuantityExceedingItem(t *testing.T) {
+	purchase := newRefundablePurchase()
+	refunder := &MockTerminal{}
+
+	service := &PurchaseService{
+		sqliteRepo:       newRefundMockRepository(purchase),
+		terminalProvider: refunder,
+		DecimalPlaces:    2,
+	}
+
+	_, err := service.RefundPurchaseItems(
+		context.Background(),
+		purchase.ID,
+		[]RefundItemInput{{PurchaseItemID: 5, Quantity: 3}},
+		7,
+	)
+	if err != ErrRefundQuantityExceedsItem {
+		t.Fatalf("expected ErrRefundQuantityExceedsItem, got %v", err)
+	}
+
+	if len(refunder.Refunds) != 0 {
+		t.Errorf("expected no SumUp refund for an invalid refund, got %v", refunder.Refunds)
+	}
+}
+
+func TestRefundPurchaseItemsWithCashRequiresOpenRegisterSession(t *testing.T) {
+	purchase := newRefundablePurchase()
+	mockRepo := &MockRepository{StoredPurchase: purchase}
+	refunder := &MockTerminal{}
+
+	service := &PurchaseService{
+		sqliteRepo:       mockRepo,
+		terminalProvider: refunder,
+		DecimalPlaces:    2,
+	}
+
+	_, err := service.RefundPurchaseItems(
+		context.Background(),
+		purchase.ID,
+		[]RefundItemInput{{PurchaseItemID: 5, Quantity: 1}},
+		7,
+	)
+	if err != ErrNoOpenRegisterSession {
+		t.Fatalf("expected ErrNoOpenRegisterSession, got %v", err)
+	}
+
+	if len(mockRepo.StoredRefunds) != 0 || len(refunder.Refunds) != 0 {
+		t.Errorf("expected nothing to be refunded, got %d refunds and SumUp refunds %v",
+			len(mockRepo.StoredRefunds), refunder.Refunds)
+	}
+}
+
+func TestRefundPurchaseItemsKeepsRefundPendingWhenCardRefundFails(t *testing.T) {
+	purchase := newRefundablePurchase()
+	mockRepo := newRefundMockRepository(purchase)
+
+	service := &PurchaseService{
+		sqliteRepo:       mockRepo,
+		terminalProvider: &MockTerminal{RefundErr: errors.New("terminal unavailable")},
+		DecimalPlaces:    2,
+	}
+
+	_, err := service.RefundPurchaseItems(
+		context.Background(),
+		purchase.ID,
+		[]RefundItemInput{{PurchaseItemID: 5, Quantity: 1}},
+		7,
+	)
+	if !errors.Is(err, ErrRefundNotSettled) {
+		t.Fatalf("expected ErrRefundNotSettled, got %v", err)
+	}
+
+	if len(mockRepo.StoredRefunds) != 1 {
+		t.Fatalf("expected the refund to be recorded, got %d refunds", len(mockRepo.StoredRefunds))
+	}
+
+	if mockRepo.StoredRefunds[0].IsSettled() {
+		t.Error("expected the refund to stay pending")
+	}
+
+	// the pending refund counts, so the remaining quantity cannot be refunded twice
+	_, err = service.RefundPurchaseItems(
+		context.Background(),
+		purchase.ID,
+		[]RefundItemInput{{PurchaseItemID: 5, Quantity: 2}},
+		7,
+	)
+	if err != ErrRefundQuantityExceedsItem {
+		t.Fatalf("expected ErrRefundQuantityExceedsItem, got %v", err)
+	}
+}
+
+func TestSettleRefundRetriesTheCardRefund(t *testing.T) {
+	purchase := newRefundablePurchase()
+	mockRepo := newRefundMockRepository(purchase)
+	mockTerminal := &MockTerminal{RefundErr: errors.New("terminal unavailable")}
+
+	service := &PurchaseService{
+		sqliteRepo:       mockRepo,
+		terminalProvider: mockTerminal,
+		DecimalPlaces:    2,
+	}
+
+	_, err := service.RefundPurchaseItems(
+		context.Background(),
+		purchase.ID,
+		[]RefundItemInput{{PurchaseItemID: 5, Quantity: 1}},
+		7,
+	)
+	if !errors.Is(err, ErrRefundNotSettled) {
+		t.Fatalf("expected ErrRefundNotSettled, got %v", err)
+	}
+
+	refundID := mockRepo.StoredRefunds[0].ID
+
+	mockTerminal.RefundErr = nil
+
+	refund, err := service.SettleRefund(context.Background(), refundID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !refund.IsSettled() {
+		t.Error("expected the refund to be settled")
+	}
+
+	if len(mockTerminal.Refunds) != 1 {
+		t.Fatalf("expected one card refund, got %d", len(mockTerminal.Refunds))
+	}
+
+	if _, err := service.SettleRefund(context.Background(), refundID); err != ErrRefundAlreadySettled {
+		t.Errorf("expected ErrRefundAlreadySettled, got %v", err)
+	}
+
+	if len(mockTerminal.Refunds) != 1 {
+		t.Errorf("expected the card to be refunded once, got %d refunds", len(mockTerminal.Refunds))
+	}
+}
+
+func TestSettleRefundSkipsRefundsBeingSettled(t *testing.T) {
+	purchase := newRefundablePurchase()
+	mockRepo := newRefundMockRepository(purchase)
+	mockTerminal := &MockTerminal{RefundErr: errors.New("terminal unavailable")}
+
+	service := &PurchaseService{
+		sqliteRepo:       mockRepo,
+		terminalProvider: mockTerminal,
+		DecimalPlaces:    2,
+	}
+
+	_, err := service.RefundPurchaseItems(
+		context.Background(),
+		purchase.ID,
+		[]RefundItemInput{{PurchaseItemID: 5, Quantity: 1}},
+		7,
+	)
+	if !errors.Is(err, ErrRefundNotSettled) {
+		t.Fatalf("expected ErrRefundNotSettled, got %v", err)
+	}
+
+	if mockRepo.StoredRefunds[0].SettlingAt != nil {
+		t.Fatal("expected the claim of the failed refund to be released")
+	}
+
+	// another settlement has claimed the refund meanwhile
+	refundID := mockRepo.StoredRefunds[0].ID
+	if claimed, _ := mockRepo.ClaimPurchaseRefundSettlement(refundID); !claimed {
+		t.Fatal("expected the refund to be claimed")
+	}
+
+	mockTerminal.RefundErr = nil
+
+	if _, err := service.SettleRefund(context.Background(), refundID); !errors.Is(err, ErrRefundBeingSettled) {
+		t.Errorf("expected ErrRefundBeingSettled, got %v", err)
+	}
+
+	if len(mockTerminal.Refunds) != 0 {
+		t.Errorf("expected the card not to be refunded, got %d refunds", len(mockTerminal.Refunds))
+	}
+}
+
+func TestRefundPurchaseAfterPartialRefund(t *testing.T) {
+	purchase := newRefundablePurchase()
+	mockRepo := newRefundMockRepository(purchase)
+	refunder := &MockTerminal{}
+
+	service := &PurchaseService{
+		sqliteRepo:       mockRepo,
+		terminalProvider: refunder,
+		DecimalPlaces:    2,
+	}
+
+	_, err := service.RefundPurchaseItems(
+		context.Background(),
+		purchase.ID,
+		[]RefundItemInput{{PurchaseItemID: 5, Quantity: 1}},
+		7,
+	)
+	if err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	refunded, err := service.RefundPurchase(context.Background(), purchase.ID, 7)
+	if err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	if refunded.Status != models.PurchaseStatusRefunded {
+		t.Errorf("expected purchase to be refunded, got %s", refunded.Status)
+	}
+
+	if len(refunder.Refunds) != 2 || !refunder.Refunds[1].Equal(decimal.NewFromFloat(10.00)) {
+		t.Errorf("unexpected SumUp refunds: %v", refunder.Refunds)
+	}
+
+	if !refunded.RefundedAmount(models.PaymentMethodCash).Equal(decimal.NewFromFloat(3.80)) {
+		t.Errorf("unexpected cash refund total: %s", refunded.RefundedAmount(models.PaymentMethodCash))
+	}
+}
+
+func TestRefundPurchaseRecordsJournalEntries(t *testing.T) {
+	purchase := newRefundablePurchase()
+	mockRepo := newRefundMockRepository(purchase)
+
+	service := &PurchaseService{
+		sqliteRepo:       mockRepo,
+		terminalProvider: &MockTerminal{},
+		DecimalPlaces:    2,
+	}
+
+	_, err := service.RefundPurchaseItems(
+		context.Background(),
+		purchase.ID,
+		[]RefundItemInput{{PurchaseItemID: 5, Quantity: 1}},
+		7,
+	)
+	if err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	if _, err := service.RefundPurchase(context.Background(), purchase.ID, 7); err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	expected := []models.JournalEventType{
+		models.JournalEventPurchaseRefunded,
+		models.JournalEventPurchaseRefunded,
+		models.JournalEventPurchaseStatusChanged,
+	}
+	if len(mockRepo.Journal) != len(expected) {
+		t.Fatalf("expected %d journal entries, got %d", len(expected), len(mockRepo.Journal))
+	}
+
+	var previous *models.JournalEntry
+
+	for i, entry := range mockRepo.Journal {
+		if entry.EventType != expected[i] {
+			t.Errorf("expected journal entry %d to be %s, got %s", i, expected[i], entry.EventType)
+		}
+
+		if err := entry.VerifyChain(previous); err != nil {
+			t.Errorf("journal entry %d breaks the chain: %v", i, err)
+		}
+
+		previous = &mockRepo.Journal[i]
+	}
+
+	if !strings.Contains(mockRepo.Journal[2].Payload, `"to":"refunded"`) {
+		t.Errorf("unexpected status change payload: %s", mockRepo.Journal[2].Payload)
+	}
+}
+
+func TestRefundPurchaseItemsRoundsTheCashPaidBack(t *testing.T) {
+	purchase := newRefundablePurchase()
+	mockRepo := newRefundMockRepository(purchase)
+
+	service := &PurchaseService{
+		sqliteRepo:       mockRepo,
+		terminalProvider: &MockTerminal{},
+		DecimalPlaces:    2,
+		roundingRules:    cashRounding(models.RoundingModeNearest),
+	}
+
+	if _, err := service.RefundPurchaseItems(
+		context.Background(),
+		purchase.ID,
+		[]RefundItemInput{{PurchaseItemID: 5, Quantity: 1}},
+		7,
+	); err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	refund := mockRepo.StoredRefunds[0]
+	if !refund.PaymentAmount(models.PaymentMethodCash).Equal(decimal.NewFromFloat(1.90)) {
+		t.Errorf("the refund payment must stay exact, got %s", refund.PaymentAmount(models.PaymentMethodCash))
+	}
+
+	if len(refund.Roundings) != 1 || !refund.Roundings[0].Amount.Equal(decimal.NewFromFloat(-0.10)) ||
+		refund.Roundings[0].PurchaseID != purchase.ID {
+		t.Errorf("expected a cash rounding of -0.10, got %+v", refund.Roundings)
+	}
+
+	if !refund.PaidBackAmount(models.PaymentMethodCash).Equal(decimal.NewFromFloat(2.00)) {
+		t.Errorf("expected 2.00 cash to be paid back, got %s", refund.PaidBackAmount(models.PaymentMethodCash))
+	}
+}
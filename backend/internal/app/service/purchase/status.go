@@ -0,0 +1,119 @@
+package purchase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/potibm/kasseapparat/internal/app/repository/sqlite"
+)
+
+var ErrIllegalStatusTransition = errors.New("the status of the purchase cannot be changed")
+
+// statusTransitions lists the statuses a purchase can change to. A pending purchase awaits its
+// payment, a confirmed one can still be refunded, all other statuses are final.
+var statusTransitions = map[models.PurchaseStatus][]models.PurchaseStatus{
+	models.PurchaseStatusPending: {
+		models.PurchaseStatusConfirmed,
+		models.PurchaseStatusFailed,
+		models.PurchaseStatusCancelled,
+	},
+	models.PurchaseStatusConfirmed: {
+		models.PurchaseStatusRefunded,
+	},
+}
+
+// CanTransition reports whether a purchase can change from one status to the other.
+func CanTransition(from, to models.PurchaseStatus) bool {
+	return slices.Contains(statusTransitions[from], to)
+}
+
+func checkTransition(from, to models.PurchaseStatus) error {
+	if CanTransition(from, to) {
+		return nil
+	}
+
+	// e.g. a payment reported for a purchase cancelled meanwhile
+	if CanTransition(models.PurchaseStatusPending, to) {
+		return ErrPurchaseNotPending
+	}
+
+	return fmt.Errorf("%w from %s to %s", ErrIllegalStatusTransition, from, to)
+}
+
+// TransitionOrigin tells through which channel and by whom the status of a purchase is changed.
+type TransitionOrigin struct {
+	Source  models.PurchaseTransitionSource
+	ActorID *int
+}
+
+type transitionOriginKey struct{}
+
+// WithTransitionOrigin returns a context in which status changes are recorded with the given origin.
+// Without an origin, status changes are recorded as made by the system.
+func WithTransitionOrigin(ctx context.Context, source models.PurchaseTransitionSource, actorID *int) context.Context {
+	return context.WithValue(ctx, transitionOriginKey{}, TransitionOrigin{Source: source, ActorID: actorID})
+}
+
+func transitionOrigin(ctx context.Context) TransitionOrigin {
+	if origin, ok := ctx.Value(transitionOriginKey{}).(TransitionOrigin); ok {
+		return origin
+	}
+
+	return TransitionOrigin{Source: models.PurchaseTransitionSourceSystem}
+}
+
+// transitionStatus changes the status of the purchase within the transaction, if the transition is
+// allowed, and records it in the history of the purchase and in the journal.
+func (s *PurchaseService) transitionStatus(
+	ctx context.Context,
+	txRepo sqlite.RepositoryInterface,
+	purchaseID uuid.UUID,
+	from models.PurchaseStatus,
+	to models.PurchaseStatus,
+) (*models.Purchase, error) {
+	if err := checkTransition(from, to); err != nil {
+		return nil, err
+	}
+
+	origin := transitionOrigin(ctx)
+
+	if err := recordTransition(txRepo, purchaseID, from, to, origin); err != nil {
+		return nil, err
+	}
+
+	purchase, err := txRepo.UpdatePurchaseStatusByID(purchaseID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.journalStatusChange(txRepo, purchase, from, origin.ActorID); err != nil {
+		return nil, err
+	}
+
+	return purchase, nil
+}
+
+func recordTransition(
+	txRepo sqlite.RepositoryInterface,
+	purchaseID uuid.UUID,
+	from models.PurchaseStatus,
+	to models.PurchaseStatus,
+	origin TransitionOrigin,
+) error {
+	err := txRepo.StorePurchaseStatusTransition(models.PurchaseStatusTransition{
+		PurchaseID: purchaseID,
+		FromStatus: from,
+		ToStatus:   to,
+		Source:     origin.Source,
+		ActorID:    origin.ActorID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record the status transition: %w", err)
+	}
+
+	return nil
+}
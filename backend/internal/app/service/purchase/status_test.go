@@ -0,0 +1,104 @@
+package purchase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/potibm/kasseapparat/internal/app/models"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to models.PurchaseStatus
+		expected bool
+	}{
+		{models.PurchaseStatusPending, models.PurchaseStatusConfirmed, true},
+		{models.PurchaseStatusPending, models.PurchaseStatusFailed, true},
+		{models.PurchaseStatusPending, models.PurchaseStatusCancelled, true},
+		{models.PurchaseStatusPending, models.PurchaseStatusRefunded, false},
+		{models.PurchaseStatusConfirmed, models.PurchaseStatusRefunded, true},
+		{models.PurchaseStatusConfirmed, models.PurchaseStatusCancelled, false},
+		{models.PurchaseStatusCancelled, models.PurchaseStatusConfirmed, false},
+		{models.PurchaseStatusFailed, models.PurchaseStatusConfirmed, false},
+		{models.PurchaseStatusRefunded, models.PurchaseStatusConfirmed, false},
+	}
+
+	for _, tt := range tests {
+		if got := CanTransition(tt.from, tt.to); got != tt.expected {
+			t.Errorf("CanTransition(%s, %s) = %v, expected %v", tt.from, tt.to, got, tt.expected)
+		}
+	}
+}
+
+func TestCheckTransitionRejectsIllegalTransitions(t *testing.T) {
+	if err := checkTransition(models.PurchaseStatusCancelled, models.PurchaseStatusFailed); !errors.Is(
+		err,
+		ErrPurchaseNotPending,
+	) {
+		t.Errorf("expected ErrPurchaseNotPending, got %v", err)
+	}
+
+	if err := checkTransition(models.PurchaseStatusFailed, models.PurchaseStatusRefunded); !errors.Is(
+		err,
+		ErrIllegalStatusTransition,
+	) {
+		t.Errorf("expected ErrIllegalStatusTransition, got %v", err)
+	}
+}
+
+func TestStatusTransitionsAreRecordedWithTheirOrigin(t *testing.T) {
+	service, mockRepo := newMockPurchaseService(withStock(5))
+
+	purchase, err := service.CreatePendingPurchase(context.Background(), stockPurchaseInput(1), 7)
+	if err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	ctx := WithTransitionOrigin(context.Background(), models.PurchaseTransitionSourcePoller, nil)
+	if _, err := service.FailPurchase(ctx, purchase.ID); err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	if len(mockRepo.Transitions) != 2 {
+		t.Fatalf("expected 2 transitions, got %d", len(mockRepo.Transitions))
+	}
+
+	created := mockRepo.Transitions[0]
+	if created.FromStatus != "" || created.ToStatus != models.PurchaseStatusPending ||
+		created.Source != models.PurchaseTransitionSourceUser || created.ActorID == nil || *created.ActorID != 7 {
+		t.Errorf("unexpected transition for the creation: %+v", created)
+	}
+
+	failed := mockRepo.Transitions[1]
+	if failed.FromStatus != models.PurchaseStatusPending || failed.ToStatus != models.PurchaseStatusFailed ||
+		failed.Source != models.PurchaseTransitionSourcePoller || failed.ActorID != nil {
+		t.Errorf("unexpected transition for the failure: %+v", failed)
+	}
+}
+
+func TestStatusTransitionsDefaultToTheSystem(t *testing.T) {
+	service, mockRepo := newMockPurchaseService(withStock(5))
+
+	purchase, err := service.CreatePendingPurchase(context.Background(), stockPurchaseInput(1), 7)
+	if err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	if _, err := service.CancelPurchase(context.Background(), purchase.ID); err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	if source := mockRepo.Transitions[len(mockRepo.Transitions)-1].Source; source != models.PurchaseTransitionSourceSystem {
+		t.Errorf("expected the transition to be made by the system, got %s", source)
+	}
+
+	// a rejected transition is not recorded
+	if _, err := service.FailPurchase(context.Background(), purchase.ID); !errors.Is(err, ErrPurchaseNotPending) {
+		t.Errorf("expected ErrPurchaseNotPending, got %v", err)
+	}
+
+	if len(mockRepo.Transitions) != 2 {
+		t.Errorf("expected 2 transitions, got %d", len(mockRepo.Transitions))
+	}
+}
@@ -0,0 +1,209 @@
+package purchase
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/potibm/kasseapparat/internal/app/repository/sqlite"
+)
+
+var ErrStockExhausted = errors.New("not enough stock left")
+
+// StockExhaustedError is returned when a purchase takes more of a product than is left in stock.
+type StockExhaustedError struct {
+	Name      string
+	Available int
+}
+
+func (e *StockExhaustedError) Error() string {
+	return fmt.Sprintf("not enough stock left for %s, %d available", e.Name, max(e.Available, 0))
+}
+
+func (e *StockExhaustedError) Unwrap() error {
+	return ErrStockExhausted
+}
+
+// stockChange is the quantity of a product or variant taken from the stock, or given back to it
+// when negative.
+type stockChange struct {
+	productID int
+	variantID *int
+	quantity  int
+}
+
+// stockOrigin is the purchase and the user moving the stock, recorded with the inventory movements.
+type stockOrigin struct {
+	purchaseID uuid.UUID
+	userID     *int
+	note       string
+}
+
+// reservedStock returns the stock changes of purchase items taken from the stock.
+func reservedStock(items []models.PurchaseItem) []stockChange {
+	return collectStockChanges(items, func(item models.PurchaseItem) int {
+		return int(item.Quantity)
+	})
+}
+
+// releasedStock returns the stock changes of purchase items given back to the stock. Refunded
+// quantities have been given back before.
+func releasedStock(items []models.PurchaseItem) []stockChange {
+	return collectStockChanges(items, func(item models.PurchaseItem) int {
+		return -int(item.RemainingQuantity())
+	})
+}
+
+// refundedStock returns the stock changes of refunded purchase items given back to the stock.
+func refundedStock(purchase *models.Purchase, refundItems []models.PurchaseRefundItem) []stockChange {
+	items := make([]models.PurchaseItem, 0, len(refundItems))
+
+	for _, refundItem := range refundItems {
+		for _, item := range purchase.PurchaseItems {
+			if item.ID == refundItem.PurchaseItemID {
+				item.Quantity = refundItem.Quantity
+				items = append(items, item)
+			}
+		}
+	}
+
+	return collectStockChanges(items, func(item models.PurchaseItem) int {
+		return -int(item.Quantity)
+	})
+}
+
+func collectStockChanges(items []models.PurchaseItem, quantity func(models.PurchaseItem) int) []stockChange {
+	changes := make([]stockChange, 0, len(items))
+
+	for _, item := range items {
+		i := len(changes)
+
+		for j, change := range changes {
+			if change.productID == item.ProductID && equalIntPtr(change.variantID, item.VariantID) {
+				i = j
+
+				break
+			}
+		}
+
+		if i == len(changes) {
+			changes = append(changes, stockChange{productID: item.ProductID, variantID: item.VariantID})
+		}
+
+		changes[i].quantity += quantity(item)
+	}
+
+	return changes
+}
+
+func equalIntPtr(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+// updateStock records the changes as sales or refunds in the inventory ledger, checks the stock on
+// hand of the changed products and variants and flips their sold-out flag. It runs in the
+// transaction storing the purchase changes, which holds the write lock of the database, so the
+// ledger it sums cannot change concurrently.
+func updateStock(txRepo sqlite.RepositoryInterface, origin stockOrigin, changes []stockChange) error {
+	for _, change := range changes {
+		if change.quantity == 0 {
+			continue
+		}
+
+		if err := recordStockMovement(txRepo, origin, change); err != nil {
+			return err
+		}
+
+		if err := updateItemStock(txRepo, change); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func recordStockMovement(txRepo sqlite.RepositoryInterface, origin stockOrigin, change stockChange) error {
+	movementType := models.InventoryMovementSale
+	if change.quantity < 0 {
+		movementType = models.InventoryMovementRefund
+	}
+
+	_, err := txRepo.StoreInventoryMovement(models.InventoryMovement{
+		ProductID:   change.productID,
+		VariantID:   change.variantID,
+		Type:        movementType,
+		Quantity:    -change.quantity,
+		PurchaseID:  &origin.purchaseID,
+		CreatedByID: origin.userID,
+		Note:        origin.note,
+	})
+
+	return err
+}
+
+func updateItemStock(txRepo sqlite.RepositoryInterface, change stockChange) error {
+	product, err := txRepo.GetProductByID(change.productID)
+	if errors.Is(err, sqlite.ErrProductNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if change.variantID != nil {
+		return updateVariantStock(txRepo, product, *change.variantID, change.quantity)
+	}
+
+	if !product.TracksStock() {
+		return nil
+	}
+
+	onHand, err := txRepo.GetStockOnHand(product.ID, nil)
+	if err != nil {
+		return err
+	}
+
+	soldOut, err := stockSoldOut(product.Name, onHand, change.quantity, product.SoldOut)
+	if err != nil || soldOut == product.SoldOut {
+		return err
+	}
+
+	return txRepo.SetProductSoldOut(product.ID, soldOut)
+}
+
+func updateVariantStock(txRepo sqlite.RepositoryInterface, product *models.Product, variantID, quantity int) error {
+	variant, ok := product.Variant(variantID)
+	if !ok || !variant.TracksStock() {
+		return nil
+	}
+
+	onHand, err := txRepo.GetStockOnHand(product.ID, &variant.ID)
+	if err != nil {
+		return err
+	}
+
+	name := product.Name + " (" + variant.Name + ")"
+
+	soldOut, err := stockSoldOut(name, onHand, quantity, variant.SoldOut)
+	if err != nil || soldOut == variant.SoldOut {
+		return err
+	}
+
+	return txRepo.SetProductVariantSoldOut(variant.ID, soldOut)
+}
+
+// stockSoldOut returns the sold-out flag of a stock once the quantity was taken from the stock
+// left on hand. Taking more than was left fails.
+func stockSoldOut(name string, onHand, quantity int, soldOut bool) (bool, error) {
+	if quantity > 0 && onHand < 0 {
+		return soldOut, &StockExhaustedError{Name: name, Available: onHand + quantity}
+	}
+
+	return models.SoldOutForStock(soldOut, onHand+quantity, onHand), nil
+}
@@ -0,0 +1,112 @@
+package purchase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/shopspring/decimal"
+)
+
+func stockPurchaseInput(quantity uint) PurchaseInput {
+	net := decimal.NewFromFloat(10.00).Mul(decimal.NewFromUint64(uint64(quantity)))
+
+	return PurchaseInput{
+		PaymentMethod:   models.PaymentMethodSumUp,
+		TotalNetPrice:   net,
+		TotalGrossPrice: decimal.NewFromFloat(11.90).Mul(decimal.NewFromUint64(uint64(quantity))),
+		Cart: []PurchaseCartItem{
+			{ID: 1, Quantity: quantity, NetPrice: decimal.NewFromFloat(10.00)},
+		},
+	}
+}
+
+func TestCreatePurchaseFailsWhenStockIsExhausted(t *testing.T) {
+	service, mockRepo := newMockPurchaseService(withStock(1))
+
+	_, err := service.CreatePendingPurchase(context.Background(), stockPurchaseInput(2), 7)
+	if !errors.Is(err, ErrStockExhausted) {
+		t.Fatalf("expected ErrStockExhausted, got %v", err)
+	}
+
+	var stockErr *StockExhaustedError
+	if !errors.As(err, &stockErr) || stockErr.Available != 1 || stockErr.Name != "Shirt" {
+		t.Errorf("unexpected stock error: %v", err)
+	}
+
+	if mockRepo.Products[1].SoldOut {
+		t.Error("the product must not be flagged sold out by a failed purchase")
+	}
+}
+
+func TestCreatePurchaseFlagsSoldOutAtZeroAndCancelReleasesStock(t *testing.T) {
+	service, mockRepo := newMockPurchaseService(withStock(2))
+
+	purchase, err := service.CreatePendingPurchase(context.Background(), stockPurchaseInput(2), 7)
+	if err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	if !mockRepo.Products[1].SoldOut {
+		t.Fatal("expected the product to be sold out once the last items are reserved")
+	}
+
+	if _, err := service.CancelPurchase(context.Background(), purchase.ID); err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	if mockRepo.Products[1].SoldOut {
+		t.Error("expected the cancelled purchase to release the stock")
+	}
+}
+
+func TestCreatePurchaseChecksTheStockOnHandOfTheInventoryLedger(t *testing.T) {
+	service, mockRepo := newMockPurchaseService(withStock(1))
+	mockRepo.Products[1].TotalStock = 100
+
+	_, err := service.CreatePendingPurchase(context.Background(), stockPurchaseInput(2), 7)
+	if !errors.Is(err, ErrStockExhausted) {
+		t.Fatalf("expected ErrStockExhausted, got %v", err)
+	}
+}
+
+func TestCreatePurchaseWithoutTrackedStock(t *testing.T) {
+	service, mockRepo := newMockPurchaseService(withStock(0))
+	mockRepo.Products[1].TrackStock = false
+
+	if _, err := service.CreatePendingPurchase(context.Background(), stockPurchaseInput(3), 7); err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	if mockRepo.Products[1].SoldOut {
+		t.Error("a product without tracked stock must not be flagged sold out")
+	}
+}
+
+func TestStockChangesAreRecordedInTheInventoryLedger(t *testing.T) {
+	service, mockRepo := newMockPurchaseService(withStock(5))
+
+	purchase, err := service.CreatePendingPurchase(context.Background(), stockPurchaseInput(2), 7)
+	if err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	if _, err := service.CancelPurchase(context.Background(), purchase.ID); err != nil {
+		t.Fatalf(errUnexpected, err)
+	}
+
+	if len(mockRepo.InventoryMovements) != 2 {
+		t.Fatalf("expected a sale and a refund movement, got %d movements", len(mockRepo.InventoryMovements))
+	}
+
+	sale, refund := mockRepo.InventoryMovements[0], mockRepo.InventoryMovements[1]
+
+	if sale.Type != models.InventoryMovementSale || sale.Quantity != -2 || *sale.CreatedByID != 7 {
+		t.Errorf("unexpected sale movement: %+v", sale)
+	}
+
+	if refund.Type != models.InventoryMovementRefund || refund.Quantity != 2 || *refund.PurchaseID != purchase.ID {
+		t.Errorf("unexpected refund movement: %+v", refund)
+	}
+}
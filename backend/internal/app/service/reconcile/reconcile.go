@@ -0,0 +1,355 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/potibm/kasseapparat/internal/app/repository/sqlite"
+	"github.com/potibm/kasseapparat/internal/app/repository/sumup"
+	purchaseService "github.com/potibm/kasseapparat/internal/app/service/purchase"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	purchaseBatchSize = 500
+	// the transaction of a purchase is created a little after the purchase, so both are fetched for a
+	// wider period to match the ones close to its start and end
+	periodMargin = time.Hour
+)
+
+var ErrInvalidPeriod = errors.New("the start of the period must be before its end")
+
+type TransactionReader interface {
+	GetTransactionsInPeriod(oldestTime, newestTime time.Time) ([]sumup.Transaction, error)
+}
+
+type PurchaseRepository interface {
+	GetPurchases(
+		limit int,
+		offset int,
+		sort string,
+		order string,
+		filters sqlite.PurchaseFilters,
+	) ([]models.Purchase, error)
+	UpdatePurchaseSumupTransactionIDByID(id, sumupTransactionID uuid.UUID) (*models.Purchase, error)
+}
+
+type PurchaseStatusService interface {
+	FinalizePurchase(ctx context.Context, id uuid.UUID) (*models.Purchase, error)
+	FailPurchase(ctx context.Context, id uuid.UUID) (*models.Purchase, error)
+	CancelPurchase(ctx context.Context, id uuid.UUID) (*models.Purchase, error)
+}
+
+type ReconcileService struct {
+	transactions  TransactionReader
+	purchases     PurchaseRepository
+	statusService PurchaseStatusService
+	decimalPlaces int32
+}
+
+type IssueKind string
+
+const (
+	// IssueOrphanedTransaction is a successful SumUp transaction without a purchase.
+	IssueOrphanedTransaction IssueKind = "orphaned_transaction"
+	// IssueOrphanedPurchase is a confirmed or refunded purchase without a SumUp transaction.
+	IssueOrphanedPurchase IssueKind = "orphaned_purchase"
+	// IssueAmountMismatch is a SumUp transaction that charged another amount than its purchase.
+	IssueAmountMismatch IssueKind = "amount_mismatch"
+	// IssueStatusMismatch is a purchase whose status does not match the status of its SumUp transaction.
+	IssueStatusMismatch IssueKind = "status_mismatch"
+)
+
+// Issue is a disagreement between a SumUp transaction and a purchase. The fields of the side that
+// is missing are empty.
+type Issue struct {
+	Kind                IssueKind
+	ClientTransactionID uuid.UUID
+	PurchaseID          uuid.UUID
+	PurchaseStatus      models.PurchaseStatus
+	PurchaseAmount      decimal.Decimal
+	TransactionID       uuid.UUID
+	TransactionCode     string
+	TransactionStatus   string
+	TransactionAmount   decimal.Decimal
+	// RepairedStatus is the status a pending purchase has been changed to, RepairError why that failed.
+	RepairedStatus models.PurchaseStatus
+	RepairError    string
+}
+
+func (i Issue) Repaired() bool {
+	return i.RepairedStatus != ""
+}
+
+type Result struct {
+	From             time.Time
+	To               time.Time
+	TransactionCount int
+	PurchaseCount    int
+	MatchedCount     int
+	Issues           []Issue
+}
+
+// Consistent reports whether the SumUp transactions and the purchases agree, apart from the issues
+// that have been repaired.
+func (r Result) Consistent() bool {
+	for _, issue := range r.Issues {
+		if !issue.Repaired() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// purchaseStatuses lists the statuses a purchase can have for the status of its SumUp transaction.
+var purchaseStatuses = map[sumup.TransactionStatus][]models.PurchaseStatus{
+	sumup.StatusPending:    {models.PurchaseStatusPending},
+	sumup.StatusSuccessful: {models.PurchaseStatusConfirmed},
+	// a purchase refunded in part stays confirmed
+	sumup.StatusRefunded:  {models.PurchaseStatusRefunded, models.PurchaseStatusConfirmed},
+	sumup.StatusFailed:    {models.PurchaseStatusFailed, models.PurchaseStatusCancelled},
+	sumup.StatusCancelled: {models.PurchaseStatusCancelled, models.PurchaseStatusFailed},
+}
+
+func NewReconcileService(
+	transactions TransactionReader,
+	purchases PurchaseRepository,
+	statusService PurchaseStatusService,
+	decimalPlaces int32,
+) *ReconcileService {
+	return &ReconcileService{
+		transactions:  transactions,
+		purchases:     purchases,
+		statusService: statusService,
+		decimalPlaces: decimalPlaces,
+	}
+}
+
+// Reconcile matches the SumUp transactions and the purchases created in the period by their client
+// transaction ID and reports every disagreement. With repair, purchases still pending whose SumUp
+// transaction is final are changed to the status of the transaction, with the origin of the context.
+func (s *ReconcileService) Reconcile(ctx context.Context, from, to time.Time, repair bool) (Result, error) {
+	result := Result{From: from, To: to}
+
+	if !from.Before(to) {
+		return result, ErrInvalidPeriod
+	}
+
+	transactions, err := s.transactions.GetTransactionsInPeriod(from.Add(-periodMargin), to.Add(periodMargin))
+	if err != nil {
+		return result, fmt.Errorf("failed to get the SumUp transactions: %w", err)
+	}
+
+	purchases, err := s.getPurchases(from.Add(-periodMargin), to.Add(periodMargin))
+	if err != nil {
+		return result, fmt.Errorf("failed to get the purchases: %w", err)
+	}
+
+	transactionsByClientID := indexTransactions(transactions)
+	matched := make(map[uuid.UUID]bool, len(purchases))
+
+	for i := range purchases {
+		purchase := &purchases[i]
+		transaction, ok := transactionsByClientID[*purchase.SumupClientTransactionID]
+		matched[*purchase.SumupClientTransactionID] = ok
+
+		if !inPeriod(purchase.CreatedAt, from, to) {
+			continue
+		}
+
+		result.PurchaseCount++
+
+		if !ok {
+			if purchase.Status == models.PurchaseStatusConfirmed || purchase.Status == models.PurchaseStatusRefunded {
+				result.Issues = append(result.Issues, s.newIssue(IssueOrphanedPurchase, purchase, nil))
+			}
+
+			continue
+		}
+
+		result.MatchedCount++
+		result.Issues = append(result.Issues, s.compare(ctx, purchase, transaction, repair)...)
+	}
+
+	for i := range transactions {
+		transaction := &transactions[i]
+		if !inPeriod(transaction.CreatedAt, from, to) {
+			continue
+		}
+
+		result.TransactionCount++
+
+		if matched[transaction.ClientTransactionID] || transactionStatus(*transaction) != sumup.StatusSuccessful {
+			continue
+		}
+
+		result.Issues = append(result.Issues, s.newIssue(IssueOrphanedTransaction, nil, transaction))
+	}
+
+	return result, nil
+}
+
+func (s *ReconcileService) getPurchases(from, to time.Time) ([]models.Purchase, error) {
+	hasClientTransactionID := true
+	filters := sqlite.PurchaseFilters{
+		HasClientTransactionID: &hasClientTransactionID,
+		CreatedAtGte:           &from,
+		CreatedAtLt:            &to,
+	}
+
+	var purchases []models.Purchase
+
+	for offset := 0; ; offset += purchaseBatchSize {
+		batch, err := s.purchases.GetPurchases(purchaseBatchSize, offset, "id", "ASC", filters)
+		if err != nil {
+			return nil, err
+		}
+
+		purchases = append(purchases, batch...)
+
+		if len(batch) < purchaseBatchSize {
+			return purchases, nil
+		}
+	}
+}
+
+func (s *ReconcileService) compare(
+	ctx context.Context,
+	purchase *models.Purchase,
+	transaction sumup.Transaction,
+	repair bool,
+) []Issue {
+	var issues []Issue
+
+	// only a transaction that charged the card has to match the amount of the purchase
+	amountsAgree := true
+	if chargedCard(transaction) {
+		amountsAgree = purchase.SumupAmount().Round(s.decimalPlaces).Equal(transaction.Amount.Round(s.decimalPlaces))
+	}
+
+	if !amountsAgree {
+		issues = append(issues, s.newIssue(IssueAmountMismatch, purchase, &transaction))
+	}
+
+	if slices.Contains(purchaseStatuses[transactionStatus(transaction)], purchase.Status) {
+		return issues
+	}
+
+	issue := s.newIssue(IssueStatusMismatch, purchase, &transaction)
+
+	if repair && purchase.Status == models.PurchaseStatusPending && amountsAgree {
+		s.repair(ctx, purchase, transaction, &issue)
+	}
+
+	return append(issues, issue)
+}
+
+// repair changes the status of a pending purchase to the final status of its SumUp transaction.
+func (s *ReconcileService) repair(
+	ctx context.Context,
+	purchase *models.Purchase,
+	transaction sumup.Transaction,
+	issue *Issue,
+) {
+	var (
+		repaired *models.Purchase
+		err      error
+	)
+
+	switch transactionStatus(transaction) {
+	case sumup.StatusSuccessful:
+		if purchase.SumupTransactionID == nil && transaction.TransactionID != uuid.Nil {
+			_, err = s.purchases.UpdatePurchaseSumupTransactionIDByID(purchase.ID, transaction.TransactionID)
+		}
+
+		if err == nil {
+			repaired, err = s.statusService.FinalizePurchase(ctx, purchase.ID)
+			// the purchase has been confirmed meanwhile, e.g. by the webhook
+			if errors.Is(err, purchaseService.ErrPurchaseAlreadyConfirmed) {
+				err = nil
+			}
+		}
+	case sumup.StatusFailed:
+		repaired, err = s.statusService.FailPurchase(ctx, purchase.ID)
+	case sumup.StatusCancelled:
+		repaired, err = s.statusService.CancelPurchase(ctx, purchase.ID)
+	default:
+		return
+	}
+
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to repair the status of the purchase", "purchase_id", purchase.ID, "error", err)
+
+		issue.RepairError = err.Error()
+
+		return
+	}
+
+	slog.InfoContext(ctx, "Repaired the status of the purchase",
+		"purchase_id", purchase.ID, "from", purchase.Status, "to", repaired.Status)
+
+	issue.RepairedStatus = repaired.Status
+}
+
+func (s *ReconcileService) newIssue(kind IssueKind, purchase *models.Purchase, transaction *sumup.Transaction) Issue {
+	issue := Issue{Kind: kind}
+
+	if purchase != nil {
+		issue.ClientTransactionID = *purchase.SumupClientTransactionID
+		issue.PurchaseID = purchase.ID
+		issue.PurchaseStatus = purchase.Status
+		issue.PurchaseAmount = purchase.SumupAmount().Round(s.decimalPlaces)
+	}
+
+	if transaction != nil {
+		issue.ClientTransactionID = transaction.ClientTransactionID
+		issue.TransactionID = transaction.TransactionID
+		issue.TransactionCode = transaction.TransactionCode
+		issue.TransactionStatus = string(transactionStatus(*transaction))
+		issue.TransactionAmount = transaction.Amount.Round(s.decimalPlaces)
+	}
+
+	return issue
+}
+
+// indexTransactions maps the transactions to their client transaction ID. A checkout can have more
+// than one transaction, e.g. a declined card followed by a successful one, then the one that charged
+// the card counts.
+func indexTransactions(transactions []sumup.Transaction) map[uuid.UUID]sumup.Transaction {
+	index := make(map[uuid.UUID]sumup.Transaction, len(transactions))
+
+	for _, transaction := range transactions {
+		if transaction.ClientTransactionID == uuid.Nil {
+			continue
+		}
+
+		if existing, ok := index[transaction.ClientTransactionID]; ok && chargedCard(existing) {
+			continue
+		}
+
+		index[transaction.ClientTransactionID] = transaction
+	}
+
+	return index
+}
+
+func chargedCard(transaction sumup.Transaction) bool {
+	status := transactionStatus(transaction)
+
+	return status == sumup.StatusSuccessful || status == sumup.StatusRefunded
+}
+
+func transactionStatus(transaction sumup.Transaction) sumup.TransactionStatus {
+	return sumup.TransactionStatus(transaction.Status).Normalize()
+}
+
+func inPeriod(t, from, to time.Time) bool {
+	return !t.Before(from) && t.Before(to)
+}
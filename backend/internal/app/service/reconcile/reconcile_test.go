@@ -0,0 +1,349 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/potibm/kasseapparat/internal/app/repository/sqlite"
+	"github.com/potibm/kasseapparat/internal/app/repository/sumup"
+	purchaseService "github.com/potibm/kasseapparat/internal/app/service/purchase"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	periodStart = time.Date(2026, time.March, 1, 18, 0, 0, 0, time.UTC)
+	periodEnd   = time.Date(2026, time.March, 2, 6, 0, 0, 0, time.UTC)
+)
+
+type MockTransactionReader struct {
+	Transactions []sumup.Transaction
+}
+
+func (m *MockTransactionReader) GetTransactionsInPeriod(oldestTime, newestTime time.Time) ([]sumup.Transaction, error) {
+	var transactions []sumup.Transaction
+
+	for _, transaction := range m.Transactions {
+		if !transaction.CreatedAt.Before(oldestTime) && !transaction.CreatedAt.After(newestTime) {
+			transactions = append(transactions, transaction)
+		}
+	}
+
+	return transactions, nil
+}
+
+type MockPurchaseRepository struct {
+	Purchases      []models.Purchase
+	TransactionIDs map[uuid.UUID]uuid.UUID
+}
+
+func (m *MockPurchaseRepository) GetPurchases(
+	limit int,
+	offset int,
+	sort string,
+	order string,
+	filters sqlite.PurchaseFilters,
+) ([]models.Purchase, error) {
+	var purchases []models.Purchase
+
+	for _, purchase := range m.Purchases {
+		if purchase.CreatedAt.Before(*filters.CreatedAtGte) || !purchase.CreatedAt.Before(*filters.CreatedAtLt) {
+			continue
+		}
+
+		purchases = append(purchases, purchase)
+	}
+
+	if offset >= len(purchases) {
+		return nil, nil
+	}
+
+	return purchases[offset:min(offset+limit, len(purchases))], nil
+}
+
+func (m *MockPurchaseRepository) UpdatePurchaseSumupTransactionIDByID(
+	id, sumupTransactionID uuid.UUID,
+) (*models.Purchase, error) {
+	if m.TransactionIDs == nil {
+		m.TransactionIDs = map[uuid.UUID]uuid.UUID{}
+	}
+
+	m.TransactionIDs[id] = sumupTransactionID
+
+	return &models.Purchase{ID: id}, nil
+}
+
+type MockPurchaseStatusService struct {
+	Calls []string
+	Err   error
+}
+
+func (m *MockPurchaseStatusService) change(
+	call string,
+	id uuid.UUID,
+	status models.PurchaseStatus,
+) (*models.Purchase, error) {
+	m.Calls = append(m.Calls, call)
+
+	// like the purchase service, the purchase is returned if it has been confirmed before
+	if m.Err != nil && !errors.Is(m.Err, purchaseService.ErrPurchaseAlreadyConfirmed) {
+		return nil, m.Err
+	}
+
+	return &models.Purchase{ID: id, Status: status}, m.Err
+}
+
+func (m *MockPurchaseStatusService) FinalizePurchase(_ context.Context, id uuid.UUID) (*models.Purchase, error) {
+	return m.change("finalize", id, models.PurchaseStatusConfirmed)
+}
+
+func (m *MockPurchaseStatusService) FailPurchase(_ context.Context, id uuid.UUID) (*models.Purchase, error) {
+	return m.change("fail", id, models.PurchaseStatusFailed)
+}
+
+func (m *MockPurchaseStatusService) CancelPurchase(_ context.Context, id uuid.UUID) (*models.Purchase, error) {
+	return m.change("cancel", id, models.PurchaseStatusCancelled)
+}
+
+func newPurchase(status models.PurchaseStatus, amount string, createdAt time.Time) models.Purchase {
+	clientTransactionID := uuid.New()
+
+	return models.Purchase{
+		ID:                       uuid.New(),
+		CreatedAt:                createdAt,
+		TotalGrossPrice:          decimal.RequireFromString(amount),
+		PaymentMethod:            models.PaymentMethodSumUp,
+		SumupClientTransactionID: &clientTransactionID,
+		Status:                   status,
+	}
+}
+
+func newTransaction(purchase *models.Purchase, status string, amount float32) sumup.Transaction {
+	transaction := sumup.Transaction{
+		TransactionID: uuid.New(),
+		Amount:        decimal.NewFromFloat(float64(amount)),
+		CreatedAt:     periodStart.Add(time.Hour),
+		Status:        status,
+	}
+
+	if purchase != nil {
+		transaction.ClientTransactionID = *purchase.SumupClientTransactionID
+		transaction.CreatedAt = purchase.CreatedAt.Add(time.Minute)
+	}
+
+	return transaction
+}
+
+func newService(
+	purchases []models.Purchase,
+	transactions []sumup.Transaction,
+) (*ReconcileService, *MockPurchaseRepository, *MockPurchaseStatusService) {
+	purchaseRepo := &MockPurchaseRepository{Purchases: purchases}
+	statusService := &MockPurchaseStatusService{}
+	service := NewReconcileService(&MockTransactionReader{Transactions: transactions}, purchaseRepo, statusService, 2)
+
+	return service, purchaseRepo, statusService
+}
+
+func TestReconcileWithMatchingTransactions(t *testing.T) {
+	confirmed := newPurchase(models.PurchaseStatusConfirmed, "12.34", periodStart.Add(time.Hour))
+	failed := newPurchase(models.PurchaseStatusFailed, "5.00", periodStart.Add(2*time.Hour))
+	service, _, _ := newService(
+		[]models.Purchase{confirmed, failed},
+		[]sumup.Transaction{
+			newTransaction(&confirmed, "SUCCESSFUL", 12.34),
+			newTransaction(&failed, "FAILED", 5),
+		},
+	)
+
+	result, err := service.Reconcile(context.Background(), periodStart, periodEnd, false)
+	require.NoError(t, err)
+
+	assert.True(t, result.Consistent())
+	assert.Empty(t, result.Issues)
+	assert.Equal(t, 2, result.PurchaseCount)
+	assert.Equal(t, 2, result.TransactionCount)
+	assert.Equal(t, 2, result.MatchedCount)
+}
+
+func TestReconcileReportsOrphans(t *testing.T) {
+	withoutTransaction := newPurchase(models.PurchaseStatusConfirmed, "10.00", periodStart.Add(time.Hour))
+	neverPaid := newPurchase(models.PurchaseStatusCancelled, "10.00", periodStart.Add(time.Hour))
+	withoutPurchase := newTransaction(nil, "SUCCESSFUL", 20)
+	declined := newTransaction(nil, "FAILED", 20)
+	service, _, _ := newService(
+		[]models.Purchase{withoutTransaction, neverPaid},
+		[]sumup.Transaction{withoutPurchase, declined},
+	)
+
+	result, err := service.Reconcile(context.Background(), periodStart, periodEnd, false)
+	require.NoError(t, err)
+
+	assert.False(t, result.Consistent())
+	require.Len(t, result.Issues, 2)
+	assert.Equal(t, IssueOrphanedPurchase, result.Issues[0].Kind)
+	assert.Equal(t, withoutTransaction.ID, result.Issues[0].PurchaseID)
+	assert.Equal(t, IssueOrphanedTransaction, result.Issues[1].Kind)
+	assert.Equal(t, withoutPurchase.TransactionID, result.Issues[1].TransactionID)
+	assert.Equal(t, 0, result.MatchedCount)
+}
+
+func TestReconcileReportsAmountMismatch(t *testing.T) {
+	purchase := newPurchase(models.PurchaseStatusConfirmed, "12.34", periodStart.Add(time.Hour))
+	service, _, _ := newService(
+		[]models.Purchase{purchase},
+		[]sumup.Transaction{newTransaction(&purchase, "SUCCESSFUL", 12.3)},
+	)
+
+	result, err := service.Reconcile(context.Background(), periodStart, periodEnd, false)
+	require.NoError(t, err)
+
+	require.Len(t, result.Issues, 1)
+	assert.Equal(t, IssueAmountMismatch, result.Issues[0].Kind)
+	assert.Equal(t, "12.34", result.Issues[0].PurchaseAmount.StringFixed(2))
+	assert.Equal(t, "12.30", result.Issues[0].TransactionAmount.StringFixed(2))
+}
+
+func TestReconcileReportsStatusMismatchWithoutRepair(t *testing.T) {
+	pending := newPurchase(models.PurchaseStatusPending, "10.00", periodStart.Add(time.Hour))
+	confirmed := newPurchase(models.PurchaseStatusConfirmed, "10.00", periodStart.Add(time.Hour))
+	service, _, statusService := newService(
+		[]models.Purchase{pending, confirmed},
+		[]sumup.Transaction{
+			newTransaction(&pending, "SUCCESSFUL", 10),
+			newTransaction(&confirmed, "FAILED", 10),
+		},
+	)
+
+	result, err := service.Reconcile(context.Background(), periodStart, periodEnd, false)
+	require.NoError(t, err)
+
+	require.Len(t, result.Issues, 2)
+
+	for _, issue := range result.Issues {
+		assert.Equal(t, IssueStatusMismatch, issue.Kind)
+		assert.False(t, issue.Repaired())
+	}
+
+	assert.Equal(t, "successful", result.Issues[0].TransactionStatus)
+	assert.Equal(t, models.PurchaseStatusPending, result.Issues[0].PurchaseStatus)
+	assert.Empty(t, statusService.Calls)
+}
+
+func TestReconcileRepairsPendingPurchases(t *testing.T) {
+	successful := newPurchase(models.PurchaseStatusPending, "10.00", periodStart.Add(time.Hour))
+	failed := newPurchase(models.PurchaseStatusPending, "10.00", periodStart.Add(2*time.Hour))
+	cancelled := newPurchase(models.PurchaseStatusPending, "10.00", periodStart.Add(3*time.Hour))
+	stillPending := newPurchase(models.PurchaseStatusPending, "10.00", periodStart.Add(4*time.Hour))
+	// a confirmed purchase is not pending, so it is left to a human
+	confirmed := newPurchase(models.PurchaseStatusConfirmed, "10.00", periodStart.Add(5*time.Hour))
+	successfulTransaction := newTransaction(&successful, "SUCCESSFUL", 10)
+	service, purchaseRepo, statusService := newService(
+		[]models.Purchase{successful, failed, cancelled, stillPending, confirmed},
+		[]sumup.Transaction{
+			successfulTransaction,
+			newTransaction(&failed, "FAILED", 10),
+			newTransaction(&cancelled, "CANCELLED", 10),
+			newTransaction(&stillPending, "PENDING", 10),
+			newTransaction(&confirmed, "FAILED", 10),
+		},
+	)
+
+	result, err := service.Reconcile(context.Background(), periodStart, periodEnd, true)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"finalize", "fail", "cancel"}, statusService.Calls)
+	assert.Equal(t, successfulTransaction.TransactionID, purchaseRepo.TransactionIDs[successful.ID])
+
+	require.Len(t, result.Issues, 4)
+	assert.Equal(t, models.PurchaseStatusConfirmed, result.Issues[0].RepairedStatus)
+	assert.Equal(t, models.PurchaseStatusFailed, result.Issues[1].RepairedStatus)
+	assert.Equal(t, models.PurchaseStatusCancelled, result.Issues[2].RepairedStatus)
+	assert.Equal(t, confirmed.ID, result.Issues[3].PurchaseID)
+	assert.False(t, result.Issues[3].Repaired())
+	assert.False(t, result.Consistent())
+}
+
+func TestReconcileDoesNotRepairOnAmountMismatch(t *testing.T) {
+	purchase := newPurchase(models.PurchaseStatusPending, "10.00", periodStart.Add(time.Hour))
+	service, _, statusService := newService(
+		[]models.Purchase{purchase},
+		[]sumup.Transaction{newTransaction(&purchase, "SUCCESSFUL", 9)},
+	)
+
+	result, err := service.Reconcile(context.Background(), periodStart, periodEnd, true)
+	require.NoError(t, err)
+
+	require.Len(t, result.Issues, 2)
+	assert.Equal(t, IssueAmountMismatch, result.Issues[0].Kind)
+	assert.Equal(t, IssueStatusMismatch, result.Issues[1].Kind)
+	assert.False(t, result.Issues[1].Repaired())
+	assert.Empty(t, statusService.Calls)
+}
+
+func TestReconcileRecordsRepairError(t *testing.T) {
+	purchase := newPurchase(models.PurchaseStatusPending, "10.00", periodStart.Add(time.Hour))
+	service, _, statusService := newService(
+		[]models.Purchase{purchase},
+		[]sumup.Transaction{newTransaction(&purchase, "FAILED", 10)},
+	)
+	statusService.Err = errors.New("database is locked")
+
+	result, err := service.Reconcile(context.Background(), periodStart, periodEnd, true)
+	require.NoError(t, err)
+
+	require.Len(t, result.Issues, 1)
+	assert.False(t, result.Issues[0].Repaired())
+	assert.Equal(t, "database is locked", result.Issues[0].RepairError)
+}
+
+func TestReconcileRepairOfAPurchaseConfirmedMeanwhile(t *testing.T) {
+	purchase := newPurchase(models.PurchaseStatusPending, "10.00", periodStart.Add(time.Hour))
+	service, _, statusService := newService(
+		[]models.Purchase{purchase},
+		[]sumup.Transaction{newTransaction(&purchase, "SUCCESSFUL", 10)},
+	)
+	statusService.Err = purchaseService.ErrPurchaseAlreadyConfirmed
+
+	result, err := service.Reconcile(context.Background(), periodStart, periodEnd, true)
+	require.NoError(t, err)
+
+	require.Len(t, result.Issues, 1)
+	assert.Equal(t, models.PurchaseStatusConfirmed, result.Issues[0].RepairedStatus)
+	assert.Empty(t, result.Issues[0].RepairError)
+	assert.True(t, result.Consistent())
+}
+
+func TestReconcileMatchesAcrossPeriodBoundary(t *testing.T) {
+	// the purchase is created just before the end of the period, its transaction just after it
+	purchase := newPurchase(models.PurchaseStatusConfirmed, "10.00", periodEnd.Add(-30*time.Second))
+	// the purchase of this transaction was created before the period
+	earlier := newPurchase(models.PurchaseStatusConfirmed, "10.00", periodStart.Add(-time.Minute))
+	service, _, _ := newService(
+		[]models.Purchase{purchase, earlier},
+		[]sumup.Transaction{
+			newTransaction(&purchase, "SUCCESSFUL", 10),
+			newTransaction(&earlier, "SUCCESSFUL", 10),
+		},
+	)
+
+	result, err := service.Reconcile(context.Background(), periodStart, periodEnd, false)
+	require.NoError(t, err)
+
+	assert.True(t, result.Consistent())
+	assert.Equal(t, 1, result.PurchaseCount)
+	assert.Equal(t, 1, result.TransactionCount)
+	assert.Equal(t, 1, result.MatchedCount)
+}
+
+func TestReconcileWithInvalidPeriod(t *testing.T) {
+	service, _, _ := newService(nil, nil)
+
+	_, err := service.Reconcile(context.Background(), periodEnd, periodStart, false)
+	assert.ErrorIs(t, err, ErrInvalidPeriod)
+}
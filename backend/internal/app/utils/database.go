@@ -71,12 +71,36 @@ func PurgeDatabase(db *gorm.DB) error {
 	err := db.Migrator().
 		DropTable(
 			&models.Product{},
+			&models.ProductBundleItem{},
+			&models.ProductVariant{},
+			&models.ProductPriceRule{},
+			&models.ProductPriceChange{},
+			&models.InventoryMovement{},
+			&models.Category{},
+			"user_categories",
 			&models.Purchase{},
 			&models.PurchaseItem{},
+			&models.PurchaseDiscount{},
+			&models.PurchasePayment{},
+			&models.PurchaseRounding{},
+			&models.PurchaseRefund{},
+			&models.PurchaseRefundItem{},
+			&models.PurchaseRefundPayment{},
+			&models.PurchaseReceiptMail{},
+			&models.PurchaseStatusTransition{},
+			&models.ReceiptNumberSequence{},
+			&models.RegisterSession{},
+			&models.RegisterSessionDenomination{},
 			&models.User{},
 			&models.Guestlist{},
 			&models.Guest{},
 			&models.ProductInterest{},
+			&models.ClosingReport{},
+			&models.JournalEntry{},
+			&models.IdempotencyKey{},
+			&models.Voucher{},
+			&models.VoucherRedemption{},
+			&models.Discount{},
 		)
 	if err != nil {
 		return fmt.Errorf("failed to purge database: %w", err)
@@ -86,22 +110,100 @@ func PurgeDatabase(db *gorm.DB) error {
 }
 
 func MigrateDatabase(db *gorm.DB) error {
+	// the stock was tracked whenever there was some before it could be tracked explicitly
+	productsTrackStock := db.Migrator().HasColumn(&models.Product{}, "TrackStock")
+	variantsTrackStock := db.Migrator().HasColumn(&models.ProductVariant{}, "TrackStock")
+
 	err := db.AutoMigrate(
+		&models.Category{},
 		&models.Product{},
+		&models.ProductBundleItem{},
+		&models.ProductVariant{},
+		&models.ProductPriceRule{},
+		&models.ProductPriceChange{},
+		&models.InventoryMovement{},
 		&models.Purchase{},
 		&models.PurchaseItem{},
+		&models.PurchaseDiscount{},
+		&models.PurchasePayment{},
+		&models.PurchaseRounding{},
+		&models.PurchaseRefund{},
+		&models.PurchaseRefundItem{},
+		&models.PurchaseRefundPayment{},
+		&models.PurchaseReceiptMail{},
+		&models.PurchaseStatusTransition{},
+		&models.ReceiptNumberSequence{},
+		&models.RegisterSession{},
+		&models.RegisterSessionDenomination{},
 		&models.User{},
 		&models.Guestlist{},
 		&models.Guest{},
 		&models.ProductInterest{},
+		&models.ClosingReport{},
+		&models.JournalEntry{},
+		&models.IdempotencyKey{},
+		&models.Voucher{},
+		&models.VoucherRedemption{},
+		&models.Discount{},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
 	}
 
+	if err := createOpenRegisterSessionIndex(db); err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	if err := backfillPurchasePayments(db); err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	if !productsTrackStock {
+		if err := backfillTrackStock(db, &models.Product{}); err != nil {
+			return fmt.Errorf("failed to migrate database: %w", err)
+		}
+	}
+
+	if !variantsTrackStock {
+		if err := backfillTrackStock(db, &models.ProductVariant{}); err != nil {
+			return fmt.Errorf("failed to migrate database: %w", err)
+		}
+	}
+
 	return nil
 }
 
+// createOpenRegisterSessionIndex allows a single open register session per cashier. The index is
+// partial, which the struct tags of the models cannot express.
+func createOpenRegisterSessionIndex(db *gorm.DB) error {
+	// SQLite does not allow parameters in the WHERE clause of a partial index
+	return db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_register_sessions_open_created_by_id
+		ON register_sessions (created_by_id)
+		WHERE status = '` + string(models.RegisterSessionStatusOpen) + `' AND deleted_at IS NULL`,
+	).Error
+}
+
+// backfillPurchasePayments creates a single payment line for every purchase that
+// was stored before purchases could be split across several payment methods.
+func backfillPurchasePayments(db *gorm.DB) error {
+	return db.Exec(`INSERT INTO purchase_payments
+		(created_at, updated_at, deleted_at, purchase_id, payment_method, amount,
+		sumup_transaction_id, sumup_client_transaction_id, settled_at)
+		SELECT created_at, updated_at, deleted_at, id, payment_method, total_gross_price,
+		sumup_transaction_id, sumup_client_transaction_id,
+		CASE WHEN status = ? THEN NULL ELSE updated_at END
+		FROM purchases
+		WHERE NOT EXISTS (SELECT 1 FROM purchase_payments WHERE purchase_payments.purchase_id = purchases.id)`,
+		models.PurchaseStatusPending,
+	).Error
+}
+
+// backfillTrackStock tracks the stock of the products or variants stored with stock before their
+// stock was tracked explicitly.
+func backfillTrackStock(db *gorm.DB, model any) error {
+	return db.Model(model).Where("total_stock > 0").Update("track_stock", true).Error
+}
+
 func SeedDatabase(db *gorm.DB, includeTestData bool) {
 	seed := NewDatabaseSeed(db)
 	seed.Seed(includeTestData)
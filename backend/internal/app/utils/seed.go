@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"time"
+
 	"github.com/brianvoe/gofakeit/v7"
 	"github.com/potibm/kasseapparat/internal/app/models"
 	"github.com/shopspring/decimal"
@@ -111,6 +113,7 @@ func (ds *DatabaseSeed) seedProducts() {
 			VATRate:    vat19,
 			Pos:        10,
 			TotalStock: gofakeit.IntRange(5, 30),
+			TrackStock: true,
 		},
 	)
 	ds.products = append(
@@ -121,6 +124,7 @@ func (ds *DatabaseSeed) seedProducts() {
 			VATRate:    vat19,
 			Pos:        10,
 			TotalStock: gofakeit.IntRange(5, 30),
+			TrackStock: true,
 		},
 	)
 	ds.products = append(
@@ -131,6 +135,7 @@ func (ds *DatabaseSeed) seedProducts() {
 			VATRate:    vat19,
 			Pos:        10,
 			TotalStock: gofakeit.IntRange(5, 30),
+			TrackStock: true,
 		},
 	)
 	ds.products = append(
@@ -141,6 +146,7 @@ func (ds *DatabaseSeed) seedProducts() {
 			VATRate:    vat19,
 			Pos:        10,
 			TotalStock: gofakeit.IntRange(5, 30),
+			TrackStock: true,
 		},
 	)
 	ds.products = append(
@@ -151,6 +157,7 @@ func (ds *DatabaseSeed) seedProducts() {
 			VATRate:    vat19,
 			Pos:        10,
 			TotalStock: gofakeit.IntRange(5, 30),
+			TrackStock: true,
 		},
 	)
 	ds.products = append(
@@ -161,6 +168,7 @@ func (ds *DatabaseSeed) seedProducts() {
 			VATRate:    vat19,
 			Pos:        10,
 			TotalStock: gofakeit.IntRange(5, 30),
+			TrackStock: true,
 		},
 	)
 	ds.products = append(
@@ -171,6 +179,7 @@ func (ds *DatabaseSeed) seedProducts() {
 			VATRate:    vat19,
 			Pos:        10,
 			TotalStock: gofakeit.IntRange(5, 30),
+			TrackStock: true,
 		},
 	)
 	ds.products = append(
@@ -181,6 +190,7 @@ func (ds *DatabaseSeed) seedProducts() {
 			VATRate:    vat19,
 			Pos:        10,
 			TotalStock: gofakeit.IntRange(5, 30),
+			TrackStock: true,
 		},
 	)
 	ds.products = append(
@@ -191,6 +201,7 @@ func (ds *DatabaseSeed) seedProducts() {
 			VATRate:    vat19,
 			Pos:        10,
 			TotalStock: gofakeit.IntRange(5, 30),
+			TrackStock: true,
 		},
 	)
 	ds.products = append(
@@ -201,6 +212,7 @@ func (ds *DatabaseSeed) seedProducts() {
 			VATRate:    vat19,
 			Pos:        10,
 			TotalStock: gofakeit.IntRange(5, 30),
+			TrackStock: true,
 		},
 	)
 	ds.products = append(
@@ -211,6 +223,7 @@ func (ds *DatabaseSeed) seedProducts() {
 			VATRate:    vat19,
 			Pos:        10,
 			TotalStock: gofakeit.IntRange(5, 30),
+			TrackStock: true,
 		},
 	)
 	ds.products = append(
@@ -341,6 +354,8 @@ func (ds *DatabaseSeed) seedPurchases(purchaseCount int) {
 		return
 	}
 
+	now := time.Now()
+
 	_ = ds.db.Transaction(func(tx *gorm.DB) error {
 		for i := 1; i < purchaseCount; i++ {
 			purchase := models.Purchase{
@@ -370,6 +385,13 @@ func (ds *DatabaseSeed) seedPurchases(purchaseCount int) {
 			}
 
 			purchase.CreatedByID = &ds.demoUser.ID
+			purchase.Payments = []models.PurchasePayment{
+				{
+					PaymentMethod: purchase.PaymentMethod,
+					Amount:        purchase.TotalGrossPrice,
+					SettledAt:     &now,
+				},
+			}
 
 			ds.db.Create(&purchase)
 		}
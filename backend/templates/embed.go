@@ -4,3 +4,12 @@ import "embed"
 
 //go:embed mail/*
 var MailTemplateFiles embed.FS
+
+//go:embed reports/*
+var ReportTemplateFiles embed.FS
+
+//go:embed receipts/*
+var ReceiptTemplateFiles embed.FS
+
+//go:embed vouchers/*
+var VoucherTemplateFiles embed.FS
@@ -0,0 +1,178 @@
+package tests_e2e
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+const (
+	categoryBaseURL  = "/api/v2/categories"
+	categoryStatsURL = "/api/v2/categoryStats"
+	demoUserURL      = "/api/v2/users/2"
+)
+
+func TestCategoryAuthentication(t *testing.T) {
+	testAuthenticationForEntityEndpoints(t, categoryBaseURL, categoryBaseURL+"/1")
+}
+
+func TestCategoryChangesRequireAdmin(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	withDemoUserAuthToken(e.GET(categoryBaseURL)).Expect().Status(http.StatusOK)
+	withDemoUserAuthToken(e.POST(categoryBaseURL)).
+		WithJSON(map[string]any{"name": "Drinks"}).
+		Expect().
+		Status(http.StatusForbidden)
+}
+
+func assignProductToCategory(productID int, categoryID any) {
+	productURL := productBaseURL + "/" + strconv.Itoa(productID)
+	product := withAdminUserAuthToken(e.GET(productURL)).Expect().Status(http.StatusOK).JSON().Object()
+
+	withAdminUserAuthToken(e.PUT(productURL)).
+		WithJSON(map[string]any{
+			"name":       product.Value("name").String().Raw(),
+			"netPrice":   product.Value("netPrice").String().Raw(),
+			"vatRate":    product.Value("vatRate").String().Raw(),
+			"pos":        product.Value("pos").Number().Raw(),
+			"wrapAfter":  product.Value("wrapAfter").Boolean().Raw(),
+			"apiExport":  product.Value("apiExport").Boolean().Raw(),
+			"hidden":     product.Value("hidden").Boolean().Raw(),
+			"soldOut":    product.Value("soldOut").Boolean().Raw(),
+			"totalStock": product.Value("totalStock").Number().Raw(),
+			"categoryId": categoryID,
+		}).
+		Expect().
+		Status(http.StatusOK)
+}
+
+func setDemoUserCategories(categoryIDs []int) {
+	withAdminUserAuthToken(e.PUT(demoUserURL)).
+		WithJSON(map[string]any{
+			"username":    "demo",
+			"email":       "demo@example.com",
+			"categoryIds": categoryIDs,
+		}).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("categories").Array().Length().IsEqual(len(categoryIDs))
+}
+
+func TestCategoryFiltersAndRestrictsProducts(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	category := withAdminUserAuthToken(e.POST(categoryBaseURL)).
+		WithJSON(map[string]any{"name": "Drinks", "pos": 1, "color": "#3366ff", "icon": "cup"}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+
+	category.Value("name").String().IsEqual("Drinks")
+	categoryID := int(category.Value("id").Number().Raw())
+	categoryURL := categoryBaseURL + "/" + strconv.Itoa(categoryID)
+
+	assignProductToCategory(2, categoryID)
+
+	products := withDemoUserAuthToken(e.GET(productBaseURL)).
+		WithQuery("categoryId", categoryID).
+		Expect().
+		Status(http.StatusOK)
+	products.Header(totalCountHeader).AsNumber().IsEqual(1)
+	products.JSON().Array().Value(0).Object().Value("categoryId").Number().IsEqual(categoryID)
+
+	// a user restricted to the category only sees its products
+	setDemoUserCategories([]int{categoryID})
+
+	products = withDemoUserAuthToken(e.GET(productBaseURL)).Expect().Status(http.StatusOK)
+	products.JSON().Array().Length().IsEqual(1)
+	products.JSON().Array().Value(0).Object().Value("id").Number().IsEqual(2)
+
+	withDemoUserAuthToken(e.GET(categoryBaseURL)).
+		Expect().
+		Status(http.StatusOK).JSON().Array().Length().IsEqual(1)
+
+	// and can neither open, quote nor sell the products of other categories
+	withDemoUserAuthToken(e.GET(productBaseURL + "/1")).
+		Expect().
+		Status(http.StatusForbidden)
+
+	errorResponse := withDemoUserAuthToken(e.POST(purchaseQuoteURL)).
+		WithJSON(quoteCart([]map[string]any{})).
+		Expect().
+		Status(http.StatusForbidden).JSON().Object()
+	validateErrorDetailMessage(errorResponse, "User is not allowed to sell the product")
+
+	errorResponse = withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithJSON(map[string]any{
+			"paymentMethod":   "CASH",
+			"totalNetPrice":   "37.38",
+			"totalGrossPrice": "40",
+			"cart": []map[string]any{
+				{"ID": 1, "quantity": 1, "netPrice": "37.38", "listItems": []map[string]any{}},
+			},
+		}).
+		Expect().
+		Status(http.StatusForbidden).JSON().Object()
+	validateErrorDetailMessage(errorResponse, "User is not allowed to sell the product")
+
+	// admins are not restricted
+	withAdminUserAuthToken(e.GET(productBaseURL)).
+		Expect().
+		Status(http.StatusOK).JSON().Array().Length().Gt(1)
+
+	setDemoUserCategories([]int{})
+
+	withAdminUserAuthToken(e.DELETE(categoryURL)).Expect().Status(http.StatusNoContent)
+	withAdminUserAuthToken(e.GET(categoryURL)).Expect().Status(http.StatusNotFound)
+
+	withAdminUserAuthToken(e.GET(productBaseURL + "/2")).
+		Expect().
+		Status(http.StatusOK).JSON().Object().Value("categoryId").IsNull()
+}
+
+func TestCategoryStats(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	category := withAdminUserAuthToken(e.POST(categoryBaseURL)).
+		WithJSON(map[string]any{"name": "Tickets"}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+	categoryID := int(category.Value("id").Number().Raw())
+
+	assignProductToCategory(1, categoryID)
+
+	purchaseURL := createPurchase()
+
+	withDemoUserAuthToken(e.GET(productStatsURL)).
+		Expect().
+		Status(http.StatusOK).JSON().Array().Value(0).Object().
+		Value("categoryName").String().IsEqual("Tickets")
+
+	stats := withDemoUserAuthToken(e.GET(categoryStatsURL)).
+		Expect().
+		Status(http.StatusOK).JSON().Array()
+
+	tickets := stats.Value(0).Object()
+	tickets.Value("id").Number().IsEqual(categoryID)
+	tickets.Value("name").String().IsEqual("Tickets")
+	tickets.Value("soldItems").Number().Ge(1)
+
+	deletePurchase(purchaseURL)
+	assignProductToCategory(1, nil)
+	withAdminUserAuthToken(e.DELETE(categoryBaseURL + "/" + strconv.Itoa(categoryID))).
+		Expect().
+		Status(http.StatusNoContent)
+}
+
+func TestCategoryWithInvalidColor(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	withAdminUserAuthToken(e.POST(categoryBaseURL)).
+		WithJSON(map[string]any{"name": "Drinks", "color": "blue-ish"}).
+		Expect().
+		Status(http.StatusBadRequest)
+}
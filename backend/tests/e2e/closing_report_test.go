@@ -0,0 +1,120 @@
+package tests_e2e
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const closingReportBaseURL = "/api/v2/closingReports"
+
+func TestCreateClosingReport(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	periodStart := time.Now().Add(-time.Hour)
+
+	purchase := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithJSON(map[string]any{
+			"paymentMethod":   "CASH",
+			"totalNetPrice":   "18.69",
+			"totalGrossPrice": "20",
+			"cart": []map[string]any{
+				{
+					"ID":        2,
+					"quantity":  1,
+					"netPrice":  "18.69",
+					"listItems": []map[string]any{},
+				},
+			},
+		}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+
+	periodEnd := time.Now()
+
+	withDemoUserAuthToken(e.POST(closingReportBaseURL)).
+		WithJSON(map[string]any{"periodStart": periodStart, "periodEnd": periodEnd}).
+		Expect().
+		Status(http.StatusForbidden)
+
+	report := withAdminUserAuthToken(e.POST(closingReportBaseURL)).
+		WithJSON(map[string]any{"periodStart": periodStart, "periodEnd": periodEnd}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+
+	report.Value("number").Number().Gt(0)
+
+	figures := report.Value("figures").Object()
+	figures.Value("purchaseCount").Number().Ge(1)
+	figures.Value("vatRates").Array().NotEmpty()
+	figures.Value("paymentMethods").Array().NotEmpty()
+	figures.Value("products").Array().NotEmpty()
+	figures.Value("refunds").Object().ContainsKey("grossPrice")
+	figures.Value("cancellations").Object().ContainsKey("count")
+
+	// the period is closed now
+	errorResponse := withAdminUserAuthToken(e.POST(closingReportBaseURL)).
+		WithJSON(map[string]any{"periodStart": periodStart.Add(time.Minute), "periodEnd": periodEnd}).
+		Expect().
+		Status(http.StatusConflict).JSON().Object()
+
+	validateErrorDetailMessage(errorResponse, "A closing report already covers this period")
+
+	reportURL := closingReportBaseURL + "/" + strconv.Itoa(int(report.Value("id").Number().Raw()))
+
+	withAdminUserAuthToken(e.GET(reportURL)).
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().Value("number").IsEqual(report.Value("number").Raw())
+
+	withAdminUserAuthToken(e.GET(reportURL)).
+		WithQuery("format", "csv").
+		Expect().
+		Status(http.StatusOK).
+		ContentType("text/csv").
+		Body().Contains("Section,Name,Quantity").Contains("VAT Rate")
+
+	withAdminUserAuthToken(e.GET(reportURL)).
+		WithQuery("format", "html").
+		Expect().
+		Status(http.StatusOK).
+		ContentType("text/html").
+		Body().Contains("Closing report #")
+
+	withAdminUserAuthToken(e.GET(reportURL)).
+		WithQuery("format", "xml").
+		Expect().
+		Status(http.StatusBadRequest)
+
+	res := withAdminUserAuthToken(e.GET(closingReportBaseURL)).
+		Expect().
+		Status(http.StatusOK)
+
+	res.Header(totalCountHeader).AsNumber().Ge(1)
+
+	deletePurchase(purchaseBaseURL + "/" + purchase.Value("id").String().Raw())
+}
+
+func TestCreateClosingReportForEventDay(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	report := withAdminUserAuthToken(e.POST(closingReportBaseURL)).
+		WithJSON(map[string]any{"day": "2020-01-01"}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+
+	report.Value("figures").Object().Value("purchaseCount").Number().IsEqual(0)
+
+	withAdminUserAuthToken(e.POST(closingReportBaseURL)).
+		WithJSON(map[string]any{"day": time.Now().Format("2006-01-02")}).
+		Expect().
+		Status(http.StatusBadRequest)
+
+	withAdminUserAuthToken(e.POST(closingReportBaseURL)).
+		WithJSON(map[string]any{"day": "01.01.2020"}).
+		Expect().
+		Status(http.StatusBadRequest)
+}
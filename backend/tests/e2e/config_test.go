@@ -40,6 +40,11 @@ func TestGetConfig(t *testing.T) {
 		obj.Value("name").String().NotEmpty()
 	}
 
+	rounding := paymentMethods.Value(0).Object().Value("rounding").Object()
+	rounding.Value("increment").String().IsEqual("0.5")
+	rounding.Value("mode").String().IsEqual("nearest")
+	paymentMethods.Value(1).Object().Value("rounding").IsNull()
+
 	vatRates := config.Value("vatRates").Array()
 	vatRates.NotEmpty()
 
@@ -0,0 +1,112 @@
+package tests_e2e
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+const discountBaseURL = "/api/v2/discounts"
+
+func TestDiscountAuthentication(t *testing.T) {
+	testAuthenticationForEntityEndpoints(t, discountBaseURL, discountBaseURL+"/1")
+}
+
+func TestDiscountChangesRequireAdmin(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	withDemoUserAuthToken(e.GET(discountBaseURL)).Expect().Status(http.StatusOK)
+	withDemoUserAuthToken(e.POST(discountBaseURL)).
+		WithJSON(map[string]any{"name": "Crew", "type": "percentage", "value": "10"}).
+		Expect().
+		Status(http.StatusForbidden)
+}
+
+func TestDiscountWithInvalidValue(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	errorResponse := withAdminUserAuthToken(e.POST(discountBaseURL)).
+		WithJSON(map[string]any{"name": "Crew", "type": "percentage", "value": "120"}).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object()
+
+	validateErrorDetailMessage(errorResponse, "Discount value must be positive and a percentage must not exceed 100")
+}
+
+func discountPurchasePayload(discount map[string]any) map[string]any {
+	return map[string]any{
+		"paymentMethod":   "CASH",
+		"totalNetPrice":   "16.82",
+		"totalGrossPrice": "18",
+		"discounts":       []map[string]any{discount},
+		"cart": []map[string]any{
+			{"ID": 2, "quantity": 1, "netPrice": "18.69", "listItems": []map[string]any{}},
+		},
+	}
+}
+
+func TestDiscountCodePurchase(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	discount := withAdminUserAuthToken(e.POST(discountBaseURL)).
+		WithJSON(map[string]any{"name": "Crew", "code": "crew", "type": "percentage", "value": "10"}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+
+	discount.Value("code").String().IsEqual("CREW")
+	discountID := int(discount.Value("id").Number().Raw())
+	discountURL := discountBaseURL + "/" + strconv.Itoa(discountID)
+
+	purchase := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithJSON(discountPurchasePayload(map[string]any{"code": "crew"})).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+
+	purchase.Value("totalGrossPrice").String().IsEqual("18")
+
+	lines := purchase.Value("discounts").Array()
+	lines.Length().IsEqual(1)
+	lines.Value(0).Object().Value("name").String().IsEqual("Crew")
+	lines.Value(0).Object().Value("grossAmount").String().IsEqual("2")
+
+	// refunding the item refunds the discounted price
+	purchaseURL := purchaseBaseURL + "/" + purchase.Value("id").String().Raw()
+	purchaseItemID := purchase.Value("purchaseItems").Array().Value(0).Object().Value("id").Number().Raw()
+
+	refunded := withDemoUserAuthToken(e.POST(purchaseURL + "/refunds")).
+		WithJSON(map[string]any{
+			"items": []map[string]any{
+				{"purchaseItemId": purchaseItemID, "quantity": 1},
+			},
+		}).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+
+	refunded.Value("refunds").Array().Value(0).Object().Value("totalGrossPrice").String().IsEqual("18")
+
+	// applying a discount without a code requires the permission to grant discounts
+	errorResponse := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithJSON(discountPurchasePayload(map[string]any{"discountId": discountID})).
+		Expect().
+		Status(http.StatusForbidden).JSON().Object()
+
+	validateErrorDetailMessage(errorResponse, "User is not allowed to grant discounts")
+
+	withAdminUserAuthToken(e.DELETE(discountURL)).Expect().Status(http.StatusNoContent)
+	withAdminUserAuthToken(e.GET(discountURL)).Expect().Status(http.StatusNotFound)
+}
+
+func TestDiscountPurchaseWithUnknownCode(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	errorResponse := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithJSON(discountPurchasePayload(map[string]any{"code": "NOPE"})).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object()
+
+	validateErrorDetailMessage(errorResponse, "Discount not found")
+}
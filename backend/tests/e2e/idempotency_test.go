@@ -0,0 +1,267 @@
+package tests_e2e
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/potibm/kasseapparat/internal/app/models"
+)
+
+func purchaseRequestBody(quantity int) map[string]any {
+	return map[string]any{
+		"paymentMethod":   "CASH",
+		"totalNetPrice":   "37.38",
+		"totalGrossPrice": "40",
+		"cart": []map[string]any{
+			{
+				"ID":        1,
+				"quantity":  quantity,
+				"netPrice":  "37.38",
+				"listItems": []map[string]any{},
+			},
+		},
+	}
+}
+
+func TestPostPurchaseWithIdempotencyKey(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	key := uuid.NewString()
+
+	firstResponse := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithHeader("Idempotency-Key", key).
+		WithJSON(purchaseRequestBody(1)).
+		Expect().
+		Status(http.StatusCreated)
+	firstResponse.Header("Idempotent-Replayed").IsEmpty()
+
+	purchaseID := firstResponse.JSON().Object().Value("id").String().Raw()
+
+	repeatedResponse := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithHeader("Idempotency-Key", key).
+		WithJSON(purchaseRequestBody(1)).
+		Expect().
+		Status(http.StatusCreated)
+	repeatedResponse.Header("Idempotent-Replayed").IsEqual("true")
+	repeatedResponse.JSON().Object().Value("id").String().IsEqual(purchaseID)
+
+	errorResponse := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithHeader("Idempotency-Key", key).
+		WithJSON(purchaseRequestBody(2)).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+	validateErrorDetailMessage(errorResponse, "Idempotency key was already used for a different request")
+
+	purchaseURL := purchaseBaseURL + "/" + purchaseID
+	deleteKey := uuid.NewString()
+
+	for range 2 {
+		withDemoUserAuthToken(e.DELETE(purchaseURL)).
+			WithHeader("Idempotency-Key", deleteKey).
+			Expect().
+			Status(http.StatusNoContent)
+	}
+}
+
+func TestFailedRequestReleasesIdempotencyKey(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	key := uuid.NewString()
+	invalidBody := purchaseRequestBody(1)
+	invalidBody["totalGrossPrice"] = "41"
+
+	withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithHeader("Idempotency-Key", key).
+		WithJSON(invalidBody).
+		Expect().
+		Status(http.StatusBadRequest)
+
+	purchaseID := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithHeader("Idempotency-Key", key).
+		WithJSON(purchaseRequestBody(1)).
+		Expect().
+		Status(http.StatusCreated).JSON().Object().
+		Value("id").String().Raw()
+
+	deletePurchase(purchaseBaseURL + "/" + purchaseID)
+}
+
+func TestServerErrorIsReplayedForItsIdempotencyKey(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	key := uuid.NewString()
+
+	// the purchase is stored before its checkout on the unknown terminal fails
+	withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithHeader("Idempotency-Key", key).
+		WithJSON(terminalPurchasePayload("reader_1")).
+		Expect().
+		Status(http.StatusInternalServerError).
+		Header("Idempotent-Replayed").IsEmpty()
+
+	var purchaseCount int64
+	if err := db.Model(&models.Purchase{}).Count(&purchaseCount).Error; err != nil {
+		t.Fatalf("failed to count the purchases: %v", err)
+	}
+
+	errorResponse := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithHeader("Idempotency-Key", key).
+		WithJSON(terminalPurchasePayload("reader_1")).
+		Expect().
+		Status(http.StatusInternalServerError)
+	errorResponse.Header("Idempotent-Replayed").IsEqual("true")
+	validateErrorDetailMessage(
+		errorResponse.JSON().Object(),
+		"Failed to create terminal checkout: payment terminal not found",
+	)
+
+	var repeatedCount int64
+	if err := db.Model(&models.Purchase{}).Count(&repeatedCount).Error; err != nil {
+		t.Fatalf("failed to count the purchases: %v", err)
+	}
+
+	if repeatedCount != purchaseCount {
+		t.Errorf("expected the repeated request not to store a purchase, got %d instead of %d", repeatedCount, purchaseCount)
+	}
+}
+
+func TestIdempotencyKeysAreKeptPerUser(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	key := uuid.NewString()
+
+	demoPurchaseID := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithHeader("Idempotency-Key", key).
+		WithJSON(purchaseRequestBody(1)).
+		Expect().
+		Status(http.StatusCreated).JSON().Object().
+		Value("id").String().Raw()
+
+	adminPurchaseID := withAdminUserAuthToken(e.POST(purchaseBaseURL)).
+		WithHeader("Idempotency-Key", key).
+		WithJSON(purchaseRequestBody(1)).
+		Expect().
+		Status(http.StatusCreated).JSON().Object().
+		Value("id").String().NotEqual(demoPurchaseID).Raw()
+
+	deletePurchase(purchaseBaseURL + "/" + demoPurchaseID)
+	deletePurchase(purchaseBaseURL + "/" + adminPurchaseID)
+}
+
+func TestIdempotencyKeyOfUnfinishedRequestIsReleasedAfterItsLease(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	var demoUser models.User
+	if err := db.Where("username = ?", "demo").First(&demoUser).Error; err != nil {
+		t.Fatalf("failed to get the demo user: %v", err)
+	}
+
+	// the lease of a request that was never completed, e.g. because the server stopped, has run out
+	abandonedKey := models.IdempotencyKey{
+		UserID:    demoUser.ID,
+		Key:       uuid.NewString(),
+		ExpiresAt: time.Now().Add(-time.Second),
+	}
+	if err := db.Create(&abandonedKey).Error; err != nil {
+		t.Fatalf("failed to store the idempotency key: %v", err)
+	}
+
+	purchaseID := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithHeader("Idempotency-Key", abandonedKey.Key).
+		WithJSON(purchaseRequestBody(1)).
+		Expect().
+		Status(http.StatusCreated).JSON().Object().
+		Value("id").String().Raw()
+
+	var completed models.IdempotencyKey
+	if err := db.Where("user_id = ? AND key = ?", demoUser.ID, abandonedKey.Key).First(&completed).Error; err != nil {
+		t.Fatalf("failed to get the idempotency key: %v", err)
+	}
+
+	if !completed.ExpiresAt.After(time.Now().Add(time.Hour)) {
+		t.Errorf("expected the completed key to be kept for the retention period, expires at %s", completed.ExpiresAt)
+	}
+
+	deletePurchase(purchaseBaseURL + "/" + purchaseID)
+}
+
+func TestRefundWithFailedCardRefundKeepsIdempotencyKey(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	purchase := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithJSON(map[string]any{
+			"paymentMethod":   "CASH",
+			"totalNetPrice":   "37.38",
+			"totalGrossPrice": "40",
+			"cart": []map[string]any{
+				{
+					"ID":        2,
+					"quantity":  2,
+					"netPrice":  "18.69",
+					"listItems": []map[string]any{},
+				},
+			},
+		}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+
+	purchaseID := purchase.Value("id").String().Raw()
+	purchaseItemID := purchase.Value("purchaseItems").Array().Value(0).Object().Value("id").Number().Raw()
+
+	// the purchase was paid by card with a transaction the payment terminal provider does not know,
+	// so refunding the card fails
+	transactionID := uuid.New()
+	if err := db.Model(&models.Purchase{}).Where("id = ?", purchaseID).Updates(map[string]any{
+		"payment_method":       models.PaymentMethodSumUp,
+		"sumup_transaction_id": transactionID,
+	}).Error; err != nil {
+		t.Fatalf("failed to update the purchase: %v", err)
+	}
+
+	if err := db.Model(&models.PurchasePayment{}).Where("purchase_id = ?", purchaseID).Updates(map[string]any{
+		"payment_method":       models.PaymentMethodSumUp,
+		"sumup_transaction_id": transactionID,
+	}).Error; err != nil {
+		t.Fatalf("failed to update the purchase payment: %v", err)
+	}
+
+	key := uuid.NewString()
+	refundBody := map[string]any{
+		"items": []map[string]any{
+			{"purchaseItemId": purchaseItemID, "quantity": 1},
+		},
+	}
+
+	refunded := withDemoUserAuthToken(e.POST(purchaseBaseURL+"/"+purchaseID+"/refunds")).
+		WithHeader("Idempotency-Key", key).
+		WithJSON(refundBody).
+		Expect().
+		Status(http.StatusAccepted)
+	refunded.JSON().Object().Value("refunds").Array().Value(0).Object().Value("settledAt").IsNull()
+
+	withDemoUserAuthToken(e.POST(purchaseBaseURL+"/"+purchaseID+"/refunds")).
+		WithHeader("Idempotency-Key", key).
+		WithJSON(refundBody).
+		Expect().
+		Status(http.StatusAccepted).
+		Header("Idempotent-Replayed").IsEqual("true")
+
+	var refundCount int64
+	if err := db.Model(&models.PurchaseRefund{}).Where("purchase_id = ?", purchaseID).Count(&refundCount).Error; err != nil {
+		t.Fatalf("failed to count the refunds: %v", err)
+	}
+
+	if refundCount != 1 {
+		t.Errorf("expected exactly one refund, got %d", refundCount)
+	}
+
+	deletePurchase(purchaseBaseURL + "/" + purchaseID)
+}
@@ -8,6 +8,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/gavv/httpexpect/v2"
 	"github.com/potibm/kasseapparat/internal/app/config"
@@ -18,8 +19,10 @@ import (
 	"github.com/potibm/kasseapparat/internal/app/models"
 	"github.com/potibm/kasseapparat/internal/app/monitor"
 	sqliteRepo "github.com/potibm/kasseapparat/internal/app/repository/sqlite"
+	"github.com/potibm/kasseapparat/internal/app/repository/terminal"
 	purchaseService "github.com/potibm/kasseapparat/internal/app/service/purchase"
 	"github.com/potibm/kasseapparat/internal/app/utils"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
@@ -58,6 +61,34 @@ func setup() {
 	}
 
 	utils.SeedDatabase(db, true)
+
+	// the seeded stock and sales open the inventory ledger
+	if err := sqliteRepo.NewRepository(db, 0).BackfillInventoryMovements(); err != nil {
+		log.Fatal("Failed to seed the inventory ledger: ", err)
+	}
+
+	openRegisterSessions()
+}
+
+// openRegisterSessions opens a register session for the seeded users, so they can sell for cash.
+func openRegisterSessions() {
+	var users []models.User
+	if err := db.Find(&users).Error; err != nil {
+		log.Fatal("Failed to load users: ", err)
+	}
+
+	for _, user := range users {
+		session := models.RegisterSession{
+			Status:       models.RegisterSessionStatusOpen,
+			OpenedAt:     time.Now(),
+			OpeningFloat: decimal.NewFromInt(100),
+		}
+		session.CreatedByID = &user.ID
+
+		if err := db.Create(&session).Error; err != nil {
+			log.Fatal("Failed to open register session: ", err)
+		}
+	}
 }
 
 func setupTestEnvironment(t *testing.T) (httpServer *httptest.Server, cleanupFunc func()) {
@@ -88,45 +119,72 @@ func setupTestEnvironment(t *testing.T) (httpServer *httptest.Server, cleanupFun
 			Realm:  "",
 			Secret: "test",
 		},
+		Purchases: config.PurchasesConfig{
+			IdempotencyKeyHours:        config.DefaultIdempotencyKeyHours,
+			IdempotencyKeyLeaseMinutes: config.DefaultIdempotencyKeyLeaseMinutes,
+			OfflineMaxAgeHours:         config.DefaultOfflineMaxAgeHours,
+		},
+		Receipt: config.ReceiptConfig{
+			MailRetentionDays: config.DefaultReceiptMailRetentionDays,
+		},
 		VATRates: config.DefaultVatRates,
 		PaymentMethods: config.PaymentMethods{
-			{Code: models.PaymentMethodCash, Name: "Cash"},
+			{
+				Code:     models.PaymentMethodCash,
+				Name:     "Cash",
+				Rounding: config.RoundingConfig{Increment: "0.50", Mode: models.RoundingModeNearest},
+			},
 			{Code: models.PaymentMethodCC, Name: "Creditcard"},
+			{Code: models.PaymentMethodVoucher, Name: "Voucher"},
 			{Code: models.PaymentMethodSumUp, Name: "SumUp"},
 		},
+		Terminal: config.TerminalConfig{Provider: terminal.ProviderFake},
 	}
 
 	sqliteRp := sqliteRepo.NewRepository(db, int32(cfg.Format.Currency.FractionDigitsMax))
 	sumupRp := NewMockSumUpRepository()
+	terminalProvider := initializer.InitializePaymentTerminal(cfg.Terminal, sumupRp)
 	mail, _ := mailer.NewMailer("smtp://127.0.0.1:1025")
 	mail.SetDisabled(true)
 
 	jwtMiddleware := initializer.InitializeJwtMiddleware(sqliteRp, cfg.Jwt, nil)
 
-	purchaseSrvc := purchaseService.NewPurchaseService(
+	purchaseSrvc, err := purchaseService.NewPurchaseService(
 		sqliteRp,
-		sumupRp,
+		terminalProvider,
 		mail,
 		int32(cfg.Format.Currency.FractionDigitsMax),
 		cfg.Format.Currency.Code,
+		purchaseService.Options{
+			ReceiptNumberPrefix:  "TEST",
+			FiscalYearStartMonth: time.January,
+			RoundingRules:        cfg.PaymentMethods.RoundingRules(),
+			QuoteSecret:          cfg.Jwt.Secret,
+			OfflineMaxAge:        cfg.Purchases.OfflineMaxAge(),
+		},
 	)
+	if err != nil {
+		log.Fatalf("Failed to create the purchase service: %v", err)
+	}
 
 	statusPublisher := MockStatusPublisher{}
-	poller := monitor.NewPoller(sumupRp, sqliteRp, purchaseSrvc, &statusPublisher)
+	poller := monitor.NewPoller(terminalProvider, sqliteRp, purchaseSrvc, &statusPublisher, 0)
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
 	httpHandlerConfig := handlerHttp.HandlerConfig{
-		Repo:            sqliteRp,
-		SumupRepository: sumupRp,
-		PurchaseService: purchaseSrvc,
-		Monitor:         poller,
-		Mailer:          *mail,
-		AppConfig:       cfg,
+		Repo:             sqliteRp,
+		SumupRepository:  sumupRp,
+		TerminalProvider: terminalProvider,
+		PurchaseService:  purchaseSrvc,
+		Monitor:          poller,
+		StatusPublisher:  &statusPublisher,
+		Mailer:           *mail,
+		AppConfig:        cfg,
 	}
 	handlerHTTPObj := handlerHttp.NewHandler(httpHandlerConfig)
 	websocketHandler := websocket.NewHandler(
 		sqliteRp,
-		sumupRp,
+		poller,
 		purchaseSrvc,
 		jwtMiddleware,
 		&cfg.App.CorsAllowOrigins,
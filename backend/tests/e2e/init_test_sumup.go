@@ -20,11 +20,25 @@ type MockSumUpRepository struct {
 		returnUrl *string) (*uuid.UUID, error)
 	CreateReaderTerminateActionFunc func(readerId string) error
 	GetTransactionsFunc             func(oldestFrom *time.Time) ([]sumup.Transaction, error)
+	GetTransactionsInPeriodFunc     func(oldestTime, newestTime time.Time) ([]sumup.Transaction, error)
 	GetTransactionByIDFunc          func(transactionId uuid.UUID) (*sumup.Transaction, error)
-	RefundTransactionFunc           func(transactionId uuid.UUID) error
+	RefundTransactionFunc           func(transactionId uuid.UUID, amount decimal.Decimal) error
 	GetWebhookURLFunc               func() *string
 }
 
+// mockOrphanedTransaction is a successful transaction without a purchase, e.g. taken on the reader
+// without Kasseapparat.
+var mockOrphanedTransaction = sumup.Transaction{
+	ID:                  "2b5cd782-0733-4fb2-bf22-5a12345bd94f",
+	TransactionID:       uuid.MustParse("2b5cd782-0733-4fb2-bf22-5a12345bd94f"),
+	ClientTransactionID: uuid.MustParse("7f0e9c4a-3b1d-4c8e-9a52-1d6f0b2e8c41"),
+	TransactionCode:     "TAAAABCP2SA",
+	Amount:              decimal.NewFromFloat(40.00),
+	Currency:            "EUR",
+	CreatedAt:           time.Date(2025, time.June, 15, 20, 45, 27, 0, time.UTC),
+	Status:              "SUCCESSFUL",
+}
+
 func NewMockSumUpRepository() *MockSumUpRepository {
 	const mockCheckoutUUID = "00000000-0000-4000-8000-000000000000"
 
@@ -59,6 +73,14 @@ func NewMockSumUpRepository() *MockSumUpRepository {
 				{ID: uuid.New().String(), Amount: decimal.NewFromFloat(10.00), Status: "COMPLETED"},
 			}, nil
 		},
+		GetTransactionsInPeriodFunc: func(oldestTime, newestTime time.Time) ([]sumup.Transaction, error) {
+			if mockOrphanedTransaction.CreatedAt.Before(oldestTime) ||
+				mockOrphanedTransaction.CreatedAt.After(newestTime) {
+				return []sumup.Transaction{}, nil
+			}
+
+			return []sumup.Transaction{mockOrphanedTransaction}, nil
+		},
 		GetTransactionByIDFunc: func(transactionID uuid.UUID) (*sumup.Transaction, error) {
 			if transactionID.String() == mockCheckoutUUID {
 				return &sumup.Transaction{
@@ -71,7 +93,7 @@ func NewMockSumUpRepository() *MockSumUpRepository {
 
 			return nil, nil
 		},
-		RefundTransactionFunc: func(transactionID uuid.UUID) error {
+		RefundTransactionFunc: func(transactionID uuid.UUID, amount decimal.Decimal) error {
 			if transactionID.String() == mockCheckoutUUID {
 				return nil
 			}
@@ -120,6 +142,12 @@ func (m *MockSumUpRepository) GetTransactions(oldestFrom *time.Time) ([]sumup.Tr
 	return m.GetTransactionsFunc(oldestFrom)
 }
 
+func (m *MockSumUpRepository) GetTransactionsInPeriod(
+	oldestTime, newestTime time.Time,
+) ([]sumup.Transaction, error) {
+	return m.GetTransactionsInPeriodFunc(oldestTime, newestTime)
+}
+
 func (m *MockSumUpRepository) GetTransactionByClientTransactionID(transactionID uuid.UUID) (*sumup.Transaction, error) {
 	return m.GetTransactionByIDFunc(transactionID)
 }
@@ -128,8 +156,8 @@ func (m *MockSumUpRepository) GetTransactionByID(transactionID uuid.UUID) (*sumu
 	return m.GetTransactionByIDFunc(transactionID)
 }
 
-func (m *MockSumUpRepository) RefundTransaction(transactionID uuid.UUID) error {
-	return m.RefundTransactionFunc(transactionID)
+func (m *MockSumUpRepository) RefundTransaction(transactionID uuid.UUID, amount decimal.Decimal) error {
+	return m.RefundTransactionFunc(transactionID, amount)
 }
 
 func (m *MockSumUpRepository) GetWebhookURL() *string {
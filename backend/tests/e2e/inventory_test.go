@@ -0,0 +1,119 @@
+package tests_e2e
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+const stockCountsURL = "/api/v2/stockCounts"
+
+func recordInventoryMovement(inventoryURL string, movement map[string]any) {
+	withDemoUserAuthToken(e.POST(inventoryURL)).
+		WithJSON(movement).
+		Expect().
+		Status(http.StatusCreated)
+}
+
+func TestInventoryLedger(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	productID := int(withAdminUserAuthToken(e.POST(productBaseURL)).
+		WithJSON(map[string]any{"name": "Ledger Mug", "netPrice": "10", "vatRate": "19", "pos": 91}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object().
+		Value("id").Number().Raw())
+	productURL := productBaseURL + "/" + strconv.Itoa(productID)
+	inventoryURL := productURL + "/inventoryMovements"
+
+	updateProductStock(productURL, "Ledger Mug", 5, false)
+	recordInventoryMovement(inventoryURL, map[string]any{"type": "restock", "quantity": 3, "note": "Box 2"})
+
+	purchaseID := purchaseStockItems(productID, 2).
+		Status(http.StatusCreated).JSON().Object().
+		Value("id").String().Raw()
+
+	recordInventoryMovement(inventoryURL, map[string]any{"type": "write_off", "quantity": 1, "note": "Broken"})
+	recordInventoryMovement(inventoryURL, map[string]any{"type": "count_correction", "countedQuantity": 4})
+
+	product := withAdminUserAuthToken(e.GET(productURL)).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	product.Value("stockOnHand").Number().IsEqual(4)
+	product.Value("totalStock").Number().IsEqual(6)
+
+	movementsResponse := withDemoUserAuthToken(e.GET(inventoryURL)).
+		Expect().
+		Status(http.StatusOK)
+	movementsResponse.Header(totalCountHeader).AsNumber().IsEqual(5)
+
+	movements := movementsResponse.JSON().Array()
+	movements.Value(0).Object().Value("type").String().IsEqual("count_correction")
+	movements.Value(0).Object().Value("quantity").Number().IsEqual(-1)
+	movements.Value(2).Object().Value("type").String().IsEqual("sale")
+	movements.Value(2).Object().Value("purchaseId").String().IsEqual(purchaseID)
+	movements.Value(4).Object().Value("type").String().IsEqual("initial")
+
+	count := withDemoUserAuthToken(e.GET(stockCountsURL)).
+		WithQuery("productId", productID).
+		Expect().
+		Status(http.StatusOK).JSON().Array().
+		Value(0).Object()
+	count.Value("productName").String().IsEqual("Ledger Mug")
+	count.Value("expectedQuantity").Number().IsEqual(5)
+	count.Value("countedQuantity").Number().IsEqual(4)
+	count.Value("difference").Number().IsEqual(-1)
+	count.Value("countedBy").String().IsEqual("demo")
+
+	deletePurchase(purchaseBaseURL + "/" + purchaseID)
+
+	withAdminUserAuthToken(e.GET(productURL)).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("stockOnHand").Number().IsEqual(6)
+
+	// less stock in the product form is a correction, not a write-off, and no count
+	updateProductStock(productURL, "Ledger Mug", 4, false)
+
+	movement := withDemoUserAuthToken(e.GET(inventoryURL)).
+		Expect().
+		Status(http.StatusOK).JSON().Array().
+		Value(0).Object()
+	movement.Value("type").String().IsEqual("count_correction")
+	movement.Value("quantity").Number().IsEqual(-2)
+	movement.Value("countedQuantity").IsNull()
+
+	withDemoUserAuthToken(e.GET(stockCountsURL)).
+		WithQuery("productId", productID).
+		Expect().
+		Status(http.StatusOK).JSON().Array().Length().IsEqual(1)
+
+	withAdminUserAuthToken(e.DELETE(productURL)).
+		Expect().
+		Status(http.StatusNoContent)
+}
+
+func TestInventoryMovementValidation(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	inventoryURL := productBaseURL + "/1/inventoryMovements"
+
+	errorResponse := withDemoUserAuthToken(e.POST(inventoryURL)).
+		WithJSON(map[string]any{"type": "count_correction"}).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object()
+	validateErrorDetailMessage(errorResponse, "A count needs the counted quantity")
+
+	errorResponse = withDemoUserAuthToken(e.POST(inventoryURL)).
+		WithJSON(map[string]any{"type": "restock"}).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object()
+	validateErrorDetailMessage(errorResponse, "The quantity must be positive")
+
+	withDemoUserAuthToken(e.POST(inventoryURL)).
+		WithJSON(map[string]any{"type": "sale", "quantity": 1}).
+		Expect().
+		Status(http.StatusBadRequest)
+}
@@ -0,0 +1,53 @@
+package tests_e2e
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/potibm/kasseapparat/internal/app/models"
+	sqliteRepo "github.com/potibm/kasseapparat/internal/app/repository/sqlite"
+	"github.com/potibm/kasseapparat/internal/app/service/journal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournalRecordsPurchaseCreationAndDeletion(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	purchase := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithJSON(map[string]any{
+			"paymentMethod":   "CASH",
+			"totalNetPrice":   "0",
+			"totalGrossPrice": "0",
+			"cart": []map[string]any{
+				{"id": 3, "quantity": 1, "netPrice": "0", "listItems": []map[string]any{}},
+			},
+		}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+
+	purchaseID := purchase.Value("id").String().Raw()
+	deletePurchase(purchaseBaseURL + "/" + purchaseID)
+
+	var entries []models.JournalEntry
+
+	err := db.Where("purchase_id = ?", purchaseID).Order("sequence ASC").Find(&entries).Error
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, models.JournalEventPurchaseCreated, entries[0].EventType)
+	assert.Equal(t, models.JournalEventPurchaseDeleted, entries[1].EventType)
+
+	result, err := journal.NewJournalService(sqliteRepo.NewRepository(db, 2)).Verify()
+	require.NoError(t, err)
+	assert.True(t, result.Valid(), "journal breaks: %v", result.Breaks)
+}
+
+func TestDeleteUnknownPurchase(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	withAdminUserAuthToken(e.DELETE(purchaseBaseURL + "/6f1c3ad2-4a4c-4c55-9f8e-0e2d1f4ff3b1")).
+		Expect().
+		Status(http.StatusNotFound)
+}
@@ -0,0 +1,127 @@
+package tests_e2e
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/potibm/kasseapparat/internal/app/config"
+)
+
+const offlineSyncURL = "/api/v2/purchases/sync"
+
+func offlinePurchase(id string, recordedAt time.Time, netPrice string) map[string]any {
+	purchase := purchaseRequestBody(1)
+	purchase["id"] = id
+	purchase["recordedAt"] = recordedAt.Format(time.RFC3339)
+	purchase["cart"].([]map[string]any)[0]["netPrice"] = netPrice
+
+	return purchase
+}
+
+func TestSyncOfflinePurchases(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	purchaseID := uuid.NewString()
+	recordedAt := time.Now().Add(-10 * time.Minute)
+
+	results := withDemoUserAuthToken(e.POST(offlineSyncURL)).
+		WithJSON(map[string]any{
+			"purchases": []map[string]any{
+				offlinePurchase(purchaseID, recordedAt, "37.38"),
+				offlinePurchase(uuid.NewString(), recordedAt, "1"),
+			},
+		}).
+		Expect().
+		Status(http.StatusOK).JSON().Array()
+	results.Length().IsEqual(2)
+
+	accepted := results.Value(0).Object()
+	accepted.Value("id").String().IsEqual(purchaseID)
+	accepted.Value("status").String().IsEqual("accepted")
+	accepted.Value("purchase").Object().Value("offline").Boolean().IsTrue()
+	accepted.Value("purchase").Object().Value("recordedAt").String().NotEmpty()
+
+	conflict := results.Value(1).Object()
+	conflict.Value("status").String().IsEqual("conflict")
+	conflict.Value("error").String().IsEqual("Invalid product price")
+
+	withDemoUserAuthToken(e.GET(purchaseBaseURL)).
+		WithQuery("offline", true).
+		Expect().
+		Status(http.StatusOK).
+		Header(totalCountHeader).AsNumber().IsEqual(1)
+
+	deletePurchase(purchaseBaseURL + "/" + purchaseID)
+
+	withDemoUserAuthToken(e.POST(offlineSyncURL)).
+		WithJSON(map[string]any{
+			"purchases": []map[string]any{offlinePurchase(purchaseID, recordedAt, "37.38")},
+		}).
+		Expect().
+		Status(http.StatusOK).JSON().Array().
+		Value(0).Object().
+		Value("status").String().IsEqual("duplicate")
+}
+
+func TestSyncOfflinePurchasesValidation(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	withDemoUserAuthToken(e.POST(offlineSyncURL)).
+		WithJSON(map[string]any{"purchases": []map[string]any{}}).
+		Expect().
+		Status(http.StatusBadRequest)
+
+	purchase := offlinePurchase(uuid.NewString(), time.Now(), "37.38")
+	purchase["cart"] = []map[string]any{}
+
+	withDemoUserAuthToken(e.POST(offlineSyncURL)).
+		WithJSON(map[string]any{"purchases": []map[string]any{purchase}}).
+		Expect().
+		Status(http.StatusOK).JSON().Array().
+		Value(0).Object().
+		Value("error").String().IsEqual("Cart must not be empty")
+}
+
+func TestSyncOfflinePurchasesRejectsPurchasesOlderThanTheMaximumAge(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	maxAge := time.Duration(config.DefaultOfflineMaxAgeHours) * time.Hour
+
+	errorResponse := withDemoUserAuthToken(e.POST(offlineSyncURL)).
+		WithJSON(map[string]any{
+			"purchases": []map[string]any{
+				offlinePurchase(uuid.NewString(), time.Now().Add(-10*time.Minute), "37.38"),
+				offlinePurchase(uuid.NewString(), time.Now().Add(-maxAge-time.Minute), "37.38"),
+			},
+		}).
+		Expect().
+		Status(http.StatusUnprocessableEntity).JSON().Object()
+
+	validateErrorDetailMessage(errorResponse, "Purchase was recorded too long ago")
+
+	withDemoUserAuthToken(e.GET(purchaseBaseURL)).
+		WithQuery("offline", true).
+		Expect().
+		Status(http.StatusOK).
+		Header(totalCountHeader).AsNumber().IsEqual(0)
+
+	purchaseID := uuid.NewString()
+
+	withDemoUserAuthToken(e.POST(offlineSyncURL)).
+		WithJSON(map[string]any{
+			"purchases": []map[string]any{
+				offlinePurchase(purchaseID, time.Now().Add(-maxAge+time.Minute), "37.38"),
+			},
+		}).
+		Expect().
+		Status(http.StatusOK).JSON().Array().
+		Value(0).Object().
+		Value("status").String().IsEqual("accepted")
+
+	deletePurchase(purchaseBaseURL + "/" + purchaseID)
+}
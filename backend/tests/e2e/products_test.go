@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/gavv/httpexpect/v2"
 )
@@ -140,6 +141,224 @@ func TestCreateUpdateAndDeleteProduct(t *testing.T) {
 		Status(http.StatusNotFound)
 }
 
+func TestBundleProductIsSoldAsComponents(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	shirt := withDemoUserAuthToken(e.POST(productBaseURL)).
+		WithJSON(map[string]any{"name": "Shirt", "netPrice": "16.81", "vatRate": "19", "pos": 200}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+	shirtURL := productBaseURL + "/" + strconv.FormatFloat(shirt.Value("id").Number().Raw(), 'f', -1, 64)
+
+	bundle := withDemoUserAuthToken(e.POST(productBaseURL)).
+		WithJSON(map[string]any{
+			"name":       "Weekend Pack",
+			"netPrice":   "0",
+			"vatRate":    "0",
+			"pos":        201,
+			"grossPrice": "35",
+			"bundleItems": []map[string]any{
+				{"productId": 2, "quantity": 1},
+				{"productId": shirt.Value("id").Number().Raw(), "quantity": 1},
+			},
+		}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+
+	bundle.Value("netPrice").String().IsEqual("31.06")
+	bundle.Value("bundleItems").Array().Length().IsEqual(2)
+
+	bundleID := bundle.Value("id").Number().Raw()
+	bundleURL := productBaseURL + "/" + strconv.FormatFloat(bundleID, 'f', -1, 64)
+
+	withDemoUserAuthToken(e.GET(bundleURL)).
+		Expect().
+		Status(http.StatusOK).JSON().Object().Value("grossPrice").String().IsEqual("35")
+
+	totalQuantity := e.GET(purchaseStatsURL).
+		Expect().
+		Status(http.StatusOK).JSON().Object().Value("totalQuantity").Number().Raw()
+
+	purchase := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithJSON(map[string]any{
+			"paymentMethod":   "CASH",
+			"totalNetPrice":   "31.06",
+			"totalGrossPrice": "35",
+			"cart": []map[string]any{
+				{"ID": bundleID, "quantity": 1, "netPrice": "31.06", "listItems": []map[string]any{}},
+			},
+		}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+
+	items := purchase.Value("purchaseItems").Array()
+	items.Length().IsEqual(2)
+	items.Value(0).Object().Value("productID").Number().IsEqual(2)
+	items.Value(0).Object().Value("bundleID").Number().IsEqual(bundleID)
+
+	// the ticket counts towards the visitors, the shirt towards its stock
+	e.GET(purchaseStatsURL).
+		Expect().
+		Status(http.StatusOK).JSON().Object().Value("totalQuantity").Number().IsEqual(totalQuantity + 1)
+
+	withDemoUserAuthToken(e.GET(shirtURL)).
+		Expect().
+		Status(http.StatusOK).JSON().Object().Value("unitsSold").Number().IsEqual(1)
+
+	deletePurchase(purchaseBaseURL + "/" + purchase.Value("id").String().Raw())
+}
+
+func TestBundleProductCannotContainBundles(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	bundle := withDemoUserAuthToken(e.POST(productBaseURL)).
+		WithJSON(map[string]any{
+			"name":        "Pack",
+			"netPrice":    "0",
+			"vatRate":     "0",
+			"pos":         201,
+			"grossPrice":  "20",
+			"bundleItems": []map[string]any{{"productId": 2, "quantity": 1}},
+		}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+
+	errorResponse := withDemoUserAuthToken(e.POST(productBaseURL)).
+		WithJSON(map[string]any{
+			"name":        "Pack of Packs",
+			"netPrice":    "0",
+			"vatRate":     "0",
+			"pos":         202,
+			"grossPrice":  "40",
+			"bundleItems": []map[string]any{{"productId": bundle.Value("id").Number().Raw(), "quantity": 2}},
+		}).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object()
+
+	validateErrorDetailMessage(errorResponse, "A bundle cannot contain another bundle")
+}
+
+func TestProductVariants(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	shirt := withDemoUserAuthToken(e.POST(productBaseURL)).
+		WithJSON(map[string]any{
+			"name":     "Shirt",
+			"netPrice": "16.81",
+			"vatRate":  "19",
+			"pos":      200,
+			"variants": []map[string]any{
+				{"name": "S", "totalStock": 5, "pos": 1},
+				{"name": "XXL", "totalStock": 3, "pos": 2, "netPrice": "20.17"},
+			},
+		}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+
+	shirtID := shirt.Value("id").Number().Raw()
+	shirtURL := productBaseURL + "/" + strconv.FormatFloat(shirtID, 'f', -1, 64)
+	variants := shirt.Value("variants").Array()
+	smallID := variants.Value(0).Object().Value("id").Number().Raw()
+	largeID := variants.Value(1).Object().Value("id").Number().Raw()
+
+	purchase := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithJSON(map[string]any{
+			"paymentMethod":   "CASH",
+			"totalNetPrice":   "57.15",
+			"totalGrossPrice": "68",
+			"cart": []map[string]any{
+				{
+					"ID":        shirtID,
+					"variantId": smallID,
+					"quantity":  1,
+					"netPrice":  "16.81",
+					"listItems": []map[string]any{},
+				},
+				{
+					"ID":        shirtID,
+					"variantId": largeID,
+					"quantity":  2,
+					"netPrice":  "20.17",
+					"listItems": []map[string]any{},
+				},
+			},
+		}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+
+	purchase.Value("purchaseItems").Array().Value(1).Object().Value("variantID").Number().IsEqual(largeID)
+
+	// a product with variants is only sold as one of them
+	withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithJSON(map[string]any{
+			"paymentMethod":   "CASH",
+			"totalNetPrice":   "16.81",
+			"totalGrossPrice": "20",
+			"cart": []map[string]any{
+				{"ID": shirtID, "quantity": 1, "netPrice": "16.81", "listItems": []map[string]any{}},
+			},
+		}).
+		Expect().
+		Status(http.StatusBadRequest)
+
+	withDemoUserAuthToken(e.POST(productInterestBaseURL)).
+		WithJSON(map[string]any{"productId": shirtID, "variantId": largeID}).
+		Expect().
+		Status(http.StatusCreated)
+
+	product := withDemoUserAuthToken(e.GET(shirtURL)).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+
+	product.Value("unitsSold").Number().IsEqual(3)
+	product.Value("totalStock").Number().IsEqual(8)
+	product.Value("soldOutRequestCount").Number().IsEqual(1)
+
+	large := product.Value("variants").Array().Value(1).Object()
+	large.Value("grossPrice").String().IsEqual("24")
+	large.Value("unitsSold").Number().IsEqual(2)
+	large.Value("soldOutRequestCount").Number().IsEqual(1)
+
+	stats := withDemoUserAuthToken(e.GET(productStatsURL)).
+		Expect().
+		Status(http.StatusOK).JSON().Array()
+
+	for _, value := range stats.Iter() {
+		if value.Object().Value("id").Number().Raw() != shirtID {
+			continue
+		}
+
+		value.Object().Value("soldItems").Number().IsEqual(3)
+		value.Object().Value("variants").Array().Value(1).Object().Value("soldItems").Number().IsEqual(2)
+	}
+
+	// updating keeps the variants listed and removes the others
+	withDemoUserAuthToken(e.PUT(shirtURL)).
+		WithJSON(map[string]any{
+			"name":     "Shirt",
+			"netPrice": "16.81",
+			"vatRate":  "19",
+			"pos":      200,
+			"variants": []map[string]any{{"id": largeID, "name": "XXL", "totalStock": 10}},
+		}).
+		Expect().
+		Status(http.StatusOK)
+
+	variants = withDemoUserAuthToken(e.GET(shirtURL)).
+		Expect().
+		Status(http.StatusOK).JSON().Object().Value("variants").Array()
+
+	variants.Length().IsEqual(1)
+	variants.Value(0).Object().Value("id").Number().IsEqual(largeID)
+	variants.Value(0).Object().Value("unitsSold").Number().IsEqual(2)
+	variants.Value(0).Object().Value("grossPrice").String().IsEqual("20")
+
+	deletePurchase(purchaseBaseURL + "/" + purchase.Value("id").String().Raw())
+}
+
 func TestDemoUserIsNotAllowedToDeleteAProduct(t *testing.T) {
 	_, cleanup := setupTestEnvironment(t)
 	defer cleanup()
@@ -183,3 +402,90 @@ func validateProductOne(product *httpexpect.Object) {
 	product.Value("soldOutRequestCount").Number().IsEqual(0)
 	product.Value("apiExport").Boolean().IsTrue()
 }
+
+func TestProductPriceRules(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	now := time.Now()
+
+	ticket := withDemoUserAuthToken(e.POST(productBaseURL)).
+		WithJSON(map[string]any{
+			"name":     "Weekend Ticket",
+			"netPrice": "37.38",
+			"vatRate":  "7",
+			"pos":      210,
+			"priceRules": []map[string]any{
+				{"netPrice": "9.35", "validFrom": now.Add(48 * time.Hour).Format(time.RFC3339)},
+				{"netPrice": "18.69", "validFrom": now.Add(-time.Hour).Format(time.RFC3339), "pos": 1},
+			},
+		}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+
+	ticketID := ticket.Value("id").Number().Raw()
+	ticketURL := productBaseURL + "/" + strconv.FormatFloat(ticketID, 'f', -1, 64)
+
+	product := withDemoUserAuthToken(e.GET(ticketURL)).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+
+	product.Value("netPrice").String().IsEqual("18.69")
+	product.Value("grossPrice").String().IsEqual("20")
+	product.Value("listNetPrice").String().IsEqual("37.38")
+	product.Value("nextPrice").Object().Value("netPrice").String().IsEqual("9.35")
+	product.Value("nextPrice").Object().Value("grossPrice").String().IsEqual("10")
+
+	purchase := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithJSON(map[string]any{
+			"paymentMethod":   "CASH",
+			"totalNetPrice":   "18.69",
+			"totalGrossPrice": "20",
+			"cart": []map[string]any{
+				{"ID": ticketID, "quantity": 1, "netPrice": "18.69", "listItems": []map[string]any{}},
+			},
+		}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+
+	purchaseURL := purchaseBaseURL + "/" + purchase.Value("id").String().Raw()
+
+	// removing the rules restores the list price, the purchase keeps its price
+	withDemoUserAuthToken(e.PUT(ticketURL)).
+		WithJSON(map[string]any{"name": "Weekend Ticket", "netPrice": "37.38", "vatRate": "7", "pos": 210}).
+		Expect().
+		Status(http.StatusOK)
+
+	product = withDemoUserAuthToken(e.GET(ticketURL)).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+
+	product.Value("netPrice").String().IsEqual("37.38")
+	product.Value("nextPrice").IsNull()
+
+	withDemoUserAuthToken(e.GET(purchaseURL)).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("purchaseItems").Array().Value(0).Object().Value("netPrice").String().IsEqual("18.69")
+
+	deletePurchase(purchaseURL)
+	withAdminUserAuthToken(e.DELETE(ticketURL)).Expect().Status(http.StatusNoContent)
+}
+
+func TestProductPriceRuleWithInvalidHours(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	errorResponse := withDemoUserAuthToken(e.POST(productBaseURL)).
+		WithJSON(map[string]any{
+			"name":       "Happy Hour Beer",
+			"netPrice":   "2.52",
+			"vatRate":    "19",
+			"pos":        220,
+			"priceRules": []map[string]any{{"netPrice": "1.68", "fromHour": 18}},
+		}).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object()
+
+	validateErrorDetailMessage(errorResponse, "Price rule hours must be given both and must differ")
+}
@@ -1,6 +1,7 @@
 package tests_e2e
 
 import (
+	"encoding/csv"
 	"net/http"
 	"strconv"
 	"strings"
@@ -46,8 +47,8 @@ func TestGetPurchaseExport(t *testing.T) {
 }
 
 func validatePurchaseExportLine(t *testing.T, columns []string, i int) {
-	if len(columns) != 15 {
-		t.Errorf("Expected 15 columns, got %d in line %d", len(columns), i)
+	if len(columns) != 17 {
+		t.Errorf("Expected 17 columns, got %d in line %d", len(columns), i)
 	}
 
 	if _, err := time.Parse("2006-01-02 15:04:05", columns[0]); err != nil {
@@ -67,6 +68,10 @@ func validatePurchaseExportLine(t *testing.T, columns []string, i int) {
 			t.Errorf("Expected a valid decimal value in column %d, got %s in line %d", j, columns[j], i)
 		}
 	}
+
+	if len(columns) == 17 && columns[16] != "" && !strings.HasPrefix(columns[16], "TEST-") {
+		t.Errorf("Expected a receipt number in column 17, got %s in line %d", columns[16], i)
+	}
 }
 
 func TestGetPurchaseExportFilterOnCASH(t *testing.T) {
@@ -98,8 +103,8 @@ func testGetPurchaseExportFilterOnPaymentMethod(t *testing.T, paymentMethod stri
 		columns := strings.Split(line, ",")
 
 		// assert that the number of columns is correct
-		if len(columns) != 15 {
-			t.Fatalf("Expected 15 columns, got %d in line %d", len(columns), i)
+		if len(columns) != 17 {
+			t.Fatalf("Expected 17 columns, got %d in line %d", len(columns), i)
 		}
 
 		paymentMethodInCSV := columns[14]
@@ -108,3 +113,59 @@ func testGetPurchaseExportFilterOnPaymentMethod(t *testing.T, paymentMethod stri
 		}
 	}
 }
+
+func TestPurchaseExportSplitsThePaymentAmountsAcrossTheLines(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	purchaseID := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithJSON(map[string]any{
+			"totalNetPrice":   "56.07",
+			"totalGrossPrice": "60",
+			"payments": []map[string]any{
+				{"paymentMethod": "CASH", "amount": "5.13"},
+				{"paymentMethod": "CC", "amount": "54.87"},
+			},
+			"cart": []map[string]any{
+				{
+					"ID":        1,
+					"quantity":  1,
+					"netPrice":  "37.38",
+					"listItems": []map[string]any{},
+				},
+				{
+					"ID":        2,
+					"quantity":  1,
+					"netPrice":  "18.69",
+					"listItems": []map[string]any{},
+				},
+			},
+		}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object().
+		Value("id").String().Raw()
+
+	raw := withDemoUserAuthToken(e.GET(purchaseExportBaseURL)).
+		Expect().
+		Status(http.StatusOK).Body().Raw()
+
+	records, err := csv.NewReader(strings.NewReader(raw)).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read the export: %v", err)
+	}
+
+	paid := map[string]decimal.Decimal{}
+
+	for _, record := range records[1:] {
+		if record[1] == purchaseID {
+			paid[record[14]] = paid[record[14]].Add(decimal.RequireFromString(record[15]))
+		}
+	}
+
+	// the cash paid is rounded to 5, the rounding line takes the difference to the exact amount
+	if !paid["CASH"].Equal(decimal.NewFromInt(5)) || !paid["CC"].Equal(decimal.NewFromFloat(54.87)) {
+		t.Errorf("expected 5 paid in cash and 54.87 by card, got %v", paid)
+	}
+
+	deletePurchase(purchaseBaseURL + "/" + purchaseID)
+}
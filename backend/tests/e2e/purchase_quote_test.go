@@ -0,0 +1,78 @@
+package tests_e2e
+
+import (
+	"net/http"
+	"testing"
+)
+
+const purchaseQuoteURL = "/api/v2/purchases/quote"
+
+func quoteCart(listItems []map[string]any) map[string]any {
+	return map[string]any{
+		"cart": []map[string]any{
+			{"ID": 1, "quantity": 1, "listItems": listItems},
+		},
+	}
+}
+
+func TestQuoteAndPurchaseWithQuoteToken(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	quote := withDemoUserAuthToken(e.POST(purchaseQuoteURL)).
+		WithJSON(quoteCart([]map[string]any{})).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	quote.Value("totalNetPrice").String().IsEqual("37.38")
+	quote.Value("totalGrossPrice").String().IsEqual("40")
+	quote.Value("items").Array().Value(0).Object().Value("netPrice").String().IsEqual("37.38")
+	quote.Value("vatRates").Array().Length().IsEqual(1)
+	quote.Value("stock").Array().Value(0).Object().Value("sufficient").Boolean().IsTrue()
+	quote.Value("guestErrors").Array().IsEmpty()
+
+	token := quote.Value("token").String().NotEmpty().Raw()
+
+	purchase := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithJSON(map[string]any{
+			"paymentMethod": "CASH",
+			"quoteToken":    token,
+			"cart": []map[string]any{
+				{"ID": 1, "quantity": 1, "listItems": []map[string]any{}},
+			},
+		}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+	purchase.Value("totalGrossPrice").String().IsEqual("40")
+
+	deletePurchase(purchaseBaseURL + "/" + purchase.Value("id").String().Raw())
+
+	errorResponse := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithJSON(map[string]any{
+			"paymentMethod": "CASH",
+			"quoteToken":    token,
+			"cart": []map[string]any{
+				{"ID": 1, "quantity": 2, "listItems": []map[string]any{}},
+			},
+		}).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object()
+	validateErrorDetailMessage(errorResponse, "The quote does not match the cart")
+}
+
+func TestQuoteReportsGuestErrors(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	guestError := withDemoUserAuthToken(e.POST(purchaseQuoteURL)).
+		WithJSON(quoteCart([]map[string]any{{"ID": 9999, "attendedGuests": 1}})).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("guestErrors").Array().Value(0).Object()
+	guestError.Value("guestId").Number().IsEqual(9999)
+	guestError.Value("error").String().IsEqual("Guest not found")
+
+	withDemoUserAuthToken(e.POST(purchaseQuoteURL)).
+		WithJSON(map[string]any{"cart": []map[string]any{}}).
+		Expect().
+		Status(http.StatusBadRequest)
+}
@@ -0,0 +1,116 @@
+package tests_e2e
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	sqliteRepo "github.com/potibm/kasseapparat/internal/app/repository/sqlite"
+)
+
+func TestGetPurchaseReceipt(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	purchase := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithJSON(map[string]any{
+			"paymentMethod":   "CASH",
+			"totalNetPrice":   "18.69",
+			"totalGrossPrice": "20",
+			"cart": []map[string]any{
+				{
+					"ID":        2,
+					"quantity":  1,
+					"netPrice":  "18.69",
+					"listItems": []map[string]any{},
+				},
+			},
+		}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+
+	receiptURL := purchaseBaseURL + "/" + purchase.Value("id").String().Raw() + "/receipt"
+
+	withDemoUserAuthToken(e.GET(receiptURL)).
+		Expect().
+		Status(http.StatusOK).
+		ContentType("text/html").
+		Body().Contains("20.00").Contains("demo")
+
+	withDemoUserAuthToken(e.GET(receiptURL)).
+		WithQuery("format", "text").
+		Expect().
+		Status(http.StatusOK).
+		ContentType("text/plain").
+		Body().Contains("TOTAL").Contains("20.00")
+
+	withDemoUserAuthToken(e.GET(receiptURL)).
+		WithQuery("format", "pdf").
+		Expect().
+		Status(http.StatusBadRequest)
+
+	withDemoUserAuthToken(e.GET(purchaseBaseURL + "/123e4567-e89b-12d3-a456-426614174000/receipt")).
+		Expect().
+		Status(http.StatusNotFound)
+
+	deletePurchase(purchaseBaseURL + "/" + purchase.Value("id").String().Raw())
+}
+
+func TestSendPurchaseReceiptMail(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	purchase := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithJSON(map[string]any{
+			"paymentMethod":   "CASH",
+			"totalNetPrice":   "18.69",
+			"totalGrossPrice": "20",
+			"cart": []map[string]any{
+				{
+					"ID":        2,
+					"quantity":  1,
+					"netPrice":  "18.69",
+					"listItems": []map[string]any{},
+				},
+			},
+		}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+
+	purchaseURL := purchaseBaseURL + "/" + purchase.Value("id").String().Raw()
+
+	withDemoUserAuthToken(e.POST(purchaseURL + "/receipt/mail")).
+		WithJSON(map[string]any{"email": "not-an-email"}).
+		Expect().
+		Status(http.StatusBadRequest)
+
+	mail := withDemoUserAuthToken(e.POST(purchaseURL + "/receipt/mail")).
+		WithJSON(map[string]any{"email": "visitor@example.com"}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+
+	mail.Value("email").String().IsEqual("visitor@example.com")
+	mail.Value("sentAt").String().NotEmpty()
+
+	receiptMails := withDemoUserAuthToken(e.GET(purchaseURL)).
+		Expect().
+		Status(http.StatusOK).JSON().Object().Value("receiptMails").Array()
+
+	receiptMails.Length().IsEqual(1)
+	receiptMails.Value(0).Object().Value("email").String().IsEqual("visitor@example.com")
+
+	// once the retention period is over only the record of the send remains
+	_, err := sqliteRepo.NewRepository(db, 2).ErasePurchaseReceiptMailAddresses(time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("failed to erase receipt mail addresses: %v", err)
+	}
+
+	receiptMails = withDemoUserAuthToken(e.GET(purchaseURL)).
+		Expect().
+		Status(http.StatusOK).JSON().Object().Value("receiptMails").Array()
+
+	receiptMails.Length().IsEqual(1)
+	receiptMails.Value(0).Object().Value("email").IsNull()
+
+	deletePurchase(purchaseURL)
+}
@@ -2,7 +2,12 @@ package tests_e2e
 
 import (
 	"net/http"
+	"strconv"
 	"testing"
+
+	"github.com/gavv/httpexpect/v2"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
 )
 
 var purchaseBaseURL = "/api/v2/purchases"
@@ -53,6 +58,24 @@ func TestGetPurchasesListWithAllFilters(t *testing.T) {
 	purchaseList.Length().IsEqual(0)
 }
 
+func TestGetExpiredPurchases(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	purchaseListResponse := withDemoUserAuthToken(e.GET(purchaseBaseURL)).
+		WithQuery("expired", "true").
+		Expect().
+		Status(http.StatusOK)
+
+	purchaseListResponse.Header(totalCountHeader).AsNumber().IsEqual(0)
+
+	withDemoUserAuthToken(e.GET(purchaseBaseURL)).
+		WithQuery("expired", "false").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Array().Value(0).Object().Value("expiredAt").IsNull()
+}
+
 func TestGetPurchasesWithSort(t *testing.T) {
 	_, cleanup := setupTestEnvironment(t)
 	defer cleanup()
@@ -144,6 +167,10 @@ func TestCreatePurchaseWithList(t *testing.T) {
 	withDemoUserAuthToken(e.GET(purchaseURL)).
 		Expect().
 		Status(http.StatusNotFound)
+
+	withDemoUserAuthToken(e.DELETE(purchaseURL)).
+		Expect().
+		Status(http.StatusNotFound)
 }
 
 func TestCreatePurchaseWithWithFreeProduct(t *testing.T) {
@@ -188,6 +215,290 @@ func TestCreatePurchaseWithWithFreeProduct(t *testing.T) {
 	deletePurchase(purchaseURL)
 }
 
+func TestCreatePurchaseAssignsConsecutiveReceiptNumbers(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	createPurchase := func() *httpexpect.Object {
+		return withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+			WithJSON(map[string]any{
+				"paymentMethod":   "CASH",
+				"totalNetPrice":   "0",
+				"totalGrossPrice": "0",
+				"cart": []map[string]any{
+					{"id": 3, "quantity": 1, "netPrice": "0", "listItems": []map[string]any{}},
+				},
+			}).
+			Expect().
+			Status(http.StatusCreated).JSON().Object()
+	}
+
+	first := createPurchase()
+	second := createPurchase()
+
+	firstNumber := first.Value("receiptNumber").String().Match(`^TEST-\d{4}-(\d{6})$`).Submatch(1)
+	secondNumber := second.Value("receiptNumber").String().Match(`^TEST-\d{4}-(\d{6})$`).Submatch(1)
+
+	firstSequence, _ := strconv.Atoi(firstNumber.Raw())
+	secondSequence, _ := strconv.Atoi(secondNumber.Raw())
+	assert.Equal(t, firstSequence+1, secondSequence)
+
+	deletePurchase(purchaseBaseURL + "/" + first.Value("id").String().Raw())
+	deletePurchase(purchaseBaseURL + "/" + second.Value("id").String().Raw())
+}
+
+func TestCreatePurchaseWithSplitPayment(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	purchase := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithJSON(map[string]any{
+			"totalNetPrice":   "18.69",
+			"totalGrossPrice": "20",
+			"payments": []map[string]any{
+				{"paymentMethod": "CASH", "amount": "5"},
+				{"paymentMethod": "CC", "amount": "15"},
+			},
+			"cart": []map[string]any{
+				{
+					"ID":        2,
+					"quantity":  1,
+					"netPrice":  "18.69",
+					"listItems": []map[string]any{},
+				},
+			},
+		}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+
+	purchase.Value("status").String().IsEqual("confirmed")
+	purchase.Value("paymentMethod").String().IsEqual("CASH")
+
+	payments := purchase.Value("payments").Array()
+	payments.Length().IsEqual(2)
+	payments.Value(0).Object().Value("paymentMethod").String().IsEqual("CASH")
+	payments.Value(0).Object().Value("amount").String().IsEqual("5")
+	payments.Value(1).Object().Value("paymentMethod").String().IsEqual("CC")
+	payments.Value(1).Object().Value("amount").String().IsEqual("15")
+
+	purchaseURL := purchaseBaseURL + "/" + purchase.Value("id").String().Raw()
+
+	withDemoUserAuthToken(e.GET(purchaseURL)).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("payments").Array().Length().IsEqual(2)
+
+	deletePurchase(purchaseURL)
+}
+
+func TestCreatePurchaseWithCashRounding(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	cashIn := func() decimal.Decimal {
+		raw := withDemoUserAuthToken(e.GET(registerSessionBaseURL + "/current")).
+			Expect().
+			Status(http.StatusOK).JSON().Object().
+			Value("cashIn").String().Raw()
+
+		return decimal.RequireFromString(raw)
+	}
+	cashInBefore := cashIn()
+
+	purchase := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithJSON(map[string]any{
+			"totalNetPrice":   "18.69",
+			"totalGrossPrice": "20",
+			"payments": []map[string]any{
+				{"paymentMethod": "CASH", "amount": "5.13"},
+				{"paymentMethod": "CC", "amount": "14.87"},
+			},
+			"cart": []map[string]any{
+				{
+					"ID":        2,
+					"quantity":  1,
+					"netPrice":  "18.69",
+					"listItems": []map[string]any{},
+				},
+			},
+		}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+
+	purchase.Value("totalGrossPrice").String().IsEqual("20")
+	purchase.Value("payments").Array().Value(0).Object().Value("amount").String().IsEqual("5.13")
+
+	roundings := purchase.Value("roundings").Array()
+	roundings.Length().IsEqual(1)
+	roundings.Value(0).Object().Value("paymentMethod").String().IsEqual("CASH")
+	roundings.Value(0).Object().Value("amount").String().IsEqual("-0.13")
+
+	if cashIn := cashIn().Sub(cashInBefore); !cashIn.Equal(decimal.NewFromInt(5)) {
+		t.Errorf("expected 5 cash to be taken, got %s", cashIn)
+	}
+
+	deletePurchase(purchaseBaseURL + "/" + purchase.Value("id").String().Raw())
+}
+
+func TestRefundPurchaseWithCashRounding(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	cashOut := func() decimal.Decimal {
+		raw := withDemoUserAuthToken(e.GET(registerSessionBaseURL + "/current")).
+			Expect().
+			Status(http.StatusOK).JSON().Object().
+			Value("cashOut").String().Raw()
+
+		return decimal.RequireFromString(raw)
+	}
+
+	purchaseID := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithJSON(map[string]any{
+			"totalNetPrice":   "18.69",
+			"totalGrossPrice": "20",
+			"payments": []map[string]any{
+				{"paymentMethod": "CASH", "amount": "5.13"},
+				{"paymentMethod": "CC", "amount": "14.87"},
+			},
+			"cart": []map[string]any{
+				{
+					"ID":        2,
+					"quantity":  1,
+					"netPrice":  "18.69",
+					"listItems": []map[string]any{},
+				},
+			},
+		}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object().
+		Value("id").String().Raw()
+	purchaseURL := purchaseBaseURL + "/" + purchaseID
+
+	cashOutBefore := cashOut()
+
+	refunded := withDemoUserAuthToken(e.POST(purchaseURL + "/refund")).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+
+	refunded.Value("roundings").Array().Length().IsEqual(1)
+
+	refund := refunded.Value("refunds").Array().Value(0).Object()
+	refund.Value("payments").Array().Value(0).Object().Value("amount").String().IsEqual("5.13")
+
+	roundings := refund.Value("roundings").Array()
+	roundings.Length().IsEqual(1)
+	roundings.Value(0).Object().Value("paymentMethod").String().IsEqual("CASH")
+	roundings.Value(0).Object().Value("amount").String().IsEqual("0.13")
+
+	if paidBack := cashOut().Sub(cashOutBefore); !paidBack.Equal(decimal.NewFromInt(5)) {
+		t.Errorf("expected 5 cash to be paid back, got %s", paidBack)
+	}
+
+	deletePurchase(purchaseURL)
+}
+
+func TestCreatePurchaseWithSplitPaymentNotMatchingTotal(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	errorResponse := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithJSON(map[string]any{
+			"totalNetPrice":   "18.69",
+			"totalGrossPrice": "20",
+			"payments": []map[string]any{
+				{"paymentMethod": "CASH", "amount": "5"},
+				{"paymentMethod": "CC", "amount": "10"},
+			},
+			"cart": []map[string]any{
+				{
+					"ID":        2,
+					"quantity":  1,
+					"netPrice":  "18.69",
+					"listItems": []map[string]any{},
+				},
+			},
+		}).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object()
+
+	validateErrorDetailMessage(errorResponse, "Payments do not add up to total gross price")
+}
+
+func TestRefundPurchaseItems(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	unitsSold := withDemoUserAuthToken(e.GET(productBaseURL + "/2")).
+		Expect().
+		Status(http.StatusOK).JSON().Object().Value("unitsSold").Number().Raw()
+
+	purchase := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithJSON(map[string]any{
+			"paymentMethod":   "CASH",
+			"totalNetPrice":   "37.38",
+			"totalGrossPrice": "40",
+			"cart": []map[string]any{
+				{
+					"ID":        2,
+					"quantity":  2,
+					"netPrice":  "18.69",
+					"listItems": []map[string]any{},
+				},
+			},
+		}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+
+	purchaseURL := purchaseBaseURL + "/" + purchase.Value("id").String().Raw()
+	purchaseItemID := purchase.Value("purchaseItems").Array().Value(0).Object().Value("id").Number().Raw()
+
+	refunded := withDemoUserAuthToken(e.POST(purchaseURL + "/refunds")).
+		WithJSON(map[string]any{
+			"items": []map[string]any{
+				{"purchaseItemId": purchaseItemID, "quantity": 1},
+			},
+		}).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+
+	refunded.Value("status").String().IsEqual("confirmed")
+	refunded.Value("purchaseItems").Array().Value(0).Object().Value("refundedQuantity").Number().IsEqual(1)
+
+	refunds := refunded.Value("refunds").Array()
+	refunds.Length().IsEqual(1)
+	refunds.Value(0).Object().Value("totalGrossPrice").String().IsEqual("20")
+	refunds.Value(0).Object().Value("payments").Array().Value(0).Object().Value("amount").String().IsEqual("20")
+
+	withDemoUserAuthToken(e.GET(productBaseURL + "/2")).
+		Expect().
+		Status(http.StatusOK).JSON().Object().Value("unitsSold").Number().IsEqual(unitsSold + 1)
+
+	errorResponse := withDemoUserAuthToken(e.POST(purchaseURL + "/refunds")).
+		WithJSON(map[string]any{
+			"items": []map[string]any{
+				{"purchaseItemId": purchaseItemID, "quantity": 2},
+			},
+		}).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object()
+
+	validateErrorDetailMessage(errorResponse, "Refund quantity exceeds the remaining quantity")
+
+	withDemoUserAuthToken(e.POST(purchaseURL + "/refund")).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("status").String().IsEqual("refunded")
+
+	errorResponse = withDemoUserAuthToken(e.POST(purchaseURL + "/refund")).
+		Expect().
+		Status(http.StatusConflict).JSON().Object()
+
+	validateErrorDetailMessage(errorResponse, "Only confirmed purchases can be refunded, the purchase is refunded")
+
+	deletePurchase(purchaseURL)
+}
+
 func TestCreatePurchaseWithWrongTotalGrossPrice(t *testing.T) {
 	_, cleanup := setupTestEnvironment(t)
 	defer cleanup()
@@ -0,0 +1,171 @@
+package tests_e2e
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/potibm/kasseapparat/internal/app/models"
+	sqliteRepo "github.com/potibm/kasseapparat/internal/app/repository/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const registerSessionBaseURL = "/api/v2/registerSessions"
+
+func TestRegisterSessionLifecycle(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	session := withDemoUserAuthToken(e.GET(registerSessionBaseURL + "/current")).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+
+	session.Value("status").String().IsEqual("open")
+	sessionID := int(session.Value("id").Number().Raw())
+	expectedCash := session.Value("expectedCash").String().Raw()
+
+	// only one session per cashier can be open at a time
+	errorResponse := withDemoUserAuthToken(e.POST(registerSessionBaseURL)).
+		WithJSON(map[string]any{"openingFloat": "50"}).
+		Expect().
+		Status(http.StatusConflict).JSON().Object()
+
+	validateErrorDetailMessage(errorResponse, "There is already an open register session")
+
+	purchase := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithJSON(map[string]any{
+			"paymentMethod":   "CASH",
+			"totalNetPrice":   "18.69",
+			"totalGrossPrice": "20",
+			"cart": []map[string]any{
+				{
+					"ID":        2,
+					"quantity":  1,
+					"netPrice":  "18.69",
+					"listItems": []map[string]any{},
+				},
+			},
+		}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+
+	purchase.Value("registerSessionId").Number().IsEqual(sessionID)
+
+	sessionURL := registerSessionBaseURL + "/" + strconv.Itoa(sessionID)
+
+	session = withDemoUserAuthToken(e.GET(sessionURL)).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+
+	session.Value("cashIn").String().NotEqual("0")
+	session.Value("expectedCash").String().NotEqual(expectedCash)
+	expectedCash = session.Value("expectedCash").String().Raw()
+
+	closedSession := withDemoUserAuthToken(e.POST(sessionURL + "/close")).
+		WithJSON(map[string]any{
+			"denominations": []map[string]any{
+				{"value": "0.5", "count": 1},
+			},
+			"note": "end of shift",
+		}).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+
+	closedSession.Value("status").String().IsEqual("closed")
+	closedSession.Value("expectedCash").String().IsEqual(expectedCash)
+	closedSession.Value("countedCash").String().IsEqual("0.5")
+	closedSession.Value("difference").String().NotEmpty()
+	closedSession.Value("denominations").Array().Length().IsEqual(1)
+
+	// cash sales are refused without an open session
+	errorResponse = withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithJSON(map[string]any{
+			"paymentMethod":   "CASH",
+			"totalNetPrice":   "18.69",
+			"totalGrossPrice": "20",
+			"cart": []map[string]any{
+				{
+					"ID":        2,
+					"quantity":  1,
+					"netPrice":  "18.69",
+					"listItems": []map[string]any{},
+				},
+			},
+		}).
+		Expect().
+		Status(http.StatusConflict).JSON().Object()
+
+	validateErrorDetailMessage(
+		errorResponse,
+		"No open register session, please open a session before selling for cash",
+	)
+
+	// and so are cash refunds
+	errorResponse = withDemoUserAuthToken(
+		e.POST(purchaseBaseURL + "/" + purchase.Value("id").String().Raw() + "/refund"),
+	).
+		Expect().
+		Status(http.StatusConflict).JSON().Object()
+
+	validateErrorDetailMessage(errorResponse, "No open register session for cash payments")
+
+	withDemoUserAuthToken(e.POST(sessionURL + "/close")).
+		WithJSON(map[string]any{"countedCash": "10"}).
+		Expect().
+		Status(http.StatusConflict)
+
+	newSession := withDemoUserAuthToken(e.POST(registerSessionBaseURL)).
+		WithJSON(map[string]any{"openingFloat": "100"}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+
+	newSession.Value("status").String().IsEqual("open")
+	newSession.Value("openingFloat").String().IsEqual("100")
+
+	deletePurchase(purchaseBaseURL + "/" + purchase.Value("id").String().Raw())
+}
+
+func TestRegisterSessionsList(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	res := withAdminUserAuthToken(e.GET(registerSessionBaseURL)).
+		Expect().
+		Status(http.StatusOK)
+
+	res.Header(totalCountHeader).AsNumber().Ge(2)
+	res.JSON().Array().Length().Ge(2)
+
+	withDemoUserAuthToken(e.GET(registerSessionBaseURL)).
+		WithQuery("status", "open").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Array().Length().IsEqual(1)
+}
+
+func TestCreateRegisterSessionOnlyIgnoresAnotherOpenSession(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	repo := sqliteRepo.NewRepository(db, 2)
+
+	var open models.RegisterSession
+	require.NoError(t, db.Where("status = ?", models.RegisterSessionStatusOpen).First(&open).Error)
+
+	second := models.RegisterSession{Status: models.RegisterSessionStatusOpen, OpenedAt: time.Now()}
+	second.CreatedByID = open.CreatedByID
+
+	_, err := repo.CreateRegisterSession(second)
+	assert.ErrorIs(t, err, sqliteRepo.ErrRegisterSessionAlreadyOpen)
+
+	// a closed session that reuses the ID violates the primary key, not the open session index
+	duplicate := models.RegisterSession{Status: models.RegisterSessionStatusClosed, OpenedAt: time.Now()}
+	duplicate.ID = open.ID
+	duplicate.CreatedByID = open.CreatedByID
+
+	_, err = repo.CreateRegisterSession(duplicate)
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, sqliteRepo.ErrRegisterSessionAlreadyOpen)
+}
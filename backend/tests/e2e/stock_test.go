@@ -0,0 +1,144 @@
+package tests_e2e
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/gavv/httpexpect/v2"
+)
+
+func updateProductStock(productURL, name string, totalStock int, soldOut bool) {
+	withAdminUserAuthToken(e.PUT(productURL)).
+		WithJSON(map[string]any{
+			"name":       name,
+			"netPrice":   "10",
+			"vatRate":    "19",
+			"pos":        90,
+			"soldOut":    soldOut,
+			"totalStock": totalStock,
+			"trackStock": true,
+		}).
+		Expect().
+		Status(http.StatusOK)
+}
+
+func purchaseStockItems(productID, quantity int) *httpexpect.Response {
+	return withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithJSON(map[string]any{
+			"paymentMethod":   "CASH",
+			"totalNetPrice":   strconv.Itoa(10 * quantity),
+			"totalGrossPrice": strconv.FormatFloat(11.9*float64(quantity), 'f', 2, 64),
+			"cart": []map[string]any{
+				{
+					"ID":        productID,
+					"quantity":  quantity,
+					"netPrice":  "10",
+					"listItems": []map[string]any{},
+				},
+			},
+		}).
+		Expect()
+}
+
+func TestPurchaseEnforcesStockAndFlagsSoldOut(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	productID := int(withAdminUserAuthToken(e.POST(productBaseURL)).
+		WithJSON(map[string]any{"name": "Stock Shirt", "netPrice": "10", "vatRate": "19", "pos": 90}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object().
+		Value("id").Number().Raw())
+	productURL := productBaseURL + "/" + strconv.Itoa(productID)
+
+	updateProductStock(productURL, "Stock Shirt", 2, false)
+
+	purchaseID := purchaseStockItems(productID, 2).
+		Status(http.StatusCreated).JSON().Object().
+		Value("id").String().Raw()
+
+	withAdminUserAuthToken(e.GET(productURL)).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("soldOut").Boolean().IsTrue()
+
+	errorResponse := purchaseStockItems(productID, 1).
+		Status(http.StatusConflict).JSON().Object()
+	validateErrorDetailMessage(errorResponse, "Not enough stock left for Stock Shirt, 0 available")
+
+	updateProductStock(productURL, "Stock Shirt", 3, true)
+
+	withAdminUserAuthToken(e.GET(productURL)).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("soldOut").Boolean().IsFalse()
+
+	updateProductStock(productURL, "Stock Shirt", 2, false)
+
+	deletePurchase(purchaseBaseURL + "/" + purchaseID)
+
+	withAdminUserAuthToken(e.GET(productURL)).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("soldOut").Boolean().IsFalse()
+
+	withAdminUserAuthToken(e.DELETE(productURL)).
+		Expect().
+		Status(http.StatusNoContent)
+}
+
+func TestTrackedProductWithoutStockIsSoldOut(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	productID := int(withAdminUserAuthToken(e.POST(productBaseURL)).
+		WithJSON(map[string]any{"name": "Empty Shirt", "netPrice": "10", "vatRate": "19", "pos": 90}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object().
+		Value("id").Number().Raw())
+	productURL := productBaseURL + "/" + strconv.Itoa(productID)
+
+	updateProductStock(productURL, "Empty Shirt", 0, false)
+
+	product := withAdminUserAuthToken(e.GET(productURL)).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+	product.Value("trackStock").Boolean().IsTrue()
+	product.Value("soldOut").Boolean().IsTrue()
+
+	purchaseStockItems(productID, 1).
+		Status(http.StatusConflict)
+
+	withAdminUserAuthToken(e.DELETE(productURL)).
+		Expect().
+		Status(http.StatusNoContent)
+}
+
+func TestWrittenOffStockSellsOutTheProduct(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	productID := int(withAdminUserAuthToken(e.POST(productBaseURL)).
+		WithJSON(map[string]any{"name": "Broken Shirt", "netPrice": "10", "vatRate": "19", "pos": 90}).
+		Expect().
+		Status(http.StatusCreated).JSON().Object().
+		Value("id").Number().Raw())
+	productURL := productBaseURL + "/" + strconv.Itoa(productID)
+
+	updateProductStock(productURL, "Broken Shirt", 2, false)
+	recordInventoryMovement(productURL+"/inventoryMovements", map[string]any{"type": "write_off", "quantity": 2})
+
+	withAdminUserAuthToken(e.GET(productURL)).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("soldOut").Boolean().IsTrue()
+
+	errorResponse := purchaseStockItems(productID, 1).
+		Status(http.StatusConflict).JSON().Object()
+	validateErrorDetailMessage(errorResponse, "Not enough stock left for Broken Shirt, 0 available")
+
+	withAdminUserAuthToken(e.DELETE(productURL)).
+		Expect().
+		Status(http.StatusNoContent)
+}
@@ -0,0 +1,75 @@
+package tests_e2e
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+const sumupReconciliationURL = "/api/v2/sumup/reconciliation"
+
+func TestReconcileSumupTransactions(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	from := time.Date(2025, time.June, 15, 18, 0, 0, 0, time.UTC)
+	to := from.Add(12 * time.Hour)
+
+	withDemoUserAuthToken(e.POST(sumupReconciliationURL)).
+		WithJSON(map[string]any{"from": from, "to": to}).
+		Expect().
+		Status(http.StatusForbidden)
+
+	result := withAdminUserAuthToken(e.POST(sumupReconciliationURL)).
+		WithJSON(map[string]any{"from": from, "to": to, "repair": true}).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+
+	result.Value("consistent").Boolean().IsFalse()
+	result.Value("transactionCount").Number().IsEqual(1)
+	result.Value("purchaseCount").Number().IsEqual(0)
+
+	issues := result.Value("issues").Array()
+	issues.Length().IsEqual(1)
+
+	issue := issues.Value(0).Object()
+	issue.Value("kind").String().IsEqual("orphaned_transaction")
+	issue.Value("transactionCode").String().IsEqual("TAAAABCP2SA")
+	issue.Value("transactionStatus").String().IsEqual("successful")
+	issue.NotContainsKey("purchaseId")
+	issue.NotContainsKey("repairedStatus")
+}
+
+func TestReconcileSumupTransactionsWithoutIssues(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	from := time.Date(2025, time.June, 10, 18, 0, 0, 0, time.UTC)
+
+	result := withAdminUserAuthToken(e.POST(sumupReconciliationURL)).
+		WithJSON(map[string]any{"from": from, "to": from.Add(12 * time.Hour)}).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+
+	result.Value("consistent").Boolean().IsTrue()
+	result.Value("issues").Array().IsEmpty()
+}
+
+func TestReconcileSumupTransactionsWithInvalidPeriod(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	from := time.Date(2025, time.June, 15, 18, 0, 0, 0, time.UTC)
+
+	errorResponse := withAdminUserAuthToken(e.POST(sumupReconciliationURL)).
+		WithJSON(map[string]any{"from": from, "to": from.Add(-time.Hour)}).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object()
+
+	validateErrorDetailMessage(errorResponse, "The start of the period must be before its end")
+
+	withAdminUserAuthToken(e.POST(sumupReconciliationURL)).
+		WithJSON(map[string]any{"from": from}).
+		Expect().
+		Status(http.StatusBadRequest)
+}
@@ -0,0 +1,100 @@
+package tests_e2e
+
+import (
+	"net/http"
+	"testing"
+)
+
+var sumupWebhookURL = "/api/v2/sumup/webhook"
+
+func TestSumupWebhookCancelsAPendingPurchase(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	purchase := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithJSON(terminalPurchasePayload("fake-timeout")).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+
+	purchaseURL := purchaseBaseURL + "/" + purchase.Value("id").String().Raw()
+
+	clientTransactionID := withDemoUserAuthToken(e.GET(purchaseURL)).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("sumupClientTransactionId").String().Raw()
+
+	webhookPayload := map[string]any{
+		"event_type": "solo.transaction.updated",
+		"payload": map[string]any{
+			"client_transaction_id": clientTransactionID,
+			"status":                "CANCELED",
+		},
+	}
+
+	e.POST(sumupWebhookURL).
+		WithJSON(webhookPayload).
+		Expect().
+		Status(http.StatusOK)
+
+	updated := withDemoUserAuthToken(e.GET(purchaseURL)).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+
+	updated.Value("status").String().IsEqual("cancelled")
+
+	transition := updated.Value("statusTransitions").Array().Value(1).Object()
+	transition.Value("toStatus").String().IsEqual("cancelled")
+	transition.Value("source").String().IsEqual("webhook")
+	transition.Value("actorId").IsNull()
+
+	// a cancelled purchase cannot be confirmed by a late webhook
+	webhookPayload["payload"].(map[string]any)["status"] = "SUCCESSFUL"
+
+	e.POST(sumupWebhookURL).
+		WithJSON(webhookPayload).
+		Expect().
+		Status(http.StatusConflict)
+
+	deletePurchase(purchaseURL)
+}
+
+func TestSumupWebhookRedeliveryConfirmsAPurchaseOnce(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	purchase := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithJSON(terminalPurchasePayload("fake-timeout")).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+
+	purchaseURL := purchaseBaseURL + "/" + purchase.Value("id").String().Raw()
+
+	clientTransactionID := withDemoUserAuthToken(e.GET(purchaseURL)).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("sumupClientTransactionId").String().Raw()
+
+	webhookPayload := map[string]any{
+		"event_type": "solo.transaction.updated",
+		"payload": map[string]any{
+			"client_transaction_id": clientTransactionID,
+			"status":                "SUCCESSFUL",
+		},
+	}
+
+	for range 2 {
+		e.POST(sumupWebhookURL).
+			WithJSON(webhookPayload).
+			Expect().
+			Status(http.StatusOK)
+	}
+
+	updated := withDemoUserAuthToken(e.GET(purchaseURL)).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+
+	updated.Value("status").String().IsEqual("confirmed")
+	updated.Value("statusTransitions").Array().Length().IsEqual(2)
+
+	deletePurchase(purchaseURL)
+}
@@ -0,0 +1,137 @@
+package tests_e2e
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+var terminalsURL = "/api/v2/terminals"
+
+func TestGetTerminals(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	res := withDemoUserAuthToken(e.GET(terminalsURL)).
+		Expect()
+
+	res.Status(http.StatusOK)
+
+	res.Header(totalCountHeader).AsNumber().IsEqual(3)
+
+	terminals := res.JSON().Array()
+	terminals.Value(0).Object().Value("id").String().IsEqual("fake-approve")
+	terminals.Value(1).Object().Value("id").String().IsEqual("fake-decline")
+	terminals.Value(2).Object().Value("id").String().IsEqual("fake-timeout")
+}
+
+func TestGetTerminalsWithoutAuth(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	e.Request("GET", terminalsURL).Expect().Status(http.StatusUnauthorized)
+}
+
+func TestCreatePurchaseOnFakeTerminal(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	purchase := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithJSON(terminalPurchasePayload("fake-approve")).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+
+	purchase.Value("status").String().IsEqual("pending")
+
+	purchaseURL := purchaseBaseURL + "/" + purchase.Value("id").String().Raw()
+
+	withDemoUserAuthToken(e.GET(purchaseURL)).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("sumupClientTransactionId").String().NotEqual("00000000-0000-0000-0000-000000000000")
+
+	deletePurchase(purchaseURL)
+}
+
+func TestCreatePurchaseOnUnknownTerminal(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	errorResponse := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithJSON(terminalPurchasePayload("reader_1")).
+		Expect().
+		Status(http.StatusInternalServerError).JSON().Object()
+
+	validateErrorDetailMessage(errorResponse, "Failed to create terminal checkout: payment terminal not found")
+}
+
+func TestCancelPurchaseOnFakeTerminal(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	purchase := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithJSON(terminalPurchasePayload("fake-timeout")).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+
+	purchaseURL := purchaseBaseURL + "/" + purchase.Value("id").String().Raw()
+
+	withDemoUserAuthToken(e.POST(purchaseURL + "/cancel")).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("status").String().IsEqual("cancelled")
+
+	errorResponse := withDemoUserAuthToken(e.POST(purchaseURL + "/cancel")).
+		Expect().
+		Status(http.StatusConflict).JSON().Object()
+
+	validateErrorDetailMessage(errorResponse, "The purchase is not pending")
+
+	transitions := withDemoUserAuthToken(e.GET(purchaseURL)).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("statusTransitions").Array()
+
+	transitions.Length().IsEqual(2)
+
+	created := transitions.Value(0).Object()
+	created.Value("fromStatus").String().IsEmpty()
+	created.Value("toStatus").String().IsEqual("pending")
+	created.Value("source").String().IsEqual("user")
+	created.Value("actor").String().IsEqual("demo")
+
+	cancelled := transitions.Value(1).Object()
+	cancelled.Value("fromStatus").String().IsEqual("pending")
+	cancelled.Value("toStatus").String().IsEqual("cancelled")
+	cancelled.Value("source").String().IsEqual("user")
+	cancelled.Value("actor").String().IsEqual("demo")
+
+	deletePurchase(purchaseURL)
+}
+
+func TestCancelUnknownPurchase(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	withDemoUserAuthToken(e.POST(purchaseBaseURL + "/" + uuid.New().String() + "/cancel")).
+		Expect().
+		Status(http.StatusNotFound)
+}
+
+func terminalPurchasePayload(terminalID string) map[string]any {
+	return map[string]any{
+		"paymentMethod":   "SUMUP",
+		"sumupReaderId":   terminalID,
+		"totalNetPrice":   "37.38",
+		"totalGrossPrice": "40",
+		"cart": []map[string]any{
+			{
+				"ID":        1,
+				"quantity":  1,
+				"netPrice":  "37.38",
+				"listItems": []map[string]any{},
+			},
+		},
+	}
+}
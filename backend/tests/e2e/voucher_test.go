@@ -0,0 +1,149 @@
+package tests_e2e
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const voucherBaseURL = "/api/v2/vouchers"
+
+func TestVoucherAuthentication(t *testing.T) {
+	testAuthenticationForEntityEndpoints(t, voucherBaseURL, voucherBaseURL+"/1")
+}
+
+func TestVoucherEndpointsRequireAdmin(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	withDemoUserAuthToken(e.GET(voucherBaseURL)).Expect().Status(http.StatusForbidden)
+	withDemoUserAuthToken(e.POST(voucherBaseURL)).
+		WithJSON(map[string]any{"value": "20"}).
+		Expect().
+		Status(http.StatusForbidden)
+}
+
+func voucherPurchasePayload(code string) map[string]any {
+	return map[string]any{
+		"paymentMethod":   "VOUCHER",
+		"voucherCode":     code,
+		"totalNetPrice":   "18.69",
+		"totalGrossPrice": "20",
+		"cart": []map[string]any{
+			{"ID": 2, "quantity": 1, "netPrice": "18.69", "listItems": []map[string]any{}},
+		},
+	}
+}
+
+func TestVoucherRedemption(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	vouchers := withAdminUserAuthToken(e.POST(voucherBaseURL)).
+		WithJSON(map[string]any{"name": "Crew", "value": "30", "count": 2}).
+		Expect().
+		Status(http.StatusCreated).JSON().Array()
+
+	vouchers.Length().IsEqual(2)
+
+	voucher := vouchers.Value(0).Object()
+	voucher.Value("balance").String().IsEqual("30")
+	code := voucher.Value("code").String().Raw()
+	voucherURL := voucherBaseURL + "/" + strconv.Itoa(int(voucher.Value("id").Number().Raw()))
+
+	// codes can be typed without dashes and in lower case
+	purchase := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithJSON(voucherPurchasePayload(strings.ToLower(strings.ReplaceAll(code, "-", "")))).
+		Expect().
+		Status(http.StatusCreated).JSON().Object()
+
+	purchase.Value("payments").Array().Value(0).Object().Value("voucherId").Number().Gt(0)
+
+	voucher = withAdminUserAuthToken(e.GET(voucherURL)).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+
+	voucher.Value("balance").String().IsEqual("10")
+	voucher.Value("redemptions").Array().Length().IsEqual(1)
+	voucher.Value("redemptions").Array().Value(0).Object().Value("amount").String().IsEqual("20")
+
+	// the remaining balance does not cover a second purchase
+	errorResponse := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithJSON(voucherPurchasePayload(code)).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object()
+
+	validateErrorDetailMessage(errorResponse, "Voucher balance is insufficient")
+
+	// deleting the purchase credits the amount back to the voucher
+	deletePurchase(purchaseBaseURL + "/" + purchase.Value("id").String().Raw())
+
+	voucher = withAdminUserAuthToken(e.GET(voucherURL)).
+		Expect().
+		Status(http.StatusOK).JSON().Object()
+
+	voucher.Value("balance").String().IsEqual("30")
+	voucher.Value("redemptions").Array().Length().IsEqual(2)
+
+	withAdminUserAuthToken(e.DELETE(voucherURL)).Expect().Status(http.StatusNoContent)
+	withAdminUserAuthToken(e.GET(voucherURL)).Expect().Status(http.StatusNotFound)
+}
+
+func TestVoucherRedemptionWithUnknownCode(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	errorResponse := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithJSON(voucherPurchasePayload("AAAA-BBBB-CCCC")).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object()
+
+	validateErrorDetailMessage(errorResponse, "Voucher not found")
+}
+
+func TestVoucherRedemptionWithoutCode(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	errorResponse := withDemoUserAuthToken(e.POST(purchaseBaseURL)).
+		WithJSON(voucherPurchasePayload("")).
+		Expect().
+		Status(http.StatusBadRequest).JSON().Object()
+
+	validateErrorDetailMessage(errorResponse, "Voucher payments require a voucher code")
+}
+
+func TestVoucherUpdateAndPrint(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	voucher := withAdminUserAuthToken(e.POST(voucherBaseURL)).
+		WithJSON(map[string]any{"name": "Sponsor", "value": "50", "expiresAt": "2099-12-31T23:59:00Z"}).
+		Expect().
+		Status(http.StatusCreated).JSON().Array().Value(0).Object()
+
+	id := strconv.Itoa(int(voucher.Value("id").Number().Raw()))
+	code := voucher.Value("code").String().Raw()
+
+	withAdminUserAuthToken(e.PUT(voucherBaseURL + "/" + id)).
+		WithJSON(map[string]any{"name": "Main sponsor", "expiresAt": "2099-12-31T23:59:00Z"}).
+		Expect().
+		Status(http.StatusOK).JSON().Object().
+		Value("name").String().IsEqual("Main sponsor")
+
+	withAdminUserAuthToken(e.GET(voucherBaseURL)).
+		WithQuery("q", code).
+		Expect().
+		Status(http.StatusOK).JSON().Array().Length().IsEqual(1)
+
+	page := withAdminUserAuthToken(e.GET(voucherBaseURL+"/print")).
+		WithQuery("id", id).
+		Expect().
+		Status(http.StatusOK)
+
+	page.Header("Content-Type").Contains("text/html")
+	page.Body().Contains(code).Contains("Main sponsor").Contains("50.00 DKK")
+
+	withAdminUserAuthToken(e.DELETE(voucherBaseURL + "/" + id)).Expect().Status(http.StatusNoContent)
+}
@@ -0,0 +1,24 @@
+package tests_models
+
+import (
+	"testing"
+
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserCategoryIDs(t *testing.T) {
+	drinks := models.Category{Name: "Drinks"}
+	drinks.ID = 3
+	snacks := models.Category{Name: "Snacks"}
+	snacks.ID = 5
+
+	user := models.User{Categories: []models.Category{drinks, snacks}}
+	assert.Equal(t, []int{3, 5}, user.CategoryIDs())
+
+	user.Categories = nil
+	assert.Empty(t, user.CategoryIDs())
+
+	admin := models.User{Admin: true, Categories: []models.Category{drinks}}
+	assert.Empty(t, admin.CategoryIDs())
+}
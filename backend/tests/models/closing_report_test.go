@@ -0,0 +1,106 @@
+package tests_models
+
+import (
+	"testing"
+
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClosingReportBuilder(t *testing.T) {
+	purchase := models.Purchase{
+		TotalGrossPrice: decimal.NewFromFloat(33.8),
+		PurchaseItems: []models.PurchaseItem{
+			{
+				ProductID: 1,
+				Product:   models.Product{Name: "Ticket"},
+				Quantity:  2,
+				NetPrice:  decimal.NewFromInt(10),
+				VATRate:   decimal.NewFromInt(19),
+			},
+			{
+				ProductID: 2,
+				Product:   models.Product{Name: "Shirt"},
+				Quantity:  1,
+				NetPrice:  decimal.NewFromInt(10),
+				VATRate:   decimal.NewFromInt(0),
+			},
+		},
+		Payments: []models.PurchasePayment{
+			{PaymentMethod: models.PaymentMethodCash, Amount: decimal.NewFromFloat(13.8)},
+			{PaymentMethod: models.PaymentMethodCC, Amount: decimal.NewFromInt(20)},
+		},
+	}
+	purchase.PurchaseItems[0].ID = 11
+
+	refund := models.PurchaseRefund{
+		TotalNetPrice:   decimal.NewFromInt(10),
+		TotalGrossPrice: decimal.NewFromFloat(11.9),
+		Items: []models.PurchaseRefundItem{
+			{PurchaseItemID: 11, Quantity: 1, NetPrice: decimal.NewFromInt(10), VATRate: decimal.NewFromInt(19)},
+		},
+		Payments: []models.PurchaseRefundPayment{
+			{PaymentMethod: models.PaymentMethodCash, Amount: decimal.NewFromFloat(11.9)},
+		},
+	}
+
+	builder := models.NewClosingReportBuilder(2)
+	builder.AddPurchase(purchase)
+	builder.AddRefund(refund, map[int]models.PurchaseItem{11: purchase.PurchaseItems[0]})
+	builder.AddCancellation(models.Purchase{TotalGrossPrice: decimal.NewFromInt(5)})
+
+	figures := builder.Figures()
+
+	assert.Equal(t, 1, figures.PurchaseCount)
+	assert.True(t, decimal.NewFromInt(20).Equal(figures.TotalNetPrice))
+	assert.True(t, decimal.NewFromFloat(1.9).Equal(figures.TotalVATAmount))
+	assert.True(t, decimal.NewFromFloat(21.9).Equal(figures.TotalGrossPrice))
+
+	assert.Len(t, figures.VATRates, 2)
+	assert.True(t, decimal.Zero.Equal(figures.VATRates[0].VATRate))
+	assert.True(t, decimal.NewFromFloat(11.9).Equal(figures.VATRates[1].GrossPrice))
+	assert.True(t, decimal.NewFromFloat(11.9).Equal(figures.VATRates[1].RefundedGrossPrice))
+
+	assert.Len(t, figures.PaymentMethods, 2)
+	assert.Equal(t, models.PaymentMethodCash, figures.PaymentMethods[0].PaymentMethod)
+	assert.True(t, decimal.NewFromFloat(1.9).Equal(figures.PaymentMethods[0].Amount))
+	assert.True(t, decimal.NewFromFloat(11.9).Equal(figures.PaymentMethods[0].RefundedAmount))
+
+	assert.Len(t, figures.Products, 2)
+	assert.Equal(t, "Ticket", figures.Products[0].Name)
+	assert.Equal(t, 1, figures.Products[0].Quantity)
+	assert.Equal(t, uint(1), figures.Products[0].RefundedQuantity)
+
+	assert.Equal(t, 1, figures.Refunds.Count)
+	assert.Equal(t, 1, figures.Cancellations.Count)
+	assert.True(t, decimal.NewFromInt(5).Equal(figures.Cancellations.GrossPrice))
+}
+
+func TestClosingReportBuilderWithDiscount(t *testing.T) {
+	purchase := models.Purchase{
+		PurchaseItems: []models.PurchaseItem{
+			{
+				ProductID: 1,
+				Product:   models.Product{Name: "Ticket"},
+				Quantity:  2,
+				NetPrice:  decimal.NewFromInt(10),
+				VATRate:   decimal.NewFromInt(19),
+			},
+		},
+	}
+
+	discount := models.Discount{Name: "Crew", Type: models.DiscountTypePercentage, Value: decimal.NewFromInt(50)}
+	discount.ApplyTo(purchase.PurchaseItems, 2)
+
+	builder := models.NewClosingReportBuilder(2)
+	builder.AddPurchase(purchase)
+
+	figures := builder.Figures()
+
+	// the discount reduces the taxable amount of its VAT rate
+	assert.True(t, decimal.NewFromInt(10).Equal(figures.VATRates[0].NetPrice))
+	assert.True(t, decimal.NewFromFloat(1.9).Equal(figures.VATRates[0].VATAmount))
+	assert.True(t, decimal.NewFromFloat(11.9).Equal(figures.TotalGrossPrice))
+	assert.True(t, decimal.NewFromFloat(11.9).Equal(figures.Products[0].GrossPrice))
+}
@@ -0,0 +1,105 @@
+package tests_models
+
+import (
+	"testing"
+
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func discountTestItems() []models.PurchaseItem {
+	return []models.PurchaseItem{
+		{ProductID: 1, Quantity: 2, NetPrice: decimal.NewFromInt(10), VATRate: decimal.NewFromInt(19)},
+		{ProductID: 2, Quantity: 1, NetPrice: decimal.NewFromInt(10), VATRate: decimal.NewFromInt(0)},
+	}
+}
+
+func TestDiscountApplyToCartWithPercentage(t *testing.T) {
+	items := discountTestItems()
+	discount := models.Discount{Name: "Crew", Type: models.DiscountTypePercentage, Value: decimal.NewFromInt(10)}
+
+	require.True(t, discount.ApplyTo(items, 2))
+
+	// 10% of 23.80 and 10.00, each line keeps the VAT rate of its item
+	require.Len(t, items[0].Discounts, 1)
+	assert.Equal(t, "2.38", items[0].DiscountGrossAmount().StringFixed(2))
+	assert.Equal(t, "2.00", items[0].DiscountNetAmount().StringFixed(2))
+	assert.True(t, items[0].Discounts[0].VATRate.Equal(decimal.NewFromInt(19)))
+
+	require.Len(t, items[1].Discounts, 1)
+	assert.Equal(t, "1.00", items[1].DiscountGrossAmount().StringFixed(2))
+	assert.Equal(t, "1.00", items[1].DiscountNetAmount().StringFixed(2))
+
+	assert.Equal(t, "21.42", items[0].TotalDiscountedGrossPrice(2).StringFixed(2))
+}
+
+func TestDiscountApplyToCartWithFixedAmount(t *testing.T) {
+	items := discountTestItems()
+	discount := models.Discount{Name: "Coupon", Type: models.DiscountTypeFixed, Value: decimal.NewFromInt(5)}
+
+	require.True(t, discount.ApplyTo(items, 2))
+
+	total := items[0].DiscountGrossAmount().Add(items[1].DiscountGrossAmount())
+	assert.Equal(t, "5.00", total.StringFixed(2))
+	assert.Equal(t, "3.52", items[0].DiscountGrossAmount().StringFixed(2))
+	assert.Equal(t, "1.48", items[1].DiscountGrossAmount().StringFixed(2))
+}
+
+func TestDiscountApplyToProductWithFixedAmountPerUnit(t *testing.T) {
+	items := discountTestItems()
+	productID := 1
+	discount := models.Discount{
+		Name:      "Early bird",
+		Type:      models.DiscountTypeFixed,
+		Value:     decimal.NewFromInt(2),
+		ProductID: &productID,
+	}
+
+	require.True(t, discount.ApplyTo(items, 2))
+
+	assert.Equal(t, "4.00", items[0].DiscountGrossAmount().StringFixed(2))
+	assert.Equal(t, "3.36", items[0].DiscountNetAmount().StringFixed(2))
+	assert.Empty(t, items[1].Discounts)
+}
+
+func TestDiscountApplyToNeverGoesBelowZero(t *testing.T) {
+	items := discountTestItems()
+	discount := models.Discount{Name: "Gift", Type: models.DiscountTypeFixed, Value: decimal.NewFromInt(100)}
+
+	require.True(t, discount.ApplyTo(items, 2))
+
+	assert.True(t, items[0].TotalDiscountedGrossPrice(2).IsZero())
+	assert.True(t, items[1].TotalDiscountedGrossPrice(2).IsZero())
+
+	// nothing is left to discount
+	assert.False(t, discount.ApplyTo(items, 2))
+}
+
+func TestDiscountApplyToProductNotInCart(t *testing.T) {
+	items := discountTestItems()
+	productID := 3
+	discount := models.Discount{
+		Name:      "Shirt",
+		Type:      models.DiscountTypePercentage,
+		Value:     decimal.NewFromInt(50),
+		ProductID: &productID,
+	}
+
+	assert.False(t, discount.ApplyTo(items, 2))
+}
+
+func TestDiscountValidate(t *testing.T) {
+	valid := models.Discount{Type: models.DiscountTypePercentage, Value: decimal.NewFromInt(100)}
+	assert.NoError(t, valid.Validate())
+
+	tooHigh := models.Discount{Type: models.DiscountTypePercentage, Value: decimal.NewFromInt(101)}
+	assert.ErrorIs(t, tooHigh.Validate(), models.ErrInvalidDiscountValue)
+
+	negative := models.Discount{Type: models.DiscountTypeFixed, Value: decimal.NewFromInt(-1)}
+	assert.ErrorIs(t, negative.Validate(), models.ErrInvalidDiscountValue)
+
+	unknown := models.Discount{Type: "bogo", Value: decimal.NewFromInt(1)}
+	assert.ErrorIs(t, unknown.Validate(), models.ErrInvalidDiscountType)
+}
@@ -0,0 +1,88 @@
+package tests_models
+
+import (
+	"testing"
+
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func newBundleComponent(id int, netPrice float64, vatRate int64) models.Product {
+	product := models.Product{
+		NetPrice: decimal.NewFromFloat(netPrice),
+		VATRate:  decimal.NewFromInt(vatRate),
+	}
+	product.ID = id
+
+	return product
+}
+
+func TestProductSetBundleItemsSplitsPriceAcrossVATRates(t *testing.T) {
+	ticket := newBundleComponent(1, 18.69, 7)
+	shirt := newBundleComponent(2, 16.81, 19)
+
+	bundle := models.Product{}
+	bundle.ID = 3
+
+	err := bundle.SetBundleItems([]models.ProductBundleItem{
+		{ComponentID: 1, Component: ticket, Quantity: 1},
+		{ComponentID: 2, Component: shirt, Quantity: 1},
+	}, decimal.NewFromInt(35), 2)
+	assert.NoError(t, err)
+
+	assert.True(t, bundle.IsBundle())
+	assert.Equal(t, "16.36", bundle.BundleItems[0].NetPrice.StringFixed(2))
+	assert.Equal(t, "7", bundle.BundleItems[0].VATRate.String())
+	assert.Equal(t, "14.70", bundle.BundleItems[1].NetPrice.StringFixed(2))
+	assert.Equal(t, "19", bundle.BundleItems[1].VATRate.String())
+
+	assert.Equal(t, "31.06", bundle.NetPrice.StringFixed(2))
+	assert.Equal(t, "35.00", bundle.GrossPrice(2).StringFixed(2))
+	assert.Equal(t, "3.94", bundle.VATAmount(2).StringFixed(2))
+}
+
+func TestProductExpandBundle(t *testing.T) {
+	ticket := newBundleComponent(1, 18.69, 7)
+	drink := newBundleComponent(2, 2.52, 19)
+
+	bundle := models.Product{}
+	bundle.ID = 3
+
+	err := bundle.SetBundleItems([]models.ProductBundleItem{
+		{ComponentID: 1, Component: ticket, Quantity: 1},
+		{ComponentID: 2, Component: drink, Quantity: 2},
+	}, decimal.NewFromInt(24), 2)
+	assert.NoError(t, err)
+
+	items := bundle.ExpandBundle(2)
+	assert.Len(t, items, 2)
+	assert.Equal(t, 1, items[0].ProductID)
+	assert.Equal(t, uint(2), items[0].Quantity)
+	assert.Equal(t, 2, items[1].ProductID)
+	assert.Equal(t, uint(4), items[1].Quantity)
+	assert.Equal(t, 3, *items[1].BundleID)
+
+	total := items[0].TotalGrossPrice(2).Add(items[1].TotalGrossPrice(2))
+	assert.True(t, total.Equal(bundle.GrossPrice(2).Mul(decimal.NewFromInt(2))), "unexpected total %s", total)
+}
+
+func TestProductSetBundleItemsRejectsNestedBundles(t *testing.T) {
+	inner := models.Product{}
+	inner.ID = 1
+	err := inner.SetBundleItems([]models.ProductBundleItem{
+		{ComponentID: 2, Component: newBundleComponent(2, 10, 19), Quantity: 1},
+	}, decimal.NewFromInt(10), 2)
+	assert.NoError(t, err)
+
+	outer := models.Product{}
+	err = outer.SetBundleItems([]models.ProductBundleItem{
+		{ComponentID: 1, Component: inner, Quantity: 1},
+	}, decimal.NewFromInt(10), 2)
+	assert.ErrorIs(t, err, models.ErrBundleComponentIsBundle)
+
+	err = outer.SetBundleItems([]models.ProductBundleItem{
+		{ComponentID: 2, Component: newBundleComponent(2, 10, 19), Quantity: 1},
+	}, decimal.Zero, 2)
+	assert.ErrorIs(t, err, models.ErrInvalidBundlePrice)
+}
@@ -0,0 +1,121 @@
+package tests_models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+// 2026-06-05 is a Friday.
+func partyTime(day, hour int) time.Time {
+	return time.Date(2026, time.June, day, hour, 0, 0, 0, time.UTC)
+}
+
+func newPricedProduct(rules ...models.ProductPriceRule) models.Product {
+	return models.Product{
+		NetPrice:   decimal.NewFromInt(30),
+		VATRate:    decimal.NewFromInt(7),
+		PriceRules: rules,
+	}
+}
+
+func TestProductPriceRuleActiveAtPeriod(t *testing.T) {
+	rule := models.ProductPriceRule{
+		ValidFrom:  timePtr(partyTime(5, 0)),
+		ValidUntil: timePtr(partyTime(6, 0)),
+	}
+
+	assert.False(t, rule.ActiveAt(partyTime(4, 23)))
+	assert.True(t, rule.ActiveAt(partyTime(5, 0)))
+	assert.True(t, rule.ActiveAt(partyTime(5, 23)))
+	assert.False(t, rule.ActiveAt(partyTime(6, 0)))
+}
+
+func TestProductPriceRuleActiveAtWeekdaysAndHours(t *testing.T) {
+	rule := models.ProductPriceRule{
+		Weekdays:  []time.Weekday{time.Friday, time.Saturday},
+		FromHour:  intPtr(22),
+		UntilHour: intPtr(2),
+	}
+
+	assert.True(t, rule.ActiveAt(partyTime(5, 22)))
+	assert.True(t, rule.ActiveAt(partyTime(6, 1)))
+	assert.False(t, rule.ActiveAt(partyTime(6, 2)))
+	assert.False(t, rule.ActiveAt(partyTime(5, 21)))
+	assert.False(t, rule.ActiveAt(partyTime(7, 23)), "Sunday is not in the pattern")
+}
+
+func TestProductPriceAtUsesFirstActiveRule(t *testing.T) {
+	product := newPricedProduct(
+		models.ProductPriceRule{NetPrice: decimal.NewFromInt(25), FromHour: intPtr(18), UntilHour: intPtr(20)},
+		models.ProductPriceRule{NetPrice: decimal.NewFromInt(20), ValidFrom: timePtr(partyTime(7, 0))},
+	)
+
+	assert.Equal(t, "30", product.PriceAt(partyTime(5, 12)).NetPrice.String())
+	assert.Equal(t, "25", product.PriceAt(partyTime(5, 18)).NetPrice.String())
+	assert.Equal(t, "25", product.PriceAt(partyTime(7, 19)).NetPrice.String())
+	assert.Equal(t, "20", product.PriceAt(partyTime(7, 20)).NetPrice.String())
+	assert.Equal(t, "30", product.NetPrice.String(), "the list price is kept")
+}
+
+func TestProductNextPriceChange(t *testing.T) {
+	product := newPricedProduct(
+		models.ProductPriceRule{NetPrice: decimal.NewFromInt(25), FromHour: intPtr(18), UntilHour: intPtr(20)},
+	)
+
+	at := partyTime(5, 12).Add(30 * time.Minute)
+
+	validFrom, netPrice, ok := product.NextPriceChange(at)
+	assert.True(t, ok)
+	assert.Equal(t, partyTime(5, 18), validFrom)
+	assert.Equal(t, "25", netPrice.String())
+
+	validFrom, netPrice, ok = product.NextPriceChange(partyTime(5, 18))
+	assert.True(t, ok)
+	assert.Equal(t, partyTime(5, 20), validFrom)
+	assert.Equal(t, "30", netPrice.String())
+
+	_, _, ok = newPricedProduct().NextPriceChange(at)
+	assert.False(t, ok)
+}
+
+func TestProductNextPriceChangeInAZoneWithAHalfHourOffset(t *testing.T) {
+	zone := time.FixedZone("IST", 5*60*60+30*60)
+
+	product := newPricedProduct(
+		models.ProductPriceRule{NetPrice: decimal.NewFromInt(25), FromHour: intPtr(18), UntilHour: intPtr(20)},
+	)
+
+	validFrom, netPrice, ok := product.NextPriceChange(time.Date(2026, time.June, 5, 12, 10, 0, 0, zone))
+	assert.True(t, ok)
+	assert.True(t, time.Date(2026, time.June, 5, 18, 0, 0, 0, zone).Equal(validFrom),
+		"expected the change at 18:00 local time, got %s", validFrom)
+	assert.Equal(t, "25", netPrice.String())
+}
+
+func TestProductPriceRuleValidate(t *testing.T) {
+	assert.NoError(t, models.ProductPriceRule{NetPrice: decimal.NewFromInt(1)}.Validate())
+
+	assert.ErrorIs(t, models.ProductPriceRule{NetPrice: decimal.NewFromInt(-1)}.Validate(),
+		models.ErrInvalidPriceRulePrice)
+
+	assert.ErrorIs(t, models.ProductPriceRule{
+		ValidFrom:  timePtr(partyTime(6, 0)),
+		ValidUntil: timePtr(partyTime(5, 0)),
+	}.Validate(), models.ErrInvalidPriceRulePeriod)
+
+	assert.ErrorIs(t, models.ProductPriceRule{FromHour: intPtr(18)}.Validate(), models.ErrInvalidPriceRuleHours)
+	assert.ErrorIs(t, models.ProductPriceRule{FromHour: intPtr(18), UntilHour: intPtr(18)}.Validate(),
+		models.ErrInvalidPriceRuleHours)
+}
@@ -0,0 +1,76 @@
+package tests_models
+
+import (
+	"testing"
+
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func newShirtWithVariants() models.Product {
+	override := decimal.NewFromFloat(20.17)
+
+	shirt := models.Product{
+		NetPrice: decimal.NewFromFloat(16.81),
+		VATRate:  decimal.NewFromInt(19),
+		Variants: []models.ProductVariant{
+			{Name: "S", TotalStock: 5},
+			{Name: "XXL", TotalStock: 3, NetPrice: &override},
+		},
+	}
+	shirt.Variants[0].ID = 1
+	shirt.Variants[1].ID = 2
+
+	return shirt
+}
+
+func TestProductWithVariantOverridesPrice(t *testing.T) {
+	shirt := newShirtWithVariants()
+
+	small, ok := shirt.Variant(1)
+	assert.True(t, ok)
+	assert.Equal(t, "20.00", shirt.WithVariant(small).GrossPrice(2).StringFixed(2))
+
+	large, ok := shirt.Variant(2)
+	assert.True(t, ok)
+	assert.Equal(t, "24.00", shirt.WithVariant(large).GrossPrice(2).StringFixed(2))
+
+	_, ok = shirt.Variant(3)
+	assert.False(t, ok)
+}
+
+func TestProductVariantRollUp(t *testing.T) {
+	shirt := newShirtWithVariants()
+
+	assert.True(t, shirt.HasVariants())
+	assert.Equal(t, 8, shirt.VariantTotalStock())
+	assert.False(t, shirt.VariantsSoldOut())
+
+	shirt.Variants[0].SoldOut = true
+	assert.False(t, shirt.VariantsSoldOut())
+
+	shirt.Variants[1].SoldOut = true
+	assert.True(t, shirt.VariantsSoldOut())
+}
+
+func TestProductTracksStock(t *testing.T) {
+	shirt := newShirtWithVariants()
+	shirt.TrackStock = true
+	shirt.Variants[1].TrackStock = true
+
+	assert.False(t, shirt.TracksStock())
+	assert.False(t, shirt.Variants[0].TracksStock())
+	assert.True(t, shirt.Variants[1].TracksStock())
+
+	mug := models.Product{TrackStock: true}
+	assert.True(t, mug.TracksStock())
+}
+
+func TestProductWithoutVariants(t *testing.T) {
+	product := models.Product{TotalStock: 4, SoldOut: true}
+
+	assert.False(t, product.HasVariants())
+	assert.Equal(t, 4, product.VariantTotalStock())
+	assert.True(t, product.VariantsSoldOut())
+}
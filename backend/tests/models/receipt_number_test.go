@@ -0,0 +1,25 @@
+package tests_models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFiscalYear(t *testing.T) {
+	february := time.Date(2026, time.February, 14, 12, 0, 0, 0, time.UTC)
+	july := time.Date(2026, time.July, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, 2026, models.FiscalYear(february, time.January))
+	assert.Equal(t, 2026, models.FiscalYear(july, time.January))
+	assert.Equal(t, 2025, models.FiscalYear(february, time.July))
+	assert.Equal(t, 2026, models.FiscalYear(july, time.July))
+	assert.Equal(t, 2026, models.FiscalYear(february, 0))
+}
+
+func TestFormatReceiptNumber(t *testing.T) {
+	assert.Equal(t, "K1-2026-000042", models.FormatReceiptNumber("K1", 2026, 42))
+	assert.Equal(t, "2026-000001", models.FormatReceiptNumber("", 2026, 1))
+}
@@ -0,0 +1,34 @@
+package tests_models
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/potibm/kasseapparat/internal/app/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateVoucherCode(t *testing.T) {
+	code, err := models.GenerateVoucherCode()
+
+	assert.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^[2-9A-HJ-NP-Z]{4}-[2-9A-HJ-NP-Z]{4}-[2-9A-HJ-NP-Z]{4}$`), code)
+	assert.Equal(t, code, models.NormalizeVoucherCode(code))
+}
+
+func TestNormalizeVoucherCode(t *testing.T) {
+	assert.Equal(t, "7KQM-X3RT-PW9C", models.NormalizeVoucherCode("7kqmx3rtpw9c"))
+	assert.Equal(t, "7KQM-X3RT-PW9C", models.NormalizeVoucherCode(" 7KQM X3RT-PW9C "))
+}
+
+func TestVoucherCheckRedeemable(t *testing.T) {
+	now := time.Date(2026, time.March, 1, 20, 0, 0, 0, time.UTC)
+	expiresAt := now.Add(time.Hour)
+	voucher := models.Voucher{Balance: decimal.NewFromInt(20), ExpiresAt: &expiresAt}
+
+	assert.NoError(t, voucher.CheckRedeemable(decimal.NewFromInt(20), now))
+	assert.ErrorIs(t, voucher.CheckRedeemable(decimal.NewFromInt(21), now), models.ErrVoucherInsufficientBalance)
+	assert.ErrorIs(t, voucher.CheckRedeemable(decimal.NewFromInt(1), expiresAt), models.ErrVoucherExpired)
+}
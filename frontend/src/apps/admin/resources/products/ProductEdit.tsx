@@ -58,6 +58,10 @@ export const ProductEdit: React.FC = () => {
         </FormTab>
         <FormTab label="Stock">
           <h3>Stock</h3>
+          <BooleanInput
+            source="trackStock"
+            helperText="Enforce the stock, the product is sold out when none is left"
+          />
           <NumberInput
             source="totalStock"
             min={0}
@@ -11,6 +11,7 @@ export interface Product extends RaRecord {
   soldOut: boolean;
   hidden: boolean;
   totalStock?: number;
+  trackStock?: boolean;
   unitsSold?: number;
   soldOutRequestCount?: number;
   apiExport?: boolean;